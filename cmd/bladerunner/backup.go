@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/backup"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+)
+
+var backupFlags struct {
+	name string
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Archive the stopped VM's disk and identity as a save point",
+	Long: `Copy disk.raw, efi-vars.bin, machine-id.bin, and runtime-metadata.json into a
+single timestamped archive under <state-dir>/backups/. Use 'br rollback' to put
+them back, e.g. after a risky upgrade inside the guest goes wrong.
+
+A backup captures no RAM and has no overlay chain; it is simply a copy of the
+files. The VM must be stopped.`,
+	Example: renderExamples(
+		example{Comment: "Take a save point before a risky change in the guest", Args: "stop && br backup"},
+		example{Comment: "See what backups exist", Args: "backup list"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runBackup,
+}
+
+var backupListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List backups with their sizes",
+	Args:    cobra.NoArgs,
+	RunE:    runBackupList,
+}
+
+var rollbackFlags struct {
+	confirm bool
+	name    string
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [backup]",
+	Short: "Restore the VM from a backup (default: the newest)",
+	Long: `Replace the VM's disk and identity files with the contents of a backup made
+by 'br backup'. Without an argument the newest backup is used; see
+'br backup list' for names. The VM must be stopped.`,
+	Example: renderExamples(
+		example{Comment: "Restore the newest backup", Args: "rollback"},
+		example{Comment: "Restore a specific backup without prompting", Args: "rollback backup-20260501T120000Z --yes"},
+	),
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRollback,
+}
+
+func init() {
+	backupCmd.PersistentFlags().StringVar(&backupFlags.name, "name", "", nameFlagUsage)
+	backupCmd.AddCommand(backupListCmd)
+	rollbackCmd.Flags().BoolVarP(&rollbackFlags.confirm, "yes", "y", false, "Skip confirmation prompt")
+	rollbackCmd.Flags().StringVar(&rollbackFlags.name, "name", "", nameFlagUsage)
+}
+
+// backupResult is the JSON payload for `br backup` and `br rollback`.
+type backupResult struct {
+	Status string      `json:"status"` // "created" | "restored"
+	Backup backup.Info `json:"backup"`
+}
+
+// backupVMDir returns the directory of the VM called name ("" for the
+// default one), refusing when it is running: copying or replacing disk.raw
+// under a live guest would corrupt it.
+func backupVMDir(name, verb string) (string, error) {
+	vmDir, err := namedVMDir(name)
+	if err != nil {
+		return "", err
+	}
+	if control.NewClient(vmDir).IsRunning() {
+		return "", fmt.Errorf("VM is running; stop it first ('br stop') before %s", verb)
+	}
+	return vmDir, nil
+}
+
+func runBackup(_ *cobra.Command, _ []string) error {
+	vmDir, err := backupVMDir(backupFlags.name, "taking a backup")
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	if !jsonOutput {
+		fmt.Printf("Backing up %s ...\n", value(vmDir))
+	}
+	b, err := backup.Create(vmDir, time.Now())
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	if jsonOutput {
+		return emitJSON(backupResult{Status: "created", Backup: b})
+	}
+	fmt.Printf("%s Backup %s created (%s)\n", success("✓"), value(b.Name), logging.HumanBytes(b.Size))
+	fmt.Printf("  Restore with: %s\n", command(withName("br rollback "+b.Name, backupFlags.name)))
+	return nil
+}
+
+func runBackupList(_ *cobra.Command, _ []string) error {
+	vmDir, err := namedVMDir(backupFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	backups, err := backup.List(vmDir)
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	if jsonOutput {
+		if backups == nil {
+			backups = []backup.Info{}
+		}
+		return emitJSON(backups)
+	}
+	if len(backups) == 0 {
+		fmt.Printf("No backups in %s\n", backup.Dir(vmDir))
+		return nil
+	}
+	for _, b := range backups {
+		fmt.Printf("  %s  %s  %s\n", value(b.Name), subtle(b.Created.Local().Format(time.DateTime)), logging.HumanBytes(b.Size))
+	}
+	return nil
+}
+
+func runRollback(_ *cobra.Command, args []string) error {
+	vmDir, err := backupVMDir(rollbackFlags.name, "rolling back")
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+	}
+	b, err := backup.Resolve(vmDir, name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	if !rollbackFlags.confirm {
+		if jsonOutput {
+			return jsonOrError(fmt.Errorf("rollback requires --yes when --json is set (cannot prompt for confirmation)"))
+		}
+		fmt.Printf("Roll back %s to %s (%s)?\n", value(vmDir), value(b.Name), b.Created.Local().Format(time.DateTime))
+		fmt.Println("The current disk and EFI state will be overwritten.")
+		if !confirmReset() {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	if err := backup.Restore(vmDir, b); err != nil {
+		return jsonOrError(err)
+	}
+
+	if jsonOutput {
+		return emitJSON(backupResult{Status: "restored", Backup: b})
+	}
+	fmt.Printf("%s Rolled back to %s\n", success("✓"), value(b.Name))
+	fmt.Printf("  Start the VM with: %s\n", command(withName("br start", rollbackFlags.name)))
+	return nil
+}
@@ -248,6 +248,8 @@ func runBoot(cmd *cobra.Command, args []string) error {
 	startFlags.timeout = bootFlags.timeout
 	startFlags.imageURL = ""
 	startFlags.imagePath = ""
+	startFlags.imageSHA256 = ""
+	startFlags.distro = ""
 	startFlags.noNested = false
 
 	// Restore-with-memory: if the slot holds saved RAM and the user didn't ask
@@ -221,7 +221,7 @@ func (r cartridgePackReport) cartridgeArg() string {
 func layoutCartridge(cmd *cobra.Command, mountpoint string, m *disk.Manifest, name, rootImg string) error {
 	// Resolve + materialize the bootable root image into the cartridge. We reuse
 	// the exact image cache/convert path boot uses, so packed bytes == booted bytes.
-	tmpCfg, err := config.Default("")
+	tmpCfg, err := config.Default("", "")
 	if err != nil {
 		return fmt.Errorf("prepare image config: %w", err)
 	}
@@ -406,6 +406,8 @@ func runBootCartridge(cmd *cobra.Command, args []string, path string) error {
 	startFlags.timeout = bootFlags.timeout
 	startFlags.imageURL = ""
 	startFlags.imagePath = ""
+	startFlags.imageSHA256 = ""
+	startFlags.distro = ""
 	startFlags.noNested = false
 	startFlags.restoreFrom = ""
 
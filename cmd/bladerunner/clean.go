@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/backup"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/util"
+)
+
+var cleanFlags struct {
+	stateDir string
+	dryRun   bool
+}
+
+// cleanMinAge is how old cruft must be before br clean removes it. A temp
+// file or a half-built slot younger than this may belong to a start, boot or
+// download that is still in flight without a control socket yet.
+const cleanMinAge = time.Hour
+
+// Kinds of artifact br clean removes.
+const (
+	cleanKindTemp      = "temp-file"
+	cleanKindSocket    = "stale-socket"
+	cleanKindPID       = "stale-pid"
+	cleanKindOrphanDir = "orphaned-slot"
+	cleanKindLog       = "orphaned-log"
+)
+
+// orphanLogFiles are the flat layout's per-run logs and reports, cruft once
+// the VM's disk is gone.
+var orphanLogFiles = []string{"console.log", "bladerunner.log", daemonOutName, "startup-report.json", "runtime-metadata.json"}
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove orphaned artifacts from the state directory",
+	Long: `Find and remove cruft that accumulates in the state directory:
+
+  - abandoned temp files (*.tmp downloads and half-written files)
+  - control sockets and PID files left by a VM host that is gone
+  - disk slots with no disk, saved state or backups
+  - logs and reports of a default VM whose disk was removed
+
+Every VM directory is checked for a live host first, and nothing belonging to
+one is touched. Files changed within the last hour are left alone too, since
+they may belong to a start that is still provisioning. Unlike 'br reset', this
+never removes a disk, saved state, backup, key or image.`,
+	Example: renderExamples(
+		example{Comment: "Show what would be removed", Args: "clean --dry-run"},
+		example{Comment: "Reclaim the space", Args: "clean"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runClean,
+}
+
+func init() {
+	cleanCmd.Flags().StringVar(&cleanFlags.stateDir, "state-dir", "", "State directory (default: ~/.local/state/bladerunner)")
+	cleanCmd.Flags().BoolVarP(&cleanFlags.dryRun, "dry-run", "n", false, "List what would be removed without removing it")
+}
+
+// cleanItem is one artifact br clean removes.
+type cleanItem struct {
+	Path  string `json:"path"`
+	Kind  string `json:"kind"`
+	Bytes int64  `json:"bytes"`
+}
+
+// cleanResult is the JSON payload emitted by `br clean --json`.
+type cleanResult struct {
+	StateDir       string      `json:"state_dir"`
+	DryRun         bool        `json:"dry_run"`
+	Items          []cleanItem `json:"items"`
+	ReclaimedBytes int64       `json:"reclaimed_bytes"`
+	Failed         []string    `json:"failed,omitempty"`
+}
+
+func runClean(_ *cobra.Command, _ []string) error {
+	stateDir := cleanFlags.stateDir
+	if stateDir == "" {
+		stateDir = config.DefaultStateDir()
+	}
+	if _, err := os.Stat(stateDir); os.IsNotExist(err) {
+		if jsonOutput {
+			return emitJSON(cleanResult{StateDir: stateDir, DryRun: cleanFlags.dryRun, Items: []cleanItem{}})
+		}
+		fmt.Printf("Nothing to clean: %s does not exist\n", stateDir)
+		return nil
+	}
+
+	items, err := findCleanable(stateDir, time.Now(), vmDirLive)
+	if err != nil {
+		return jsonOrError(fmt.Errorf("scan %s: %w", stateDir, err))
+	}
+
+	res := cleanResult{StateDir: stateDir, DryRun: cleanFlags.dryRun, Items: []cleanItem{}}
+	for _, it := range items {
+		if !cleanFlags.dryRun {
+			if err := os.RemoveAll(it.Path); err != nil {
+				res.Failed = append(res.Failed, fmt.Sprintf("%s: %v", it.Path, err))
+				continue
+			}
+		}
+		res.Items = append(res.Items, it)
+		res.ReclaimedBytes += it.Bytes
+	}
+
+	if jsonOutput {
+		return emitJSON(res)
+	}
+	reportCleanHuman(res)
+	if len(res.Failed) > 0 {
+		return fmt.Errorf("%d artifact(s) could not be removed", len(res.Failed))
+	}
+	return nil
+}
+
+func reportCleanHuman(res cleanResult) {
+	if len(res.Items) == 0 && len(res.Failed) == 0 {
+		fmt.Printf("%s Nothing to clean in %s\n", success("✓"), res.StateDir)
+		return
+	}
+	for _, it := range res.Items {
+		fmt.Printf("  %s %s %s\n", subtle(fmt.Sprintf("%-14s", it.Kind)), it.Path, subtle("("+logging.HumanBytes(it.Bytes)+")"))
+	}
+	for _, f := range res.Failed {
+		fmt.Printf("  %s %s\n", warning("failed"), f)
+	}
+	if res.DryRun {
+		fmt.Printf("\nWould reclaim %s from %d artifact(s); run %s to remove them.\n",
+			value(logging.HumanBytes(res.ReclaimedBytes)), len(res.Items), command("br clean"))
+		return
+	}
+	fmt.Printf("\n%s Reclaimed %s from %d artifact(s)\n", success("✓"), value(logging.HumanBytes(res.ReclaimedBytes)), len(res.Items))
+}
+
+// vmDirLive reports whether a VM host owns vmDir: its control server answers
+// (or exists but is wedged), or its PID file names a live process. Only a
+// socket nobody is listening on counts as dead.
+func vmDirLive(vmDir string) bool {
+	err := control.NewClient(vmDir).Ping(context.Background())
+	if !errors.Is(err, control.ErrNotRunning) {
+		return true
+	}
+	pid := readPIDFile(vmDir)
+	return pid > 0 && syscall.Kill(pid, 0) == nil
+}
+
+// findCleanable lists the orphaned artifacts under stateDir, skipping
+// everything in a VM directory live reports as owned and anything modified
+// within cleanMinAge of now. VM directories are stateDir itself (the flat
+// layout), each named VM's directory, and each disk slot under disks/.
+// Attached cartridges under mnt/ are mounted volumes and never scanned.
+func findCleanable(stateDir string, now time.Time, live func(vmDir string) bool) ([]cleanItem, error) {
+	old := func(fi fs.FileInfo) bool { return now.Sub(fi.ModTime()) >= cleanMinAge }
+
+	vmDirs := []string{stateDir}
+	top, err := os.ReadDir(stateDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range top {
+		// Any subdirectory that could be a VM name is one: the state dir's
+		// own subdirectories are reserved names.
+		if dir, err := config.VMDirFor(stateDir, e.Name()); err == nil && e.IsDir() {
+			vmDirs = append(vmDirs, dir)
+		}
+	}
+	slotsRoot := filepath.Join(stateDir, "disks")
+	entries, err := os.ReadDir(slotsRoot)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			vmDirs = append(vmDirs, filepath.Join(slotsRoot, e.Name()))
+		}
+	}
+
+	var items []cleanItem
+	liveDirs := map[string]bool{}
+	removedDirs := map[string]bool{}
+	// A socket override puts every VM's control socket at one path.
+	seen := map[string]bool{}
+	for _, dir := range vmDirs {
+		if live(dir) {
+			liveDirs[dir] = true
+			continue
+		}
+		if filepath.Dir(dir) == slotsRoot && orphanedSlot(dir, old) {
+			items = append(items, cleanItem{Path: dir, Kind: cleanKindOrphanDir, Bytes: treeSize(dir)})
+			removedDirs[dir] = true
+			continue
+		}
+		for _, stale := range []struct{ path, kind string }{
+			{control.SocketPath(dir), cleanKindSocket},
+			{control.OwnerPIDPath(control.SocketPath(dir)), cleanKindPID},
+			{pidFilePath(dir), cleanKindPID},
+		} {
+			if fi, err := os.Lstat(stale.path); err == nil && !seen[stale.path] {
+				seen[stale.path] = true
+				items = append(items, cleanItem{Path: stale.path, Kind: stale.kind, Bytes: fi.Size()})
+			}
+		}
+		if dir == stateDir && !util.FileExists(filepath.Join(dir, "disk.raw")) {
+			for _, name := range orphanLogFiles {
+				path := filepath.Join(dir, name)
+				if fi, err := os.Lstat(path); err == nil && fi.Mode().IsRegular() && old(fi) {
+					items = append(items, cleanItem{Path: path, Kind: cleanKindLog, Bytes: fi.Size()})
+				}
+			}
+		}
+	}
+
+	err = filepath.WalkDir(stateDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == stateDir {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path == filepath.Join(stateDir, "mnt") || removedDirs[path] || (path != stateDir && liveDirs[path]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !isTempName(d.Name()) {
+			return nil
+		}
+		// Temp files directly in the state dir (or its cache) belong to the
+		// flat layout's VM, so a live default VM keeps them too.
+		if liveDirs[stateDir] && !strings.HasPrefix(path, slotsRoot+string(filepath.Separator)) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil || !old(fi) {
+			return nil
+		}
+		items = append(items, cleanItem{Path: path, Kind: cleanKindTemp, Bytes: fi.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	return items, nil
+}
+
+// orphanedSlot reports whether a stopped disk slot holds nothing worth
+// keeping: no disk, no saved state, no backups, and no recent changes.
+func orphanedSlot(dir string, old func(fs.FileInfo) bool) bool {
+	fi, err := os.Stat(dir)
+	if err != nil || !old(fi) {
+		return false
+	}
+	if util.FileExists(filepath.Join(dir, "disk.raw")) || util.FileExists(savedStatePath(dir)) {
+		return false
+	}
+	backups, err := backup.List(dir)
+	return err == nil && len(backups) == 0
+}
+
+// isTempName matches the temp files this tree writes: "<dest>.tmp" downloads,
+// CreateTemp's "<name>.tmp-*" and ".tmp-*", and export's ".export-*.tmp".
+func isTempName(name string) bool {
+	return strings.HasSuffix(name, ".tmp") || strings.Contains(name, ".tmp-") || strings.HasPrefix(name, ".tmp-")
+}
+
+// treeSize sums the sizes of the regular files under dir.
+func treeSize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if fi, err := d.Info(); err == nil {
+				total += fi.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindCleanable(t *testing.T) {
+	state := t.TempDir()
+	stale := time.Now().Add(-2 * cleanMinAge)
+	write := func(rel string, fresh bool) string {
+		t.Helper()
+		path := filepath.Join(state, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("cruft"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if !fresh {
+			if err := os.Chtimes(path, stale, stale); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return path
+	}
+	mkSlot := func(name string) string {
+		t.Helper()
+		dir := filepath.Join(state, "disks", name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	// Flat layout: no disk, so its old logs are orphaned.
+	want := map[string]string{
+		write("cache/images/abc.raw.tmp", false): cleanKindTemp,
+		write("settings.json.tmp-123", false):    cleanKindTemp,
+		write("console.log", false):              cleanKindLog,
+		write("daemon.out", false):               cleanKindLog,
+		write("control.sock", false):             cleanKindSocket,
+		write("control.pid", false):              cleanKindPID,
+		write("bladerunner.pid", false):          cleanKindPID,
+	}
+	keep := []string{
+		write("cache/images/def.raw.tmp", true), // may be downloading
+		write("cache/images/abc.raw", false),
+		write("client.key", false),
+	}
+
+	// A stopped slot with a disk keeps everything but its temp files.
+	mkSlot("kept")
+	keep = append(keep, write("disks/kept/disk.raw", false), write("disks/kept/console.log", false))
+	want[write("disks/kept/disk.raw.tmp", false)] = cleanKindTemp
+
+	// A live slot is untouched, even its old temp files and socket.
+	mkSlot("live")
+	keep = append(keep, write("disks/live/disk.raw.tmp", false), write("disks/live/control.sock", false))
+
+	// A slot holding only backups is not orphaned.
+	backedUp := mkSlot("backed-up")
+	keep = append(keep, write("disks/backed-up/backups/backup-20260101T000000Z.tar.gz", false))
+	if err := os.Chtimes(backedUp, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	// An old slot with neither disk nor state is removed whole.
+	gone := mkSlot("gone")
+	write("disks/gone/console.log", false)
+	if err := os.Chtimes(gone, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	want[gone] = cleanKindOrphanDir
+
+	// A live named VM is untouched too; a stopped one loses only its stale
+	// socket, never the directory itself.
+	keep = append(keep, write("build/disk.raw.tmp", false), write("build/control.sock", false))
+	keep = append(keep, write("test/config.json", false))
+	want[write("test/control.sock", false)] = cleanKindSocket
+
+	items, err := findCleanable(state, time.Now(), func(dir string) bool {
+		return dir == filepath.Join(state, "disks", "live") || dir == filepath.Join(state, "build")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, it := range items {
+		got[it.Path] = it.Kind
+	}
+	for path, kind := range want {
+		if got[path] != kind {
+			t.Errorf("%s: kind %q, want %q", path, got[path], kind)
+		}
+	}
+	for _, path := range keep {
+		if kind, ok := got[path]; ok {
+			t.Errorf("%s would be removed as %s", path, kind)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("found %d items, want %d: %v", len(got), len(want), items)
+	}
+}
+
+func TestFindCleanableSkipsLiveDefaultVM(t *testing.T) {
+	state := t.TempDir()
+	stale := time.Now().Add(-2 * cleanMinAge)
+	for _, rel := range []string{"console.log", "control.sock", "cache/images/abc.raw.tmp"} {
+		path := filepath.Join(state, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, stale, stale); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := findCleanable(state, time.Now(), func(dir string) bool { return dir == state })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Errorf("live default VM: found %v, want nothing", items)
+	}
+}
@@ -16,8 +16,9 @@ const maxDisplayValueLen = 60
 
 // configGetResult is the JSON shape for `br config get <key> --json`.
 type configGetResult struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"` // with --source
 }
 
 // configSetResult is the JSON shape for `br config set <key> <value> --json`.
@@ -34,6 +35,16 @@ type configKeyInfo struct {
 	RequiresVM    bool   `json:"requires_vm"`
 	RequiresReset bool   `json:"requires_reset"`
 	Writable      bool   `json:"writable"`
+	// Value is the running VM's value, else the saved or default one; empty
+	// for runtime-only keys while the VM is stopped.
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"` // with --sources
+}
+
+var configFlags struct {
+	source  bool
+	sources bool
+	name    string
 }
 
 var configCmd = &cobra.Command{
@@ -43,22 +54,16 @@ var configCmd = &cobra.Command{
 
 Most config values can be read without a running VM. Values that are
 only available at runtime (e.g. pid, ssh-config-path) require the VM
-to be started.
-
-Examples:
-  # List all config keys with their defaults and status
-  runner config keys
-
-  # Get a specific config value
-  runner config get base-image-url
-
-  # Set a config value (only certain keys are modifiable)
-  runner config set base-image-url https://cloud-images.ubuntu.com/releases/noble/release/ubuntu-24.04-server-cloudimg-arm64.img
+to be started.`,
+	Example: configExamples(),
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    runConfig,
+}
 
-  # List all available config keys
-  runner config keys`,
-	Args: cobra.MinimumNArgs(1),
-	RunE: runConfig,
+func init() {
+	configCmd.Flags().BoolVar(&configFlags.source, "source", false, "With get: also print where the value came from (default, env, settings, saved, manifest, flag, runtime)")
+	configCmd.Flags().BoolVar(&configFlags.sources, "sources", false, "With keys: show where each value came from")
+	configCmd.Flags().StringVar(&configFlags.name, "name", "", nameFlagUsage)
 }
 
 func runConfig(_ *cobra.Command, args []string) error {
@@ -89,6 +94,10 @@ func defaultConfigValue(cfg *config.Config, k string) string {
 		return strconv.FormatUint(uint64(cfg.CPUs), 10)
 	case control.ConfigKeyDiskSizeGiB:
 		return strconv.Itoa(cfg.DiskSizeGiB)
+	case control.ConfigKeyDiskBackingMode:
+		return cfg.DiskBackingMode
+	case control.ConfigKeyDistro:
+		return cfg.Distro
 	case control.ConfigKeyGUI:
 		return strconv.FormatBool(cfg.GUI)
 	case control.ConfigKeyHostname:
@@ -136,19 +145,21 @@ func runConfigGet(args []string) error {
 		return err
 	}
 
-	stateDir := config.DefaultStateDir()
-	client := control.NewClient(stateDir)
+	vmDir, err := namedVMDir(configFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	client := control.NewClient(vmDir)
 	vmRunning := client.IsRunning()
 
 	// If VM is running, prefer live values
 	if vmRunning {
 		configValue, err := client.GetConfig(configKey)
 		if err == nil {
-			if jsonOutput {
-				return emitJSON(configGetResult{Key: configKey, Value: configValue})
-			}
-			fmt.Println(configValue)
-			return nil
+			return printConfigGet(configKey, configValue, func() string {
+				src, _ := client.GetConfigSource(configKey)
+				return src
+			})
 		}
 	}
 
@@ -161,8 +172,8 @@ func runConfigGet(args []string) error {
 		return err
 	}
 
-	// Fall back to default config
-	cfg, err := config.Default("")
+	// Fall back to the defaults plus any saved `br config set` values
+	cfg, err := savedConfigDefaults(configFlags.name)
 	if err != nil {
 		err = fmt.Errorf("load defaults: %w", err)
 		if jsonOutput {
@@ -172,10 +183,25 @@ func runConfigGet(args []string) error {
 	}
 
 	configValue := defaultConfigValue(cfg, configKey)
+	return printConfigGet(configKey, configValue, func() string {
+		return string(cfg.SourceOf(configKey))
+	})
+}
+
+// printConfigGet prints a `config get` result; source is only consulted with
+// --source.
+func printConfigGet(configKey, configValue string, source func() string) error {
+	res := configGetResult{Key: configKey, Value: configValue}
+	if configFlags.source {
+		res.Source = source()
+	}
 	if jsonOutput {
-		return emitJSON(configGetResult{Key: configKey, Value: configValue})
+		return emitJSON(res)
 	}
 	fmt.Println(configValue)
+	if res.Source != "" {
+		fmt.Printf("%s %s\n", subtle("from:"), value(res.Source))
+	}
 	return nil
 }
 
@@ -208,30 +234,58 @@ func runConfigSet(args []string) error {
 		return err
 	}
 
-	stateDir := config.DefaultStateDir()
-	client := control.NewClient(stateDir)
+	vmDir, err := namedVMDir(configFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	client := control.NewClient(vmDir)
 
-	if !client.IsRunning() {
-		err := fmt.Errorf("VM is not running; start it first with: %s", command("br start"))
+	// A stopped VM has no server to ask; keys that persist are written straight
+	// to its saved config for the next start.
+	running := client.IsRunning()
+	switch {
+	case running:
+		err = client.SetConfig(configKey, configValue)
+	case config.IsSavedKey(configKey):
+		err = setSavedConfig(configFlags.name, configKey, configValue)
+	default:
+		err = fmt.Errorf("VM is not running; start it first with: %s", command("br start"))
+	}
+	if err != nil {
 		if jsonOutput {
 			emitJSONError(err)
 		}
 		return err
 	}
 
-	if err := client.SetConfig(configKey, configValue); err != nil {
-		if jsonOutput {
-			emitJSONError(err)
+	// The server clamps the balloon target to the boot size range; report
+	// what it applied rather than what was asked for.
+	applied := configValue
+	if running && configKey == control.ConfigKeyMemoryBalloonGiB {
+		if v, err := client.GetConfig(configKey); err == nil && v != "" {
+			applied = v
 		}
-		return err
 	}
 
 	if jsonOutput {
-		return emitJSON(configSetResult{Key: configKey, Value: configValue, Status: "ok"})
+		status := "ok"
+		if !running {
+			status = "saved"
+		}
+		return emitJSON(configSetResult{Key: configKey, Value: applied, Status: status})
 	}
 
-	fmt.Printf("%s Set %s to %s\n", success("✓"), key(configKey), value(configValue))
+	fmt.Printf("%s Set %s to %s\n", success("✓"), key(configKey), value(applied))
+	if applied != configValue {
+		fmt.Println(subtle(fmt.Sprintf("Clamped from %s to between %d GiB and the boot size.", configValue, config.MinMemoryGiB)))
+	}
+	if !running {
+		fmt.Println(subtle("Saved; the next 'br start' uses it."))
+	}
 
+	if running && configKey == control.ConfigKeyMemoryGiB {
+		fmt.Println(subtle("The running VM keeps its memory; resize it live with " + control.ConfigKeyMemoryBalloonGiB + "."))
+	}
 	if meta.RequiresReset {
 		fmt.Printf("\n%s This change requires a VM reset to take effect.\n", errorf("⚠"))
 		fmt.Printf("  Run %s and then %s\n", command("br reset"), command("br start"))
@@ -240,32 +294,65 @@ func runConfigSet(args []string) error {
 	return nil
 }
 
+// savedConfigDefaults returns the defaults for the VM called name with its
+// saved `br config set` values applied — what it reports for those keys while
+// stopped.
+func savedConfigDefaults(name string) (*config.Config, error) {
+	cfg, err := config.Default("", name)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := config.Load(config.ConfigPath(cfg.VMDir))
+	if err != nil {
+		return nil, err
+	}
+	cfg.ApplySaved(saved)
+	return cfg, nil
+}
+
+// setSavedConfig applies a `br config set` to the saved config of the stopped
+// VM called name, through the same setters and validation the control server
+// uses.
+func setSavedConfig(name, configKey, configValue string) error {
+	cfg, err := savedConfigDefaults(name)
+	if err != nil {
+		return err
+	}
+	cr := control.NewConfigRouter(cfg)
+	cr.SaveTo(config.ConfigPath(cfg.VMDir))
+	return cr.Set(configKey, configValue)
+}
+
 func runConfigKeys() error {
 	registry := control.ConfigKeyRegistry()
 
+	cfg, err := savedConfigDefaults(configFlags.name)
+	if err != nil {
+		return jsonOrError(fmt.Errorf("load defaults: %w", err))
+	}
+
+	client := control.NewClient(cfg.VMDir)
+	vmRunning := client.IsRunning()
+
 	if jsonOutput {
 		keys := make([]configKeyInfo, 0, len(registry))
 		for _, meta := range registry {
-			keys = append(keys, configKeyInfo{
+			info := configKeyInfo{
 				Key:           meta.Key,
 				Description:   meta.Description,
 				RequiresVM:    meta.RequiresVM,
 				RequiresReset: meta.RequiresReset,
 				Writable:      meta.Writable,
-			})
+				Value:         resolveDisplayValue(meta, cfg, client, vmRunning),
+			}
+			if configFlags.sources {
+				info.Source = resolveSource(meta, cfg, client, vmRunning)
+			}
+			keys = append(keys, info)
 		}
 		return emitJSON(keys)
 	}
 
-	cfg, err := config.Default("")
-	if err != nil {
-		return fmt.Errorf("load defaults: %w", err)
-	}
-
-	stateDir := config.DefaultStateDir()
-	client := control.NewClient(stateDir)
-	vmRunning := client.IsRunning()
-
 	fmt.Println(title("Configuration Keys"))
 	fmt.Println()
 
@@ -281,8 +368,13 @@ func runConfigKeys() error {
 			displayValue = displayValue[:maxDisplayValueLen-3] + "..."
 		}
 
+		source := ""
+		if configFlags.sources && displayValue != "" {
+			source = resolveSource(meta, cfg, client, vmRunning)
+		}
+
 		tags := buildTags(meta)
-		printKeyLine(meta.Key, displayValue, annotation)
+		printKeyLine(meta.Key, displayValue, source, annotation)
 		printDescLine(meta.Description, tags)
 	}
 	fmt.Println()
@@ -304,6 +396,20 @@ func resolveDisplayValue(meta control.ConfigKeyMeta, cfg *config.Config, client
 	return defaultConfigValue(cfg, meta.Key)
 }
 
+// resolveSource reports where a key's value came from: the running VM's record
+// when it is up, else the local defaults' (default or env).
+func resolveSource(meta control.ConfigKeyMeta, cfg *config.Config, client *control.Client, vmRunning bool) string {
+	if vmRunning {
+		if src, err := client.GetConfigSource(meta.Key); err == nil {
+			return src
+		}
+	}
+	if meta.RequiresVM {
+		return ""
+	}
+	return string(cfg.SourceOf(meta.Key))
+}
+
 func buildTags(meta control.ConfigKeyMeta) []string {
 	var tags []string
 	if meta.RequiresVM {
@@ -318,8 +424,10 @@ func buildTags(meta control.ConfigKeyMeta) []string {
 	return tags
 }
 
-func printKeyLine(k, displayValue, annotation string) {
+func printKeyLine(k, displayValue, source, annotation string) {
 	switch {
+	case displayValue != "" && source != "":
+		fmt.Printf("  %s %s %s  %s\n", key(k), subtle("="), value(displayValue), subtle("from: "+source))
 	case displayValue != "":
 		fmt.Printf("  %s %s %s\n", key(k), subtle("="), value(displayValue))
 	case annotation != "":
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+)
+
+const (
+	// daemonEnvVar marks the re-executed `br start` as the background VM host,
+	// so it runs in the foreground of its own session instead of forking again.
+	daemonEnvVar = "BLADERUNNER_DAEMON"
+
+	// pidFileName holds the VM host's PID while it runs, under the VM dir.
+	pidFileName = "bladerunner.pid"
+
+	// daemonOutName takes the background VM host's stdout and stderr, under
+	// the VM dir: output that is not a log record, such as a panic. It is
+	// kept apart from the log, which the host rotates itself.
+	daemonOutName = "daemon.out"
+
+	// daemonReadyTimeout bounds how long `br start --daemon` waits for the
+	// background process to bring up its control socket.
+	daemonReadyTimeout = 30 * time.Second
+)
+
+// daemonResult is the JSON payload for `br start --daemon`.
+type daemonResult struct {
+	Status string `json:"status"` // "starting"
+	PID    int    `json:"pid"`
+	Log    string `json:"log"`
+}
+
+func pidFilePath(vmDir string) string {
+	return filepath.Join(vmDir, pidFileName)
+}
+
+func daemonOutPath(vmDir string) string {
+	return filepath.Join(vmDir, daemonOutName)
+}
+
+// writePIDFile records this process as the VM host and returns a func that
+// removes the file again.
+func writePIDFile(vmDir string) (func(), error) {
+	path := pidFilePath(vmDir)
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return func() {}, fmt.Errorf("write pid file: %w", err)
+	}
+	return func() { _ = os.Remove(path) }, nil
+}
+
+// readPIDFile returns the PID recorded by a running VM host, or 0.
+func readPIDFile(vmDir string) int {
+	b, err := os.ReadFile(pidFilePath(vmDir))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	return pid
+}
+
+// isDaemonChild reports whether this process is the re-executed background
+// VM host.
+func isDaemonChild() bool {
+	return os.Getenv(daemonEnvVar) != ""
+}
+
+// withoutDaemonFlag drops --daemon from args so the re-executed start runs
+// the VM instead of forking again.
+func withoutDaemonFlag(args []string) []string {
+	out := make([]string, 0, len(args))
+	for _, a := range args {
+		if a == "--daemon" || strings.HasPrefix(a, "--daemon=") {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// runStartDaemon re-executes this `br start` in a new session with its std
+// streams in daemonOutName, and returns once the background process is
+// serving its control socket. From there `br status`, `br stop` and the rest
+// talk to it exactly as they would to a foreground start. The child writes
+// its log records to the log alone (see logging.SetQuiet in runStart).
+func runStartDaemon(cfg *config.Config) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate br executable: %w", err)
+	}
+	if err := os.MkdirAll(cfg.VMDir, 0o755); err != nil {
+		return fmt.Errorf("create vm dir: %w", err)
+	}
+	outPath := daemonOutPath(cfg.VMDir)
+	outFile, err := os.OpenFile(outPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", daemonOutName, err)
+	}
+	defer func() { _ = outFile.Close() }()
+	devnull, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", os.DevNull, err)
+	}
+	defer func() { _ = devnull.Close() }()
+
+	// context.Background(): the child is the long-lived VM host and must
+	// outlive this command.
+	cmd := exec.CommandContext(context.Background(), exe, withoutDaemonFlag(os.Args[1:])...)
+	cmd.Env = append(os.Environ(), daemonEnvVar+"=1")
+	cmd.Stdin = devnull
+	cmd.Stdout = outFile
+	cmd.Stderr = outFile
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start background VM host: %w", err)
+	}
+	pid := cmd.Process.Pid
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	if err := waitForDaemon(control.NewClient(cfg.VMDir), exited, daemonReadyTimeout); err != nil {
+		return fmt.Errorf("%w; see %s and %s", err, cfg.LogPath, outPath)
+	}
+
+	if jsonOutput {
+		return emitJSON(daemonResult{Status: "starting", PID: pid, Log: cfg.LogPath})
+	}
+	fmt.Printf("%s VM starting in the background (pid %d)\n", success("✓"), pid)
+	fmt.Printf("  %s %s\n", key("Status:"), command("br status"))
+	fmt.Printf("  %s %s\n", key("Stop:"), command("br stop"))
+	fmt.Printf("  %s %s\n", key("Log:"), value(cfg.LogPath))
+	return nil
+}
+
+// waitForDaemon polls until the background process answers on its control
+// socket, failing early if it exits first.
+func waitForDaemon(client *control.Client, exited <-chan error, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	tick := time.NewTicker(250 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exited")
+			}
+			return fmt.Errorf("background VM host stopped before it was ready: %w", err)
+		case <-deadline:
+			return fmt.Errorf("background VM host did not come up within %s", timeout)
+		case <-tick.C:
+			if client.IsRunning() {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWithoutDaemonFlag(t *testing.T) {
+	got := withoutDaemonFlag([]string{"start", "--daemon", "--cpus", "4", "--daemon=true", "--gui"})
+	want := []string{"start", "--cpus", "4", "--gui"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withoutDaemonFlag = %q, want %q", got, want)
+	}
+}
+
+func TestPIDFile(t *testing.T) {
+	dir := t.TempDir()
+	if pid := readPIDFile(dir); pid != 0 {
+		t.Fatalf("missing pid file: got %d", pid)
+	}
+
+	remove, err := writePIDFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid := readPIDFile(dir); pid != os.Getpid() {
+		t.Errorf("readPIDFile = %d, want %d", pid, os.Getpid())
+	}
+	remove()
+	if _, err := os.Stat(pidFilePath(dir)); !os.IsNotExist(err) {
+		t.Errorf("pid file not removed: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, pidFileName), []byte("garbage\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if pid := readPIDFile(dir); pid != 0 {
+		t.Errorf("garbage pid file: got %d", pid)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/incus"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+// doctorDialTimeout bounds each forwarder dial.
+const doctorDialTimeout = 2 * time.Second
+
+// Doctor check outcomes.
+const (
+	doctorPass = "pass"
+	doctorFail = "fail"
+	doctorSkip = "skip"
+)
+
+var doctorFlags struct {
+	name string
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the host and the running VM end to end",
+	Long: `Run every check a healthy setup needs and print a pass/fail checklist:
+hardware virtualization, the qemu-img and hdiutil tools, the base image, and,
+while the VM is running, its port forwards and the Incus API.
+
+Exits non-zero when a critical check fails. A missing or qcow2 base image is
+reported but not critical, since 'br start' downloads and converts it.`,
+	Example: renderExamples(
+		example{Comment: "Diagnose a start that fails or an Incus that never comes up", Args: "doctor"},
+		example{Comment: "Machine-readable checklist", Args: "doctor --json"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().StringVar(&doctorFlags.name, "name", "", nameFlagUsage)
+}
+
+// doctorCheck is one checklist item, also the element of `br doctor --json`.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Critical marks checks whose failure makes br doctor exit non-zero.
+	Critical bool   `json:"critical"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// doctorReport is the JSON payload emitted by `br doctor --json`.
+type doctorReport struct {
+	OK     bool          `json:"ok"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	checks := []doctorCheck{
+		checkVirtualization(),
+		checkTool("qemu-img", vm.RequireQemuImg),
+		checkTool("hdiutil", func() error {
+			_, err := exec.LookPath("hdiutil")
+			return err
+		}),
+	}
+
+	vmDir, err := namedVMDir(doctorFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	client := control.NewClient(vmDir)
+	running := client.IsRunning()
+	checks = append(checks, checkBaseImage(doctorBaseImagePath(client, running, doctorFlags.name)))
+	if running {
+		checks = append(checks, checkForwards(client)...)
+		checks = append(checks, checkIncusAPI(client, doctorFlags.name))
+	} else {
+		checks = append(checks,
+			doctorCheck{Name: "forwards", Status: doctorSkip, Detail: "VM is not running"},
+			doctorCheck{Name: "incus api", Status: doctorSkip, Detail: "VM is not running"},
+		)
+	}
+
+	failed := criticalFailures(checks)
+	if jsonOutput {
+		if err := emitJSON(doctorReport{OK: failed == 0, Checks: checks}); err != nil {
+			return err
+		}
+	} else {
+		printDoctorChecks(checks, failed)
+	}
+	if failed > 0 {
+		// The checklist already says what failed; only the exit status is left.
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+func checkVirtualization() doctorCheck {
+	c := doctorCheck{Name: "virtualization", Critical: true}
+	if vm.VirtualizationSupported() {
+		c.Status = doctorPass
+		c.Detail = "Virtualization framework available"
+		if vm.NestedVirtualizationSupported() {
+			c.Detail += " (nested VMs supported)"
+		}
+		return c
+	}
+	c.Status = doctorFail
+	c.Detail = "hardware virtualization is not available on this host"
+	return c
+}
+
+// checkTool reports whether a host tool the VM needs is installed.
+func checkTool(name string, lookup func() error) doctorCheck {
+	c := doctorCheck{Name: name, Critical: true, Status: doctorPass, Detail: "found on PATH"}
+	if err := lookup(); err != nil {
+		c.Status = doctorFail
+		c.Detail = err.Error()
+	}
+	return c
+}
+
+// doctorBaseImagePath returns the running VM's resolved base image, or where
+// the next start of the VM called name would look for it.
+func doctorBaseImagePath(client *control.Client, running bool, name string) string {
+	if running {
+		if path, err := client.GetConfig(control.ConfigKeyBaseImagePath); err == nil && path != "" {
+			return path
+		}
+	}
+	cfg, err := savedConfigDefaults(name)
+	if err != nil {
+		return ""
+	}
+	return vm.LocalBaseImagePath(cfg)
+}
+
+// checkBaseImage confirms the base image exists and is raw, the only format
+// the Virtualization framework boots. Neither failure is critical: a start
+// downloads a missing image and converts a qcow2 one.
+func checkBaseImage(path string) doctorCheck {
+	c := doctorCheck{Name: "base image", Status: doctorFail}
+	if path == "" {
+		c.Detail = "could not resolve the base image path"
+		return c
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		c.Detail = "not downloaded yet; 'br start' fetches it (" + path + ")"
+		return c
+	}
+	qcow2, err := vm.IsQcow2(path)
+	switch {
+	case err != nil:
+		c.Detail = err.Error()
+	case qcow2:
+		c.Detail = "qcow2, not raw; 'br start' converts it (" + path + ")"
+	default:
+		c.Status = doctorPass
+		c.Detail = fmt.Sprintf("raw, %s (%s)", logging.HumanBytes(info.Size()), path)
+	}
+	return c
+}
+
+// checkForwards dials every host listener of the running VM.
+func checkForwards(client *control.Client) []doctorCheck {
+	forwards, err := client.ListForwards()
+	if err != nil {
+		return []doctorCheck{{Name: "forwards", Status: doctorFail, Critical: true, Detail: err.Error()}}
+	}
+	if len(forwards) == 0 {
+		return []doctorCheck{{Name: "forwards", Status: doctorFail, Critical: true, Detail: "VM reports no port forwards"}}
+	}
+	checks := make([]doctorCheck, 0, len(forwards))
+	for _, f := range forwards {
+		checks = append(checks, checkForward(f))
+	}
+	return checks
+}
+
+func checkForward(f control.ForwardInfo) doctorCheck {
+	c := doctorCheck{Name: "forward " + f.Name, Critical: true}
+	if f.Paused {
+		c.Status = doctorSkip
+		c.Detail = "paused (resume with 'br forward resume " + f.Name + "')"
+		return c
+	}
+	conn, err := net.DialTimeout("tcp", f.Listen, doctorDialTimeout)
+	if err != nil {
+		c.Status = doctorFail
+		c.Detail = err.Error()
+		return c
+	}
+	_ = conn.Close()
+	c.Status = doctorPass
+	c.Detail = f.Listen
+	return c
+}
+
+// checkIncusAPI fetches the Incus server info through the API forward, which
+// also proves the client certificate of the VM called name is trusted.
+func checkIncusAPI(client *control.Client, name string) doctorCheck {
+	c := doctorCheck{Name: "incus api", Critical: true, Status: doctorFail}
+	info, err := probeIncus(client, name)
+	if err != nil {
+		c.Detail = err.Error()
+		return c
+	}
+	c.Status = doctorPass
+	c.Detail = "Incus " + info.ServerVersion
+	return c
+}
+
+func probeIncus(client *control.Client, name string) (*incus.ServerInfo, error) {
+	port, err := client.GetConfig(control.ConfigKeyLocalAPIPort)
+	if err != nil {
+		return nil, err
+	}
+	if port == "" {
+		return nil, errors.New("local-api-port not configured")
+	}
+	cfg, err := config.Default("", name)
+	if err != nil {
+		return nil, fmt.Errorf("load defaults: %w", err)
+	}
+	certPEM, err := os.ReadFile(cfg.ClientCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("read client certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(cfg.ClientKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read client key: %w", err)
+	}
+	return incus.ProbeServer("https://127.0.0.1:"+port, certPEM, keyPEM)
+}
+
+// criticalFailures counts the failed checks that make br doctor exit
+// non-zero.
+func criticalFailures(checks []doctorCheck) int {
+	n := 0
+	for _, c := range checks {
+		if c.Critical && c.Status == doctorFail {
+			n++
+		}
+	}
+	return n
+}
+
+func printDoctorChecks(checks []doctorCheck, failed int) {
+	fmt.Println(title("Bladerunner Doctor"))
+	for _, c := range checks {
+		var mark string
+		switch {
+		case c.Status == doctorPass:
+			mark = success("✓")
+		case c.Status == doctorSkip:
+			mark = subtle("-")
+		case c.Critical:
+			mark = errorf("✗")
+		default:
+			mark = warning("!")
+		}
+		fmt.Printf("  %s %-16s %s\n", mark, c.Name, subtle(c.Detail))
+	}
+	fmt.Println()
+	if failed > 0 {
+		fmt.Println(errorf(fmt.Sprintf("%d critical check(s) failed", failed)))
+		return
+	}
+	fmt.Println(success("All critical checks passed"))
+}
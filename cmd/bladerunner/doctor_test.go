@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/control"
+)
+
+func TestCheckBaseImage(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "base-image.raw")
+	qcow2 := filepath.Join(dir, "base-image.qcow2")
+	if err := os.WriteFile(raw, make([]byte, 4096), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(qcow2, []byte("QFI\xfb\x00\x00\x00\x03"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path, status, detail string
+	}{
+		{raw, doctorPass, "raw"},
+		{qcow2, doctorFail, "qcow2"},
+		{filepath.Join(dir, "missing.raw"), doctorFail, "not downloaded"},
+	}
+	for _, tt := range tests {
+		c := checkBaseImage(tt.path)
+		if c.Status != tt.status || !strings.Contains(c.Detail, tt.detail) {
+			t.Errorf("checkBaseImage(%s) = %+v, want status %s and %q in the detail", filepath.Base(tt.path), c, tt.status, tt.detail)
+		}
+		if c.Critical {
+			t.Errorf("checkBaseImage(%s) is critical; a start repairs it", filepath.Base(tt.path))
+		}
+	}
+}
+
+func TestCheckForward(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := ln.Addr().String()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	_ = closed.Close()
+	defer func() { _ = ln.Close() }()
+
+	if c := checkForward(control.ForwardInfo{Name: "ssh", Listen: open}); c.Status != doctorPass {
+		t.Errorf("listening forward = %+v, want pass", c)
+	}
+	if c := checkForward(control.ForwardInfo{Name: "ssh", Listen: closedAddr}); c.Status != doctorFail || !c.Critical {
+		t.Errorf("closed forward = %+v, want a critical fail", c)
+	}
+	if c := checkForward(control.ForwardInfo{Name: "ssh", Listen: closedAddr, Paused: true}); c.Status != doctorSkip {
+		t.Errorf("paused forward = %+v, want skip", c)
+	}
+}
+
+func TestCriticalFailures(t *testing.T) {
+	checks := []doctorCheck{
+		{Name: "a", Status: doctorPass, Critical: true},
+		{Name: "b", Status: doctorFail, Critical: true},
+		{Name: "c", Status: doctorFail},
+		{Name: "d", Status: doctorSkip, Critical: true},
+	}
+	if got := criticalFailures(checks); got != 1 {
+		t.Errorf("criticalFailures = %d, want 1", got)
+	}
+}
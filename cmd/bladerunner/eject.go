@@ -10,6 +10,7 @@ import (
 	"github.com/stuffbucket/bladerunner/internal/cartridge"
 	"github.com/stuffbucket/bladerunner/internal/config"
 	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/disk"
 )
 
 var ejectFlags struct {
@@ -95,6 +96,11 @@ const ejectWaitMargin = 15 * time.Second
 // than one requires a name.
 func resolveEjectSlot(name string) (baseDir, slotName string, err error) {
 	if name != "" {
+		// Slot names are disk names; anything else could walk out of the
+		// state dir.
+		if name != "default" && !disk.ValidName(name) {
+			return "", "", fmt.Errorf("invalid slot name %q: must be lowercase letters, digits, and dashes (start alphanumeric)", name)
+		}
 		return ejectSlotDirForName(name), name, nil
 	}
 
@@ -15,6 +15,7 @@ import (
 
 var eventsFlags struct {
 	types []string
+	name  string
 }
 
 var eventsCmd = &cobra.Command{
@@ -35,6 +36,7 @@ var validEventTypes = map[string]struct{}{
 
 func init() {
 	eventsCmd.Flags().StringSliceVar(&eventsFlags.types, "type", nil, "Event type filter (repeatable): lifecycle, operation, logging, network-acl")
+	eventsCmd.Flags().StringVar(&eventsFlags.name, "name", "", nameFlagUsage)
 }
 
 func runEvents(_ *cobra.Command, _ []string) error {
@@ -48,7 +50,7 @@ func runEvents(_ *cobra.Command, _ []string) error {
 		}
 	}
 
-	client, err := connectIncus()
+	client, err := connectIncus(eventsFlags.name)
 	if err != nil {
 		return err
 	}
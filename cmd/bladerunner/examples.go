@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/stuffbucket/bladerunner/internal/control"
+)
+
+// example is one entry in a command's --help "Examples:" section. Args is the
+// command line without the leading binary name.
+type example struct {
+	Comment string
+	Args    string
+}
+
+// renderExamples formats examples for cobra's Example field: each one is a
+// comment line followed by the command, indented as cobra expects.
+func renderExamples(exs ...example) string {
+	blocks := make([]string, 0, len(exs))
+	for _, ex := range exs {
+		var b strings.Builder
+		if ex.Comment != "" {
+			b.WriteString("  # " + ex.Comment + "\n")
+		}
+		b.WriteString("  br " + ex.Args)
+		blocks = append(blocks, b.String())
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// configExamples builds `br config --help` examples, listing one `config set`
+// per writable key in the registry so the help never names a key that can't
+// be set, nor misses one that can.
+func configExamples() string {
+	exs := []example{
+		{Comment: "List all config keys with their defaults and status", Args: "config keys"},
+		{Comment: "Get a specific config value", Args: "config get " + control.ConfigKeyBaseImageURL},
+		{Comment: "Why this value? Show the layer it came from (default, env, settings, saved, manifest, flag, runtime)", Args: "config get " + control.ConfigKeyCPUs + " --source"},
+	}
+	for _, meta := range control.ConfigKeyRegistry() {
+		if !meta.Writable {
+			continue
+		}
+		comment := "Set: " + meta.Description
+		if meta.RequiresReset {
+			comment += " (takes effect after 'br reset')"
+		}
+		exs = append(exs, example{Comment: comment, Args: "config set " + meta.Key + " " + quoteExampleArg(meta.Example)})
+	}
+	return renderExamples(exs...)
+}
+
+// quoteExampleArg single-quotes a value for copy-pasting into a shell when it
+// holds anything the shell would split or expand.
+func quoteExampleArg(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t'\"\\$`&;|<>()*?!#~") {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
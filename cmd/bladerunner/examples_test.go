@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/control"
+)
+
+func TestConfigExamplesFollowRegistry(t *testing.T) {
+	got := configExamples()
+	for _, meta := range control.ConfigKeyRegistry() {
+		line := "br config set " + meta.Key + " "
+		if meta.Writable != strings.Contains(got, line) {
+			t.Errorf("key %q (writable=%v): example presence mismatch in:\n%s", meta.Key, meta.Writable, got)
+		}
+	}
+	if configCmd.Example != got {
+		t.Error("config --help should use the registry-driven examples")
+	}
+}
+
+func TestRenderExamples(t *testing.T) {
+	got := renderExamples(
+		example{Comment: "First", Args: "status"},
+		example{Args: "stop --force"},
+	)
+	want := "  # First\n  br status\n\n  br stop --force"
+	if got != want {
+		t.Errorf("renderExamples =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestQuoteExampleArg(t *testing.T) {
+	for in, want := range map[string]string{
+		"4":                     "4",
+		"https://x.test/a.img":  "https://x.test/a.img",
+		"https://x.test/?a=1&b": "'https://x.test/?a=1&b'",
+		"it's":                  `'it'\''s'`,
+		"":                      "''",
+	} {
+		if got := quoteExampleArg(in); got != want {
+			t.Errorf("quoteExampleArg(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
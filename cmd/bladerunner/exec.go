@@ -14,16 +14,18 @@ import (
 var execFlags struct {
 	stdin bool
 	tty   bool
+	name  string
 }
 
 var execCmd = &cobra.Command{
 	Use:   "exec <instance> -- <cmd>...",
 	Short: "Execute a command inside an Incus instance",
 	Long: `Execute a command inside the named Incus instance. Use -- to separate
-br flags from the command. Examples:
-
-  runner exec mybox -- ls /
-  runner exec -i -t mybox -- /bin/bash`,
+br flags from the command.`,
+	Example: renderExamples(
+		example{Comment: "Run a one-off command", Args: "exec mybox -- ls /"},
+		example{Comment: "Open an interactive shell", Args: "exec -i -t mybox -- /bin/bash"},
+	),
 	Args:              cobra.MinimumNArgs(2),
 	RunE:              runExec,
 	ValidArgsFunction: instanceNameCompletion,
@@ -32,6 +34,7 @@ br flags from the command. Examples:
 func init() {
 	execCmd.Flags().BoolVarP(&execFlags.stdin, "stdin", "i", false, "Forward stdin to the remote process")
 	execCmd.Flags().BoolVarP(&execFlags.tty, "tty", "t", false, "Allocate a pseudo-TTY (interactive)")
+	execCmd.Flags().StringVar(&execFlags.name, "name", "", nameFlagUsage)
 }
 
 // exitError carries the remote exit code so the root command can set the process status.
@@ -55,7 +58,7 @@ func runExec(cmdCobra *cobra.Command, args []string) error {
 	cmdCobra.SilenceErrors = true
 	cmdCobra.SilenceUsage = true
 
-	client, err := connectIncus()
+	client, err := connectIncus(execFlags.name)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Error:", err)
 		return &exitError{code: 1}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/portable"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [name] <file.tar.zst>",
+	Short: "Archive a stopped VM so it can be imported on another machine",
+	Long: `Write disk.raw, efi-vars.bin, machine-id.bin, runtime-metadata.json, and the
+effective config into a zstd-compressed tar, with a manifest of checksums. The
+sparse disk compresses down to roughly the data the guest has written.
+
+Without a name the default VM is exported; give the name of one started with
+'br start --name' to export that instead. Copy the file to another machine and
+run 'br import' there. The VM must be stopped.`,
+	Example: renderExamples(
+		example{Comment: "Archive the VM for another machine", Args: "export ~/vm.tar.zst"},
+		example{Comment: "Archive the VM named build", Args: "export build ~/build.tar.zst"},
+	),
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runExport,
+}
+
+var importFlags struct {
+	name string
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file.tar.zst>",
+	Short: "Recreate a VM from an archive made by 'br export'",
+	Long: `Verify an archive made by 'br export' and unpack it into this machine's VM
+directory, or that of --name. The disk, EFI variables, and machine-id are
+restored and the MAC address is regenerated. The SSH and Incus API ports are
+checked on this machine: one that is taken here is moved to the next free
+port and saved, so the next 'br start' binds it.
+
+Refuses if a VM disk already exists there; remove it first with 'br reset'.`,
+	Example: renderExamples(
+		example{Comment: "Unpack an exported VM, then boot it", Args: "import ~/vm.tar.zst && br start"},
+		example{Comment: "Unpack it as a second, named VM", Args: "import ~/vm.tar.zst --name build && br start --name build"},
+	),
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFlags.name, "name", "", nameFlagUsage)
+}
+
+// portableResult is the JSON payload for `br export` and `br import`.
+type portableResult struct {
+	Status string               `json:"status"` // "exported" | "imported"
+	File   string               `json:"file"`
+	Size   int64                `json:"size,omitempty"`
+	Files  []portable.FileEntry `json:"files"`
+	// Ports lists the ports import moved off ones taken on this machine.
+	Ports []config.PortMove `json:"ports,omitempty"`
+}
+
+func runExport(_ *cobra.Command, args []string) error {
+	name, file := "", args[0]
+	if len(args) == 2 {
+		name, file = args[0], args[1]
+	}
+	vmDir, err := backupVMDir(name, "exporting")
+	if err != nil {
+		return jsonOrError(err)
+	}
+	dest, err := filepath.Abs(file)
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	if !jsonOutput {
+		fmt.Printf("Exporting %s to %s ...\n", value(vmDir), value(dest))
+	}
+	// Write next to the destination and rename so an interrupted export
+	// never leaves a truncated archive under the requested name.
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".export-*.tmp")
+	if err != nil {
+		return jsonOrError(err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	m, err := portable.Export(vmDir, tmp, time.Now())
+	if err != nil {
+		_ = tmp.Close()
+		return jsonOrError(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return jsonOrError(err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return jsonOrError(err)
+	}
+	var size int64
+	if fi, err := os.Stat(dest); err == nil {
+		size = fi.Size()
+	}
+
+	if jsonOutput {
+		return emitJSON(portableResult{Status: "exported", File: dest, Size: size, Files: m.Files})
+	}
+	fmt.Printf("%s Exported %s (%s)\n", success("✓"), value(dest), logging.HumanBytes(size))
+	fmt.Printf("  On the other machine: %s\n", command("br import "+filepath.Base(dest)))
+	return nil
+}
+
+func runImport(_ *cobra.Command, args []string) error {
+	if _, err := backupVMDir(importFlags.name, "importing"); err != nil {
+		return jsonOrError(err)
+	}
+	cfg, err := config.Default(config.DefaultStateDir(), importFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return jsonOrError(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if !jsonOutput {
+		fmt.Printf("Importing %s into %s ...\n", value(args[0]), value(cfg.VMDir))
+	}
+	m, err := portable.Import(f, cfg.VMDir)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	moves, err := pinImportedPorts(cfg)
+	if err != nil {
+		return jsonOrError(fmt.Errorf("VM imported, but %w", err))
+	}
+
+	if jsonOutput {
+		return emitJSON(portableResult{Status: "imported", File: args[0], Files: m.Files, Ports: moves})
+	}
+	fmt.Printf("%s Imported VM exported %s\n", success("✓"), m.Created.Local().Format(time.DateTime))
+	for _, mv := range moves {
+		fmt.Printf("  %s %d is taken here; using %d\n", key(mv.Key), mv.From, mv.To)
+	}
+	next := "br start"
+	if importFlags.name != "" {
+		next += " --name " + importFlags.name
+	}
+	fmt.Printf("  Start the VM with: %s\n", command(next))
+	return nil
+}
+
+// pinImportedPorts moves the imported VM's SSH and Incus API ports off any
+// taken on this machine and saves the moves in its config, on top of any
+// `br config set` values already saved there.
+func pinImportedPorts(cfg *config.Config) ([]config.PortMove, error) {
+	path := config.ConfigPath(cfg.VMDir)
+	saved, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ApplySaved(saved)
+	moves, err := cfg.PinFreePorts()
+	if err != nil || len(moves) == 0 {
+		return nil, err
+	}
+	if err := cfg.Save(path); err != nil {
+		return nil, fmt.Errorf("save ports: %w", err)
+	}
+	return moves, nil
+}
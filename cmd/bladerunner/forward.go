@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+var forwardFlags struct {
+	name string
+}
+
+var forwardCmd = &cobra.Command{
+	Use:   "forward",
+	Short: "Add, remove, pause or resume the localhost port forwards",
+	Long: `Control the host listeners that forward localhost ports to the guest (SSH,
+the Incus API, and any added with --forward) without stopping the VM — e.g.
+reach a service in an Incus container from the host, or stop exposing the
+forwards while on an untrusted network.
+
+'add' takes a --forward spec and 'remove' a forward name; both also install or
+remove the relay inside the guest, and last until the VM stops (pass --forward
+to 'br start' to keep one). 'pause' and 'resume' take an optional forwarder name
+(ssh, incus-api, or e.g. fwd-8080); without one they apply to all forwarders.
+'resume' re-binds the original ports and fails if one was taken in the
+meantime.
+
+Forwards carry TCP only. UDP is out of scope: the guest relay runs over vsock,
+which is stream-only, so a spec such as 8080:udp:80 is rejected.`,
+	Example: renderExamples(
+		example{Comment: "Reach a guest web server on localhost:8080", Args: "forward add 8080:tcp:80"},
+		example{Comment: "Stop forwarding it again", Args: "forward remove fwd-8080"},
+		example{Comment: "Stop exposing SSH on localhost while the VM keeps running", Args: "forward pause ssh"},
+		example{Comment: "Bring every forward back", Args: "forward resume"},
+		example{Comment: "Show which forwards are paused", Args: "forward list"},
+	),
+}
+
+var forwardPauseCmd = &cobra.Command{
+	Use:   "pause [name]",
+	Short: "Close the host listeners (the VM keeps running)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  func(_ *cobra.Command, args []string) error { return runForwardToggle(true, args) },
+}
+
+var forwardResumeCmd = &cobra.Command{
+	Use:   "resume [name]",
+	Short: "Re-bind paused host listeners on their original ports",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  func(_ *cobra.Command, args []string) error { return runForwardToggle(false, args) },
+}
+
+var forwardAddCmd = &cobra.Command{
+	Use:   "add <[host:]localport[:tcp]:guestport>",
+	Short: "Forward another localhost TCP port to a guest port on the running VM (UDP is not supported)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runForwardAdd,
+}
+
+var forwardRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Stop a forward added with 'forward add' or --forward",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runForwardRemove,
+}
+
+var forwardListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List forwarders and whether each is paused",
+	Args:    cobra.NoArgs,
+	RunE:    runForwardList,
+}
+
+func init() {
+	forwardCmd.PersistentFlags().StringVar(&forwardFlags.name, "name", "", nameFlagUsage)
+	forwardCmd.AddCommand(forwardAddCmd, forwardRemoveCmd, forwardPauseCmd, forwardResumeCmd, forwardListCmd)
+}
+
+// forwardRouter builds the "forward" control sub-router. getRunner returns nil
+// until the VM has started.
+func forwardRouter(getRunner func() *vm.Runner) *control.Router {
+	router := control.NewRouter()
+	withRunner := func(fn func(ctx context.Context, r *vm.Runner, req *control.Request) *control.Message) control.HandlerFunc {
+		return func(ctx context.Context, req *control.Request) *control.Message {
+			r := getRunner()
+			if r == nil {
+				return &control.Message{Error: "VM is not started yet"}
+			}
+			return fn(ctx, r, req)
+		}
+	}
+	router.HandleFunc("add", withRunner(func(ctx context.Context, r *vm.Runner, req *control.Request) *control.Message {
+		f, err := config.ParseForward(req.Args["0"])
+		if err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		if err := r.AddForward(ctx, f); err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		return &control.Message{Response: control.RespOK}
+	}))
+	router.HandleFunc("remove", withRunner(func(ctx context.Context, r *vm.Runner, req *control.Request) *control.Message {
+		if err := r.RemoveForward(ctx, req.Args["0"]); err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		return &control.Message{Response: control.RespOK}
+	}))
+	router.HandleFunc("pause", withRunner(func(_ context.Context, r *vm.Runner, req *control.Request) *control.Message {
+		if err := r.PauseForwarders(req.Args["0"]); err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		return &control.Message{Response: control.RespOK}
+	}))
+	router.HandleFunc("resume", withRunner(func(_ context.Context, r *vm.Runner, req *control.Request) *control.Message {
+		if err := r.ResumeForwarders(req.Args["0"]); err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		return &control.Message{Response: control.RespOK}
+	}))
+	router.HandleFunc("list", withRunner(func(_ context.Context, r *vm.Runner, _ *control.Request) *control.Message {
+		b, err := json.Marshal(forwardInfos(r.Forwarders()))
+		if err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		return &control.Message{Response: string(b)}
+	}))
+	return router
+}
+
+// forwardInfos converts the runner's forwarder states to their wire form.
+func forwardInfos(states []vm.ForwarderState) []control.ForwardInfo {
+	infos := make([]control.ForwardInfo, 0, len(states))
+	for _, st := range states {
+		infos = append(infos, control.ForwardInfo{Name: st.Name, Listen: st.Listen, Paused: st.Paused})
+	}
+	return infos
+}
+
+// forwardStats converts the runner's forwarder traffic counters to their wire
+// form.
+func forwardStats(states []vm.ForwarderState) []control.ForwardStats {
+	stats := make([]control.ForwardStats, 0, len(states))
+	for _, st := range states {
+		stats = append(stats, control.ForwardStats{
+			Name:           st.Name,
+			BytesToGuest:   st.BytesToGuest,
+			BytesFromGuest: st.BytesFromGuest,
+			ActiveConns:    st.ActiveConns,
+			TotalConns:     st.TotalConns,
+		})
+	}
+	return stats
+}
+
+// forwardClient returns a control client for the running VM that --name
+// picked.
+func forwardClient() (*control.Client, error) {
+	vmDir, err := namedVMDir(forwardFlags.name)
+	if err != nil {
+		return nil, err
+	}
+	client := control.NewClient(vmDir)
+	if !client.IsRunning() {
+		return nil, fmt.Errorf("VM is not running")
+	}
+	return client, nil
+}
+
+func runForwardToggle(pause bool, args []string) error {
+	client, err := forwardClient()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+	}
+
+	if pause {
+		err = client.PauseForwards(name)
+	} else {
+		err = client.ResumeForwards(name)
+	}
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	verb := "Resumed"
+	if pause {
+		verb = "Paused"
+	}
+	target := "all forwarders"
+	if name != "" {
+		target = name
+	}
+	return printForwardChange(verb+" "+target, client)
+}
+
+func runForwardAdd(_ *cobra.Command, args []string) error {
+	f, err := config.ParseForward(args[0])
+	if err != nil {
+		return jsonOrError(err)
+	}
+	client, err := forwardClient()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	if err := client.AddForward(args[0]); err != nil {
+		return jsonOrError(err)
+	}
+	return printForwardChange("Added "+f.Name, client)
+}
+
+func runForwardRemove(_ *cobra.Command, args []string) error {
+	client, err := forwardClient()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	if err := client.RemoveForward(args[0]); err != nil {
+		return jsonOrError(err)
+	}
+	return printForwardChange("Removed "+args[0], client)
+}
+
+// printForwardChange reports a successful add or remove and lists the
+// forwards that result.
+func printForwardChange(done string, client *control.Client) error {
+	forwards, err := client.ListForwards()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	if jsonOutput {
+		return emitJSON(forwards)
+	}
+	fmt.Printf("%s %s\n", success("✓"), done)
+	printForwards(forwards)
+	return nil
+}
+
+func runForwardList(_ *cobra.Command, _ []string) error {
+	client, err := forwardClient()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	forwards, err := client.ListForwards()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	if jsonOutput {
+		return emitJSON(forwards)
+	}
+	printForwards(forwards)
+	return nil
+}
+
+func printForwards(forwards []control.ForwardInfo) {
+	for _, f := range forwards {
+		fmt.Printf("  %-10s %-22s %s\n", f.Name, value(f.Listen), forwardStateLabel(f))
+	}
+}
+
+// forwardStateLabel renders a forwarder's state, styled for the terminal.
+func forwardStateLabel(f control.ForwardInfo) string {
+	if f.Paused {
+		return warning("paused")
+	}
+	return success("active")
+}
+
+// pausedForwards returns the names of paused forwarders, for status output.
+func pausedForwards(forwards []control.ForwardInfo) string {
+	var names []string
+	for _, f := range forwards {
+		if f.Paused {
+			names = append(names, f.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+// guestQueryTimeout bounds one exchange with the guest agent, leaving room
+// inside the client's own command timeout.
+const guestQueryTimeout = 3 * time.Second
+
+var guestStatusFlags struct {
+	name string
+}
+
+var guestStatusCmd = &cobra.Command{
+	Use:   "guest-status",
+	Short: "Ask the guest agent whether provisioning finished",
+	Long: `Query the agent inside the running VM over its vsock control channel and
+print what it reports: whether the bootstrap finished (and when), cloud-init's
+status, and the guest's uptime. Unlike the console log, this answers from the
+guest itself, so it works after the boot messages have scrolled away.`,
+	Example: renderExamples(
+		example{Args: "guest-status"},
+		example{Comment: "Check from a script whether provisioning finished", Args: "guest-status --json"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runGuestStatus,
+}
+
+func init() {
+	guestStatusCmd.Flags().StringVar(&guestStatusFlags.name, "name", "", nameFlagUsage)
+}
+
+// guestStatusHandler serves control.CmdGuestStatus by relaying it to the
+// guest agent on cfg.VsockControlPort.
+func guestStatusHandler(cfg *config.Config, getRunner func() *vm.Runner) control.HandlerFunc {
+	return func(_ context.Context, _ *control.Request) *control.Message {
+		r := getRunner()
+		if r == nil {
+			return &control.Message{Error: "VM is not running yet"}
+		}
+		if r.Paused() {
+			return &control.Message{Error: "VM is paused"}
+		}
+		if cfg.VsockControlPort == 0 {
+			return &control.Message{Error: "guest control channel is disabled"}
+		}
+		conn, err := r.DialGuest(cfg.VsockControlPort)
+		if err != nil {
+			return &control.Message{Error: fmt.Sprintf("dial guest agent: %v", err)}
+		}
+		defer func() { _ = conn.Close() }()
+		st, err := control.QueryGuestStatus(conn, guestQueryTimeout)
+		if err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		b, err := json.Marshal(st)
+		if err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		return &control.Message{Response: string(b)}
+	}
+}
+
+func runGuestStatus(_ *cobra.Command, _ []string) error {
+	vmDir, err := namedVMDir(guestStatusFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	client := control.NewClient(vmDir)
+	if !client.IsRunning() {
+		return jsonOrError(fmt.Errorf("VM is not running"))
+	}
+	st, err := client.GetGuestStatus()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	if jsonOutput {
+		return emitJSON(st)
+	}
+	ready := warning("not yet")
+	if st.Ready {
+		ready = success("yes")
+		if st.ReadyAt != "" {
+			ready += subtle(" (" + st.ReadyAt + ")")
+		}
+	}
+	fmt.Println(title("Guest Status"))
+	fmt.Printf("  %s %s\n", key(fmt.Sprintf("%-11s", "Ready:")), ready)
+	fmt.Printf("  %s %s\n", key(fmt.Sprintf("%-11s", "Cloud-init:")), value(st.CloudInit))
+	fmt.Printf("  %s %s\n", key(fmt.Sprintf("%-11s", "Uptime:")), value((time.Duration(st.UptimeSeconds) * time.Second).String()))
+	return nil
+}
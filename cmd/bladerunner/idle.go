@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+// idleCheckInterval is how often the idle watcher samples activity.
+const idleCheckInterval = 30 * time.Second
+
+// idleTracker decides when a VM has gone unused for its idle timeout. It
+// errs toward keeping the VM: any open forwarded connection counts as use
+// however quiet it is, so an SSH session left sitting at a prompt never lets
+// the VM stop under it.
+type idleTracker struct {
+	timeout    time.Duration
+	lastActive time.Time
+	// bytes and conns are the forwarders' lifetime totals at the previous
+	// sample; a change means traffic since.
+	bytes uint64
+	conns uint64
+}
+
+func newIdleTracker(timeout time.Duration, now time.Time) *idleTracker {
+	return &idleTracker{timeout: timeout, lastActive: now}
+}
+
+// observe folds in one sample, taken at now, of when the control socket last
+// served a command and the forwarders' counters, and reports whether the VM
+// has now been idle for the whole timeout.
+func (t *idleTracker) observe(now, lastControl time.Time, fwds []vm.ForwarderState) bool {
+	var bytes, conns uint64
+	open := false
+	for _, f := range fwds {
+		bytes += f.BytesToGuest + f.BytesFromGuest
+		conns += f.TotalConns
+		open = open || f.ActiveConns > 0
+	}
+	if open || bytes != t.bytes || conns != t.conns {
+		t.lastActive = now
+	}
+	t.bytes, t.conns = bytes, conns
+	if lastControl.After(t.lastActive) {
+		t.lastActive = lastControl
+	}
+	return now.Sub(t.lastActive) >= t.timeout
+}
+
+// watchIdle calls stop once the VM has been idle for timeout, checking every
+// idleCheckInterval until ctx ends. lastControl and forwarders are sampled on
+// each check.
+func watchIdle(ctx context.Context, timeout time.Duration, lastControl func() time.Time, forwarders func() []vm.ForwarderState, stop func()) {
+	tracker := newIdleTracker(timeout, time.Now())
+	tick := time.NewTicker(idleCheckInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-tick.C:
+			if tracker.observe(now, lastControl(), forwarders()) {
+				logging.L().Info("stopping idle vm", "idle_timeout", timeout.String(), "last_active", tracker.lastActive.Format(time.RFC3339))
+				stop()
+				return
+			}
+		}
+	}
+}
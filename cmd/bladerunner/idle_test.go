@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+func TestIdleTracker(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return t0.Add(time.Duration(m) * time.Minute) }
+	ssh := func(bytes uint64, active int64, total uint64) []vm.ForwarderState {
+		return []vm.ForwarderState{{Name: "ssh", BytesToGuest: bytes, ActiveConns: active, TotalConns: total}}
+	}
+
+	steps := []struct {
+		name        string
+		minute      int
+		lastControl time.Time
+		fwds        []vm.ForwarderState
+		want        bool
+	}{
+		{"quiet, inside the window", 30, time.Time{}, ssh(0, 0, 0), false},
+		{"quiet for the whole window", 60, time.Time{}, ssh(0, 0, 0), true},
+		{"a connection came and went", 61, time.Time{}, ssh(100, 0, 1), false},
+		{"an SSH session sits open", 200, time.Time{}, ssh(100, 1, 2), false},
+		{"the session closed at the last sample", 259, time.Time{}, ssh(100, 0, 2), false},
+		{"a control command came in", 330, at(300), ssh(100, 0, 2), false},
+		{"an hour after that command", 360, at(300), ssh(100, 0, 2), true},
+	}
+	tr := newIdleTracker(time.Hour, t0)
+	for _, s := range steps {
+		if got := tr.observe(at(s.minute), s.lastControl, s.fwds); got != s.want {
+			t.Errorf("%s (minute %d): idle = %v, want %v", s.name, s.minute, got, s.want)
+		}
+	}
+}
+
+func TestWatchIdleStopsOnContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watchIdle(ctx, time.Hour, func() time.Time { return time.Time{} }, func() []vm.ForwarderState { return nil }, func() {
+			t.Error("stop called before the timeout")
+		})
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchIdle did not return when its context ended")
+	}
+}
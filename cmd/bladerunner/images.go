@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+var imagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "Discover the built-in base images",
+	Args:  cobra.NoArgs,
+}
+
+var imagesReleasesCmd = &cobra.Command{
+	Use:   "releases",
+	Short: "List the built-in base image releases and their URLs",
+	Long: `List every distro, release and architecture bladerunner has a built-in base
+image for, with the URL it downloads. The default is what a plain 'br start'
+uses; select another distro with 'br start --distro <distro>' and another
+architecture with --arch. Any other image works through --image-url or
+--image-path.`,
+	Example: renderExamples(
+		example{Comment: "List the built-in images", Args: "images releases"},
+		example{Comment: "Emit them as JSON", Args: "images releases --json"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runImagesReleases,
+}
+
+func init() {
+	imagesCmd.AddCommand(imagesReleasesCmd)
+}
+
+func runImagesReleases(_ *cobra.Command, _ []string) error {
+	releases := config.BaseImageReleases()
+	if jsonOutput {
+		return emitJSON(releases)
+	}
+	return renderReleasesTable(os.Stdout, releases)
+}
+
+func renderReleasesTable(out io.Writer, releases []config.BaseImageRelease) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "DISTRO\tRELEASE\tARCH\tNOTE\tURL"); err != nil {
+		return err
+	}
+	for _, r := range releases {
+		note := ""
+		switch {
+		case r.Default:
+			note = "default"
+		case r.Pinned:
+			note = "pinned"
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Distro, r.Release, r.Arch, note, r.URL); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
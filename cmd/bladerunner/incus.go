@@ -8,9 +8,11 @@ import (
 )
 
 var incusCmd = &cobra.Command{
-	Use:                "incus [args...]",
-	Short:              "Run incus commands in the VM",
-	Long:               `Execute incus commands inside the Bladerunner VM. All arguments are passed to the incus command in the VM.`,
+	Use:   "incus [--name vm] [args...]",
+	Short: "Run incus commands in the VM",
+	Long: `Execute incus commands inside the Bladerunner VM. All arguments are passed to the incus command in the VM.
+
+A leading --name picks a VM started with 'br start --name'; it is not passed on.`,
 	DisableFlagParsing: true,
 	RunE:               runIncus,
 }
@@ -26,7 +28,8 @@ func runIncus(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	configPath, err := sshConfigFromControl()
+	name, args := leadingNameArg(args)
+	configPath, err := sshConfigFromControl(name)
 	if err != nil {
 		return err
 	}
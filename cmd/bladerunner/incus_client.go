@@ -11,20 +11,21 @@ import (
 	"github.com/stuffbucket/bladerunner/internal/logging"
 )
 
-// connectIncus dials the local Incus API using config from the running control
-// listener, offering to start the VM first when needed (see requireRunningVM).
-func connectIncus() (*incus.Client, error) {
-	ctl, err := requireRunningVM()
+// connectIncus dials the Incus API of the VM called name using config from its
+// running control listener, offering to start the VM first when needed (see
+// requireRunningVM).
+func connectIncus(name string) (*incus.Client, error) {
+	ctl, err := requireRunningVM(name)
 	if err != nil {
 		return nil, err
 	}
-	return incusClientFromControl(ctl)
+	return incusClientFromControl(ctl, name)
 }
 
 // incusClientFromControl builds an Incus client from an already-connected
-// control client. It does not prompt, so it is safe to call from shell
-// completion.
-func incusClientFromControl(ctl *control.Client) (*incus.Client, error) {
+// control client of the VM called name, whose client certificate it presents.
+// It does not prompt, so it is safe to call from shell completion.
+func incusClientFromControl(ctl *control.Client, name string) (*incus.Client, error) {
 	port, err := ctl.GetConfig(control.ConfigKeyLocalAPIPort)
 	if err != nil {
 		logging.L().Debug("read local-api-port failed", "err", err)
@@ -35,7 +36,7 @@ func incusClientFromControl(ctl *control.Client) (*incus.Client, error) {
 	}
 	endpoint := fmt.Sprintf("https://127.0.0.1:%s", port)
 
-	cfg, err := config.Default("")
+	cfg, err := config.Default("", name)
 	if err != nil {
 		return nil, fmt.Errorf("load defaults: %w", err)
 	}
@@ -44,17 +45,22 @@ func incusClientFromControl(ctl *control.Client) (*incus.Client, error) {
 
 // instanceNameCompletion provides shell completion for instance name arguments.
 // Falls back to no completion if the VM is not running or the API is unreachable.
-func instanceNameCompletion(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+func instanceNameCompletion(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 	if len(args) > 0 {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 	// Completion must never block on a prompt: check silently and bail if the VM
-	// is not running.
-	ctl := control.NewClient(config.DefaultStateDir())
+	// is not running. The --name already typed, if any, picks the VM.
+	name, _ := cmd.Flags().GetString("name")
+	vmDir, err := namedVMDir(name)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	ctl := control.NewClient(vmDir)
 	if !ctl.IsRunning() {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
-	client, err := incusClientFromControl(ctl)
+	client, err := incusClientFromControl(ctl, name)
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveError | cobra.ShellCompDirectiveNoFileComp
 	}
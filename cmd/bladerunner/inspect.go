@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/inspect"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+)
+
+var inspectFlags struct {
+	disk      string
+	partition int
+}
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Read files from the stopped VM's disk without booting it",
+	Long: `Read the guest filesystem straight out of disk.raw, read-only, for diagnosing a
+VM that won't boot: cloud-init logs, vsock-diag.txt, the ready sentinel.
+
+Nothing is mounted and the disk is never written. Reads go through debugfs
+(install with: brew install e2fsprogs). The ext4 journal is not replayed, so
+after a crash the very latest writes may not show. The VM must be stopped.
+
+Each subcommand takes an optional VM name before its path, for a VM started
+with 'br start --name'; guest paths are absolute, so a lone argument without a
+leading / is taken as the name.`,
+	Example: renderExamples(
+		example{Comment: "Did the guest ever finish first boot?", Args: "inspect cat /var/lib/bladerunner/ready"},
+		example{Comment: "Why did cloud-init fail?", Args: "inspect cat /var/log/cloud-init-output.log"},
+		example{Comment: "Browse a directory", Args: "inspect ls /var/lib/bladerunner"},
+		example{Comment: "Read the same file from the VM named build", Args: "inspect cat build /var/lib/bladerunner/ready"},
+	),
+}
+
+var inspectCatCmd = &cobra.Command{
+	Use:   "cat [name] <path>",
+	Short: "Print a file from the guest filesystem",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, path := "", args[0]
+		if len(args) == 2 {
+			name, path = args[0], args[1]
+		}
+		return runInspect(cmd.Context(), name, path, (*inspect.Image).Cat)
+	},
+}
+
+var inspectLsCmd = &cobra.Command{
+	Use:   "ls [name] [path]",
+	Short: "List a directory in the guest filesystem (default: /)",
+	Args:  cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, path := "", "/"
+		switch {
+		case len(args) == 2:
+			name, path = args[0], args[1]
+		case len(args) == 1 && strings.HasPrefix(args[0], "/"):
+			path = args[0]
+		case len(args) == 1:
+			name = args[0]
+		}
+		return runInspect(cmd.Context(), name, path, (*inspect.Image).List)
+	},
+}
+
+var inspectPartsCmd = &cobra.Command{
+	Use:   "partitions [name]",
+	Short: "List the disk's partitions and which one is read",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runInspectPartitions,
+}
+
+func init() {
+	inspectCmd.PersistentFlags().StringVar(&inspectFlags.disk, "disk", "", "Disk image to read (default: the VM's disk.raw)")
+	inspectCmd.PersistentFlags().IntVar(&inspectFlags.partition, "partition", 0, "GPT partition number to read (default: the root partition)")
+	inspectCmd.AddCommand(inspectCatCmd, inspectLsCmd, inspectPartsCmd)
+}
+
+// inspectResult is the JSON payload for `br inspect cat|ls`.
+type inspectResult struct {
+	Disk      string `json:"disk"`
+	Partition int    `json:"partition,omitempty"`
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+}
+
+// inspectDiskPath returns the disk image of the VM called name ("" for the
+// default one) to read, refusing while that VM is running: its page cache
+// holds writes not yet on disk, so reads would be inconsistent, and the guest
+// may be mid-write.
+func inspectDiskPath(name string) (string, error) {
+	stateDir := config.DefaultStateDir()
+	vmDir, err := config.VMDirFor(stateDir, name)
+	if err != nil {
+		return "", err
+	}
+	if control.NewClient(vmDir).IsRunning() {
+		return "", fmt.Errorf("VM is running; stop it first ('br stop') before inspecting its disk, or use 'br ssh'")
+	}
+	if inspectFlags.disk != "" {
+		return inspectFlags.disk, nil
+	}
+	cfg, err := config.Default(stateDir, name)
+	if err != nil {
+		return "", err
+	}
+	return cfg.DiskPath, nil
+}
+
+func runInspect(ctx context.Context, name, guestPath string, read func(*inspect.Image, context.Context, string, io.Writer) error) error {
+	diskPath, err := inspectDiskPath(name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	img, err := inspect.Open(diskPath, inspectFlags.partition)
+	if err != nil {
+		return jsonOrError(fmt.Errorf("open %s: %w", diskPath, err))
+	}
+
+	if !jsonOutput {
+		return read(img, ctx, guestPath, os.Stdout)
+	}
+	var out bytes.Buffer
+	if err := read(img, ctx, guestPath, &out); err != nil {
+		return jsonOrError(err)
+	}
+	return emitJSON(inspectResult{Disk: diskPath, Partition: img.Partition.Number, Path: guestPath, Content: out.String()})
+}
+
+func runInspectPartitions(_ *cobra.Command, args []string) error {
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+	}
+	diskPath, err := inspectDiskPath(name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	parts, err := inspect.ReadPartitions(diskPath)
+	if err != nil {
+		return jsonOrError(fmt.Errorf("read %s: %w", diskPath, err))
+	}
+	root, rootErr := inspect.RootPartition(parts)
+
+	if jsonOutput {
+		if parts == nil {
+			parts = []inspect.Partition{}
+		}
+		return emitJSON(parts)
+	}
+	for _, p := range parts {
+		marker := " "
+		if rootErr == nil && p.Number == root.Number {
+			marker = success("*")
+		}
+		fmt.Printf("%s %2d  %-12s %8s  %s\n", marker, p.Number, value(p.Name), logging.HumanBytes(p.Size), subtle(p.Type))
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/util"
+)
+
+// defaultVMLabel names the unnamed VM rooted at the state dir in `br list`.
+const defaultVMLabel = "(default)"
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List Bladerunner VMs and their status",
+	Long: `List every VM in the state directory: the default VM and each one
+started with 'br start --name'. Running VMs are asked for their PID, sizing and
+uptime; stopped ones are listed as stopped.
+
+Not to be confused with 'br ls', which lists the Incus instances inside the
+running VM.`,
+	Example: renderExamples(
+		example{Comment: "Show every VM", Args: "list"},
+		example{Comment: "Then address one by name", Args: "status --name build"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runList,
+}
+
+// vmListEntry is one row of `br list`, and of its JSON output.
+type vmListEntry struct {
+	Name          string `json:"name"`
+	Default       bool   `json:"default,omitempty"`
+	Dir           string `json:"dir"`
+	Status        string `json:"status"`
+	PID           int    `json:"pid,omitempty"`
+	CPUs          string `json:"cpus,omitempty"`
+	MemoryGiB     string `json:"memory_gib,omitempty"`
+	UptimeSeconds int64  `json:"uptime_seconds,omitempty"`
+}
+
+func runList(_ *cobra.Command, _ []string) error {
+	vms, err := findVMs(config.DefaultStateDir())
+	if err != nil {
+		return jsonOrError(err)
+	}
+	for i := range vms {
+		probeVM(&vms[i])
+	}
+	if jsonOutput {
+		return emitJSON(vms)
+	}
+	if len(vms) == 0 {
+		fmt.Println("No VMs yet; create one with", command("br start"))
+		return nil
+	}
+	renderVMList(os.Stdout, vms)
+	return nil
+}
+
+// findVMs lists the VMs under stateDir: the state dir itself (the default VM)
+// and each subdirectory named like a VM, provided it has ever been started
+// (it holds runtime metadata or a saved config). The default VM comes first,
+// then the named ones by name. A missing state dir holds no VMs.
+func findVMs(stateDir string) ([]vmListEntry, error) {
+	entries, err := os.ReadDir(stateDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []vmListEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state dir %s: %w", stateDir, err)
+	}
+
+	vms := []vmListEntry{}
+	if isVMDir(stateDir, "") {
+		vms = append(vms, vmListEntry{Name: defaultVMLabel, Default: true, Dir: stateDir})
+	}
+	var named []vmListEntry
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir, err := config.VMDirFor(stateDir, e.Name())
+		if err != nil || !isVMDir(stateDir, e.Name()) {
+			continue
+		}
+		named = append(named, vmListEntry{Name: e.Name(), Dir: dir})
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].Name < named[j].Name })
+	return append(vms, named...), nil
+}
+
+// isVMDir reports whether the VM called name under stateDir has left its
+// runtime metadata or saved config behind.
+func isVMDir(stateDir, name string) bool {
+	cfg, err := config.Default(stateDir, name)
+	if err != nil {
+		return false
+	}
+	return util.FileExists(cfg.MetadataPath) || util.FileExists(config.ConfigPath(cfg.VMDir))
+}
+
+// probeVM fills in vm's status from its control socket. A VM nobody is
+// listening for is stopped; one whose socket doesn't answer is unresponsive.
+func probeVM(vm *vmListEntry) {
+	client := control.NewClient(vm.Dir)
+	if err := client.Ping(context.Background()); err != nil {
+		vm.Status = control.StatusStopped
+		if errors.Is(err, control.ErrUnresponsive) {
+			vm.Status = control.StatusUnresponsive
+		}
+		return
+	}
+
+	info, err := client.GetStatusInfo()
+	if err == nil {
+		vm.Status = info.State
+	} else if vm.Status, err = client.GetStatus(); err != nil {
+		vm.Status = control.StatusUnresponsive
+		return
+	}
+	get := func(k string) string {
+		if v, ok := statusInfoValue(info, k); ok {
+			return v
+		}
+		v, _ := client.GetConfig(k)
+		return v
+	}
+	vm.PID, _ = strconv.Atoi(get(control.ConfigKeyPID))
+	vm.CPUs = get(control.ConfigKeyCPUs)
+	vm.MemoryGiB = get(control.ConfigKeyMemoryGiB)
+	if started, ok := vmStartedAt(client, info); ok {
+		vm.UptimeSeconds = int64(time.Since(started) / time.Second)
+	}
+}
+
+// renderVMList writes the `br list` table. Cells are padded before they are
+// styled, so the columns line up with or without color; off a TTY the styles
+// are no-ops and the table is plain text.
+func renderVMList(w io.Writer, vms []vmListEntry) {
+	header := []string{"NAME", "STATUS", "PID", "CPUS", "MEMORY", "UPTIME"}
+	rows := make([][]string, 0, len(vms))
+	for _, vm := range vms {
+		row := []string{vm.Name, vm.Status, "-", "-", "-", "-"}
+		if vm.PID > 0 {
+			row[2] = strconv.Itoa(vm.PID)
+		}
+		if vm.CPUs != "" {
+			row[3] = vm.CPUs
+		}
+		if vm.MemoryGiB != "" {
+			row[4] = vm.MemoryGiB + " GiB"
+		}
+		if vm.UptimeSeconds > 0 {
+			row[5] = formatUptime(time.Duration(vm.UptimeSeconds) * time.Second)
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(header))
+	for _, r := range append([][]string{header}, rows...) {
+		for i, cell := range r {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	pad := func(cells []string, style func(col int, s string) string) string {
+		out := make([]string, len(cells))
+		for i, cell := range cells {
+			padded := cell
+			if i < len(cells)-1 {
+				padded = fmt.Sprintf("%-*s", widths[i], cell)
+			}
+			out[i] = style(i, padded)
+		}
+		return strings.TrimRight(strings.Join(out, "  "), " ")
+	}
+
+	_, _ = fmt.Fprintln(w, pad(header, func(_ int, s string) string { return key(s) }))
+	for _, r := range rows {
+		_, _ = fmt.Fprintln(w, pad(r, func(col int, s string) string {
+			switch col {
+			case 0:
+				return value(s)
+			case 1:
+				return vmStatusStyle(strings.TrimSpace(s))(s)
+			}
+			return s
+		}))
+	}
+}
+
+// vmStatusStyle colors a VM status the way `br status` does: running is
+// green, paused or not answering is amber, and stopped is muted.
+func vmStatusStyle(status string) func(string) string {
+	switch status {
+	case control.StatusRunning:
+		return success
+	case control.StatusPaused, control.StatusUnreachable, control.StatusUnresponsive:
+		return warning
+	case control.StatusStopped:
+		return subtle
+	}
+	return errorf
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/control"
+)
+
+func TestFindVMs(t *testing.T) {
+	state := t.TempDir()
+	touch := func(rel string) {
+		t.Helper()
+		path := filepath.Join(state, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	touch("runtime-metadata.json")
+	touch("test/runtime-metadata.json")
+	touch("build/config.json")
+	touch("disks/config.json") // a reserved name, never a VM
+	touch("empty/console.log") // never started
+	touch("stray.json")
+
+	vms, err := findVMs(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, vm := range vms {
+		names = append(names, vm.Name)
+	}
+	if got, want := strings.Join(names, ","), defaultVMLabel+",build,test"; got != want {
+		t.Fatalf("findVMs = %s, want %s", got, want)
+	}
+	if !vms[0].Default || vms[0].Dir != state || vms[1].Dir != filepath.Join(state, "build") {
+		t.Errorf("unexpected entries: %+v", vms)
+	}
+
+	// Nobody is listening on these sockets: each VM is stopped, not an error.
+	for i := range vms {
+		probeVM(&vms[i])
+		if vms[i].Status != control.StatusStopped {
+			t.Errorf("%s status = %q, want stopped", vms[i].Name, vms[i].Status)
+		}
+	}
+
+	if vms, err := findVMs(filepath.Join(state, "missing")); err != nil || len(vms) != 0 {
+		t.Errorf("missing state dir: %v, %v", vms, err)
+	}
+}
+
+func TestRenderVMList(t *testing.T) {
+	var buf bytes.Buffer
+	renderVMList(&buf, []vmListEntry{
+		{Name: defaultVMLabel, Status: control.StatusStopped},
+		{Name: "build", Status: control.StatusRunning, PID: 4242, CPUs: "4", MemoryGiB: "8", UptimeSeconds: 3700},
+	})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	// Off a TTY the table is plain and its columns line up.
+	col := strings.Index(lines[0], "STATUS")
+	if strings.Index(lines[1], "stopped") != col || strings.Index(lines[2], "running") != col {
+		t.Errorf("STATUS column misaligned:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[1], "-") || !strings.Contains(lines[2], "4242") || !strings.Contains(lines[2], "8 GiB") || !strings.HasSuffix(lines[2], "1h1m") {
+		t.Errorf("unexpected rows:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+)
+
+var logLevelFlags struct {
+	name string
+}
+
+var logLevelCmd = &cobra.Command{
+	Use:   "log-level [debug|info|warn|error]",
+	Short: "Show or change the running VM host's log verbosity",
+	Long: `Show the log level of the running VM host, or change it without a restart.
+
+Turn on debug to capture a transient problem, such as forwarder dial retries,
+in the host log, then set it back. The change lasts until the VM host exits;
+the next start uses ` + logging.LogLevelEnvVar + ` again (info when unset).`,
+	Example: renderExamples(
+		example{Comment: "Show the current level", Args: "log-level"},
+		example{Comment: "Log forwarder retries and other debug detail", Args: "log-level debug"},
+		example{Comment: "Back to normal", Args: "log-level info"},
+	),
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"debug", "info", "warn", "error"},
+	RunE:      runLogLevel,
+}
+
+func init() {
+	logLevelCmd.Flags().StringVar(&logLevelFlags.name, "name", "", nameFlagUsage)
+}
+
+type logLevelResult struct {
+	Level string `json:"level"`
+}
+
+func runLogLevel(_ *cobra.Command, args []string) error {
+	vmDir, err := namedVMDir(logLevelFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	client := control.NewClient(vmDir)
+	if !client.IsRunning() {
+		return jsonOrError(fmt.Errorf("VM is not running"))
+	}
+	var level string
+	if len(args) == 0 {
+		level, err = client.LogLevel()
+	} else {
+		level, err = client.SetLogLevel(args[0])
+	}
+	if err != nil {
+		return jsonOrError(err)
+	}
+	if jsonOutput {
+		return emitJSON(logLevelResult{Level: level})
+	}
+	if len(args) == 0 {
+		fmt.Printf("%s %s\n", key("Log level:"), value(level))
+		return nil
+	}
+	fmt.Println(success("Log level set to " + level))
+	return nil
+}
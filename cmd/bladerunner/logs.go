@@ -3,38 +3,94 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/boot"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+	"golang.org/x/term"
 )
 
 var logsFlags struct {
 	follow bool
+	incus  bool
+	since  string
+	boot   bool
+	name   string
 }
 
 var logsCmd = &cobra.Command{
-	Use:               "logs <instance>",
-	Short:             "Stream console logs from an Incus instance",
-	Long:              `Stream the console log of the named Incus instance. Use --follow to tail.`,
-	Args:              cobra.ExactArgs(1),
+	Use:   "logs [instance] | logs --incus",
+	Short: "Stream the VM's console log, an Incus instance's console, or incusd's log",
+	Long: `Without an instance, print the VM's own console log (kernel, cloud-init and
+bootstrap output). --follow tails it with the detected boot status kept on the
+last line, and stops on Ctrl+C or once sshd and Incus are up. --boot prints
+just the status parsed from the current boot instead of the raw text.
+
+With an instance name, stream that Incus instance's console log instead. Use
+--follow to tail.
+
+With --incus, stream the guest's incusd log instead (` + vm.IncusdLogPath + `,
+or the incus unit's journal when that file is absent). This is where Incus API
+failures show up, and it is read over SSH, so it works while the Incus API is
+still unreachable. --since limits it to recent entries from the journal and
+takes a duration ("15m") or anything journalctl --since accepts.`,
+	Example: renderExamples(
+		example{Comment: "Watch the VM boot", Args: "logs --follow"},
+		example{Comment: "Summarize the current boot", Args: "logs --boot"},
+		example{Comment: "Tail an instance's console", Args: "logs mybox --follow"},
+		example{Comment: "Tail incusd's log in the VM", Args: "logs --incus -f"},
+		example{Comment: "Show the last hour of incusd's log", Args: "logs --incus --since 1h"},
+	),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if logsFlags.incus {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MaximumNArgs(1)(cmd, args)
+	},
 	RunE:              runLogs,
 	ValidArgsFunction: instanceNameCompletion,
 }
 
 func init() {
 	logsCmd.Flags().BoolVarP(&logsFlags.follow, "follow", "f", false, "Follow log output")
+	logsCmd.Flags().BoolVar(&logsFlags.incus, "incus", false, "Stream incusd's log from the VM instead of an instance console")
+	logsCmd.Flags().StringVar(&logsFlags.since, "since", "", "With --incus, show entries since a duration ago (e.g. 15m) or a journalctl time")
+	logsCmd.Flags().BoolVar(&logsFlags.boot, "boot", false, "Print the boot status parsed from the VM's console log instead of the log itself")
+	logsCmd.Flags().StringVar(&logsFlags.name, "name", "", nameFlagUsage)
 }
 
 func runLogs(_ *cobra.Command, args []string) error {
-	if err := rejectJSONForInteractive("logs"); err != nil {
-		return err
+	// --boot is a one-shot report, so it alone has a JSON form.
+	if !logsFlags.boot {
+		if err := rejectJSONForInteractive("logs"); err != nil {
+			return err
+		}
+	}
+	if logsFlags.boot && (logsFlags.incus || logsFlags.follow || len(args) > 0) {
+		return jsonOrError(errors.New("--boot reports on the VM's console log; it cannot be combined with --incus, --follow or an instance"))
+	}
+
+	if logsFlags.incus {
+		return runIncusdLogs(logsFlags.follow, logsFlags.since)
+	}
+	if logsFlags.since != "" {
+		return errors.New("--since requires --incus")
+	}
+	if len(args) == 0 {
+		return runConsoleLogs()
 	}
 
 	instance := args[0]
 
-	client, err := connectIncus()
+	client, err := connectIncus(logsFlags.name)
 	if err != nil {
 		return err
 	}
@@ -56,3 +112,281 @@ func runLogs(_ *cobra.Command, args []string) error {
 	}
 	return err
 }
+
+// runIncusdLogs replaces this process with an ssh session streaming the
+// guest's incusd log. It probes the guest's sshd first so an unreachable guest
+// gets actionable guidance instead of a bare ssh connection error.
+func runIncusdLogs(follow bool, since string) error {
+	client, err := requireRunningVM(logsFlags.name)
+	if err != nil {
+		return err
+	}
+	configPath, addr, err := sshEndpointFromControl(client)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), sshProbeTimeout)
+		err = probeSSHBanner(ctx, addr)
+		cancel()
+	}
+	if err != nil {
+		return fmt.Errorf("guest is not reachable over SSH (%w); the incusd log is read over SSH, so check %s or wait for the guest with %s",
+			err, command("br status"), command("br ssh --wait"))
+	}
+
+	sshPath, argv, err := sshArgv(configPath, []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=5"},
+		vm.IncusdLogStreamCommand(follow, since)...)
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(sshPath, argv, os.Environ())
+}
+
+// consoleBootReport is the `br logs --boot` payload.
+type consoleBootReport struct {
+	ConsoleLog      string   `json:"console_log"`
+	Summary         string   `json:"summary"`
+	Healthy         bool     `json:"healthy"`
+	KernelBooted    bool     `json:"kernel_booted"`
+	SystemdReached  bool     `json:"systemd_reached"`
+	CloudInitDone   bool     `json:"cloud_init_done"`
+	CloudInitFailed bool     `json:"cloud_init_failed"`
+	SSHReady        bool     `json:"ssh_ready"`
+	IncusReady      bool     `json:"incus_ready"`
+	LoginPrompt     bool     `json:"login_prompt"`
+	KernelPanic     bool     `json:"kernel_panic"`
+	EmergencyMode   bool     `json:"emergency_mode"`
+	DiskFull        bool     `json:"disk_full"`
+	Errors          []string `json:"errors"`
+	// Timeline lists the phases reached, in order, with their time since the
+	// kernel started.
+	Timeline []bootPhase `json:"timeline"`
+}
+
+type bootPhase struct {
+	Phase   string  `json:"phase"`
+	Seconds float64 `json:"seconds"`
+}
+
+func newConsoleBootReport(path string, st boot.Status) consoleBootReport {
+	r := consoleBootReport{
+		ConsoleLog:      path,
+		Summary:         st.Summary(),
+		Healthy:         st.Healthy(),
+		KernelBooted:    st.KernelBooted,
+		SystemdReached:  st.SystemdReached,
+		CloudInitDone:   st.CloudInitDone,
+		CloudInitFailed: st.CloudInitFailed,
+		SSHReady:        st.SSHReady,
+		IncusReady:      st.IncusReady,
+		LoginPrompt:     st.LoginPrompt,
+		KernelPanic:     st.KernelPanic,
+		EmergencyMode:   st.EmergencyMode,
+		DiskFull:        st.DiskFull,
+		Errors:          []string{},
+		Timeline:        []bootPhase{},
+	}
+	for _, e := range st.Errors {
+		r.Errors = append(r.Errors, e.String())
+	}
+	for _, p := range st.Timeline() {
+		r.Timeline = append(r.Timeline, bootPhase{Phase: p.Name, Seconds: p.At.Seconds()})
+	}
+	return r
+}
+
+// formatTimeline renders a boot timeline as "kernel: 0s, cloud-init: 48s".
+func formatTimeline(phases []boot.Phase) string {
+	parts := make([]string, len(phases))
+	for i, p := range phases {
+		parts[i] = fmt.Sprintf("%s: %s", p.Name, p.At.Round(time.Second))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// runConsoleLogs prints the VM's console log, follows it, or reports the
+// current boot's status, per --follow and --boot.
+func runConsoleLogs() error {
+	cfg, err := config.Default("", logsFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	path := cfg.ConsoleLogPath
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) && !logsFlags.follow {
+		return jsonOrError(fmt.Errorf("no console log at %s yet; start the VM with 'br start'", path))
+	}
+
+	if logsFlags.boot {
+		st, err := boot.ScanLastBoot(path)
+		if err != nil {
+			return jsonOrError(err)
+		}
+		if jsonOutput {
+			return emitJSON(newConsoleBootReport(path, st))
+		}
+		printBootStatus(os.Stdout, st)
+		return nil
+	}
+	if !logsFlags.follow {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		_, err = io.Copy(os.Stdout, f)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+	return followConsoleLog(ctx, path, os.Stdout, term.IsTerminal(int(os.Stdout.Fd())))
+}
+
+// followConsoleLog prints the console log at path and tails it until ctx is
+// canceled or the current boot turns healthy. On a terminal the boot summary
+// is kept as a status line under the log; otherwise each change of summary
+// is printed as a line of its own.
+func followConsoleLog(ctx context.Context, path string, out io.Writer, tty bool) error {
+	// Print what is already there, tracking the status of the boot it ends
+	// with, then tail from exactly where that read stopped.
+	var st boot.Status
+	f := &consoleFollower{out: out, tty: tty}
+	size, err := boot.ScanLines(path, func(line string) {
+		if boot.NewBoot(line) {
+			st = boot.Status{}
+		}
+		st.Observe(line)
+		f.line(line)
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	f.status(st)
+	if st.Healthy() {
+		return f.done(st)
+	}
+
+	for ev := range boot.WatchEvents(ctx, path, boot.WatchOptions{StartAt: size}) {
+		if boot.NewBoot(ev.Line) {
+			st = boot.Status{}
+		}
+		st.Observe(ev.Line)
+		f.line(ev.Line)
+		f.status(st)
+		if st.Healthy() {
+			return f.done(st)
+		}
+	}
+	return f.done(st)
+}
+
+// consoleFollower writes followed console lines while keeping the boot
+// summary in view.
+type consoleFollower struct {
+	out     io.Writer
+	tty     bool
+	summary string // last summary shown
+	footer  bool   // the status line is on screen
+}
+
+const clearLine = "\r\033[K"
+
+func (f *consoleFollower) line(s string) {
+	f.clearFooter()
+	_, _ = fmt.Fprintln(f.out, s)
+	f.drawFooter()
+}
+
+// status shows st's summary when it changed since the last call.
+func (f *consoleFollower) status(st boot.Status) {
+	sum := st.Summary()
+	if sum == f.summary {
+		return
+	}
+	f.summary = sum
+	if !f.tty {
+		_, _ = fmt.Fprintln(f.out, subtle("-- boot: "+sum))
+		return
+	}
+	f.clearFooter()
+	f.drawFooter()
+}
+
+// done replaces the status line with the final summary.
+func (f *consoleFollower) done(st boot.Status) error {
+	f.clearFooter()
+	if st.Healthy() {
+		_, _ = fmt.Fprintf(f.out, "%s Boot %s\n", success("✓"), st.Summary())
+		_, _ = fmt.Fprintf(f.out, "  %s\n", subtle(formatTimeline(st.Timeline())))
+	} else if f.tty {
+		_, _ = fmt.Fprintln(f.out, subtle("boot: "+st.Summary()))
+	}
+	return nil
+}
+
+func (f *consoleFollower) drawFooter() {
+	if !f.tty || f.summary == "" {
+		return
+	}
+	_, _ = fmt.Fprint(f.out, subtle("boot: "+f.summary))
+	f.footer = true
+}
+
+func (f *consoleFollower) clearFooter() {
+	if f.footer {
+		_, _ = fmt.Fprint(f.out, clearLine)
+		f.footer = false
+	}
+}
+
+// printBootStatus renders `br logs --boot`.
+func printBootStatus(out io.Writer, st boot.Status) {
+	mark := func(ok bool) string {
+		if ok {
+			return success("✓")
+		}
+		return subtle("-")
+	}
+	_, _ = fmt.Fprintf(out, "%s %s\n", key("Boot:"), value(st.Summary()))
+	if phases := st.Timeline(); len(phases) > 0 {
+		_, _ = fmt.Fprintf(out, "%s %s\n", key("Timeline:"), formatTimeline(phases))
+	}
+	rows := []struct {
+		label string
+		ok    bool
+	}{
+		{"Kernel booted", st.KernelBooted},
+		{"systemd reached", st.SystemdReached},
+		{"cloud-init done", st.CloudInitDone},
+		{"sshd ready", st.SSHReady},
+		{"Incus ready", st.IncusReady},
+		{"Login prompt", st.LoginPrompt},
+	}
+	for _, r := range rows {
+		_, _ = fmt.Fprintf(out, "  %s %s\n", mark(r.ok), r.label)
+	}
+	for _, bad := range []struct {
+		label string
+		on    bool
+	}{
+		{"cloud-init failed", st.CloudInitFailed},
+		{"kernel panic", st.KernelPanic},
+		{"emergency mode", st.EmergencyMode},
+		{diskFullHint, st.DiskFull},
+	} {
+		if bad.on {
+			_, _ = fmt.Fprintf(out, "  %s %s\n", errorf("✗"), bad.label)
+		}
+	}
+	if len(st.Errors) > 0 {
+		_, _ = fmt.Fprintf(out, "%s\n", key("Errors:"))
+		for _, e := range st.Errors {
+			_, _ = fmt.Fprintf(out, "  %s\n", warning(e.String()))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/boot"
+)
+
+const healthyBoot = "[    0.0] Linux version 6.x\n" +
+	"sshd[1]: Server listening on :: port 22\n" +
+	"Started incus.service - Incus - Main daemon\n"
+
+func TestFollowConsoleLogReturnsForHealthyBoot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.log")
+	if err := os.WriteFile(path, []byte(healthyBoot), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var out bytes.Buffer
+	if err := followConsoleLog(ctx, path, &out, false); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("follow waited for the timeout instead of stopping at a healthy boot")
+	}
+	if got := out.String(); !strings.Contains(got, "Linux version") || !strings.Contains(got, "Boot healthy") {
+		t.Errorf("output = %q, want the log and a healthy summary", got)
+	}
+}
+
+func TestFollowConsoleLogWaitsForCurrentBoot(t *testing.T) {
+	// The previous run was healthy, the current one has only just booted.
+	path := filepath.Join(t.TempDir(), "console.log")
+	if err := os.WriteFile(path, []byte(healthyBoot+"[    0.0] Linux version 6.x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	var out bytes.Buffer
+	go func() { done <- followConsoleLog(ctx, path, &out, false) }()
+
+	select {
+	case <-done:
+		t.Fatal("follow stopped on the previous boot's health")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString("sshd[1]: Server listening on :: port 22\nStarted incus.service - Incus - Main daemon\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-ctx.Done():
+		t.Fatal("follow did not stop once the current boot turned healthy")
+	}
+	if got := out.String(); !strings.Contains(got, "-- boot: kernel booting") || !strings.Contains(got, "Boot healthy") {
+		t.Errorf("output = %q, want the status progression", got)
+	}
+}
+
+func TestFollowConsoleLogStopsOnCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.log")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- followConsoleLog(ctx, path, &bytes.Buffer{}, false) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("follow after cancel = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("follow ignored cancellation")
+	}
+}
+
+func TestFormatTimeline(t *testing.T) {
+	got := formatTimeline([]boot.Phase{
+		{Name: "kernel", At: 0},
+		{Name: "cloud-init", At: 48120 * time.Millisecond},
+		{Name: "incus", At: 71 * time.Second},
+	})
+	if want := "kernel: 0s, cloud-init: 48s, incus: 1m11s"; got != want {
+		t.Errorf("formatTimeline = %q, want %q", got, want)
+	}
+}
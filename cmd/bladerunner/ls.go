@@ -11,6 +11,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var lsFlags struct {
+	name string
+}
+
 var lsCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List Incus instances",
@@ -19,8 +23,12 @@ var lsCmd = &cobra.Command{
 	RunE:  runLs,
 }
 
+func init() {
+	lsCmd.Flags().StringVar(&lsFlags.name, "name", "", nameFlagUsage)
+}
+
 func runLs(_ *cobra.Command, _ []string) error {
-	client, err := connectIncus()
+	client, err := connectIncus(lsFlags.name)
 	if err != nil {
 		if jsonOutput {
 			emitJSONError(err)
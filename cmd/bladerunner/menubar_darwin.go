@@ -341,6 +341,9 @@ func vmHealth() vmState {
 		return vmWedged
 	case control.StatusStopped:
 		return vmStopped
+	case control.StatusPaused:
+		// Deliberately frozen, not wedged: amber without a wedged notification.
+		return vmUnknown
 	default:
 		return vmUnknown
 	}
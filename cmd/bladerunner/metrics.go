@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/control"
+)
+
+var metricsFlags struct {
+	name string
+}
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Show control-plane command counts and latencies",
+	Long: `Show how often each control command has been called on the running instance
+since it started, how many failed, and how long its handler took (percentiles
+are read off a latency histogram, so they are bucket upper bounds).
+
+Use it to spot a chatty client or a slow handler. With --http-control-addr the
+same numbers are served in the Prometheus format at GET /metrics.`,
+	Example: renderExamples(
+		example{Comment: "Show per-command counts and latencies", Args: "metrics"},
+		example{Comment: "Emit them as JSON", Args: "metrics --json"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runMetrics,
+}
+
+func init() {
+	metricsCmd.Flags().StringVar(&metricsFlags.name, "name", "", nameFlagUsage)
+}
+
+func runMetrics(_ *cobra.Command, _ []string) error {
+	vmDir, err := namedVMDir(metricsFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	client := control.NewClient(vmDir)
+	if !client.IsRunning() {
+		return jsonOrError(fmt.Errorf("VM is not running"))
+	}
+	snap, err := client.Metrics()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	if jsonOutput {
+		return emitJSON(snap)
+	}
+	fmt.Printf("%s %s\n\n", key("Since:"), value(snap.Since.Local().Format(time.DateTime)))
+	return renderMetricsTable(os.Stdout, snap.Commands)
+}
+
+func renderMetricsTable(out io.Writer, commands []control.CommandMetrics) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "COMMAND\tCOUNT\tERRORS\tP50\tP90\tP99\tMAX"); err != nil {
+		return err
+	}
+	for _, c := range commands {
+		if _, err := fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\n",
+			c.Command, c.Count, c.Errors, fmtMs(c.P50Ms), fmtMs(c.P90Ms), fmtMs(c.P99Ms), fmtMs(c.MaxMs)); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// fmtMs renders a millisecond latency at a precision that suits its size.
+func fmtMs(ms float64) string {
+	if ms < 1 {
+		return fmt.Sprintf("%.3fms", ms)
+	}
+	return fmt.Sprintf("%.1fms", ms)
+}
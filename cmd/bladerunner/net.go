@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+var netCmd = &cobra.Command{
+	Use:   "net",
+	Short: "Discover host networking for the VM",
+	Args:  cobra.NoArgs,
+}
+
+var netInterfacesCmd = &cobra.Command{
+	Use:   "interfaces",
+	Short: "List the host interfaces a bridged VM can use",
+	Long: `List the host interfaces Virtualization.framework can bridge a VM onto,
+as the name --bridge takes and, in parentheses, the display name it also
+accepts. Pass one, a comma-separated list tried in order, or "auto" to
+'br start --network bridged --bridge'.`,
+	Example: renderExamples(
+		example{Args: "net interfaces"},
+		example{Comment: "Bridge onto one of them", Args: "start --network bridged --bridge en0"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runNetInterfaces,
+}
+
+func init() {
+	netCmd.AddCommand(netInterfacesCmd)
+}
+
+func runNetInterfaces(_ *cobra.Command, _ []string) error {
+	ifaces := vm.ListBridgeInterfaces()
+	if jsonOutput {
+		return emitJSON(ifaces)
+	}
+	if len(ifaces) == 0 {
+		fmt.Println(warning("No host interface can be bridged (bridged networking needs the VM networking entitlement)"))
+		return nil
+	}
+	for _, name := range ifaces {
+		fmt.Println(name)
+	}
+	return nil
+}
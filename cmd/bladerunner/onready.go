@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+)
+
+// onReadyOutputLimit bounds how much of the hook's output is logged.
+const onReadyOutputLimit = 4 << 10
+
+// onReadyEnv describes the ready VM to the on-ready hook, on top of the
+// runner's own environment.
+func onReadyEnv(cfg *config.Config) []string {
+	api := fmt.Sprintf("127.0.0.1:%d", cfg.LocalAPIPort)
+	return append(os.Environ(),
+		"BR_NAME="+cfg.Name,
+		"BR_STATE_DIR="+cfg.StateDir,
+		"BR_API_ENDPOINT=https://"+api,
+		"BR_DASHBOARD_URL=https://"+api+cfg.DashboardPath,
+		"BR_CLIENT_CERT="+cfg.ClientCertPath,
+		"BR_CLIENT_KEY="+cfg.ClientKeyPath,
+		fmt.Sprintf("BR_SSH_PORT=%d", cfg.LocalSSHPort),
+		"BR_SSH_USER="+cfg.SSHUser,
+		"BR_SSH_KEY="+cfg.SSHPrivateKeyPath,
+		"BR_SSH_CONFIG="+cfg.SSHConfigPath,
+		"BR_REPORT="+cfg.ReportPath,
+	)
+}
+
+// runOnReadyCommand runs cfg.OnReadyCommand through sh once the guest is
+// ready, with onReadyEnv set. Its output goes to the log; a failure is only a
+// warning, since the VM itself is fine. ctx ends the command with the VM.
+func runOnReadyCommand(ctx context.Context, cfg *config.Config, env []string) error {
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, "sh", "-c", cfg.OnReadyCommand)
+	cmd.Env = env
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	logging.L().Info("running on-ready command", "command", cfg.OnReadyCommand)
+	err := cmd.Run()
+
+	output := strings.TrimSpace(out.String())
+	if len(output) > onReadyOutputLimit {
+		output = output[:onReadyOutputLimit] + "…"
+	}
+	elapsed := time.Since(start).Round(time.Millisecond).String()
+	if err != nil {
+		logging.L().Warn("on-ready command failed", "command", cfg.OnReadyCommand, "err", err, "elapsed", elapsed, "output", output)
+		return err
+	}
+	logging.L().Info("on-ready command finished", "command", cfg.OnReadyCommand, "elapsed", elapsed, "output", output)
+	return nil
+}
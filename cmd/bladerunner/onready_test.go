@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+// The hook runs through sh with the VM described in BR_* env vars, so a
+// command like 'open $BR_DASHBOARD_URL' expands as written.
+func TestRunOnReadyCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	cfg.OnReadyCommand = `printf '%s %s' "$BR_DASHBOARD_URL" "$BR_SSH_PORT" >` + out
+
+	if err := runOnReadyCommand(context.Background(), cfg, onReadyEnv(cfg)); err != nil {
+		t.Fatalf("runOnReadyCommand: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://127.0.0.1:" + strconv.Itoa(cfg.LocalAPIPort) + cfg.DashboardPath + " " + strconv.Itoa(cfg.LocalSSHPort)
+	if string(b) != want {
+		t.Errorf("hook saw %q, want %q", b, want)
+	}
+
+	cfg.OnReadyCommand = "echo nope >&2; exit 3"
+	if err := runOnReadyCommand(context.Background(), cfg, onReadyEnv(cfg)); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("failing hook: got %v, want exit status 3", err)
+	}
+}
+
+// --on-ready is carried onto the config.
+func TestApplyFlagOverridesOnReady(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.onReady = "open $BR_DASHBOARD_URL"
+		applyFlagOverrides(cfg, changedSet("on-ready"), false)
+	})
+	if cfg.OnReadyCommand != "open $BR_DASHBOARD_URL" {
+		t.Errorf("OnReadyCommand = %q", cfg.OnReadyCommand)
+	}
+}
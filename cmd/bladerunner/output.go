@@ -11,6 +11,32 @@ import (
 // so that agents and scripts can consume them.
 var jsonOutput bool
 
+// outputFormat is the global --output/-o flag: "json" is the long form of
+// --json, and "text" (the default) keeps the human-formatted output. It writes
+// straight through to jsonOutput, so commands only ever check that.
+type outputFormat struct{}
+
+func (outputFormat) String() string {
+	if jsonOutput {
+		return "json"
+	}
+	return "text"
+}
+
+func (outputFormat) Set(s string) error {
+	switch s {
+	case "json":
+		jsonOutput = true
+	case "text":
+		jsonOutput = false
+	default:
+		return fmt.Errorf("unknown output format %q (want json or text)", s)
+	}
+	return nil
+}
+
+func (outputFormat) Type() string { return "format" }
+
 // jsonFieldStatus is the common "status" key used across command JSON results.
 const jsonFieldStatus = "status"
 
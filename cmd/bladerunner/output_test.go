@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestOutputFormatFlag(t *testing.T) {
+	t.Cleanup(func() { jsonOutput = false })
+
+	var f outputFormat
+	if err := f.Set("json"); err != nil || !jsonOutput || f.String() != "json" {
+		t.Errorf("Set(json): err=%v jsonOutput=%v String=%q", err, jsonOutput, f.String())
+	}
+	if err := f.Set("text"); err != nil || jsonOutput || f.String() != "text" {
+		t.Errorf("Set(text): err=%v jsonOutput=%v String=%q", err, jsonOutput, f.String())
+	}
+	if err := f.Set("yaml"); err == nil {
+		t.Error("Set(yaml) accepted an unknown format")
+	}
+}
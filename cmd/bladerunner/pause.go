@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/control"
+)
+
+// vmActionFlags are shared by pause, resume and reboot.
+var vmActionFlags struct {
+	name string
+}
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Freeze the running VM in place",
+	Long: `Freeze the guest's vCPUs. The VM keeps its memory and the host listeners stay
+bound, but nothing in the guest runs until 'br resume'. Nothing is written to
+disk; for a snapshot that survives the host process, use 'br save'.
+
+While paused, 'br status' reports "paused".`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return runVMAction("pause", "Paused", control.StatusPaused, (*control.Client).Pause)
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Continue a paused VM",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return runVMAction("resume", "Resumed", control.StatusRunning, (*control.Client).Resume)
+	},
+}
+
+var rebootCmd = &cobra.Command{
+	Use:   "reboot",
+	Short: "Reboot the guest without restarting the VM",
+	Long: `Ask the guest to reboot itself. The host process, its forwards and the
+control server stay up; the guest comes back on the same disk. The request
+is sent over SSH, so a wedged or paused guest can't be rebooted this way —
+use 'br stop --force' and 'br start' instead.`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return runVMAction("reboot", "Reboot requested", "", (*control.Client).Reboot)
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{pauseCmd, resumeCmd, rebootCmd} {
+		c.Flags().StringVar(&vmActionFlags.name, "name", "", nameFlagUsage)
+	}
+}
+
+// vmActionResult is the JSON payload emitted by `br pause|resume|reboot --json`.
+type vmActionResult struct {
+	Action string `json:"action"`
+	Status string `json:"status"`
+	// Unchanged is set when the VM was already in the state the action
+	// would put it in, so nothing was sent.
+	Unchanged bool `json:"unchanged,omitempty"`
+}
+
+// runVMAction sends one lifecycle command to the running VM and reports the
+// status it settles in. When the VM already reports settled (e.g. pausing a
+// paused VM) nothing is sent and the action is reported as a no-op.
+func runVMAction(action, done, settled string, send func(*control.Client) error) error {
+	vmDir, err := namedVMDir(vmActionFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	client := control.NewClient(vmDir)
+	if !client.IsRunning() {
+		return jsonOrError(fmt.Errorf("VM is not running"))
+	}
+	if settled != "" {
+		if status, err := client.GetStatus(); err == nil && status == settled {
+			if jsonOutput {
+				return emitJSON(vmActionResult{Action: action, Status: status, Unchanged: true})
+			}
+			fmt.Printf("%s VM is already %s\n", success("✓"), status)
+			return nil
+		}
+	}
+	if err := send(client); err != nil {
+		return jsonOrError(err)
+	}
+
+	status, err := client.GetStatus()
+	if err != nil {
+		return jsonOrError(fmt.Errorf("get status: %w", err))
+	}
+	if jsonOutput {
+		return emitJSON(vmActionResult{Action: action, Status: status})
+	}
+	fmt.Printf("%s %s %s\n", success("✓"), done, subtle("(status: "+status+")"))
+	return nil
+}
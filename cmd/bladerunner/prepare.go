@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/ssh"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+var prepareFlags struct {
+	stateDir string
+	name     string
+}
+
+var prepareCmd = &cobra.Command{
+	Use:   "prepare",
+	Short: "Build the VM's disk and cloud-init artifacts without booting",
+	Long: `Run the provisioning half of 'br start' and stop before the VM is created:
+ensure the client TLS credentials, write the cloud-init seed and build its ISO,
+fetch or verify the base image, and create the main disk. The produced paths
+are printed (or emitted with --json).
+
+A later 'br start' reuses the base image and disk. Use this to pre-stage a VM,
+or in CI to validate provisioning artifacts without booting a guest. Your saved
+settings apply; 'br start' flags do not.`,
+	Example: renderExamples(
+		example{Comment: "Stage the default VM's artifacts", Args: "prepare"},
+		example{Comment: "Report the artifact paths as JSON", Args: "prepare --json"},
+		example{Comment: "Stage a named VM for a later 'br start --name build'", Args: "prepare --name build"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runPrepare,
+}
+
+func init() {
+	prepareCmd.Flags().StringVar(&prepareFlags.stateDir, "state-dir", "", "State directory (default: ~/.local/state/bladerunner)")
+	prepareCmd.Flags().StringVar(&prepareFlags.name, "name", "", nameFlagUsage)
+}
+
+func runPrepare(cmd *cobra.Command, _ []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, err := config.Default(prepareFlags.stateDir, prepareFlags.name)
+	if err != nil {
+		return jsonOrError(fmt.Errorf("config: %w", err))
+	}
+	if cmd.Flags().Changed("state-dir") {
+		cfg.SetStateDirSource(config.SourceFlag)
+	}
+	// The running VM owns its disk and seed ISO; rebuilding them underneath it
+	// would corrupt the guest.
+	if control.NewClient(cfg.VMDir).IsRunning() {
+		return jsonOrError(fmt.Errorf("VM is running (use 'br stop' first)"))
+	}
+
+	// Same layering as `br start` minus the flags: saved settings, then saved
+	// `br config set` values, over defaults, so the staged disk and image match
+	// what a plain start uses.
+	settings, settingsErr := config.LoadSettings(config.DefaultStateDir())
+	if settingsErr != nil {
+		settings = config.DefaultSettings()
+	}
+	beforeSettings := *cfg
+	settings.ApplyTo(cfg)
+	cfg.MarkChanged(&beforeSettings, config.SourceSettings)
+	saved, savedErr := config.Load(config.ConfigPath(cfg.VMDir))
+	if savedErr == nil {
+		cfg.ApplySaved(saved)
+	}
+
+	cfg.NormalizeBaseImage()
+	if err := cfg.ValidateBaseImage(); err != nil {
+		return jsonOrError(err)
+	}
+	if err := cfg.LoadIncusProfile(); err != nil {
+		return jsonOrError(err)
+	}
+	if err := vm.EnsureVMDir(cfg); err != nil {
+		return jsonOrError(err)
+	}
+
+	if err := logging.Init(cfg.LogPath, logRotation(cfg), logging.Format(cfg.LogFormat)); err != nil {
+		return jsonOrError(err)
+	}
+	if settingsErr != nil {
+		logging.L().Warn("ignoring invalid settings; using defaults", "err", settingsErr)
+	}
+	if savedErr != nil {
+		logging.L().Warn("ignoring saved config", "err", savedErr)
+	}
+
+	// The seed authorizes the host's SSH key, so it has to exist first.
+	keyPair, err := ssh.EnsureKeyPair()
+	if err != nil {
+		return jsonOrError(fmt.Errorf("ssh keys: %w", err))
+	}
+	cfg.SetSSHKeys(keyPair.PublicKey, keyPair.PrivateKeyPath)
+
+	artifacts, err := vm.Prepare(ctx, cfg)
+	if err != nil {
+		return jsonOrError(fmt.Errorf("prepare: %w", err))
+	}
+
+	if jsonOutput {
+		return emitJSON(artifacts)
+	}
+
+	disk := "reused"
+	if artifacts.DiskCreated {
+		disk = "created"
+	}
+	fmt.Printf("%s VM artifacts ready\n", success("✓"))
+	fmt.Printf("  %s %s\n", key("Client cert:"), value(artifacts.ClientCertPath))
+	fmt.Printf("  %s %s\n", key("Client key:"), value(artifacts.ClientKeyPath))
+	fmt.Printf("  %s %s\n", key("User data:"), value(artifacts.UserDataPath))
+	fmt.Printf("  %s %s\n", key("Meta data:"), value(artifacts.MetaDataPath))
+	fmt.Printf("  %s %s\n", key("Cloud-init ISO:"), value(artifacts.CloudInitISO))
+	fmt.Printf("  %s %s\n", key("Base image:"), value(artifacts.BaseImagePath))
+	fmt.Printf("  %s %s %s\n", key("Disk:"), value(artifacts.DiskPath), subtle("("+disk+")"))
+	fmt.Printf("\nBoot it with %s\n", command(withName("br start", prepareFlags.name)))
+	return nil
+}
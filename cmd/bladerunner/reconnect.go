@@ -15,6 +15,10 @@ const guestExecTimeout = 20 * time.Second
 // that require root.
 const sudoCmd = "sudo"
 
+var reconnectFlags struct {
+	name string
+}
+
 var reconnectCmd = &cobra.Command{
 	Use:   "reconnect",
 	Short: "Nudge the guest clock back into sync after a host sleep, without restarting",
@@ -33,8 +37,12 @@ Restart).`,
 	RunE: runReconnect,
 }
 
+func init() {
+	reconnectCmd.Flags().StringVar(&reconnectFlags.name, "name", "", nameFlagUsage)
+}
+
 func runReconnect(_ *cobra.Command, _ []string) error {
-	configPath, err := sshConfigFromControl()
+	configPath, err := sshConfigFromControl(reconnectFlags.name)
 	if err != nil {
 		if jsonOutput {
 			emitJSONError(err)
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/report"
+)
+
+var reportFlags struct {
+	name   string
+	format string
+}
+
+// Report formats for `br report --format`.
+const (
+	reportFormatText     = "text"
+	reportFormatMarkdown = "markdown"
+	reportFormatJSON     = "json"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Show the startup report from the last boot",
+	Long: `Print the startup report 'br start' saved when the VM last came up: how to
+reach it over SSH and the API, the client certificate paths, the Incus
+version, and any boot problems. --format markdown renders it as tables to
+paste into a bug report; --format json (or --json) prints the saved report as
+is.`,
+	Example: renderExamples(
+		example{Args: "report"},
+		example{Comment: "Environment details for a bug report", Args: "report --format markdown"},
+	),
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return runReport()
+	},
+}
+
+func init() {
+	reportCmd.Flags().StringVar(&reportFlags.name, "name", "", nameFlagUsage)
+	reportCmd.Flags().StringVar(&reportFlags.format, "format", reportFormatText, "Output format: text, markdown, or json")
+}
+
+func runReport() error {
+	format := reportFlags.format
+	if jsonOutput {
+		format = reportFormatJSON
+	}
+	switch format {
+	case reportFormatText, reportFormatMarkdown, reportFormatJSON:
+	default:
+		return jsonOrError(fmt.Errorf("unknown report format %q (want text, markdown, or json)", format))
+	}
+
+	cfg, err := config.Default("", reportFlags.name)
+	if err != nil {
+		return jsonOrError(fmt.Errorf("load defaults: %w", err))
+	}
+	rep, err := report.LoadJSON(cfg.ReportPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return jsonOrError(fmt.Errorf("no startup report yet; one is written when 'br start' finishes booting"))
+	}
+	if err != nil {
+		return jsonOrError(err)
+	}
+	switch format {
+	case reportFormatJSON:
+		return emitJSON(rep)
+	case reportFormatMarkdown:
+		fmt.Print(report.RenderMarkdown(rep))
+	default:
+		fmt.Println(title("Bladerunner Startup Report"))
+		fmt.Print(report.RenderText(rep))
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/stuffbucket/bladerunner/internal/config"
@@ -24,16 +25,21 @@ var resetFlags struct {
 	full    bool
 	all     bool
 	confirm bool
+	name    string
 }
 
 func init() {
 	resetCmd.Flags().BoolVar(&resetFlags.full, "full", false, "Also remove the base image")
 	resetCmd.Flags().BoolVar(&resetFlags.all, "all", false, "Remove everything (complete reset)")
 	resetCmd.Flags().BoolVarP(&resetFlags.confirm, "yes", "y", false, "Skip confirmation prompt")
+	resetCmd.Flags().StringVar(&resetFlags.name, "name", "", nameFlagUsage)
 }
 
 func runReset(_ *cobra.Command, _ []string) error {
-	stateDir := config.DefaultStateDir()
+	stateDir, err := namedVMDir(resetFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
 
 	if _, err := os.Stat(stateDir); os.IsNotExist(err) {
 		if jsonOutput {
@@ -108,6 +114,7 @@ func resetFileList(full, all bool) ([]string, string) {
 		"cloud-init.iso",
 		"cloud-init/user-data",
 		"cloud-init/meta-data",
+		"effective-config.json",
 		"console.log",
 		"startup-report.json",
 		"runtime-metadata.json",
@@ -204,6 +211,31 @@ type resetResult struct {
 	DirectoryGone bool     `json:"directory_gone,omitempty"`
 }
 
+// replaceFileList is the baseline reset plus the saved guest state, which
+// describes the disk being replaced and could not be restored onto a new one.
+func replaceFileList(cfg *config.Config) []string {
+	files, _ := resetFileList(false, false)
+	return append(files, filepath.Base(cfg.SavedStatePath))
+}
+
+// replaceVM performs the baseline reset for `br start --replace`, so the start
+// that follows provisions a fresh guest with the existing keys, certificates
+// and base image.
+func replaceVM(cfg *config.Config) error {
+	existing := existingResetFiles(cfg.VMDir, replaceFileList(cfg))
+	if len(existing) == 0 {
+		return nil
+	}
+	outcome := applyReset(cfg.VMDir, existing, false)
+	if len(outcome.failed) > 0 {
+		return fmt.Errorf("replace VM: could not remove %s from %s", strings.Join(outcome.failed, ", "), cfg.VMDir)
+	}
+	if !jsonOutput {
+		fmt.Printf("Replacing VM: removed %d files from %s\n", len(outcome.removed), cfg.VMDir)
+	}
+	return nil
+}
+
 // confirmReset prompts the user for confirmation.
 // Returns true if user confirms, false otherwise.
 func confirmReset() bool {
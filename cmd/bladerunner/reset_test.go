@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+func TestReplaceVMKeepsIdentity(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := cfg.VMDir
+	if err := os.MkdirAll(filepath.Join(dir, "cloud-init"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gone := []string{"disk.raw", "efi-vars.bin", "cloud-init/user-data", "console.log", filepath.Base(cfg.SavedStatePath)}
+	kept := []string{"base-image.raw", "client.crt", "client.key"}
+	for _, f := range append(append([]string{}, gone...), kept...) {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := replaceVM(cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range gone {
+		if _, err := os.Stat(filepath.Join(dir, f)); !os.IsNotExist(err) {
+			t.Errorf("%s survived --replace", f)
+		}
+	}
+	for _, f := range kept {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Errorf("%s was removed: %v", f, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cloud-init")); !os.IsNotExist(err) {
+		t.Error("empty cloud-init dir not pruned")
+	}
+
+	// A second replace on an already-baseline VM is a no-op.
+	if err := replaceVM(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+// restartExitGrace is how long restart waits, once the control socket is
+// gone, for the old host process to exit and release its forwarded ports.
+const restartExitGrace = 15 * time.Second
+
+var restartFlags struct {
+	stopTimeout int
+	name        string
+	daemon      bool
+}
+
+var restartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Stop the running VM and start it again",
+	Long: `Stop the running VM gracefully, wait until its host process has exited and
+released the control socket and forwarded ports, then start it again.
+
+The new start takes its settings the way a plain 'br start' does: the
+defaults, Settings, and anything saved with 'br config set'. Flags passed to
+the original 'br start' are not kept. If no VM is running, restart just
+starts one.`,
+	Example: renderExamples(
+		example{Args: "restart"},
+		example{Comment: "Bounce a named VM and leave it running in the background", Args: "restart --name dev --daemon"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runRestart,
+}
+
+func init() {
+	f := restartCmd.Flags()
+	// Not --timeout: runStart reads this command's flags, where that name is
+	// start's Incus wait.
+	f.IntVar(&restartFlags.stopTimeout, "stop-timeout", config.DefaultStopTimeout, "Seconds to wait for graceful shutdown")
+	f.StringVar(&restartFlags.name, "name", "", nameFlagUsage)
+	f.BoolVar(&restartFlags.daemon, "daemon", false, "Run the restarted VM in the background, as 'br start --daemon' does")
+}
+
+func runRestart(cmd *cobra.Command, args []string) error {
+	startFlags.name = restartFlags.name
+	startFlags.daemon = restartFlags.daemon
+	// The background child of `restart --daemon` re-runs this command; the
+	// parent already stopped the old VM.
+	if isDaemonChild() {
+		return runStart(cmd, args)
+	}
+
+	vmDir, err := namedVMDir(restartFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = stopForRestart(ctx, vmDir, time.Duration(restartFlags.stopTimeout)*time.Second)
+	stopSignals()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	return runStart(cmd, args)
+}
+
+// stopForRestart stops the VM in vmDir, if one is running, and returns once
+// its host process is gone, so the start that follows can bind the same
+// socket and ports.
+func stopForRestart(ctx context.Context, vmDir string, timeout time.Duration) error {
+	client := control.NewClient(vmDir)
+	if !client.IsRunning() {
+		if !jsonOutput {
+			fmt.Println(subtle("VM is not running; starting it"))
+		}
+		return nil
+	}
+	hostPID := readHostPID(client)
+	if hostPID == 0 {
+		hostPID = readPIDFile(vmDir)
+	}
+	vm.ClearStopResult(vmDir)
+
+	if !jsonOutput {
+		fmt.Println("Stopping VM (sending graceful shutdown signal)...")
+	}
+	if err := client.StopVM(); err != nil {
+		return err
+	}
+	if !waitForSocketGoneContext(ctx, control.SocketPath(vmDir), timeout) {
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted; the VM may still be shutting down (check with 'br status')")
+		}
+		return fmt.Errorf("timeout waiting for VM to stop (use 'br stop --force', then 'br start')")
+	}
+	// The socket closes before the host finishes tearing down; its
+	// forwarders hold their ports until it exits.
+	if hostPID > 0 && !waitForProcessGone(hostPID, restartExitGrace) {
+		return fmt.Errorf("VM stopped, but host process %d is still running after %s", hostPID, restartExitGrace)
+	}
+	if !jsonOutput {
+		_, msg := stopOutcome(vm.ReadStopResult(vmDir))
+		fmt.Println(msg)
+	}
+	return nil
+}
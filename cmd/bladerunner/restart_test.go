@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestRestartCmdWiring verifies br restart is a Lifecycle command and keeps
+// clear of start's flag names that runStart would read as overrides.
+func TestRestartCmdWiring(t *testing.T) {
+	if restartCmd.GroupID != groupLifecycle {
+		t.Errorf("restartCmd.GroupID = %q, want %q", restartCmd.GroupID, groupLifecycle)
+	}
+	if f := restartCmd.Flags().Lookup("timeout"); f != nil {
+		t.Error("restart defines --timeout, which runStart would take as start's Incus wait")
+	}
+	for _, name := range []string{"stop-timeout", "name", "daemon"} {
+		if restartCmd.Flags().Lookup(name) == nil {
+			t.Errorf("restart is missing --%s", name)
+		}
+	}
+}
+
+// TestStopForRestartNotRunning verifies a restart with no VM running skips
+// the stop and goes straight to the start.
+func TestStopForRestartNotRunning(t *testing.T) {
+	if err := stopForRestart(context.Background(), t.TempDir(), time.Second); err != nil {
+		t.Fatalf("stopForRestart with no VM = %v, want nil", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 var restoreFlags struct {
 	path string
+	name string
 }
 
 var restoreCmd = &cobra.Command{
@@ -30,26 +31,28 @@ running.`,
 
 func init() {
 	restoreCmd.Flags().StringVar(&restoreFlags.path, "path", "", "Saved-state file (default: <state-dir>/saved-state.bin)")
+	restoreCmd.Flags().StringVar(&restoreFlags.name, "name", "", nameFlagUsage)
 }
 
 func runRestore(cmd *cobra.Command, args []string) error {
-	if control.NewClient(config.DefaultStateDir()).IsRunning() {
+	cfg, err := config.Default(startFlags.stateDir, restoreFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	if control.NewClient(cfg.VMDir).IsRunning() {
 		return jsonOrError(fmt.Errorf("VM is already running; stop it first ('br stop') before restoring"))
 	}
 
 	path := restoreFlags.path
 	if path == "" {
-		cfg, err := config.Default(startFlags.stateDir)
-		if err != nil {
-			return jsonOrError(err)
-		}
 		path = cfg.SavedStatePath
 	}
 	if _, err := os.Stat(path); err != nil {
 		return jsonOrError(fmt.Errorf("saved state not found at %s (run 'br save' first): %w", path, err))
 	}
 
-	// Hand off to the start flow in restore mode.
+	// Hand off to the start flow in restore mode, for the same VM.
 	startFlags.restoreFrom = path
+	startFlags.name = restoreFlags.name
 	return runStart(cmd, args)
 }
@@ -50,6 +50,7 @@ func init() {
 
 	// Global --json flag: commands emit machine-readable JSON for agents.
 	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output in JSON format (for scripting/agents)")
+	rootCmd.PersistentFlags().VarP(outputFormat{}, "output", "o", "Output format: text or json (-o json is the same as --json)")
 
 	// Titled command buckets for `br --help`. Order here is the display order.
 	rootCmd.AddGroup(
@@ -71,20 +72,20 @@ func init() {
 	}
 
 	addToGroup(groupLifecycle,
-		upCmd, startCmd, stopCmd, bootCmd, ejectCmd,
-		saveCmd, restoreCmd, resetCmd, upgradeCmd, selfUpdateCmd, reconnectCmd,
+		upCmd, startCmd, prepareCmd, stopCmd, restartCmd, pauseCmd, resumeCmd, rebootCmd, bootCmd, ejectCmd,
+		saveCmd, restoreCmd, backupCmd, rollbackCmd, snapshotCmd, exportCmd, importCmd, resetCmd, cleanCmd, upgradeCmd, selfUpdateCmd, reconnectCmd,
 	)
 	addToGroup(groupAccess,
-		sshCmd, shellCmd, execCmd, incusCmd, lsCmd, logsCmd, eventsCmd,
+		sshCmd, shellCmd, execCmd, incusCmd, trustCmd, lsCmd, logsCmd, eventsCmd, watchCmd, forwardCmd,
 	)
 	addToGroup(groupMedia,
-		diskCmd, disksCmd,
+		diskCmd, disksCmd, imagesCmd, netCmd,
 	)
 	addToGroup(groupUI,
 		webCmd, menubarCmd,
 	)
 	addToGroup(groupConfig,
-		statusCmd, configCmd, userCmd, noticeCmd,
+		statusCmd, guestStatusCmd, doctorCmd, reportCmd, listCmd, configCmd, inspectCmd, metricsCmd, logLevelCmd, userCmd, noticeCmd,
 	)
 
 	// With groups defined, the built-in help/completion commands would otherwise
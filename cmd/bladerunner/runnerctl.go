@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+// errVMNotStarted answers lifecycle commands that arrive before StartVM has
+// created the runner.
+var errVMNotStarted = errors.New("VM is not started yet")
+
+// runnerController is the control.Controller, and control.Lifecycle, for the
+// VM this process runs. Stop and the guest-liveness probe come from the
+// embedded LocalController: stopping unblocks runStart, whose deferred
+// runner.Stop shuts the VM down. Pause, resume and reboot act on the runner
+// once StartVM has created it; getRunner returns nil until then.
+type runnerController struct {
+	*control.LocalController
+	getRunner func() *vm.Runner
+}
+
+func newRunnerController(stop func(), getRunner func() *vm.Runner) *runnerController {
+	return &runnerController{LocalController: control.NewLocalController(stop), getRunner: getRunner}
+}
+
+// Status implements control.Controller. A paused guest can't answer the
+// liveness probe, so it is reported as paused rather than unreachable.
+func (c *runnerController) Status(ctx context.Context) (string, error) {
+	if !c.IsStopped() {
+		if r := c.getRunner(); r != nil && r.Paused() {
+			return control.StatusPaused, nil
+		}
+	}
+	return c.LocalController.Status(ctx)
+}
+
+// Pause implements control.Lifecycle.
+func (c *runnerController) Pause(context.Context) error {
+	r, err := c.runner()
+	if err != nil {
+		return err
+	}
+	return r.PauseVM()
+}
+
+// Resume implements control.Lifecycle.
+func (c *runnerController) Resume(context.Context) error {
+	r, err := c.runner()
+	if err != nil {
+		return err
+	}
+	return r.ResumeVM()
+}
+
+// Reboot implements control.Lifecycle.
+func (c *runnerController) Reboot(ctx context.Context) error {
+	r, err := c.runner()
+	if err != nil {
+		return err
+	}
+	return r.Reboot(ctx)
+}
+
+func (c *runnerController) runner() (*vm.Runner, error) {
+	if c.IsStopped() {
+		return nil, errors.New("VM is stopping")
+	}
+	r := c.getRunner()
+	if r == nil {
+		return nil, errVMNotStarted
+	}
+	return r, nil
+}
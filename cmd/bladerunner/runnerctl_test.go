@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+func TestRunnerControllerBeforeStart(t *testing.T) {
+	stopped := false
+	c := newRunnerController(func() { stopped = true }, func() *vm.Runner { return nil })
+	ctx := context.Background()
+
+	for name, fn := range map[string]func(context.Context) error{
+		"pause": c.Pause, "resume": c.Resume, "reboot": c.Reboot,
+	} {
+		if err := fn(ctx); !errors.Is(err, errVMNotStarted) {
+			t.Errorf("%s before start = %v, want errVMNotStarted", name, err)
+		}
+	}
+	if st, err := c.Status(ctx); err != nil || st != control.StatusRunning {
+		t.Errorf("Status = %q, %v; want %q", st, err, control.StatusRunning)
+	}
+
+	if err := c.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if !stopped {
+		t.Error("Stop did not call the stop func")
+	}
+	if st, _ := c.Status(ctx); st != control.StatusStopped {
+		t.Errorf("Status after Stop = %q, want %q", st, control.StatusStopped)
+	}
+	if err := c.Pause(ctx); err == nil {
+		t.Error("Pause after Stop succeeded")
+	}
+}
@@ -5,13 +5,13 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/stuffbucket/bladerunner/internal/config"
 	"github.com/stuffbucket/bladerunner/internal/control"
 	"github.com/stuffbucket/bladerunner/internal/vm"
 )
 
 var saveFlags struct {
 	path string
+	name string
 }
 
 var saveCmd = &cobra.Command{
@@ -29,10 +29,15 @@ configurations are eligible.`,
 
 func init() {
 	saveCmd.Flags().StringVar(&saveFlags.path, "path", "", "Destination file (default: <state-dir>/saved-state.bin)")
+	saveCmd.Flags().StringVar(&saveFlags.name, "name", "", nameFlagUsage)
 }
 
 func runSave(_ *cobra.Command, _ []string) error {
-	client := control.NewClient(config.DefaultStateDir())
+	vmDir, err := namedVMDir(saveFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	client := control.NewClient(vmDir)
 	if !client.IsRunning() {
 		return jsonOrError(fmt.Errorf("VM is not running"))
 	}
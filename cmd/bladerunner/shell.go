@@ -8,9 +8,11 @@ import (
 )
 
 var shellCmd = &cobra.Command{
-	Use:                "shell [-- command...]",
-	Short:              "Open an interactive shell in the VM",
-	Long:               `Open an interactive shell in the running Bladerunner VM. Any arguments after -- are run as a command.`,
+	Use:   "shell [--name vm] [-- command...]",
+	Short: "Open an interactive shell in the VM",
+	Long: `Open an interactive shell in the running Bladerunner VM. Any arguments after -- are run as a command.
+
+A leading --name picks a VM started with 'br start --name'.`,
 	DisableFlagParsing: true,
 	RunE:               runShell,
 }
@@ -20,7 +22,8 @@ func runShell(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Parse args: [-- command...]
+	// Parse args: [--name vm] [-- command...]
+	name, args := leadingNameArg(args)
 	var shellArgs []string
 	for i, arg := range args {
 		if arg == "--" {
@@ -32,7 +35,7 @@ func runShell(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	configPath, err := sshConfigFromControl()
+	configPath, err := sshConfigFromControl(name)
 	if err != nil {
 		return err
 	}
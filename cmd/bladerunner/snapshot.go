@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+var snapshotFlags struct {
+	name    string
+	confirm bool
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Checkpoint and restore the VM's disk",
+	Long: `Copy the stopped VM's disk.raw to <vm-dir>/snapshots/<name>.raw, e.g. to
+checkpoint a configured Incus setup before experimenting, and put it back
+later. The copy is sparse, so it takes only as much space as the disk uses.
+
+Unlike 'br backup' a snapshot is named and holds only the disk, not the EFI
+variables or machine identity. The VM must be stopped to create or restore
+one.`,
+	Example: renderExamples(
+		example{Comment: "Checkpoint the disk before an experiment", Args: "snapshot create clean-incus"},
+		example{Comment: "See what snapshots exist", Args: "snapshot list"},
+		example{Comment: "Go back to the checkpoint", Args: "snapshot restore clean-incus"},
+	),
+	Args: cobra.NoArgs,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Snapshot the stopped VM's disk",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSnapshotCreate,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List snapshots with their sizes",
+	Args:    cobra.NoArgs,
+	RunE:    runSnapshotList,
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Replace the stopped VM's disk with a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSnapshotRestore,
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotCreateCmd, snapshotListCmd, snapshotRestoreCmd)
+	snapshotCmd.PersistentFlags().StringVar(&snapshotFlags.name, "name", "", nameFlagUsage)
+	snapshotRestoreCmd.Flags().BoolVarP(&snapshotFlags.confirm, "yes", "y", false, "Skip confirmation prompt")
+}
+
+// snapshotResult is the JSON payload for `br snapshot create` and
+// `br snapshot restore`.
+type snapshotResult struct {
+	Status   string          `json:"status"` // "created" | "restored"
+	Snapshot vm.SnapshotInfo `json:"snapshot"`
+}
+
+// snapshotConfig returns the config of the VM picked by --name.
+func snapshotConfig() (*config.Config, error) {
+	return config.Default(config.DefaultStateDir(), snapshotFlags.name)
+}
+
+func runSnapshotCreate(_ *cobra.Command, args []string) error {
+	cfg, err := snapshotConfig()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	s, err := vm.CreateSnapshot(cfg, args[0])
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	if jsonOutput {
+		return emitJSON(snapshotResult{Status: "created", Snapshot: s})
+	}
+	fmt.Printf("%s Snapshot %s created (%s disk)\n", success("✓"), value(s.Name), logging.HumanBytes(s.Size))
+	fmt.Printf("  Restore with: %s\n", command("br snapshot restore "+s.Name))
+	return nil
+}
+
+func runSnapshotList(_ *cobra.Command, _ []string) error {
+	cfg, err := snapshotConfig()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	snapshots, err := vm.ListSnapshots(cfg)
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	if jsonOutput {
+		return emitJSON(snapshots)
+	}
+	if len(snapshots) == 0 {
+		fmt.Printf("No snapshots in %s\n", vm.SnapshotDir(cfg))
+		return nil
+	}
+	for _, s := range snapshots {
+		fmt.Printf("  %s  %s  %s\n", value(s.Name), subtle(s.Created.Local().Format(time.DateTime)), logging.HumanBytes(s.Size))
+	}
+	return nil
+}
+
+func runSnapshotRestore(_ *cobra.Command, args []string) error {
+	cfg, err := snapshotConfig()
+	if err != nil {
+		return jsonOrError(err)
+	}
+	name := args[0]
+
+	if !snapshotFlags.confirm {
+		if jsonOutput {
+			return jsonOrError(fmt.Errorf("snapshot restore requires --yes when --json is set (cannot prompt for confirmation)"))
+		}
+		fmt.Printf("Restore %s from snapshot %s?\n", value(cfg.DiskPath), value(name))
+		fmt.Println("The current disk will be overwritten.")
+		if !confirmReset() {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	s, err := vm.RestoreSnapshot(cfg, name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+
+	if jsonOutput {
+		return emitJSON(snapshotResult{Status: "restored", Snapshot: s})
+	}
+	fmt.Printf("%s Restored disk from snapshot %s\n", success("✓"), value(s.Name))
+	fmt.Printf("  Start the VM with: %s\n", command("br start"))
+	return nil
+}
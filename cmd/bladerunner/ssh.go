@@ -1,10 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/control"
 )
 
 // sshHostAlias is the SSH host alias written into the generated ssh config
@@ -29,15 +39,53 @@ func sshArgv(configPath string, opts []string, tail ...string) (sshPath string,
 	return sshPath, argv, nil
 }
 
+const (
+	// sshWaitDefault is the --wait timeout when the flag is given bare.
+	sshWaitDefault = 2 * time.Minute
+	// sshWaitInterval paces the readiness polls.
+	sshWaitInterval = 500 * time.Millisecond
+	// sshProbeTimeout bounds one connect-and-read-banner attempt.
+	sshProbeTimeout = 3 * time.Second
+)
+
+var sshFlags struct {
+	wait time.Duration
+	name string
+}
+
 var sshCmd = &cobra.Command{
-	Use:   "ssh",
+	Use:   "ssh [--wait[=timeout]] [-- command...]",
 	Short: "Show SSH connection details",
-	Long:  `Display the SSH command and configuration needed to connect to the Bladerunner VM.`,
-	RunE:  runSSH,
+	Long: `Display the SSH command and configuration needed to connect to the Bladerunner VM.
+
+With --wait, block until the guest's SSH server answers on the forwarded port
+(which can be well before Incus is ready), then open an SSH session, running
+the command after -- if one is given. This avoids the "connection refused"
+race right after 'br start --daemon'.`,
+	Example: renderExamples(
+		example{Comment: "Print the ssh command", Args: "ssh"},
+		example{Comment: "Print it for the VM started with 'br start --name build'", Args: "ssh --name build"},
+		example{Comment: "Wait up to the default 2m for SSH, then log in", Args: "ssh --wait"},
+		example{Comment: "Wait up to 5m, then run one command", Args: "ssh --wait=5m -- uname -a"},
+	),
+	RunE: runSSH,
+}
+
+func init() {
+	sshCmd.Flags().DurationVar(&sshFlags.wait, "wait", 0, "Wait up to this long for SSH to accept connections, then connect (bare --wait means "+sshWaitDefault.String()+")")
+	sshCmd.Flags().Lookup("wait").NoOptDefVal = sshWaitDefault.String()
+	sshCmd.Flags().StringVar(&sshFlags.name, "name", "", nameFlagUsage)
 }
 
-func runSSH(_ *cobra.Command, _ []string) error {
-	configPath, err := sshConfigFromControl()
+func runSSH(cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed("wait") {
+		return runSSHWait(sshFlags.name, sshFlags.wait, args)
+	}
+	if len(args) > 0 {
+		return errors.New("a command to run needs --wait; use 'br shell -- <command>' otherwise")
+	}
+
+	configPath, err := sshConfigFromControl(sshFlags.name)
 	if err != nil {
 		if jsonOutput {
 			emitJSONError(err)
@@ -56,3 +104,115 @@ func runSSH(_ *cobra.Command, _ []string) error {
 	fmt.Printf("ssh -F %s %s\n", configPath, sshHostAlias)
 	return nil
 }
+
+// runSSHWait waits for the sshd of the VM called name, then replaces this process with an
+// ssh session like `br shell`. It does not start the VM: it is meant to follow
+// a `br start` (often --daemon) that may still be booting.
+func runSSHWait(name string, timeout time.Duration, args []string) error {
+	if err := rejectJSONForInteractive("ssh --wait"); err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("--wait timeout must be positive, got %s", timeout)
+	}
+	vmDir, err := namedVMDir(name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client := control.NewClient(vmDir)
+
+	fmt.Fprintf(os.Stderr, "%s Waiting up to %s for SSH…\n", subtle("›"), timeout)
+	var configPath string
+	err = waitUntil(ctx, sshWaitInterval, func(ctx context.Context) error {
+		path, addr, err := sshEndpointFromControl(client)
+		if err != nil {
+			return err
+		}
+		if err := probeSSHBanner(ctx, addr); err != nil {
+			return err
+		}
+		configPath = path
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("SSH not ready within %s: %w", timeout, err)
+	}
+	fmt.Fprintln(os.Stderr, success("✓ SSH ready"))
+
+	sshPath, argv, err := sshArgv(configPath, []string{"-t"}, args...)
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(sshPath, argv, os.Environ())
+}
+
+// sshEndpointFromControl returns the SSH config path and forwarded SSH address
+// once the VM host has published them and its guest liveness probe (a vsock
+// dial to the guest's sshd, see `br status`) passes.
+func sshEndpointFromControl(client *control.Client) (configPath, addr string, err error) {
+	if !client.IsRunning() {
+		return "", "", errVMNotRunning
+	}
+	// Hosts that predate status.json skip straight to the banner probe.
+	if info, err := client.GetStatusInfo(); err == nil && info.State != control.StatusRunning {
+		return "", "", fmt.Errorf("guest is %s", info.State)
+	}
+	configPath, err = client.GetConfig(control.ConfigKeySSHConfigPath)
+	if err != nil || configPath == "" {
+		return "", "", errors.New("VM has not published its SSH config yet")
+	}
+	port, err := client.GetConfig(control.ConfigKeyLocalSSHPort)
+	if err != nil {
+		return "", "", fmt.Errorf("get ssh port: %w", err)
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return "", "", fmt.Errorf("bad ssh port %q", port)
+	}
+	return configPath, net.JoinHostPort("127.0.0.1", port), nil
+}
+
+// probeSSHBanner connects to addr and reads the server's identification line.
+// A bare TCP connect is not enough: the host-side forwarder accepts before the
+// guest's sshd is up and only then finds nothing to relay to.
+func probeSSHBanner(ctx context.Context, addr string) error {
+	ctx, cancel := context.WithTimeout(ctx, sshProbeTimeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetReadDeadline(deadline)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("read ssh banner: %w", err)
+	}
+	if !strings.HasPrefix(line, "SSH-") {
+		return fmt.Errorf("unexpected ssh banner %q", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+// waitUntil calls check every interval until it succeeds or ctx ends, and
+// then returns the last check error.
+func waitUntil(ctx context.Context, interval time.Duration, check func(context.Context) error) error {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-tick.C:
+		}
+	}
+}
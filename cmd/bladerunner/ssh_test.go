@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net"
 	"os/exec"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSSHArgv(t *testing.T) {
@@ -52,3 +57,66 @@ func TestSSHArgv(t *testing.T) {
 		})
 	}
 }
+
+// serveOnce accepts connections on a loopback listener and hands each to
+// handle, returning the listener address.
+func serveOnce(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			handle(c)
+			_ = c.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestProbeSSHBanner(t *testing.T) {
+	ctx := context.Background()
+
+	sshd := serveOnce(t, func(c net.Conn) { _, _ = c.Write([]byte("SSH-2.0-OpenSSH_9.9\r\n")) })
+	if err := probeSSHBanner(ctx, sshd); err != nil {
+		t.Errorf("probe against sshd: %v", err)
+	}
+
+	// The forwarder accepts and then hangs up when the guest side is not up.
+	forwarderOnly := serveOnce(t, func(net.Conn) {})
+	if err := probeSSHBanner(ctx, forwarderOnly); err == nil {
+		t.Error("probe should fail when the connection closes without a banner")
+	}
+
+	http := serveOnce(t, func(c net.Conn) { _, _ = c.Write([]byte("HTTP/1.1 400 Bad Request\r\n")) })
+	if err := probeSSHBanner(ctx, http); err == nil || !strings.Contains(err.Error(), "unexpected ssh banner") {
+		t.Errorf("probe against a non-ssh server = %v", err)
+	}
+}
+
+func TestWaitUntil(t *testing.T) {
+	calls := 0
+	err := waitUntil(context.Background(), time.Millisecond, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("waitUntil = %v after %d calls, want success on the 3rd", err, calls)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = waitUntil(ctx, time.Millisecond, func(context.Context) error { return errors.New("refused") })
+	if err == nil || err.Error() != "refused" {
+		t.Errorf("timed-out waitUntil = %v, want the last check error", err)
+	}
+}
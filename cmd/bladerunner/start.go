@@ -2,13 +2,15 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -20,6 +22,7 @@ import (
 	"github.com/stuffbucket/bladerunner/internal/control"
 	"github.com/stuffbucket/bladerunner/internal/logging"
 	"github.com/stuffbucket/bladerunner/internal/oidc"
+	"github.com/stuffbucket/bladerunner/internal/report"
 	"github.com/stuffbucket/bladerunner/internal/ssh"
 	"github.com/stuffbucket/bladerunner/internal/timesource"
 	"github.com/stuffbucket/bladerunner/internal/ui"
@@ -34,14 +37,47 @@ var startFlags struct {
 	memory      uint64
 	disk        int
 	gui         bool
+	noGUIInput  bool
 	stateDir    string
 	imageURL    string
 	imagePath   string
+	imageSHA256 string
 	hostedImage bool
 	debianImage bool
+	distro      string
 	timeout     time.Duration
+	dialRetries int
+	dialDelay   time.Duration
+	logMaxSize  int
+	logBackups  int
+	logFormat   string
 	noNested    bool
 	restoreFrom string
+	dns         []string
+	searchDoms  []string
+	refreshImg  bool
+	kernelCons  string
+	fastEntropy bool
+	noResize    bool
+	fbReboot    bool
+	onReady     string
+	daemon      bool
+	incusProf   string
+	httpAddr    string
+	network     string
+	bridge      string
+	replace     bool
+	require     []string
+	packages    []string
+	sshPort     int
+	apiPort     int
+	autoPort    bool
+	name        string
+	attach      []string
+	forward     []string
+	arch        string
+	idleTimeout time.Duration
+	macAddress  string
 }
 
 var startCmd = &cobra.Command{
@@ -58,13 +94,46 @@ func init() {
 	f.Uint64Var(&startFlags.memory, "memory", config.DefaultMemoryGiB, "Memory in GiB")
 	f.IntVar(&startFlags.disk, "disk", config.DefaultDiskSizeGiB, "Disk size in GiB")
 	f.BoolVar(&startFlags.gui, "gui", false, "Open GUI console window")
+	f.BoolVar(&startFlags.noGUIInput, "no-gui-input", false, "With --gui, attach no pointing or keyboard device: a view-only console for screen capture")
+	f.StringVar(&startFlags.name, "name", "", "Run a separate named VM with its own directory (<state-dir>/<name>), control socket and ports (letters, digits, '_' and '-')")
 	f.StringVar(&startFlags.stateDir, "state-dir", "", "State directory (default: ~/.local/state/bladerunner)")
+	f.StringVar(&startFlags.arch, "arch", "", "Guest architecture, arm64 or amd64 (default: the host's); must match the host, as the Virtualization framework cannot emulate another arch")
 	f.StringVar(&startFlags.imageURL, "image-url", "", "Base image URL")
 	f.StringVar(&startFlags.imagePath, "image-path", "", "Local base image path")
+	f.StringVar(&startFlags.imageSHA256, "image-sha256", "", "Expected SHA-256 of the downloaded base image; the start fails on a mismatch")
 	f.BoolVar(&startFlags.hostedImage, "hosted-image", false, "Force the pre-baked hosted guest image (guest-image-latest release); the default already resolves to it (also settable via BLADERUNNER_FORCE_HOSTED_IMAGE=1)")
 	f.BoolVar(&startFlags.debianImage, "debian-image", false, "Escape hatch: force the Debian Trixie genericcloud + cloud-init path instead of the pre-baked default (also settable via BLADERUNNER_FORCE_DEBIAN_IMAGE=1)")
+	f.StringVar(&startFlags.distro, "distro", "", "Boot a stock cloud image of this distro ("+strings.Join(config.Distros(), ", ")+") with cloud-init instead of the pre-baked default; see 'br images releases'")
 	f.DurationVar(&startFlags.timeout, "timeout", config.DefaultTimeout, "Wait timeout for Incus")
+	f.IntVar(&startFlags.dialRetries, "forward-dial-retries", config.DefaultForwarderDialRetries, "Times a port forward dials the guest for a new connection before giving up")
+	f.DurationVar(&startFlags.dialDelay, "forward-dial-delay", config.DefaultForwarderDialRetryDelay, "Delay between a port forward's guest dial attempts (raise this or --forward-dial-retries on hardware slow to start the guest relay)")
+	f.DurationVar(&startFlags.idleTimeout, "idle-timeout", 0, "Stop the VM after this long with no control commands and no port-forward traffic, e.g. 1h; an open forwarded connection such as an SSH session always counts as use; not with --network bridged (0: never)")
+	f.IntVar(&startFlags.logMaxSize, "log-max-size", config.DefaultLogMaxSizeMiB, "Size in MiB at which the host log rotates")
+	f.IntVar(&startFlags.logBackups, "log-max-backups", config.DefaultLogMaxBackups, "Rotated host log files to keep (compressed)")
+	f.StringVar(&startFlags.logFormat, "log-format", "", "Host log format: text or json, for log pipelines (default: $"+config.LogFormatEnvVar+", else text)")
 	f.BoolVar(&startFlags.noNested, "no-nested-virt", false, "Disable nested virtualization even if the host supports it (Incus VMs will be unavailable)")
+	f.StringVar(&startFlags.network, "network", "", "Network mode: shared (NAT) or bridged (default: from settings, else shared)")
+	f.StringVar(&startFlags.bridge, "bridge", "", "Host interface for --network bridged: a name (en0), \"auto\" for the primary active interface, or a comma-separated list tried in order")
+	f.StringVar(&startFlags.macAddress, "mac-address", "", "Guest NIC MAC address, e.g. 52:54:00:12:34:56, for a DHCP reservation on a bridged network; kept for later starts (default: generated at first start)")
+	f.StringSliceVar(&startFlags.dns, "dns", nil, "Guest DNS server address (repeatable or comma-separated); overrides the DHCP/NAT resolver")
+	f.StringSliceVar(&startFlags.searchDoms, "search-domain", nil, "Guest resolver search domain (repeatable or comma-separated)")
+	f.BoolVar(&startFlags.refreshImg, "refresh-image", false, "Re-download and re-verify the base image instead of using the cached copy (applies to newly created disks; combine with 'br reset')")
+	f.StringVar(&startFlags.kernelCons, "kernel-console", config.DefaultKernelConsole, "Guest kernel console args, e.g. \"console=hvc0,115200n8 console=tty0\" (applied when a new disk is provisioned)")
+	f.BoolVar(&startFlags.fastEntropy, "fast-entropy", false, "Seed the guest's entropy pool from the host and run rngd, for kernels that stall on first-boot key generation (applied when a new disk is provisioned)")
+	f.BoolVar(&startFlags.fbReboot, "first-boot-reboot", false, "Apply the kernel console args with one cloud-init power_state reboot at the end of the first boot, instead of an early reboot from bootcmd (applied when a new disk is provisioned)")
+	f.BoolVar(&startFlags.noResize, "no-resize", false, "Use the base image as the disk at its own size instead of growing it to --disk, skipping qemu-img (applied when a new disk is created)")
+	f.StringSliceVar(&startFlags.require, "require", nil, "Command the guest must have once Incus is ready, e.g. incus,jq (repeatable or comma-separated); the start reports failure naming any that are missing")
+	f.IntVar(&startFlags.sshPort, "ssh-port", 0, "Host port for the localhost SSH forward (default: 6022, offset per --name)")
+	f.IntVar(&startFlags.apiPort, "api-port", 0, "Host port for the localhost Incus API forward (default: 18443, offset per --name)")
+	f.BoolVar(&startFlags.autoPort, "auto-port", false, "Move the ssh and Incus API forwards to the next free host port when theirs is taken, instead of failing")
+	f.StringSliceVar(&startFlags.packages, "packages", nil, "Extra guest packages to install with apt or dnf, e.g. htop,git (repeatable or comma-separated; applied when a new disk is provisioned)")
+	f.StringArrayVar(&startFlags.attach, "attach", nil, "Attach a host image (data ISO, dataset, drivers) as an extra block device: path[:ro|:rw], read-only by default (repeatable; guest sees /dev/disk/by-id/virtio-extraN)")
+	f.StringArrayVar(&startFlags.forward, "forward", nil, "Forward a host port to a guest port, beyond the built-in ssh and incus-api forwards: [host:]localport[:tcp]:guestport, e.g. 8080:tcp:8080 (repeatable; the guest relay is installed when a new disk is provisioned)")
+	f.StringVar(&startFlags.incusProf, "incus-profile", "", "YAML Incus profile to apply inside the guest after Incus is initialised (applied when a new disk is provisioned)")
+	f.StringVar(&startFlags.onReady, "on-ready", "", "Host command (run via sh) once Incus is ready, e.g. 'open $BR_DASHBOARD_URL'; BR_API_ENDPOINT, BR_DASHBOARD_URL, BR_SSH_CONFIG and friends are set, output goes to the log, and a failure only warns")
+	f.StringVar(&startFlags.httpAddr, "http-control-addr", "", "Also serve the control API over HTTP on this localhost address (e.g. 127.0.0.1:8765); requests need the bearer token from <state-dir>/"+control.HTTPTokenName)
+	f.BoolVar(&startFlags.daemon, "daemon", false, "Run the VM in the background and return once it is starting (manage it with 'br status' / 'br stop'; output goes to the log)")
+	f.BoolVar(&startFlags.replace, "replace", false, "Recreate the guest from the base image before starting (as 'br reset' then 'br start'), keeping SSH keys, client certificates and the base image")
 	f.StringVar(&startFlags.restoreFrom, "restore", "", "Restore the guest from a saved-state file (see 'br save') instead of cold-booting")
 }
 
@@ -83,8 +152,9 @@ func nestedVirtBanner() string {
 }
 
 // registerUpgradeHandlers registers the control commands that back `runner
-// upgrade` and `br eject`: reporting the server's build version,
-// pausing+saving the guest state, and the clean ACPI shutdown. getRunner returns
+// upgrade`, `br eject` and `br stop --force`: reporting the server's build
+// version, pausing+saving the guest state, the clean ACPI shutdown, and the
+// forced stop. getRunner returns
 // the active runner once StartVM has created it (nil before). cancel unblocks the
 // foreground runStart so the process exits after a graceful eject — the deferred
 // runner.Stop() then tears the VMM down and the deferred cartridge detach (if
@@ -126,6 +196,18 @@ func registerUpgradeHandlers(router *control.Router, cfg *config.Config, getRunn
 		cancel()
 		return &control.Message{Response: control.RespOK}
 	})
+	router.HandleFunc(control.CmdKill, func(_ context.Context, _ *control.Request) *control.Message {
+		// Before StartVM has created the VM there is nothing to force down;
+		// just let the foreground exit. The deferred runner.Stop() then
+		// returns this forced stop's result.
+		if r := getRunner(); r != nil {
+			if _, err := r.ForceStop(); err != nil {
+				return &control.Message{Error: err.Error()}
+			}
+		}
+		cancel()
+		return &control.Message{Response: control.RespOK}
+	})
 }
 
 // ejectTimeoutFromArgs parses the positional timeout (seconds) from an eject
@@ -152,25 +234,148 @@ func ejectForceFromArgs(req *control.Request) bool {
 // persisted Settings baseline is not clobbered by cobra's flag defaults.
 func applyFlagOverrides(cfg *config.Config, changed func(string) bool, driven bool) {
 	apply := func(name string) bool { return driven || changed(name) }
+	// Only a flag the user actually passed is credited as the source; on a
+	// driven start an untouched flag just carries the manifest or default
+	// value through, which keeps whatever source that layer recorded.
+	fromFlag := func(name, key string) {
+		if changed(name) {
+			cfg.SetSource(key, config.SourceFlag)
+		}
+	}
 
+	if startFlags.name != "" && apply("name") {
+		cfg.Name = startFlags.name
+		fromFlag("name", control.ConfigKeyName)
+	}
 	if apply("cpus") {
 		cfg.CPUs = startFlags.cpus
+		fromFlag("cpus", control.ConfigKeyCPUs)
 	}
 	if apply("memory") {
 		cfg.MemoryGiB = startFlags.memory
+		fromFlag("memory", control.ConfigKeyMemoryGiB)
 	}
 	if apply("disk") {
 		cfg.DiskSizeGiB = startFlags.disk
+		fromFlag("disk", control.ConfigKeyDiskSizeGiB)
 	}
 	if apply("gui") {
 		cfg.GUI = startFlags.gui
+		fromFlag("gui", control.ConfigKeyGUI)
+	}
+	if apply("no-gui-input") {
+		cfg.GUIInput = !startFlags.noGUIInput
 	}
 	if apply("timeout") {
 		cfg.WaitForIncus = startFlags.timeout
 	}
+	if apply("forward-dial-retries") {
+		cfg.ForwarderDialRetries = startFlags.dialRetries
+	}
+	if apply("forward-dial-delay") {
+		cfg.ForwarderDialRetryDelay = startFlags.dialDelay
+	}
+	if apply("idle-timeout") {
+		cfg.IdleTimeout = startFlags.idleTimeout
+	}
+	if apply("log-max-size") {
+		cfg.LogMaxSizeMiB = startFlags.logMaxSize
+	}
+	if apply("log-max-backups") {
+		cfg.LogMaxBackups = startFlags.logBackups
+	}
+	// Only when given, so BLADERUNNER_LOG_FORMAT holds on a plain start.
+	if startFlags.logFormat != "" && apply("log-format") {
+		cfg.LogFormat = startFlags.logFormat
+	}
 	if apply("no-nested-virt") {
 		cfg.NestedVirtDisabled = startFlags.noNested
 	}
+	// Like the image flags, network flags only apply when given, so a
+	// persisted Settings network choice survives a plain start.
+	if startFlags.network != "" && apply("network") {
+		cfg.NetworkMode = startFlags.network
+		fromFlag("network", control.ConfigKeyNetworkMode)
+	}
+	if startFlags.bridge != "" && apply("bridge") {
+		cfg.BridgeInterface = startFlags.bridge
+	}
+	if startFlags.refreshImg && apply("refresh-image") {
+		cfg.RefreshBaseImage = true
+	}
+	// Like the image flags below, DNS only applies when given: a boot/cartridge
+	// start has no DNS of its own to carry.
+	if len(startFlags.dns) > 0 && apply("dns") {
+		cfg.DNS = startFlags.dns
+	}
+	if len(startFlags.searchDoms) > 0 && apply("search-domain") {
+		cfg.SearchDomains = startFlags.searchDoms
+	}
+	if startFlags.kernelCons != "" && apply("kernel-console") {
+		cfg.KernelConsole = startFlags.kernelCons
+	}
+	if startFlags.fastEntropy && apply("fast-entropy") {
+		cfg.FastEntropy = true
+	}
+	if startFlags.fbReboot && apply("first-boot-reboot") {
+		cfg.FirstBootReboot = true
+	}
+	if startFlags.noResize && apply("no-resize") {
+		cfg.NoResize = true
+	}
+	if len(startFlags.require) > 0 && apply("require") {
+		cfg.RequireCommands = startFlags.require
+	}
+	if startFlags.sshPort != 0 && apply("ssh-port") {
+		cfg.LocalSSHPort = startFlags.sshPort
+		fromFlag("ssh-port", control.ConfigKeyLocalSSHPort)
+	}
+	if startFlags.apiPort != 0 && apply("api-port") {
+		cfg.LocalAPIPort = startFlags.apiPort
+		fromFlag("api-port", control.ConfigKeyLocalAPIPort)
+	}
+	if startFlags.autoPort && apply("auto-port") {
+		cfg.AutoPort = true
+	}
+	if len(startFlags.packages) > 0 && apply("packages") {
+		cfg.ExtraPackages = startFlags.packages
+	}
+	// Specs were checked by runStart; the images themselves by Validate.
+	if len(startFlags.attach) > 0 && apply("attach") {
+		cfg.ExtraDisks = nil
+		for _, spec := range startFlags.attach {
+			if d, err := config.ParseExtraDisk(spec); err == nil {
+				cfg.ExtraDisks = append(cfg.ExtraDisks, d)
+			}
+		}
+	}
+	// Likewise for forwards; the set as a whole by Validate.
+	if len(startFlags.forward) > 0 && apply("forward") {
+		cfg.Forwards = nil
+		for _, spec := range startFlags.forward {
+			if f, err := config.ParseForward(spec); err == nil {
+				cfg.Forwards = append(cfg.Forwards, f)
+			}
+		}
+	}
+	if startFlags.incusProf != "" && apply("incus-profile") {
+		cfg.IncusProfilePath = startFlags.incusProf
+	}
+	if startFlags.onReady != "" && apply("on-ready") {
+		cfg.OnReadyCommand = startFlags.onReady
+	}
+	if startFlags.macAddress != "" && apply("mac-address") {
+		cfg.MACAddress = startFlags.macAddress
+	}
+	if startFlags.httpAddr != "" && apply("http-control-addr") {
+		cfg.HTTPControlAddr = startFlags.httpAddr
+	}
+	// Arch lands before the image flags: it re-points a built-in default image
+	// at the matching arch's build, which an explicit --image-url then overrides.
+	if startFlags.arch != "" && apply("arch") {
+		_ = cfg.SetArch(startFlags.arch) // validated up front in runStart
+		fromFlag("arch", control.ConfigKeyArch)
+	}
 	// Image flags keep their "non-empty means set" guard: a boot/cartridge start
 	// clears them (it carries the image via the manifest), and a plain start
 	// leaves them empty unless the user passed one.
@@ -179,9 +384,14 @@ func applyFlagOverrides(cfg *config.Config, changed func(string) bool, driven bo
 		// A custom image isn't the pinned Debian default, so the embedded
 		// SHA-512 no longer applies; fall back to sidecar verification.
 		cfg.BaseImageSHA512 = ""
+		fromFlag("image-url", control.ConfigKeyBaseImageURL)
+	}
+	if startFlags.imageSHA256 != "" && apply("image-sha256") {
+		cfg.BaseImageExpectedSHA256 = strings.ToLower(startFlags.imageSHA256) // validated up front in runStart
 	}
 	if startFlags.imagePath != "" && apply("image-path") {
 		cfg.BaseImagePath = startFlags.imagePath
+		fromFlag("image-path", control.ConfigKeyBaseImagePath)
 	}
 	// --hosted-image (or BLADERUNNER_FORCE_HOSTED_IMAGE=1) forces the pre-baked
 	// hosted guest image. Since the hosted image is now the DEFAULT, this mostly
@@ -193,6 +403,11 @@ func applyFlagOverrides(cfg *config.Config, changed func(string) bool, driven bo
 	// the Debian genericcloud + cloud-init path. Conflicts (both flags, or either
 	// with --image-url/--image-path) are rejected up front by
 	// validateImageOverrideFlags, so at most one force lands here.
+	beforeForce := *cfg
+	forceSource := config.SourceEnv
+	if startFlags.hostedImage || startFlags.debianImage || startFlags.distro != "" {
+		forceSource = config.SourceFlag
+	}
 	if forceHostedImage() {
 		if url, err := config.HostedGuestImageURL(cfg.Arch); err == nil {
 			cfg.BaseImageURL = url
@@ -209,6 +424,24 @@ func applyFlagOverrides(cfg *config.Config, changed func(string) bool, driven bo
 		// path directly. Best effort: an unsupported arch leaves the default URL.
 		_ = config.UseDebianImage(cfg)
 	}
+	if startFlags.distro != "" {
+		// Record the choice even when the arch has no image for it, so
+		// cfg.Validate rejects the start instead of booting the default.
+		cfg.Distro = startFlags.distro
+		_ = config.UseDistroImage(cfg, startFlags.distro)
+	}
+	cfg.MarkChanged(&beforeForce, forceSource)
+}
+
+// logRotation is how cfg's host log rotates: by size, with old files
+// compressed and dropped after two weeks whatever their count.
+func logRotation(cfg *config.Config) logging.RotateOptions {
+	return logging.RotateOptions{
+		MaxSize:    cfg.LogMaxSizeMiB,
+		MaxBackups: cfg.LogMaxBackups,
+		MaxAge:     14, // days
+		Compress:   true,
+	}
 }
 
 // forceHostedImage reports whether this run must use the pre-baked hosted guest
@@ -226,21 +459,40 @@ func forceDebianImage() bool {
 }
 
 // validateImageOverrideFlags rejects contradictory image-selection overrides.
-// --hosted-image (or its force env) selects the pre-baked hosted image and
-// --debian-image (or its force env) selects the Debian escape hatch, so the two
-// cannot be combined with each other or with an explicit --image-url/--image-path
-// (which pick a different, user-supplied image). Asking for two at once is a user
-// error, not something to resolve silently by precedence.
+// --hosted-image (or its force env) selects the pre-baked hosted image,
+// --debian-image (or its force env) selects the Debian escape hatch and
+// --distro a stock distro image, so none can be combined with another or with
+// an explicit --image-url/--image-path (which pick a different, user-supplied
+// image) or --image-sha256 (a digest the forced image would not match). Asking
+// for two at once is a user error, not something to resolve silently by
+// precedence. A malformed --image-sha256 or unknown --distro is rejected too.
 func validateImageOverrideFlags() error {
+	if s := startFlags.imageSHA256; s != "" {
+		if b, err := hex.DecodeString(s); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("--image-sha256 %q is not a hex SHA-256 digest", s)
+		}
+	}
+	if startFlags.distro != "" {
+		if err := config.ValidateDistro(startFlags.distro); err != nil {
+			return err
+		}
+	}
 	if forceHostedImage() && forceDebianImage() {
 		return fmt.Errorf("--hosted-image conflicts with --debian-image (also check BLADERUNNER_FORCE_HOSTED_IMAGE / BLADERUNNER_FORCE_DEBIAN_IMAGE)")
 	}
-	if !forceHostedImage() && !forceDebianImage() {
+	var which string
+	switch {
+	case forceHostedImage():
+		which = "--hosted-image"
+	case forceDebianImage():
+		which = "--debian-image"
+	case startFlags.distro != "":
+		which = "--distro"
+	default:
 		return nil
 	}
-	which := "--hosted-image"
-	if forceDebianImage() {
-		which = "--debian-image"
+	if which != "--distro" && startFlags.distro != "" {
+		return fmt.Errorf("%s conflicts with --distro", which)
 	}
 	if startFlags.imageURL != "" {
 		return fmt.Errorf("%s conflicts with --image-url", which)
@@ -248,6 +500,9 @@ func validateImageOverrideFlags() error {
 	if startFlags.imagePath != "" {
 		return fmt.Errorf("%s conflicts with --image-path", which)
 	}
+	if startFlags.imageSHA256 != "" {
+		return fmt.Errorf("%s conflicts with --image-sha256", which)
+	}
 	return nil
 }
 
@@ -260,6 +515,35 @@ func runStart(cmd *cobra.Command, args []string) error {
 	if err := validateImageOverrideFlags(); err != nil {
 		return err
 	}
+	// The name ends up in paths and SSH config; refuse a bad one before
+	// anything is created.
+	if startFlags.name != "" {
+		if err := config.ValidateName(startFlags.name); err != nil {
+			return err
+		}
+	}
+	for _, spec := range startFlags.attach {
+		if _, err := config.ParseExtraDisk(spec); err != nil {
+			return err
+		}
+	}
+	for _, spec := range startFlags.forward {
+		if _, err := config.ParseForward(spec); err != nil {
+			return err
+		}
+	}
+	// A foreign arch can't boot here; say so before an image is downloaded.
+	if startFlags.arch != "" {
+		if err := config.ValidateArch(startFlags.arch); err != nil {
+			return err
+		}
+		if err := vm.CheckGuestArch(startFlags.arch); err != nil {
+			return err
+		}
+	}
+	if startFlags.replace && startFlags.restoreFrom != "" {
+		return fmt.Errorf("--replace conflicts with --restore (a saved state belongs to the disk being replaced)")
+	}
 
 	// A cartridge boot OWNS the mounted image: detach it on the way out. This
 	// defer is registered first so, running LIFO, it executes LAST — after the
@@ -267,10 +551,19 @@ func runStart(cmd *cobra.Command, args []string) error {
 	defer detachBootCartridge()
 
 	// Build config
-	cfg, err := config.Default(startFlags.stateDir)
+	cfg, err := config.Default(startFlags.stateDir, startFlags.name)
 	if err != nil {
 		return fmt.Errorf("config: %w", err)
 	}
+	if startFlags.stateDir != "" {
+		// `br boot` picks a per-disk slot itself; only a user-passed
+		// --state-dir counts as a flag.
+		if cmd.Flags().Changed("state-dir") {
+			cfg.SetStateDirSource(config.SourceFlag)
+		} else {
+			cfg.SetStateDirSource(config.SourceManifest)
+		}
+	}
 
 	// Check if already running
 	client := control.NewClient(cfg.VMDir)
@@ -278,33 +571,69 @@ func runStart(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("VM is already running (use 'br stop' first)")
 	}
 
-	// Start control server. We build the controller explicitly (rather than
-	// via NewServer) so a guest-liveness probe can be attached once the VM is
-	// running — see runner.ProbeGuest below.
-	ctrl := control.NewLocalController(cancel)
-	ctrlServer, err := control.NewListener(cfg.VMDir, ctrl)
-	if err != nil {
-		return fmt.Errorf("start control server: %w", err)
+	// Replace in the foreground only: a daemon child re-runs with the same
+	// flags and would otherwise wipe the state a second time.
+	if startFlags.replace && !isDaemonChild() {
+		if err := replaceVM(cfg); err != nil {
+			return err
+		}
 	}
-	defer func() { _ = ctrlServer.Close() }()
 
-	// Mount config handler (captures cfg by reference; sees values set after VM start)
-	cfgHandler := control.NewConfigRouter(cfg)
-	ctrlServer.Router().Mount("config", cfgHandler.Router())
+	if startFlags.daemon && !isDaemonChild() {
+		return runStartDaemon(cfg)
+	}
 
-	// Synchronized holder so the save handler (registered before the server
-	// starts serving, to avoid a handlers-map race) can reach the runner once
-	// it exists.
+	// Synchronized holder so the control handlers (registered before the
+	// server starts serving, to avoid a handlers-map race) can reach the
+	// runner once it exists.
 	var (
 		runnerMu     sync.Mutex
 		activeRunner *vm.Runner
 	)
 	setRunner := func(r *vm.Runner) { runnerMu.Lock(); activeRunner = r; runnerMu.Unlock() }
-	registerUpgradeHandlers(ctrlServer.Router(), cfg, func() *vm.Runner {
+	getRunner := func() *vm.Runner {
 		runnerMu.Lock()
 		defer runnerMu.Unlock()
 		return activeRunner
-	}, cancel)
+	}
+
+	// The control socket and PID file below are the first writes into the VM
+	// dir; a read-only or full state dir should fail here with its cause.
+	if err := vm.EnsureVMDir(cfg); err != nil {
+		return err
+	}
+
+	// Start control server. The controller acts on the runner for pause,
+	// resume and reboot, and gets a guest-liveness probe once the VM is
+	// running — see runner.ProbeGuest below.
+	ctrl := newRunnerController(cancel, getRunner)
+	ctrlServer, err := control.NewListenerWithConfig(control.ListenerConfig{
+		StateDir:   cfg.VMDir,
+		SocketPath: cfg.ControlSocketPath,
+		Controller: ctrl,
+	})
+	if err != nil {
+		return fmt.Errorf("start control server: %w", err)
+	}
+	defer func() { _ = ctrlServer.Close() }()
+
+	removePIDFile, err := writePIDFile(cfg.VMDir)
+	if err != nil {
+		return err
+	}
+	defer removePIDFile()
+
+	// Mount config handler (captures cfg by reference; sees values set after VM start)
+	cfgHandler := control.NewConfigRouter(cfg)
+	ctrlServer.Router().Mount("config", cfgHandler.Router())
+
+	registerUpgradeHandlers(ctrlServer.Router(), cfg, getRunner, cancel)
+	ctrlServer.Router().Mount("forward", forwardRouter(getRunner))
+	ctrlServer.Router().HandleFunc(control.CmdStatusJSON, statusInfoHandler(ctrl, cfg, cfgHandler, getRunner))
+	ctrlServer.Router().HandleFunc(control.CmdUptime, uptimeHandler(getRunner))
+	ctrlServer.Router().HandleFunc(control.CmdStats, statsHandler(getRunner))
+	ctrlServer.Router().HandleFunc(control.CmdGuestStatus, guestStatusHandler(cfg, getRunner))
+	ctrlServer.Router().HandleFunc(control.CmdTrustRotate, trustRotateHandler(getRunner))
 
 	go ctrlServer.Start(ctx)
 
@@ -318,15 +647,32 @@ func runStart(cmd *cobra.Command, args []string) error {
 	if settingsErr != nil {
 		settings = config.DefaultSettings()
 	}
+	// The control server is already answering config.get/config.source, so
+	// layer under the config lock; each layer records the values it moved.
+	cfgHandler.Lock()
+	beforeSettings := *cfg
 	settings.ApplyTo(cfg)
+	cfg.MarkChanged(&beforeSettings, config.SourceSettings)
+
+	// Then the values `br config set` saved for this state dir: more specific
+	// than the host-wide Settings, still under the manifest and flags. An
+	// unreadable file is logged and ignored, like invalid settings.
+	savedPath := config.ConfigPath(cfg.VMDir)
+	saved, savedErr := config.Load(savedPath)
+	if savedErr == nil {
+		cfg.ApplySaved(saved)
+	}
 
 	// Apply a disk manifest (set by `br boot`) as defaults AFTER Settings but
 	// BEFORE the flag overrides below, so the manifest's image/sizing/boot-mode
 	// overrides saved Settings and explicit flags still win. No-op for a plain
 	// `br start`.
+	beforeManifest := *cfg
 	if err := applyBootManifest(cfg); err != nil {
+		cfgHandler.Unlock()
 		return err
 	}
+	cfg.MarkChanged(&beforeManifest, config.SourceManifest)
 
 	// Apply CLI flags. On a boot/cartridge-driven start the flags carry
 	// pre-resolved precedence (flag-or-manifest-or-default, incl. a --headless
@@ -339,15 +685,71 @@ func runStart(cmd *cobra.Command, args []string) error {
 	// A cartridge boot roots every per-VM path inside the mounted image and wires
 	// the RW share. This must land AFTER the manifest/flag overrides so the
 	// cartridge's own root.img / state / share win. No-op for a non-cartridge boot.
+	beforeCartridge := *cfg
 	applyBootCartridge(cfg)
+	cfg.MarkChanged(&beforeCartridge, config.SourceManifest)
+	cfgHandler.Unlock()
+	cfgHandler.SaveTo(savedPath)
+
+	// Reject a bad image URL or path, or a malformed Incus profile, now rather
+	// than after setup, deep in provisioning.
+	cfg.NormalizeBaseImage()
+	if err := cfg.ValidateBaseImage(); err != nil {
+		return err
+	}
+	if err := cfg.LoadIncusProfile(); err != nil {
+		return err
+	}
+	// Likewise a --bridge naming no host interface, which would otherwise
+	// only surface while the VM is being built.
+	if cfg.NetworkMode == config.NetworkModeBridged {
+		if err := vm.CheckBridgeInterfaces(cfg.BridgeCandidates()); err != nil {
+			return err
+		}
+	}
 
 	// Setup logging
-	if err := logging.Init(cfg.LogPath); err != nil {
+	if err := logging.Init(cfg.LogPath, logRotation(cfg), logging.Format(cfg.LogFormat)); err != nil {
 		return err
 	}
+	// A daemon child's stdout is daemonOutName, not a terminal; records sent
+	// there too would only duplicate the log.
+	if isDaemonChild() {
+		logging.SetQuiet(true)
+	}
 	if settingsErr != nil {
 		logging.L().Warn("ignoring invalid settings; using defaults", "err", settingsErr)
 	}
+	if savedErr != nil {
+		logging.L().Warn("ignoring saved config", "err", savedErr)
+	}
+
+	// With --auto-port, step past taken ssh/API ports before anything
+	// (the report, ssh config, forwarders) reads them.
+	cfgHandler.Lock()
+	moves, err := cfg.ResolveAutoPorts()
+	cfgHandler.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, m := range moves {
+		logging.L().Warn("port in use; using next free port", "key", m.Key, "from", m.From, "to", m.To)
+	}
+
+	// Optional REST mirror of the control socket, same router and handlers.
+	if cfg.HTTPControlAddr != "" {
+		token, err := control.EnsureHTTPToken(cfg.VMDir)
+		if err != nil {
+			return err
+		}
+		httpCtrl, err := control.NewHTTPServer(cfg.HTTPControlAddr, ctrlServer.Router(), token)
+		if err != nil {
+			return fmt.Errorf("start http control server: %w", err)
+		}
+		defer func() { _ = httpCtrl.Close() }()
+		go httpCtrl.Start()
+		logging.L().Info("http control api listening", "addr", httpCtrl.Addr(), "token_file", control.HTTPTokenPath(cfg.VMDir))
+	}
 
 	// Ensure SSH keys
 	keyPair, err := ssh.EnsureKeyPair()
@@ -390,7 +792,17 @@ func runStart(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("create runner: %w", err)
 	}
-	defer func() { _ = runner.Stop() }()
+	// Record how the VM went down for `br stop`, which only sees the control
+	// socket disappear.
+	defer func() {
+		res, err := runner.Stop()
+		if err != nil {
+			logging.L().Warn("vm stop reported an error", "err", err)
+		}
+		if err := vm.WriteStopResult(cfg.VMDir, res); err != nil {
+			logging.L().Warn("could not record the stop result", "err", err)
+		}
+	}()
 	setRunner(runner)
 
 	// --restore: bring the guest up from a saved-state file (and resume it)
@@ -404,7 +816,7 @@ func runStart(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  %s %s\n", key("Name:"), value(cfg.Name))
 		fmt.Printf("  %s %d\n", key("CPUs:"), cfg.CPUs)
 		fmt.Printf("  %s %d GiB\n", key("Memory:"), cfg.MemoryGiB)
-		fmt.Printf("  %s %s\n", key("Arch:"), value(runtime.GOARCH))
+		fmt.Printf("  %s %s\n", key("Arch:"), value(cfg.Arch))
 		fmt.Printf("  %s %s\n", key("Incus VMs:"), nestedVirtBanner())
 		fmt.Println()
 	}
@@ -462,8 +874,11 @@ func runStart(cmd *cobra.Command, args []string) error {
 	cfg.NestedVirt = runner.NestedVirtState()
 	cfgHandler.Unlock()
 
+	// memory-balloon-gib resizes the running guest from here on.
+	cfgHandler.SetMemoryBalloon(runner)
+
 	// Write SSH config after VM starts
-	sshConfigPath, err := ssh.WriteSSHConfig(cfg.LocalSSHPort, cfg.SSHUser, cfg.SSHPrivateKeyPath)
+	sshConfigPath, err := ssh.WriteSSHConfig(cfg.Instance(), cfg.LocalSSHPort, cfg.SSHUser, cfg.SSHPrivateKeyPath)
 	if err != nil {
 		logging.L().Warn("ssh config", "error", err)
 	} else {
@@ -491,14 +906,24 @@ func runStart(cmd *cobra.Command, args []string) error {
 		defer func() { _ = webProxy.Close() }()
 	}
 
+	// With an idle timeout, stop the VM through the same path as 'br stop'
+	// once it goes unused. Armed only after the Incus wait, so a long first
+	// boot, which sees no traffic, is not taken for idleness.
+	watchIdleVM := func() {
+		if cfg.IdleTimeout > 0 {
+			go watchIdle(ctx, cfg.IdleTimeout, ctrlServer.Router().Metrics().LastObserved, runner.Forwarders, func() { _ = ctrl.Stop(ctx) })
+		}
+	}
+
 	// In headless mode we block the foreground on Incus readiness so the
 	// board can render the full boot through to "ready" before yielding to
 	// the SIGINT wait. In GUI mode we tear the board down first because
 	// StartGUI takes over the macOS event loop and the user is watching
 	// the guest window, not the terminal.
-	// report emits the running summary as JSON or human text, depending on the
-	// --json flag, after tearing down the boot board.
-	report := func(bootErr error) {
+	// summarize emits the running summary as JSON or human text, depending on
+	// the --json flag, after tearing down the boot board. A startup report from
+	// this boot, when there is one, is printed in full ahead of the summary.
+	summarize := func(rep *report.StartupReport, bootErr error) {
 		if brd != nil {
 			brd.Stop()
 			tailCancel()
@@ -507,6 +932,11 @@ func runStart(cmd *cobra.Command, args []string) error {
 			_ = startReportJSON(cfg, result.Endpoint, bootErr)
 			return
 		}
+		if rep != nil {
+			fmt.Println()
+			fmt.Println(title("Startup Report"))
+			fmt.Print(report.RenderText(rep))
+		}
 		printRunningSummary(cfg, result.Endpoint, bootErr)
 	}
 
@@ -514,8 +944,12 @@ func runStart(cmd *cobra.Command, args []string) error {
 		// GUI mode can't block on Incus before opening the window — the
 		// macOS event loop must run on the main thread immediately. We
 		// don't yet know if boot will succeed, so don't claim it did.
-		report(nil)
-		go func() { _ = waitForGuestReady(ctx, cfg, runner) }()
+		summarize(nil, nil)
+		go func() {
+			_, _ = waitForGuestReady(ctx, cfg, runner)
+			watchIdleVM()
+			runner.RefreshReport(ctx, reportRefreshInterval)
+		}()
 
 		if !jsonOutput {
 			fmt.Println(subtle("Opening GUI window (runs on main thread)..."))
@@ -524,8 +958,13 @@ func runStart(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("start gui: %w", err)
 		}
 	} else {
-		bootErr := waitForGuestReady(ctx, cfg, runner)
-		report(bootErr)
+		rep, bootErr := waitForGuestReady(ctx, cfg, runner)
+		if rep == nil {
+			rep = savedReportSince(cfg.ReportPath, runner.StartedAt())
+		}
+		summarize(rep, bootErr)
+		watchIdleVM()
+		go runner.RefreshReport(ctx, reportRefreshInterval)
 		if !jsonOutput {
 			fmt.Println(subtle("Headless mode. Press Ctrl+C to stop."))
 		}
@@ -552,27 +991,57 @@ func startReportJSON(cfg *config.Config, endpoint string, bootErr error) error {
 		r[jsonFieldStatus] = "running-degraded"
 		r["boot_error"] = bootErr.Error()
 		r["console_log"] = cfg.ConsoleLogPath
+		var missing *vm.MissingCommandsError
+		if errors.As(bootErr, &missing) {
+			r["missing_commands"] = missing.Missing
+		}
 	}
 	return emitJSON(r)
 }
 
-// waitForGuestReady runs the Incus readiness wait. Returns nil if the guest
-// reached the Incus-ready state, or an error describing why it didn't. Errors
-// are non-fatal at the call site (partial reports are still useful) but the
-// caller should warn the user rather than pretend everything is fine.
-func waitForGuestReady(ctx context.Context, _ *config.Config, runner *vm.Runner) error {
-	if _, err := runner.WaitForIncus(ctx); err != nil {
+// waitForGuestReady runs the Incus readiness wait. It returns the startup
+// report, when one was assembled, and nil if the guest reached the
+// Incus-ready state, or an error describing why it didn't. Errors are
+// non-fatal at the call site (partial reports are still useful) but the caller
+// should warn the user rather than pretend everything is fine.
+func waitForGuestReady(ctx context.Context, cfg *config.Config, runner *vm.Runner) (*report.StartupReport, error) {
+	rep, err := runner.WaitForIncus(ctx)
+	if err != nil {
 		logging.L().Error("wait for incus", "error", err)
-		return err
+		return rep, err
 	}
-	return nil
+	if cfg.OnReadyCommand != "" {
+		// The env is captured now, while the report has just set the SSH
+		// config path, and the hook runs alongside the VM.
+		env := onReadyEnv(cfg)
+		go func() { _ = runOnReadyCommand(ctx, cfg, env) }()
+	}
+	return rep, nil
+}
+
+// savedReportSince loads the startup report at path if it was written at or
+// after since, i.e. by this run; a failed Incus wait saves a partial report
+// but does not return it. A report from an earlier run is ignored.
+func savedReportSince(path string, since time.Time) *report.StartupReport {
+	rep, err := report.LoadJSON(path)
+	if err != nil || rep.GeneratedAt.Before(since) {
+		return nil
+	}
+	return rep
 }
 
 func printRunningSummary(cfg *config.Config, endpoint string, bootErr error) {
 	fmt.Println()
-	if bootErr == nil {
+	var missing *vm.MissingCommandsError
+	switch {
+	case bootErr == nil:
 		fmt.Println(success("✓ VM is running"))
-	} else {
+	case errors.As(bootErr, &missing):
+		// The guest booted; it just lacks tools the start was told to require.
+		fmt.Println(warning("⚠ VM is running but provisioning is incomplete"))
+		fmt.Printf("  %s %s\n", key("Missing:"), value(strings.Join(missing.Missing, ", ")))
+		fmt.Printf("  %s %s\n", key("Report:"), value(cfg.ReportPath))
+	default:
 		fmt.Println(warning("⚠ VM is running but the guest did not finish booting"))
 		fmt.Printf("  %s %v\n", key("Reason:"), bootErr)
 		fmt.Printf("  %s %s\n", key("Console:"), value(cfg.ConsoleLogPath))
@@ -663,12 +1132,21 @@ func mapRunnerStage(s string) string {
 
 const consoleTailPollInterval = 250 * time.Millisecond
 
+// diskFullHint is the advice for a guest that ran out of disk space. --disk
+// only sizes a new disk, so the disk has to be recreated.
+const diskFullHint = "the guest disk is full; recreate it larger with 'br reset' then 'br start --disk <GiB>'"
+
+// reportRefreshInterval paces the running VM's startup-report refresh (see
+// vm.Runner.RefreshReport): often enough that a late Incus shows up within
+// half a minute, rarely enough that the probes cost nothing.
+const reportRefreshInterval = 30 * time.Second
+
 // tailConsoleIntoBoard streams the guest serial console into the board's
 // tail panel and advances the cloud-init / ssh stages from the parsed boot
 // status. The kernel-boot transition is implicit (it happens before
 // cloud-init starts running).
 func tailConsoleIntoBoard(ctx context.Context, b *board.Board, path string) {
-	var seenKernel, seenCIBegin, seenCIDone, seenCIFail, seenSSH bool
+	var seenKernel, seenCIBegin, seenCIDone, seenCIFail, seenDiskFull, seenSSH bool
 	for ev := range boot.WatchEvents(ctx, path, boot.WatchOptions{
 		PollInterval: consoleTailPollInterval,
 		FromEnd:      true,
@@ -681,6 +1159,12 @@ func tailConsoleIntoBoard(ctx context.Context, b *board.Board, path string) {
 			seenCIBegin = true
 			b.Begin(boardStageCloudInit)
 		}
+		// A full disk is the usual cause of a cloud-init failure, so it
+		// replaces the generic message whichever is seen first.
+		if ev.Status.DiskFull && !seenDiskFull {
+			seenDiskFull, seenCIFail = true, true
+			b.Fail(boardStageCloudInit, errors.New(diskFullHint))
+		}
 		if ev.Status.CloudInitFailed && !seenCIFail {
 			seenCIFail = true
 			b.Fail(boardStageCloudInit, fmt.Errorf("cloud-init reported failure (see console.log)"))
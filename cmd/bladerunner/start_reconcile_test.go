@@ -1,11 +1,14 @@
 package main
 
 import (
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
 )
 
 // changedSet returns a predicate reporting whether a flag name is in the set,
@@ -30,7 +33,7 @@ func withStartFlags(t *testing.T, f func()) {
 // On a plain `br start` with no flags changed, applyFlagOverrides must leave the
 // persisted Settings baseline intact (nothing is clobbered by flag defaults).
 func TestApplyFlagOverridesPlainNoChangeKeepsSettings(t *testing.T) {
-	cfg, err := config.Default(t.TempDir())
+	cfg, err := config.Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -67,7 +70,7 @@ func TestApplyFlagOverridesPlainNoChangeKeepsSettings(t *testing.T) {
 // A flag the user actually changed overrides the persisted Settings value, and
 // only that field changes.
 func TestApplyFlagOverridesPlainChangedWins(t *testing.T) {
-	cfg, err := config.Default(t.TempDir())
+	cfg, err := config.Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -94,7 +97,7 @@ func TestApplyFlagOverridesPlainChangedWins(t *testing.T) {
 // changed predicate, preserving the pre-resolved precedence (e.g. a --headless
 // override of a GUI manifest stuffed into startFlags.gui).
 func TestApplyFlagOverridesDrivenAppliesVerbatim(t *testing.T) {
-	cfg, err := config.Default(t.TempDir())
+	cfg, err := config.Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -128,7 +131,7 @@ func TestApplyFlagOverridesDrivenAppliesVerbatim(t *testing.T) {
 }
 
 func TestApplyFlagOverridesImageURLClearsSHA(t *testing.T) {
-	cfg, err := config.Default(t.TempDir())
+	cfg, err := config.Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -158,7 +161,7 @@ func TestApplyFlagOverridesImageURLClearsSHA(t *testing.T) {
 // An empty image-url flag must never clobber a Settings-provided image URL, even
 // if somehow marked changed.
 func TestApplyFlagOverridesEmptyImageURLNoClobber(t *testing.T) {
-	cfg, err := config.Default(t.TempDir())
+	cfg, err := config.Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -181,7 +184,7 @@ func TestApplyFlagOverridesEmptyImageURLNoClobber(t *testing.T) {
 // and clearing the pinned SHA-512 so the fail-closed sidecar path applies.
 func TestApplyFlagOverridesHostedImageForce(t *testing.T) {
 	t.Setenv(config.ForceHostedImageEnvVar, "")
-	cfg, err := config.Default(t.TempDir())
+	cfg, err := config.Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -218,7 +221,7 @@ func TestApplyFlagOverridesHostedImageForce(t *testing.T) {
 func TestApplyFlagOverridesHostedImageForceViaEnv(t *testing.T) {
 	t.Setenv(config.ForceHostedImageEnvVar, "1")
 	t.Setenv(config.ForceDebianImageEnvVar, "")
-	cfg, err := config.Default(t.TempDir())
+	cfg, err := config.Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -251,7 +254,7 @@ func TestApplyFlagOverridesHostedImageForceViaEnv(t *testing.T) {
 func TestApplyFlagOverridesDebianImageForce(t *testing.T) {
 	t.Setenv(config.ForceHostedImageEnvVar, "")
 	t.Setenv(config.ForceDebianImageEnvVar, "")
-	cfg, err := config.Default(t.TempDir())
+	cfg, err := config.Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -279,12 +282,39 @@ func TestApplyFlagOverridesDebianImageForce(t *testing.T) {
 	}
 }
 
+// TestApplyFlagOverridesDistro verifies --distro repoints the hosted default at
+// the distro's stock image and records the choice for the bootstrap script.
+func TestApplyFlagOverridesDistro(t *testing.T) {
+	t.Setenv(config.ForceHostedImageEnvVar, "")
+	t.Setenv(config.ForceDebianImageEnvVar, "")
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withStartFlags(t, func() {
+		startFlags.distro = config.DistroUbuntu
+		applyFlagOverrides(cfg, changedSet("distro"), false)
+	})
+
+	wantURL, err := config.DistroImageURL(config.DistroUbuntu, cfg.Arch)
+	if err != nil {
+		t.Skipf("no ubuntu image for arch %s", cfg.Arch)
+	}
+	if cfg.Distro != config.DistroUbuntu || cfg.BaseImageURL != wantURL || cfg.UseHostedGuestImage {
+		t.Errorf("Distro=%q URL=%q hosted=%v, want ubuntu %q", cfg.Distro, cfg.BaseImageURL, cfg.UseHostedGuestImage, wantURL)
+	}
+	if got := cfg.SourceOf(control.ConfigKeyDistro); got != config.SourceFlag {
+		t.Errorf("distro source = %q, want flag", got)
+	}
+}
+
 // TestApplyFlagOverridesDebianImageForceViaEnv verifies BLADERUNNER_FORCE_DEBIAN_IMAGE=1
 // forces the Debian escape hatch with no flag.
 func TestApplyFlagOverridesDebianImageForceViaEnv(t *testing.T) {
 	t.Setenv(config.ForceHostedImageEnvVar, "")
 	t.Setenv(config.ForceDebianImageEnvVar, "1")
-	cfg, err := config.Default(t.TempDir())
+	cfg, err := config.Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -311,6 +341,8 @@ func TestValidateImageOverrideFlagsConflicts(t *testing.T) {
 		debianEnv   string
 		imageURL    string
 		imagePath   string
+		imageSHA256 string
+		distro      string
 		wantErr     bool
 		wantErrText string
 	}{
@@ -330,6 +362,14 @@ func TestValidateImageOverrideFlagsConflicts(t *testing.T) {
 		{name: "debian flag + image-url", debianFlag: true, imageURL: "https://x.test/i.qcow2", wantErr: true, wantErrText: "--image-url"},
 		{name: "debian flag + image-path", debianFlag: true, imagePath: "/tmp/i.qcow2", wantErr: true, wantErrText: "--image-path"},
 		{name: "debian env + image-path", debianEnv: "1", imagePath: "/tmp/i.qcow2", wantErr: true, wantErrText: "--image-path"},
+		{name: "image-sha256 alone", imageSHA256: strings.Repeat("ab", 32), wantErr: false},
+		{name: "hosted flag + image-sha256", hostedFlag: true, imageSHA256: strings.Repeat("ab", 32), wantErr: true, wantErrText: "--image-sha256"},
+		{name: "malformed image-sha256", imageSHA256: "abc123", wantErr: true, wantErrText: "not a hex SHA-256"},
+		{name: "distro alone", distro: "fedora", wantErr: false},
+		{name: "unknown distro", distro: "arch", wantErr: true, wantErrText: "unsupported distro"},
+		{name: "distro + debian flag", distro: "ubuntu", debianFlag: true, wantErr: true, wantErrText: "--distro"},
+		{name: "distro + hosted env", distro: "ubuntu", hostedEnv: "1", wantErr: true, wantErrText: "--distro"},
+		{name: "distro + image-url", distro: "ubuntu", imageURL: "https://x.test/i.qcow2", wantErr: true, wantErrText: "--image-url"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -340,6 +380,8 @@ func TestValidateImageOverrideFlagsConflicts(t *testing.T) {
 				startFlags.debianImage = tt.debianFlag
 				startFlags.imageURL = tt.imageURL
 				startFlags.imagePath = tt.imagePath
+				startFlags.imageSHA256 = tt.imageSHA256
+				startFlags.distro = tt.distro
 				err := validateImageOverrideFlags()
 				if (err != nil) != tt.wantErr {
 					t.Fatalf("validateImageOverrideFlags() err = %v, wantErr %v", err, tt.wantErr)
@@ -351,3 +393,384 @@ func TestValidateImageOverrideFlagsConflicts(t *testing.T) {
 		})
 	}
 }
+
+// --dns / --search-domain land on the config when given and leave it alone
+// otherwise (including on a driven start, which carries no DNS of its own).
+func TestApplyFlagOverridesDNS(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.dns = []string{"1.1.1.1"}
+		startFlags.searchDoms = []string{"corp.example.com"}
+		applyFlagOverrides(cfg, changedSet("dns", "search-domain"), false)
+	})
+	if len(cfg.DNS) != 1 || cfg.DNS[0] != "1.1.1.1" {
+		t.Errorf("DNS = %v, want [1.1.1.1]", cfg.DNS)
+	}
+	if len(cfg.SearchDomains) != 1 || cfg.SearchDomains[0] != "corp.example.com" {
+		t.Errorf("SearchDomains = %v, want [corp.example.com]", cfg.SearchDomains)
+	}
+
+	withStartFlags(t, func() {
+		startFlags.dns = nil
+		startFlags.searchDoms = nil
+		applyFlagOverrides(cfg, changedSet(), true)
+	})
+	if len(cfg.DNS) != 1 {
+		t.Errorf("driven start without --dns cleared DNS: %v", cfg.DNS)
+	}
+}
+
+func TestApplyFlagOverridesPackages(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.packages = []string{"htop", "git"}
+		applyFlagOverrides(cfg, changedSet("packages"), false)
+	})
+	if len(cfg.ExtraPackages) != 2 || cfg.ExtraPackages[1] != "git" {
+		t.Errorf("ExtraPackages = %v, want [htop git]", cfg.ExtraPackages)
+	}
+}
+
+func TestApplyFlagOverridesPorts(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.sshPort = 7022
+		startFlags.apiPort = 19443
+		applyFlagOverrides(cfg, changedSet("ssh-port", "api-port"), false)
+	})
+	if cfg.LocalSSHPort != 7022 || cfg.LocalAPIPort != 19443 {
+		t.Errorf("ports = %d/%d, want 7022/19443", cfg.LocalSSHPort, cfg.LocalAPIPort)
+	}
+	if src := cfg.SourceOf(control.ConfigKeyLocalAPIPort); src != config.SourceFlag {
+		t.Errorf("local-api-port source = %v, want flag", src)
+	}
+	if cfg.AutoPort {
+		t.Error("AutoPort set without --auto-port")
+	}
+	withStartFlags(t, func() {
+		startFlags.autoPort = true
+		applyFlagOverrides(cfg, changedSet("auto-port"), false)
+	})
+	if !cfg.AutoPort {
+		t.Error("--auto-port did not set AutoPort")
+	}
+}
+
+func TestApplyFlagOverridesForwardDial(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.dialRetries = 120
+		startFlags.dialDelay = 2 * time.Second
+		applyFlagOverrides(cfg, changedSet("forward-dial-retries", "forward-dial-delay"), false)
+	})
+	if cfg.ForwarderDialRetries != 120 || cfg.ForwarderDialRetryDelay != 2*time.Second {
+		t.Errorf("forward dial = %d x %v, want 120 x 2s", cfg.ForwarderDialRetries, cfg.ForwarderDialRetryDelay)
+	}
+}
+
+func TestApplyFlagOverridesIdleTimeout(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.idleTimeout = time.Hour
+		applyFlagOverrides(cfg, changedSet("idle-timeout"), false)
+	})
+	if cfg.IdleTimeout != time.Hour {
+		t.Errorf("IdleTimeout = %v, want 1h", cfg.IdleTimeout)
+	}
+}
+
+func TestApplyFlagOverridesMACAddress(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.macAddress = "52:54:00:12:34:56"
+		applyFlagOverrides(cfg, changedSet("mac-address"), false)
+	})
+	if cfg.MACAddress != "52:54:00:12:34:56" {
+		t.Errorf("MACAddress = %q, want 52:54:00:12:34:56", cfg.MACAddress)
+	}
+}
+
+func TestApplyFlagOverridesLogRotation(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.logMaxSize = 100
+		startFlags.logBackups = 2
+		applyFlagOverrides(cfg, changedSet("log-max-size", "log-max-backups"), false)
+	})
+	if cfg.LogMaxSizeMiB != 100 || cfg.LogMaxBackups != 2 {
+		t.Errorf("log rotation = %d MiB x %d, want 100 MiB x 2", cfg.LogMaxSizeMiB, cfg.LogMaxBackups)
+	}
+	if got := logRotation(cfg); got.MaxSize != 100 || got.MaxBackups != 2 {
+		t.Errorf("logRotation = %+v", got)
+	}
+}
+
+func TestApplyFlagOverridesLogFormat(t *testing.T) {
+	t.Setenv(config.LogFormatEnvVar, "JSON")
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LogFormat != config.LogFormatJSON {
+		t.Fatalf("env default LogFormat = %q, want json", cfg.LogFormat)
+	}
+	// A driven start without --log-format keeps the env's choice.
+	withStartFlags(t, func() { applyFlagOverrides(cfg, changedSet(), true) })
+	if cfg.LogFormat != config.LogFormatJSON {
+		t.Errorf("untouched flag reset LogFormat to %q", cfg.LogFormat)
+	}
+	withStartFlags(t, func() {
+		startFlags.logFormat = config.LogFormatText
+		applyFlagOverrides(cfg, changedSet("log-format"), false)
+	})
+	if cfg.LogFormat != config.LogFormatText {
+		t.Errorf("--log-format text gave %q", cfg.LogFormat)
+	}
+}
+
+func TestApplyFlagOverridesKernelConsole(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.kernelCons = "console=hvc0,115200n8"
+		applyFlagOverrides(cfg, changedSet(), false)
+	})
+	if cfg.KernelConsole != config.DefaultKernelConsole {
+		t.Errorf("unchanged --kernel-console overrode the config: %q", cfg.KernelConsole)
+	}
+	withStartFlags(t, func() {
+		startFlags.kernelCons = "console=hvc0,115200n8"
+		applyFlagOverrides(cfg, changedSet("kernel-console"), false)
+	})
+	if cfg.KernelConsole != "console=hvc0,115200n8" {
+		t.Errorf("KernelConsole = %q, want the flag value", cfg.KernelConsole)
+	}
+}
+
+func TestApplyFlagOverridesRecordsSources(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetSource(control.ConfigKeyMemoryGiB, config.SourceManifest)
+	withStartFlags(t, func() {
+		startFlags.cpus = 2
+		startFlags.memory = 6
+		// Driven: every flag is applied, but only --cpus was passed.
+		applyFlagOverrides(cfg, changedSet("cpus"), true)
+	})
+	if got := cfg.SourceOf(control.ConfigKeyCPUs); got != config.SourceFlag {
+		t.Errorf("cpus from %q, want flag", got)
+	}
+	if got := cfg.SourceOf(control.ConfigKeyMemoryGiB); got != config.SourceManifest {
+		t.Errorf("memory-gib from %q, want the manifest's record kept", got)
+	}
+}
+
+// --network/--bridge override a persisted bridged Settings choice only when
+// given, and a given --network is credited as a flag.
+func TestApplyFlagOverridesNetwork(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := config.DefaultSettings()
+	s.NetworkMode = config.NetSettingBridged
+	s.BridgeInterface = "en7"
+	s.ApplyTo(cfg)
+
+	withStartFlags(t, func() { applyFlagOverrides(cfg, changedSet(), false) })
+	if cfg.NetworkMode != config.NetworkModeBridged || cfg.BridgeInterface != "en7" {
+		t.Fatalf("plain start changed network to %s/%s", cfg.NetworkMode, cfg.BridgeInterface)
+	}
+
+	withStartFlags(t, func() {
+		startFlags.network = config.NetworkModeBridged
+		startFlags.bridge = config.BridgeInterfaceAuto
+		applyFlagOverrides(cfg, changedSet("network", "bridge"), false)
+	})
+	if cfg.BridgeInterface != config.BridgeInterfaceAuto {
+		t.Errorf("BridgeInterface = %q, want auto", cfg.BridgeInterface)
+	}
+	if got := cfg.SourceOf(control.ConfigKeyNetworkMode); got != config.SourceFlag {
+		t.Errorf("network-mode source = %s, want flag", got)
+	}
+}
+
+// --no-gui-input turns the input devices off; without it they stay on.
+func TestApplyFlagOverridesNoGUIInput(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() { applyFlagOverrides(cfg, changedSet(), false) })
+	if !cfg.GUIInput {
+		t.Fatal("GUIInput should default on")
+	}
+	withStartFlags(t, func() {
+		startFlags.gui = true
+		startFlags.noGUIInput = true
+		applyFlagOverrides(cfg, changedSet("gui", "no-gui-input"), false)
+	})
+	if !cfg.GUI || cfg.GUIInput {
+		t.Errorf("GUI=%v GUIInput=%v, want a view-only GUI", cfg.GUI, cfg.GUIInput)
+	}
+}
+
+// --require lands in RequireCommands; a plain start leaves it empty.
+func TestApplyFlagOverridesRequire(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() { applyFlagOverrides(cfg, changedSet(), false) })
+	if len(cfg.RequireCommands) != 0 {
+		t.Fatalf("plain start set RequireCommands = %v", cfg.RequireCommands)
+	}
+
+	withStartFlags(t, func() {
+		startFlags.require = []string{"incus", "jq"}
+		applyFlagOverrides(cfg, changedSet("require"), false)
+	})
+	if got := strings.Join(cfg.RequireCommands, ","); got != "incus,jq" {
+		t.Errorf("RequireCommands = %q, want incus,jq", got)
+	}
+}
+
+// --name renames the VM and is credited to the flag; a plain start keeps the
+// default name.
+func TestApplyFlagOverridesName(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() { applyFlagOverrides(cfg, changedSet(), false) })
+	if cfg.Name != "bladerunner" {
+		t.Fatalf("plain start set Name = %q", cfg.Name)
+	}
+
+	withStartFlags(t, func() {
+		startFlags.name = "build"
+		applyFlagOverrides(cfg, changedSet("name"), false)
+	})
+	if cfg.Name != "build" {
+		t.Errorf("Name = %q, want build", cfg.Name)
+	}
+	if got := cfg.SourceOf(control.ConfigKeyName); got != config.SourceFlag {
+		t.Errorf("name source = %s, want flag", got)
+	}
+}
+
+// --arch sets the guest arch and re-points the default image at that arch's
+// build; an explicit --image-url still wins.
+func TestApplyFlagOverridesArch(t *testing.T) {
+	foreign := "amd64"
+	if runtime.GOARCH == "amd64" {
+		foreign = "arm64"
+	}
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.arch = foreign
+		applyFlagOverrides(cfg, changedSet("arch"), false)
+	})
+	if cfg.Arch != foreign {
+		t.Errorf("Arch = %q, want %s", cfg.Arch, foreign)
+	}
+	if want, _ := config.HostedGuestImageURL(foreign); cfg.BaseImageURL != want {
+		t.Errorf("BaseImageURL = %q, want %q", cfg.BaseImageURL, want)
+	}
+	if got := cfg.SourceOf(control.ConfigKeyArch); got != config.SourceFlag {
+		t.Errorf("arch source = %s, want flag", got)
+	}
+
+	cfg, _ = config.Default(t.TempDir(), "")
+	withStartFlags(t, func() {
+		startFlags.arch = foreign
+		startFlags.imageURL = "https://example.com/mine.qcow2"
+		applyFlagOverrides(cfg, changedSet("arch", "image-url"), false)
+	})
+	if cfg.BaseImageURL != "https://example.com/mine.qcow2" {
+		t.Errorf("BaseImageURL = %q, want the --image-url value", cfg.BaseImageURL)
+	}
+}
+
+// --attach specs become read-only (unless :rw) extra disks, in order.
+func TestApplyFlagOverridesAttach(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.attach = []string{"/data/set.iso:ro", "/data/scratch.img:rw"}
+		applyFlagOverrides(cfg, changedSet("attach"), false)
+	})
+	want := []config.ExtraDisk{{Path: "/data/set.iso", ReadOnly: true}, {Path: "/data/scratch.img", ReadOnly: false}}
+	if !slices.Equal(cfg.ExtraDisks, want) {
+		t.Errorf("ExtraDisks = %+v, want %+v", cfg.ExtraDisks, want)
+	}
+}
+
+// --forward specs become user-declared forwards after the built-ins.
+func TestApplyFlagOverridesForward(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStartFlags(t, func() {
+		startFlags.forward = []string{"8080:tcp:8080", "9000:3000"}
+		applyFlagOverrides(cfg, changedSet("forward"), false)
+	})
+	var names []string
+	for _, f := range cfg.ForwardSpecs() {
+		names = append(names, f.Name)
+	}
+	want := []string{config.ForwardSSH, config.ForwardIncusAPI, "fwd-8080", "fwd-9000"}
+	if !slices.Equal(names, want) {
+		t.Errorf("forwards = %v, want %v", names, want)
+	}
+}
+
+// --no-resize is carried onto the config; without it disks are still grown.
+func TestApplyFlagOverridesNoResize(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyFlagOverrides(cfg, changedSet(), false)
+	if cfg.NoResize {
+		t.Fatal("NoResize set without --no-resize")
+	}
+	withStartFlags(t, func() {
+		startFlags.noResize = true
+		applyFlagOverrides(cfg, changedSet("no-resize"), false)
+	})
+	if !cfg.NoResize {
+		t.Error("NoResize = false, want true with --no-resize")
+	}
+}
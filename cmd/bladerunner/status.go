@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 	"github.com/stuffbucket/bladerunner/internal/config"
 	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/logging"
 	"github.com/stuffbucket/bladerunner/internal/ui"
 	"github.com/stuffbucket/bladerunner/internal/vm"
 )
@@ -24,6 +31,10 @@ const (
 	edgeMargin    = 2          // columns kept clear at each terminal edge
 )
 
+var statusFlags struct {
+	name string
+}
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show the status of Bladerunner VM",
@@ -31,9 +42,16 @@ var statusCmd = &cobra.Command{
 	RunE:  runStatus,
 }
 
+func init() {
+	statusCmd.Flags().StringVar(&statusFlags.name, "name", "", nameFlagUsage)
+}
+
 func runStatus(_ *cobra.Command, _ []string) error {
-	stateDir := config.DefaultStateDir()
-	client := control.NewClient(stateDir)
+	vmDir, err := namedVMDir(statusFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
+	client := control.NewClient(vmDir)
 
 	// Right panel: always build info.
 	right := newPanel("Build")
@@ -41,7 +59,27 @@ func runStatus(_ *cobra.Command, _ []string) error {
 	right.row("Commit", commit)
 	right.row("Built", date)
 
-	if !client.IsRunning() {
+	pingErr := client.Ping(context.Background())
+	if errors.Is(pingErr, control.ErrUnresponsive) {
+		if jsonOutput {
+			return emitJSON(statusReport{Running: false, Status: control.StatusUnresponsive, Error: pingErr.Error(), Build: currentBuildInfo()})
+		}
+		left := newPanel("VM")
+		left.row("Status", warning(control.StatusUnresponsive))
+		left.row("Socket", control.SocketPath(vmDir))
+		if cfg, err := config.Default("", statusFlags.name); err == nil {
+			right.sep()
+			right.row("Log", cfg.LogPath)
+		}
+
+		fmt.Println(title("Bladerunner Status"))
+		fmt.Println(renderPanels(left, right))
+		fmt.Println(warning("  Control socket present but unresponsive:"), subtle(pingErr.Error()))
+		fmt.Println(subtle("  Check the socket's permissions and the host log for a hung server."))
+		fmt.Println()
+		return nil
+	}
+	if pingErr != nil {
 		if jsonOutput {
 			return emitJSON(statusReport{Running: false, Status: control.StatusStopped, Build: currentBuildInfo()})
 		}
@@ -55,8 +93,13 @@ func runStatus(_ *cobra.Command, _ []string) error {
 		return nil
 	}
 
-	status, err := client.GetStatus()
-	if err != nil {
+	// Prefer the single status.json round trip; servers that predate it only
+	// answer the plain status command and per-key config.get.
+	var status string
+	info, err := client.GetStatusInfo()
+	if err == nil {
+		status = info.State
+	} else if status, err = client.GetStatus(); err != nil {
 		err = fmt.Errorf("get status: %w", err)
 		if jsonOutput {
 			emitJSONError(err)
@@ -64,7 +107,16 @@ func runStatus(_ *cobra.Command, _ []string) error {
 		return err
 	}
 
+	// One config.getmany covers every key shown; servers whose protocol
+	// predates it answer a config.get per key.
+	values, manyErr := client.GetConfigMany(statusConfigKeys)
 	getConfig := func(k string) string {
+		if v, ok := statusInfoValue(info, k); ok {
+			return v
+		}
+		if manyErr == nil {
+			return values[k]
+		}
 		v, err := client.GetConfig(k)
 		if err != nil {
 			return ""
@@ -73,24 +125,27 @@ func runStatus(_ *cobra.Command, _ []string) error {
 	}
 
 	// Color the status by health: running is green, an unreachable guest
-	// (host alive but guest not answering — e.g. kernel panic) is amber, and
-	// anything else (stopped/unknown) is red.
+	// (host alive but guest not answering — e.g. kernel panic) or a paused one
+	// is amber, and anything else (stopped/unknown) is red.
 	statusStyle := errorf
 	switch status {
 	case control.StatusRunning:
 		statusStyle = success
-	case control.StatusUnreachable:
+	case control.StatusUnreachable, control.StatusPaused:
 		statusStyle = warning
 	}
 
 	left := newPanel("VM")
 	left.row("Status", statusStyle(status))
 	left.rowIf("PID", getConfig(control.ConfigKeyPID))
+	if started, ok := vmStartedAt(client, info); ok {
+		left.row("Uptime", value(formatUptime(time.Since(started))))
+	}
 	left.rowIf("Name", getConfig(control.ConfigKeyName))
 	left.rowIf("Arch", getConfig(control.ConfigKeyArch))
 	left.sep()
 	left.rowIf("CPUs", getConfig(control.ConfigKeyCPUs))
-	left.rowGiB("Memory", getConfig(control.ConfigKeyMemoryGiB))
+	left.rowGiB("Memory", effectiveMemoryGiB(getConfig))
 	left.rowGiB("Disk", getConfig(control.ConfigKeyDiskSizeGiB))
 	if nv := getConfig(control.ConfigKeyNestedVirt); nv != "" {
 		// "enabled" = Incus VMs work; "unsupported"/"disabled" = containers only.
@@ -104,11 +159,29 @@ func runStatus(_ *cobra.Command, _ []string) error {
 		left.row("Incus VMs", nvStyle(nv))
 	}
 	left.sep()
+	// Traffic counters come from the stats command; older servers lack it.
+	stats, _ := client.GetStats()
 	if p := getConfig(control.ConfigKeyLocalSSHPort); p != "" {
-		left.row("SSH", "localhost:"+p)
+		left.row("SSH", "localhost:"+p+forwardActivity(stats, config.ForwardSSH))
 	}
 	if p := getConfig(control.ConfigKeyLocalAPIPort); p != "" {
-		left.row("API", "localhost:"+p)
+		left.row("API", "localhost:"+p+forwardActivity(stats, config.ForwardIncusAPI))
+	}
+	// An older server without forward.list just reports nothing paused.
+	var forwards []control.ForwardInfo
+	if info != nil {
+		forwards = info.Forwards
+	} else {
+		forwards, _ = client.ListForwards()
+	}
+	paused := pausedForwards(forwards)
+	if paused != "" {
+		left.row("Paused", warning(paused))
+	}
+	if stats != nil {
+		in, out := forwardTotals(stats.Forwards)
+		left.row("Fwd in", value(logging.HumanBytes(int64(in))))
+		left.row("Fwd out", value(logging.HumanBytes(int64(out))))
 	}
 	left.rowIf("Network", getConfig(control.ConfigKeyNetworkMode))
 
@@ -122,7 +195,11 @@ func runStatus(_ *cobra.Command, _ []string) error {
 	right.rowIf("Disk", getConfig(control.ConfigKeyDiskPath))
 
 	if jsonOutput {
-		return emitJSON(runningStatusReport(status, getConfig))
+		report := runningStatusReport(status, getConfig)
+		report.VM.Forwards = forwards
+		report.Info = info
+		report.Stats = stats
+		return emitJSON(report)
 	}
 
 	if b := bannerHeader(); b != "" {
@@ -139,13 +216,161 @@ func runStatus(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
+// statusInfoHandler serves control.CmdStatusJSON from the foreground runner
+// process. getRunner returns nil until the VM has been created; until then
+// the VM fields report the configured values and nothing has started.
+func statusInfoHandler(ctrl control.Controller, cfg *config.Config, cfgHandler *control.ConfigRouter, getRunner func() *vm.Runner) control.HandlerFunc {
+	return func(ctx context.Context, _ *control.Request) *control.Message {
+		state, err := ctrl.Status(ctx)
+		if err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+
+		cfgHandler.Lock()
+		info := control.StatusInfo{
+			SchemaVersion: control.StatusSchemaVersion,
+			State:         state,
+			PID:           os.Getpid(),
+			CPUs:          cfg.CPUs,
+			MemoryGiB:     cfg.MemoryGiB,
+			Forwards:      []control.ForwardInfo{},
+		}
+		cfgHandler.Unlock()
+
+		if r := getRunner(); r != nil {
+			if started := r.StartedAt(); !started.IsZero() {
+				info.StartedAt = &started
+				info.UptimeSeconds = int64(time.Since(started) / time.Second)
+				info.MemoryGiB = r.MemoryTargetGiB()
+			}
+			info.IncusReady = r.IncusReady()
+			info.Forwards = forwardInfos(r.Forwarders())
+		}
+
+		b, err := json.Marshal(info)
+		if err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		return &control.Message{Response: string(b)}
+	}
+}
+
+// uptimeHandler serves control.CmdUptime: the VM's start time, which the
+// client turns into a live uptime.
+func uptimeHandler(getRunner func() *vm.Runner) control.HandlerFunc {
+	return func(_ context.Context, _ *control.Request) *control.Message {
+		var started time.Time
+		if r := getRunner(); r != nil {
+			started = r.StartedAt()
+		}
+		if started.IsZero() {
+			return &control.Message{Error: "VM is not running yet"}
+		}
+		return &control.Message{Response: started.UTC().Format(time.RFC3339Nano)}
+	}
+}
+
+// statsHandler serves control.CmdStats: the VM's start time, balloon target
+// and per-forwarder traffic, read live from the runner.
+func statsHandler(getRunner func() *vm.Runner) control.HandlerFunc {
+	return func(_ context.Context, _ *control.Request) *control.Message {
+		r := getRunner()
+		if r == nil {
+			return &control.Message{Error: "VM is not running yet"}
+		}
+		st := r.Stats()
+		stats := control.Stats{
+			MemoryTargetGiB: st.MemoryTargetGiB,
+			Forwards:        forwardStats(st.Forwarders),
+		}
+		if !st.StartedAt.IsZero() {
+			stats.StartedAt = &st.StartedAt
+			stats.UptimeSeconds = int64(time.Since(st.StartedAt) / time.Second)
+		}
+		b, err := json.Marshal(stats)
+		if err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		return &control.Message{Response: string(b)}
+	}
+}
+
+// forwardTotals sums the bytes all forwarders have proxied to and from the
+// guest.
+func forwardTotals(forwards []control.ForwardStats) (toGuest, fromGuest uint64) {
+	for _, f := range forwards {
+		toGuest += f.BytesToGuest
+		fromGuest += f.BytesFromGuest
+	}
+	return toGuest, fromGuest
+}
+
+// forwardActivity renders the named forwarder's connections and traffic as a
+// suffix for its status row, e.g. " (2 active, 14.0MiB)", or "" when stats
+// are unavailable or the forwarder has carried nothing yet.
+func forwardActivity(stats *control.Stats, name string) string {
+	if stats == nil {
+		return ""
+	}
+	for _, f := range stats.Forwards {
+		if f.Name != name {
+			continue
+		}
+		if f.TotalConns == 0 {
+			return ""
+		}
+		xfer := logging.HumanBytes(int64(f.BytesToGuest + f.BytesFromGuest))
+		return subtle(fmt.Sprintf(" (%d active, %s)", f.ActiveConns, xfer))
+	}
+	return ""
+}
+
+// vmStartedAt returns the VM's start time from the status.json object when
+// the server sent one, else via the uptime command. ok is false while the VM
+// is booting or when the server predates both.
+func vmStartedAt(client *control.Client, info *control.StatusInfo) (time.Time, bool) {
+	if info != nil {
+		if info.StartedAt == nil {
+			return time.Time{}, false
+		}
+		return *info.StartedAt, true
+	}
+	started, err := client.StartedAt()
+	return started, err == nil
+}
+
+// formatUptime renders a duration compactly with its two most significant
+// units, e.g. "42s", "5m12s", "1h23m", "3d4h".
+func formatUptime(d time.Duration) string {
+	d = max(d, 0).Truncate(time.Second)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	h, m, s := d/time.Hour, (d%time.Hour)/time.Minute, (d%time.Minute)/time.Second
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, h)
+	case h > 0:
+		return fmt.Sprintf("%dh%dm", h, m)
+	case m > 0:
+		return fmt.Sprintf("%dm%ds", m, s)
+	default:
+		return fmt.Sprintf("%ds", s)
+	}
+}
+
 // --- JSON output (runner status --json) ---
 
 type statusReport struct {
 	Running bool      `json:"running"`
 	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
 	Build   buildInfo `json:"build"`
 	VM      *vmInfo   `json:"vm,omitempty"`
+
+	// Info is the server's status.json object, when it supports one.
+	Info *control.StatusInfo `json:"info,omitempty"`
+	// Stats is the server's live stats object, when it supports one.
+	Stats *control.Stats `json:"stats,omitempty"`
 }
 
 type buildInfo struct {
@@ -172,6 +397,8 @@ type vmInfo struct {
 	Hosted       string `json:"hosted,omitempty"`
 	CloudInitISO string `json:"cloud_init_iso,omitempty"`
 	LogPath      string `json:"log_path,omitempty"`
+
+	Forwards []control.ForwardInfo `json:"forwards,omitempty"`
 }
 
 func currentBuildInfo() buildInfo {
@@ -188,7 +415,7 @@ func runningStatusReport(status string, get func(string) string) statusReport {
 			Name:         get(control.ConfigKeyName),
 			Arch:         get(control.ConfigKeyArch),
 			CPUs:         get(control.ConfigKeyCPUs),
-			MemoryGiB:    get(control.ConfigKeyMemoryGiB),
+			MemoryGiB:    effectiveMemoryGiB(get),
 			DiskSizeGiB:  get(control.ConfigKeyDiskSizeGiB),
 			DiskPath:     get(control.ConfigKeyDiskPath),
 			NestedVirt:   get(control.ConfigKeyNestedVirt),
@@ -205,6 +432,56 @@ func runningStatusReport(status string, get func(string) string) statusReport {
 	}
 }
 
+// statusConfigKeys are the config keys br status reads, fetched together
+// with one config.getmany.
+var statusConfigKeys = []string{
+	control.ConfigKeyPID,
+	control.ConfigKeyName,
+	control.ConfigKeyArch,
+	control.ConfigKeyCPUs,
+	control.ConfigKeyMemoryGiB,
+	control.ConfigKeyMemoryBalloonGiB,
+	control.ConfigKeyDiskSizeGiB,
+	control.ConfigKeyDiskPath,
+	control.ConfigKeyNestedVirt,
+	control.ConfigKeyNetworkMode,
+	control.ConfigKeyLocalSSHPort,
+	control.ConfigKeyLocalAPIPort,
+	control.ConfigKeyBaseImageURL,
+	control.ConfigKeyBaseImagePath,
+	control.ConfigKeySSHConfigPath,
+	control.ConfigKeyUseHostedGuestImage,
+	control.ConfigKeyCloudInitISO,
+	control.ConfigKeyLogPath,
+}
+
+// statusInfoValue answers a config key from the server's StatusInfo for the
+// fields it carries, so those don't cost a config.get each. ok is false when
+// info is nil or doesn't cover the key.
+func statusInfoValue(info *control.StatusInfo, k string) (string, bool) {
+	if info == nil {
+		return "", false
+	}
+	switch k {
+	case control.ConfigKeyPID:
+		return strconv.Itoa(info.PID), true
+	case control.ConfigKeyCPUs:
+		return strconv.FormatUint(uint64(info.CPUs), 10), true
+	case control.ConfigKeyMemoryBalloonGiB:
+		return strconv.FormatUint(info.MemoryGiB, 10), true
+	}
+	return "", false
+}
+
+// effectiveMemoryGiB is the memory the guest has now: the balloon target
+// once the VM is up, else the boot size.
+func effectiveMemoryGiB(get func(string) string) string {
+	if v := get(control.ConfigKeyMemoryBalloonGiB); v != "" {
+		return v
+	}
+	return get(control.ConfigKeyMemoryGiB)
+}
+
 // guestImageVersionForStatus reads /etc/bladerunner-image-version via SSH
 // when the SSH config path is available. Returns an empty string if the
 // VM doesn't expose SSH yet or the file is missing (typical when the
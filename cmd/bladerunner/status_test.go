@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+func TestStatusInfoHandlerBeforeVMStart(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("config.Default: %v", err)
+	}
+	cfg.CPUs = 3
+	cfg.MemoryGiB = 5
+
+	h := statusInfoHandler(control.ControllerFunc{}, cfg, control.NewConfigRouter(cfg), func() *vm.Runner { return nil })
+	resp := h(context.Background(), &control.Request{Command: control.CmdStatusJSON})
+	if resp.Error != "" {
+		t.Fatalf("handler error: %s", resp.Error)
+	}
+
+	var info control.StatusInfo
+	if err := json.Unmarshal([]byte(resp.Response), &info); err != nil {
+		t.Fatalf("decode %q: %v", resp.Response, err)
+	}
+	if info.SchemaVersion != control.StatusSchemaVersion || info.State != control.StatusRunning {
+		t.Errorf("schema/state = %d/%q", info.SchemaVersion, info.State)
+	}
+	if info.PID != os.Getpid() || info.CPUs != 3 || info.MemoryGiB != 5 {
+		t.Errorf("pid/cpus/mem = %d/%d/%d", info.PID, info.CPUs, info.MemoryGiB)
+	}
+	if info.StartedAt != nil || info.UptimeSeconds != 0 || info.IncusReady {
+		t.Errorf("VM not started yet but info = %+v", info)
+	}
+	if info.Forwards == nil {
+		t.Error("forwards should encode as an empty array, not null")
+	}
+}
+
+func TestStatusInfoValue(t *testing.T) {
+	if _, ok := statusInfoValue(nil, control.ConfigKeyPID); ok {
+		t.Error("nil info should not answer")
+	}
+	info := &control.StatusInfo{PID: 7, CPUs: 2, MemoryGiB: 4}
+	for k, want := range map[string]string{
+		control.ConfigKeyPID:              "7",
+		control.ConfigKeyCPUs:             "2",
+		control.ConfigKeyMemoryBalloonGiB: "4",
+	} {
+		if got, ok := statusInfoValue(info, k); !ok || got != want {
+			t.Errorf("statusInfoValue(%s) = %q, %v; want %q", k, got, ok, want)
+		}
+	}
+	if _, ok := statusInfoValue(info, control.ConfigKeyName); ok {
+		t.Error("name is not carried by StatusInfo")
+	}
+}
+
+// TestStatusConfigKeys verifies the batched status fetch covers every key
+// the report reads, and only keys the server knows.
+func TestStatusConfigKeys(t *testing.T) {
+	fetched := map[string]bool{}
+	for _, k := range statusConfigKeys {
+		fetched[k] = true
+	}
+	runningStatusReport(control.StatusRunning, func(k string) string {
+		if !fetched[k] {
+			t.Errorf("status reads %q, which statusConfigKeys does not fetch", k)
+		}
+		return ""
+	})
+	meta := control.ConfigKeyMetaMap()
+	for _, k := range statusConfigKeys {
+		if _, ok := meta[k]; !ok {
+			t.Errorf("statusConfigKeys has unknown key %q", k)
+		}
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		-time.Second:                                 "0s",
+		42 * time.Second:                             "42s",
+		5*time.Minute + 12*time.Second:               "5m12s",
+		time.Hour + 23*time.Minute + 59*time.Second:  "1h23m",
+		3*24*time.Hour + 4*time.Hour + 5*time.Minute: "3d4h",
+	} {
+		if got := formatUptime(d); got != want {
+			t.Errorf("formatUptime(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestUptimeHandlerBeforeVMStart(t *testing.T) {
+	resp := uptimeHandler(func() *vm.Runner { return nil })(context.Background(), &control.Request{Command: control.CmdUptime})
+	if resp.Error == "" {
+		t.Errorf("expected an error before the VM starts, got %q", resp.Response)
+	}
+}
+
+func TestStatsHandlerBeforeVMStart(t *testing.T) {
+	resp := statsHandler(func() *vm.Runner { return nil })(context.Background(), &control.Request{Command: control.CmdStats})
+	if resp.Error == "" {
+		t.Errorf("expected an error before the VM starts, got %q", resp.Response)
+	}
+}
+
+func TestForwardTotals(t *testing.T) {
+	in, out := forwardTotals([]control.ForwardStats{
+		{Name: "ssh", BytesToGuest: 100, BytesFromGuest: 2000},
+		{Name: "incus-api", BytesToGuest: 5, BytesFromGuest: 7},
+	})
+	if in != 105 || out != 2007 {
+		t.Errorf("forwardTotals = %d, %d; want 105, 2007", in, out)
+	}
+}
+
+func TestForwardActivity(t *testing.T) {
+	stats := &control.Stats{Forwards: []control.ForwardStats{
+		{Name: "ssh", BytesToGuest: 4 << 20, BytesFromGuest: 10 << 20, ActiveConns: 2, TotalConns: 14},
+		{Name: "incus-api"},
+	}}
+	if got := forwardActivity(stats, "ssh"); !strings.Contains(got, "2 active, 14.0MiB") {
+		t.Errorf("forwardActivity(ssh) = %q, want it to show 2 active, 14.0MiB", got)
+	}
+	for _, name := range []string{"incus-api", "fwd-8080"} {
+		if got := forwardActivity(stats, name); got != "" {
+			t.Errorf("forwardActivity(%s) = %q, want empty for a forwarder with no connections", name, got)
+		}
+	}
+	if got := forwardActivity(nil, "ssh"); got != "" {
+		t.Errorf("forwardActivity(nil) = %q, want empty", got)
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strconv"
 	"syscall"
 	"time"
@@ -10,20 +12,23 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/stuffbucket/bladerunner/internal/config"
 	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/vm"
 )
 
 var stopFlags struct {
 	timeout int
 	force   bool
+	name    string
 }
 
 // Force-stop timing. A panicked guest ignores ACPI shutdown, so the normal
-// graceful path hangs; --force bounds that by escalating to SIGTERM then
-// SIGKILL on the host process.
+// graceful path hangs; --force skips it by asking the server to force-stop
+// the VM, and only if that also stalls, escalating to SIGTERM then SIGKILL on
+// the host process.
 const (
-	// forceGracePeriod is how long --force waits for graceful shutdown before
-	// escalating.
-	forceGracePeriod = 5 * time.Second
+	// killExitGrace is how long we wait for the host process to exit after
+	// the server acknowledged a kill.
+	killExitGrace = 10 * time.Second
 	// sigtermGrace / sigkillGrace bound how long we wait for the process to
 	// exit after each signal.
 	sigtermGrace = 3 * time.Second
@@ -35,25 +40,37 @@ var stopCmd = &cobra.Command{
 	Short: "Stop the running VM",
 	Long: `Stop the running Bladerunner VM.
 
-By default sends a graceful shutdown signal and waits. If the guest is
-unresponsive (e.g. a kernel panic), graceful shutdown never completes; use
---force to escalate to terminating the host process after a short grace
-period.`,
+By default asks the guest to shut down (ACPI) and waits, then reports whether
+the guest acknowledged the request or ignored it and had to be force-stopped.
+If the guest is known to be unresponsive (e.g. a kernel panic), use --force to
+skip the shutdown request entirely: the VM is force-stopped via the control
+server, and if the host process still doesn't exit it is terminated with
+SIGTERM, then SIGKILL.
+
+Ctrl-C abandons the wait; the VM keeps shutting down on its own.`,
 	RunE: runStop,
 }
 
 func init() {
 	stopCmd.Flags().IntVarP(&stopFlags.timeout, "timeout", "t", config.DefaultStopTimeout, "Seconds to wait for graceful shutdown")
-	stopCmd.Flags().BoolVarP(&stopFlags.force, "force", "f", false, "Force-stop: terminate the host process if graceful shutdown stalls (e.g. panicked guest)")
+	stopCmd.Flags().BoolVarP(&stopFlags.force, "force", "f", false, "Force-stop without asking the guest to shut down (e.g. panicked guest)")
+	stopCmd.Flags().StringVar(&stopFlags.name, "name", "", nameFlagUsage)
 }
 
 func runStop(_ *cobra.Command, _ []string) error {
-	stateDir := config.DefaultStateDir()
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	vmDir, err := namedVMDir(stopFlags.name)
+	if err != nil {
+		return jsonOrError(err)
+	}
 
-	client := control.NewClient(stateDir)
+	client := control.NewClient(vmDir)
 
-	if !client.IsRunning() {
-		err := fmt.Errorf("VM is not running")
+	// Ping distinguishes a stopped VM from a socket that exists but doesn't
+	// answer, so the latter isn't misreported as "not running".
+	if err := client.Ping(ctx); err != nil {
 		if jsonOutput {
 			emitJSONError(err)
 		}
@@ -61,60 +78,133 @@ func runStop(_ *cobra.Command, _ []string) error {
 	}
 
 	// Capture the host PID up front, while the control server still answers —
-	// --force needs it even if the server later wedges.
+	// --force needs it even if the server later wedges. The PID file covers a
+	// server that answers Ping but not config reads.
 	hostPID := readHostPID(client)
+	if hostPID == 0 {
+		hostPID = readPIDFile(vmDir)
+	}
+
+	socketPath := control.SocketPath(vmDir)
+	// The host records how the VM went down as it exits; clear any record of
+	// an earlier stop first.
+	vm.ClearStopResult(vmDir)
+
+	if stopFlags.force {
+		return forceStop(ctx, client, socketPath, hostPID)
+	}
 
 	if !jsonOutput {
 		fmt.Println("Stopping VM (sending graceful shutdown signal)...")
 	}
 	if err := client.StopVM(); err != nil {
-		// Under --force a wedged server is exactly the case we handle below;
-		// don't abort, fall through to the PID escalation.
-		if !stopFlags.force {
-			if jsonOutput {
-				emitJSONError(err)
-			}
-			return err
-		}
-		if !jsonOutput {
-			fmt.Printf("Graceful stop request failed (%v); will force-terminate.\n", err)
+		if jsonOutput {
+			emitJSONError(err)
 		}
+		return err
 	}
 
-	socketPath := control.SocketPath(stateDir)
-
-	// Graceful wait. With --force this is just the short grace period before
-	// escalating; otherwise it is the full user-specified timeout.
 	graceful := time.Duration(stopFlags.timeout) * time.Second
-	if stopFlags.force && graceful > forceGracePeriod {
-		graceful = forceGracePeriod
-	}
 	if !jsonOutput {
 		fmt.Printf("Waiting up to %s for shutdown...\n", graceful.Round(time.Second))
 	}
-	if waitForSocketGone(socketPath, graceful) {
+	if waitForSocketGoneContext(ctx, socketPath, graceful) {
+		res, msg := stopOutcome(vm.ReadStopResult(vmDir))
 		if jsonOutput {
-			return emitJSON(stopResult{Status: control.StatusStopped})
+			return emitJSON(res)
 		}
-		fmt.Println("VM stopped")
+		fmt.Println(msg)
 		return nil
 	}
+	if err := stopInterrupted(ctx); err != nil {
+		return err
+	}
+
+	err = fmt.Errorf("timeout waiting for VM to stop (use 'br stop --force' to terminate a hung/panicked VM)")
+	if jsonOutput {
+		emitJSONError(err)
+	}
+	return err
+}
 
-	if !stopFlags.force {
-		err := fmt.Errorf("timeout waiting for VM to stop (use 'br stop --force' to terminate a hung/panicked VM)")
+// forceStop is `br stop --force`: have the server force-stop the VM (no ACPI)
+// and exit, and if it doesn't answer or the host lingers, signal the host
+// process.
+func forceStop(ctx context.Context, client *control.Client, socketPath string, hostPID int) error {
+	if !jsonOutput {
+		fmt.Println("Force-stopping the VM (skipping graceful shutdown)...")
+	}
+	killErr := killContext(ctx, client)
+	if killErr == nil && waitForSocketGoneContext(ctx, socketPath, killExitGrace) {
 		if jsonOutput {
-			emitJSONError(err)
+			return emitJSON(stopResult{Status: "force-stopped", Method: "kill"})
 		}
+		fmt.Println("VM force-stopped")
+		return nil
+	}
+	if err := stopInterrupted(ctx); err != nil {
 		return err
 	}
-
+	if killErr != nil && !jsonOutput {
+		fmt.Printf("Force-stop request failed (%v).\n", killErr)
+	}
 	return forceTerminate(socketPath, hostPID)
 }
 
+// stopOutcome turns the host's record of a requested stop into the JSON
+// result and the line printed for it. Without a record (a host that predates
+// it) all that is known is that the VM stopped.
+func stopOutcome(res vm.StopResult, ok bool) (stopResult, string) {
+	out := stopResult{Status: control.StatusStopped, Method: "graceful"}
+	if !ok {
+		return out, "VM stopped"
+	}
+	acked := res.Graceful
+	out.GuestAcknowledged = &acked
+	out.StopRequests = res.Attempts
+	if res.Forced {
+		out.Method = "forced"
+		return out, fmt.Sprintf("VM stopped, but the guest ignored %d shutdown request(s) and was force-stopped", res.Attempts)
+	}
+	return out, "VM stopped gracefully (the guest acknowledged the shutdown request)"
+}
+
 // stopResult is the JSON payload emitted by `br stop --json` on success.
 type stopResult struct {
 	Status string `json:"status"`           // "stopped" or "force-stopped"
-	Signal string `json:"signal,omitempty"` // "SIGTERM"|"SIGKILL" on the force path
+	Method string `json:"method"`           // "graceful", "forced" (guest ignored the request), "kill" (--force) or "signal"
+	Signal string `json:"signal,omitempty"` // "SIGTERM"|"SIGKILL" on the signal path
+
+	// GuestAcknowledged reports, for a requested stop, whether the guest
+	// powered off on its own; nil when the host didn't record it.
+	GuestAcknowledged *bool `json:"guest_acknowledged,omitempty"`
+	// StopRequests is how many ACPI stop requests the host sent.
+	StopRequests int `json:"stop_requests,omitempty"`
+}
+
+// stopInterrupted reports a Ctrl-C during one of the stop waits. The stop
+// request has already been sent, so the VM may still go down on its own.
+func stopInterrupted(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	err := fmt.Errorf("interrupted; the VM may still be shutting down (check with 'br status')")
+	if jsonOutput {
+		emitJSONError(err)
+	}
+	return err
+}
+
+// killContext sends the kill command, giving up early if ctx is canceled.
+func killContext(ctx context.Context, client *control.Client) error {
+	errc := make(chan error, 1)
+	go func() { errc <- client.Kill() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // readHostPID returns the host process PID reported by the control server, or
@@ -134,12 +224,22 @@ func readHostPID(client *control.Client) int {
 // waitForSocketGone polls until the control socket disappears (process exited)
 // or the deadline passes. Returns true if the socket is gone.
 func waitForSocketGone(socketPath string, within time.Duration) bool {
+	return waitForSocketGoneContext(context.Background(), socketPath, within)
+}
+
+// waitForSocketGoneContext is waitForSocketGone that also stops waiting (and
+// returns false) once ctx is canceled.
+func waitForSocketGoneContext(ctx context.Context, socketPath string, within time.Duration) bool {
 	deadline := time.Now().Add(within)
 	for time.Now().Before(deadline) {
 		if _, err := os.Stat(socketPath); os.IsNotExist(err) {
 			return true
 		}
-		time.Sleep(500 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(500 * time.Millisecond):
+		}
 	}
 	_, err := os.Stat(socketPath)
 	return os.IsNotExist(err)
@@ -156,14 +256,14 @@ func forceTerminate(socketPath string, pid int) error {
 		return err
 	}
 	if !jsonOutput {
-		fmt.Printf("Graceful shutdown stalled; force-terminating host process %d...\n", pid)
+		fmt.Printf("VM did not stop; terminating host process %d...\n", pid)
 	}
 
 	_ = syscall.Kill(pid, syscall.SIGTERM)
 	if waitForProcessGone(pid, sigtermGrace) {
 		cleanupSocket(socketPath)
 		if jsonOutput {
-			return emitJSON(stopResult{Status: "force-stopped", Signal: "SIGTERM"})
+			return emitJSON(stopResult{Status: "force-stopped", Method: "signal", Signal: "SIGTERM"})
 		}
 		fmt.Println("VM force-stopped (SIGTERM)")
 		return nil
@@ -173,7 +273,7 @@ func forceTerminate(socketPath string, pid int) error {
 	if waitForProcessGone(pid, sigkillGrace) {
 		cleanupSocket(socketPath)
 		if jsonOutput {
-			return emitJSON(stopResult{Status: "force-stopped", Signal: "SIGKILL"})
+			return emitJSON(stopResult{Status: "force-stopped", Method: "signal", Signal: "SIGKILL"})
 		}
 		fmt.Println("VM force-stopped (SIGKILL)")
 		return nil
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+func TestWaitForSocketGoneContextCancel(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "control.sock")
+	if err := os.WriteFile(sock, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if waitForSocketGoneContext(ctx, sock, time.Minute) {
+		t.Fatal("socket still exists; wait should report false")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("canceled wait took %s; should return promptly", time.Since(start))
+	}
+	if err := stopInterrupted(ctx); err == nil {
+		t.Error("stopInterrupted should report a canceled context")
+	}
+	if err := stopInterrupted(context.Background()); err != nil {
+		t.Errorf("stopInterrupted(live ctx) = %v", err)
+	}
+}
+
+func TestWaitForSocketGoneContextSeesRemoval(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "control.sock")
+	if err := os.WriteFile(sock, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = os.Remove(sock)
+	}()
+	if !waitForSocketGoneContext(context.Background(), sock, 5*time.Second) {
+		t.Error("expected the removed socket to be seen as gone")
+	}
+}
+
+func TestStopOutcome(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name      string
+		res       vm.StopResult
+		ok        bool
+		method    string
+		acked     *bool
+		wantInMsg string
+	}{
+		{name: "no record", method: "graceful", wantInMsg: "VM stopped"},
+		{name: "graceful", res: vm.StopResult{Graceful: true, Attempts: 1}, ok: true, method: "graceful", acked: &yes, wantInMsg: "acknowledged"},
+		{name: "forced", res: vm.StopResult{Forced: true, Attempts: 3}, ok: true, method: "forced", acked: &no, wantInMsg: "ignored 3 shutdown request(s)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, msg := stopOutcome(tt.res, tt.ok)
+			if res.Status != control.StatusStopped || res.Method != tt.method {
+				t.Errorf("status/method = %q/%q, want %q/%q", res.Status, res.Method, control.StatusStopped, tt.method)
+			}
+			if (res.GuestAcknowledged == nil) != (tt.acked == nil) ||
+				(tt.acked != nil && *res.GuestAcknowledged != *tt.acked) {
+				t.Errorf("GuestAcknowledged = %v, want %v", res.GuestAcknowledged, tt.acked)
+			}
+			if !strings.Contains(msg, tt.wantInMsg) {
+				t.Errorf("message %q does not contain %q", msg, tt.wantInMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/incus"
+	"github.com/stuffbucket/bladerunner/internal/vm"
+)
+
+var trustCmd = &cobra.Command{
+	Use:     "trust",
+	Aliases: []string{"cert"},
+	Short:   "Manage the client certificate the guest's Incus trusts",
+	Long: `Manage the TLS client certificate bladerunner uses for the Incus API. Each
+'br start' adds it to the guest's trust store over SSH, so a new certificate
+takes effect without reprovisioning the VM.`,
+	Example: renderExamples(
+		example{Comment: "Replace a leaked or expiring client certificate", Args: "trust rotate"},
+		example{Comment: "The same, under its cert alias", Args: "cert rotate --json"},
+	),
+}
+
+var trustFlags struct {
+	name string
+}
+
+var trustRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Generate a new client certificate and trust it in the guest",
+	Long: `Generate a new Incus client key pair and replace the old one. With the VM
+running, the new certificate is trusted in the guest and the old one removed
+before the files are replaced, so a failed rotation keeps the old pair working.
+With the VM stopped, the files are replaced now and the guest trusts the new
+certificate (and drops the old one) on the next 'br start'.`,
+	Args: cobra.NoArgs,
+	RunE: runTrustRotate,
+}
+
+func init() {
+	trustCmd.PersistentFlags().StringVar(&trustFlags.name, "name", "", nameFlagUsage)
+	trustCmd.AddCommand(trustRotateCmd)
+}
+
+// trustRotateResult is the JSON payload emitted by `br trust rotate --json`.
+type trustRotateResult struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+	// Fingerprint is the new certificate's SHA-256, as `incus config trust
+	// list` shows it.
+	Fingerprint string `json:"fingerprint"`
+	// Pending is set when the VM was stopped, so the guest only trusts the
+	// new certificate from the next start.
+	Pending bool `json:"pending,omitempty"`
+}
+
+// trustRotateHandler serves control.CmdTrustRotate against the runner.
+func trustRotateHandler(getRunner func() *vm.Runner) control.HandlerFunc {
+	return func(ctx context.Context, _ *control.Request) *control.Message {
+		r := getRunner()
+		if r == nil {
+			return &control.Message{Error: "VM is not running yet"}
+		}
+		if err := r.RotateClientCert(ctx); err != nil {
+			return &control.Message{Error: err.Error()}
+		}
+		return &control.Message{Response: control.RespOK}
+	}
+}
+
+func runTrustRotate(_ *cobra.Command, _ []string) error {
+	cfg, err := config.Default("", trustFlags.name)
+	if err != nil {
+		return jsonOrError(fmt.Errorf("load defaults: %w", err))
+	}
+	res := trustRotateResult{Cert: cfg.ClientCertPath, Key: cfg.ClientKeyPath}
+
+	var certPEM []byte
+	client := control.NewClient(cfg.VMDir)
+	if client.IsRunning() {
+		if err := client.RotateTrust(); err != nil {
+			return jsonOrError(err)
+		}
+		if certPEM, err = os.ReadFile(cfg.ClientCertPath); err != nil {
+			return jsonOrError(fmt.Errorf("read client certificate: %w", err))
+		}
+	} else {
+		if certPEM, _, err = incus.RotateClientCertificate(cfg.ClientCertPath, cfg.ClientKeyPath); err != nil {
+			return jsonOrError(err)
+		}
+		res.Pending = true
+	}
+	if res.Fingerprint, err = incus.CertFingerprint(certPEM); err != nil {
+		return jsonOrError(err)
+	}
+
+	if jsonOutput {
+		return emitJSON(res)
+	}
+	fmt.Printf("%s Rotated the Incus client certificate\n", success("✓"))
+	fmt.Printf("  %s %s\n", key("Cert:"), value(res.Cert))
+	fmt.Printf("  %s %s\n", key("Key:"), value(res.Key))
+	fmt.Printf("  %s %s\n", key("Fingerprint:"), value(res.Fingerprint))
+	if res.Pending {
+		fmt.Println(subtle("The VM is not running; the guest trusts the new certificate on the next '" + withName("br start", trustFlags.name) + "'."))
+	}
+	return nil
+}
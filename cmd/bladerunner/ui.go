@@ -18,10 +18,11 @@ var (
 	command = ui.Command
 )
 
-// sshConfigFromControl retrieves the SSH config path from the running bladerunner
-// instance, offering to start the VM first when one is needed (see requireRunningVM).
-func sshConfigFromControl() (string, error) {
-	client, err := requireRunningVM()
+// sshConfigFromControl retrieves the SSH config path from the running VM called
+// name (a named VM publishes its own config-<name> file), offering to start the
+// VM first when one is needed (see requireRunningVM).
+func sshConfigFromControl(name string) (string, error) {
+	client, err := requireRunningVM(name)
 	if err != nil {
 		return "", err
 	}
@@ -28,7 +28,7 @@ into it. Use 'br start' directly when you need to pass CPU/memory/image flags.`,
 func runUp(cmd *cobra.Command, args []string) error {
 	// If a VM is already running, don't try to start a second one (runStart
 	// would error) — just report and point at the next steps.
-	if cfg, err := config.Default(startFlags.stateDir); err == nil {
+	if cfg, err := config.Default(startFlags.stateDir, startFlags.name); err == nil {
 		if control.NewClient(cfg.VMDir).IsRunning() {
 			if jsonOutput {
 				return emitJSON(map[string]string{jsonFieldStatus: "already-running"})
@@ -13,7 +13,6 @@ import (
 
 	"golang.org/x/term"
 
-	"github.com/stuffbucket/bladerunner/internal/config"
 	"github.com/stuffbucket/bladerunner/internal/control"
 	"github.com/stuffbucket/bladerunner/internal/logging"
 )
@@ -27,21 +26,29 @@ var errVMNotRunning = errors.New("VM is not running; start it with 'br start'")
 // VM to publish its readiness signal.
 const vmStartReadyTimeout = 3 * time.Minute
 
-// requireRunningVM returns a control client for a running VM. If the VM is not
-// running it offers to start it when attached to an interactive terminal;
-// otherwise (or if the user declines) it returns errVMNotRunning. The raw
+// requireRunningVM returns a control client for the running VM called name
+// ("" for the default one). If the VM is not running it offers to start it
+// when attached to an interactive terminal; otherwise (or if the user declines) it returns errVMNotRunning. The raw
 // control-socket dial failure is logged, never printed, so the terminal stays
 // clean. Commands that need the VM (all except `status`) should funnel through
 // this rather than touching the control client directly.
-func requireRunningVM() (*control.Client, error) {
-	stateDir := config.DefaultStateDir()
-	client := control.NewClient(stateDir)
+func requireRunningVM(name string) (*control.Client, error) {
+	vmDir, err := namedVMDir(name)
+	if err != nil {
+		return nil, err
+	}
+	client := control.NewClient(vmDir)
 	if client.IsRunning() {
+		// Fail loudly on a server this binary would misparse (one started by
+		// an incompatible bladerunner) rather than on its first reply.
+		if _, err := client.Hello(); err != nil {
+			return nil, err
+		}
 		return client, nil
 	}
 	// Log the detail for `BLADERUNNER_LOG_LEVEL=debug`; keep it off the terminal.
 	logging.L().Debug("VM control socket unreachable; VM not running",
-		"socket", control.SocketPath(stateDir))
+		"socket", control.SocketPath(vmDir))
 
 	if !interactiveTerminal() {
 		return nil, errVMNotRunning
@@ -49,7 +56,7 @@ func requireRunningVM() (*control.Client, error) {
 	if !confirmStartVM() {
 		return nil, errVMNotRunning
 	}
-	if err := startVMDetachedAndWait(stateDir); err != nil {
+	if err := startVMDetachedAndWait(vmDir, name); err != nil {
 		return nil, err
 	}
 	return client, nil
@@ -85,11 +92,11 @@ func confirmStartVMFrom(r io.Reader) bool {
 	}
 }
 
-// startVMDetachedAndWait launches `br start` as a detached background process
-// (so it outlives this short-lived command and becomes the VM host) and waits
-// until the VM publishes its SSH config path — the signal that StartVM has
+// startVMDetachedAndWait launches `br start [--name name]` as a detached
+// background process (so it outlives this short-lived command and becomes the
+// VM host) and waits, on the control socket in vmDir, until the VM publishes its SSH config path — the signal that StartVM has
 // returned and the VM is up — or the timeout elapses.
-func startVMDetachedAndWait(stateDir string) error {
+func startVMDetachedAndWait(vmDir, name string) error {
 	exe, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("locate br executable: %w", err)
@@ -102,7 +109,11 @@ func startVMDetachedAndWait(stateDir string) error {
 
 	// context.Background(): the child must outlive this short-lived command, so
 	// it is intentionally not bound to a cancelable context.
-	cmd := exec.CommandContext(context.Background(), exe, "start")
+	startArgs := []string{"start"}
+	if name != "" {
+		startArgs = append(startArgs, "--name", name)
+	}
+	cmd := exec.CommandContext(context.Background(), exe, startArgs...)
 	cmd.Stdin = devnull
 	cmd.Stdout = devnull
 	cmd.Stderr = devnull
@@ -116,7 +127,7 @@ func startVMDetachedAndWait(stateDir string) error {
 
 	fmt.Printf("%s Starting VM (pid %d)…\n", subtle("›"), pid)
 
-	client := control.NewClient(stateDir)
+	client := control.NewClient(vmDir)
 	deadline := time.Now().Add(vmStartReadyTimeout)
 	for time.Now().Before(deadline) {
 		if client.IsRunning() {
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+// nameFlagUsage is the --name help of the commands that address one VM.
+const nameFlagUsage = "VM to act on: a named VM started with 'br start --name' (default: the unnamed VM)"
+
+// namedVMDir returns the directory of the VM a --name flag picked: the state
+// dir itself for the default VM (name ""), else <state-dir>/<name>.
+func namedVMDir(name string) (string, error) {
+	return config.VMDirFor(config.DefaultStateDir(), name)
+}
+
+// withName appends "--name <name>" to a suggested br command line when name
+// picks a named VM, so the hint addresses the VM just acted on.
+func withName(cmdline, name string) string {
+	if name == "" {
+		return cmdline
+	}
+	return cmdline + " --name " + name
+}
+
+// leadingNameArg splits a leading "--name <vm>" or "--name=<vm>" off the args
+// of a command that passes the rest through untouched (DisableFlagParsing), so
+// only a --name given before anything else is taken as ours.
+func leadingNameArg(args []string) (name string, rest []string) {
+	if len(args) == 0 {
+		return "", args
+	}
+	if v, ok := strings.CutPrefix(args[0], "--name="); ok {
+		return v, args[1:]
+	}
+	if args[0] == "--name" && len(args) > 1 {
+		return args[1], args[2:]
+	}
+	return "", args
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLeadingNameArg(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		wantName string
+		wantRest []string
+	}{
+		{"none", []string{"list"}, "", []string{"list"}},
+		{"empty", nil, "", nil},
+		{"separate value", []string{"--name", "build", "list"}, "build", []string{"list"}},
+		{"equals value", []string{"--name=build", "--", "uname"}, "build", []string{"--", "uname"}},
+		{"not leading", []string{"launch", "--name", "x"}, "", []string{"launch", "--name", "x"}},
+		{"missing value", []string{"--name"}, "", []string{"--name"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, rest := leadingNameArg(tc.args)
+			if name != tc.wantName || !slices.Equal(rest, tc.wantRest) {
+				t.Fatalf("leadingNameArg(%q) = %q, %q; want %q, %q", tc.args, name, rest, tc.wantName, tc.wantRest)
+			}
+		})
+	}
+}
+
+func TestWithName(t *testing.T) {
+	if got := withName("br start", ""); got != "br start" {
+		t.Fatalf("withName default = %q", got)
+	}
+	if got := withName("br start", "build"); got != "br start --name build" {
+		t.Fatalf("withName named = %q", got)
+	}
+}
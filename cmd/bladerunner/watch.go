@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stuffbucket/bladerunner/internal/boot"
+	"github.com/stuffbucket/bladerunner/internal/bootstage"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+)
+
+var watchFlags struct {
+	name string
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream VM lifecycle events (boot, network, Incus)",
+	Long: `Stream a live, ordered feed of lifecycle events: the VM host coming up,
+kernel boot, guest bootstrap stages, SSH and port forwards, and Incus readiness.
+
+Each event is timestamped and tagged boot, net or incus. Start it before
+'br start' to see a boot from the first kernel line, or run it against a
+running VM to follow it from now on. With --json each event is a JSON line.`,
+	Example: renderExamples(
+		example{Comment: "Follow a start from another terminal", Args: "watch"},
+		example{Comment: "Machine-readable event stream", Args: "watch --json"},
+	),
+	Args: cobra.NoArgs,
+	RunE: runWatch,
+}
+
+func init() {
+	watchCmd.Flags().StringVar(&watchFlags.name, "name", "", nameFlagUsage)
+}
+
+// Event categories.
+const (
+	watchBoot  = "boot"
+	watchNet   = "net"
+	watchIncus = "incus"
+)
+
+// watchPollInterval paces the control-socket and boot-stage polls; console
+// lines stream as they are written.
+const watchPollInterval = time.Second
+
+// watchEvent is one line of `br watch` output.
+type watchEvent struct {
+	Time     time.Time `json:"time"`
+	Category string    `json:"category"`
+	Event    string    `json:"event"`
+	Message  string    `json:"message"`
+}
+
+func runWatch(_ *cobra.Command, _ []string) error {
+	cfg, err := config.Default("", watchFlags.name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	// Polled every second: hold one connection rather than dial per poll.
+	client := control.NewClientWithConfig(control.ClientConfig{StateDir: cfg.VMDir, KeepAlive: true})
+	defer func() { _ = client.Close() }()
+	emit := watchPrinter(os.Stdout)
+	w := newWatchState()
+	poll := func() {
+		var info *control.StatusInfo
+		if client.IsRunning() {
+			var err error
+			// A host that predates status.json still counts as up.
+			if info, err = client.GetStatusInfo(); err != nil {
+				info = &control.StatusInfo{State: control.StatusRunning}
+			}
+		}
+		now := time.Now()
+		emit(w.status(info, now)...)
+		// The boot-stage file only describes a run while its host is up.
+		if st, ok := bootstage.Read(cfg.VMDir); ok && info != nil {
+			emit(w.stage(st.Stage, now)...)
+		}
+	}
+
+	// The console log is appended across runs, so only lines written from
+	// now on describe the boot being watched.
+	lines := boot.WatchEvents(ctx, cfg.ConsoleLogPath, boot.WatchOptions{FromEnd: true})
+	tick := time.NewTicker(watchPollInterval)
+	defer tick.Stop()
+	poll()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			emit(w.consoleLine(ev.Line, time.Now())...)
+		case <-tick.C:
+			poll()
+		}
+	}
+}
+
+// watchPrinter returns the event sink for the current output mode: JSON lines
+// or one styled line per event.
+func watchPrinter(out io.Writer) func(...watchEvent) {
+	if jsonOutput {
+		enc := json.NewEncoder(out)
+		return func(evs ...watchEvent) {
+			for _, ev := range evs {
+				_ = enc.Encode(ev)
+			}
+		}
+	}
+	return func(evs ...watchEvent) {
+		for _, ev := range evs {
+			_, _ = fmt.Fprintf(out, "%s %s %s\n", subtle(ev.Time.Format("15:04:05.000")), key(fmt.Sprintf("%-5s", ev.Category)), ev.Message)
+		}
+	}
+}
+
+// watchState turns raw observations (console lines, status polls, boot-stage
+// reads) into discrete events, each reported once per VM run. It is reset
+// when the VM host goes away so the next start is reported in full.
+type watchState struct {
+	attached bool // the first status poll has been reported
+	hostUp   bool
+	running  bool
+	state    string
+	incus    bool
+	forwards map[string]control.ForwardInfo
+	stageNow bootstage.Stage
+	seen     map[string]bool // console signals already reported this run
+}
+
+func newWatchState() *watchState {
+	w := &watchState{}
+	w.reset()
+	return w
+}
+
+func (w *watchState) reset() {
+	w.hostUp, w.running, w.incus = false, false, false
+	w.state = ""
+	w.stageNow = ""
+	w.forwards = map[string]control.ForwardInfo{}
+	w.seen = map[string]bool{}
+}
+
+// consoleSignals maps the boot package's console detections onto events, in
+// boot order.
+var consoleSignals = []struct {
+	event, category, message string
+	has                      func(boot.Status) bool
+}{
+	{"kernel-booted", watchBoot, "Linux kernel booting", func(s boot.Status) bool { return s.KernelBooted }},
+	{"systemd-target", watchBoot, "systemd reached its first target", func(s boot.Status) bool { return s.SystemdReached }},
+	{"cloud-init-done", watchBoot, "cloud-init finished", func(s boot.Status) bool { return s.CloudInitDone }},
+	{"cloud-init-failed", watchBoot, "cloud-init reported a failure", func(s boot.Status) bool { return s.CloudInitFailed }},
+	{"sshd-ready", watchNet, "guest sshd listening", func(s boot.Status) bool { return s.SSHReady }},
+	{"incusd-started", watchIncus, "incusd started in the guest", func(s boot.Status) bool { return s.IncusReady }},
+	{"kernel-panic", watchBoot, "kernel panic", func(s boot.Status) bool { return s.KernelPanic }},
+	{"emergency-mode", watchBoot, "guest dropped to emergency mode", func(s boot.Status) bool { return s.EmergencyMode }},
+	{"disk-full", watchBoot, diskFullHint, func(s boot.Status) bool { return s.DiskFull }},
+}
+
+func (w *watchState) consoleLine(line string, now time.Time) []watchEvent {
+	if stage, _, ok := boot.BootstrapStage(line); ok {
+		id := "bootstrap:" + stage
+		if w.seen[id] {
+			return nil
+		}
+		w.seen[id] = true
+		return []watchEvent{{Time: now, Category: bootstrapCategory(stage), Event: "bootstrap-" + stage, Message: "bootstrap: " + stage}}
+	}
+
+	st := boot.LineStatus(line)
+	var evs []watchEvent
+	for _, sig := range consoleSignals {
+		if !sig.has(st) || w.seen[sig.event] {
+			continue
+		}
+		w.seen[sig.event] = true
+		msg := sig.message
+		if sig.event == "cloud-init-failed" || sig.event == "kernel-panic" || sig.event == "emergency-mode" || sig.event == "disk-full" {
+			msg += ": " + strings.TrimSpace(line)
+		}
+		evs = append(evs, watchEvent{Time: now, Category: sig.category, Event: sig.event, Message: msg})
+	}
+	return evs
+}
+
+// bootstrapCategory files a guest bootstrap stage (see provision's br_stage)
+// under the subsystem it concerns.
+func bootstrapCategory(stage string) string {
+	switch {
+	case strings.Contains(stage, "incus"):
+		return watchIncus
+	case strings.HasPrefix(stage, "ssh"), strings.HasPrefix(stage, "vsock"):
+		return watchNet
+	default:
+		return watchBoot
+	}
+}
+
+// status diffs a control-socket status poll (nil when no VM host answers)
+// against the last one.
+func (w *watchState) status(info *control.StatusInfo, now time.Time) []watchEvent {
+	ev := func(cat, event, msg string) watchEvent {
+		return watchEvent{Time: now, Category: cat, Event: event, Message: msg}
+	}
+	var evs []watchEvent
+
+	if !w.attached {
+		w.attached = true
+		if info == nil {
+			evs = append(evs, ev(watchBoot, "waiting", "no VM running; waiting for 'br start'"))
+		} else {
+			evs = append(evs, ev(watchBoot, "attached", fmt.Sprintf("attached to running VM (%s)", info.State)))
+		}
+	}
+
+	if info == nil {
+		if w.hostUp {
+			evs = append(evs, ev(watchBoot, "vm-stopped", "VM host stopped"))
+			w.reset()
+		}
+		return evs
+	}
+
+	if !w.hostUp {
+		w.hostUp = true
+		msg := "VM host up"
+		if info.PID > 0 {
+			msg = fmt.Sprintf("VM host up (pid %d)", info.PID)
+		}
+		evs = append(evs, ev(watchBoot, "vm-host-up", msg))
+	}
+	if info.StartedAt != nil && !w.running {
+		w.running = true
+		evs = append(evs, ev(watchBoot, "vm-running", "VM running"))
+	}
+	if w.state != "" && info.State != w.state {
+		switch info.State {
+		case control.StatusPaused:
+			evs = append(evs, ev(watchBoot, "vm-paused", "VM paused"))
+		case control.StatusUnreachable:
+			evs = append(evs, ev(watchNet, "guest-unreachable", "guest stopped answering liveness probes"))
+		case control.StatusRunning:
+			if w.state == control.StatusPaused {
+				evs = append(evs, ev(watchBoot, "vm-resumed", "VM resumed"))
+			} else {
+				evs = append(evs, ev(watchNet, "guest-reachable", "guest answering liveness probes"))
+			}
+		}
+	}
+	w.state = info.State
+
+	evs = append(evs, w.forwardEvents(info.Forwards, ev)...)
+
+	if info.IncusReady && !w.incus {
+		evs = append(evs, ev(watchIncus, "incus-ready", "Incus API ready"))
+	}
+	w.incus = info.IncusReady
+	return evs
+}
+
+func (w *watchState) forwardEvents(fwds []control.ForwardInfo, ev func(cat, event, msg string) watchEvent) []watchEvent {
+	var evs []watchEvent
+	current := make(map[string]control.ForwardInfo, len(fwds))
+	for _, f := range fwds {
+		current[f.Name] = f
+		prev, known := w.forwards[f.Name]
+		switch {
+		case !known && f.Paused:
+			evs = append(evs, ev(watchNet, "forward-paused", fmt.Sprintf("%s forward on %s (paused)", f.Name, f.Listen)))
+		case !known:
+			evs = append(evs, ev(watchNet, "forward-up", fmt.Sprintf("%s forward listening on %s", f.Name, f.Listen)))
+		case f.Paused && !prev.Paused:
+			evs = append(evs, ev(watchNet, "forward-paused", fmt.Sprintf("%s forward paused", f.Name)))
+		case !f.Paused && prev.Paused:
+			evs = append(evs, ev(watchNet, "forward-resumed", fmt.Sprintf("%s forward resumed on %s", f.Name, f.Listen)))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(w.forwards)) {
+		if _, ok := current[name]; !ok {
+			evs = append(evs, ev(watchNet, "forward-down", fmt.Sprintf("%s forward closed", name)))
+		}
+	}
+	w.forwards = current
+	return evs
+}
+
+// stage reports the runner's coarse boot phase (the menubar's splash text)
+// when it moves.
+func (w *watchState) stage(s bootstage.Stage, now time.Time) []watchEvent {
+	if s == w.stageNow {
+		return nil
+	}
+	w.stageNow = s
+	return []watchEvent{{Time: now, Category: watchBoot, Event: "phase-" + string(s), Message: "phase: " + bootstage.Message(s)}}
+}
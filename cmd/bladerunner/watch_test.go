@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/bootstage"
+	"github.com/stuffbucket/bladerunner/internal/control"
+)
+
+func watchEventIDs(evs []watchEvent) []string {
+	ids := make([]string, 0, len(evs))
+	for _, ev := range evs {
+		ids = append(ids, ev.Category+"/"+ev.Event)
+	}
+	return ids
+}
+
+// A full start seen from a watcher that was already waiting: every step of
+// the lifecycle shows up once, in order.
+func TestWatchStateLifecycle(t *testing.T) {
+	w := newWatchState()
+	now := time.Now()
+	started := now
+	var got []string
+	add := func(evs []watchEvent) { got = append(got, watchEventIDs(evs)...) }
+
+	add(w.status(nil, now))
+	add(w.status(&control.StatusInfo{State: control.StatusRunning, PID: 42}, now))
+	add(w.stage(bootstage.Boot, now))
+	add(w.status(&control.StatusInfo{State: control.StatusRunning, PID: 42, StartedAt: &started}, now))
+	add(w.consoleLine("[    0.000000] Booting Linux on physical CPU 0x0", now))
+	add(w.consoleLine("[    0.000001] Linux version 6.12.0", now)) // already reported
+	add(w.consoleLine("bladerunner-bootstrap: stage=ssh-up t=2026-01-01T00:00:00Z", now))
+	add(w.stage(bootstage.Incus, now))
+	add(w.consoleLine("bladerunner-bootstrap: stage=incus-ready t=2026-01-01T00:00:05Z", now))
+	fwd := []control.ForwardInfo{{Name: "ssh", Listen: "127.0.0.1:2222"}}
+	add(w.status(&control.StatusInfo{State: control.StatusRunning, StartedAt: &started, Forwards: fwd}, now))
+	add(w.status(&control.StatusInfo{State: control.StatusRunning, StartedAt: &started, Forwards: fwd, IncusReady: true}, now))
+	add(w.status(&control.StatusInfo{State: control.StatusRunning, StartedAt: &started, Forwards: fwd, IncusReady: true}, now))
+	add(w.status(nil, now))
+
+	want := []string{
+		"boot/waiting",
+		"boot/vm-host-up",
+		"boot/phase-boot",
+		"boot/vm-running",
+		"boot/kernel-booted",
+		"net/bootstrap-ssh-up",
+		"boot/phase-incus",
+		"incus/bootstrap-incus-ready",
+		"net/forward-up",
+		"incus/incus-ready",
+		"boot/vm-stopped",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("events:\n got %v\nwant %v", got, want)
+	}
+
+	// After a stop the next run is reported from scratch.
+	if ids := watchEventIDs(w.consoleLine("Booting Linux on physical CPU 0x0", now)); len(ids) != 1 || ids[0] != "boot/kernel-booted" {
+		t.Errorf("second boot = %v, want kernel-booted again", ids)
+	}
+}
+
+func TestWatchStateAttachAndForwards(t *testing.T) {
+	w := newWatchState()
+	now := time.Now()
+	info := &control.StatusInfo{State: control.StatusRunning, Forwards: []control.ForwardInfo{
+		{Name: "api", Listen: "127.0.0.1:18443"},
+		{Name: "ssh", Listen: "127.0.0.1:2222", Paused: true},
+	}}
+	got := watchEventIDs(w.status(info, now))
+	want := []string{"boot/attached", "boot/vm-host-up", "net/forward-up", "net/forward-paused"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("attach = %v, want %v", got, want)
+	}
+
+	info = &control.StatusInfo{State: control.StatusUnreachable, Forwards: []control.ForwardInfo{
+		{Name: "ssh", Listen: "127.0.0.1:2222"},
+	}}
+	got = watchEventIDs(w.status(info, now))
+	want = []string{"net/guest-unreachable", "net/forward-resumed", "net/forward-down"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
+
+func TestWatchStatePauseResume(t *testing.T) {
+	w := newWatchState()
+	now := time.Now()
+	w.status(&control.StatusInfo{State: control.StatusRunning}, now)
+
+	got := watchEventIDs(w.status(&control.StatusInfo{State: control.StatusPaused}, now))
+	if strings.Join(got, " ") != "boot/vm-paused" {
+		t.Errorf("pause = %v, want [boot/vm-paused]", got)
+	}
+	got = watchEventIDs(w.status(&control.StatusInfo{State: control.StatusRunning}, now))
+	if strings.Join(got, " ") != "boot/vm-resumed" {
+		t.Errorf("resume = %v, want [boot/vm-resumed]", got)
+	}
+}
+
+func TestWatchConsoleFailureCarriesLine(t *testing.T) {
+	w := newWatchState()
+	evs := w.consoleLine("  Kernel panic - not syncing: VFS: Unable to mount root fs", time.Now())
+	if len(evs) == 0 || evs[0].Event != "kernel-panic" {
+		t.Fatalf("events = %v", watchEventIDs(evs))
+	}
+	if !strings.Contains(evs[0].Message, "Unable to mount root fs") {
+		t.Errorf("message %q should quote the console line", evs[0].Message)
+	}
+}
+
+func TestWatchConsoleDiskFull(t *testing.T) {
+	w := newWatchState()
+	evs := w.consoleLine("incusd[412]: write /var/lib/incus/database/global/db.bin: no space left on device", time.Now())
+	if ids := watchEventIDs(evs); len(ids) != 1 || ids[0] != "boot/disk-full" {
+		t.Fatalf("events = %v, want boot/disk-full", ids)
+	}
+	if !strings.Contains(evs[0].Message, "br reset") || !strings.Contains(evs[0].Message, "no space left") {
+		t.Errorf("message %q should carry the hint and the console line", evs[0].Message)
+	}
+}
+
+func TestWatchPrinterJSON(t *testing.T) {
+	saved := jsonOutput
+	jsonOutput = true
+	t.Cleanup(func() { jsonOutput = saved })
+
+	var out bytes.Buffer
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	watchPrinter(&out)(
+		watchEvent{Time: at, Category: watchIncus, Event: "incus-ready", Message: "Incus API ready"},
+		watchEvent{Time: at, Category: watchNet, Event: "forward-up", Message: "ssh"},
+	)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want one JSON line per event, got %q", out.String())
+	}
+	var ev watchEvent
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Category != watchIncus || ev.Event != "incus-ready" || !ev.Time.Equal(at) {
+		t.Errorf("decoded %+v", ev)
+	}
+}
@@ -36,6 +36,11 @@ const (
 	webHTTPTimeout = 10 * time.Second
 )
 
+// webFlags are shared by 'br web' and its subcommands.
+var webFlags struct {
+	name string
+}
+
 var webCmd = &cobra.Command{
 	Use:   "web",
 	Short: "Open the Incus web UI with single sign-on",
@@ -88,17 +93,18 @@ var webUntrustCmd = &cobra.Command{
 }
 
 func init() {
+	webCmd.PersistentFlags().StringVar(&webFlags.name, "name", "", nameFlagUsage)
 	webCmd.AddCommand(webApproveCmd)
 	webTrustCmd.Flags().BoolVar(&webTrustFlags.system, "system", false, "Install into the system keychain (trusts for all users; requires sudo)")
 	webCmd.AddCommand(webTrustCmd, webUntrustCmd)
 }
 
-// webHostPort returns "127.0.0.1:<web-port>" for the running VM's web proxy —
-// the endpoint the browser actually connects to, and whose certificate must be
-// trusted to silence the "not private" warning. Falls back to the Incus API
-// port when the proxy port isn't published (older engine).
-func webHostPort() (string, error) {
-	client, err := requireRunningVM()
+// webHostPort returns "127.0.0.1:<web-port>" for the web proxy of the running
+// VM called name — the endpoint the browser actually connects to, and whose
+// certificate must be trusted to silence the "not private" warning. Falls back
+// to the Incus API port when the proxy port isn't published (older engine).
+func webHostPort(name string) (string, error) {
+	client, err := requireRunningVM(name)
 	if err != nil {
 		return "", err
 	}
@@ -137,7 +143,7 @@ func fetchIncusServerCertPEM(hostPort string) ([]byte, error) {
 }
 
 func runWebTrust(_ *cobra.Command, _ []string) error {
-	hostPort, err := webHostPort()
+	hostPort, err := webHostPort(webFlags.name)
 	if err != nil {
 		return err
 	}
@@ -172,13 +178,13 @@ func runWebUntrust(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-// webEndpoints resolves the running VM's provider URL, Incus UI URLs and SSH key
-// path from the control socket. incusUI is the UI root (for the manual-login
+// webEndpoints resolves the provider URL, Incus UI URLs and SSH key path of the
+// running VM called name from its control socket. incusUI is the UI root (for the manual-login
 // fallback); incusLogin is Incus's /oidc/login entry point, which initiates the
 // OIDC redirect itself so the browser lands authenticated without the user
 // having to click "Login with SSO" on the Incus login page.
-func webEndpoints() (providerBase, incusUI, incusLogin, keyPath string, err error) {
-	client, err := requireRunningVM()
+func webEndpoints(name string) (providerBase, incusUI, incusLogin, keyPath string, err error) {
+	client, err := requireRunningVM(name)
 	if err != nil {
 		return "", "", "", "", err
 	}
@@ -213,7 +219,7 @@ func webEndpoints() (providerBase, incusUI, incusLogin, keyPath string, err erro
 }
 
 func runWeb(_ *cobra.Command, _ []string) error {
-	providerBase, incusUI, incusLogin, keyPath, err := webEndpoints()
+	providerBase, incusUI, incusLogin, keyPath, err := webEndpoints(webFlags.name)
 	if err != nil {
 		return err
 	}
@@ -243,7 +249,7 @@ func runWebApprove(_ *cobra.Command, args []string) error {
 	if reqID == "" {
 		return errors.New("request id is required")
 	}
-	providerBase, _, _, keyPath, err := webEndpoints()
+	providerBase, _, _, keyPath, err := webEndpoints(webFlags.name)
 	if err != nil {
 		return err
 	}
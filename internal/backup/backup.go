@@ -0,0 +1,287 @@
+// Package backup implements lightweight save points for a stopped VM: a single
+// timestamped tar.gz of the files that make up its disk and identity, stored
+// under <vmDir>/backups/. Unlike saved state it captures no RAM, and unlike a
+// snapshot chain there is no overlay to manage — a rollback simply puts the
+// archived files back.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/util"
+)
+
+const (
+	// DirName is the backups directory under the VM directory.
+	DirName = "backups"
+
+	archiveExt   = ".tar.gz"
+	namePrefix   = "backup-"
+	timeLayout   = "20060102T150405Z"
+	requiredFile = "disk.raw"
+)
+
+// Files lists the VM directory entries captured in a backup, relative to the
+// VM directory. disk.raw is required; the rest are included when present.
+var Files = []string{
+	"disk.raw",
+	"efi-vars.bin",
+	"machine-id.bin",
+	"runtime-metadata.json",
+}
+
+// ErrNoBackups is returned when a rollback is requested but none exist.
+var ErrNoBackups = errors.New("no backups found")
+
+// Info describes one backup archive.
+type Info struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Created time.Time `json:"created"`
+	Size    int64     `json:"size_bytes"`
+}
+
+// Dir returns the backups directory for vmDir.
+func Dir(vmDir string) string {
+	return filepath.Join(vmDir, DirName)
+}
+
+// Create archives the backup Files from vmDir into a new timestamped archive
+// and returns its Info. The caller must ensure the VM is stopped.
+func Create(vmDir string, now time.Time) (Info, error) {
+	if _, err := os.Stat(filepath.Join(vmDir, requiredFile)); err != nil {
+		return Info{}, fmt.Errorf("nothing to back up: %w", err)
+	}
+	dir := Dir(vmDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Info{}, fmt.Errorf("create backups dir: %w", err)
+	}
+
+	name := namePrefix + now.UTC().Format(timeLayout)
+	path := filepath.Join(dir, name+archiveExt)
+	if _, err := os.Stat(path); err == nil {
+		return Info{}, fmt.Errorf("backup %s already exists", name)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+name+"-*")
+	if err != nil {
+		return Info{}, fmt.Errorf("create backup: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := writeArchive(tmp, vmDir); err != nil {
+		_ = tmp.Close()
+		return Info{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Info{}, fmt.Errorf("close backup: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return Info{}, fmt.Errorf("finalize backup: %w", err)
+	}
+	return stat(path)
+}
+
+func writeArchive(w io.Writer, vmDir string) error {
+	gz, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gz)
+	for _, f := range Files {
+		if err := addFile(tw, vmDir, f); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("finish backup archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("finish backup archive: %w", err)
+	}
+	return nil
+}
+
+func addFile(tw *tar.Writer, vmDir, name string) error {
+	src, err := os.Open(filepath.Join(vmDir, name))
+	if errors.Is(err, os.ErrNotExist) && name != requiredFile {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open %s: %w", name, err)
+	}
+	defer func() { _ = src.Close() }()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", name, err)
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(fi.Mode().Perm()),
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		Format:  tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("archive %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, src); err != nil {
+		return fmt.Errorf("archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// List returns the backups under vmDir, newest first. A missing backups
+// directory yields an empty list.
+func List(vmDir string) ([]Info, error) {
+	entries, err := os.ReadDir(Dir(vmDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read backups dir: %w", err)
+	}
+	var out []Info
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), namePrefix) || !strings.HasSuffix(e.Name(), archiveExt) {
+			continue
+		}
+		info, err := stat(filepath.Join(Dir(vmDir), e.Name()))
+		if err != nil {
+			continue
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.After(out[j].Created) })
+	return out, nil
+}
+
+// Resolve finds the named backup (with or without the archive extension), or
+// the newest one when name is empty.
+func Resolve(vmDir, name string) (Info, error) {
+	backups, err := List(vmDir)
+	if err != nil {
+		return Info{}, err
+	}
+	if len(backups) == 0 {
+		return Info{}, ErrNoBackups
+	}
+	if name == "" {
+		return backups[0], nil
+	}
+	name = strings.TrimSuffix(name, archiveExt)
+	for _, b := range backups {
+		if b.Name == name {
+			return b, nil
+		}
+	}
+	return Info{}, fmt.Errorf("backup %q not found", name)
+}
+
+// Restore replaces the backup Files in vmDir with the contents of the archive.
+// Files listed in Files but absent from the archive are removed, so the VM
+// directory matches the moment the backup was taken. Each file is extracted to
+// a temporary name and renamed into place; the caller must ensure the VM is
+// stopped.
+func Restore(vmDir string, b Info) error {
+	f, err := os.Open(b.Path)
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("read backup %s: %w", b.Name, err)
+	}
+	tr := tar.NewReader(gz)
+
+	restored := make(map[string]bool, len(Files))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read backup %s: %w", b.Name, err)
+		}
+		if !isBackupFile(hdr.Name) {
+			return fmt.Errorf("backup %s: unexpected entry %q", b.Name, hdr.Name)
+		}
+		if err := extractFile(vmDir, hdr, tr); err != nil {
+			return err
+		}
+		restored[hdr.Name] = true
+	}
+	if !restored[requiredFile] {
+		return fmt.Errorf("backup %s: missing %s", b.Name, requiredFile)
+	}
+
+	for _, name := range Files {
+		if restored[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(vmDir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func isBackupFile(name string) bool {
+	for _, f := range Files {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+func extractFile(vmDir string, hdr *tar.Header, r io.Reader) error {
+	dst := filepath.Join(vmDir, hdr.Name)
+	tmp, err := os.CreateTemp(vmDir, "."+hdr.Name+"-rollback-*")
+	if err != nil {
+		return fmt.Errorf("restore %s: %w", hdr.Name, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	// Skip zero runs so a restored disk.raw stays sparse like the original.
+	if err := util.WriteSparse(tmp, r); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("restore %s: %w", hdr.Name, err)
+	}
+	if err := tmp.Chmod(os.FileMode(hdr.Mode).Perm()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("restore %s: %w", hdr.Name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("restore %s: %w", hdr.Name, err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("restore %s: %w", hdr.Name, err)
+	}
+	return nil
+}
+
+func stat(path string) (Info, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return Info{}, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), archiveExt)
+	created, err := time.Parse(timeLayout, strings.TrimPrefix(name, namePrefix))
+	if err != nil {
+		created = fi.ModTime()
+	}
+	return Info{Name: name, Path: path, Created: created, Size: fi.Size()}, nil
+}
@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeVMFiles(t *testing.T, dir string, contents map[string]string) {
+	t.Helper()
+	for name, body := range contents {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCreateAndRestoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writeVMFiles(t, dir, map[string]string{
+		"disk.raw":       "disk-v1\x00\x00\x00tail",
+		"efi-vars.bin":   "efi-v1",
+		"machine-id.bin": "mid-v1",
+	})
+
+	b, err := Create(dir, time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if b.Name != "backup-20260501T120000Z" || b.Size == 0 {
+		t.Fatalf("unexpected backup info: %+v", b)
+	}
+
+	// "Break" the VM: rewrite the disk and add a file the backup didn't have.
+	writeVMFiles(t, dir, map[string]string{
+		"disk.raw":              "corrupted",
+		"efi-vars.bin":          "efi-v2",
+		"runtime-metadata.json": "{}",
+	})
+
+	got, err := Resolve(dir, "")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if err := Restore(dir, got); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	if s := readFile(t, filepath.Join(dir, "disk.raw")); s != "disk-v1\x00\x00\x00tail" {
+		t.Errorf("disk.raw = %q", s)
+	}
+	if s := readFile(t, filepath.Join(dir, "efi-vars.bin")); s != "efi-v1" {
+		t.Errorf("efi-vars.bin = %q", s)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "runtime-metadata.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("runtime-metadata.json should be removed, stat err = %v", err)
+	}
+}
+
+func TestCreateRequiresDisk(t *testing.T) {
+	if _, err := Create(t.TempDir(), time.Now()); err == nil {
+		t.Fatal("expected error without disk.raw")
+	}
+}
+
+func TestListNewestFirst(t *testing.T) {
+	dir := t.TempDir()
+	writeVMFiles(t, dir, map[string]string{"disk.raw": "d"})
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		if _, err := Create(dir, base.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Stray files in the backups dir are ignored.
+	writeVMFiles(t, Dir(dir), map[string]string{"notes.txt": "x"})
+
+	list, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("got %d backups, want 3", len(list))
+	}
+	if list[0].Name != "backup-20260101T020000Z" {
+		t.Errorf("newest = %s", list[0].Name)
+	}
+
+	if _, err := Resolve(dir, "backup-20260101T000000Z.tar.gz"); err != nil {
+		t.Errorf("Resolve by file name: %v", err)
+	}
+	if _, err := Resolve(dir, "missing"); err == nil {
+		t.Error("expected error for unknown backup")
+	}
+	if _, err := Resolve(t.TempDir(), ""); !errors.Is(err, ErrNoBackups) {
+		t.Errorf("Resolve on empty dir = %v, want ErrNoBackups", err)
+	}
+}
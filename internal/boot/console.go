@@ -4,9 +4,12 @@ package boot
 import (
 	"bufio"
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,6 +24,28 @@ type Event struct {
 
 const maxErrorLength = 200
 
+// MaxErrors caps how many distinct errors a Status records. Repeats of an
+// already-recorded error never count against it; they only raise its Count.
+const MaxErrors = 10
+
+// Error is one distinct error seen on the console. Message is the first
+// occurrence as it appeared; Count is how many lines normalized to it.
+type Error struct {
+	Message string
+	Count   int
+
+	key string // normalizeError(line), what repeats are matched on
+}
+
+// String renders the error with its repeat count, e.g.
+// "connection refused ×14".
+func (e Error) String() string {
+	if e.Count > 1 {
+		return fmt.Sprintf("%s ×%d", e.Message, e.Count)
+	}
+	return e.Message
+}
+
 // Status represents the detected boot state from console output.
 type Status struct {
 	KernelBooted    bool
@@ -32,9 +57,50 @@ type Status struct {
 	LoginPrompt     bool
 	KernelPanic     bool
 	EmergencyMode   bool
+	// DiskFull is set when the guest reported running out of disk space,
+	// usually because the disk was created too small for the image.
+	DiskFull bool
+
+	// When each phase was reached, measured on the guest's kernel clock (time
+	// since the kernel started). Only meaningful once the matching flag is
+	// set; see Timeline.
+	KernelBootedAt  time.Duration
+	CloudInitDoneAt time.Duration
+	SSHReadyAt      time.Duration
+	IncusReadyAt    time.Duration
+
+	// Errors detected during boot, deduplicated and in first-seen order.
+	Errors []Error
 
-	// Errors detected during boot
-	Errors []string
+	// clock is the latest time seen on the console. Wall-clock stamps are
+	// mapped onto it through the first one seen (wallAnchor), which was
+	// written at wallAnchorAt.
+	clock        time.Duration
+	wallAnchor   time.Time
+	wallAnchorAt time.Duration
+}
+
+// Phase is one step of a boot timeline.
+type Phase struct {
+	Name string
+	// At is when the phase was reached, from the start of the kernel.
+	At time.Duration
+}
+
+// Timeline returns the phases reached so far, in the order they happened.
+func (s Status) Timeline() []Phase {
+	var out []Phase
+	add := func(reached bool, name string, at time.Duration) {
+		if reached {
+			out = append(out, Phase{Name: name, At: at})
+		}
+	}
+	add(s.KernelBooted, "kernel", s.KernelBootedAt)
+	add(s.CloudInitDone, "cloud-init", s.CloudInitDoneAt)
+	add(s.SSHReady, "ssh", s.SSHReadyAt)
+	add(s.IncusReady, "incus", s.IncusReadyAt)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At < out[j].At })
+	return out
 }
 
 // Pattern definitions for boot stage detection.
@@ -48,9 +114,160 @@ var (
 	patternLoginPrompt   = regexp.MustCompile(`(?i)login:|^[a-z]+ login:`)
 	patternKernelPanic   = regexp.MustCompile(`(?i)Kernel panic|BUG:|Oops:`)
 	patternEmergency     = regexp.MustCompile(`(?i)emergency\.target|You are in emergency mode|systemd-emergency`)
+	patternDiskFull      = regexp.MustCompile(`(?i)No space left on device|\bENOSPC\b`)
 	patternError         = regexp.MustCompile(`(?i)\berror\b.*:|failed to|cannot|unable to`)
+
+	// patternLinePrefix matches the per-line noise that differs between
+	// repeats of the same error: a kernel "[   12.345678]" timestamp, a
+	// syslog/ISO timestamp, and a "unit[pid]:" tag.
+	patternLinePrefix = regexp.MustCompile(`^(?:\[\s*\d+\.\d+\]\s*)?(?:\d{4}-\d\d-\d\d[T ][\d:.]+(?:Z|[+-]\d\d:?\d\d)?\s*|[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d\s*)?(?:(?:\S+\s+)?[\w@.-]+\[\d+\]:\s*)?`)
+	patternSpace      = regexp.MustCompile(`\s+`)
+
+	// Timestamps a console line may carry: the kernel's "[   12.345678]"
+	// uptime, a leading ISO or syslog date, and the "Up 48.12 seconds"
+	// cloud-init prints when it finishes.
+	patternKernelTime = regexp.MustCompile(`^\[\s*(\d+\.\d+)\]`)
+	patternISOTime    = regexp.MustCompile(`^(?:\[\s*\d+\.\d+\]\s*)?(\d{4}-\d\d-\d\d[T ]\d\d:\d\d:\d\d(?:\.\d+)?(?:Z|[+-]\d\d:?\d\d)?)`)
+	patternSyslogTime = regexp.MustCompile(`^(?:\[\s*\d+\.\d+\]\s*)?([A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d)`)
+	patternUptime     = regexp.MustCompile(`\bUp (\d+(?:\.\d+)?) seconds`)
+
+	// patternBootstrapStage matches the markers the guest bootstrap script's
+	// br_stage writes to the console (see provision.BuildCloudInit).
+	patternBootstrapStage = regexp.MustCompile(`bladerunner-bootstrap: stage=(\S+)(?: t=(\S+))?`)
 )
 
+// BootstrapStage extracts the stage name from a guest bootstrap marker line,
+// with the guest's timestamp when it carries a parseable one.
+func BootstrapStage(line string) (stage string, at time.Time, ok bool) {
+	m := patternBootstrapStage.FindStringSubmatch(line)
+	if m == nil {
+		return "", time.Time{}, false
+	}
+	if m[2] != "" {
+		at, _ = time.Parse(time.RFC3339, m[2])
+	}
+	return m[1], at, true
+}
+
+// LineStatus returns the boot signals a single console line carries, without
+// the accumulation WatchEvents applies across lines.
+func LineStatus(line string) Status {
+	var s Status
+	parseLine(&s, line)
+	return s
+}
+
+// Observe folds one console line into s, the way WatchEvents accumulates the
+// lines it tails.
+func (s *Status) Observe(line string) {
+	parseLine(s, line)
+}
+
+// NewBoot reports whether line starts a fresh kernel boot. The console log is
+// appended across runs, so a caller tracking the current boot resets its
+// Status on such a line.
+func NewBoot(line string) bool {
+	return patternKernelBoot.MatchString(line)
+}
+
+// Healthy reports whether the guest is up: sshd and Incus have started and
+// nothing fatal (a panic, emergency mode, a full disk or a cloud-init
+// failure) was seen.
+func (s Status) Healthy() bool {
+	return s.SSHReady && s.IncusReady && !s.failed()
+}
+
+func (s Status) failed() bool {
+	return s.KernelPanic || s.EmergencyMode || s.DiskFull || s.CloudInitFailed
+}
+
+// Summary describes the boot's progress in a few words, e.g. "ssh up,
+// waiting for incus (2 errors)": the fatal condition if one was seen,
+// otherwise the furthest stage reached.
+func (s Status) Summary() string {
+	var msg string
+	switch {
+	case s.KernelPanic:
+		msg = "kernel panic"
+	case s.EmergencyMode:
+		msg = "emergency mode"
+	case s.DiskFull:
+		// Checked before cloud-init: a full disk is usually why it failed.
+		msg = "disk full"
+	case s.CloudInitFailed:
+		msg = "cloud-init failed"
+	case s.Healthy():
+		msg = "healthy: ssh and incus up"
+	case s.IncusReady:
+		msg = "incus up, waiting for ssh"
+	case s.SSHReady:
+		msg = "ssh up, waiting for incus"
+	case s.CloudInitDone:
+		msg = "cloud-init done"
+	case s.SystemdReached:
+		msg = "systemd up"
+	case s.KernelBooted:
+		msg = "kernel booting"
+	default:
+		msg = "waiting for the kernel"
+	}
+	switch n := len(s.Errors); n {
+	case 0:
+	case 1:
+		msg += " (1 error)"
+	default:
+		msg += fmt.Sprintf(" (%d errors)", n)
+	}
+	return msg
+}
+
+// ScanFile parses a complete console log and returns the status accumulated
+// over all of its lines, for after-the-fact reporting.
+func ScanFile(path string) (Status, error) {
+	var s Status
+	_, err := ScanLines(path, s.Observe)
+	return s, err
+}
+
+// ScanLastBoot is ScanFile limited to the most recent boot in the log: the
+// status restarts at every NewBoot line.
+func ScanLastBoot(path string) (Status, error) {
+	var s Status
+	_, err := ScanLines(path, func(line string) {
+		if NewBoot(line) {
+			s = Status{}
+		}
+		s.Observe(line)
+	})
+	return s, err
+}
+
+// ScanLines calls fn for each line of the file at path, without its line
+// ending, and returns how many bytes it read.
+func ScanLines(path string, fn func(string)) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var n int64
+	r := bufio.NewReaderSize(f, readerBufferSize)
+	for {
+		line, err := r.ReadString('\n')
+		n += int64(len(line))
+		if line != "" {
+			fn(strings.TrimRight(line, "\r\n"))
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
 // WatchOptions configures WatchEvents.
 type WatchOptions struct {
 	// PollInterval is how often to re-stat the file. Required.
@@ -59,6 +276,9 @@ type WatchOptions struct {
 	// appended after WatchEvents starts is emitted. Useful when the log may
 	// already contain stale content from a previous run.
 	FromEnd bool
+	// StartAt skips the first StartAt bytes on initial open, for a caller
+	// that has already read them (see ScanLines). Ignored with FromEnd.
+	StartAt int64
 }
 
 // WatchEvents tails the file at path and emits one Event per new line. The
@@ -66,9 +286,9 @@ type WatchOptions struct {
 // for the file to appear and recovers from truncation/rotation by reopening
 // when the file shrinks.
 //
-// Unlike WatchFile, this function does not stop on its own — callers control
-// the lifetime via ctx so they can keep streaming console output even after
-// boot is "healthy" (the tail is still useful for diagnosing later failures).
+// It does not stop on its own — callers control the lifetime via ctx so they
+// can keep streaming console output even after boot is "healthy" (the tail is
+// still useful for diagnosing later failures).
 func WatchEvents(ctx context.Context, path string, opts WatchOptions) <-chan Event {
 	if opts.PollInterval <= 0 {
 		opts.PollInterval = defaultPollInterval
@@ -110,7 +330,7 @@ func runWatchEvents(ctx context.Context, path string, opts WatchOptions, ch chan
 	defer ticker.Stop()
 
 	for {
-		t.refreshOpen(path, opts.FromEnd)
+		t.refreshOpen(path, opts)
 		if !t.drainInto(ctx, ch) {
 			return
 		}
@@ -122,7 +342,7 @@ func runWatchEvents(ctx context.Context, path string, opts WatchOptions, ch chan
 	}
 }
 
-func (t *tailState) refreshOpen(path string, fromEnd bool) {
+func (t *tailState) refreshOpen(path string, opts WatchOptions) {
 	info, err := os.Stat(path)
 	if err != nil {
 		return
@@ -137,14 +357,21 @@ func (t *tailState) refreshOpen(path string, fromEnd bool) {
 	}
 
 	// On the very first open, optionally skip past existing content so the
-	// caller only sees new lines appended after the watcher started.
-	// Reopens triggered by truncation/rotation always start from the new
-	// file's beginning regardless — that's the whole point of detecting
-	// the shrink.
+	// caller only sees new lines appended after the watcher started (or
+	// after what it has already read). Reopens triggered by
+	// truncation/rotation always start from the new file's beginning
+	// regardless — that's the whole point of detecting the shrink.
 	var startPos int64
-	if fromEnd && !t.hasOpenedOnce {
-		if end, serr := f.Seek(0, io.SeekEnd); serr == nil {
-			startPos = end
+	if !t.hasOpenedOnce {
+		switch {
+		case opts.FromEnd:
+			if end, serr := f.Seek(0, io.SeekEnd); serr == nil {
+				startPos = end
+			}
+		case opts.StartAt > 0:
+			if pos, serr := f.Seek(opts.StartAt, io.SeekStart); serr == nil {
+				startPos = pos
+			}
 		}
 	}
 	t.hasOpenedOnce = true
@@ -185,41 +412,152 @@ func (t *tailState) drainInto(ctx context.Context, ch chan<- Event) bool {
 }
 
 func parseLine(status *Status, line string) {
+	at := status.lineTime(line)
+	// A line is recorded as one error however many patterns it trips, and
+	// as critical if any fatal one does.
+	critical := false
 	if patternKernelBoot.MatchString(line) {
-		status.KernelBooted = true
+		reach(&status.KernelBooted, &status.KernelBootedAt, at)
 	}
 	if patternSystemdTarget.MatchString(line) {
 		status.SystemdReached = true
 	}
 	if patternCloudInitDone.MatchString(line) {
+		// Early "ci-info" lines match too; the final "finished" line comes
+		// last, so the latest match is when cloud-init was done.
 		status.CloudInitDone = true
+		status.CloudInitDoneAt = at
 	}
 	if patternCloudInitFail.MatchString(line) {
 		status.CloudInitFailed = true
-		status.Errors = append(status.Errors, extractError(line))
+		critical = true
 	}
 	if patternSSHReady.MatchString(line) {
-		status.SSHReady = true
+		reach(&status.SSHReady, &status.SSHReadyAt, at)
 	}
 	if patternIncusReady.MatchString(line) {
-		status.IncusReady = true
+		reach(&status.IncusReady, &status.IncusReadyAt, at)
 	}
 	if patternLoginPrompt.MatchString(line) {
 		status.LoginPrompt = true
 	}
 	if patternKernelPanic.MatchString(line) {
 		status.KernelPanic = true
-		status.Errors = append(status.Errors, extractError(line))
+		critical = true
 	}
 	if patternEmergency.MatchString(line) {
 		status.EmergencyMode = true
-		status.Errors = append(status.Errors, extractError(line))
+		critical = true
+	}
+	if patternDiskFull.MatchString(line) {
+		status.DiskFull = true
+		critical = true
 	}
-	if len(status.Errors) < 10 && patternError.MatchString(line) {
-		if !isNoiseError(line) {
-			status.Errors = append(status.Errors, extractError(line))
+	if critical || (patternError.MatchString(line) && !isNoiseError(line)) {
+		addError(status, line, critical)
+	}
+}
+
+// reach sets a phase flag, recording at as its time the first time only.
+func reach(flag *bool, when *time.Duration, at time.Duration) {
+	if !*flag {
+		*flag = true
+		*when = at
+	}
+}
+
+// lineTime returns when line was written on the kernel clock and advances
+// s.clock to it. A kernel timestamp or cloud-init's uptime is used as is; a
+// wall-clock stamp (ISO, syslog or a bootstrap marker's t=) is placed relative
+// to the first one seen. A line without a usable stamp gets the latest time
+// seen, since console lines arrive in order.
+func (s *Status) lineTime(line string) time.Duration {
+	if m := patternKernelTime.FindStringSubmatch(line); m != nil {
+		if secs, err := strconv.ParseFloat(m[1], 64); err == nil {
+			s.clock = time.Duration(secs * float64(time.Second))
 		}
 	}
+	if wall, ok := lineWallTime(line); ok {
+		switch d := wall.Sub(s.wallAnchor); {
+		case s.wallAnchor.IsZero():
+			s.wallAnchor, s.wallAnchorAt = wall, s.clock
+		case d >= 0 && d < maxWallSpan && s.wallAnchorAt+d > s.clock:
+			s.clock = s.wallAnchorAt + d
+		}
+	}
+	if m := patternUptime.FindStringSubmatch(line); m != nil {
+		if secs, err := strconv.ParseFloat(m[1], 64); err == nil {
+			s.clock = time.Duration(secs * float64(time.Second))
+		}
+	}
+	return s.clock
+}
+
+// maxWallSpan bounds how far a wall-clock stamp may be from the anchor and
+// still count: a larger gap means a different stamp format (syslog dates have
+// no year) or a clock step, not boot progress.
+const maxWallSpan = 24 * time.Hour
+
+// isoLayouts are the ISO-style stamps lineWallTime accepts.
+var isoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// lineWallTime parses a wall-clock stamp from line, if it has one.
+func lineWallTime(line string) (time.Time, bool) {
+	if _, at, ok := BootstrapStage(line); ok && !at.IsZero() {
+		return at, true
+	}
+	if m := patternISOTime.FindStringSubmatch(line); m != nil {
+		for _, layout := range isoLayouts {
+			if t, err := time.Parse(layout, m[1]); err == nil {
+				return t, true
+			}
+		}
+	}
+	if m := patternSyslogTime.FindStringSubmatch(line); m != nil {
+		if t, err := time.Parse(time.Stamp, m[1]); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// addError records line in status.Errors. A line that normalizes to an
+// already-recorded error only bumps that entry's count, so a flapping service
+// yields one counted entry instead of crowding out distinct errors. A new
+// error is dropped once MaxErrors distinct ones are held, unless critical
+// (panic, emergency mode, full disk, cloud-init failure), which is always
+// kept.
+func addError(status *Status, line string, critical bool) {
+	key := normalizeError(line)
+	for i := range status.Errors {
+		if status.Errors[i].key == key {
+			status.Errors[i].Count++
+			return
+		}
+	}
+	if !critical && len(status.Errors) >= MaxErrors {
+		return
+	}
+	status.Errors = append(status.Errors, Error{Message: extractError(line), Count: 1, key: key})
+}
+
+// normalizeError reduces a console line to what identifies the error: the
+// timestamp and pid prefix are stripped, whitespace collapsed and case
+// folded, so repeats of one failure compare equal.
+func normalizeError(line string) string {
+	line = strings.TrimSpace(line)
+	line = patternLinePrefix.ReplaceAllString(line, "")
+	line = patternSpace.ReplaceAllString(line, " ")
+	if len(line) > maxErrorLength {
+		line = line[:maxErrorLength]
+	}
+	return strings.ToLower(line)
 }
 
 func extractError(line string) string {
@@ -249,7 +587,7 @@ func isNoiseError(line string) bool {
 
 func copyStatus(s *Status) *Status {
 	cp := *s
-	cp.Errors = make([]string, len(s.Errors))
+	cp.Errors = make([]Error, len(s.Errors))
 	copy(cp.Errors, s.Errors)
 	return &cp
 }
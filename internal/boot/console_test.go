@@ -2,6 +2,7 @@ package boot
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -144,3 +145,249 @@ func TestExtractError_Truncation(t *testing.T) {
 		t.Error("expected truncated string to end with ...")
 	}
 }
+
+func TestBootstrapStage(t *testing.T) {
+	stage, at, ok := BootstrapStage("[   12.3] bladerunner-bootstrap: stage=incus-ready t=2026-01-02T03:04:05Z")
+	if !ok || stage != "incus-ready" {
+		t.Fatalf("BootstrapStage = %q, %v", stage, ok)
+	}
+	if want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC); !at.Equal(want) {
+		t.Errorf("at = %v, want %v", at, want)
+	}
+
+	if stage, at, ok := BootstrapStage("bladerunner-bootstrap: stage=start"); !ok || stage != "start" || !at.IsZero() {
+		t.Errorf("marker without t= = %q, %v, %v", stage, at, ok)
+	}
+	if _, _, ok := BootstrapStage("Booting Linux on physical CPU 0x0"); ok {
+		t.Error("plain console line parsed as a bootstrap stage")
+	}
+}
+
+func TestLineStatus(t *testing.T) {
+	if s := LineStatus("Booting Linux on physical CPU 0x0"); !s.KernelBooted || s.SSHReady {
+		t.Errorf("kernel line = %+v", s)
+	}
+	if s := LineStatus("Started ssh.service - OpenBSD Secure Shell server."); !s.SSHReady {
+		t.Errorf("ssh line = %+v", s)
+	}
+}
+
+// TestErrorsDeduplicateRepeats pins the flapping-service case: a repeating
+// error collapses into one counted entry and leaves room for distinct ones.
+func TestErrorsDeduplicateRepeats(t *testing.T) {
+	var s Status
+	for i := 0; i < 14; i++ {
+		parseLine(&s, fmt.Sprintf("[   %d.%06d] incusd[%d]: failed to connect: connection refused", 10+i, i, 300+i))
+	}
+	for i := 0; i < MaxErrors-1; i++ {
+		parseLine(&s, fmt.Sprintf("unable to mount volume %d", i))
+	}
+
+	if len(s.Errors) != MaxErrors {
+		t.Fatalf("len(Errors) = %d, want %d: %v", len(s.Errors), MaxErrors, s.Errors)
+	}
+	first := s.Errors[0]
+	if first.Count != 14 {
+		t.Errorf("repeated error Count = %d, want 14", first.Count)
+	}
+	if got := first.String(); !strings.HasSuffix(got, "connection refused ×14") {
+		t.Errorf("String() = %q, want a ×14 suffix", got)
+	}
+	if got := s.Errors[1].String(); got != "unable to mount volume 0" {
+		t.Errorf("single error String() = %q, want no count", got)
+	}
+
+	// At the cap, repeats still count but new distinct errors are dropped.
+	parseLine(&s, "[   99.000000] incusd[999]: failed to connect: connection refused")
+	parseLine(&s, "unable to do something new")
+	if len(s.Errors) != MaxErrors {
+		t.Errorf("len(Errors) = %d after cap, want %d", len(s.Errors), MaxErrors)
+	}
+	if s.Errors[0].Count != 15 {
+		t.Errorf("Count after cap = %d, want 15", s.Errors[0].Count)
+	}
+
+	// Critical errors are kept even past the cap.
+	parseLine(&s, "Kernel panic - not syncing: VFS: Unable to mount root fs")
+	if !s.KernelPanic || len(s.Errors) != MaxErrors+1 {
+		t.Errorf("kernel panic not recorded past the cap: %v", s.Errors)
+	}
+}
+
+// A full disk is critical and recorded once even though the line also
+// matches the generic error pattern.
+func TestDiskFull(t *testing.T) {
+	var s Status
+	parseLine(&s, "cloud-init[1123]: ERROR: write failed: No space left on device")
+	if !s.DiskFull {
+		t.Fatal("DiskFull not set")
+	}
+	if len(s.Errors) != 1 || s.Errors[0].Count != 1 {
+		t.Errorf("Errors = %v, want one entry counted once", s.Errors)
+	}
+	s.SSHReady, s.IncusReady = true, true
+	if s.Healthy() {
+		t.Error("full disk counted as healthy")
+	}
+	if got := s.Summary(); !strings.HasPrefix(got, "disk full") {
+		t.Errorf("Summary() = %q, want a disk full prefix", got)
+	}
+}
+
+func TestNormalizeError(t *testing.T) {
+	same := []string{
+		"[   12.345678] foo.service[123]: Failed to start thing",
+		"2024-05-01T10:00:00.123Z host foo.service[456]: Failed to start thing",
+		"May  1 10:00:00 host foo.service[789]: failed to  start thing",
+	}
+	want := normalizeError(same[0])
+	for _, l := range same[1:] {
+		if got := normalizeError(l); got != want {
+			t.Errorf("normalizeError(%q) = %q, want %q", l, got, want)
+		}
+	}
+	if normalizeError("Failed to start thing") == normalizeError("Failed to start other") {
+		t.Error("distinct messages normalized equal")
+	}
+}
+
+func TestScanFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.log")
+	content := "[    0.0] Linux version 6.x\n" +
+		"[    3.0] unable to open /dev/foo\n" +
+		"[    4.0] unable to open /dev/foo\n" +
+		"[    5.0] Reached target multi-user"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := ScanFile(path)
+	if err != nil {
+		t.Fatalf("ScanFile: %v", err)
+	}
+	if !s.KernelBooted || !s.SystemdReached {
+		t.Errorf("status = %+v, want kernel booted and systemd reached (incl. an unterminated last line)", s)
+	}
+	if len(s.Errors) != 1 || s.Errors[0].Count != 2 {
+		t.Errorf("Errors = %v, want one entry counted twice", s.Errors)
+	}
+	if _, err := ScanFile(filepath.Join(t.TempDir(), "missing.log")); err == nil {
+		t.Error("ScanFile of a missing file should fail")
+	}
+}
+
+func TestWatchEvents_StartAt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.log")
+	seen := "[seen] already printed\n"
+	if err := os.WriteFile(path, []byte(seen+"[new] not yet printed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	events := WatchEvents(ctx, path, WatchOptions{PollInterval: 20 * time.Millisecond, StartAt: int64(len(seen))})
+
+	select {
+	case ev := <-events:
+		if ev.Line != "[new] not yet printed" {
+			t.Errorf("first event = %q, want the line after StartAt", ev.Line)
+		}
+	case <-ctx.Done():
+		t.Fatal("no event emitted")
+	}
+}
+
+func TestScanLastBoot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.log")
+	log := "[    0.0] Linux version 6.x\n" +
+		"sshd[1]: Server listening on :: port 22\n" +
+		"Started incus.service - Incus - Main daemon\n" +
+		"reboot: Power down\n" +
+		"[    0.0] Linux version 6.x\n" +
+		"[    4.0] Reached target multi-user\n"
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := ScanFile(path)
+	if err != nil || !all.Healthy() {
+		t.Fatalf("ScanFile = %+v, %v; want the whole log healthy", all, err)
+	}
+	last, err := ScanLastBoot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Healthy() || last.SSHReady || !last.SystemdReached {
+		t.Errorf("ScanLastBoot = %+v, want only the second boot's systemd target", last)
+	}
+}
+
+func TestStatusSummary(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   string
+	}{
+		{Status{}, "waiting for the kernel"},
+		{Status{KernelBooted: true, SystemdReached: true}, "systemd up"},
+		{Status{SSHReady: true}, "ssh up, waiting for incus"},
+		{Status{SSHReady: true, IncusReady: true}, "healthy: ssh and incus up"},
+		{Status{SSHReady: true, IncusReady: true, KernelPanic: true}, "kernel panic"},
+		{Status{CloudInitFailed: true, Errors: []Error{{Message: "a"}, {Message: "b"}}}, "cloud-init failed (2 errors)"},
+	}
+	for _, tt := range tests {
+		if got := tt.status.Summary(); got != tt.want {
+			t.Errorf("Summary(%+v) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+	if (Status{SSHReady: true, IncusReady: true, EmergencyMode: true}).Healthy() {
+		t.Error("emergency mode counted as healthy")
+	}
+}
+
+func TestStatusTimeline(t *testing.T) {
+	var s Status
+	for _, line := range []string{
+		"[    0.000000] Linux version 6.12.0",
+		"[    3.500000] systemd[1]: Reached target multi-user.target",
+		// Wall-clock stamps are placed relative to the first one seen.
+		"2026-05-01T10:00:00Z cloud-init[512]: ci-info: eth0 up",
+		"2026-05-01T10:00:10Z sshd[700]: Server listening on 0.0.0.0 port 22.",
+		"Cloud-init v. 25.1 finished at Fri, 01 May 2026 10:00:44 +0000. Datasource DataSourceNoCloud. Up 48.12 seconds",
+		// No stamp: the latest time seen.
+		"[  OK  ] Started incus.service - Incus - Main daemon.",
+	} {
+		s.Observe(line)
+	}
+
+	want := []Phase{
+		{"kernel", 0},
+		{"ssh", 13500 * time.Millisecond},
+		{"cloud-init", 48120 * time.Millisecond},
+		{"incus", 48120 * time.Millisecond},
+	}
+	got := s.Timeline()
+	if len(got) != len(want) {
+		t.Fatalf("Timeline() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Timeline()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStatusTimelineSyslogAndBootstrapStamps(t *testing.T) {
+	var s Status
+	s.Observe("May  1 10:00:00 host systemd[1]: Starting ssh.service")
+	s.Observe("May  1 10:00:05 host sshd[9]: Server listening on :: port 22.")
+	if s.SSHReadyAt != 5*time.Second {
+		t.Errorf("SSHReadyAt = %v, want 5s after the first syslog stamp", s.SSHReadyAt)
+	}
+
+	s = Status{}
+	s.Observe("bladerunner-bootstrap: stage=start t=2026-05-01T10:00:00Z")
+	s.Observe("bladerunner-bootstrap: stage=incus-socket-up t=2026-05-01T10:01:11Z")
+	s.Observe("Started incus.service - Incus - Main daemon.")
+	if s.IncusReadyAt != 71*time.Second {
+		t.Errorf("IncusReadyAt = %v, want 71s from the bootstrap markers", s.IncusReadyAt)
+	}
+}
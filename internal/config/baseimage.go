@@ -0,0 +1,302 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// NormalizeBaseImage tidies a user-supplied base image location: surrounding
+// whitespace is trimmed and a local path has a leading ~/ expanded and is made
+// absolute, so later checks and log lines see the path that will be opened.
+func (c *Config) NormalizeBaseImage() {
+	c.BaseImageURL = strings.TrimSpace(c.BaseImageURL)
+	p := strings.TrimSpace(c.BaseImagePath)
+	if p == "" {
+		c.BaseImagePath = ""
+		return
+	}
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[1:])
+		}
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	c.BaseImagePath = p
+}
+
+// ValidateBaseImage checks the base image path before any setup runs, so a
+// missing --image-path fails immediately instead of minutes into
+// provisioning. It reads the filesystem, so it stays out of Validate; the URL,
+// which needs no I/O, is checked there instead (see validateBaseImageURL). A
+// ref wins over a path, matching the order assets resolves them in. The path
+// is only required to exist while the disk is still to be created: an
+// already-provisioned VM never reads it.
+func (c *Config) ValidateBaseImage() error {
+	if c.BaseImageRef != "" || c.BaseImagePath == "" {
+		return nil
+	}
+	if _, err := os.Stat(c.DiskPath); err == nil {
+		return nil
+	}
+	return checkBaseImagePath(c.BaseImagePath)
+}
+
+// validateBaseImageURL checks BaseImageURL when it is the image that would be
+// used: a local path wins over a URL, and a ref over both.
+func (c *Config) validateBaseImageURL() error {
+	if c.BaseImageRef != "" || c.BaseImagePath != "" || c.BaseImageURL == "" {
+		return nil
+	}
+	return ValidateBaseImageURL(c.BaseImageURL)
+}
+
+func checkBaseImagePath(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("base image path does not exist: %s (check --image-path)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("base image path: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("base image path is a directory, not a disk image: %s", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("base image path is not readable: %w", err)
+	}
+	_ = f.Close()
+	return nil
+}
+
+// ValidateBaseImageURL checks raw is an absolute http(s) URL, so a typo'd
+// --image-url fails immediately instead of minutes into provisioning.
+func ValidateBaseImageURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid base image URL %q: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		if u.Scheme == "" || u.Scheme == "file" {
+			return fmt.Errorf("invalid base image URL %q: must be an absolute http(s) URL (use --image-path for a local file)", raw)
+		}
+		return fmt.Errorf("invalid base image URL %q: scheme %q is not supported, use http or https", raw, u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid base image URL %q: missing host", raw)
+	}
+	return nil
+}
+
+// Distros selectable with `br start --distro`. Each has a built-in cloud image
+// per supported arch and a known package manager for the bootstrap script.
+const (
+	DistroDebian = "debian"
+	DistroUbuntu = "ubuntu"
+	DistroFedora = "fedora"
+)
+
+// Releases of the non-Debian --distro images. The Ubuntu image is the release's
+// rolling "release" build, verified against the directory's SHA256SUMS; the
+// Fedora one is a fixed build with no sidecar checksum to fetch.
+const (
+	UbuntuRelease    = "noble"
+	ubuntuVersion    = "24.04"
+	FedoraRelease    = "42"
+	fedoraCloudBuild = "1.1"
+)
+
+// Package managers a --distro's bootstrap script uses.
+const (
+	packageManagerApt = "apt"
+	packageManagerDNF = "dnf"
+)
+
+// distroImage is one --distro's image lookup: arches maps each supported GOARCH
+// to the name the distro uses for it in image URLs.
+type distroImage struct {
+	arches         map[string]string
+	url            func(arch string) string
+	packageManager string
+	// adminGroup grants sudo; empty means "sudo".
+	adminGroup string
+	// debianMirror marks images that take bladerunner's Debian apt mirror.
+	debianMirror bool
+}
+
+var distroImages = map[string]distroImage{
+	DistroDebian: {
+		arches: map[string]string{archARM64: archARM64, archAMD64: archAMD64},
+		url: func(arch string) string {
+			u, _ := DebianTrixieGenericCloudURL(arch)
+			return u
+		},
+		packageManager: packageManagerApt,
+		debianMirror:   true,
+	},
+	DistroUbuntu: {
+		arches: map[string]string{archARM64: archARM64, archAMD64: archAMD64},
+		url: func(arch string) string {
+			return fmt.Sprintf("https://cloud-images.ubuntu.com/releases/%s/release/ubuntu-%s-server-cloudimg-%s.img",
+				UbuntuRelease, ubuntuVersion, arch)
+		},
+		packageManager: packageManagerApt,
+	},
+	DistroFedora: {
+		arches: map[string]string{archARM64: "aarch64", archAMD64: "x86_64"},
+		url: func(arch string) string {
+			return fmt.Sprintf("https://download.fedoraproject.org/pub/fedora/linux/releases/%s/Cloud/%s/images/Fedora-Cloud-Base-Generic-%s-%s.%s.qcow2",
+				FedoraRelease, arch, FedoraRelease, fedoraCloudBuild, arch)
+		},
+		packageManager: packageManagerDNF,
+		adminGroup:     "wheel",
+	},
+}
+
+// Distros returns the names accepted by --distro, sorted.
+func Distros() []string {
+	names := make([]string, 0, len(distroImages))
+	for name := range distroImages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateDistro reports whether distro is a known --distro name.
+func ValidateDistro(distro string) error {
+	if _, ok := distroImages[distro]; !ok {
+		return fmt.Errorf("unsupported distro %q (want one of %s)", distro, strings.Join(Distros(), ", "))
+	}
+	return nil
+}
+
+// DistroImageURL returns the built-in cloud image URL for distro on goarch. It
+// errors for an unknown distro or one with no image for the arch.
+func DistroImageURL(distro, goarch string) (string, error) {
+	if err := ValidateDistro(distro); err != nil {
+		return "", err
+	}
+	d := distroImages[distro]
+	arch, ok := d.arches[goarch]
+	if !ok {
+		return "", fmt.Errorf("distro %s has no image for architecture %q", distro, goarch)
+	}
+	return d.url(arch), nil
+}
+
+// DistroPackageManager returns "apt" or "dnf" for a known distro, or "" when
+// distro is empty or unknown and the package manager must be detected.
+func DistroPackageManager(distro string) string {
+	return distroImages[distro].packageManager
+}
+
+// DistroAdminGroup returns the group that grants the SSH user sudo on distro's
+// image: "wheel" on Fedora, "sudo" otherwise.
+func DistroAdminGroup(distro string) string {
+	if g := distroImages[distro].adminGroup; g != "" {
+		return g
+	}
+	return "sudo"
+}
+
+// DistroUsesDebianMirror reports whether cloud-init should point apt at
+// DefaultAptMirrorURI: true for Debian and for the pre-baked or custom images
+// (distro empty), false for a distro that brings its own mirrors.
+func DistroUsesDebianMirror(distro string) bool {
+	return distro == "" || distroImages[distro].debianMirror
+}
+
+// UseDistroImage repoints cfg at distro's built-in image for its arch and
+// records the choice in cfg.Distro. Debian goes through UseDebianImage, so it
+// keeps its pinned SHA-512; the others clear every pin and rely on the
+// download's published checksums. It errors if the distro has no image for
+// cfg.Arch.
+func UseDistroImage(cfg *Config, distro string) error {
+	if distro == DistroDebian {
+		if err := UseDebianImage(cfg); err != nil {
+			return err
+		}
+		cfg.Distro = distro
+		return nil
+	}
+	imageURL, err := DistroImageURL(distro, cfg.Arch)
+	if err != nil {
+		return err
+	}
+	cfg.Distro = distro
+	cfg.BaseImageURL = imageURL
+	cfg.BaseImageSHA512 = ""
+	cfg.BaseImageExpectedSHA256 = ""
+	cfg.BaseImagePath = ""
+	cfg.UseHostedGuestImage = false
+	return nil
+}
+
+// BaseImageRelease is one distro/release/arch combination bladerunner can
+// build a base image URL for, as listed by `br images releases`.
+type BaseImageRelease struct {
+	Distro  string `json:"distro"`
+	Release string `json:"release"`
+	Arch    string `json:"arch"`
+	URL     string `json:"url"`
+	// Default marks the image a plain `br start` resolves to.
+	Default bool `json:"default"`
+	// Pinned is set when the download is verified against an embedded hash.
+	Pinned bool `json:"pinned"`
+}
+
+// baseImageReleases is the set of built-in base images, each with the same URL
+// builder Default and UseDebianImage resolve through, so the listing can't
+// drift from what a start downloads.
+var baseImageReleases = []struct {
+	distro, release string
+	url             func(goarch string) (string, error)
+	sha512          func(goarch string) string // nil when not pinned
+	isDefault       bool
+}{
+	{"bladerunner", HostedGuestImageTag, HostedGuestImageURL, nil, true},
+	{DistroDebian, "trixie-" + DebianTrixieBuild, DebianTrixieGenericCloudURL, DebianTrixieGenericCloudSHA512, false},
+	{DistroUbuntu, UbuntuRelease, distroURL(DistroUbuntu), nil, false},
+	{DistroFedora, FedoraRelease + "-" + fedoraCloudBuild, distroURL(DistroFedora), nil, false},
+}
+
+// distroURL adapts DistroImageURL to baseImageReleases' per-arch URL builder.
+func distroURL(distro string) func(goarch string) (string, error) {
+	return func(goarch string) (string, error) { return DistroImageURL(distro, goarch) }
+}
+
+// SupportedArches lists the guest architectures every built-in base image is
+// published for.
+func SupportedArches() []string {
+	return []string{archARM64, archAMD64}
+}
+
+// BaseImageReleases returns every built-in base image for every supported
+// arch, default first.
+func BaseImageReleases() []BaseImageRelease {
+	var out []BaseImageRelease
+	for _, r := range baseImageReleases {
+		for _, arch := range SupportedArches() {
+			url, err := r.url(arch)
+			if err != nil {
+				continue
+			}
+			out = append(out, BaseImageRelease{
+				Distro:  r.distro,
+				Release: r.release,
+				Arch:    arch,
+				URL:     url,
+				Default: r.isDefault,
+				Pinned:  r.sha512 != nil && r.sha512(arch) != "",
+			})
+		}
+	}
+	return out
+}
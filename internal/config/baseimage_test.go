@@ -0,0 +1,197 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateBaseImage(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "base.raw")
+	if err := os.WriteFile(image, []byte("raw"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	disk := filepath.Join(dir, "disk.raw")
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "url only", cfg: Config{BaseImageURL: "not-a-url"}},
+		{name: "existing path", cfg: Config{BaseImagePath: image, DiskPath: disk}},
+		{name: "missing path", cfg: Config{BaseImagePath: filepath.Join(dir, "nope.raw"), DiskPath: disk}, wantErr: "does not exist"},
+		{name: "directory path", cfg: Config{BaseImagePath: dir, DiskPath: disk}, wantErr: "is a directory"},
+		// A provisioned disk never reads its base image again.
+		{name: "missing path with disk", cfg: Config{BaseImagePath: filepath.Join(dir, "nope.raw"), DiskPath: image}},
+		{name: "ref wins over path", cfg: Config{BaseImageRef: "oci://x", BaseImagePath: filepath.Join(dir, "nope.raw"), DiskPath: disk}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.ValidateBaseImage()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateBaseImage() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateBaseImage() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateBaseImageURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "https url", cfg: Config{BaseImageURL: "https://example.com/base.raw"}},
+		{name: "not a url", cfg: Config{BaseImageURL: "not-a-url"}, wantErr: "absolute http(s) URL"},
+		{name: "file scheme", cfg: Config{BaseImageURL: "file:///tmp/base.raw"}, wantErr: "--image-path"},
+		{name: "ftp scheme", cfg: Config{BaseImageURL: "ftp://example.com/base.raw"}, wantErr: `scheme "ftp"`},
+		{name: "missing host", cfg: Config{BaseImageURL: "https:///base.raw"}, wantErr: "missing host"},
+		// The path is what gets used, so a bad URL alongside it is ignored.
+		{name: "path wins over url", cfg: Config{BaseImagePath: "/nope.raw", BaseImageURL: "not-a-url"}},
+		{name: "ref wins over url", cfg: Config{BaseImageRef: "oci://x", BaseImageURL: "not-a-url"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateBaseImageURL()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateBaseImageURL() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateBaseImageURL() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeBaseImage(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	c := Config{BaseImagePath: " ~/images/base.raw\n", BaseImageURL: "  https://example.com/x.raw "}
+	c.NormalizeBaseImage()
+	if want := filepath.Join(home, "images/base.raw"); c.BaseImagePath != want {
+		t.Errorf("BaseImagePath = %q, want %q", c.BaseImagePath, want)
+	}
+	if c.BaseImageURL != "https://example.com/x.raw" {
+		t.Errorf("BaseImageURL = %q", c.BaseImageURL)
+	}
+
+	c = Config{BaseImagePath: "rel/base.raw"}
+	c.NormalizeBaseImage()
+	if !filepath.IsAbs(c.BaseImagePath) {
+		t.Errorf("relative path not made absolute: %q", c.BaseImagePath)
+	}
+}
+
+func TestBaseImageReleases(t *testing.T) {
+	releases := BaseImageReleases()
+	if want := len(baseImageReleases) * len(SupportedArches()); len(releases) != want {
+		t.Fatalf("got %d releases, want %d", len(releases), want)
+	}
+	if !releases[0].Default {
+		t.Errorf("first release %+v is not the default", releases[0])
+	}
+	for _, r := range releases {
+		var want string
+		var err error
+		switch r.Distro {
+		case "bladerunner":
+			want, err = HostedGuestImageURL(r.Arch)
+		case "debian":
+			want, err = DebianTrixieGenericCloudURL(r.Arch)
+			if !r.Pinned {
+				t.Errorf("%s/%s is not marked pinned", r.Release, r.Arch)
+			}
+		case DistroUbuntu, DistroFedora:
+			want, err = DistroImageURL(r.Distro, r.Arch)
+		default:
+			t.Fatalf("unexpected distro %q", r.Distro)
+		}
+		if err != nil || r.URL != want {
+			t.Errorf("%s/%s URL = %q, want %q (%v)", r.Release, r.Arch, r.URL, want, err)
+		}
+	}
+
+	// The default entry matches what Default resolves for the host.
+	cfg, err := Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, r := range releases {
+		if r.Default && r.Arch == cfg.Arch {
+			found = r.URL == cfg.BaseImageURL
+		}
+	}
+	if !found {
+		t.Errorf("no default release matches Default's BaseImageURL %q", cfg.BaseImageURL)
+	}
+}
+
+func TestDistroImageURL(t *testing.T) {
+	tests := []struct {
+		distro, arch, want string
+	}{
+		{DistroUbuntu, "arm64", "https://cloud-images.ubuntu.com/releases/noble/release/ubuntu-24.04-server-cloudimg-arm64.img"},
+		{DistroUbuntu, "amd64", "https://cloud-images.ubuntu.com/releases/noble/release/ubuntu-24.04-server-cloudimg-amd64.img"},
+		{DistroFedora, "arm64", "https://download.fedoraproject.org/pub/fedora/linux/releases/42/Cloud/aarch64/images/Fedora-Cloud-Base-Generic-42-1.1.aarch64.qcow2"},
+		{DistroFedora, "amd64", "https://download.fedoraproject.org/pub/fedora/linux/releases/42/Cloud/x86_64/images/Fedora-Cloud-Base-Generic-42-1.1.x86_64.qcow2"},
+	}
+	for _, tt := range tests {
+		got, err := DistroImageURL(tt.distro, tt.arch)
+		if err != nil || got != tt.want {
+			t.Errorf("DistroImageURL(%s, %s) = %q, %v; want %q", tt.distro, tt.arch, got, err, tt.want)
+		}
+	}
+	debian, _ := DebianTrixieGenericCloudURL("arm64")
+	if got, _ := DistroImageURL(DistroDebian, "arm64"); got != debian {
+		t.Errorf("DistroImageURL(debian) = %q, want the pinned trixie image %q", got, debian)
+	}
+	if _, err := DistroImageURL("arch", "arm64"); err == nil {
+		t.Error("unknown distro: want an error")
+	}
+	if _, err := DistroImageURL(DistroFedora, "riscv64"); err == nil {
+		t.Error("unsupported arch: want an error")
+	}
+}
+
+func TestUseDistroImage(t *testing.T) {
+	cfg, err := Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.BaseImageExpectedSHA256 = "stale"
+	if err := UseDistroImage(cfg, DistroUbuntu); err != nil {
+		t.Fatalf("UseDistroImage(ubuntu): %v", err)
+	}
+	want, _ := DistroImageURL(DistroUbuntu, cfg.Arch)
+	if cfg.Distro != DistroUbuntu || cfg.BaseImageURL != want || cfg.UseHostedGuestImage ||
+		cfg.BaseImageSHA512 != "" || cfg.BaseImageExpectedSHA256 != "" {
+		t.Errorf("ubuntu: Distro=%q URL=%q hosted=%v sha512=%q sha256=%q", cfg.Distro, cfg.BaseImageURL,
+			cfg.UseHostedGuestImage, cfg.BaseImageSHA512, cfg.BaseImageExpectedSHA256)
+	}
+
+	if err := UseDistroImage(cfg, DistroDebian); err != nil {
+		t.Fatalf("UseDistroImage(debian): %v", err)
+	}
+	if cfg.Distro != DistroDebian || cfg.BaseImageSHA512 != DebianTrixieGenericCloudSHA512(cfg.Arch) {
+		t.Errorf("debian: Distro=%q sha512=%q, want the pinned hash", cfg.Distro, cfg.BaseImageSHA512)
+	}
+
+	if DistroPackageManager(DistroFedora) != "dnf" || DistroPackageManager(DistroUbuntu) != "apt" || DistroPackageManager("") != "" {
+		t.Error("DistroPackageManager does not map fedora=dnf, ubuntu=apt, default=detect")
+	}
+}
@@ -1,21 +1,36 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/util"
 )
 
 const (
 	NetworkModeShared  = "shared"
 	NetworkModeBridged = "bridged"
 
+	// DiskBackingCopy makes a fresh main disk as a full copy of the base
+	// image. DiskBackingQcow2Overlay makes it a qcow2 overlay backed by the
+	// base image instead, where the VM can attach qcow2 disks; where it
+	// cannot, the VM falls back to a copy.
+	DiskBackingCopy         = "copy"
+	DiskBackingQcow2Overlay = "qcow2-overlay"
+
 	// DefaultBridgeInterface is the host interface used for bridged networking.
 	DefaultBridgeInterface = "en0"
+	// BridgeInterfaceAuto, as a BridgeInterface entry, picks the host's primary
+	// active interface (the one carrying the default route) at start.
+	BridgeInterfaceAuto = "auto"
 
 	// Default values for CLI flags and config
 	DefaultCPUs        = 4
@@ -25,6 +40,18 @@ const (
 	// bootstrap (apt install incus + admin init) can exceed 5m on stock M-series
 	// hardware; 10m absorbs that. Dial back with --timeout. (#52)
 	DefaultTimeout = 10 * time.Minute
+	// DefaultForwarderDialRetries and DefaultForwarderDialRetryDelay bound how
+	// long a forwarder keeps dialing the guest relay for one connection: 15s,
+	// enough for the relay to come up on a typical first boot.
+	DefaultForwarderDialRetries    = 30
+	DefaultForwarderDialRetryDelay = 500 * time.Millisecond
+	// DefaultLogMaxSizeMiB and DefaultLogMaxBackups bound the host log: it
+	// rotates at this size and keeps this many compressed old files.
+	DefaultLogMaxSizeMiB = 25
+	DefaultLogMaxBackups = 5
+	// MinIdleTimeout is the shortest IdleTimeout allowed, so a VM is not
+	// stopped between a user's commands.
+	MinIdleTimeout = time.Minute
 
 	// Port assignments (avoid conflicts with common services)
 	DefaultLocalSSHPort  = 6022
@@ -36,6 +63,9 @@ const (
 	DefaultVsockOIDCPort = 18556
 	DefaultLocalNTPPort  = 15557
 	DefaultVsockNTPPort  = 18557
+	// DefaultVsockControlPort is where the guest agent answers status
+	// queries in the control wire format.
+	DefaultVsockControlPort = 18558
 
 	// Default OIDC client ID and audience baked into Incus config.
 	DefaultOIDCClientID = "bladerunner"
@@ -52,6 +82,11 @@ const (
 	// enabled.
 	DefaultShareGuestPath = "/mnt/share"
 
+	// DefaultKernelConsole routes the guest kernel's console to hvc0, the
+	// virtio console VZ streams into console.log, with tty0 (the GUI display)
+	// as a secondary. The last console= listed becomes /dev/console.
+	DefaultKernelConsole = "console=hvc0 console=tty0"
+
 	// HostedGuestImageTag is the GitHub Release tag bladerunner pulls pre-baked
 	// guest images from when UseHostedGuestImage is enabled. The "latest" tag is
 	// maintained as a moving pointer by the build-guest-image workflow.
@@ -67,6 +102,7 @@ const (
 
 	// Validation constraints
 	MinDiskSizeGiB     = 16
+	MinMemoryGiB       = 2
 	DefaultStopTimeout = 30 // seconds
 
 	// XDG directory structure
@@ -84,6 +120,8 @@ const (
 	logFileName          = "bladerunner.log"
 	reportFileName       = "startup-report.json"
 	metadataFileName     = "runtime-metadata.json"
+	effectiveConfigName  = "effective-config.json"
+	configFileName       = "config.json"
 	savedStateFileName   = "saved-state.bin"
 	clientCertFileName   = "client.crt"
 	clientKeyFileName    = "client.key"
@@ -105,7 +143,8 @@ type Config struct {
 	// back to sidecar verification) or a local --image-path.
 	BaseImageSHA512 string
 	// BaseImageExpectedSHA256 is an explicit expected SHA-256 of the downloaded
-	// base image artifact, set by a disk manifest's image.arches[arch].sha256.
+	// base image artifact, set by a disk manifest's image.arches[arch].sha256
+	// or `br start --image-sha256`; a mismatch fails the start.
 	// Distinct from BaseImageSHA512 (the pinned Debian default) and from the
 	// --image-url path (which clears verification). Empty => sidecar fallback.
 	BaseImageExpectedSHA256 string
@@ -118,6 +157,7 @@ type Config struct {
 	LogPath                 string
 	ReportPath              string
 	MetadataPath            string
+	EffectiveConfigPath     string
 	SSHUser                 string
 	SSHPublicKey            string
 	SSHPrivateKeyPath       string
@@ -133,6 +173,13 @@ type Config struct {
 	VsockOIDCPort           uint32
 	LocalNTPPort            int
 	VsockNTPPort            uint32
+	// VsockControlPort is the guest agent's vsock port; see
+	// control.QueryGuestStatus.
+	VsockControlPort uint32
+	// AutoPort moves the ssh and API forwards to the next free host port when
+	// LocalSSHPort or LocalAPIPort is already taken, instead of failing the
+	// start. See ResolveAutoPorts.
+	AutoPort bool
 	// OIDCIssuerURL is the issuer URL advertised in discovery and tokens. It uses
 	// the host provider's loopback port (LocalOIDCPort) so it resolves identically
 	// from inside the VM (Incus, via the guest→host vsock bridge) and on the host
@@ -146,10 +193,24 @@ type Config struct {
 	// OIDCStateDir is where the signing key and runtime state live.
 	OIDCStateDir string
 	// IdentityDir is the directory of registered SSH-pubkey identity files.
-	IdentityDir     string
-	NetworkMode     string
+	IdentityDir string
+	NetworkMode string
+	// BridgeInterface names the host interface for bridged networking: an
+	// interface name (en0) or display name, BridgeInterfaceAuto, or a
+	// comma-separated list of those tried in order (see BridgeCandidates).
 	BridgeInterface string
-	GUI             bool
+	// MACAddress pins the guest NIC's MAC, e.g. for a DHCP reservation on a
+	// bridged network. Empty keeps the one generated at first start.
+	MACAddress string
+	GUI        bool
+	// GUIInput attaches the USB pointing and keyboard devices alongside the GUI
+	// framebuffer. Turning it off leaves a view-only console (screen capture,
+	// kiosk recording); it only means anything with GUI on.
+	GUIInput bool
+	// Distro is the built-in distro image picked with `br start --distro`
+	// (a Distro* constant). Empty means the pre-baked default or a
+	// user-supplied image, whose package manager the bootstrap script detects.
+	Distro string
 	// UseHostedGuestImage selects the pre-baked bladerunner guest image hosted on
 	// GitHub Releases (the guest-image-latest release). It defaults to TRUE: a
 	// fresh install resolves to the pre-baked image (faster first boot, no
@@ -161,7 +222,23 @@ type Config struct {
 	MemoryGiB           uint64
 	Arch                string
 	WaitForIncus        time.Duration
-	DashboardPath       string
+	// ForwarderDialRetries and ForwarderDialRetryDelay are how many times,
+	// and how far apart, a forwarder dials the guest relay for a connection
+	// before giving up; raise them on hardware slow to start the relay.
+	ForwarderDialRetries    int
+	ForwarderDialRetryDelay time.Duration
+	// IdleTimeout stops the VM once neither the control socket nor any port
+	// forward has seen use for this long; an open forwarded connection, such
+	// as an SSH session, always counts as use. Zero never stops it.
+	IdleTimeout time.Duration
+	// LogMaxSizeMiB and LogMaxBackups set when LogPath rotates and how many
+	// rotated files are kept.
+	LogMaxSizeMiB int
+	LogMaxBackups int
+	// LogFormat is how LogPath records are written: LogFormatText or
+	// LogFormatJSON.
+	LogFormat     string
+	DashboardPath string
 	// NestedVirtDisabled opts out of nested virtualization even when the host
 	// supports it (set via --no-nested-virt). When false, bladerunner enables
 	// nested virt where available so the guest's Incus can run VMs.
@@ -183,6 +260,88 @@ type Config struct {
 	// cartridge manifest's Share.GuestPath so a non-default path actually mounts
 	// there (not just reported).
 	ShareGuestPath string
+	// DNS lists nameserver addresses the guest resolver prefers over the ones
+	// learned via DHCP (the host's NAT resolver in shared mode), for hosts on
+	// VPNs or split-horizon DNS. Applies in both network modes. Empty => the
+	// DHCP-provided servers only.
+	DNS []string
+	// SearchDomains lists extra resolver search domains for the guest.
+	SearchDomains []string
+	// RefreshBaseImage bypasses the cached base image (per-VM or
+	// content-addressed) and re-downloads and re-verifies it, for when a new
+	// build is published at the same URL. Only affects disks created after the
+	// refresh; an existing disk.raw is kept.
+	RefreshBaseImage bool
+	// KernelConsole holds the kernel console args (console=, earlycon, ...)
+	// appended to the guest's grub command line, e.g. to add a baud rate.
+	// Empty means DefaultKernelConsole.
+	KernelConsole string
+	// FastEntropy makes a newly provisioned guest seed its entropy pool from
+	// the host and run rngd over the virtio RNG, so sshd key generation and
+	// early TLS don't stall on kernels that are slow to initialise the CRNG.
+	// Off by default; only applied when a new disk is provisioned.
+	FastEntropy bool
+	// DiskBackingMode is how a new main disk is made from the base image:
+	// DiskBackingCopy (the default) or DiskBackingQcow2Overlay. Only applied
+	// when a new disk is created.
+	DiskBackingMode string
+	// NoResize creates the main disk as a plain copy of the base image,
+	// skipping the grow to DiskSizeGiB (and so the qemu-img dependency), for
+	// images already sized right or grown from inside the guest. Only applied
+	// when a new disk is created.
+	NoResize bool
+	// FirstBootReboot applies the kernel console args with a reboot driven by
+	// cloud-init's power_state module at the end of the first boot, instead of
+	// the early reboot from bootcmd. The first boot then runs every module,
+	// and the guest comes back once on the new cmdline. Only applied when a
+	// new disk is provisioned.
+	FirstBootReboot bool
+	// RequireCommands lists commands (incus, jq, a custom binary) the guest
+	// must have on the SSH user's PATH once Incus is ready. Any that are
+	// missing fail the readiness wait, naming them. Empty => no check.
+	RequireCommands []string
+	// ExtraPackages lists distro packages (htop, git) the bootstrap installs
+	// with apt or dnf after Incus is provisioned. Best-effort, and only applied
+	// when a new disk is provisioned: later boots don't re-run the bootstrap.
+	ExtraPackages []string
+	// ExtraDisks are host images attached after the main disk and the
+	// cloud-init seed, in order, as virtio block devices the guest sees as
+	// /dev/disk/by-id/virtio-extraN. At most MaxExtraDisks.
+	ExtraDisks []ExtraDisk
+	// Forwards are user-declared host-to-guest port forwards, stood up after
+	// the built-in ssh and incus-api ones (see ForwardSpecs). The guest-side
+	// relays are installed when a new disk is provisioned. At most MaxForwards.
+	Forwards []ForwardSpec
+	// BaseImageRef selects the base image through a registered image source
+	// ("<scheme>://...", e.g. an OCI reference) instead of BaseImageURL. A
+	// local BaseImagePath still takes precedence.
+	BaseImageRef string
+	// ControlSocketPath overrides where the control socket is created. Empty
+	// means <VMDir>/control.sock, or a short hashed path under $TMPDIR when
+	// that would exceed MaxSocketPathLen. Defaults to $BLADERUNNER_CONTROL_SOCKET.
+	ControlSocketPath string
+	// IncusProfilePath is a YAML Incus profile (as printed by `incus profile
+	// show`) the guest bootstrap applies after `incus admin init`, so instances
+	// get consistent limits and devices. Its name selects the profile to edit
+	// ("default" when unset); any other name is created first. Empty => the
+	// stock default profile. Only applied when a new disk is provisioned.
+	IncusProfilePath string
+	// IncusProfile holds the contents of IncusProfilePath, read and checked by
+	// LoadIncusProfile before the VM starts.
+	IncusProfile string
+	// HTTPControlAddr, when set, serves a REST mirror of the control protocol
+	// (status, stop, config get/set) on this loopback host:port, guarded by a
+	// bearer token. Empty => off.
+	HTTPControlAddr string
+	// OnReadyCommand is a host shell command run once Incus is ready, with the
+	// endpoints and SSH settings in BR_* env vars (e.g. to open the dashboard
+	// or register the VM somewhere). Its output is logged; a failure only
+	// warns. Empty => none.
+	OnReadyCommand string
+	// Sources records which layer (default, env, settings, saved, manifest,
+	// flag, runtime) set each tracked key, keyed by `br config` key name. Missing
+	// entries mean SourceDefault; see SourceOf.
+	Sources map[string]Source
 }
 
 // DefaultBaseImageURL returns the default base image URL for the given GOARCH.
@@ -283,6 +442,51 @@ func ResolveBaseImageURL(goarch string, useHosted bool) (string, error) {
 	return DebianTrixieGenericCloudURL(goarch)
 }
 
+// ValidateArch reports whether arch is a guest architecture bladerunner has
+// base images for.
+func ValidateArch(arch string) error {
+	switch arch {
+	case archARM64, archAMD64:
+		return nil
+	default:
+		return fmt.Errorf("unsupported architecture %q (want %s or %s)", arch, archARM64, archAMD64)
+	}
+}
+
+// SetArch sets the guest architecture. When the base image is still one of the
+// built-in defaults (hosted, pinned Debian or a --distro image) for the
+// previous arch, it is re-resolved for the new one so the downloaded image
+// matches the VM; a user-supplied image URL or path is left as given.
+func (c *Config) SetArch(arch string) error {
+	if err := ValidateArch(arch); err != nil {
+		return err
+	}
+	if arch == c.Arch {
+		return nil
+	}
+	defaultURL, err := ResolveBaseImageURL(c.Arch, c.UseHostedGuestImage)
+	if c.Distro != "" {
+		defaultURL, err = DistroImageURL(c.Distro, c.Arch)
+	}
+	builtin := err == nil && c.BaseImagePath == "" && c.BaseImageURL == defaultURL
+	c.Arch = arch
+	if !builtin {
+		return nil
+	}
+	if c.Distro != "" {
+		return UseDistroImage(c, c.Distro)
+	}
+	if !c.UseHostedGuestImage {
+		return UseDebianImage(c)
+	}
+	url, err := HostedGuestImageURL(arch)
+	if err != nil {
+		return err
+	}
+	c.BaseImageURL = url
+	return nil
+}
+
 // ForceHostedImageEnvVar, when set to a truthy value ("1", "true", "yes", "on"),
 // forces the pre-baked hosted guest image for the run — the non-interactive
 // equivalent of the --hosted-image start flag. Since the hosted image is now the
@@ -304,6 +508,56 @@ func ForceHostedImage() bool {
 	return envTruthy(ForceHostedImageEnvVar)
 }
 
+// ControlSocketEnvVar overrides the default VM's control socket path for
+// both the server and every client, which must agree on it. See
+// ControlSocketOverride.
+const ControlSocketEnvVar = "BLADERUNNER_CONTROL_SOCKET"
+
+// ControlSocketOverride returns the ControlSocketEnvVar path when it applies
+// to the VM in vmDir, else "". The variable names a single socket, so a named
+// VM (<DefaultStateDir>/<name>) ignores it and keeps its own socket; two VMs
+// sharing one would let status or stop reach the wrong VM.
+func ControlSocketOverride(vmDir string) string {
+	p := os.Getenv(ControlSocketEnvVar)
+	if p == "" || isNamedVMDir(vmDir) {
+		return ""
+	}
+	return p
+}
+
+// isNamedVMDir reports whether vmDir is where VMDirFor puts a named VM of
+// the default state dir.
+func isNamedVMDir(vmDir string) bool {
+	parent, name := filepath.Split(filepath.Clean(vmDir))
+	if ValidateName(name) != nil || reservedNames[name] {
+		return false
+	}
+	return filepath.Clean(parent) == filepath.Clean(DefaultStateDir())
+}
+
+// LogFormatEnvVar sets the default LogFormat, for CI runs that want JSON
+// logs without passing --log-format to every start.
+const LogFormatEnvVar = "BLADERUNNER_LOG_FORMAT"
+
+// Log formats. Text is the default, for people reading the log.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// logFormatFromEnv returns LogFormatEnvVar's value, or LogFormatText when it
+// is unset.
+func logFormatFromEnv() string {
+	if f := strings.ToLower(strings.TrimSpace(os.Getenv(LogFormatEnvVar))); f != "" {
+		return f
+	}
+	return LogFormatText
+}
+
+// MaxSocketPathLen is the longest Unix socket path that binds on both macOS
+// (104-byte sun_path) and Linux (108), less the terminating NUL.
+const MaxSocketPathLen = 103
+
 // ForceDebianImage reports whether the forced-Debian-image override is set via
 // the ForceDebianImageEnvVar environment variable.
 func ForceDebianImage() bool {
@@ -320,10 +574,24 @@ func envTruthy(name string) bool {
 	}
 }
 
-func Default(baseDir string) (*Config, error) {
-	if baseDir == "" {
+// Default returns the default config for the VM called name under the state
+// dir baseDir (DefaultStateDir when empty). The default VM, name "", is rooted
+// at baseDir itself; a named VM gets its own directory, baseDir/<name>, for
+// every per-VM file and its control socket, and its own block of local ports
+// (see namedPortOffset), so several can run at once.
+func Default(baseDir, name string) (*Config, error) {
+	baseDirFromEnv := baseDir == ""
+	if baseDirFromEnv {
 		baseDir = DefaultStateDir()
 	}
+	vmDir, err := VMDirFor(baseDir, name)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = appName
+	}
+	portOffset := namedPortOffset(vmDir, baseDir)
 
 	// The pre-baked (hosted) guest image is the default: faster first boot, no
 	// first-boot apt. The Debian genericcloud path is the warned auto-fallback
@@ -341,53 +609,67 @@ func Default(baseDir string) (*Config, error) {
 	}
 
 	cfg := &Config{
-		Name:                appName,
-		Hostname:            appName,
-		StateDir:            baseDir,
-		VMDir:               baseDir,
-		DiskPath:            filepath.Join(baseDir, diskFileName),
-		SavedStatePath:      filepath.Join(baseDir, savedStateFileName),
-		DiskSizeGiB:         DefaultDiskSizeGiB,
-		BaseImageURL:        imageURL,
-		BaseImageSHA512:     baseImageSHA512,
-		BaseImagePath:       "",
-		MachineIDPath:       filepath.Join(baseDir, machineIDFileName),
-		EFIVarsPath:         filepath.Join(baseDir, efiVarsFileName),
-		CloudInitISO:        filepath.Join(baseDir, cloudInitISOFileName),
-		CloudInitDir:        filepath.Join(baseDir, cloudInitDirName),
-		ConsoleLogPath:      filepath.Join(baseDir, consoleLogFileName),
-		LogPath:             filepath.Join(baseDir, logFileName),
-		ReportPath:          filepath.Join(baseDir, reportFileName),
-		MetadataPath:        filepath.Join(baseDir, metadataFileName),
-		SSHUser:             "bladerunner",
-		SSHPublicKey:        "", // Set by EnsureSSHKeys
-		SSHPrivateKeyPath:   "", // Set by EnsureSSHKeys
-		SSHConfigPath:       "", // Set after VM starts
-		ClientCertPath:      filepath.Join(baseDir, clientCertFileName),
-		ClientKeyPath:       filepath.Join(baseDir, clientKeyFileName),
-		LocalSSHPort:        DefaultLocalSSHPort,
-		LocalAPIPort:        DefaultLocalAPIPort,
-		LocalWebPort:        DefaultLocalWebPort,
-		LocalOIDCPort:       DefaultLocalOIDCPort,
-		VsockSSHPort:        DefaultVsockSSHPort,
-		VsockAPIPort:        DefaultVsockAPIPort,
-		VsockOIDCPort:       DefaultVsockOIDCPort,
-		LocalNTPPort:        DefaultLocalNTPPort,
-		VsockNTPPort:        DefaultVsockNTPPort,
-		OIDCIssuerURL:       fmt.Sprintf("http://127.0.0.1:%d", DefaultLocalOIDCPort),
-		OIDCClientID:        DefaultOIDCClientID,
-		OIDCAudience:        DefaultOIDCAudience,
-		OIDCStateDir:        filepath.Join(baseDir, "oidc"),
-		IdentityDir:         DefaultIdentityDir(),
-		NetworkMode:         NetworkModeShared,
-		BridgeInterface:     DefaultBridgeInterface,
-		GUI:                 false, // off by default; opt in via Settings.ShowConsole or --gui
-		UseHostedGuestImage: useHosted,
-		CPUs:                DefaultCPUs,
-		MemoryGiB:           DefaultMemoryGiB,
-		Arch:                runtime.GOARCH,
-		WaitForIncus:        DefaultTimeout,
-		DashboardPath:       "/ui/",
+		Name:                    name,
+		Hostname:                name,
+		StateDir:                baseDir,
+		VMDir:                   vmDir,
+		DiskPath:                filepath.Join(vmDir, diskFileName),
+		SavedStatePath:          filepath.Join(vmDir, savedStateFileName),
+		DiskSizeGiB:             DefaultDiskSizeGiB,
+		BaseImageURL:            imageURL,
+		BaseImageSHA512:         baseImageSHA512,
+		BaseImagePath:           "",
+		MachineIDPath:           filepath.Join(vmDir, machineIDFileName),
+		EFIVarsPath:             filepath.Join(vmDir, efiVarsFileName),
+		CloudInitISO:            filepath.Join(vmDir, cloudInitISOFileName),
+		CloudInitDir:            filepath.Join(vmDir, cloudInitDirName),
+		ConsoleLogPath:          filepath.Join(vmDir, consoleLogFileName),
+		LogPath:                 filepath.Join(vmDir, logFileName),
+		ReportPath:              filepath.Join(vmDir, reportFileName),
+		MetadataPath:            filepath.Join(vmDir, metadataFileName),
+		EffectiveConfigPath:     filepath.Join(vmDir, effectiveConfigName),
+		SSHUser:                 "bladerunner",
+		SSHPublicKey:            "", // Set by EnsureSSHKeys
+		SSHPrivateKeyPath:       "", // Set by EnsureSSHKeys
+		SSHConfigPath:           "", // Set after VM starts
+		ClientCertPath:          filepath.Join(vmDir, clientCertFileName),
+		ClientKeyPath:           filepath.Join(vmDir, clientKeyFileName),
+		LocalSSHPort:            DefaultLocalSSHPort + portOffset,
+		LocalAPIPort:            DefaultLocalAPIPort + portOffset,
+		LocalWebPort:            DefaultLocalWebPort + portOffset,
+		LocalOIDCPort:           DefaultLocalOIDCPort + portOffset,
+		VsockSSHPort:            DefaultVsockSSHPort,
+		VsockAPIPort:            DefaultVsockAPIPort,
+		VsockOIDCPort:           DefaultVsockOIDCPort,
+		LocalNTPPort:            DefaultLocalNTPPort + portOffset,
+		VsockNTPPort:            DefaultVsockNTPPort,
+		VsockControlPort:        DefaultVsockControlPort,
+		OIDCIssuerURL:           fmt.Sprintf("http://127.0.0.1:%d", DefaultLocalOIDCPort+portOffset),
+		OIDCClientID:            DefaultOIDCClientID,
+		OIDCAudience:            DefaultOIDCAudience,
+		OIDCStateDir:            filepath.Join(vmDir, "oidc"),
+		IdentityDir:             DefaultIdentityDir(),
+		NetworkMode:             NetworkModeShared,
+		DiskBackingMode:         DiskBackingCopy,
+		BridgeInterface:         DefaultBridgeInterface,
+		GUI:                     false, // off by default; opt in via Settings.ShowConsole or --gui
+		GUIInput:                true,
+		UseHostedGuestImage:     useHosted,
+		CPUs:                    DefaultCPUs,
+		MemoryGiB:               DefaultMemoryGiB,
+		Arch:                    runtime.GOARCH,
+		WaitForIncus:            DefaultTimeout,
+		ForwarderDialRetries:    DefaultForwarderDialRetries,
+		ForwarderDialRetryDelay: DefaultForwarderDialRetryDelay,
+		LogMaxSizeMiB:           DefaultLogMaxSizeMiB,
+		LogMaxBackups:           DefaultLogMaxBackups,
+		LogFormat:               logFormatFromEnv(),
+		DashboardPath:           "/ui/",
+		KernelConsole:           DefaultKernelConsole,
+		ControlSocketPath:       ControlSocketOverride(vmDir),
+	}
+	if baseDirFromEnv && stateDirFromEnv() {
+		cfg.SetStateDirSource(SourceEnv)
 	}
 
 	return cfg, nil
@@ -403,27 +685,99 @@ func (c *Config) Validate() error {
 	if err := c.validatePorts(); err != nil {
 		return err
 	}
-	if c.DiskSizeGiB < MinDiskSizeGiB {
-		return fmt.Errorf("disk size must be at least %d GiB", MinDiskSizeGiB)
+	if err := c.validateDNS(); err != nil {
+		return err
 	}
-	if c.CPUs < 1 {
-		return errors.New("cpus must be >= 1")
+	if n := len(c.ControlSocketPath); n > MaxSocketPathLen {
+		return fmt.Errorf("control socket path is %d bytes; Unix sockets allow at most %d: %s", n, MaxSocketPathLen, c.ControlSocketPath)
+	}
+	if err := ValidateName(c.Name); err != nil {
+		return err
+	}
+	if err := ValidateArch(c.Arch); err != nil {
+		return err
+	}
+	if c.Distro != "" {
+		if _, err := DistroImageURL(c.Distro, c.Arch); err != nil {
+			return err
+		}
 	}
-	if c.MemoryGiB < 2 {
-		return errors.New("memory must be at least 2 GiB")
+	if err := validateKernelConsole(c.KernelConsole); err != nil {
+		return err
 	}
-	if c.BaseImagePath == "" && c.BaseImageURL == "" {
-		return errors.New("either base image path or base image url must be set")
+	if err := validateRequireCommands(c.RequireCommands); err != nil {
+		return err
+	}
+	if err := validateExtraPackages(c.ExtraPackages); err != nil {
+		return err
+	}
+	if err := validateExtraDisks(c.ExtraDisks); err != nil {
+		return err
+	}
+	if err := c.validateForwards(); err != nil {
+		return err
+	}
+	if c.HTTPControlAddr != "" {
+		if err := CheckLoopbackAddr(c.HTTPControlAddr); err != nil {
+			return err
+		}
+	}
+	if err := ValidateSizing(c.CPUs, c.MemoryGiB, c.DiskSizeGiB); err != nil {
+		return err
+	}
+	if c.BaseImagePath == "" && c.BaseImageURL == "" && c.BaseImageRef == "" {
+		return errors.New("either base image path, url, or ref must be set")
+	}
+	if err := c.validateBaseImageURL(); err != nil {
+		return err
 	}
 	if c.WaitForIncus < time.Second {
 		return errors.New("wait-for-incus must be at least 1s")
 	}
+	if c.ForwarderDialRetries < 1 {
+		return errors.New("forward dial retries must be at least 1")
+	}
+	if c.ForwarderDialRetryDelay < 10*time.Millisecond {
+		return errors.New("forward dial retry delay must be at least 10ms")
+	}
+	if c.IdleTimeout != 0 && c.IdleTimeout < MinIdleTimeout {
+		return fmt.Errorf("idle timeout must be 0 (off) or at least %s", MinIdleTimeout)
+	}
+	// The idle check watches the forwarders, and a bridged guest is also
+	// reached at its own address, so SSH to it would go unseen.
+	if c.IdleTimeout != 0 && c.NetworkMode == NetworkModeBridged {
+		return errors.New("idle timeout is not supported with bridged networking: connections to the guest's own address bypass the forwards it watches")
+	}
+	if c.LogMaxSizeMiB < 1 {
+		return errors.New("log max size must be at least 1 MiB")
+	}
+	if c.LogMaxBackups < 1 {
+		return errors.New("log max backups must be at least 1")
+	}
+	if c.LogFormat != LogFormatText && c.LogFormat != LogFormatJSON {
+		return fmt.Errorf("log format %q is not %s or %s", c.LogFormat, LogFormatText, LogFormatJSON)
+	}
 	if c.ShareDir != "" && c.ShareTag == "" {
 		return errors.New("share tag must be set when a share directory is configured")
 	}
 	return nil
 }
 
+// ValidateSizing checks VM sizing against the lower bounds Virtualization.framework
+// and the guest image need.
+func ValidateSizing(cpus uint, memoryGiB uint64, diskSizeGiB int) error {
+	if diskSizeGiB < MinDiskSizeGiB {
+		return fmt.Errorf("disk size must be at least %d GiB", MinDiskSizeGiB)
+	}
+	if cpus < 1 {
+		return errors.New("cpus must be >= 1")
+	}
+	if memoryGiB < MinMemoryGiB {
+		return fmt.Errorf("memory must be at least %d GiB", MinMemoryGiB)
+	}
+	return nil
+}
+
 func (c *Config) validateRequiredFields() error {
 	if c.Name == "" {
 		return errors.New("name is required")
@@ -453,9 +807,58 @@ func (c *Config) validateModes() error {
 	if c.NetworkMode != NetworkModeShared && c.NetworkMode != NetworkModeBridged {
 		return fmt.Errorf("invalid network mode: %s", c.NetworkMode)
 	}
+	if err := ValidateDiskBackingMode(c.DiskBackingMode); err != nil {
+		return err
+	}
+	if !c.GUIInput && !c.GUI {
+		return errors.New("gui input can only be disabled with the GUI console enabled")
+	}
+	if c.NetworkMode == NetworkModeBridged && len(c.BridgeCandidates()) == 0 {
+		return errors.New("bridged networking needs a bridge interface (a name, a list, or \"auto\")")
+	}
+	if c.MACAddress != "" {
+		if err := ValidateMACAddress(c.MACAddress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateMACAddress reports whether mac can be a guest NIC's address: a
+// 48-bit unicast MAC such as 52:54:00:12:34:56.
+func ValidateMACAddress(mac string) error {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		return fmt.Errorf("invalid MAC address %q (want six colon-separated hex bytes, e.g. 52:54:00:12:34:56)", mac)
+	}
+	if hw[0]&1 != 0 {
+		return fmt.Errorf("MAC address %q is multicast; a NIC needs a unicast address", mac)
+	}
 	return nil
 }
 
+// ValidateDiskBackingMode checks mode is a DiskBacking* value. Whether the
+// VM can attach the disk a mode makes is the VM's call, not the config's.
+func ValidateDiskBackingMode(mode string) error {
+	switch mode {
+	case DiskBackingCopy, DiskBackingQcow2Overlay:
+		return nil
+	}
+	return fmt.Errorf("invalid disk backing mode %q (want %s or %s)", mode, DiskBackingCopy, DiskBackingQcow2Overlay)
+}
+
+// BridgeCandidates splits BridgeInterface into the interfaces to try, in
+// order, dropping blanks. "auto" is kept as an entry of its own.
+func (c *Config) BridgeCandidates() []string {
+	var out []string
+	for _, name := range strings.Split(c.BridgeInterface, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
 func (c *Config) validatePorts() error {
 	const minPort, maxPort = 1, 65535
 	if c.LocalSSHPort < minPort || c.LocalSSHPort > maxPort {
@@ -482,9 +885,297 @@ func (c *Config) validatePorts() error {
 			return errors.New("guest vsock ntp port must differ from ssh/api/oidc vsock ports")
 		}
 	}
+	if c.VsockControlPort != 0 {
+		switch c.VsockControlPort {
+		case c.VsockSSHPort, c.VsockAPIPort, c.VsockOIDCPort, c.VsockNTPPort:
+			return errors.New("guest vsock control port must differ from ssh/api/oidc/ntp vsock ports")
+		}
+	}
 	return nil
 }
 
+func (c *Config) validateDNS() error {
+	for _, addr := range c.DNS {
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("invalid dns server address: %q", addr)
+		}
+	}
+	for _, d := range c.SearchDomains {
+		if !validSearchDomain(d) {
+			return fmt.Errorf("invalid search domain: %q", d)
+		}
+	}
+	return nil
+}
+
+// validSearchDomain reports whether d is a plausible DNS domain: dot-separated
+// labels of letters, digits, and inner hyphens, each 1-63 characters.
+func validSearchDomain(d string) bool {
+	d = strings.TrimSuffix(d, ".")
+	if d == "" || len(d) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(d, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CheckLoopbackAddr requires the HTTP control address to be a loopback
+// host:port: the API controls the VM and must not face the network.
+func CheckLoopbackAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("http control address %q: %w", addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("http control address %q must be on localhost (e.g. 127.0.0.1:8765)", addr)
+	}
+	return nil
+}
+
+// validateKernelConsole restricts kernel console args to characters that are
+// safe inside the grub drop-in's double-quoted GRUB_CMDLINE_LINUX and the
+// first-boot shell script that checks /proc/cmdline for them.
+func validateKernelConsole(args string) error {
+	for _, arg := range strings.Fields(args) {
+		for _, r := range arg {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("=,._:-", r)) {
+				return fmt.Errorf("invalid kernel console arg %q: only letters, digits and =,._:- are allowed", arg)
+			}
+		}
+	}
+	return nil
+}
+
+// MaxNameLen bounds a VM name, which ends up in directory and socket paths
+// and SSH host aliases.
+const MaxNameLen = 63
+
+// ValidateName requires a VM name to be 1-MaxNameLen letters, digits, '_' or
+// '-', so it can never add a path component, escape the state dir, or break
+// an SSH config line.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("vm name is empty")
+	}
+	if len(name) > MaxNameLen {
+		return fmt.Errorf("vm name %q is %d characters; at most %d are allowed", name, len(name), MaxNameLen)
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return fmt.Errorf("invalid vm name %q: only letters, digits, '_' and '-' are allowed", name)
+		}
+	}
+	return nil
+}
+
+// NamedDir returns the directory for the VM called name under parent. The
+// name is validated and the join checked, so the result is always a direct
+// child of parent.
+func NamedDir(parent, name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	return util.SafeJoin(parent, name)
+}
+
+// reservedNames are the state dir's own subdirectories, which a named VM's
+// directory must not shadow.
+var reservedNames = map[string]bool{
+	"backups":    true,
+	"cache":      true,
+	"cloud-init": true,
+	"disks":      true,
+	"mnt":        true,
+	"oidc":       true,
+	"snapshots":  true,
+}
+
+// VMDirFor returns the directory of the VM called name under stateDir:
+// stateDir itself for the default VM (name ""), else stateDir/<name>.
+func VMDirFor(stateDir, name string) (string, error) {
+	if name == "" {
+		return stateDir, nil
+	}
+	if reservedNames[name] {
+		return "", fmt.Errorf("vm name %q is reserved for the state directory's own use", name)
+	}
+	return NamedDir(stateDir, name)
+}
+
+// namedPortBlocks is how many 10-port blocks named VMs spread over; the
+// highest default port plus the largest offset stays well below 65535.
+const namedPortBlocks = 100
+
+// namedPortOffset shifts a named VM's local ports off the defaults by a block
+// derived from its name, so it stays put across restarts (the OIDC issuer URL
+// is baked into the guest) and rarely meets another VM's. The default VM,
+// rooted at the state dir, keeps the defaults.
+func namedPortOffset(vmDir, stateDir string) int {
+	if vmDir == stateDir {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(filepath.Base(vmDir)))
+	return 10 * (1 + int(h.Sum32()%namedPortBlocks))
+}
+
+// Instance returns the name of a VM with its own directory under StateDir,
+// or "" for the default VM rooted at StateDir itself.
+func (c *Config) Instance() string {
+	if c.VMDir == "" || c.VMDir == c.StateDir {
+		return ""
+	}
+	return filepath.Base(c.VMDir)
+}
+
+// validateRequireCommands restricts required command names to characters
+// that are safe to pass unquoted to `command -v` in the guest: a bare name or
+// an absolute path.
+func validateRequireCommands(cmds []string) error {
+	for _, name := range cmds {
+		if name == "" {
+			return errors.New("required command name is empty")
+		}
+		for _, r := range name {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._+-/", r)) {
+				return fmt.Errorf("invalid required command %q: only letters, digits and ._+-/ are allowed", name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateExtraPackages restricts package names to characters that are safe
+// unquoted on the bootstrap's install line. A name must start with a letter
+// or digit, so it can't be read as an option.
+func validateExtraPackages(pkgs []string) error {
+	for _, name := range pkgs {
+		if name == "" {
+			return errors.New("extra package name is empty")
+		}
+		for i, r := range name {
+			alnum := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+			if !alnum && (i == 0 || !strings.ContainsRune(".+-_:", r)) {
+				return fmt.Errorf("invalid package name %q: only letters, digits and .+-_: are allowed, starting with a letter or digit", name)
+			}
+		}
+	}
+	return nil
+}
+
+// ConfigPath returns where Save persists the config for a state dir.
+func ConfigPath(stateDir string) string {
+	return filepath.Join(stateDir, configFileName)
+}
+
+// savedKeys are the `br config` keys whose `br config set` values persist
+// across restarts, each with how it is carried over from a saved Config.
+var savedKeys = map[string]func(dst, saved *Config){
+	"base-image-url": func(dst, saved *Config) {
+		dst.BaseImageURL = saved.BaseImageURL
+		dst.BaseImageSHA512 = saved.BaseImageSHA512
+	},
+	"cpus":              func(dst, saved *Config) { dst.CPUs = saved.CPUs },
+	"memory-gib":        func(dst, saved *Config) { dst.MemoryGiB = saved.MemoryGiB },
+	"disk-size-gib":     func(dst, saved *Config) { dst.DiskSizeGiB = saved.DiskSizeGiB },
+	"disk-backing-mode": func(dst, saved *Config) { dst.DiskBackingMode = saved.DiskBackingMode },
+}
+
+// pinnedPortKeys are the host ports a saved config can pin. Only `br import`
+// pins them (PinFreePorts); a port the writing run moved itself with
+// --auto-port is that run's own and is not carried over.
+var pinnedPortKeys = map[string]func(dst, saved *Config){
+	"local-ssh-port": func(dst, saved *Config) { dst.LocalSSHPort = saved.LocalSSHPort },
+	"local-api-port": func(dst, saved *Config) { dst.LocalAPIPort = saved.LocalAPIPort },
+}
+
+// IsSavedKey reports whether a `br config set` of key persists across
+// restarts.
+func IsSavedKey(key string) bool {
+	_, ok := savedKeys[key]
+	return ok
+}
+
+// Save atomically writes c to path as JSON (temp file + rename), so `br config
+// set` values survive a restart. Only ApplySaved reads it back.
+func (c *Config) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create config dir %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, configFileName+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp config: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write temp config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp config: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("rename config into place: %w", err)
+	}
+	return nil
+}
+
+// Load reads a Config written by Save. A missing file is not an error: it
+// yields an empty Config, which ApplySaved treats as nothing saved. Fields a
+// file from an older release lacks stay zero and fields from a newer one are
+// ignored; neither matters, since only the recorded `br config set` values
+// are ever applied.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+	var saved Config
+	if err := json.Unmarshal(b, &saved); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return &saved, nil
+}
+
+// ApplySaved overlays the values `br config set` recorded in saved onto c and
+// credits them to SourceSaved. Everything else in saved is ignored: sizing
+// other layers chose (so a saved file never pins stale values over
+// settings.json), and the paths, ports and SSH keys of the run that wrote it,
+// which this run resolves for itself, except ports pinned by PinFreePorts.
+func (c *Config) ApplySaved(saved *Config) {
+	for key, apply := range savedKeys {
+		switch saved.SourceOf(key) {
+		case SourceRuntime, SourceSaved:
+			apply(c, saved)
+			c.SetSource(key, SourceSaved)
+		}
+	}
+	for key, apply := range pinnedPortKeys {
+		if saved.SourceOf(key) == SourceSaved {
+			apply(c, saved)
+			c.SetSource(key, SourceSaved)
+		}
+	}
+}
+
 // SetSSHKeys sets the SSH key paths from externally provided values.
 func (c *Config) SetSSHKeys(publicKey, privateKeyPath string) {
 	if c.SSHPublicKey == "" {
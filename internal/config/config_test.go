@@ -5,8 +5,10 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stuffbucket/bladerunner/internal/ssh"
 )
@@ -129,7 +131,7 @@ func TestUseDebianImageCarriesPinnedChecksum(t *testing.T) {
 	if want == "" {
 		t.Skipf("no pinned checksum for arch %s", runtime.GOARCH)
 	}
-	cfg, err := Default(t.TempDir())
+	cfg, err := Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatalf("Default() error = %v", err)
 	}
@@ -148,6 +150,62 @@ func TestUseDebianImageCarriesPinnedChecksum(t *testing.T) {
 	}
 }
 
+// TestSetArchRepointsBuiltinImage checks that switching arch swaps a default
+// image for the matching arch's build and leaves a user-supplied one alone.
+func TestSetArchRepointsBuiltinImage(t *testing.T) {
+	foreign := "amd64"
+	if runtime.GOARCH == "amd64" {
+		foreign = "arm64"
+	}
+
+	cfg, err := Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	if err := cfg.SetArch(foreign); err != nil {
+		t.Fatalf("SetArch(%s) error = %v", foreign, err)
+	}
+	if want, _ := HostedGuestImageURL(foreign); cfg.Arch != foreign || cfg.BaseImageURL != want {
+		t.Errorf("hosted: Arch=%q URL=%q, want %q %q", cfg.Arch, cfg.BaseImageURL, foreign, want)
+	}
+
+	cfg, _ = Default(t.TempDir(), "")
+	if err := UseDebianImage(cfg); err != nil {
+		t.Fatalf("UseDebianImage() error = %v", err)
+	}
+	if err := cfg.SetArch(foreign); err != nil {
+		t.Fatalf("SetArch(%s) error = %v", foreign, err)
+	}
+	wantURL, _ := DebianTrixieGenericCloudURL(foreign)
+	if cfg.BaseImageURL != wantURL || cfg.BaseImageSHA512 != DebianTrixieGenericCloudSHA512(foreign) {
+		t.Errorf("debian: URL=%q SHA=%q, want the %s build", cfg.BaseImageURL, cfg.BaseImageSHA512, foreign)
+	}
+
+	cfg, _ = Default(t.TempDir(), "")
+	if err := UseDistroImage(cfg, DistroFedora); err != nil {
+		t.Fatalf("UseDistroImage() error = %v", err)
+	}
+	if err := cfg.SetArch(foreign); err != nil {
+		t.Fatalf("SetArch(%s) error = %v", foreign, err)
+	}
+	if want, _ := DistroImageURL(DistroFedora, foreign); cfg.BaseImageURL != want {
+		t.Errorf("fedora: URL=%q, want %q", cfg.BaseImageURL, want)
+	}
+
+	cfg, _ = Default(t.TempDir(), "")
+	cfg.BaseImageURL = "https://example.com/custom.qcow2"
+	if err := cfg.SetArch(foreign); err != nil {
+		t.Fatalf("SetArch(%s) error = %v", foreign, err)
+	}
+	if cfg.BaseImageURL != "https://example.com/custom.qcow2" {
+		t.Errorf("custom URL rewritten to %q", cfg.BaseImageURL)
+	}
+
+	if err := cfg.SetArch("riscv64"); err == nil {
+		t.Error("SetArch(riscv64) = nil, want an unsupported-arch error")
+	}
+}
+
 func TestHostedGuestImageURL(t *testing.T) {
 	tests := []struct {
 		arch    string
@@ -207,7 +265,7 @@ func TestResolveBaseImageURL(t *testing.T) {
 // image is verified fail-closed against its .sha256 sidecar, not an embedded
 // hash).
 func TestDefaultConfigIsHosted(t *testing.T) {
-	cfg, err := Default(t.TempDir())
+	cfg, err := Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatalf("Default() error = %v", err)
 	}
@@ -292,7 +350,7 @@ func TestDefaultAptMirrorURI(t *testing.T) {
 func TestDefaultConfig(t *testing.T) {
 	tmpDir := t.TempDir()
 
-	cfg, err := Default(tmpDir)
+	cfg, err := Default(tmpDir, "")
 	if err != nil {
 		t.Fatalf("Default() error = %v", err)
 	}
@@ -326,6 +384,78 @@ func TestDefaultConfig(t *testing.T) {
 	}
 }
 
+func TestControlSocketOverride(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("BLADERUNNER_STATE_DIR", stateDir)
+	t.Setenv(ControlSocketEnvVar, "/run/br.sock")
+
+	flat, err := Default("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flat.ControlSocketPath != "/run/br.sock" {
+		t.Errorf("default VM ControlSocketPath = %q, want the env override", flat.ControlSocketPath)
+	}
+	build, err := Default("", "build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build.ControlSocketPath != "" || ControlSocketOverride(build.VMDir) != "" {
+		t.Errorf("named VM takes the env override: %q", build.ControlSocketPath)
+	}
+	// The state dir's own subdirectories are not VMs.
+	if got := ControlSocketOverride(filepath.Join(stateDir, "disks")); got != "/run/br.sock" {
+		t.Errorf("ControlSocketOverride(disks) = %q, want the env override", got)
+	}
+}
+
+func TestDefaultNamedVM(t *testing.T) {
+	stateDir := t.TempDir()
+	flat, err := Default(stateDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	build, err := Default(stateDir, "build")
+	if err != nil {
+		t.Fatalf("Default(build) error = %v", err)
+	}
+
+	vmDir := filepath.Join(stateDir, "build")
+	if build.StateDir != stateDir || build.VMDir != vmDir {
+		t.Errorf("StateDir, VMDir = %s, %s; want %s, %s", build.StateDir, build.VMDir, stateDir, vmDir)
+	}
+	if build.DiskPath != filepath.Join(vmDir, "disk.raw") || build.LogPath != filepath.Join(vmDir, "bladerunner.log") {
+		t.Errorf("per-VM paths not under %s: disk %s, log %s", vmDir, build.DiskPath, build.LogPath)
+	}
+	if build.Name != "build" || build.Hostname != "build" {
+		t.Errorf("Name, Hostname = %s, %s; want build", build.Name, build.Hostname)
+	}
+	if build.Instance() != "build" || flat.Instance() != "" {
+		t.Errorf("Instance() = %q (named), %q (flat)", build.Instance(), flat.Instance())
+	}
+
+	// Its ports move off the default VM's, the same way every time.
+	if build.LocalSSHPort == flat.LocalSSHPort || build.LocalOIDCPort == flat.LocalOIDCPort {
+		t.Errorf("named VM shares the default ports: ssh %d, oidc %d", build.LocalSSHPort, build.LocalOIDCPort)
+	}
+	if want := fmt.Sprintf("http://127.0.0.1:%d", build.LocalOIDCPort); build.OIDCIssuerURL != want {
+		t.Errorf("OIDCIssuerURL = %s, want %s", build.OIDCIssuerURL, want)
+	}
+	again, _ := Default(stateDir, "build")
+	if again.LocalSSHPort != build.LocalSSHPort {
+		t.Errorf("ports not stable: %d then %d", build.LocalSSHPort, again.LocalSSHPort)
+	}
+	if err := build.validatePorts(); err != nil {
+		t.Errorf("named ports invalid: %v", err)
+	}
+
+	for _, bad := range []string{"disks", "mnt", "../up", "a b"} {
+		if _, err := Default(stateDir, bad); err == nil {
+			t.Errorf("Default(%q) succeeded", bad)
+		}
+	}
+}
+
 func TestConfigValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -358,12 +488,258 @@ func TestConfigValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "non-URL base image url fails",
+			setup: func(c *Config) {
+				c.BaseImageURL = "not-a-url"
+				c.BaseImagePath = ""
+				c.BaseImageRef = ""
+			},
+			wantErr: true,
+		},
+		{
+			name: "qcow2-overlay disk backing passes",
+			setup: func(c *Config) {
+				c.DiskBackingMode = DiskBackingQcow2Overlay
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid disk backing mode fails",
+			setup: func(c *Config) {
+				c.DiskBackingMode = "clone"
+			},
+			wantErr: true,
+		},
+		{
+			name: "gui without input passes",
+			setup: func(c *Config) {
+				c.GUI = true
+				c.GUIInput = false
+			},
+			wantErr: false,
+		},
+		{
+			name: "disabling gui input without the gui fails",
+			setup: func(c *Config) {
+				c.GUIInput = false
+			},
+			wantErr: true,
+		},
+		{
+			name: "bridged with auto passes",
+			setup: func(c *Config) {
+				c.NetworkMode = NetworkModeBridged
+				c.BridgeInterface = "en7, auto"
+			},
+			wantErr: false,
+		},
+		{
+			name: "bridged without an interface fails",
+			setup: func(c *Config) {
+				c.NetworkMode = NetworkModeBridged
+				c.BridgeInterface = " , "
+			},
+			wantErr: true,
+		},
+		{
+			name: "dns servers and search domains pass",
+			setup: func(c *Config) {
+				c.DNS = []string{"1.1.1.1", "2606:4700:4700::1111"}
+				c.SearchDomains = []string{"corp.example.com", "lan."}
+			},
+			wantErr: false,
+		},
+		{
+			name: "unparseable dns server fails",
+			setup: func(c *Config) {
+				c.DNS = []string{"dns.example.com"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed search domain fails",
+			setup: func(c *Config) {
+				c.SearchDomains = []string{"bad domain"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "extra packages pass",
+			setup: func(c *Config) {
+				c.ExtraPackages = []string{"htop", "git", "g++", "libc6:arm64", "python3.12"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "extra package with shell metacharacters fails",
+			setup: func(c *Config) {
+				c.ExtraPackages = []string{"htop;reboot"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "extra package starting with a dash fails",
+			setup: func(c *Config) {
+				c.ExtraPackages = []string{"--allow-unauthenticated"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "kernel console with baud rate passes",
+			setup: func(c *Config) {
+				c.KernelConsole = "console=hvc0 console=ttyS0,115200n8 earlycon"
+			},
+			wantErr: false,
+		},
+		{
+			name: "kernel console with shell metacharacters fails",
+			setup: func(c *Config) {
+				c.KernelConsole = `console=hvc0"; reboot; "`
+			},
+			wantErr: true,
+		},
+		{
+			name: "path traversal in vm name fails",
+			setup: func(c *Config) {
+				c.Name = "../evil"
+			},
+			wantErr: true,
+		},
+		{
+			name: "required commands pass",
+			setup: func(c *Config) {
+				c.RequireCommands = []string{"incus", "jq", "/usr/local/bin/my-tool", "g++"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "required command with shell metacharacters fails",
+			setup: func(c *Config) {
+				c.RequireCommands = []string{"jq;reboot"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "loopback http control address passes",
+			setup: func(c *Config) {
+				c.HTTPControlAddr = "127.0.0.1:8765"
+			},
+			wantErr: false,
+		},
+		{
+			name: "non-loopback http control address fails",
+			setup: func(c *Config) {
+				c.HTTPControlAddr = "0.0.0.0:8765"
+			},
+			wantErr: true,
+		},
+		{
+			name: "slower forward dial retries pass",
+			setup: func(c *Config) {
+				c.ForwarderDialRetries = 120
+				c.ForwarderDialRetryDelay = 2 * time.Second
+			},
+			wantErr: false,
+		},
+		{
+			name: "zero forward dial retries fail",
+			setup: func(c *Config) {
+				c.ForwarderDialRetries = 0
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero forward dial delay fails",
+			setup: func(c *Config) {
+				c.ForwarderDialRetryDelay = 0
+			},
+			wantErr: true,
+		},
+		{
+			name: "unicast mac address passes",
+			setup: func(c *Config) {
+				c.MACAddress = "52:54:00:12:34:56"
+			},
+			wantErr: false,
+		},
+		{
+			name: "malformed mac address fails",
+			setup: func(c *Config) {
+				c.MACAddress = "52:54:00:12:34"
+			},
+			wantErr: true,
+		},
+		{
+			name: "multicast mac address fails",
+			setup: func(c *Config) {
+				c.MACAddress = "01:00:5e:00:00:01"
+			},
+			wantErr: true,
+		},
+		{
+			name: "hour idle timeout passes",
+			setup: func(c *Config) {
+				c.IdleTimeout = time.Hour
+			},
+			wantErr: false,
+		},
+		{
+			name: "sub-minute idle timeout fails",
+			setup: func(c *Config) {
+				c.IdleTimeout = 30 * time.Second
+			},
+			wantErr: true,
+		},
+		{
+			name: "idle timeout with bridged networking fails",
+			setup: func(c *Config) {
+				c.IdleTimeout = time.Hour
+				c.NetworkMode = NetworkModeBridged
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero log max size fails",
+			setup: func(c *Config) {
+				c.LogMaxSizeMiB = 0
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero log backups fail",
+			setup: func(c *Config) {
+				c.LogMaxBackups = 0
+			},
+			wantErr: true,
+		},
+		{
+			name: "json log format passes",
+			setup: func(c *Config) {
+				c.LogFormat = LogFormatJSON
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown log format fails",
+			setup: func(c *Config) {
+				c.LogFormat = "xml"
+			},
+			wantErr: true,
+		},
+		{
+			name: "over-long control socket path fails",
+			setup: func(c *Config) {
+				c.ControlSocketPath = "/" + strings.Repeat("x", MaxSocketPathLen)
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tmpDir := t.TempDir()
-			cfg, err := Default(tmpDir)
+			cfg, err := Default(tmpDir, "")
 			if err != nil {
 				t.Fatalf("Default() error = %v", err)
 			}
@@ -406,7 +782,7 @@ func TestStateDirectoryDefault(t *testing.T) {
 	t.Setenv("BLADERUNNER_STATE_DIR", "")
 	t.Setenv("XDG_STATE_HOME", "")
 
-	cfg, err := Default("")
+	cfg, err := Default("", "")
 	if err != nil {
 		t.Fatalf("Default() error = %v", err)
 	}
@@ -427,7 +803,7 @@ func TestStateDirectoryXDG(t *testing.T) {
 	t.Setenv("BLADERUNNER_STATE_DIR", "")
 	t.Setenv("XDG_STATE_HOME", tmpDir)
 
-	cfg, err := Default("")
+	cfg, err := Default("", "")
 	if err != nil {
 		t.Fatalf("Default() error = %v", err)
 	}
@@ -442,7 +818,7 @@ func TestStateDirectoryEnvOverride(t *testing.T) {
 	tmpDir := t.TempDir()
 	t.Setenv("BLADERUNNER_STATE_DIR", tmpDir)
 
-	cfg, err := Default("")
+	cfg, err := Default("", "")
 	if err != nil {
 		t.Fatalf("Default() error = %v", err)
 	}
@@ -451,3 +827,48 @@ func TestStateDirectoryEnvOverride(t *testing.T) {
 		t.Errorf("StateDir = %v, want %v", cfg.StateDir, tmpDir)
 	}
 }
+
+func TestBridgeCandidates(t *testing.T) {
+	cases := map[string][]string{
+		"":               nil,
+		"en0":            {"en0"},
+		"auto":           {"auto"},
+		" en7, ,auto , ": {"en7", "auto"},
+		"Wi-Fi,en1":      {"Wi-Fi", "en1"},
+	}
+	for in, want := range cases {
+		c := &Config{BridgeInterface: in}
+		if got := c.BridgeCandidates(); !slices.Equal(got, want) {
+			t.Errorf("BridgeCandidates(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"bladerunner", "build_01", "Test-VM", strings.Repeat("a", MaxNameLen)} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "..", "../evil", "a/b", "/etc", "has space", "dot.name", "tab\t", "ünïcode", strings.Repeat("a", MaxNameLen+1)} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) = nil, want error", name)
+		}
+	}
+}
+
+func TestNamedDir(t *testing.T) {
+	parent := t.TempDir()
+	got, err := NamedDir(parent, "build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(parent, "build"); got != want {
+		t.Errorf("NamedDir = %q, want %q", got, want)
+	}
+	for _, name := range []string{"../evil", "..", ".", "a/../../b", "/abs"} {
+		if dir, err := NamedDir(parent, name); err == nil {
+			t.Errorf("NamedDir(%q) = %q, want error", name, dir)
+		}
+	}
+}
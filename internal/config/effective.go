@@ -0,0 +1,39 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// redactedValue replaces secret material in the effective-config snapshot.
+const redactedValue = "<redacted>"
+
+// Redacted returns a copy of c with key material blanked out, safe to write to
+// disk or attach to a bug report. Paths to keys are kept — they say where the
+// run looked, not what it found.
+func (c *Config) Redacted() Config {
+	out := *c
+	if out.SSHPublicKey != "" {
+		out.SSHPublicKey = redactedValue
+	}
+	return out
+}
+
+// WriteEffectiveConfig records the resolved configuration for the current run
+// at c.EffectiveConfigPath, with secrets redacted. It reflects every layer
+// (defaults, settings, manifests, flags) so "what did this run actually use?"
+// has a single answer next to the cloud-init seed.
+func WriteEffectiveConfig(c *Config) error {
+	if c.EffectiveConfigPath == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(c.Redacted(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal effective config: %w", err)
+	}
+	if err := os.WriteFile(c.EffectiveConfigPath, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write effective config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteEffectiveConfigRedactsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := Default(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SSHPublicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIsecret user@host"
+	cfg.CPUs = 6
+
+	if err := WriteEffectiveConfig(cfg); err != nil {
+		t.Fatalf("WriteEffectiveConfig: %v", err)
+	}
+	if cfg.EffectiveConfigPath != filepath.Join(dir, "effective-config.json") {
+		t.Errorf("EffectiveConfigPath = %q", cfg.EffectiveConfigPath)
+	}
+
+	b, err := os.ReadFile(cfg.EffectiveConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "AAAAC3NzaC1lZDI1NTE5") {
+		t.Error("effective config leaks SSH key material")
+	}
+
+	var got Config
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.SSHPublicKey != redactedValue {
+		t.Errorf("SSHPublicKey = %q, want %q", got.SSHPublicKey, redactedValue)
+	}
+	if got.CPUs != 6 || got.DiskPath != cfg.DiskPath {
+		t.Errorf("resolved values not preserved: cpus=%d disk=%q", got.CPUs, got.DiskPath)
+	}
+	if cfg.SSHPublicKey == redactedValue {
+		t.Error("Redacted mutated the live config")
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxExtraDisks bounds the images attached beyond the main disk and the
+// cloud-init seed.
+const MaxExtraDisks = 8
+
+// ExtraDisk is a host image (a data ISO, a driver or dataset image) attached
+// to the guest as an additional virtio block device. It is referenced in
+// place on the host; nothing is copied into the state dir.
+type ExtraDisk struct {
+	Path     string
+	ReadOnly bool
+}
+
+// ParseExtraDisk reads an --attach spec: a path with an optional ":ro" or
+// ":rw" suffix. Images attach read-only unless ":rw" is given. The path is
+// made absolute so the attachment doesn't depend on the working directory.
+func ParseExtraDisk(spec string) (ExtraDisk, error) {
+	d := ExtraDisk{Path: spec, ReadOnly: true}
+	if p, ok := strings.CutSuffix(spec, ":ro"); ok {
+		d.Path = p
+	} else if p, ok := strings.CutSuffix(spec, ":rw"); ok {
+		d.Path, d.ReadOnly = p, false
+	}
+	if d.Path == "" {
+		return ExtraDisk{}, fmt.Errorf("attach %q: path is empty", spec)
+	}
+	abs, err := filepath.Abs(d.Path)
+	if err != nil {
+		return ExtraDisk{}, fmt.Errorf("attach %q: %w", spec, err)
+	}
+	d.Path = abs
+	return d, nil
+}
+
+// validateExtraDisks checks the attachment count and that each image is an
+// existing regular file.
+func validateExtraDisks(disks []ExtraDisk) error {
+	if len(disks) > MaxExtraDisks {
+		return fmt.Errorf("%d extra disks attached; at most %d are allowed", len(disks), MaxExtraDisks)
+	}
+	for _, d := range disks {
+		st, err := os.Stat(d.Path)
+		if err != nil {
+			return fmt.Errorf("extra disk: %w", err)
+		}
+		if !st.Mode().IsRegular() {
+			return fmt.Errorf("extra disk %s is not a regular file", d.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseExtraDisk(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		spec     string
+		path     string
+		readOnly bool
+	}{
+		{"/data/set.iso", "/data/set.iso", true},
+		{"/data/set.iso:ro", "/data/set.iso", true},
+		{"/data/scratch.img:rw", "/data/scratch.img", false},
+		{"data.iso:ro", filepath.Join(wd, "data.iso"), true},
+		// Only a known suffix is an option; other colons belong to the path.
+		{"/data/a:b.iso", "/data/a:b.iso", true},
+	}
+	for _, tc := range cases {
+		d, err := ParseExtraDisk(tc.spec)
+		if err != nil {
+			t.Errorf("ParseExtraDisk(%q): %v", tc.spec, err)
+			continue
+		}
+		if d.Path != tc.path || d.ReadOnly != tc.readOnly {
+			t.Errorf("ParseExtraDisk(%q) = %+v, want {%s %v}", tc.spec, d, tc.path, tc.readOnly)
+		}
+	}
+	for _, bad := range []string{"", ":ro", ":rw"} {
+		if _, err := ParseExtraDisk(bad); err == nil {
+			t.Errorf("ParseExtraDisk(%q) should fail", bad)
+		}
+	}
+}
+
+func TestValidateExtraDisks(t *testing.T) {
+	dir := t.TempDir()
+	img := filepath.Join(dir, "data.iso")
+	if err := os.WriteFile(img, []byte("iso"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := validateExtraDisks([]ExtraDisk{{Path: img, ReadOnly: true}}); err != nil {
+		t.Errorf("existing image: %v", err)
+	}
+	if err := validateExtraDisks([]ExtraDisk{{Path: filepath.Join(dir, "missing.iso")}}); err == nil {
+		t.Error("missing image should fail")
+	}
+	if err := validateExtraDisks([]ExtraDisk{{Path: dir}}); err == nil || !strings.Contains(err.Error(), "not a regular file") {
+		t.Errorf("directory: got %v", err)
+	}
+
+	many := make([]ExtraDisk, MaxExtraDisks+1)
+	for i := range many {
+		many[i] = ExtraDisk{Path: img, ReadOnly: true}
+	}
+	if err := validateExtraDisks(many); err == nil {
+		t.Error("too many extra disks should fail")
+	}
+}
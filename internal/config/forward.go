@@ -0,0 +1,272 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/stuffbucket/bladerunner/internal/util"
+)
+
+// Built-in forward names. Their addresses come from LocalSSHPort/VsockSSHPort
+// and LocalAPIPort/VsockAPIPort, which are how they are overridden.
+const (
+	ForwardSSH      = "ssh"
+	ForwardIncusAPI = "incus-api"
+)
+
+// MaxForwards bounds the user-declared forwards beyond the built-ins.
+const MaxForwards = 16
+
+// ForwardProtocolTCP is the only protocol forwards carry: the host side
+// relays over a vsock stream.
+const ForwardProtocolTCP = "tcp"
+
+// forwardVsockBase offsets a forward's guest TCP port into vsock port space,
+// above every TCP port so it can't collide with the built-in channels'
+// vsock ports (DefaultVsockSSHPort and friends).
+const forwardVsockBase = 1 << 16
+
+// ForwardSpec declares one host-to-guest port forward: connections to
+// LocalAddr on the host are relayed over vsock (VsockPort) to GuestPort on
+// the guest's loopback.
+type ForwardSpec struct {
+	Name      string
+	LocalAddr string
+	GuestPort int
+	VsockPort uint32
+	Protocol  string
+}
+
+// ParseForward reads a --forward spec: [host:]localport[:proto]:guestport,
+// e.g. "8080:tcp:8080" or "127.0.0.1:8080:80". The host defaults to
+// 127.0.0.1 and the protocol to tcp; the forward is named "fwd-<localport>".
+func ParseForward(spec string) (ForwardSpec, error) {
+	parts := strings.Split(spec, ":")
+	host, proto := "127.0.0.1", ForwardProtocolTCP
+	var local, guest string
+	switch len(parts) {
+	case 2:
+		local, guest = parts[0], parts[1]
+	case 3:
+		if _, err := strconv.Atoi(parts[1]); err == nil {
+			host, local, guest = parts[0], parts[1], parts[2]
+		} else {
+			local, proto, guest = parts[0], parts[1], parts[2]
+		}
+	case 4:
+		host, local, proto, guest = parts[0], parts[1], parts[2], parts[3]
+	default:
+		return ForwardSpec{}, fmt.Errorf("forward %q: want [host:]localport[:proto]:guestport", spec)
+	}
+	localPort, err := strconv.Atoi(local)
+	if err != nil {
+		return ForwardSpec{}, fmt.Errorf("forward %q: local port %q is not a number", spec, local)
+	}
+	guestPort, err := strconv.Atoi(guest)
+	if err != nil {
+		return ForwardSpec{}, fmt.Errorf("forward %q: guest port %q is not a number", spec, guest)
+	}
+	f := ForwardSpec{
+		Name:      "fwd-" + local,
+		LocalAddr: net.JoinHostPort(host, local),
+		GuestPort: guestPort,
+		VsockPort: forwardVsockBase + uint32(max(guestPort, 0)),
+		Protocol:  strings.ToLower(proto),
+	}
+	if err := validateForward(f); err != nil {
+		return ForwardSpec{}, fmt.Errorf("forward %q: %w", spec, err)
+	}
+	if localPort < 1 || localPort > 65535 {
+		return ForwardSpec{}, fmt.Errorf("forward %q: local port must be in range 1-65535", spec)
+	}
+	return f, nil
+}
+
+// ForwardSpecs returns every forward the runner stands up: the built-in ssh
+// and incus-api forwards followed by the user-declared Forwards.
+func (c *Config) ForwardSpecs() []ForwardSpec {
+	return c.ForwardSpecsWith(c.Forwards)
+}
+
+// ForwardSpecsWith is ForwardSpecs with forwards in place of Forwards, for a
+// running VM whose forwards have changed since it started.
+func (c *Config) ForwardSpecsWith(forwards []ForwardSpec) []ForwardSpec {
+	specs := []ForwardSpec{
+		{
+			Name:      ForwardSSH,
+			LocalAddr: fmt.Sprintf("127.0.0.1:%d", c.LocalSSHPort),
+			GuestPort: 22,
+			VsockPort: c.VsockSSHPort,
+			Protocol:  ForwardProtocolTCP,
+		},
+		{
+			Name:      ForwardIncusAPI,
+			LocalAddr: fmt.Sprintf("127.0.0.1:%d", c.LocalAPIPort),
+			GuestPort: 8443,
+			VsockPort: c.VsockAPIPort,
+			Protocol:  ForwardProtocolTCP,
+		},
+	}
+	return append(specs, forwards...)
+}
+
+// validateForward checks one spec in isolation.
+func validateForward(f ForwardSpec) error {
+	if f.Protocol != ForwardProtocolTCP {
+		return fmt.Errorf("protocol %q is not supported (only %s)", f.Protocol, ForwardProtocolTCP)
+	}
+	if f.GuestPort < 1 || f.GuestPort > 65535 {
+		return fmt.Errorf("guest port must be in range 1-65535")
+	}
+	host, _, err := net.SplitHostPort(f.LocalAddr)
+	if err != nil {
+		return fmt.Errorf("local address %q: %w", f.LocalAddr, err)
+	}
+	// Like the built-ins, forwards only ever expose the guest to this host.
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("local address %q must be on localhost", f.LocalAddr)
+	}
+	return nil
+}
+
+// validateForwards checks the user-declared forwards and that, together with
+// the built-ins and the other host listeners, no two share a name, a local
+// port or a guest port.
+func (c *Config) validateForwards() error {
+	return c.checkForwards(c.Forwards)
+}
+
+// checkForwards is validateForwards for forwards in place of Forwards.
+func (c *Config) checkForwards(forwards []ForwardSpec) error {
+	if len(forwards) > MaxForwards {
+		return fmt.Errorf("%d forwards declared; at most %d are allowed", len(forwards), MaxForwards)
+	}
+	names := map[string]bool{}
+	localPorts := map[string]string{}
+	guestPorts := map[int]string{}
+	for _, port := range []int{c.LocalWebPort, c.LocalOIDCPort, c.LocalNTPPort} {
+		if port != 0 {
+			localPorts[strconv.Itoa(port)] = "a bladerunner service"
+		}
+	}
+	for i, f := range c.ForwardSpecsWith(forwards) {
+		builtin := i < 2
+		if !builtin {
+			if err := validateForward(f); err != nil {
+				return fmt.Errorf("forward %s: %w", f.Name, err)
+			}
+		}
+		if names[f.Name] {
+			if !builtin && (f.Name == ForwardSSH || f.Name == ForwardIncusAPI) {
+				return fmt.Errorf("forward name %q is built in; change its port setting instead", f.Name)
+			}
+			return fmt.Errorf("forward name %q is used twice", f.Name)
+		}
+		names[f.Name] = true
+		_, port, _ := net.SplitHostPort(f.LocalAddr)
+		if other, ok := localPorts[port]; ok {
+			return fmt.Errorf("forward %s: local port %s is already used by %s", f.Name, port, other)
+		}
+		localPorts[port] = "forward " + f.Name
+		if other, ok := guestPorts[f.GuestPort]; ok {
+			return fmt.Errorf("forward %s: guest port %d is already forwarded by %s", f.Name, f.GuestPort, other)
+		}
+		guestPorts[f.GuestPort] = "forward " + f.Name
+	}
+	return nil
+}
+
+// AddForward returns forwards, a running VM's user-declared forwards, with f
+// appended, or an error when f clashes with them or with c's other host
+// ports. forwards itself is left as it was.
+func (c *Config) AddForward(forwards []ForwardSpec, f ForwardSpec) ([]ForwardSpec, error) {
+	next := append(slices.Clone(forwards), f)
+	if err := c.checkForwards(next); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// RemoveForward returns forwards without the one named name, and that
+// forward. The built-in ssh and incus-api forwards can only be paused.
+// forwards itself is left as it was.
+func RemoveForward(forwards []ForwardSpec, name string) ([]ForwardSpec, ForwardSpec, error) {
+	if name == ForwardSSH || name == ForwardIncusAPI {
+		return nil, ForwardSpec{}, fmt.Errorf("forward %q is built in; pause it instead", name)
+	}
+	i := slices.IndexFunc(forwards, func(f ForwardSpec) bool { return f.Name == name })
+	if i < 0 {
+		return nil, ForwardSpec{}, fmt.Errorf("unknown forward %q", name)
+	}
+	return slices.Delete(slices.Clone(forwards), i, i+1), forwards[i], nil
+}
+
+// PortMove is one host port ResolveAutoPorts or PinFreePorts changed.
+type PortMove struct {
+	Key  string `json:"key"` // `br config` key of the port (local-ssh-port, local-api-port)
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+// ResolveAutoPorts moves LocalSSHPort and LocalAPIPort to the next free host
+// port when AutoPort is set and the configured one is taken, recording each
+// move as a runtime value. The other host ports bladerunner binds (web, OIDC,
+// NTP, declared forwards) are never picked, even if they are not bound yet.
+// The vsock ports are guest-internal and stay fixed.
+func (c *Config) ResolveAutoPorts() ([]PortMove, error) {
+	if !c.AutoPort {
+		return nil, nil
+	}
+	return c.moveTakenPorts(SourceRuntime)
+}
+
+// PinFreePorts moves LocalSSHPort and LocalAPIPort off ports taken on this
+// host, as ResolveAutoPorts does, but credits each move to SourceSaved so a
+// Save pins it for later starts (see ApplySaved). `br import` uses it: the
+// ports an imported VM defaults to may be in use on the new machine.
+func (c *Config) PinFreePorts() ([]PortMove, error) {
+	return c.moveTakenPorts(SourceSaved)
+}
+
+// moveTakenPorts moves LocalSSHPort and LocalAPIPort to the next free host
+// port when theirs is taken, crediting each move to src.
+func (c *Config) moveTakenPorts(src Source) ([]PortMove, error) {
+	ports := []struct {
+		key  string
+		port *int
+	}{
+		{"local-ssh-port", &c.LocalSSHPort},
+		{"local-api-port", &c.LocalAPIPort},
+	}
+	var moves []PortMove
+	for _, p := range ports {
+		reserved := c.reservedHostPorts(*p.port)
+		free, err := util.FindFreePort(*p.port, reserved...)
+		if err != nil {
+			return moves, fmt.Errorf("%s %d is in use: %w", p.key, *p.port, err)
+		}
+		if free == *p.port {
+			continue
+		}
+		moves = append(moves, PortMove{Key: p.key, From: *p.port, To: free})
+		*p.port = free
+		c.SetSource(p.key, src)
+	}
+	return moves, nil
+}
+
+// reservedHostPorts lists the host ports bladerunner binds, other than self.
+func (c *Config) reservedHostPorts(self int) []int {
+	ports := []int{c.LocalSSHPort, c.LocalAPIPort, c.LocalWebPort, c.LocalOIDCPort, c.LocalNTPPort}
+	for _, f := range c.Forwards {
+		if _, port, err := net.SplitHostPort(f.LocalAddr); err == nil {
+			if n, err := strconv.Atoi(port); err == nil {
+				ports = append(ports, n)
+			}
+		}
+	}
+	return slices.DeleteFunc(ports, func(p int) bool { return p == self || p == 0 })
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseForward(t *testing.T) {
+	cases := []struct {
+		spec      string
+		name      string
+		localAddr string
+		guestPort int
+	}{
+		{"8080:8080", "fwd-8080", "127.0.0.1:8080", 8080},
+		{"8080:tcp:80", "fwd-8080", "127.0.0.1:8080", 80},
+		{"8080:TCP:80", "fwd-8080", "127.0.0.1:8080", 80},
+		{"localhost:9000:3000", "fwd-9000", "localhost:9000", 3000},
+		{"127.0.0.1:9000:tcp:3000", "fwd-9000", "127.0.0.1:9000", 3000},
+	}
+	for _, tc := range cases {
+		f, err := ParseForward(tc.spec)
+		if err != nil {
+			t.Errorf("ParseForward(%q): %v", tc.spec, err)
+			continue
+		}
+		if f.Name != tc.name || f.LocalAddr != tc.localAddr || f.GuestPort != tc.guestPort || f.Protocol != ForwardProtocolTCP {
+			t.Errorf("ParseForward(%q) = %+v, want {%s %s %d tcp}", tc.spec, f, tc.name, tc.localAddr, tc.guestPort)
+		}
+		if f.VsockPort != forwardVsockBase+uint32(tc.guestPort) {
+			t.Errorf("ParseForward(%q).VsockPort = %d, want %d", tc.spec, f.VsockPort, forwardVsockBase+tc.guestPort)
+		}
+	}
+	for _, bad := range []string{"", "8080", "x:80", "8080:y", "8080:udp:80", "0:80", "8080:70000", "0.0.0.0:8080:80", "a:b:c:d:e"} {
+		if _, err := ParseForward(bad); err == nil {
+			t.Errorf("ParseForward(%q) should fail", bad)
+		}
+	}
+}
+
+func TestForwardSpecsBuiltinsFirst(t *testing.T) {
+	cfg, err := Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fwd, err := ParseForward("8080:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Forwards = []ForwardSpec{fwd}
+
+	specs := cfg.ForwardSpecs()
+	if len(specs) != 3 || specs[0].Name != ForwardSSH || specs[1].Name != ForwardIncusAPI || specs[2].Name != "fwd-8080" {
+		t.Fatalf("ForwardSpecs() = %+v, want ssh, incus-api, fwd-8080", specs)
+	}
+	if specs[0].VsockPort != cfg.VsockSSHPort || specs[1].VsockPort != cfg.VsockAPIPort {
+		t.Errorf("built-in vsock ports = %d/%d, want %d/%d", specs[0].VsockPort, specs[1].VsockPort, cfg.VsockSSHPort, cfg.VsockAPIPort)
+	}
+}
+
+func TestValidateForwards(t *testing.T) {
+	cfg, err := Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustParse := func(spec string) ForwardSpec {
+		t.Helper()
+		f, err := ParseForward(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	cfg.Forwards = []ForwardSpec{mustParse("8080:80"), mustParse("8081:81")}
+	if err := cfg.validateForwards(); err != nil {
+		t.Errorf("distinct forwards: %v", err)
+	}
+
+	cases := []struct {
+		forwards []ForwardSpec
+		want     string
+	}{
+		{[]ForwardSpec{mustParse("8080:80"), mustParse("127.0.0.2:8080:81")}, "used twice"},
+		{[]ForwardSpec{mustParse("8080:80"), mustParse("8081:80")}, "guest port 80"},
+		{[]ForwardSpec{mustParse("8080:22")}, "guest port 22"},
+		{[]ForwardSpec{{Name: "other", LocalAddr: "127.0.0.1:" + strconv.Itoa(cfg.LocalSSHPort), GuestPort: 80, Protocol: ForwardProtocolTCP}}, "local port"},
+		{[]ForwardSpec{{Name: ForwardSSH, LocalAddr: "127.0.0.1:9999", GuestPort: 80, Protocol: ForwardProtocolTCP}}, "built in"},
+	}
+	for _, tc := range cases {
+		cfg.Forwards = tc.forwards
+		if err := cfg.validateForwards(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("forwards %+v: got %v, want error containing %q", tc.forwards, err, tc.want)
+		}
+	}
+
+	cfg.Forwards = make([]ForwardSpec, MaxForwards+1)
+	if err := cfg.validateForwards(); err == nil {
+		t.Error("too many forwards should fail")
+	}
+}
+
+func TestAddRemoveForward(t *testing.T) {
+	cfg, err := Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fwd, err := ParseForward("8080:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forwards, err := cfg.AddForward(nil, fwd)
+	if err != nil {
+		t.Fatalf("AddForward: %v", err)
+	}
+	if len(forwards) != 1 || len(cfg.Forwards) != 0 {
+		t.Fatalf("AddForward = %+v, cfg.Forwards %+v; want one forward, cfg untouched", forwards, cfg.Forwards)
+	}
+	clash, err := ParseForward("8081:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.AddForward(forwards, clash); err == nil || !strings.Contains(err.Error(), "guest port 80") {
+		t.Errorf("AddForward(clash) = %v, want a guest port 80 error", err)
+	}
+
+	if _, _, err := RemoveForward(forwards, ForwardSSH); err == nil {
+		t.Error("RemoveForward(ssh) should refuse a built-in")
+	}
+	if _, _, err := RemoveForward(forwards, "fwd-9999"); err == nil {
+		t.Error("RemoveForward of an unknown forward should fail")
+	}
+	left, got, err := RemoveForward(forwards, "fwd-8080")
+	if err != nil || got != fwd || len(left) != 0 || len(forwards) != 1 {
+		t.Errorf("RemoveForward(fwd-8080) = %+v, %+v, %v; input now %+v", left, got, err, forwards)
+	}
+}
+
+func TestResolveAutoPorts(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+	taken := l.Addr().(*net.TCPAddr).Port
+
+	cfg, err := Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.LocalSSHPort = taken
+
+	if moves, err := cfg.ResolveAutoPorts(); err != nil || moves != nil || cfg.LocalSSHPort != taken {
+		t.Fatalf("without AutoPort: moves=%v err=%v port=%d, want no change", moves, err, cfg.LocalSSHPort)
+	}
+
+	cfg.AutoPort = true
+	cfg.LocalAPIPort = taken + 1 // the next port must be skipped for ssh
+	moves, err := cfg.ResolveAutoPorts()
+	if err != nil {
+		t.Fatalf("ResolveAutoPorts: %v", err)
+	}
+	if cfg.LocalSSHPort == taken || cfg.LocalSSHPort == cfg.LocalAPIPort {
+		t.Errorf("LocalSSHPort = %d, want a free port other than %d and the API port", cfg.LocalSSHPort, taken)
+	}
+	if len(moves) == 0 || moves[0] != (PortMove{Key: "local-ssh-port", From: taken, To: cfg.LocalSSHPort}) {
+		t.Errorf("moves = %+v, want local-ssh-port %d -> %d first", moves, taken, cfg.LocalSSHPort)
+	}
+	if src := cfg.SourceOf("local-ssh-port"); src != SourceRuntime {
+		t.Errorf("local-ssh-port source = %v, want runtime", src)
+	}
+}
+
+func TestPinFreePortsSurvivesSave(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+	taken := l.Addr().(*net.TCPAddr).Port
+
+	dir := t.TempDir()
+	cfg, err := Default(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.LocalSSHPort = taken
+	moves, err := cfg.PinFreePorts()
+	if err != nil || len(moves) != 1 || moves[0].Key != "local-ssh-port" {
+		t.Fatalf("PinFreePorts = %+v, %v; want the ssh port moved", moves, err)
+	}
+	if err := cfg.Save(ConfigPath(dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := Load(ConfigPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := Default(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.ApplySaved(saved)
+	if next.LocalSSHPort != moves[0].To || next.LocalAPIPort != cfg.LocalAPIPort {
+		t.Errorf("after ApplySaved ssh=%d api=%d, want ssh=%d api=%d", next.LocalSSHPort, next.LocalAPIPort, moves[0].To, cfg.LocalAPIPort)
+	}
+
+	// A port an --auto-port run moved for itself is not carried over.
+	saved.SetSource("local-ssh-port", SourceRuntime)
+	other, _ := Default(dir, "")
+	other.ApplySaved(saved)
+	if other.LocalSSHPort != DefaultLocalSSHPort {
+		t.Errorf("runtime-moved port carried over: %d", other.LocalSSHPort)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultIncusProfile is the profile an Incus profile file edits when it
+// doesn't name one.
+const DefaultIncusProfile = "default"
+
+// incusProfileNameRe is what the bootstrap accepts as a profile name; it is
+// interpolated into a shell command, so keep it to Incus's own name charset.
+var incusProfileNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,62}$`)
+
+// IncusProfileDoc is the subset of an Incus profile YAML document bladerunner
+// checks before handing the file to `incus profile edit`.
+type IncusProfileDoc struct {
+	Name        string                       `yaml:"name"`
+	Description string                       `yaml:"description"`
+	Config      map[string]string            `yaml:"config"`
+	Devices     map[string]map[string]string `yaml:"devices"`
+	// Project and UsedBy appear in `incus profile show` output; Incus
+	// ignores them on edit.
+	Project string   `yaml:"project"`
+	UsedBy  []string `yaml:"used_by"`
+}
+
+// ParseIncusProfile checks that data is an Incus profile document and returns
+// it with Name defaulted to DefaultIncusProfile.
+func ParseIncusProfile(data []byte) (IncusProfileDoc, error) {
+	var doc IncusProfileDoc
+	if err := yaml.UnmarshalStrict(data, &doc); err != nil {
+		return IncusProfileDoc{}, fmt.Errorf("not a valid Incus profile: %w", err)
+	}
+	if doc.Name == "" {
+		doc.Name = DefaultIncusProfile
+	}
+	if !incusProfileNameRe.MatchString(doc.Name) {
+		return IncusProfileDoc{}, fmt.Errorf("invalid Incus profile name %q", doc.Name)
+	}
+	return doc, nil
+}
+
+// LoadIncusProfile reads and checks IncusProfilePath into IncusProfile, so a
+// malformed file fails at start instead of silently inside the guest. No-op
+// when no profile is configured.
+func (c *Config) LoadIncusProfile() error {
+	if c.IncusProfilePath == "" {
+		c.IncusProfile = ""
+		return nil
+	}
+	data, err := os.ReadFile(c.IncusProfilePath)
+	if err != nil {
+		return fmt.Errorf("incus profile: %w", err)
+	}
+	if _, err := ParseIncusProfile(data); err != nil {
+		return fmt.Errorf("incus profile %s: %w", c.IncusProfilePath, err)
+	}
+	c.IncusProfile = string(data)
+	return nil
+}
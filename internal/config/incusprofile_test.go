@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseIncusProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantName string
+		wantErr  string
+	}{
+		{name: "unnamed edits default", data: "config:\n  limits.cpu: 2\n", wantName: "default"},
+		{
+			name:     "profile show output",
+			data:     "name: dev\ndescription: Dev\nconfig:\n  limits.memory: 2GiB\ndevices:\n  root:\n    path: /\n    pool: default\n    type: disk\nproject: default\nused_by: []\n",
+			wantName: "dev",
+		},
+		{name: "not yaml", data: "config: [unterminated", wantErr: "not a valid Incus profile"},
+		{name: "unknown key", data: "limits.cpu: 2\n", wantErr: "not a valid Incus profile"},
+		{name: "shell in name", data: "name: dev; reboot\n", wantErr: "invalid Incus profile name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseIncusProfile([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseIncusProfile() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", doc.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestLoadIncusProfile(t *testing.T) {
+	var c Config
+	if err := c.LoadIncusProfile(); err != nil || c.IncusProfile != "" {
+		t.Fatalf("no profile: %q, %v", c.IncusProfile, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "default.yaml")
+	const data = "config:\n  limits.cpu: \"2\"\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c.IncusProfilePath = path
+	if err := c.LoadIncusProfile(); err != nil {
+		t.Fatal(err)
+	}
+	if c.IncusProfile != data {
+		t.Errorf("IncusProfile = %q", c.IncusProfile)
+	}
+
+	c.IncusProfilePath = filepath.Join(t.TempDir(), "missing.yaml")
+	if err := c.LoadIncusProfile(); err == nil {
+		t.Error("missing profile file should fail")
+	}
+}
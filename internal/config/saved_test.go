@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveLoadApplySaved(t *testing.T) {
+	dir := t.TempDir()
+	running, err := Default(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	running.SSHPublicKey = "ssh-ed25519 AAAA old"
+	running.CPUs = 12 // from settings, say: must not be pinned by the file
+	running.SetSource("cpus", SourceSettings)
+	running.BaseImageURL = "https://example.com/custom.img"
+	running.BaseImageSHA512 = ""
+	running.SetSource("base-image-url", SourceRuntime)
+
+	path := ConfigPath(dir)
+	if err := running.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	saved, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	next, err := Default(filepath.Join(dir, "elsewhere"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.SSHPublicKey = "ssh-ed25519 AAAA current"
+	next.ApplySaved(saved)
+
+	if next.BaseImageURL != "https://example.com/custom.img" {
+		t.Errorf("BaseImageURL = %q, want the saved URL", next.BaseImageURL)
+	}
+	if next.BaseImageSHA512 != "" {
+		t.Errorf("BaseImageSHA512 = %q, want the saved (cleared) hash", next.BaseImageSHA512)
+	}
+	if got := next.SourceOf("base-image-url"); got != SourceSaved {
+		t.Errorf("base-image-url source = %q, want %q", got, SourceSaved)
+	}
+	if next.CPUs != DefaultCPUs {
+		t.Errorf("CPUs = %d; a value from another layer was applied from the file", next.CPUs)
+	}
+	if next.SSHPublicKey != "ssh-ed25519 AAAA current" || next.DiskPath == running.DiskPath {
+		t.Error("identity or paths were taken from the saved file")
+	}
+
+	// A value re-saved by a later run stays saved.
+	if err := next.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, _ := Default(dir, "")
+	fresh.ApplySaved(again)
+	if fresh.BaseImageURL != "https://example.com/custom.img" {
+		t.Errorf("after a second save, BaseImageURL = %q", fresh.BaseImageURL)
+	}
+}
+
+func TestLoadTolerance(t *testing.T) {
+	dir := t.TempDir()
+
+	saved, err := Load(ConfigPath(dir))
+	if err != nil {
+		t.Fatalf("missing file: %v", err)
+	}
+	cfg, _ := Default(dir, "")
+	before := cfg.BaseImageURL
+	cfg.ApplySaved(saved)
+	if cfg.BaseImageURL != before || cfg.SourceOf("base-image-url") == SourceSaved {
+		t.Error("a missing file changed the config")
+	}
+
+	// Unknown fields (a newer release) and absent ones (an older one) load.
+	doc := `{"BaseImageURL":"https://example.com/a.img","FutureField":true,"Sources":{"base-image-url":"runtime"}}`
+	if err := os.WriteFile(ConfigPath(dir), []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	saved, err = Load(ConfigPath(dir))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	cfg.ApplySaved(saved)
+	if cfg.BaseImageURL != "https://example.com/a.img" {
+		t.Errorf("BaseImageURL = %q", cfg.BaseImageURL)
+	}
+
+	if err := os.WriteFile(ConfigPath(dir), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(ConfigPath(dir)); err == nil {
+		t.Error("Load accepted a corrupt file")
+	}
+}
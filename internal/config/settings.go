@@ -227,14 +227,8 @@ func (s Settings) Validate() error {
 	if !s.Image.Valid() {
 		return fmt.Errorf("invalid image source: kind=%q url=%q path=%q", s.Image.Kind, s.Image.URL, s.Image.Path)
 	}
-	if s.CPUs < 1 {
-		return errors.New("cpus must be >= 1")
-	}
-	if s.MemoryGiB < 2 {
-		return errors.New("memory must be at least 2 GiB")
-	}
-	if s.DiskSizeGiB < MinDiskSizeGiB {
-		return fmt.Errorf("disk size must be at least %d GiB", MinDiskSizeGiB)
+	if err := ValidateSizing(s.CPUs, s.MemoryGiB, s.DiskSizeGiB); err != nil {
+		return err
 	}
 	if time.Duration(s.WaitForIncus) < time.Second {
 		return errors.New("wait-for-incus must be at least 1s")
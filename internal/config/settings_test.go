@@ -22,7 +22,7 @@ func TestDefaultSettingsIsValid(t *testing.T) {
 // drifting apart.
 func TestDefaultSettingsMatchesConfigDefault(t *testing.T) {
 	dir := t.TempDir()
-	cfg, err := Default(dir)
+	cfg, err := Default(dir, "")
 	if err != nil {
 		t.Fatalf("Default: %v", err)
 	}
@@ -252,7 +252,7 @@ func TestApplyToImageSources(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg, err := Default(t.TempDir())
+			cfg, err := Default(t.TempDir(), "")
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -269,7 +269,7 @@ func TestApplyToNilConfigNoPanic(t *testing.T) {
 }
 
 func TestApplyToBridgedNetwork(t *testing.T) {
-	cfg, err := Default(t.TempDir())
+	cfg, err := Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// Source names the layer an effective config value came from, for
+// `br config get --source` and `br config keys --sources`.
+type Source string
+
+// Sources, in the order the layers are applied; later layers win.
+const (
+	SourceDefault  Source = "default"
+	SourceEnv      Source = "env"
+	SourceSettings Source = "settings" // persisted Settings (settings.json)
+	SourceSaved    Source = "saved"    // `br config set`, persisted in config.json
+	SourceManifest Source = "manifest" // disk or cartridge manifest (`br boot`)
+	SourceFlag     Source = "flag"
+	SourceRuntime  Source = "runtime" // set by the running VM or `br config set`
+)
+
+// sourcedKeys maps each provenance-tracked key (the `br config` key names) to
+// its value, so a layer's effect can be recorded by comparing before and after.
+var sourcedKeys = map[string]func(*Config) string{
+	"state-dir":              func(c *Config) string { return c.StateDir },
+	"vm-dir":                 func(c *Config) string { return c.VMDir },
+	"cpus":                   func(c *Config) string { return strconv.FormatUint(uint64(c.CPUs), 10) },
+	"memory-gib":             func(c *Config) string { return strconv.FormatUint(c.MemoryGiB, 10) },
+	"disk-size-gib":          func(c *Config) string { return strconv.Itoa(c.DiskSizeGiB) },
+	"disk-backing-mode":      func(c *Config) string { return c.DiskBackingMode },
+	"gui":                    func(c *Config) string { return strconv.FormatBool(c.GUI) },
+	"network-mode":           func(c *Config) string { return c.NetworkMode },
+	"base-image-url":         func(c *Config) string { return c.BaseImageURL },
+	"base-image-path":        func(c *Config) string { return c.BaseImagePath },
+	"distro":                 func(c *Config) string { return c.Distro },
+	"use-hosted-guest-image": func(c *Config) string { return strconv.FormatBool(c.UseHostedGuestImage) },
+	"disk-path":              func(c *Config) string { return c.DiskPath },
+	"log-path":               func(c *Config) string { return c.LogPath },
+	"local-ssh-port":         func(c *Config) string { return strconv.Itoa(c.LocalSSHPort) },
+	"local-api-port":         func(c *Config) string { return strconv.Itoa(c.LocalAPIPort) },
+}
+
+// SourcedKeys returns the keys whose provenance is tracked.
+func SourcedKeys() []string {
+	keys := make([]string, 0, len(sourcedKeys))
+	for k := range sourcedKeys {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// SetSource records that key's current value came from src.
+func (c *Config) SetSource(key string, src Source) {
+	if c.Sources == nil {
+		c.Sources = make(map[string]Source)
+	}
+	c.Sources[key] = src
+}
+
+// SourceOf returns where key's value came from; untracked or untouched keys
+// report SourceDefault.
+func (c *Config) SourceOf(key string) Source {
+	if src, ok := c.Sources[key]; ok {
+		return src
+	}
+	return SourceDefault
+}
+
+// MarkChanged attributes every tracked key whose value differs from before to
+// src. Layers that overwrite wholesale (Settings, manifests) call this with a
+// copy taken before they ran, so only the values they actually moved are
+// credited to them.
+func (c *Config) MarkChanged(before *Config, src Source) {
+	for k, get := range sourcedKeys {
+		if get(c) != get(before) {
+			c.SetSource(k, src)
+		}
+	}
+}
+
+// SetStateDirSource credits the state dir, and the paths derived from it, to
+// src.
+func (c *Config) SetStateDirSource(src Source) {
+	for _, k := range []string{"state-dir", "vm-dir", "disk-path", "log-path"} {
+		c.SetSource(k, src)
+	}
+}
+
+// stateDirFromEnv reports whether DefaultStateDir resolves from the
+// environment rather than the home-directory fallback.
+func stateDirFromEnv() bool {
+	return os.Getenv("BLADERUNNER_STATE_DIR") != "" || os.Getenv("XDG_STATE_HOME") != ""
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSourceLayers(t *testing.T) {
+	cfg, err := Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.SourceOf("cpus"); got != SourceDefault {
+		t.Fatalf("fresh config: cpus from %q, want default", got)
+	}
+
+	// Settings that leave a value alone don't claim it.
+	before := *cfg
+	s := DefaultSettings()
+	s.CPUs = cfg.CPUs + 2
+	s.ApplyTo(cfg)
+	cfg.MarkChanged(&before, SourceSettings)
+	if got := cfg.SourceOf("cpus"); got != SourceSettings {
+		t.Errorf("cpus from %q, want settings", got)
+	}
+	if got := cfg.SourceOf("memory-gib"); got != SourceDefault {
+		t.Errorf("unchanged memory-gib from %q, want default", got)
+	}
+
+	// A later layer wins.
+	cfg.SetSource("cpus", SourceFlag)
+	if got := cfg.SourceOf("cpus"); got != SourceFlag {
+		t.Errorf("cpus from %q, want flag", got)
+	}
+}
+
+func TestSourceStateDirFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("BLADERUNNER_STATE_DIR", dir)
+
+	cfg, err := Default("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"state-dir", "vm-dir", "disk-path", "log-path"} {
+		if got := cfg.SourceOf(k); got != SourceEnv {
+			t.Errorf("%s from %q, want env", k, got)
+		}
+	}
+
+	cfg, err = Default(filepath.Join(dir, "explicit"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.SourceOf("state-dir"); got != SourceDefault {
+		t.Errorf("explicit base dir: state-dir from %q, want default (the caller credits it)", got)
+	}
+}
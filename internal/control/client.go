@@ -1,11 +1,18 @@
 package control
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -14,6 +21,13 @@ type ClientConfig struct {
 	StateDir   string
 	Transport  Transport
 	WireFormat WireFormat
+	// SocketPath overrides the socket location; see ResolveSocketPath.
+	SocketPath string
+	// KeepAlive holds one connection open across commands instead of
+	// dialing per command, for callers that poll (see CmdKeepAlive). Against
+	// a server that predates it, commands fall back to a connection each.
+	// Close releases the held connection.
+	KeepAlive bool
 }
 
 // Client sends commands to a running control listener.
@@ -21,6 +35,11 @@ type Client struct {
 	address    string
 	transport  Transport
 	wireFormat WireFormat
+	protocol   atomic.Pointer[Protocol] // set by Hello
+
+	keepAlive bool
+	connMu    sync.Mutex // serializes commands on conn
+	conn      *heldConn  // held between commands when keepAlive
 }
 
 // NewClient creates a client with default transport and wire format.
@@ -40,10 +59,12 @@ func NewClientWithConfig(cfg ClientConfig) *Client {
 	if cfg.WireFormat == nil {
 		cfg.WireFormat = DefaultWireFormat
 	}
+	address, _ := ResolveSocketPath(cfg.SocketPath, cfg.StateDir)
 	return &Client{
-		address:    SocketPath(cfg.StateDir),
+		address:    address,
 		transport:  cfg.Transport,
 		wireFormat: cfg.WireFormat,
+		keepAlive:  cfg.KeepAlive,
 	}
 }
 
@@ -94,12 +115,85 @@ func (d *dialerAdapter) Cleanup(_ string) error {
 
 // sendCommand sends a command and returns the response.
 func (c *Client) sendCommand(cmd string, timeout time.Duration) (*Message, error) {
+	if c.keepAlive {
+		return c.sendKeepAlive(cmd, timeout)
+	}
+	return c.sendOnce(cmd, timeout)
+}
+
+// sendOnce sends cmd on a connection of its own.
+func (c *Client) sendOnce(cmd string, timeout time.Duration) (*Message, error) {
 	conn, err := c.transport.Dial(c.address, dialTimeout)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = conn.Close() }()
+	return c.roundTrip(conn, conn, cmd, timeout)
+}
+
+// sendKeepAlive sends cmd on the held connection, dialing and opening one
+// with CmdKeepAlive if there is none. A server that predates CmdKeepAlive
+// gets cmd on a connection of its own. A held connection the server has
+// since dropped (it closes idle ones after listenerRWTimeout) gets one retry
+// on a fresh connection.
+func (c *Client) sendKeepAlive(cmd string, timeout time.Duration) (*Message, error) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	reused := c.conn != nil
+	for {
+		if c.conn == nil {
+			if err := c.requireMinor(CmdKeepAlive, MinorKeepAlive); errors.Is(err, ErrUnsupported) {
+				return c.sendOnce(cmd, timeout)
+			} else if err != nil {
+				return nil, err
+			}
+			conn, err := c.transport.Dial(c.address, dialTimeout)
+			if err != nil {
+				return nil, err
+			}
+			held := &heldConn{Conn: conn, r: bufio.NewReader(conn)}
+			if err := c.openKeepAlive(held); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+			c.conn = held
+		}
+		resp, err := c.roundTrip(c.conn.Conn, c.conn.r, cmd, timeout)
+		if err == nil {
+			return resp, nil
+		}
+		_ = c.conn.Close()
+		c.conn = nil
+		if !reused || !connDropped(err) {
+			return nil, err
+		}
+		reused = false
+	}
+}
 
+// connDropped reports whether err is a round trip on a connection the
+// server had already closed: the send is refused or the read finds EOF.
+func connDropped(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// Close releases the connection a KeepAlive client holds. The client stays
+// usable; the next command dials again.
+func (c *Client) Close() error {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// roundTrip sends cmd on conn and reads the response from r, which reads
+// conn.
+func (c *Client) roundTrip(conn net.Conn, r io.Reader, cmd string, timeout time.Duration) (*Message, error) {
 	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
 		return nil, fmt.Errorf("set deadline: %w", err)
 	}
@@ -108,7 +202,7 @@ func (c *Client) sendCommand(cmd string, timeout time.Duration) (*Message, error
 		return nil, fmt.Errorf("send command: %w", err)
 	}
 
-	resp, err := c.wireFormat.Decode(conn)
+	resp, err := c.wireFormat.Decode(r)
 	if err != nil {
 		return nil, fmt.Errorf("read response: %w", err)
 	}
@@ -220,9 +314,49 @@ func (c *Client) Eject(force bool, timeoutSeconds int) error {
 	return nil
 }
 
+// Kill asks the running server to force-stop the VM without a graceful guest
+// shutdown and exit. Like Eject, the server exits after replying, so the caller
+// should wait for the control socket to disappear.
+func (c *Client) Kill() error {
+	resp, err := c.sendCommand(CmdKill, killCommandTimeout)
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("kill error: %s", resp.Error)
+	}
+	return nil
+}
+
+// ErrNotRunning is returned by Ping when there is no control socket, or the
+// socket is stale (nothing listening): the VM is simply stopped.
+var ErrNotRunning = errors.New("VM is not running")
+
+// ErrUnresponsive is returned (wrapped with the underlying cause) by Ping when
+// the control socket exists but the server could not be reached or did not
+// answer — a permissions problem, a hung server, or a protocol mismatch. It is
+// deliberately distinct from ErrNotRunning so callers don't report a live but
+// wedged instance as stopped.
+var ErrUnresponsive = errors.New("control socket present but unresponsive")
+
+// Ping checks the server and classifies a failure as ErrNotRunning or
+// ErrUnresponsive. Use it instead of IsRunning when the reason matters.
+func (c *Client) Ping(ctx context.Context) error {
+	err := c.PingContext(ctx)
+	switch {
+	case err == nil:
+		return nil
+	case isSocketNotAvailable(err):
+		return ErrNotRunning
+	default:
+		return fmt.Errorf("%w: %v", ErrUnresponsive, err)
+	}
+}
+
 // --- Convenience methods (without context) ---
 
-// IsRunning checks if a bladerunner instance is running.
+// IsRunning checks if a bladerunner instance is running. It collapses every
+// failure to false; use Ping to tell "stopped" from "unresponsive".
 func (c *Client) IsRunning() bool {
 	return c.PingContext(context.Background()) == nil
 }
@@ -249,6 +383,41 @@ func (c *Client) GetConfig(key string) (string, error) {
 	return resp.Response, nil
 }
 
+// GetConfigMany reads keys from the running instance in one round trip. A
+// deferred key with no value yet is missing from the result. Against a server
+// whose protocol predates CmdConfigGetMany it returns ErrUnsupported without
+// sending it; callers fall back to GetConfig per key.
+func (c *Client) GetConfigMany(keys []string) (map[string]string, error) {
+	if err := c.requireMinor(CmdConfigGetMany, MinorConfigGetMany); err != nil {
+		return nil, err
+	}
+	resp, err := c.sendCommand(BuildCommand(CmdConfigGetMany, keys...), clientCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("get config: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("config error: %s", resp.Error)
+	}
+	var values map[string]string
+	if err := json.Unmarshal([]byte(resp.Response), &values); err != nil {
+		return nil, fmt.Errorf("decode config values: %w", err)
+	}
+	return values, nil
+}
+
+// GetConfigSource returns where the running instance's value for key came
+// from: default, env, settings, saved, manifest, flag, or runtime.
+func (c *Client) GetConfigSource(key string) (string, error) {
+	resp, err := c.sendCommand(BuildCommand(CmdConfigSource, key), clientCmdTimeout)
+	if err != nil {
+		return "", fmt.Errorf("get config source %s: %w", key, err)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("config error: %s", resp.Error)
+	}
+	return resp.Response, nil
+}
+
 // SetConfig sets a config value on the running instance by key.
 func (c *Client) SetConfig(key, value string) error {
 	resp, err := c.sendCommand(BuildCommand(CmdConfigSet, key, value), clientCmdTimeout)
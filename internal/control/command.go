@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"unicode"
 )
 
 // Request represents a parsed command with optional arguments.
@@ -14,30 +15,100 @@ type Request struct {
 }
 
 // NewRequest parses a command string into a Request.
-// Supports formats: "command", "command arg1 arg2", "command key=value"
+// Supports formats: "command", "command arg1 arg2", "command key=value".
+// Arguments may be single- or double-quoted, and a backslash escapes the next
+// character outside single quotes, so `config.set key "a b"` yields the value
+// "a b". Only an unquoted '=' makes an argument a key=value pair.
 func NewRequest(raw string) *Request {
-	parts := strings.Fields(raw)
+	parts := tokenize(raw)
 	if len(parts) == 0 {
 		return &Request{Raw: raw}
 	}
 
 	req := &Request{
-		Command: parts[0],
+		Command: parts[0].text,
 		Args:    make(map[string]string),
 		Raw:     raw,
 	}
 
 	for i, part := range parts[1:] {
-		if idx := strings.Index(part, "="); idx > 0 {
-			req.Args[part[:idx]] = part[idx+1:]
+		if part.eq > 0 {
+			req.Args[part.text[:part.eq]] = part.text[part.eq+1:]
 		} else {
-			req.Args[fmt.Sprintf("%d", i)] = part
+			req.Args[fmt.Sprintf("%d", i)] = part.text
 		}
 	}
 
 	return req
 }
 
+// token is one shell-style word of a command line. eq is the offset in text
+// of the first '=' that appeared outside quotes, or -1.
+type token struct {
+	text string
+	eq   int
+}
+
+// tokenize splits raw on whitespace, honoring single quotes (literal), double
+// quotes (inside which a backslash escapes only \ and "), and backslash
+// escapes outside quotes. An unterminated quote runs to the end of the input.
+func tokenize(raw string) []token {
+	var (
+		toks  []token
+		cur   strings.Builder
+		eq    = -1
+		inTok bool
+		quote rune
+		esc   bool
+	)
+	flush := func() {
+		if inTok {
+			toks = append(toks, token{text: cur.String(), eq: eq})
+		}
+		cur.Reset()
+		eq, inTok = -1, false
+	}
+	for _, r := range raw {
+		switch {
+		case esc:
+			if quote == '"' && r != '"' && r != '\\' {
+				cur.WriteRune('\\')
+			}
+			cur.WriteRune(r)
+			esc = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			esc, inTok = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inTok = r, true
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			if r == '=' && eq < 0 {
+				eq = cur.Len()
+			}
+			cur.WriteRune(r)
+			inTok = true
+		}
+	}
+	if esc {
+		cur.WriteRune('\\')
+	}
+	flush()
+	return toks
+}
+
 // Handler processes a command request and returns a response.
 type Handler interface {
 	Handle(ctx context.Context, req *Request) *Message
@@ -52,9 +123,25 @@ func (f HandlerFunc) Handle(ctx context.Context, req *Request) *Message {
 }
 
 // BuildCommand constructs a command string from a command name and positional arguments.
+// Arguments that NewRequest would otherwise split or read as key=value are
+// double-quoted, so each one arrives as a single positional argument.
 func BuildCommand(cmd string, args ...string) string {
 	parts := make([]string, 0, 1+len(args))
 	parts = append(parts, cmd)
-	parts = append(parts, args...)
+	for _, a := range args {
+		parts = append(parts, quoteArg(a))
+	}
 	return strings.Join(parts, " ")
 }
+
+// quoteArg double-quotes a when it is empty or contains whitespace, quotes,
+// backslashes, or '='.
+func quoteArg(a string) string {
+	if a != "" && !strings.ContainsFunc(a, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"'\=`, r)
+	}) {
+		return a
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(a) + `"`
+}
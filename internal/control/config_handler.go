@@ -2,7 +2,10 @@ package control
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"sort"
 	"strconv"
@@ -10,6 +13,7 @@ import (
 	"sync"
 
 	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/logging"
 )
 
 // configEntry defines a config key with its getter, optional setter, and whether it is deferred
@@ -25,13 +29,33 @@ type configEntry struct {
 // Callers must hold Lock when mutating cfg fields that the handler reads.
 type ConfigRouter struct {
 	mu      sync.RWMutex
+	cfg     *config.Config
 	entries map[string]configEntry
 	router  *Router
+	balloon MemoryBalloon
+	// savePath, when set, is where a successful config.set persists cfg (see
+	// config.Config.Save), so the change survives a restart.
+	savePath string
+	// next holds the values of reset-required sizing keys set on this run.
+	// They go into the saved config for the next start but never into cfg,
+	// which the running VM's balloon and saved-state metadata read.
+	next map[string]func(*config.Config)
+}
+
+// MemoryBalloon adjusts a running guest's memory through its balloon device.
+// The boot-time size (config MemoryGiB) is the ceiling: the balloon can give
+// memory back to the host and reclaim it, but never grow the guest past it.
+type MemoryBalloon interface {
+	// MemoryTargetGiB returns the current balloon target.
+	MemoryTargetGiB() uint64
+	// SetMemoryTargetGiB inflates or deflates the balloon to the given size.
+	SetMemoryTargetGiB(gib uint64) error
 }
 
 // NewConfigRouter creates a ConfigRouter for config.get / config.set commands.
 func NewConfigRouter(cfg *config.Config) *ConfigRouter {
 	cr := &ConfigRouter{
+		cfg: cfg,
 		entries: map[string]configEntry{
 			ConfigKeySSHConfigPath:     {getter: func() string { return cfg.SSHConfigPath }, deferred: true},
 			ConfigKeySSHUser:           {getter: func() string { return cfg.SSHUser }},
@@ -43,10 +67,8 @@ func NewConfigRouter(cfg *config.Config) *ConfigRouter {
 			ConfigKeyName:              {getter: func() string { return cfg.Name }},
 			ConfigKeyVMDir:             {getter: func() string { return cfg.VMDir }},
 			ConfigKeyStateDir:          {getter: func() string { return cfg.StateDir }},
-			ConfigKeyCPUs:              {getter: func() string { return strconv.FormatUint(uint64(cfg.CPUs), 10) }},
-			ConfigKeyMemoryGiB:         {getter: func() string { return strconv.FormatUint(cfg.MemoryGiB, 10) }},
-			ConfigKeyDiskSizeGiB:       {getter: func() string { return strconv.Itoa(cfg.DiskSizeGiB) }},
 			ConfigKeyArch:              {getter: func() string { return cfg.Arch }},
+			ConfigKeyDistro:            {getter: func() string { return cfg.Distro }},
 			ConfigKeyHostname:          {getter: func() string { return cfg.Hostname }},
 			ConfigKeyNetworkMode:       {getter: func() string { return cfg.NetworkMode }},
 			ConfigKeyLogPath:           {getter: func() string { return cfg.LogPath }},
@@ -55,7 +77,13 @@ func NewConfigRouter(cfg *config.Config) *ConfigRouter {
 			ConfigKeyBaseImageURL: {
 				getter: func() string { return cfg.BaseImageURL },
 				setter: func(val string) error {
+					if err := config.ValidateBaseImageURL(val); err != nil {
+						return err
+					}
 					cfg.BaseImageURL = val
+					// As with --image-url: the pinned Debian SHA-512 doesn't
+					// apply to another image.
+					cfg.BaseImageSHA512 = ""
 					return nil
 				},
 			},
@@ -76,10 +104,38 @@ func NewConfigRouter(cfg *config.Config) *ConfigRouter {
 		},
 		router: NewRouter(),
 	}
+	cr.entries[ConfigKeyCPUs] = configEntry{
+		getter: func() string { return strconv.FormatUint(uint64(cfg.CPUs), 10) },
+		setter: cr.setCPUs,
+	}
+	cr.entries[ConfigKeyDiskSizeGiB] = configEntry{
+		getter: func() string { return strconv.Itoa(cfg.DiskSizeGiB) },
+		setter: cr.setDiskSizeGiB,
+	}
+	cr.entries[ConfigKeyDiskBackingMode] = configEntry{
+		getter: func() string { return cfg.DiskBackingMode },
+		setter: cr.setDiskBackingMode,
+	}
+	cr.entries[ConfigKeyMemoryGiB] = configEntry{
+		getter: func() string { return strconv.FormatUint(cfg.MemoryGiB, 10) },
+		setter: cr.setMemoryGiB,
+	}
+	cr.entries[ConfigKeyMemoryBalloonGiB] = configEntry{
+		getter: func() string {
+			if cr.balloon != nil {
+				return strconv.FormatUint(cr.balloon.MemoryTargetGiB(), 10)
+			}
+			return ""
+		},
+		setter:   cr.setMemoryBalloonGiB,
+		deferred: true,
+	}
 
 	cr.router.HandleFunc("get", cr.handleGet)
+	cr.router.HandleFunc("getmany", cr.handleGetMany)
 	cr.router.HandleFunc("set", cr.handleSet)
 	cr.router.HandleFunc("keys", cr.handleKeys)
+	cr.router.HandleFunc("source", cr.handleSource)
 
 	return cr
 }
@@ -93,6 +149,103 @@ func (cr *ConfigRouter) Lock() { cr.mu.Lock() }
 // Unlock releases the write lock.
 func (cr *ConfigRouter) Unlock() { cr.mu.Unlock() }
 
+// SaveTo makes every successful config.set also write the config to path,
+// where the next start's config.Load picks it up.
+func (cr *ConfigRouter) SaveTo(path string) {
+	cr.mu.Lock()
+	cr.savePath = path
+	cr.mu.Unlock()
+}
+
+// SetMemoryBalloon makes memory-balloon-gib available once the VM is running.
+// Until it is called, setting memory-balloon-gib fails.
+func (cr *ConfigRouter) SetMemoryBalloon(b MemoryBalloon) {
+	cr.mu.Lock()
+	cr.balloon = b
+	cr.mu.Unlock()
+}
+
+// setNext records a reset-required value for the next start; it runs with
+// cr.mu held.
+func (cr *ConfigRouter) setNext(key string, apply func(*config.Config)) {
+	if cr.next == nil {
+		cr.next = make(map[string]func(*config.Config))
+	}
+	cr.next[key] = apply
+}
+
+// setCPUs is the cpus setter; it runs with cr.mu held.
+func (cr *ConfigRouter) setCPUs(val string) error {
+	n, err := strconv.ParseUint(val, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid CPU count %q: want a whole number", val)
+	}
+	if err := config.ValidateSizing(uint(n), config.MinMemoryGiB, config.MinDiskSizeGiB); err != nil {
+		return err
+	}
+	cr.setNext(ConfigKeyCPUs, func(c *config.Config) { c.CPUs = uint(n) })
+	return nil
+}
+
+// setDiskSizeGiB is the disk-size-gib setter; it runs with cr.mu held. The
+// size only applies when the disk is next created.
+func (cr *ConfigRouter) setDiskSizeGiB(val string) error {
+	gib, err := strconv.Atoi(val)
+	if err != nil {
+		return fmt.Errorf("invalid disk size %q: want a whole number of GiB", val)
+	}
+	if err := config.ValidateSizing(1, config.MinMemoryGiB, gib); err != nil {
+		return err
+	}
+	cr.setNext(ConfigKeyDiskSizeGiB, func(c *config.Config) { c.DiskSizeGiB = gib })
+	return nil
+}
+
+// setDiskBackingMode is the disk-backing-mode setter; it runs with cr.mu
+// held. Like the disk size, it only applies when the disk is next created.
+func (cr *ConfigRouter) setDiskBackingMode(val string) error {
+	if err := config.ValidateDiskBackingMode(val); err != nil {
+		return err
+	}
+	cr.setNext(ConfigKeyDiskBackingMode, func(c *config.Config) { c.DiskBackingMode = val })
+	return nil
+}
+
+// setMemoryGiB is the memory-gib setter; it runs with cr.mu held. The value
+// only becomes the next boot size; memory-balloon-gib resizes a running VM.
+func (cr *ConfigRouter) setMemoryGiB(val string) error {
+	gib, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid memory size %q: want a whole number of GiB", val)
+	}
+	if err := config.ValidateSizing(1, gib, config.MinDiskSizeGiB); err != nil {
+		return err
+	}
+	cr.setNext(ConfigKeyMemoryGiB, func(c *config.Config) { c.MemoryGiB = gib })
+	return nil
+}
+
+// setMemoryBalloonGiB is the memory-balloon-gib setter; it runs with cr.mu
+// held. It only drives the running VM's balloon, and never changes the next
+// boot size. The target is clamped to config.MinMemoryGiB and the boot size;
+// a config.get of the key reports the target actually applied.
+func (cr *ConfigRouter) setMemoryBalloonGiB(val string) error {
+	gib, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid memory size %q: want a whole number of GiB", val)
+	}
+	if cr.balloon == nil {
+		return errors.New("the memory balloon is only available once the VM is running")
+	}
+	applied := min(max(gib, config.MinMemoryGiB), cr.cfg.MemoryGiB)
+	if applied != gib {
+		logging.L().Info("clamped memory balloon target to the boot size range",
+			"requested_gib", gib, "applied_gib", applied,
+			"min_gib", config.MinMemoryGiB, "boot_gib", cr.cfg.MemoryGiB)
+	}
+	return cr.balloon.SetMemoryTargetGiB(applied)
+}
+
 func (cr *ConfigRouter) handleGet(_ context.Context, req *Request) *Message {
 	key := req.Args["0"]
 	if key == "" {
@@ -114,31 +267,116 @@ func (cr *ConfigRouter) handleGet(_ context.Context, req *Request) *Message {
 	return &Message{Response: val}
 }
 
+func (cr *ConfigRouter) handleGetMany(_ context.Context, req *Request) *Message {
+	var entries []configEntry
+	var keys []string
+	for i := 0; ; i++ {
+		key, ok := req.Args[strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		entry, ok := cr.entries[key]
+		if !ok {
+			return &Message{Error: fmt.Sprintf("unknown config key: %s", key)}
+		}
+		keys = append(keys, key)
+		entries = append(entries, entry)
+	}
+	if len(keys) == 0 {
+		return &Message{Error: "usage: config.getmany <key>..."}
+	}
+
+	values := make(map[string]string, len(keys))
+	cr.mu.RLock()
+	for i, entry := range entries {
+		if val := entry.getter(); val != "" || !entry.deferred {
+			values[keys[i]] = val
+		}
+	}
+	cr.mu.RUnlock()
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		return &Message{Error: err.Error()}
+	}
+	return &Message{Response: string(b)}
+}
+
 func (cr *ConfigRouter) handleSet(_ context.Context, req *Request) *Message {
 	key := req.Args["0"]
 	value := req.Args["1"]
 	if key == "" || value == "" {
 		return &Message{Error: "usage: config.set <key> <value>"}
 	}
+	if err := cr.Set(key, value); err != nil {
+		return &Message{Error: err.Error()}
+	}
+	return &Message{Response: RespOK}
+}
 
+// Set applies a config.set: it runs key's setter under the write lock,
+// credits the value to config.SourceRuntime, and saves the config when SaveTo
+// was called. Reset-required sizing keys are only saved, leaving the running
+// VM's values alone. `br config set` uses it directly to edit a stopped VM's saved
+// config.
+func (cr *ConfigRouter) Set(key, value string) error {
 	entry, ok := cr.entries[key]
 	if !ok {
-		return &Message{Error: fmt.Sprintf("unknown config key: %s", key)}
+		return fmt.Errorf("unknown config key: %s", key)
 	}
-
 	if entry.setter == nil {
-		return &Message{Error: fmt.Sprintf("config key %s is read-only or not supported for remote modification", key)}
+		return fmt.Errorf("config key %s is read-only or not supported for remote modification", key)
 	}
 
 	cr.mu.Lock()
-	err := entry.setter(value)
-	cr.mu.Unlock()
+	defer cr.mu.Unlock()
+	if err := entry.setter(value); err != nil {
+		return fmt.Errorf("failed to set %s: %v", key, err)
+	}
+	if _, pending := cr.next[key]; !pending {
+		cr.cfg.SetSource(key, config.SourceRuntime)
+	}
+	if cr.savePath != "" {
+		if err := cr.save(); err != nil {
+			return fmt.Errorf("set %s for this run, but could not save it: %v", key, err)
+		}
+	}
+	return nil
+}
 
-	if err != nil {
-		return &Message{Error: fmt.Sprintf("failed to set %s: %v", key, err)}
+// save writes cfg, with the pending next-start values applied to a copy, to
+// savePath. It runs with cr.mu held.
+func (cr *ConfigRouter) save() error {
+	out := *cr.cfg
+	out.Sources = maps.Clone(cr.cfg.Sources)
+	for key, apply := range cr.next {
+		apply(&out)
+		out.SetSource(key, config.SourceRuntime)
 	}
+	return out.Save(cr.savePath)
+}
 
-	return &Message{Response: RespOK}
+// handleSource reports which layer set key. Runtime-only keys (the pid, the
+// ssh config path) report runtime unless a layer recorded otherwise, as
+// --image-path does for base-image-path.
+func (cr *ConfigRouter) handleSource(_ context.Context, req *Request) *Message {
+	key := req.Args["0"]
+	if key == "" {
+		return &Message{Error: "usage: config.source <key>"}
+	}
+	if _, ok := cr.entries[key]; !ok {
+		return &Message{Error: fmt.Sprintf("unknown config key: %s", key)}
+	}
+
+	cr.mu.RLock()
+	_, recorded := cr.cfg.Sources[key]
+	src := cr.cfg.SourceOf(key)
+	cr.mu.RUnlock()
+
+	if !recorded && ConfigKeyMetaMap()[key].RequiresVM {
+		src = config.SourceRuntime
+	}
+	return &Message{Response: string(src)}
 }
 
 func (cr *ConfigRouter) handleKeys(_ context.Context, _ *Request) *Message {
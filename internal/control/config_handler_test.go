@@ -2,8 +2,11 @@ package control
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -15,7 +18,7 @@ import (
 // touch the host filesystem. Pass the result of t.TempDir() as baseDir.
 func newTestConfig(t *testing.T, baseDir string) *config.Config {
 	t.Helper()
-	cfg, err := config.Default(baseDir)
+	cfg, err := config.Default(baseDir, "")
 	if err != nil {
 		t.Fatalf("config.Default(%q) error = %v", baseDir, err)
 	}
@@ -120,7 +123,7 @@ func TestConfigGetDeferredKeys(t *testing.T) {
 	}
 
 	baseDir := t.TempDir()
-	cfg, err := config.Default(baseDir)
+	cfg, err := config.Default(baseDir, "")
 	if err != nil {
 		t.Fatalf("config.Default() error = %v", err)
 	}
@@ -142,10 +145,46 @@ func TestConfigGetDeferredKeys(t *testing.T) {
 	}
 }
 
+func TestConfigGetMany(t *testing.T) {
+	baseDir := t.TempDir()
+	cfg, err := config.Default(baseDir, "")
+	if err != nil {
+		t.Fatalf("config.Default() error = %v", err)
+	}
+	cfg.CPUs = 6
+	// SSH config path is deferred and left empty: absent, not an error.
+	cr := NewConfigRouter(cfg)
+	router := cr.Router()
+
+	req := NewRequest(BuildCommand("getmany", ConfigKeyCPUs, ConfigKeyVMDir, ConfigKeySSHConfigPath))
+	resp := router.Dispatch(context.Background(), req)
+	if resp.Error != "" {
+		t.Fatalf("getmany: %s", resp.Error)
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(resp.Response), &got); err != nil {
+		t.Fatalf("decode %q: %v", resp.Response, err)
+	}
+	want := map[string]string{ConfigKeyCPUs: "6", ConfigKeyVMDir: cfg.VMDir}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getmany = %v, want %v", got, want)
+	}
+
+	for name, args := range map[string][]string{
+		"unknown key": {ConfigKeyCPUs, "nonexistent"},
+		"no keys":     nil,
+	} {
+		resp := router.Dispatch(context.Background(), NewRequest(BuildCommand("getmany", args...)))
+		if resp.Error == "" {
+			t.Errorf("%s: expected error, got %q", name, resp.Response)
+		}
+	}
+}
+
 func TestConfigGetLateBinding(t *testing.T) {
 	// Values set on the cfg pointer after router creation should be visible.
 	baseDir := t.TempDir()
-	cfg, err := config.Default(baseDir)
+	cfg, err := config.Default(baseDir, "")
 	if err != nil {
 		t.Fatalf("config.Default() error = %v", err)
 	}
@@ -211,6 +250,215 @@ func TestConfigSetWritableKeys(t *testing.T) {
 			t.Errorf("cfg.BaseImageURL = %q, want %q", cfg.BaseImageURL, newURL)
 		}
 	})
+
+	t.Run("set base-image-url rejects a non-URL", func(t *testing.T) {
+		before := cfg.BaseImageURL
+		req := &Request{Command: "set", Args: map[string]string{"0": ConfigKeyBaseImageURL, "1": "not-a-url"}}
+		resp := router.Dispatch(context.Background(), req)
+		if !strings.Contains(resp.Error, "absolute http(s) URL") {
+			t.Errorf("Error = %q, want an absolute http(s) URL error", resp.Error)
+		}
+		if cfg.BaseImageURL != before {
+			t.Errorf("cfg.BaseImageURL = %q after a refused set, want %q", cfg.BaseImageURL, before)
+		}
+	})
+}
+
+func TestConfigSetSavesConfig(t *testing.T) {
+	baseDir := t.TempDir()
+	cfg := newTestConfig(t, baseDir)
+	cr := NewConfigRouter(cfg)
+	path := config.ConfigPath(baseDir)
+	cr.SaveTo(path)
+
+	newURL := "https://example.com/custom.img"
+	req := &Request{Command: "set", Args: map[string]string{"0": ConfigKeyBaseImageURL, "1": newURL}}
+	if resp := cr.Router().Dispatch(context.Background(), req); resp.Error != "" {
+		t.Fatalf("set: %s", resp.Error)
+	}
+
+	saved, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	next := newTestConfig(t, t.TempDir())
+	next.ApplySaved(saved)
+	if next.BaseImageURL != newURL {
+		t.Errorf("next start's BaseImageURL = %q, want %q", next.BaseImageURL, newURL)
+	}
+
+	// A failed set leaves the file alone.
+	before, _ := os.ReadFile(path)
+	if err := cr.Set(ConfigKeyHostname, "other"); err == nil {
+		t.Fatal("set of a read-only key succeeded")
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Error("a failed set rewrote the saved config")
+	}
+}
+
+type fakeBalloon struct {
+	target uint64
+	err    error
+}
+
+func (b *fakeBalloon) MemoryTargetGiB() uint64 { return b.target }
+
+func (b *fakeBalloon) SetMemoryTargetGiB(gib uint64) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.target = gib
+	return nil
+}
+
+func TestConfigSetMemoryNextBootOnly(t *testing.T) {
+	cfg := newTestConfig(t, t.TempDir())
+	cfg.MemoryGiB = 8
+	cr := NewConfigRouter(cfg)
+	router := cr.Router()
+	set := func(v string) *Message {
+		return router.Dispatch(context.Background(), &Request{Command: "set", Args: map[string]string{"0": ConfigKeyMemoryGiB, "1": v}})
+	}
+	get := func() string {
+		return router.Dispatch(context.Background(), &Request{Command: "get", Args: map[string]string{"0": ConfigKeyMemoryGiB}}).Response
+	}
+
+	balloon := &fakeBalloon{target: 6}
+	cr.SetMemoryBalloon(balloon)
+
+	if resp := set("0"); resp.Error == "" {
+		t.Error("expected error for zero memory")
+	}
+	if resp := set("1"); !strings.Contains(resp.Error, "at least 2 GiB") {
+		t.Errorf("set below the minimum: error = %q", resp.Error)
+	}
+	if resp := set("lots"); resp.Error == "" {
+		t.Error("expected error for non-numeric memory")
+	}
+
+	// Whether under or past the boot size, only the next boot changes: the
+	// balloon is left alone and get keeps reporting the boot size.
+	for _, v := range []string{"4", "64"} {
+		if resp := set(v); resp.Error != "" {
+			t.Fatalf("set %s: %s", v, resp.Error)
+		}
+		if balloon.target != 6 || cfg.MemoryGiB != 8 || get() != "8" {
+			t.Errorf("after set %s: balloon target = %d, boot size = %d, get = %q; want 6, 8, 8", v, balloon.target, cfg.MemoryGiB, get())
+		}
+	}
+}
+
+func TestConfigSetMemoryBalloon(t *testing.T) {
+	cfg := newTestConfig(t, t.TempDir())
+	cfg.MemoryGiB = 8
+	cr := NewConfigRouter(cfg)
+	router := cr.Router()
+	set := func(v string) *Message {
+		return router.Dispatch(context.Background(), &Request{Command: "set", Args: map[string]string{"0": ConfigKeyMemoryBalloonGiB, "1": v}})
+	}
+
+	if resp := set("4"); resp.Error == "" {
+		t.Error("expected error before the VM has a balloon")
+	}
+
+	balloon := &fakeBalloon{target: cfg.MemoryGiB}
+	cr.SetMemoryBalloon(balloon)
+
+	for _, tt := range []struct {
+		val     string
+		want    uint64
+		wantErr string
+	}{
+		{val: "4", want: 4},
+		{val: "2", want: 2},
+		{val: "8", want: 8},
+		{val: "1", want: 2},
+		{val: "0", want: 2},
+		{val: "64", want: 8},
+		{val: "lots", wantErr: "invalid memory size"},
+	} {
+		balloon.target = 5
+		resp := set(tt.val)
+		if tt.wantErr != "" {
+			if !strings.Contains(resp.Error, tt.wantErr) {
+				t.Errorf("set %s: error = %q, want %q", tt.val, resp.Error, tt.wantErr)
+			}
+			if balloon.target != 5 {
+				t.Errorf("set %s: invalid target still moved the balloon to %d", tt.val, balloon.target)
+			}
+			continue
+		}
+		if resp.Error != "" {
+			t.Fatalf("set %s: %s", tt.val, resp.Error)
+		}
+		if balloon.target != tt.want {
+			t.Errorf("set %s: balloon target = %d, want %d", tt.val, balloon.target, tt.want)
+		}
+		get := router.Dispatch(context.Background(), &Request{Command: "get", Args: map[string]string{"0": ConfigKeyMemoryBalloonGiB}})
+		if want := strconv.FormatUint(tt.want, 10); get.Response != want {
+			t.Errorf("set %s: get = %q, want the applied %s", tt.val, get.Response, want)
+		}
+	}
+	if cfg.MemoryGiB != 8 {
+		t.Errorf("boot size = %d, want it left at 8", cfg.MemoryGiB)
+	}
+
+	balloon.err = errors.New("balloon unavailable")
+	if resp := set("2"); !strings.Contains(resp.Error, "balloon unavailable") {
+		t.Errorf("balloon error not surfaced: %q", resp.Error)
+	}
+	get := router.Dispatch(context.Background(), &Request{Command: "get", Args: map[string]string{"0": ConfigKeyMemoryBalloonGiB}})
+	if get.Response != "5" {
+		t.Errorf("get = %q, want the balloon's target 5", get.Response)
+	}
+}
+
+func TestConfigSetSizingSavedForNextStart(t *testing.T) {
+	baseDir := t.TempDir()
+	cfg := newTestConfig(t, baseDir)
+	cfg.CPUs, cfg.MemoryGiB, cfg.DiskSizeGiB = 2, 4, 32
+	cr := NewConfigRouter(cfg)
+	path := config.ConfigPath(baseDir)
+	cr.SaveTo(path)
+
+	for _, tc := range []struct{ key, val, want string }{
+		{ConfigKeyCPUs, "0", "cpus must be >= 1"},
+		{ConfigKeyCPUs, "many", "invalid CPU count"},
+		{ConfigKeyMemoryGiB, "1", "at least 2 GiB"},
+		{ConfigKeyDiskSizeGiB, "8", "at least 16 GiB"},
+	} {
+		if err := cr.Set(tc.key, tc.val); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Set(%s, %s) error = %v, want %q", tc.key, tc.val, err, tc.want)
+		}
+	}
+
+	for key, val := range map[string]string{ConfigKeyCPUs: "8", ConfigKeyMemoryGiB: "16", ConfigKeyDiskSizeGiB: "128"} {
+		if err := cr.Set(key, val); err != nil {
+			t.Fatalf("Set(%s, %s): %v", key, val, err)
+		}
+	}
+
+	// The running VM's values and their sources are untouched.
+	if cfg.CPUs != 2 || cfg.MemoryGiB != 4 || cfg.DiskSizeGiB != 32 {
+		t.Errorf("live config changed: cpus=%d memory=%d disk=%d", cfg.CPUs, cfg.MemoryGiB, cfg.DiskSizeGiB)
+	}
+	if src := cfg.SourceOf(ConfigKeyCPUs); src == config.SourceRuntime {
+		t.Errorf("live cpus source = %s, want unchanged", src)
+	}
+
+	saved, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	next := newTestConfig(t, t.TempDir())
+	next.ApplySaved(saved)
+	if next.CPUs != 8 || next.MemoryGiB != 16 || next.DiskSizeGiB != 128 {
+		t.Errorf("next start: cpus=%d memory=%d disk=%d, want 8, 16, 128", next.CPUs, next.MemoryGiB, next.DiskSizeGiB)
+	}
+	if src := next.SourceOf(ConfigKeyCPUs); src != config.SourceSaved {
+		t.Errorf("next start's cpus source = %s, want %s", src, config.SourceSaved)
+	}
 }
 
 func TestConfigSetReadOnlyKeys(t *testing.T) {
@@ -219,20 +467,17 @@ func TestConfigSetReadOnlyKeys(t *testing.T) {
 	cr := NewConfigRouter(cfg)
 	router := cr.Router()
 
-	// Every key except base-image-url should be read-only
+	// Every key except base-image-url and the sizing keys should be read-only
 	readOnlyKeys := []string{
 		ConfigKeyArch,
 		ConfigKeyBaseImagePath,
 		ConfigKeyCloudInitISO,
-		ConfigKeyCPUs,
-		ConfigKeyDiskSizeGiB,
 		ConfigKeyGUI,
 		ConfigKeyHostname,
 		ConfigKeyLocalAPIPort,
 		ConfigKeyLocalWebPort,
 		ConfigKeyLocalSSHPort,
 		ConfigKeyLogPath,
-		ConfigKeyMemoryGiB,
 		ConfigKeyName,
 		ConfigKeyNetworkMode,
 		ConfigKeyPID,
@@ -436,6 +681,13 @@ func TestConfigKeyRegistryConsistency(t *testing.T) {
 		}
 		seen[meta.Key] = true
 	}
+
+	// Writable keys feed `br config --help`, so each needs an example value.
+	for _, meta := range registry {
+		if meta.Writable && meta.Example == "" {
+			t.Errorf("writable key %q has no Example", meta.Key)
+		}
+	}
 }
 
 func TestConfigKeyRegistryMatchesRouter(t *testing.T) {
@@ -478,12 +730,19 @@ func TestConfigKeyMetaWritableMatchesSetter(t *testing.T) {
 	baseDir := t.TempDir()
 	cfg := newTestConfig(t, baseDir)
 	cr := NewConfigRouter(cfg)
+	cr.SetMemoryBalloon(&fakeBalloon{})
 	router := cr.Router()
 	metaMap := ConfigKeyMetaMap()
+	// Keys whose setter validates its input need a well-formed value.
+	validValues := map[string]string{ConfigKeyCPUs: "2", ConfigKeyMemoryGiB: "2", ConfigKeyMemoryBalloonGiB: "2", ConfigKeyDiskSizeGiB: "32", ConfigKeyDiskBackingMode: config.DiskBackingCopy, ConfigKeyBaseImageURL: "https://example.com/base.raw"}
 
 	for k, meta := range metaMap {
 		t.Run("writable-consistency/"+k, func(t *testing.T) {
-			setReq := &Request{Command: "set", Args: map[string]string{"0": k, "1": "test-value"}}
+			val := "test-value"
+			if v, ok := validValues[k]; ok {
+				val = v
+			}
+			setReq := &Request{Command: "set", Args: map[string]string{"0": k, "1": val}}
 			setResp := router.Dispatch(context.Background(), setReq)
 
 			if meta.Writable {
@@ -498,3 +757,49 @@ func TestConfigKeyMetaWritableMatchesSetter(t *testing.T) {
 		})
 	}
 }
+
+// --- config.source tests ---
+
+func TestConfigSource(t *testing.T) {
+	cfg := newTestConfig(t, t.TempDir())
+	cfg.SetSource(ConfigKeyCPUs, config.SourceFlag)
+	cr := NewConfigRouter(cfg)
+	router := cr.Router()
+
+	source := func(key string) *Message {
+		return router.Dispatch(context.Background(), &Request{Command: "source", Args: map[string]string{"0": key}})
+	}
+	for key, want := range map[string]config.Source{
+		ConfigKeyCPUs:          config.SourceFlag,
+		ConfigKeyMemoryGiB:     config.SourceDefault,
+		ConfigKeyPID:           config.SourceRuntime,
+		ConfigKeySSHConfigPath: config.SourceRuntime,
+	} {
+		if resp := source(key); resp.Error != "" || resp.Response != string(want) {
+			t.Errorf("source %s = %+v, want %q", key, resp, want)
+		}
+	}
+	if resp := source("nope"); resp.Error == "" {
+		t.Error("unknown key should error")
+	}
+
+	// `br config set` marks the value as set at runtime.
+	set := &Request{Command: "set", Args: map[string]string{"0": ConfigKeyBaseImageURL, "1": "https://example.com/x.raw"}}
+	if resp := router.Dispatch(context.Background(), set); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	if resp := source(ConfigKeyBaseImageURL); resp.Response != string(config.SourceRuntime) {
+		t.Errorf("after set: source = %q, want runtime", resp.Response)
+	}
+}
+
+// Every provenance-tracked key must be a real config key, or its source
+// could never be queried.
+func TestSourcedKeysRegistered(t *testing.T) {
+	meta := ConfigKeyMetaMap()
+	for _, k := range config.SourcedKeys() {
+		if _, ok := meta[k]; !ok {
+			t.Errorf("config.SourcedKeys has %q, which is not a registered config key", k)
+		}
+	}
+}
@@ -60,8 +60,9 @@ func (f ControllerFunc) Stop(ctx context.Context) error {
 	return nil
 }
 
-// ProtocolVersion is the current control protocol version.
-// Bump this when making breaking changes to the wire format.
+// ProtocolVersion is the current control protocol's major version.
+// Bump this when making breaking changes to the wire format; CmdHello
+// refuses peers on a different one. See ProtocolMinor.
 const ProtocolVersion = 1
 
 // Status constants
@@ -74,6 +75,14 @@ const (
 	// still booting). The host run-state alone would report StatusRunning, so
 	// this exists to avoid reporting a dead guest as healthy.
 	StatusUnreachable = "unreachable"
+	// StatusPaused means the guest's vCPUs are frozen (CmdPause, or the window
+	// of a live snapshot save). A paused guest can't answer the liveness
+	// probe, so this is reported instead of StatusUnreachable.
+	StatusPaused = "paused"
+	// StatusUnresponsive is reported client-side when the control socket
+	// exists but the host process does not answer (see ErrUnresponsive). The
+	// VM may or may not be running; it is certainly not cleanly stopped.
+	StatusUnresponsive = "unresponsive"
 )
 
 // Command constants
@@ -96,6 +105,11 @@ const (
 	// out. Positional arg 0 is the timeout in seconds; arg 1 is EjectModeForce
 	// when a forced stop was explicitly requested. The response body is RespOK.
 	CmdEject = "eject"
+	// CmdKill force-stops the VM without asking the guest (no ACPI), then
+	// unblocks the foreground runner so it exits. It backs `br stop --force`
+	// for a guest known to ignore graceful shutdown. The response body is
+	// RespOK once the VM has stopped.
+	CmdKill = "kill"
 )
 
 // EjectModeForce is the CmdEject argument that forces a stop without waiting the
@@ -115,6 +129,14 @@ const (
 	CmdConfigGet  = "config.get"
 	CmdConfigSet  = "config.set"
 	CmdConfigKeys = "config.keys"
+	// CmdConfigSource reports which layer set a key's value (see
+	// config.Source).
+	CmdConfigSource = "config.source"
+	// CmdConfigGetMany reads several keys in one round trip. The positional
+	// args are the keys; the response body is a JSON object of key to value.
+	// A deferred key with no value yet is left out rather than failing the
+	// whole request.
+	CmdConfigGetMany = "config.getmany"
 )
 
 // Config key constants
@@ -132,7 +154,9 @@ const (
 	ConfigKeyCPUs              = "cpus"
 	ConfigKeyMemoryGiB         = "memory-gib"
 	ConfigKeyDiskSizeGiB       = "disk-size-gib"
+	ConfigKeyDiskBackingMode   = "disk-backing-mode"
 	ConfigKeyArch              = "arch"
+	ConfigKeyDistro            = "distro"
 	ConfigKeyHostname          = "hostname"
 	ConfigKeyNetworkMode       = "network-mode"
 	ConfigKeyLogPath           = "log-path"
@@ -142,6 +166,9 @@ const (
 	ConfigKeyBaseImagePath     = "base-image-path"
 	ConfigKeyCloudInitISO      = "cloud-init-iso"
 	ConfigKeyDiskPath          = "disk-path"
+	// ConfigKeyMemoryBalloonGiB is the running guest's live memory balloon
+	// target. Setting it leaves the next boot size (memory-gib) alone.
+	ConfigKeyMemoryBalloonGiB = "memory-balloon-gib"
 	// ConfigKeyGuestImageVersion is the YYYY.MM.DD build date baked into the
 	// guest image at /etc/bladerunner-image-version. Read via SSH; empty when
 	// the running image was not built by scripts/build-guest-image.sh
@@ -169,18 +196,23 @@ type ConfigKeyMeta struct {
 	Writable bool
 	// Description is a short human-readable description.
 	Description string
+	// Example is a sample value shown in `br config --help`. Every writable
+	// key has one.
+	Example string
 }
 
 // ConfigKeyRegistry returns metadata for all known config keys.
 func ConfigKeyRegistry() []ConfigKeyMeta {
 	return []ConfigKeyMeta{
-		{Key: ConfigKeyArch, Description: "Host architecture"},
+		{Key: ConfigKeyArch, Description: "Guest architecture"},
 		{Key: ConfigKeyBaseImagePath, RequiresVM: true, Description: "Resolved base image path"},
-		{Key: ConfigKeyBaseImageURL, Writable: true, RequiresReset: true, Description: "Cloud image URL"},
+		{Key: ConfigKeyBaseImageURL, Writable: true, RequiresReset: true, Description: "Cloud image URL", Example: "https://cloud-images.ubuntu.com/releases/noble/release/ubuntu-24.04-server-cloudimg-arm64.img"},
 		{Key: ConfigKeyCloudInitISO, Description: "Cloud-init ISO path"},
-		{Key: ConfigKeyCPUs, RequiresReset: true, Description: "Number of CPUs"},
+		{Key: ConfigKeyCPUs, Writable: true, RequiresReset: true, Description: "Number of CPUs", Example: "8"},
+		{Key: ConfigKeyDiskBackingMode, Writable: true, RequiresReset: true, Description: "How a new disk is made from the base image (copy, qcow2-overlay)", Example: "copy"},
 		{Key: ConfigKeyDiskPath, Description: "Main disk image path"},
-		{Key: ConfigKeyDiskSizeGiB, RequiresReset: true, Description: "Disk size in GiB"},
+		{Key: ConfigKeyDiskSizeGiB, Writable: true, RequiresReset: true, Description: "Disk size in GiB", Example: "64"},
+		{Key: ConfigKeyDistro, Description: "Stock distro image picked with --distro (empty for the default or a custom image)"},
 		{Key: ConfigKeyGuestImageVersion, RequiresVM: true, Description: "Pre-baked guest image build date (YYYY.MM.DD)"},
 		{Key: ConfigKeyGUI, RequiresReset: true, Description: "GUI console enabled"},
 		{Key: ConfigKeyHostname, RequiresReset: true, Description: "VM hostname"},
@@ -189,7 +221,8 @@ func ConfigKeyRegistry() []ConfigKeyMeta {
 		{Key: ConfigKeyLocalSSHPort, RequiresReset: true, Description: "Local SSH port"},
 		{Key: ConfigKeyLocalWebPort, RequiresReset: true, Description: "Local web UI port"},
 		{Key: ConfigKeyLogPath, Description: "Log file path"},
-		{Key: ConfigKeyMemoryGiB, RequiresReset: true, Description: "Memory in GiB"},
+		{Key: ConfigKeyMemoryBalloonGiB, RequiresVM: true, Writable: true, Description: "Live memory balloon target in GiB, clamped to 2 and the boot size; not saved", Example: "4"},
+		{Key: ConfigKeyMemoryGiB, Writable: true, RequiresReset: true, Description: "Memory in GiB at boot; resize a running VM with memory-balloon-gib", Example: "4"},
 		{Key: ConfigKeyName, Description: "Instance name"},
 		{Key: ConfigKeyNestedVirt, RequiresVM: true, Description: "Nested virtualization / Incus VM support (enabled/unsupported/disabled)"},
 		{Key: ConfigKeyNetworkMode, RequiresReset: true, Description: "Network mode (shared/bridged)"},
@@ -1,15 +1,23 @@
 package control
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
 )
 
 // wireFormats defines the wire formats to test integration against.
@@ -150,6 +158,45 @@ func TestClientEject(t *testing.T) {
 	})
 }
 
+func TestClientKill(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-kill-")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	server, err := NewListenerWithConfig(ListenerConfig{
+		StateDir:   tmpDir,
+		Controller: ControllerFunc{},
+	})
+	if err != nil {
+		t.Fatalf("NewListenerWithConfig: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	fail := false
+	server.Router().HandleFunc(CmdKill, func(_ context.Context, _ *Request) *Message {
+		if fail {
+			return &Message{Error: "vm did not stop"}
+		}
+		return &Message{Response: RespOK}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	client := NewClient(tmpDir)
+	if err := client.Kill(); err != nil {
+		t.Fatalf("Kill: %v", err)
+	}
+	fail = true
+	if err := client.Kill(); err == nil || !strings.Contains(err.Error(), "vm did not stop") {
+		t.Errorf("Kill error = %v, want server error surfaced", err)
+	}
+}
+
 func TestClientNotRunning(t *testing.T) {
 	tmpDir := t.TempDir()
 	client := NewClient(tmpDir)
@@ -178,7 +225,69 @@ func TestClientNotRunning(t *testing.T) {
 	})
 }
 
+// TestListenerReclaimsDeadOwnerSocket verifies a socket that still accepts
+// connections is only treated as taken while the PID recorded beside it is
+// alive, and that the new server records itself as the owner.
+func TestListenerReclaimsDeadOwnerSocket(t *testing.T) {
+	t.Setenv(config.ControlSocketEnvVar, "")
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	sock := filepath.Join(tmpDir, SocketName)
+	pidPath := filepath.Join(tmpDir, "control.pid")
+
+	// A listener nobody serves still completes connects from its backlog.
+	held, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = held.Close() }()
+
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewListener(tmpDir, nil); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("live owner: err = %v, want already running", err)
+	}
+
+	dead := exec.Command("true")
+	if err := dead.Run(); err != nil {
+		t.Skipf("no exited process to borrow a PID from: %v", err)
+	}
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(dead.Process.Pid)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewListener(tmpDir, nil)
+	if err != nil {
+		t.Fatalf("dead owner: %v", err)
+	}
+	if got := readOwnerPID(pidPath); got != os.Getpid() {
+		t.Errorf("recorded owner = %d, want %d", got, os.Getpid())
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(pidPath); !os.IsNotExist(err) {
+		t.Errorf("Close left %s behind: %v", pidPath, err)
+	}
+}
+
+func TestOwnerPIDPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/state/control.sock":         "/state/control.pid",
+		"/tmp/bladerunner-abc12.sock": "/tmp/bladerunner-abc12.pid",
+		"/run/br":                     "/run/br.pid",
+	} {
+		if got := OwnerPIDPath(in); got != want {
+			t.Errorf("OwnerPIDPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
 func TestSocketPath(t *testing.T) {
+	t.Setenv(config.ControlSocketEnvVar, "")
 	stateDir := "/test/state"
 	expected := filepath.Join(stateDir, SocketName)
 	got := SocketPath(stateDir)
@@ -187,6 +296,65 @@ func TestSocketPath(t *testing.T) {
 	}
 }
 
+func TestResolveSocketPath(t *testing.T) {
+	t.Setenv(config.ControlSocketEnvVar, "")
+	t.Setenv("TMPDIR", "/tmp")
+	deep := "/" + strings.Repeat("nested/", 20) + "state"
+
+	got, err := ResolveSocketPath("", deep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "/tmp/bladerunner-") || len(got) > config.MaxSocketPathLen {
+		t.Errorf("long state dir resolved to %q, want a short $TMPDIR path", got)
+	}
+	if again, _ := ResolveSocketPath("", deep); again != got {
+		t.Errorf("fallback not stable: %q then %q", got, again)
+	}
+	if other, _ := ResolveSocketPath("", deep+"2"); other == got {
+		t.Error("different state dirs share a fallback socket")
+	}
+
+	if got, err := ResolveSocketPath("/run/br.sock", deep); err != nil || got != "/run/br.sock" {
+		t.Errorf("override: %q, %v", got, err)
+	}
+	t.Setenv(config.ControlSocketEnvVar, "/run/env.sock")
+	if got := SocketPath(deep); got != "/run/env.sock" {
+		t.Errorf("env override: %q", got)
+	}
+	if _, err := ResolveSocketPath("/"+strings.Repeat("x", config.MaxSocketPathLen), deep); err == nil {
+		t.Error("over-long override should be rejected")
+	}
+}
+
+// TestListenerDeepStateDir runs a server and client under a state dir whose
+// control.sock would exceed the Unix socket path limit.
+func TestListenerDeepStateDir(t *testing.T) {
+	t.Setenv(config.ControlSocketEnvVar, "")
+	base, err := os.MkdirTemp("/tmp", "ctrl-deep-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(base) })
+	stateDir := filepath.Join(base, strings.Repeat("d", 60), strings.Repeat("e", 60))
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewListener(stateDir, NewLocalController(func() {}))
+	if err != nil {
+		t.Fatalf("NewListener: %v", err)
+	}
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	if err := NewClient(stateDir).Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+}
+
 func TestServerClose(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -327,6 +495,24 @@ func TestClientWithMockDialer(t *testing.T) {
 		}
 	})
 
+	t.Run("GetConfigMany with mock", func(t *testing.T) {
+		conn := &mockConn{readData: []byte(`v1 {"cpus":"4","vm-dir":"/vm"}` + "\n")}
+		client := NewClientWithDialer("/tmp/test", &mockDialer{conn: conn})
+		negotiated := CurrentProtocol
+		client.protocol.Store(&negotiated)
+
+		got, err := client.GetConfigMany([]string{ConfigKeyCPUs, ConfigKeyVMDir})
+		if err != nil {
+			t.Fatalf("GetConfigMany() error = %v", err)
+		}
+		if want := "v1 config.getmany cpus vm-dir\n"; string(conn.writeData) != want {
+			t.Errorf("sent = %q, want %q", conn.writeData, want)
+		}
+		if want := map[string]string{"cpus": "4", "vm-dir": "/vm"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GetConfigMany() = %v, want %v", got, want)
+		}
+	})
+
 	t.Run("legacy v0 response accepted", func(t *testing.T) {
 		conn := &mockConn{readData: []byte("pong\n")}
 		dialer := &mockDialer{conn: conn}
@@ -381,6 +567,48 @@ func TestNewRequest(t *testing.T) {
 			t.Errorf("Args[1] = %q, want %q", req.Args["1"], "world")
 		}
 	})
+
+	t.Run("quoted values keep their spaces", func(t *testing.T) {
+		cases := []struct {
+			raw  string
+			want map[string]string
+		}{
+			{`config.set base-image-url "https://example.test/my image.qcow2"`, map[string]string{"0": "base-image-url", "1": "https://example.test/my image.qcow2"}},
+			{`config.set key 'a  b'`, map[string]string{"0": "key", "1": "a  b"}},
+			{`config.set key=" spaced value "`, map[string]string{"key": " spaced value "}},
+			{`echo a\ b "say \"hi\"" 'it''s'`, map[string]string{"0": "a b", "1": `say "hi"`, "2": "its"}},
+			{`echo "C:\dir\x" 'back\slash'`, map[string]string{"0": `C:\dir\x`, "1": `back\slash`}},
+			{`echo "" x`, map[string]string{"0": "", "1": "x"}},
+		}
+		for _, tc := range cases {
+			req := NewRequest(tc.raw)
+			if !reflect.DeepEqual(req.Args, tc.want) {
+				t.Errorf("NewRequest(%s).Args = %q, want %q", tc.raw, req.Args, tc.want)
+			}
+		}
+	})
+
+	t.Run("embedded equals", func(t *testing.T) {
+		req := NewRequest(`config.set url "https://x.test/?a=1&b=2" opt=k=v`)
+		if req.Args["1"] != "https://x.test/?a=1&b=2" {
+			t.Errorf("quoted '=' should stay positional: Args = %q", req.Args)
+		}
+		if req.Args["opt"] != "k=v" {
+			t.Errorf("Args[opt] = %q, want %q", req.Args["opt"], "k=v")
+		}
+	})
+}
+
+func TestBuildCommandRoundTrip(t *testing.T) {
+	for _, v := range []string{"plain", "a b", "", `quote"d`, `back\slash`, "k=v", "it's", "tab\there"} {
+		req := NewRequest(BuildCommand(CmdConfigSet, "key", v))
+		if req.Command != CmdConfigSet || req.Args["0"] != "key" || req.Args["1"] != v || len(req.Args) != 2 {
+			t.Errorf("round trip of %q: %q %q", v, req.Command, req.Args)
+		}
+	}
+	if got := BuildCommand(CmdEject, "30", EjectModeForce); got != "eject 30 "+EjectModeForce {
+		t.Errorf("simple args should not be quoted: %q", got)
+	}
 }
 
 func TestRouter(t *testing.T) {
@@ -508,6 +736,35 @@ func TestClientDialError(t *testing.T) {
 	})
 }
 
+func TestClientPingClassifiesFailures(t *testing.T) {
+	t.Run("missing socket is not running", func(t *testing.T) {
+		dialer := &errorDialer{dialErr: fmt.Errorf("dial unix /nonexistent: no such file or directory")}
+		err := NewClientWithDialer("/tmp/test", dialer).Ping(context.Background())
+		if !errors.Is(err, ErrNotRunning) {
+			t.Errorf("Ping() = %v, want ErrNotRunning", err)
+		}
+	})
+
+	t.Run("permission denied is unresponsive", func(t *testing.T) {
+		dialer := &errorDialer{dialErr: fmt.Errorf("dial unix /tmp/test/control.sock: permission denied")}
+		err := NewClientWithDialer("/tmp/test", dialer).Ping(context.Background())
+		if !errors.Is(err, ErrUnresponsive) {
+			t.Errorf("Ping() = %v, want ErrUnresponsive", err)
+		}
+		if !strings.Contains(err.Error(), "permission denied") {
+			t.Errorf("Ping() = %v, want underlying cause in message", err)
+		}
+	})
+
+	t.Run("hung server is unresponsive", func(t *testing.T) {
+		dialer := &errorDialer{conn: &errorConn{readErr: os.ErrDeadlineExceeded}}
+		err := NewClientWithDialer("/tmp/test", dialer).Ping(context.Background())
+		if !errors.Is(err, ErrUnresponsive) {
+			t.Errorf("Ping() = %v, want ErrUnresponsive", err)
+		}
+	})
+}
+
 func TestClientReadError(t *testing.T) {
 	t.Run("read error during ping", func(t *testing.T) {
 		conn := &errorConn{readErr: net.ErrClosed}
@@ -766,6 +1023,223 @@ func TestRapidConnectDisconnect(t *testing.T) {
 
 // --- Wire Format Tests ---
 
+// countingTransport is a UnixTransport that counts dials.
+type countingTransport struct {
+	UnixTransport
+	dials atomic.Int32
+}
+
+func (c *countingTransport) Dial(address string, timeout time.Duration) (net.Conn, error) {
+	c.dials.Add(1)
+	return c.UnixTransport.Dial(address, timeout)
+}
+
+func TestClientKeepAlive(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-keep-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	server, err := NewServer(tmpDir, func() {})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	transport := &countingTransport{}
+	client := NewClientWithConfig(ClientConfig{StateDir: tmpDir, Transport: transport, KeepAlive: true})
+	defer func() { _ = client.Close() }()
+	for i := 0; i < 10; i++ {
+		if status, err := client.GetStatus(); err != nil || status != StatusRunning {
+			t.Fatalf("GetStatus #%d = %q, %v", i, status, err)
+		}
+	}
+	// One dial negotiates the protocol, one opens the held connection.
+	if n := transport.dials.Load(); n != 2 {
+		t.Errorf("dialed %d times for 10 commands, want 2", n)
+	}
+
+	// After Close the next command dials again.
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !client.IsRunning() {
+		t.Error("IsRunning() = false after Close")
+	}
+	if n := transport.dials.Load(); n != 3 {
+		t.Errorf("dials after Close = %d, want 3", n)
+	}
+}
+
+// TestListenerKeepAliveOptIn verifies the server closes a connection after
+// one command unless the client sent CmdKeepAlive, and that a keep-alive
+// connection answers commands the client pipelined.
+func TestListenerKeepAliveOptIn(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-keep-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	server, err := NewServer(tmpDir, func() {})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+	sock := filepath.Join(tmpDir, SocketName)
+
+	dial := func(t *testing.T) (net.Conn, *bufio.Reader) {
+		t.Helper()
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+		return conn, bufio.NewReader(conn)
+	}
+
+	t.Run("one command by default", func(t *testing.T) {
+		conn, r := dial(t)
+		if _, err := conn.Write([]byte("status\nstatus\n")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Fatalf("first reply: %v", err)
+		}
+		if line, err := r.ReadString('\n'); err != io.EOF {
+			t.Errorf("second reply = %q, %v; want EOF", line, err)
+		}
+	})
+
+	t.Run("pipelined after keepalive", func(t *testing.T) {
+		conn, r := dial(t)
+		if _, err := conn.Write([]byte("keepalive\nstatus\nping\n")); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{RespOK, StatusRunning, RespPong} {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading reply %q: %v", want, err)
+			}
+			if !strings.Contains(line, want) {
+				t.Errorf("reply = %q, want %q", line, want)
+			}
+		}
+	})
+}
+
+// TestClientKeepAliveRedials verifies a KeepAlive client recovers when the
+// server drops the held connection, as it does with idle ones.
+func TestClientKeepAliveRedials(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-keep-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	sock := filepath.Join(tmpDir, SocketName)
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	// Answers hello, acknowledges keepalive, then serves one more command
+	// and drops the connection.
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					break
+				}
+				switch {
+				case strings.Contains(line, CmdHello):
+					_, _ = conn.Write([]byte("v1 " + CurrentProtocol.String() + "\n"))
+				case strings.Contains(line, CmdKeepAlive):
+					_, _ = conn.Write([]byte("v1 ok\n"))
+					continue
+				default:
+					_, _ = conn.Write([]byte("v1 pong\n"))
+				}
+				break
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	transport := &countingTransport{}
+	client := NewClientWithConfig(ClientConfig{SocketPath: sock, Transport: transport, KeepAlive: true})
+	defer func() { _ = client.Close() }()
+	for i := 0; i < 3; i++ {
+		if err := client.PingContext(context.Background()); err != nil {
+			t.Fatalf("ping #%d: %v", i, err)
+		}
+	}
+	// One for hello, then a held connection per ping: each ping after the
+	// first finds the last one dropped and redials.
+	if n := transport.dials.Load(); n != 4 {
+		t.Errorf("dials = %d, want 4", n)
+	}
+}
+
+// TestClientKeepAliveOldServer verifies a KeepAlive client falls back to a
+// connection per command against a server that predates CmdKeepAlive.
+func TestClientKeepAliveOldServer(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-keep-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	sock := filepath.Join(tmpDir, SocketName)
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			if strings.Contains(line, CmdHello) {
+				_, _ = conn.Write([]byte("v1 error: unknown command: hello\n"))
+			} else {
+				_, _ = conn.Write([]byte("v1 pong\n"))
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	transport := &countingTransport{}
+	client := NewClientWithConfig(ClientConfig{SocketPath: sock, Transport: transport, KeepAlive: true})
+	defer func() { _ = client.Close() }()
+	for i := 0; i < 3; i++ {
+		if err := client.PingContext(context.Background()); err != nil {
+			t.Fatalf("ping #%d: %v", i, err)
+		}
+	}
+	// One for the hello that finds the server old, then one per command.
+	if n := transport.dials.Load(); n != 4 {
+		t.Errorf("dials = %d, want 4", n)
+	}
+}
+
 func TestWireFormatEncodeDecode(t *testing.T) {
 	t.Run("LineFormat command", func(t *testing.T) {
 		format := LineFormat{}
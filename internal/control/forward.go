@@ -0,0 +1,86 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Forward command constants. The optional positional arg 0 names a single
+// forwarder ("ssh", "incus-api"); without it the command applies to all.
+const (
+	CmdForwardPause  = "forward.pause"
+	CmdForwardResume = "forward.resume"
+	// CmdForwardList responds with a JSON array of ForwardInfo.
+	CmdForwardList = "forward.list"
+	// CmdForwardAdd takes a --forward spec as arg 0 and starts that forward
+	// on the running VM; CmdForwardRemove takes a forward name.
+	CmdForwardAdd    = "forward.add"
+	CmdForwardRemove = "forward.remove"
+)
+
+// ForwardInfo is one host-to-guest port forwarder as reported by
+// CmdForwardList.
+type ForwardInfo struct {
+	Name   string `json:"name"`
+	Listen string `json:"listen"`
+	Paused bool   `json:"paused"`
+}
+
+// PauseForwards closes the host listeners of the named forwarder (all when
+// name is empty) on the running instance, leaving the VM running.
+func (c *Client) PauseForwards(name string) error {
+	return c.forwardCommand(CmdForwardPause, name)
+}
+
+// ResumeForwards re-binds previously paused forwarders on their original
+// ports. It fails if a port was taken while paused.
+func (c *Client) ResumeForwards(name string) error {
+	return c.forwardCommand(CmdForwardResume, name)
+}
+
+// AddForward starts the forward described by spec ([host:]localport[:tcp]:guestport)
+// on the running instance, installing its guest relay.
+func (c *Client) AddForward(spec string) error {
+	return c.forwardCommandTimeout(CmdForwardAdd, spec, forwardEditTimeout)
+}
+
+// RemoveForward stops the named forward added with AddForward or --forward
+// and removes its guest relay.
+func (c *Client) RemoveForward(name string) error {
+	return c.forwardCommandTimeout(CmdForwardRemove, name, forwardEditTimeout)
+}
+
+// ListForwards returns the running instance's forwarders and paused state.
+func (c *Client) ListForwards() ([]ForwardInfo, error) {
+	resp, err := c.sendCommand(CmdForwardList, clientCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("list forwards: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("forward error: %s", resp.Error)
+	}
+	var out []ForwardInfo
+	if err := json.Unmarshal([]byte(resp.Response), &out); err != nil {
+		return nil, fmt.Errorf("decode forward list: %w", err)
+	}
+	return out, nil
+}
+
+func (c *Client) forwardCommand(cmd, name string) error {
+	return c.forwardCommandTimeout(cmd, name, clientCmdTimeout)
+}
+
+func (c *Client) forwardCommandTimeout(cmd, name string, timeout time.Duration) error {
+	if name != "" {
+		cmd = BuildCommand(cmd, name)
+	}
+	resp, err := c.sendCommand(cmd, timeout)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("forward error: %s", resp.Error)
+	}
+	return nil
+}
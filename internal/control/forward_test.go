@@ -0,0 +1,103 @@
+package control
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestClientForwardCommands verifies the forward.* client methods reach a
+// mounted "forward" sub-router with the optional name arg, and that the list
+// response decodes into ForwardInfo.
+func TestClientForwardCommands(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-forward-")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	server, err := NewListenerWithConfig(ListenerConfig{
+		StateDir:   tmpDir,
+		Controller: ControllerFunc{},
+	})
+	if err != nil {
+		t.Fatalf("NewListenerWithConfig: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	paused := map[string]bool{"ssh": false, "incus-api": false}
+	set := func(v bool) HandlerFunc {
+		return func(_ context.Context, req *Request) *Message {
+			for name := range paused {
+				if req.Args["0"] == "" || req.Args["0"] == name {
+					paused[name] = v
+				}
+			}
+			return &Message{Response: RespOK}
+		}
+	}
+	fwd := NewRouter()
+	fwd.HandleFunc("pause", set(true))
+	fwd.HandleFunc("resume", set(false))
+	fwd.HandleFunc("list", func(_ context.Context, _ *Request) *Message {
+		return &Message{Response: `[{"name":"ssh","listen":"127.0.0.1:6022","paused":` + strconv.FormatBool(paused["ssh"]) + `}]`}
+	})
+	var added, removed string
+	fwd.HandleFunc("add", func(_ context.Context, req *Request) *Message {
+		added = req.Args["0"]
+		return &Message{Response: RespOK}
+	})
+	fwd.HandleFunc("remove", func(_ context.Context, req *Request) *Message {
+		removed = req.Args["0"]
+		if removed == "ssh" {
+			return &Message{Error: `forward "ssh" is built in; pause it instead`}
+		}
+		return &Message{Response: RespOK}
+	})
+	server.Router().Mount("forward", fwd)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	client := NewClient(tmpDir)
+
+	if err := client.PauseForwards("ssh"); err != nil {
+		t.Fatalf("PauseForwards: %v", err)
+	}
+	if !paused["ssh"] || paused["incus-api"] {
+		t.Errorf("pause ssh affected the wrong forwarders: %v", paused)
+	}
+
+	list, err := client.ListForwards()
+	if err != nil {
+		t.Fatalf("ListForwards: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "ssh" || !list[0].Paused {
+		t.Errorf("ListForwards = %+v, want paused ssh", list)
+	}
+
+	if err := client.ResumeForwards(""); err != nil {
+		t.Fatalf("ResumeForwards: %v", err)
+	}
+	if paused["ssh"] || paused["incus-api"] {
+		t.Errorf("resume all left forwarders paused: %v", paused)
+	}
+
+	if err := client.AddForward("127.0.0.1:8080:tcp:80"); err != nil {
+		t.Fatalf("AddForward: %v", err)
+	}
+	if added != "127.0.0.1:8080:tcp:80" {
+		t.Errorf("forward.add got spec %q", added)
+	}
+	if err := client.RemoveForward("fwd-8080"); err != nil || removed != "fwd-8080" {
+		t.Errorf("RemoveForward(fwd-8080) = %v, handler got %q", err, removed)
+	}
+	if err := client.RemoveForward("ssh"); err == nil || !strings.Contains(err.Error(), "built in") {
+		t.Errorf("RemoveForward(ssh) = %v, want the handler's error", err)
+	}
+}
@@ -0,0 +1,66 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// CmdGuestStatus responds with a GuestStatus JSON object. The host serves it
+// by relaying the same command, in LineFormat, to the guest agent listening on
+// the guest's vsock control port.
+const CmdGuestStatus = "guest.status"
+
+// GuestStatus is what the guest agent reports about provisioning.
+type GuestStatus struct {
+	// Ready is whether the bootstrap wrote /var/lib/bladerunner/ready.
+	Ready bool `json:"ready"`
+	// ReadyAt is the timestamp the bootstrap wrote into the ready file.
+	ReadyAt string `json:"ready_at,omitempty"`
+	// CloudInit is `cloud-init status` ("running", "done", "error", ...), or
+	// "unknown" when the guest has no cloud-init.
+	CloudInit     string `json:"cloud_init"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// QueryGuestStatus asks the guest agent on conn for its GuestStatus. The
+// whole exchange must finish within timeout; conn is left open.
+func QueryGuestStatus(conn net.Conn, timeout time.Duration) (*GuestStatus, error) {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, fmt.Errorf("set deadline: %w", err)
+	}
+	var wire LineFormat
+	if err := wire.Encode(conn, &Message{Version: ProtocolVersion, Command: CmdGuestStatus}); err != nil {
+		return nil, fmt.Errorf("send guest command: %w", err)
+	}
+	resp, err := wire.Decode(conn)
+	if err != nil {
+		return nil, fmt.Errorf("read guest response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("guest agent: %s", resp.Error)
+	}
+	return decodeGuestStatus(resp.Response)
+}
+
+// GetGuestStatus fetches the guest agent's status through the running
+// instance, which dials the guest over vsock.
+func (c *Client) GetGuestStatus() (*GuestStatus, error) {
+	resp, err := c.sendCommand(CmdGuestStatus, clientCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("get guest status: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("guest status error: %s", resp.Error)
+	}
+	return decodeGuestStatus(resp.Response)
+}
+
+func decodeGuestStatus(s string) (*GuestStatus, error) {
+	var st GuestStatus
+	if err := json.Unmarshal([]byte(s), &st); err != nil {
+		return nil, fmt.Errorf("decode guest status: %w", err)
+	}
+	return &st, nil
+}
@@ -0,0 +1,68 @@
+package control
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGuestAgent answers one request on conn the way the guest agent script
+// does, recording the request line it read.
+func fakeGuestAgent(t *testing.T, conn net.Conn, reply string) <-chan string {
+	t.Helper()
+	got := make(chan string, 1)
+	go func() {
+		defer func() { _ = conn.Close() }()
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			got <- "read error: " + err.Error()
+			return
+		}
+		got <- line
+		_, _ = conn.Write([]byte(reply))
+	}()
+	return got
+}
+
+func TestQueryGuestStatus(t *testing.T) {
+	host, guest := net.Pipe()
+	defer func() { _ = host.Close() }()
+	req := fakeGuestAgent(t, guest,
+		`v1 {"ready":true,"ready_at":"2026-03-01T12:00:00Z","cloud_init":"done","uptime_seconds":42}`+"\n")
+
+	st, err := QueryGuestStatus(host, time.Second)
+	if err != nil {
+		t.Fatalf("QueryGuestStatus: %v", err)
+	}
+	if line := <-req; line != "v1 guest.status\n" {
+		t.Errorf("request line = %q, want %q", line, "v1 guest.status\n")
+	}
+	want := GuestStatus{Ready: true, ReadyAt: "2026-03-01T12:00:00Z", CloudInit: "done", UptimeSeconds: 42}
+	if *st != want {
+		t.Errorf("status = %+v, want %+v", *st, want)
+	}
+}
+
+func TestQueryGuestStatusAgentError(t *testing.T) {
+	host, guest := net.Pipe()
+	defer func() { _ = host.Close() }()
+	fakeGuestAgent(t, guest, "v1 error: unknown command: guest.status\n")
+
+	_, err := QueryGuestStatus(host, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Fatalf("err = %v, want the agent's unknown command error", err)
+	}
+}
+
+func TestQueryGuestStatusTimeout(t *testing.T) {
+	host, guest := net.Pipe()
+	defer func() { _ = host.Close() }()
+	defer func() { _ = guest.Close() }()
+	go func() { _, _ = bufio.NewReader(guest).ReadString('\n') }() // never replies
+
+	if _, err := QueryGuestStatus(host, 50*time.Millisecond); err == nil {
+		t.Fatal("QueryGuestStatus returned no error for a silent agent")
+	}
+}
@@ -0,0 +1,154 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CmdHello negotiates the protocol version. Positional arg 0 is the client's
+// version as "major.minor"; the response body is the version both sides
+// speak: the shared major and the lower of the two minors. A client whose
+// major differs gets an error, so peers that would misparse each other fail
+// up front instead.
+const CmdHello = "hello"
+
+// ProtocolMinor counts backward-compatible additions, such as new commands,
+// within ProtocolVersion. Bump it when adding one, and gate the client side
+// of the addition with requireMinor; reset it to 0 when ProtocolVersion
+// changes.
+const ProtocolMinor = 3
+
+// The minors that added each command since CmdHello.
+const (
+	// MinorConfigGetMany added CmdConfigGetMany.
+	MinorConfigGetMany = 1
+	// MinorLogLevel added CmdLogLevel.
+	MinorLogLevel = 2
+	// MinorKeepAlive added CmdKeepAlive.
+	MinorKeepAlive = 3
+)
+
+// ErrUnsupported is returned (wrapped with the versions involved) by client
+// calls whose command the server's negotiated protocol predates.
+var ErrUnsupported = errors.New("not supported by the running instance")
+
+// Protocol is a control protocol version.
+type Protocol struct {
+	Major int
+	Minor int
+}
+
+// CurrentProtocol is the protocol version this build speaks.
+var CurrentProtocol = Protocol{Major: ProtocolVersion, Minor: ProtocolMinor}
+
+// String formats p as "major.minor".
+func (p Protocol) String() string {
+	return fmt.Sprintf("%d.%d", p.Major, p.Minor)
+}
+
+// ParseProtocol parses a "major.minor" protocol version. A bare major means
+// minor 0.
+func ParseProtocol(s string) (Protocol, error) {
+	majorStr, minorStr, hasMinor := strings.Cut(s, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil || major < 0 {
+		return Protocol{}, fmt.Errorf("invalid protocol version %q", s)
+	}
+	p := Protocol{Major: major}
+	if hasMinor {
+		minor, err := strconv.Atoi(minorStr)
+		if err != nil || minor < 0 {
+			return Protocol{}, fmt.Errorf("invalid protocol version %q", s)
+		}
+		p.Minor = minor
+	}
+	return p, nil
+}
+
+// negotiateProtocol returns the version to speak with a peer offering
+// theirs, or an error when the majors differ.
+func negotiateProtocol(theirs Protocol) (Protocol, error) {
+	if theirs.Major != ProtocolVersion {
+		return Protocol{}, fmt.Errorf("client speaks control protocol %s, server speaks %s; use matching bladerunner versions", theirs, CurrentProtocol)
+	}
+	return Protocol{Major: ProtocolVersion, Minor: min(theirs.Minor, ProtocolMinor)}, nil
+}
+
+// RegisterHello registers CmdHello.
+func (r *Router) RegisterHello() {
+	r.HandleFunc(CmdHello, func(_ context.Context, req *Request) *Message {
+		theirs, err := ParseProtocol(req.Args["0"])
+		if err != nil {
+			return &Message{Error: err.Error()}
+		}
+		p, err := negotiateProtocol(theirs)
+		if err != nil {
+			return &Message{Error: err.Error()}
+		}
+		return &Message{Response: p.String()}
+	})
+}
+
+// Hello negotiates the protocol version with the server and records it on c
+// (see NegotiatedProtocol). A server from before CmdHello existed is taken
+// to speak minor 0 of the version its replies carry, with unversioned legacy
+// replies accepted as sendCommand accepts them. A server on a different
+// major version is an error.
+func (c *Client) Hello() (Protocol, error) {
+	// Always on a connection of its own: a KeepAlive client negotiates
+	// before it opens the connection it holds.
+	resp, err := c.sendOnce(BuildCommand(CmdHello, CurrentProtocol.String()), clientPingTimeout)
+	if err != nil {
+		return Protocol{}, err
+	}
+	var p Protocol
+	switch {
+	case strings.HasPrefix(resp.Error, "unknown command"):
+		p = Protocol{Major: resp.Version}
+		if p.Major == 0 {
+			p.Major = ProtocolVersion
+		}
+	case resp.Error != "":
+		return Protocol{}, fmt.Errorf("negotiate protocol: %s", resp.Error)
+	default:
+		if p, err = ParseProtocol(resp.Response); err != nil {
+			return Protocol{}, fmt.Errorf("negotiate protocol: %w", err)
+		}
+	}
+	if p.Major != ProtocolVersion {
+		return Protocol{}, fmt.Errorf("server speaks control protocol %s, this client speaks %s; use matching bladerunner versions", p, CurrentProtocol)
+	}
+	c.protocol.Store(&p)
+	return p, nil
+}
+
+// NegotiatedProtocol returns the version agreed by the last successful Hello,
+// and false if c has not negotiated one.
+func (c *Client) NegotiatedProtocol() (Protocol, bool) {
+	p := c.protocol.Load()
+	if p == nil {
+		return Protocol{}, false
+	}
+	return *p, true
+}
+
+// requireMinor negotiates the protocol unless c already has, and fails with
+// ErrUnsupported when the server's minor predates minor, the one that added
+// command. Callers check it before sending command, so an older server is
+// detected from the version it speaks rather than from its error text.
+func (c *Client) requireMinor(command string, minor int) error {
+	p, ok := c.NegotiatedProtocol()
+	if !ok {
+		var err error
+		if p, err = c.Hello(); err != nil {
+			return err
+		}
+	}
+	if p.Minor < minor {
+		return fmt.Errorf("%s: %w (needs control protocol %d.%d, server speaks %s)", command, ErrUnsupported, ProtocolVersion, minor, p)
+	}
+	return nil
+}
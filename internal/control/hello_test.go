@@ -0,0 +1,131 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProtocol(t *testing.T) {
+	for in, want := range map[string]Protocol{
+		"1":    {Major: 1},
+		"1.0":  {Major: 1},
+		"2.13": {Major: 2, Minor: 13},
+	} {
+		got, err := ParseProtocol(in)
+		if err != nil || got != want {
+			t.Errorf("ParseProtocol(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "v1", "1.x", "-1", "1.-2"} {
+		if _, err := ParseProtocol(in); err == nil {
+			t.Errorf("ParseProtocol(%q) accepted", in)
+		}
+	}
+}
+
+func TestNegotiateProtocol(t *testing.T) {
+	got, err := negotiateProtocol(Protocol{Major: ProtocolVersion, Minor: ProtocolMinor + 5})
+	if err != nil || got != CurrentProtocol {
+		t.Errorf("newer minor: %v, %v; want %v", got, err, CurrentProtocol)
+	}
+	if _, err := negotiateProtocol(Protocol{Major: ProtocolVersion + 1}); err == nil || !strings.Contains(err.Error(), "control protocol") {
+		t.Errorf("other major: err = %v, want a protocol mismatch", err)
+	}
+}
+
+func TestClientHello(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-hello-")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	server, err := NewListenerWithConfig(ListenerConfig{StateDir: tmpDir})
+	if err != nil {
+		t.Fatalf("NewListenerWithConfig: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	client := NewClient(tmpDir)
+	if _, ok := client.NegotiatedProtocol(); ok {
+		t.Fatal("NegotiatedProtocol before Hello reported a version")
+	}
+	got, err := client.Hello()
+	if err != nil || got != CurrentProtocol {
+		t.Fatalf("Hello = %v, %v; want %v", got, err, CurrentProtocol)
+	}
+	if stored, ok := client.NegotiatedProtocol(); !ok || stored != got {
+		t.Errorf("NegotiatedProtocol = %v, %v; want %v", stored, ok, got)
+	}
+}
+
+func TestClientHelloReplies(t *testing.T) {
+	for _, tc := range []struct {
+		name, reply string
+		want        Protocol
+		wantErr     string
+	}{
+		{name: "negotiated", reply: "v1 1.0\n", want: Protocol{Major: 1}},
+		{name: "server predates hello", reply: "v1 error: unknown command: hello\n", want: Protocol{Major: 1}},
+		{name: "legacy server", reply: "error: unknown command: hello\n", want: Protocol{Major: ProtocolVersion}},
+		{name: "server refuses", reply: "v1 error: client speaks control protocol 1.0, server speaks 0.4; use matching bladerunner versions\n", wantErr: "server speaks 0.4"},
+		{name: "major mismatch", reply: "v1 2.0\n", wantErr: "server speaks control protocol 2.0"},
+		{name: "bad reply", reply: "v1 one\n", wantErr: "invalid protocol version"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &mockConn{readData: []byte(tc.reply)}
+			client := NewClientWithDialer("/tmp/test", &mockDialer{conn: conn})
+			got, err := client.Hello()
+			if want := "v1 hello " + CurrentProtocol.String() + "\n"; string(conn.writeData) != want {
+				t.Errorf("sent %q, want %q", conn.writeData, want)
+			}
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				if _, ok := client.NegotiatedProtocol(); ok {
+					t.Error("failed Hello recorded a version")
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("Hello = %v, %v; want %v", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestClientRequireMinor(t *testing.T) {
+	// A server from before hello negotiates minor 0, so commands added since
+	// are refused without being sent.
+	conn := &mockConn{readData: []byte("v1 error: unknown command: hello\n")}
+	client := NewClientWithDialer("/tmp/test", &mockDialer{conn: conn})
+	if _, err := client.GetConfigMany([]string{ConfigKeyCPUs}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("GetConfigMany err = %v, want ErrUnsupported", err)
+	}
+	if _, err := client.LogLevel(); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("LogLevel err = %v, want ErrUnsupported", err)
+	}
+	if want := "v1 hello " + CurrentProtocol.String() + "\n"; string(conn.writeData) != want {
+		t.Errorf("sent %q, want only the hello %q", conn.writeData, want)
+	}
+
+	// A server one minor behind has config.getmany but not log.level.
+	older := Protocol{Major: ProtocolVersion, Minor: MinorLogLevel - 1}
+	client = NewClientWithDialer("/tmp/test", &mockDialer{conn: &mockConn{}})
+	client.protocol.Store(&older)
+	if err := client.requireMinor(CmdConfigGetMany, MinorConfigGetMany); err != nil {
+		t.Errorf("requireMinor(getmany) at %s: %v", older, err)
+	}
+	if err := client.requireMinor(CmdLogLevel, MinorLogLevel); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("requireMinor(log.level) at %s: %v, want ErrUnsupported", older, err)
+	}
+}
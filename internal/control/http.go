@@ -0,0 +1,220 @@
+package control
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+// HTTPTokenName is the file under the state dir holding the bearer token the
+// HTTP control API requires.
+const HTTPTokenName = "http-control.token"
+
+const (
+	httpReadTimeout = 10 * time.Second
+	// httpMaxBody bounds a POST body; config values are short strings.
+	httpMaxBody = 64 << 10
+)
+
+// HTTPServer mirrors part of the control protocol as a small REST API, for
+// browser and curl clients that would rather not speak the line protocol:
+//
+//	GET  /status        the status.json payload (StatusInfo)
+//	POST /stop          graceful stop
+//	GET  /config/{key}  {"key":..,"value":..}
+//	POST /config/{key}  body {"value":..}; same rules as config.set
+//	GET  /metrics       command metrics in the Prometheus text format
+//
+// Every request goes through the same Router as the socket, so it counts as
+// activity for the idle timeout (a /metrics scrape does not), and must carry
+// "Authorization: Bearer <token>". The server only binds loopback addresses.
+type HTTPServer struct {
+	router *Router
+	token  string
+	ln     net.Listener
+	srv    *http.Server
+}
+
+// NewHTTPServer listens on addr, which must be a loopback host:port.
+func NewHTTPServer(addr string, router *Router, token string) (*HTTPServer, error) {
+	if err := config.CheckLoopbackAddr(addr); err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, errors.New("http control: empty token")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	s := &HTTPServer{router: router, token: token, ln: ln}
+	s.srv = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: httpReadTimeout}
+	return s, nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *HTTPServer) Addr() string { return s.ln.Addr().String() }
+
+// Start serves requests until Close (blocking).
+func (s *HTTPServer) Start() {
+	_ = s.srv.Serve(s.ln)
+}
+
+// Close stops the server.
+func (s *HTTPServer) Close() error {
+	return s.srv.Close()
+}
+
+// Handler returns the API's http.Handler, with authentication applied.
+func (s *HTTPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("POST /stop", s.handleStop)
+	mux.HandleFunc("GET /config/{key}", s.handleConfigGet)
+	mux.HandleFunc("POST /config/{key}", s.handleConfigSet)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	return s.authenticate(mux)
+}
+
+func (s *HTTPServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeHTTPError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// dispatch runs a control command line through the router, exactly as the
+// socket listener would.
+func (s *HTTPServer) dispatch(ctx context.Context, command string, args ...string) *Message {
+	return s.router.Dispatch(ctx, NewRequest(BuildCommand(command, args...)))
+}
+
+func (s *HTTPServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	resp := s.dispatch(r.Context(), CmdStatusJSON)
+	if resp.Error != "" {
+		writeHTTPError(w, http.StatusServiceUnavailable, resp.Error)
+		return
+	}
+	// The response is already a StatusInfo JSON document.
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = io.WriteString(w, resp.Response+"\n")
+}
+
+func (s *HTTPServer) handleStop(w http.ResponseWriter, r *http.Request) {
+	resp := s.dispatch(r.Context(), CmdStop)
+	if resp.Error != "" {
+		writeHTTPError(w, http.StatusInternalServerError, resp.Error)
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, map[string]string{"status": RespOK})
+}
+
+func (s *HTTPServer) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = s.router.Metrics().WritePrometheus(w)
+}
+
+// httpConfigValue is the body of GET and POST /config/{key}.
+type httpConfigValue struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+func (s *HTTPServer) handleConfigGet(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	resp := s.dispatch(r.Context(), CmdConfigGet, key)
+	if resp.Error != "" {
+		writeHTTPError(w, configErrorStatus(resp.Error), resp.Error)
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, httpConfigValue{Key: key, Value: resp.Response})
+}
+
+func (s *HTTPServer) handleConfigSet(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	var body httpConfigValue
+	if err := json.NewDecoder(io.LimitReader(r.Body, httpMaxBody)).Decode(&body); err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Sprintf("body must be JSON like {\"value\": \"...\"}: %v", err))
+		return
+	}
+	if body.Value == "" {
+		writeHTTPError(w, http.StatusBadRequest, "value must not be empty")
+		return
+	}
+	resp := s.dispatch(r.Context(), CmdConfigSet, key, body.Value)
+	if resp.Error != "" {
+		writeHTTPError(w, configErrorStatus(resp.Error), resp.Error)
+		return
+	}
+	writeHTTPJSON(w, http.StatusOK, httpConfigValue{Key: key, Value: body.Value})
+}
+
+// configErrorStatus maps a config handler error onto an HTTP status.
+func configErrorStatus(msg string) int {
+	switch {
+	case strings.HasPrefix(msg, "unknown config key"):
+		return http.StatusNotFound
+	case strings.Contains(msg, "read-only"), strings.HasPrefix(msg, "failed to set"):
+		return http.StatusBadRequest
+	case strings.Contains(msg, "not available"):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeHTTPJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeHTTPError(w http.ResponseWriter, code int, msg string) {
+	writeHTTPJSON(w, code, map[string]string{"error": msg})
+}
+
+// HTTPTokenPath returns where the HTTP control token for stateDir is kept.
+func HTTPTokenPath(stateDir string) string {
+	return filepath.Join(stateDir, HTTPTokenName)
+}
+
+// EnsureHTTPToken returns the HTTP control token for stateDir, generating it
+// (owner-only) on first use so it stays stable across restarts.
+func EnsureHTTPToken(stateDir string) (string, error) {
+	path := HTTPTokenPath(stateDir)
+	if b, err := os.ReadFile(path); err == nil {
+		if tok := strings.TrimSpace(string(b)); tok != "" {
+			return tok, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("read http control token: %w", err)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate http control token: %w", err)
+	}
+	tok := hex.EncodeToString(buf)
+	if err := os.WriteFile(path, []byte(tok+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("write http control token: %w", err)
+	}
+	return tok, nil
+}
@@ -0,0 +1,160 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testHTTPToken = "s3cret"
+
+func newTestHTTPServer(t *testing.T, stopped *bool) *httptest.Server {
+	t.Helper()
+	cfg := newTestConfig(t, t.TempDir())
+	router := NewRouter()
+	router.RegisterController(ControllerFunc{StopFn: func(context.Context) error { *stopped = true; return nil }})
+	router.Mount("config", NewConfigRouter(cfg).Router())
+	router.HandleFunc(CmdStatusJSON, func(context.Context, *Request) *Message {
+		b, _ := json.Marshal(StatusInfo{SchemaVersion: StatusSchemaVersion, State: StatusRunning})
+		return &Message{Response: string(b)}
+	})
+	s := &HTTPServer{router: router, token: testHTTPToken}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func doHTTP(t *testing.T, method, url, token, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestHTTPServerMetrics(t *testing.T) {
+	var stopped bool
+	ts := newTestHTTPServer(t, &stopped)
+
+	doHTTP(t, "GET", ts.URL+"/status", testHTTPToken, "")
+	if code, _ := doHTTP(t, "GET", ts.URL+"/metrics", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", code)
+	}
+	code, body := doHTTP(t, "GET", ts.URL+"/metrics", testHTTPToken, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET /metrics: status %d: %s", code, body)
+	}
+	if want := `bladerunner_control_commands_total{command="status.json"} 1`; !strings.Contains(body, want) {
+		t.Errorf("GET /metrics missing %q:\n%s", want, body)
+	}
+}
+
+func TestHTTPServerCountsAsActivity(t *testing.T) {
+	router := NewRouter()
+	router.HandleFunc(CmdStatusJSON, func(context.Context, *Request) *Message {
+		return &Message{Response: "{}"}
+	})
+	ts := httptest.NewServer((&HTTPServer{router: router, token: testHTTPToken}).Handler())
+	t.Cleanup(ts.Close)
+
+	// A Prometheus scrape is not use; it must not hold off an idle stop.
+	doHTTP(t, "GET", ts.URL+"/metrics", testHTTPToken, "")
+	if last := router.Metrics().LastObserved(); !last.IsZero() {
+		t.Fatalf("LastObserved after GET /metrics = %v, want zero", last)
+	}
+	doHTTP(t, "GET", ts.URL+"/status", testHTTPToken, "")
+	if router.Metrics().LastObserved().IsZero() {
+		t.Fatal("GET /status did not update LastObserved")
+	}
+}
+
+func TestHTTPServer(t *testing.T) {
+	var stopped bool
+	ts := newTestHTTPServer(t, &stopped)
+
+	if code, _ := doHTTP(t, "GET", ts.URL+"/status", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", code)
+	}
+	if code, _ := doHTTP(t, "GET", ts.URL+"/status", "wrong", ""); code != http.StatusUnauthorized {
+		t.Errorf("wrong token: status %d, want 401", code)
+	}
+
+	code, body := doHTTP(t, "GET", ts.URL+"/status", testHTTPToken, "")
+	var info StatusInfo
+	if code != http.StatusOK || json.Unmarshal([]byte(body), &info) != nil || info.State != StatusRunning {
+		t.Errorf("GET /status = %d %s", code, body)
+	}
+
+	code, body = doHTTP(t, "GET", ts.URL+"/config/"+ConfigKeySSHUser, testHTTPToken, "")
+	if code != http.StatusOK || !strings.Contains(body, `"value":"bladerunner"`) {
+		t.Errorf("GET /config/ssh-user = %d %s", code, body)
+	}
+	if code, _ := doHTTP(t, "GET", ts.URL+"/config/nope", testHTTPToken, ""); code != http.StatusNotFound {
+		t.Errorf("unknown key: status %d, want 404", code)
+	}
+
+	// Values with spaces and '=' survive the trip through the line protocol.
+	const url = "https://example.com/a b.raw?x=1"
+	code, body = doHTTP(t, "POST", ts.URL+"/config/"+ConfigKeyBaseImageURL, testHTTPToken, `{"value":"`+url+`"}`)
+	if code != http.StatusOK {
+		t.Fatalf("POST /config/base-image-url = %d %s", code, body)
+	}
+	if _, body = doHTTP(t, "GET", ts.URL+"/config/"+ConfigKeyBaseImageURL, testHTTPToken, ""); !strings.Contains(body, `"value":"`+url+`"`) {
+		t.Errorf("value after set = %s", body)
+	}
+	if code, _ := doHTTP(t, "POST", ts.URL+"/config/"+ConfigKeySSHUser, testHTTPToken, `{"value":"x"}`); code != http.StatusBadRequest {
+		t.Errorf("read-only key: status %d, want 400", code)
+	}
+	if code, _ := doHTTP(t, "POST", ts.URL+"/config/"+ConfigKeyCPUs, testHTTPToken, "4"); code != http.StatusBadRequest {
+		t.Errorf("non-JSON body: status %d, want 400", code)
+	}
+
+	if code, _ := doHTTP(t, "GET", ts.URL+"/stop", testHTTPToken, ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /stop: status %d, want 405", code)
+	}
+	if code, _ := doHTTP(t, "POST", ts.URL+"/stop", testHTTPToken, ""); code != http.StatusOK || !stopped {
+		t.Errorf("POST /stop: status %d, stopped %v", code, stopped)
+	}
+}
+
+func TestNewHTTPServerLoopbackOnly(t *testing.T) {
+	if _, err := NewHTTPServer("0.0.0.0:0", NewRouter(), "t"); err == nil {
+		t.Error("wildcard address should be rejected")
+	}
+	s, err := NewHTTPServer("127.0.0.1:0", NewRouter(), "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+}
+
+func TestEnsureHTTPToken(t *testing.T) {
+	dir := t.TempDir()
+	tok, err := EnsureHTTPToken(dir)
+	if err != nil || len(tok) != 64 {
+		t.Fatalf("EnsureHTTPToken = %q, %v", tok, err)
+	}
+	info, err := os.Stat(HTTPTokenPath(dir))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, %v", info.Mode(), err)
+	}
+	again, err := EnsureHTTPToken(dir)
+	if err != nil || again != tok {
+		t.Errorf("token not stable across calls: %q vs %q", again, tok)
+	}
+}
@@ -0,0 +1,41 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+)
+
+// CmdKeepAlive asks the server to keep the connection it arrives on open for
+// further commands; the response is RespOK. Without it the server closes a
+// connection after its first command, as it always has, so keep-alive is
+// opt-in per connection.
+const CmdKeepAlive = "keepalive"
+
+// RegisterKeepAlive registers CmdKeepAlive. The handler only acknowledges it;
+// handleConnection is what keeps the connection open.
+func (r *Router) RegisterKeepAlive() {
+	r.HandleFunc(CmdKeepAlive, func(context.Context, *Request) *Message {
+		return &Message{Response: RespOK}
+	})
+}
+
+// openKeepAlive switches a freshly dialed conn to keep-alive.
+func (c *Client) openKeepAlive(conn *heldConn) error {
+	resp, err := c.roundTrip(conn.Conn, conn.r, CmdKeepAlive, clientPingTimeout)
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("%s: %s", CmdKeepAlive, resp.Error)
+	}
+	return nil
+}
+
+// heldConn is a KeepAlive client's connection with the one reader every
+// response on it is decoded from.
+type heldConn struct {
+	net.Conn
+	r *bufio.Reader
+}
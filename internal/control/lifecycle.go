@@ -0,0 +1,64 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Lifecycle command constants. They are registered by RegisterController when
+// the Controller also implements Lifecycle.
+const (
+	// CmdPause freezes the guest's vCPUs in place; the VM keeps its memory and
+	// the host listeners stay bound. The response body is RespOK.
+	CmdPause = "pause"
+	// CmdResume continues a paused guest. The response body is RespOK.
+	CmdResume = "resume"
+	// CmdReboot asks the guest to reboot itself; the VM and the host process
+	// stay up. The response body is RespOK once the request is accepted.
+	CmdReboot = "reboot"
+)
+
+// Lifecycle is implemented by Controllers that can act on the VM itself, not
+// just stop the process serving it.
+type Lifecycle interface {
+	Pause(ctx context.Context) error
+	Resume(ctx context.Context) error
+	Reboot(ctx context.Context) error
+}
+
+// registerLifecycle registers the Lifecycle commands against lc.
+func (r *Router) registerLifecycle(lc Lifecycle) {
+	for cmd, fn := range map[string]func(context.Context) error{
+		CmdPause:  lc.Pause,
+		CmdResume: lc.Resume,
+		CmdReboot: lc.Reboot,
+	} {
+		r.HandleFunc(cmd, func(ctx context.Context, _ *Request) *Message {
+			if err := fn(ctx); err != nil {
+				return &Message{Error: err.Error()}
+			}
+			return &Message{Response: RespOK}
+		})
+	}
+}
+
+// Pause freezes the running instance's guest.
+func (c *Client) Pause() error { return c.lifecycleCommand(CmdPause, clientCmdTimeout) }
+
+// Resume continues the running instance's paused guest.
+func (c *Client) Resume() error { return c.lifecycleCommand(CmdResume, clientCmdTimeout) }
+
+// Reboot asks the running instance's guest to reboot.
+func (c *Client) Reboot() error { return c.lifecycleCommand(CmdReboot, rebootCommandTimeout) }
+
+func (c *Client) lifecycleCommand(cmd string, timeout time.Duration) error {
+	resp, err := c.sendCommand(cmd, timeout)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("%s error: %s", cmd, resp.Error)
+	}
+	return nil
+}
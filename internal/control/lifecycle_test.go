@@ -0,0 +1,76 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeLifecycle is a Controller that also implements Lifecycle, recording
+// which lifecycle commands reached it.
+type fakeLifecycle struct {
+	ControllerFunc
+	calls   []string
+	failing error
+}
+
+func (f *fakeLifecycle) call(name string) error {
+	f.calls = append(f.calls, name)
+	return f.failing
+}
+
+func (f *fakeLifecycle) Pause(context.Context) error  { return f.call(CmdPause) }
+func (f *fakeLifecycle) Resume(context.Context) error { return f.call(CmdResume) }
+func (f *fakeLifecycle) Reboot(context.Context) error { return f.call(CmdReboot) }
+
+// TestClientLifecycleCommands verifies pause/resume/reboot reach a Controller
+// that implements Lifecycle and that its errors come back to the client.
+func TestClientLifecycleCommands(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-lifecycle-")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	lc := &fakeLifecycle{}
+	server, err := NewListenerWithConfig(ListenerConfig{StateDir: tmpDir, Controller: lc})
+	if err != nil {
+		t.Fatalf("NewListenerWithConfig: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	client := NewClient(tmpDir)
+	for _, send := range []func() error{client.Pause, client.Resume, client.Reboot} {
+		if err := send(); err != nil {
+			t.Fatalf("lifecycle command: %v", err)
+		}
+	}
+	if got := strings.Join(lc.calls, " "); got != "pause resume reboot" {
+		t.Errorf("calls = %q, want %q", got, "pause resume reboot")
+	}
+
+	lc.failing = errors.New("vm is paused")
+	if err := client.Reboot(); err == nil || !strings.Contains(err.Error(), "vm is paused") {
+		t.Errorf("Reboot error = %v, want the controller's error", err)
+	}
+}
+
+// TestRegisterControllerWithoutLifecycle verifies a plain Controller doesn't
+// get the lifecycle commands.
+func TestRegisterControllerWithoutLifecycle(t *testing.T) {
+	r := NewRouter()
+	r.RegisterController(ControllerFunc{})
+	for _, cmd := range []string{CmdPause, CmdResume, CmdReboot} {
+		if resp := r.Dispatch(context.Background(), NewRequest(cmd)); resp.Error == "" {
+			t.Errorf("%s was handled without a Lifecycle controller", cmd)
+		}
+	}
+}
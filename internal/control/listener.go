@@ -1,14 +1,21 @@
 package control
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/stuffbucket/bladerunner/internal/config"
 	"github.com/stuffbucket/bladerunner/internal/logging"
 )
 
@@ -24,6 +31,17 @@ const (
 	// saveCommandTimeout bounds the server-side CmdSave handling (pause + write
 	// the full guest RAM image), which can run for many seconds.
 	saveCommandTimeout = 10 * time.Minute
+	// killCommandTimeout bounds CmdKill: a forced stop of the VM.
+	killCommandTimeout = 15 * time.Second
+	// rebootCommandTimeout bounds CmdReboot: an SSH round trip into the guest
+	// to queue the reboot, which can take a few seconds to connect.
+	rebootCommandTimeout = 20 * time.Second
+	// trustCommandTimeout bounds CmdTrustRotate: generating a key pair and an
+	// SSH round trip that updates the guest's Incus trust store.
+	trustCommandTimeout = 45 * time.Second
+	// forwardEditTimeout bounds CmdForwardAdd and CmdForwardRemove: an SSH
+	// round trip that installs or removes the forward's guest relay.
+	forwardEditTimeout = 45 * time.Second
 )
 
 // ListenerConfig holds configuration for a control listener.
@@ -32,6 +50,8 @@ type ListenerConfig struct {
 	Transport  Transport
 	WireFormat WireFormat
 	Controller Controller
+	// SocketPath overrides the socket location; see ResolveSocketPath.
+	SocketPath string
 }
 
 // Listener accepts control connections and dispatches commands.
@@ -63,13 +83,27 @@ func NewListenerWithConfig(cfg ListenerConfig) (*Listener, error) {
 		cfg.WireFormat = DefaultWireFormat
 	}
 
-	address := filepath.Join(cfg.StateDir, SocketName)
+	address, err := ResolveSocketPath(cfg.SocketPath, cfg.StateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	_, isUnix := cfg.Transport.(UnixTransport)
 
-	// Check if listener is already running
+	// Check if listener is already running. Something answering is only
+	// trusted when its recorded owner is still alive: a crashed run can
+	// leave the socket open in a process that will never serve it.
 	conn, err := cfg.Transport.Dial(address, SocketCheckTimeout)
 	if err == nil {
 		_ = conn.Close()
-		return nil, fmt.Errorf("listener already running on %s", address)
+		owner := 0
+		if isUnix {
+			owner = readOwnerPID(OwnerPIDPath(address))
+		}
+		if owner == 0 || processAlive(owner) {
+			return nil, fmt.Errorf("listener already running on %s", address)
+		}
+		logging.L().Warn("reclaiming control socket from dead owner", "socket", address, "pid", owner)
 	}
 
 	// Clean up stale socket
@@ -82,15 +116,23 @@ func NewListenerWithConfig(cfg ListenerConfig) (*Listener, error) {
 		return nil, fmt.Errorf("listen on %s: %w", address, err)
 	}
 
-	// Restrict permissions for Unix sockets
-	if _, ok := cfg.Transport.(UnixTransport); ok {
+	// Restrict permissions for Unix sockets and record their owner
+	if isUnix {
 		if err := os.Chmod(address, 0o600); err != nil {
 			_ = netListen.Close()
 			return nil, fmt.Errorf("chmod socket: %w", err)
 		}
+		if err := os.WriteFile(OwnerPIDPath(address), []byte(strconv.Itoa(os.Getpid())+"\n"), 0o600); err != nil {
+			_ = netListen.Close()
+			return nil, fmt.Errorf("write owner pid: %w", err)
+		}
 	}
 
 	router := NewRouter()
+	router.RegisterMetrics()
+	router.RegisterHello()
+	router.RegisterKeepAlive()
+	router.RegisterLogLevel()
 	if cfg.Controller != nil {
 		router.RegisterController(cfg.Controller)
 	}
@@ -134,15 +176,37 @@ func (l *Listener) Start(ctx context.Context) {
 	}
 }
 
+// handleConnection serves one command on conn, or, once the client has sent
+// CmdKeepAlive, commands until the client closes it, it sits idle for
+// listenerRWTimeout, or a reply cannot be sent.
 func (l *Listener) handleConnection(ctx context.Context, conn net.Conn) {
 	defer func() { _ = conn.Close() }()
-	_ = conn.SetDeadline(time.Now().Add(listenerRWTimeout))
-
-	msg, err := l.wireFormat.Decode(conn)
-	if err != nil {
-		return
+	// One reader for the life of the connection: a reader per message would
+	// drop whatever it buffered past the first line, such as a pipelined
+	// second command.
+	r := bufio.NewReader(conn)
+	keepAlive := false
+	for ctx.Err() == nil {
+		// Each message gets the full deadline, however long the
+		// connection has been open.
+		_ = conn.SetDeadline(time.Now().Add(listenerRWTimeout))
+		msg, err := l.wireFormat.Decode(r)
+		if err != nil {
+			return
+		}
+		if !l.handleMessage(ctx, conn, msg) {
+			return
+		}
+		keepAlive = keepAlive || NewRequest(msg.Command).Command == CmdKeepAlive
+		if !keepAlive {
+			return
+		}
 	}
+}
 
+// handleMessage dispatches one message and writes its reply, reporting
+// whether conn can carry another.
+func (l *Listener) handleMessage(ctx context.Context, conn net.Conn, msg *Message) bool {
 	// Reject unsupported future protocol versions
 	if msg.Version > ProtocolVersion {
 		resp := &Message{
@@ -150,19 +214,28 @@ func (l *Listener) handleConnection(ctx context.Context, conn net.Conn) {
 			Error:   fmt.Sprintf("unsupported protocol version %d (server supports up to %d)", msg.Version, ProtocolVersion),
 		}
 		_ = l.wireFormat.Encode(conn, resp)
-		return
+		return false
 	}
 
 	req := NewRequest(msg.Command)
 	// Saving a VM's RAM state (multi-GB write) and ejecting (a graceful ACPI
 	// shutdown that waits for the guest to power off) can both take many seconds;
 	// give them a much longer deadline than the default request timeout.
-	if req.Command == CmdSave || req.Command == CmdEject {
+	switch req.Command {
+	case CmdSave, CmdEject:
 		_ = conn.SetDeadline(time.Now().Add(saveCommandTimeout))
+	case CmdKill:
+		_ = conn.SetDeadline(time.Now().Add(killCommandTimeout))
+	case CmdReboot:
+		_ = conn.SetDeadline(time.Now().Add(rebootCommandTimeout))
+	case CmdTrustRotate:
+		_ = conn.SetDeadline(time.Now().Add(trustCommandTimeout))
+	case CmdForwardAdd, CmdForwardRemove:
+		_ = conn.SetDeadline(time.Now().Add(forwardEditTimeout))
 	}
 	resp := l.router.Dispatch(ctx, req)
 	resp.Version = ProtocolVersion
-	_ = l.wireFormat.Encode(conn, resp)
+	return l.wireFormat.Encode(conn, resp) == nil
 }
 
 // Close shuts down the control listener.
@@ -176,15 +249,60 @@ func (l *Listener) Close() error {
 	if err := l.transport.Cleanup(l.address); err != nil {
 		errs = append(errs, fmt.Errorf("cleanup: %w", err))
 	}
+	if _, ok := l.transport.(UnixTransport); ok {
+		// Leave the record alone if a later server has reclaimed the socket.
+		path := OwnerPIDPath(l.address)
+		if readOwnerPID(path) == os.Getpid() {
+			if err := removeIfExists(path); err != nil {
+				errs = append(errs, fmt.Errorf("cleanup owner pid: %w", err))
+			}
+		}
+	}
 	if len(errs) > 0 {
 		return errs[0]
 	}
 	return nil
 }
 
-// SocketPath returns the socket path for a state directory.
+// SocketPath returns the socket path for a state directory, honoring
+// config.ControlSocketEnvVar. See ResolveSocketPath.
 func SocketPath(stateDir string) string {
-	return filepath.Join(stateDir, SocketName)
+	p, _ := ResolveSocketPath("", stateDir)
+	return p
+}
+
+// ResolveSocketPath returns where the control socket for stateDir lives.
+// An explicit override wins, then config.ControlSocketOverride, then
+// <stateDir>/control.sock. When that default would be longer than a Unix
+// socket path may be (deep temp or home directories), it falls back to a
+// short path under $TMPDIR named by a hash of stateDir, so the server and
+// every client still derive the same path. The error reports a path that
+// cannot be bound; the path is returned regardless for display.
+func ResolveSocketPath(override, stateDir string) (string, error) {
+	if override == "" {
+		override = config.ControlSocketOverride(stateDir)
+	}
+	if override != "" {
+		if len(override) > config.MaxSocketPathLen {
+			return override, fmt.Errorf("control socket path is %d bytes; Unix sockets allow at most %d: %s", len(override), config.MaxSocketPathLen, override)
+		}
+		return override, nil
+	}
+
+	p := filepath.Join(stateDir, SocketName)
+	if len(p) <= config.MaxSocketPathLen {
+		return p, nil
+	}
+	abs, err := filepath.Abs(stateDir)
+	if err != nil {
+		abs = stateDir
+	}
+	sum := sha256.Sum256([]byte(abs))
+	short := filepath.Join(os.TempDir(), "bladerunner-"+hex.EncodeToString(sum[:8])+".sock")
+	if len(short) > config.MaxSocketPathLen {
+		return p, fmt.Errorf("control socket path %s is too long for a Unix socket (max %d bytes) and so is the $TMPDIR fallback; set %s to a shorter path", p, config.MaxSocketPathLen, config.ControlSocketEnvVar)
+	}
+	return short, nil
 }
 
 // --- Backward compatibility ---
@@ -211,6 +329,37 @@ func NewServerWithConfig(cfg ServerConfig) (*Listener, error) {
 
 // --- Utility functions ---
 
+// OwnerPIDPath returns where the PID of the server owning the socket at
+// address is recorded: beside it, with the extension swapped, so
+// <stateDir>/control.sock pairs with <stateDir>/control.pid.
+func OwnerPIDPath(address string) string {
+	return strings.TrimSuffix(address, filepath.Ext(address)) + ".pid"
+}
+
+// readOwnerPID returns the PID recorded at path, or 0 if there is none.
+func readOwnerPID(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	return pid
+}
+
+// processAlive reports whether pid names a running process. A process we
+// may not signal still exists.
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
 // removeIfExists removes a file if it exists.
 func removeIfExists(path string) error {
 	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
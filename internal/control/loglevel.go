@@ -0,0 +1,57 @@
+package control
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stuffbucket/bladerunner/internal/logging"
+)
+
+// CmdLogLevel reports the running instance's log level, or with positional
+// arg 0 (debug, info, warn or error) changes it until the next restart. The
+// response body is the level now in effect.
+const CmdLogLevel = "log.level"
+
+// RegisterLogLevel registers CmdLogLevel, which acts on this process's
+// logger.
+func (r *Router) RegisterLogLevel() {
+	r.HandleFunc(CmdLogLevel, func(_ context.Context, req *Request) *Message {
+		if name := req.Args["0"]; name != "" {
+			level, err := logging.ParseLevel(name)
+			if err != nil {
+				return &Message{Error: err.Error()}
+			}
+			prev := logging.Level()
+			logging.SetLevel(level)
+			if level != prev {
+				logging.L().Info("log level changed", "from", prev, "to", level)
+			}
+		}
+		return &Message{Response: logging.Level().String()}
+	})
+}
+
+// LogLevel returns the running instance's log level.
+func (c *Client) LogLevel() (string, error) {
+	return c.logLevel(CmdLogLevel)
+}
+
+// SetLogLevel changes the running instance's log level and returns the level
+// now in effect.
+func (c *Client) SetLogLevel(level string) (string, error) {
+	return c.logLevel(BuildCommand(CmdLogLevel, level))
+}
+
+func (c *Client) logLevel(cmd string) (string, error) {
+	if err := c.requireMinor(CmdLogLevel, MinorLogLevel); err != nil {
+		return "", err
+	}
+	resp, err := c.sendCommand(cmd, clientCmdTimeout)
+	if err != nil {
+		return "", fmt.Errorf("log level: %w", err)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("log level: %s", resp.Error)
+	}
+	return resp.Response, nil
+}
@@ -0,0 +1,49 @@
+package control
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/logging"
+)
+
+func TestClientLogLevel(t *testing.T) {
+	orig := logging.Level()
+	t.Cleanup(func() { logging.SetLevel(orig) })
+
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-loglevel-")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	server, err := NewListenerWithConfig(ListenerConfig{StateDir: tmpDir})
+	if err != nil {
+		t.Fatalf("NewListenerWithConfig: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	client := NewClient(tmpDir)
+	if got, err := client.LogLevel(); err != nil || got != orig.String() {
+		t.Errorf("LogLevel = %q, %v; want %q", got, err, orig)
+	}
+	if got, err := client.SetLogLevel("DEBUG"); err != nil || got != "debug" {
+		t.Errorf("SetLogLevel(DEBUG) = %q, %v; want debug", got, err)
+	}
+	if got := logging.Level().String(); got != "debug" {
+		t.Errorf("process level = %q after SetLogLevel(debug)", got)
+	}
+	if _, err := client.SetLogLevel("loud"); err == nil || !strings.Contains(err.Error(), "unknown log level") {
+		t.Errorf("SetLogLevel(loud): err = %v, want unknown log level", err)
+	}
+	if got := logging.Level().String(); got != "debug" {
+		t.Errorf("rejected level changed the process level to %q", got)
+	}
+}
@@ -0,0 +1,280 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CmdMetrics responds with a MetricsSnapshot JSON object: per-command call and
+// error counts and handler latencies since the server started.
+const CmdMetrics = "metrics"
+
+// unknownCommandLabel is the metrics key for commands no handler matched, so a
+// client sending garbage can't grow the table without bound.
+const unknownCommandLabel = "(unknown)"
+
+// latencyBuckets are the upper bounds of the handler latency histogram. A
+// control command is a lookup or a short VM call; anything past the last
+// bound lands in an implicit +Inf bucket.
+var latencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Metrics counts dispatched commands. Recording takes a read lock to find the
+// command's counters (a write lock only the first time a command is seen) and
+// then only atomic adds, so handlers never serialize on it.
+type Metrics struct {
+	started time.Time
+	// last is when the latest command was observed, in unix nanos; 0 before
+	// the first.
+	last atomic.Int64
+
+	mu       sync.RWMutex
+	commands map[string]*commandStats
+}
+
+type commandStats struct {
+	count   atomic.Uint64
+	errors  atomic.Uint64
+	sumNs   atomic.Uint64
+	maxNs   atomic.Uint64
+	buckets []atomic.Uint64 // len(latencyBuckets)+1; the last is +Inf
+}
+
+// NewMetrics returns an empty Metrics whose window starts now.
+func NewMetrics() *Metrics {
+	return &Metrics{started: time.Now(), commands: make(map[string]*commandStats)}
+}
+
+// Observe records one dispatch of command that took d and failed when failed
+// is set.
+func (m *Metrics) Observe(command string, d time.Duration, failed bool) {
+	m.last.Store(time.Now().UnixNano())
+	st := m.stats(command)
+	ns := uint64(max(d, 0))
+	st.count.Add(1)
+	if failed {
+		st.errors.Add(1)
+	}
+	st.sumNs.Add(ns)
+	for {
+		cur := st.maxNs.Load()
+		if ns <= cur || st.maxNs.CompareAndSwap(cur, ns) {
+			break
+		}
+	}
+	i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+	st.buckets[i].Add(1)
+}
+
+// LastObserved returns when the latest command was recorded, or the zero
+// time if none has been.
+func (m *Metrics) LastObserved() time.Time {
+	ns := m.last.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+func (m *Metrics) stats(command string) *commandStats {
+	m.mu.RLock()
+	st, ok := m.commands[command]
+	m.mu.RUnlock()
+	if ok {
+		return st
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if st, ok := m.commands[command]; ok {
+		return st
+	}
+	st = &commandStats{buckets: make([]atomic.Uint64, len(latencyBuckets)+1)}
+	m.commands[command] = st
+	return st
+}
+
+// MetricsSnapshot is the control server's command metrics as reported by
+// CmdMetrics.
+type MetricsSnapshot struct {
+	// Since is when the server started counting.
+	Since    time.Time        `json:"since"`
+	Commands []CommandMetrics `json:"commands"`
+}
+
+// CommandMetrics summarizes one command. Percentiles are read off the latency
+// histogram, so each is the upper bound of the bucket it falls in (or the
+// observed maximum, when that is smaller).
+type CommandMetrics struct {
+	Command string  `json:"command"`
+	Count   uint64  `json:"count"`
+	Errors  uint64  `json:"errors"`
+	MeanMs  float64 `json:"mean_ms"`
+	P50Ms   float64 `json:"p50_ms"`
+	P90Ms   float64 `json:"p90_ms"`
+	P99Ms   float64 `json:"p99_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
+
+// Snapshot returns the current counts, busiest command first.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	snap := MetricsSnapshot{Since: m.started, Commands: []CommandMetrics{}}
+	m.each(func(name string, st *commandStats, buckets []uint64) {
+		count := st.count.Load()
+		if count == 0 {
+			return
+		}
+		maxNs := st.maxNs.Load()
+		snap.Commands = append(snap.Commands, CommandMetrics{
+			Command: name,
+			Count:   count,
+			Errors:  st.errors.Load(),
+			MeanMs:  nsToMs(st.sumNs.Load() / count),
+			P50Ms:   nsToMs(percentile(buckets, 0.50, maxNs)),
+			P90Ms:   nsToMs(percentile(buckets, 0.90, maxNs)),
+			P99Ms:   nsToMs(percentile(buckets, 0.99, maxNs)),
+			MaxMs:   nsToMs(maxNs),
+		})
+	})
+	sort.Slice(snap.Commands, func(i, j int) bool {
+		a, b := snap.Commands[i], snap.Commands[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Command < b.Command
+	})
+	return snap
+}
+
+// each calls fn for every command in name order with a copy of its bucket
+// counts.
+func (m *Metrics) each(fn func(name string, st *commandStats, buckets []uint64)) {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.commands))
+	for name := range m.commands {
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		st := m.stats(name)
+		buckets := make([]uint64, len(st.buckets))
+		for i := range st.buckets {
+			buckets[i] = st.buckets[i].Load()
+		}
+		fn(name, st, buckets)
+	}
+}
+
+// percentile returns the upper bound, in nanoseconds, of the bucket holding
+// the q-th observation, capped at the observed maximum.
+func percentile(buckets []uint64, q float64, maxNs uint64) uint64 {
+	var total uint64
+	for _, n := range buckets {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, n := range buckets {
+		seen += n
+		if seen >= rank {
+			if i < len(latencyBuckets) {
+				return min(uint64(latencyBuckets[i]), maxNs)
+			}
+			break
+		}
+	}
+	return maxNs
+}
+
+func nsToMs(ns uint64) float64 {
+	return math.Round(float64(ns)/1e3) / 1e3
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	type row struct {
+		name    string
+		st      *commandStats
+		buckets []uint64
+	}
+	var rows []row
+	m.each(func(name string, st *commandStats, buckets []uint64) {
+		rows = append(rows, row{name, st, buckets})
+	})
+
+	printf("# HELP bladerunner_control_commands_total Control commands dispatched.\n")
+	printf("# TYPE bladerunner_control_commands_total counter\n")
+	for _, r := range rows {
+		printf("bladerunner_control_commands_total{command=%q} %d\n", r.name, r.st.count.Load())
+	}
+	printf("# HELP bladerunner_control_command_errors_total Control commands that returned an error.\n")
+	printf("# TYPE bladerunner_control_command_errors_total counter\n")
+	for _, r := range rows {
+		printf("bladerunner_control_command_errors_total{command=%q} %d\n", r.name, r.st.errors.Load())
+	}
+	printf("# HELP bladerunner_control_command_duration_seconds Control command handler latency.\n")
+	printf("# TYPE bladerunner_control_command_duration_seconds histogram\n")
+	for _, r := range rows {
+		var cum uint64
+		for i, n := range r.buckets {
+			cum += n
+			le := "+Inf"
+			if i < len(latencyBuckets) {
+				le = strconv.FormatFloat(latencyBuckets[i].Seconds(), 'g', -1, 64)
+			}
+			printf("bladerunner_control_command_duration_seconds_bucket{command=%q,le=%q} %d\n", r.name, le, cum)
+		}
+		printf("bladerunner_control_command_duration_seconds_sum{command=%q} %g\n", r.name, time.Duration(r.st.sumNs.Load()).Seconds())
+		printf("bladerunner_control_command_duration_seconds_count{command=%q} %d\n", r.name, cum)
+	}
+	return err
+}
+
+// Metrics fetches the running instance's control command metrics.
+func (c *Client) Metrics() (*MetricsSnapshot, error) {
+	resp, err := c.sendCommand(CmdMetrics, clientCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("get metrics: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("metrics error: %s", resp.Error)
+	}
+	var snap MetricsSnapshot
+	if err := json.Unmarshal([]byte(resp.Response), &snap); err != nil {
+		return nil, fmt.Errorf("decode metrics: %w", err)
+	}
+	return &snap, nil
+}
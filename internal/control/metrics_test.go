@@ -0,0 +1,113 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsSnapshotPercentiles(t *testing.T) {
+	m := NewMetrics()
+	// 90 fast calls and 10 slow ones: p50/p90 sit in the fast bucket, p99 in
+	// the slow one, capped at the observed maximum.
+	for i := 0; i < 90; i++ {
+		m.Observe("status", 200*time.Microsecond, false)
+	}
+	for i := 0; i < 10; i++ {
+		m.Observe("status", 40*time.Millisecond, i == 0)
+	}
+	m.Observe("ping", time.Millisecond, false)
+
+	snap := m.Snapshot()
+	if len(snap.Commands) != 2 || snap.Commands[0].Command != "status" {
+		t.Fatalf("commands = %+v, want status first", snap.Commands)
+	}
+	st := snap.Commands[0]
+	if st.Count != 100 || st.Errors != 1 {
+		t.Errorf("count/errors = %d/%d, want 100/1", st.Count, st.Errors)
+	}
+	if st.P50Ms != 0.25 || st.P90Ms != 0.25 {
+		t.Errorf("p50/p90 = %v/%v, want the 0.25ms bucket", st.P50Ms, st.P90Ms)
+	}
+	if st.P99Ms != 40 || st.MaxMs != 40 {
+		t.Errorf("p99/max = %v/%v, want 40/40", st.P99Ms, st.MaxMs)
+	}
+}
+
+func TestRouterRecordsMetrics(t *testing.T) {
+	r := NewRouter()
+	r.RegisterMetrics()
+	r.HandleFunc("ok", func(context.Context, *Request) *Message { return &Message{Response: RespOK} })
+	sub := NewRouter()
+	sub.HandleFunc("get", func(context.Context, *Request) *Message { return &Message{Error: "boom"} })
+	r.Mount("config", sub)
+
+	ctx := context.Background()
+	r.Dispatch(ctx, NewRequest("ok"))
+	r.Dispatch(ctx, NewRequest("ok"))
+	r.Dispatch(ctx, NewRequest("config.get x"))
+	r.Dispatch(ctx, NewRequest("nope"))
+	r.Dispatch(ctx, NewRequest("config.nope"))
+
+	resp := r.Dispatch(ctx, NewRequest(CmdMetrics))
+	var snap MetricsSnapshot
+	if err := json.Unmarshal([]byte(resp.Response), &snap); err != nil {
+		t.Fatalf("decode %q: %v", resp.Response, err)
+	}
+	got := map[string][2]uint64{}
+	for _, c := range snap.Commands {
+		got[c.Command] = [2]uint64{c.Count, c.Errors}
+	}
+	want := map[string][2]uint64{
+		"ok":                {2, 0},
+		"config.get":        {1, 1},
+		unknownCommandLabel: {2, 2},
+	}
+	for cmd, w := range want {
+		if got[cmd] != w {
+			t.Errorf("%s count/errors = %v, want %v", cmd, got[cmd], w)
+		}
+	}
+	if len(sub.Metrics().Snapshot().Commands) != 0 {
+		t.Error("a mounted sub-router counted a call the parent already counted")
+	}
+}
+
+func TestMetricsLastObserved(t *testing.T) {
+	m := NewMetrics()
+	if got := m.LastObserved(); !got.IsZero() {
+		t.Fatalf("LastObserved before any command = %v, want zero", got)
+	}
+	before := time.Now()
+	m.Observe("ping", time.Millisecond, false)
+	if got := m.LastObserved(); got.Before(before) || got.After(time.Now()) {
+		t.Errorf("LastObserved = %v, want between %v and now", got, before)
+	}
+}
+
+func TestMetricsWritePrometheus(t *testing.T) {
+	m := NewMetrics()
+	m.Observe("config.get", 3*time.Millisecond, false)
+	m.Observe("config.get", 3*time.Millisecond, true)
+
+	var b strings.Builder
+	if err := m.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE bladerunner_control_commands_total counter",
+		`bladerunner_control_commands_total{command="config.get"} 2`,
+		`bladerunner_control_command_errors_total{command="config.get"} 1`,
+		`bladerunner_control_command_duration_seconds_bucket{command="config.get",le="0.0025"} 0`,
+		`bladerunner_control_command_duration_seconds_bucket{command="config.get",le="0.005"} 2`,
+		`bladerunner_control_command_duration_seconds_bucket{command="config.get",le="+Inf"} 2`,
+		`bladerunner_control_command_duration_seconds_count{command="config.get"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n---\n%s", want, out)
+		}
+	}
+}
@@ -2,14 +2,17 @@ package control
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // Router dispatches command requests to registered handlers.
 type Router struct {
 	handlers map[string]Handler
 	prefix   map[string]*Router // nested routers for namespaced commands
+	metrics  *Metrics
 }
 
 // NewRouter creates an empty router.
@@ -17,9 +20,17 @@ func NewRouter() *Router {
 	return &Router{
 		handlers: make(map[string]Handler),
 		prefix:   make(map[string]*Router),
+		metrics:  NewMetrics(),
 	}
 }
 
+// Metrics returns the counts of commands dispatched through this router.
+// Commands routed to a mounted sub-router are counted here, under their full
+// name, not in the sub-router.
+func (r *Router) Metrics() *Metrics {
+	return r.metrics
+}
+
 // Handle registers a handler for a command.
 func (r *Router) Handle(command string, h Handler) {
 	r.handlers[command] = h
@@ -36,11 +47,25 @@ func (r *Router) Mount(prefix string, sub *Router) {
 	r.prefix[prefix] = sub
 }
 
-// Dispatch routes a request to the appropriate handler.
+// Dispatch routes a request to the appropriate handler, recording the call in
+// the router's metrics.
 func (r *Router) Dispatch(ctx context.Context, req *Request) *Message {
+	start := time.Now()
+	resp, known := r.dispatch(ctx, req)
+	label := req.Command
+	if !known {
+		label = unknownCommandLabel
+	}
+	r.metrics.Observe(label, time.Since(start), resp.Error != "")
+	return resp
+}
+
+// dispatch routes req without recording it; known reports whether a handler
+// matched.
+func (r *Router) dispatch(ctx context.Context, req *Request) (resp *Message, known bool) {
 	// Check for exact command match
 	if h, ok := r.handlers[req.Command]; ok {
-		return h.Handle(ctx, req)
+		return h.Handle(ctx, req), true
 	}
 
 	// Check for namespaced command (e.g., "config.get" -> prefix "config", cmd "get")
@@ -52,11 +77,11 @@ func (r *Router) Dispatch(ctx context.Context, req *Request) *Message {
 				Args:    req.Args,
 				Raw:     req.Raw,
 			}
-			return sub.Dispatch(ctx, subReq)
+			return sub.dispatch(ctx, subReq)
 		}
 	}
 
-	return &Message{Error: fmt.Sprintf("unknown command: %s", req.Command)}
+	return &Message{Error: fmt.Sprintf("unknown command: %s", req.Command)}, false
 }
 
 // Commands returns all registered command names including mounted prefixes.
@@ -73,8 +98,24 @@ func (r *Router) Commands() []string {
 	return cmds
 }
 
-// RegisterController registers standard commands that delegate to a Controller.
+// RegisterMetrics registers CmdMetrics, which reports this router's metrics.
+func (r *Router) RegisterMetrics() {
+	r.HandleFunc(CmdMetrics, func(context.Context, *Request) *Message {
+		b, err := json.Marshal(r.metrics.Snapshot())
+		if err != nil {
+			return &Message{Error: err.Error()}
+		}
+		return &Message{Response: string(b)}
+	})
+}
+
+// RegisterController registers standard commands that delegate to a Controller,
+// plus the lifecycle commands when ctrl also implements Lifecycle.
 func (r *Router) RegisterController(ctrl Controller) {
+	if lc, ok := ctrl.(Lifecycle); ok {
+		r.registerLifecycle(lc)
+	}
+
 	r.HandleFunc(CmdPing, func(ctx context.Context, _ *Request) *Message {
 		if err := ctrl.Ping(ctx); err != nil {
 			return &Message{Error: err.Error()}
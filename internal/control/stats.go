@@ -0,0 +1,48 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CmdStats responds with a Stats JSON object: live figures for the running VM
+// that static config doesn't capture.
+const CmdStats = "stats"
+
+// Stats is the running VM's live state as reported by CmdStats.
+type Stats struct {
+	// StartedAt is when the VM reached the running state; nil while booting.
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	UptimeSeconds int64      `json:"uptime_seconds"`
+	// MemoryTargetGiB is the memory balloon's current target.
+	MemoryTargetGiB uint64         `json:"memory_target_gib"`
+	Forwards        []ForwardStats `json:"forwards"`
+}
+
+// ForwardStats is the traffic one host-to-guest forwarder has proxied since
+// it started, and its connections.
+type ForwardStats struct {
+	Name           string `json:"name"`
+	BytesToGuest   uint64 `json:"bytes_to_guest"`
+	BytesFromGuest uint64 `json:"bytes_from_guest"`
+	ActiveConns    int64  `json:"active_conns"`
+	TotalConns     uint64 `json:"total_conns"`
+}
+
+// GetStats fetches the running instance's Stats. It fails with an "unknown
+// command" error against servers that predate CmdStats.
+func (c *Client) GetStats() (*Stats, error) {
+	resp, err := c.sendCommand(CmdStats, clientCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("get stats: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("stats error: %s", resp.Error)
+	}
+	var stats Stats
+	if err := json.Unmarshal([]byte(resp.Response), &stats); err != nil {
+		return nil, fmt.Errorf("decode stats: %w", err)
+	}
+	return &stats, nil
+}
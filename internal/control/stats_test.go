@@ -0,0 +1,64 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientGetStats(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-stats-")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	server, err := NewListenerWithConfig(ListenerConfig{
+		StateDir:   tmpDir,
+		Controller: ControllerFunc{},
+	})
+	if err != nil {
+		t.Fatalf("NewListenerWithConfig: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	client := NewClient(tmpDir)
+	if _, err := client.GetStats(); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Fatalf("GetStats without handler: err = %v, want unknown command", err)
+	}
+
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	want := Stats{
+		StartedAt:       &started,
+		UptimeSeconds:   720,
+		MemoryTargetGiB: 6,
+		Forwards: []ForwardStats{
+			{Name: "ssh", BytesToGuest: 1024, BytesFromGuest: 4096},
+			{Name: "incus-api"},
+		},
+	}
+	server.Router().HandleFunc(CmdStats, func(_ context.Context, _ *Request) *Message {
+		b, _ := json.Marshal(want)
+		return &Message{Response: string(b)}
+	})
+
+	got, err := client.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if got.StartedAt == nil || !got.StartedAt.Equal(started) {
+		t.Errorf("StartedAt = %v, want %v", got.StartedAt, started)
+	}
+	if got.UptimeSeconds != 720 || got.MemoryTargetGiB != 6 || !reflect.DeepEqual(got.Forwards, want.Forwards) {
+		t.Errorf("GetStats = %+v, want %+v", got, want)
+	}
+}
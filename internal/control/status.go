@@ -0,0 +1,71 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CmdStatusJSON responds with a StatusInfo JSON object: the full machine-
+// readable status in one round trip, rather than one config.get per field.
+const CmdStatusJSON = "status.json"
+
+// CmdUptime responds with the time the VM reached the running state, in RFC
+// 3339 form with nanoseconds, or an error while it is still booting. The
+// server holds the timestamp, so any client can compute a live uptime.
+const CmdUptime = "uptime"
+
+// StatusSchemaVersion is the StatusInfo schema version. Bump it when a field
+// is removed or changes meaning; adding fields does not require a bump.
+const StatusSchemaVersion = 1
+
+// StatusInfo is the canonical status of a running instance as reported by
+// CmdStatusJSON.
+type StatusInfo struct {
+	SchemaVersion int `json:"schema_version"`
+	// State is one of the Status* constants.
+	State string `json:"state"`
+	PID   int    `json:"pid"`
+	// StartedAt is when the VM reached the running state; nil while booting.
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	UptimeSeconds int64      `json:"uptime_seconds"`
+	CPUs          uint       `json:"cpus"`
+	// MemoryGiB is the effective memory, i.e. the balloon target.
+	MemoryGiB  uint64        `json:"memory_gib"`
+	IncusReady bool          `json:"incus_ready"`
+	Forwards   []ForwardInfo `json:"forwards"`
+}
+
+// GetStatusInfo fetches the full StatusInfo from the running instance. It
+// fails with an "unknown command" error against servers that predate
+// CmdStatusJSON; callers fall back to GetStatus and GetConfig.
+func (c *Client) GetStatusInfo() (*StatusInfo, error) {
+	resp, err := c.sendCommand(CmdStatusJSON, clientCmdTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("get status info: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("status error: %s", resp.Error)
+	}
+	var info StatusInfo
+	if err := json.Unmarshal([]byte(resp.Response), &info); err != nil {
+		return nil, fmt.Errorf("decode status info: %w", err)
+	}
+	return &info, nil
+}
+
+// StartedAt returns when the running instance's VM reached the running state.
+func (c *Client) StartedAt() (time.Time, error) {
+	resp, err := c.sendCommand(CmdUptime, clientCmdTimeout)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get uptime: %w", err)
+	}
+	if resp.Error != "" {
+		return time.Time{}, fmt.Errorf("uptime error: %s", resp.Error)
+	}
+	started, err := time.Parse(time.RFC3339Nano, resp.Response)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decode uptime: %w", err)
+	}
+	return started, nil
+}
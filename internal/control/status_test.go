@@ -0,0 +1,107 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestClientGetStatusInfo verifies status.json is dispatched as an exact
+// command (not a "status" namespace) and decodes into StatusInfo, and that a
+// server without the handler yields an error the CLI can fall back on.
+func TestClientGetStatusInfo(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-status-")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	server, err := NewListenerWithConfig(ListenerConfig{
+		StateDir:   tmpDir,
+		Controller: ControllerFunc{},
+	})
+	if err != nil {
+		t.Fatalf("NewListenerWithConfig: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	client := NewClient(tmpDir)
+	if _, err := client.GetStatusInfo(); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Fatalf("GetStatusInfo without handler: err = %v, want unknown command", err)
+	}
+
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	want := StatusInfo{
+		SchemaVersion: StatusSchemaVersion,
+		State:         StatusRunning,
+		PID:           4242,
+		StartedAt:     &started,
+		UptimeSeconds: 90,
+		CPUs:          4,
+		MemoryGiB:     6,
+		IncusReady:    true,
+		Forwards:      []ForwardInfo{{Name: "ssh", Listen: "127.0.0.1:6022"}},
+	}
+	server.Router().HandleFunc(CmdStatusJSON, func(_ context.Context, _ *Request) *Message {
+		b, _ := json.Marshal(want)
+		return &Message{Response: string(b)}
+	})
+
+	got, err := client.GetStatusInfo()
+	if err != nil {
+		t.Fatalf("GetStatusInfo: %v", err)
+	}
+	if got.SchemaVersion != StatusSchemaVersion || got.State != StatusRunning || got.PID != 4242 ||
+		got.UptimeSeconds != 90 || got.CPUs != 4 || got.MemoryGiB != 6 || !got.IncusReady {
+		t.Errorf("GetStatusInfo = %+v, want %+v", got, want)
+	}
+	if got.StartedAt == nil || !got.StartedAt.Equal(started) {
+		t.Errorf("StartedAt = %v, want %v", got.StartedAt, started)
+	}
+	if len(got.Forwards) != 1 || got.Forwards[0].Name != "ssh" {
+		t.Errorf("Forwards = %+v", got.Forwards)
+	}
+}
+
+func TestClientStartedAt(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("/tmp", "ctrl-uptime-")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	server, err := NewListenerWithConfig(ListenerConfig{
+		StateDir:   tmpDir,
+		Controller: ControllerFunc{},
+	})
+	if err != nil {
+		t.Fatalf("NewListenerWithConfig: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+
+	started := time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	server.Router().HandleFunc(CmdUptime, func(_ context.Context, _ *Request) *Message {
+		return &Message{Response: started.Format(time.RFC3339Nano)}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	got, err := NewClient(tmpDir).StartedAt()
+	if err != nil {
+		t.Fatalf("StartedAt: %v", err)
+	}
+	if !got.Equal(started) {
+		t.Errorf("StartedAt = %v, want %v", got, started)
+	}
+}
@@ -0,0 +1,22 @@
+package control
+
+import "fmt"
+
+// CmdTrustRotate replaces the running instance's Incus client certificate: a
+// new key pair is trusted in the guest, the old one is dropped, and the new
+// pair is saved over the client cert and key files. The response body is
+// RespOK.
+const CmdTrustRotate = "trust.rotate"
+
+// RotateTrust asks the running instance to rotate its Incus client
+// certificate.
+func (c *Client) RotateTrust() error {
+	resp, err := c.sendCommand(CmdTrustRotate, trustCommandTimeout)
+	if err != nil {
+		return fmt.Errorf("rotate client certificate: %w", err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("rotate client certificate: %s", resp.Error)
+	}
+	return nil
+}
@@ -160,7 +160,7 @@ func TestApplyToPrecedence(t *testing.T) {
 
 func mustDefault(t *testing.T) *config.Config {
 	t.Helper()
-	cfg, err := config.Default(t.TempDir())
+	cfg, err := config.Default(t.TempDir(), "")
 	if err != nil {
 		t.Fatalf("config.Default: %v", err)
 	}
@@ -2,11 +2,15 @@ package incus
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"time"
 
-	incusclient "github.com/lxc/incus/v6/client"
 	"github.com/lxc/incus/v6/shared/api"
 	sharedtls "github.com/lxc/incus/v6/shared/tls"
 	"github.com/stuffbucket/bladerunner/internal/logging"
@@ -25,6 +29,8 @@ type WaitProgress struct {
 	Attempt   int
 	Elapsed   time.Duration
 	LastError error
+	// Kind classifies LastError; empty when it fits no known class.
+	Kind ErrorKind
 }
 
 type WaitProgressCallback func(WaitProgress)
@@ -47,13 +53,88 @@ func EnsureClientCertificate(certPath, keyPath string) ([]byte, []byte, error) {
 	return certPEM, keyPEM, nil
 }
 
-func WaitForServer(ctx context.Context, endpoint string, certPEM, keyPEM []byte, retryEvery time.Duration, cb WaitProgressCallback) (*ServerInfo, error) {
-	ticker := time.NewTicker(retryEvery)
-	defer ticker.Stop()
+// GenerateClientCertificate creates a new client key pair in memory, for
+// rotating the one EnsureClientCertificate loaded. Nothing is written until
+// SaveClientCertificate.
+func GenerateClientCertificate() ([]byte, []byte, error) {
+	certPEM, keyPEM, err := sharedtls.GenerateMemCert(true, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate client cert: %w", err)
+	}
+	return certPEM, keyPEM, nil
+}
+
+// RotateClientCertificate replaces the client key pair at certPath and
+// keyPath with a fresh one and returns it. It only touches the files; a
+// running guest must be told to trust the new certificate separately.
+func RotateClientCertificate(certPath, keyPath string) ([]byte, []byte, error) {
+	certPEM, keyPEM, err := GenerateClientCertificate()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := SaveClientCertificate(certPath, keyPath, certPEM, keyPEM); err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+// CertFingerprint returns the SHA-256 fingerprint Incus identifies a trusted
+// certificate by: the lowercase hex digest of its DER bytes.
+func CertFingerprint(certPEM []byte) (string, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", errors.New("client certificate is not a PEM certificate")
+	}
+	sum := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SaveClientCertificate replaces the client key pair at certPath and keyPath.
+// Each file is renamed into place, so a reader sees the old or the new file,
+// never a partial one.
+func SaveClientCertificate(certPath, keyPath string, certPEM, keyPEM []byte) error {
+	if err := replaceFile(keyPath, keyPEM, 0o600); err != nil {
+		return fmt.Errorf("write client key: %w", err)
+	}
+	if err := replaceFile(certPath, certPEM, 0o644); err != nil {
+		return fmt.Errorf("write client cert: %w", err)
+	}
+	return nil
+}
+
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Readiness retry backoff: the first retry comes quickly, since the API is
+// often moments away, then the delay doubles up to a cap so a slow first boot
+// is not polled hard.
+const (
+	waitInitialDelay = 500 * time.Millisecond
+	waitMaxDelay     = 8 * time.Second
+	// waitJitter is the fraction of each delay randomized either way.
+	waitJitter = 0.1
+)
+
+// WaitForServer polls endpoint until the Incus API answers and trusts this
+// client, backing off exponentially between attempts. cb, when set, sees every
+// failed attempt. Errors from a server that is simply not listening yet
+// (refused, reset, EOF) are logged at debug level; anything else, such as a
+// TLS or authorization failure, is logged as soon as it appears.
+func WaitForServer(ctx context.Context, endpoint string, certPEM, keyPEM []byte, cb WaitProgressCallback) (*ServerInfo, error) {
 	start := time.Now()
 	attempt := 0
+	lastWarned := ""
 
-	logging.L().Info("waiting for Incus API readiness", "endpoint", endpoint, "retry_every", retryEvery.String())
+	logging.L().Info("waiting for Incus API readiness", "endpoint", endpoint, "initial_delay", waitInitialDelay.String(), "max_delay", waitMaxDelay.String())
 
 	for {
 		attempt++
@@ -67,37 +148,73 @@ func WaitForServer(ctx context.Context, endpoint string, certPEM, keyPEM []byte,
 				Attempt:   attempt,
 				Elapsed:   time.Since(start),
 				LastError: err,
+				Kind:      ClassifyError(err),
 			})
 		}
 
-		if attempt == 1 || attempt%5 == 0 {
-			logging.L().Warn("Incus API not ready yet", "attempt", attempt, "elapsed", time.Since(start).Round(time.Second).String(), "err", err)
+		elapsed := time.Since(start).Round(time.Second).String()
+		switch {
+		case ClassifyError(err) == ErrorKindConnectionRefused:
+			logging.L().Debug("Incus API not listening yet", "attempt", attempt, "elapsed", elapsed, "err", err)
+		case err.Error() != lastWarned:
+			lastWarned = err.Error()
+			logging.L().Warn("Incus API not ready yet", "attempt", attempt, "elapsed", elapsed, "err", err)
 		}
 
+		timer := time.NewTimer(jittered(backoffDelay(attempt), rand.Float64()))
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			waitErr := fmt.Errorf("wait for incus server: %w", ctx.Err())
 			logging.L().Error("Incus API readiness timed out", "endpoint", endpoint, "attempts", attempt, "elapsed", time.Since(start).Round(time.Second).String(), "err", waitErr)
-			return nil, fmt.Errorf("wait for incus server: %w", ctx.Err())
-		case <-ticker.C:
+			return nil, waitErr
+		case <-timer.C:
 		}
 	}
 }
 
+// backoffDelay is the wait after the given failed attempt (1-based):
+// waitInitialDelay doubled per attempt, capped at waitMaxDelay.
+func backoffDelay(attempt int) time.Duration {
+	d := waitInitialDelay
+	for i := 1; i < attempt && d < waitMaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, waitMaxDelay)
+}
+
+// jittered spreads d by up to waitJitter either way; r is a uniform sample
+// in [0, 1).
+func jittered(d time.Duration, r float64) time.Duration {
+	return time.Duration(float64(d) * (1 + waitJitter*(2*r-1)))
+}
+
+// ProbeServer makes a single readiness check against endpoint: it returns the
+// server info when the API answers and trusts this client, and the reason
+// otherwise. Unlike WaitForServer it does not retry.
+func ProbeServer(endpoint string, certPEM, keyPEM []byte) (*ServerInfo, error) {
+	return connectAndGet(endpoint, certPEM, keyPEM)
+}
+
+// connectAndGet fetches the server info over the pooled connection for
+// endpoint, so the readiness loop reuses one session across attempts instead
+// of re-dialing every tick. Any failure evicts the connection so the next
+// attempt starts from a fresh dial.
 func connectAndGet(endpoint string, certPEM, keyPEM []byte) (*ServerInfo, error) {
-	client, err := incusclient.ConnectIncus(endpoint, &incusclient.ConnectionArgs{
-		TLSClientCert:      string(certPEM),
-		TLSClientKey:       string(keyPEM),
-		InsecureSkipVerify: true,
-		SkipGetEvents:      true,
-	})
+	cfg := ClientConfig{Endpoint: endpoint, CertPEM: certPEM, KeyPEM: keyPEM}
+	client, server, err := defaultPool.get(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	server, _, err := client.GetServer()
-	if err != nil {
-		return nil, err
+	// A health check in get already fetched the server info; only a cached or
+	// freshly dialed client needs asking.
+	if server == nil {
+		server, _, err = client.server.GetServer()
+		if err != nil {
+			defaultPool.Evict(cfg)
+			return nil, err
+		}
 	}
 
 	if err := checkAuthorized(server); err != nil {
@@ -123,7 +240,7 @@ func checkAuthorized(server *api.Server) error {
 		return fmt.Errorf("incus server response was empty")
 	}
 	if server.Auth != authTrusted {
-		return fmt.Errorf("incus client not authorized yet (auth=%q)", server.Auth)
+		return &untrustedError{auth: server.Auth}
 	}
 	return nil
 }
@@ -1,8 +1,20 @@
 package incus
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/lxc/incus/v6/shared/api"
 )
@@ -68,3 +80,107 @@ func TestCheckAuthorizedErrorSurfacesAuth(t *testing.T) {
 		t.Fatalf("error %q does not surface the auth state", err)
 	}
 }
+
+func TestBackoffDelay(t *testing.T) {
+	want := []time.Duration{
+		500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second,
+	}
+	for i, w := range want {
+		if got := backoffDelay(i + 1); got != w {
+			t.Errorf("backoffDelay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	if got := backoffDelay(100); got != waitMaxDelay {
+		t.Errorf("backoffDelay(100) = %v, want the %v cap", got, waitMaxDelay)
+	}
+}
+
+func TestJittered(t *testing.T) {
+	d := 4 * time.Second
+	if got := jittered(d, 0.5); got != d {
+		t.Errorf("jittered(mid) = %v, want %v", got, d)
+	}
+	if lo, hi := jittered(d, 0), jittered(d, 0.999); lo < 3600*time.Millisecond || hi > 4400*time.Millisecond || lo >= hi {
+		t.Errorf("jittered range = [%v, %v], want within 10%% of %v", lo, hi, d)
+	}
+}
+
+// TestClassifyError pins the kinds the readiness wait reports. Only
+// connection-refused is kept quiet in the log, so TLS and trust failures must
+// never land there.
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorKind
+	}{
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, ErrorKindConnectionRefused},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), ErrorKindConnectionRefused},
+		{io.EOF, ErrorKindConnectionRefused},
+		{errors.New(`Get "https://127.0.0.1:18443/1.0": read tcp 127.0.0.1:50000->127.0.0.1:18443: read: connection reset by peer`), ErrorKindConnectionRefused},
+		{errors.New("tls: failed to verify certificate: x509: certificate has expired or is not yet valid"), ErrorKindTLSHandshake},
+		{fmt.Errorf("get server: %w", x509.UnknownAuthorityError{}), ErrorKindTLSHandshake},
+		{checkAuthorized(&api.Server{ServerUntrusted: api.ServerUntrusted{Auth: "untrusted"}}), ErrorKindCertUntrusted},
+		{&net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, ErrorKindTimeout},
+		{context.DeadlineExceeded, ErrorKindTimeout},
+		{errors.New("something else"), ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCertFingerprint(t *testing.T) {
+	certPEM, _, err := GenerateClientCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(cert.Raw)
+
+	got, err := CertFingerprint(certPEM)
+	if err != nil {
+		t.Fatalf("CertFingerprint: %v", err)
+	}
+	if want := hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("CertFingerprint = %s, want %s", got, want)
+	}
+
+	if _, err := CertFingerprint([]byte("not a certificate")); err == nil {
+		t.Error("CertFingerprint accepted non-PEM input")
+	}
+}
+
+func TestRotateClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := dir+"/client.crt", dir+"/client.key"
+	oldCert, _, err := EnsureClientCertificate(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newCert, newKey, err := RotateClientCertificate(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("RotateClientCertificate: %v", err)
+	}
+	if string(newCert) == string(oldCert) {
+		t.Fatal("rotation kept the old certificate")
+	}
+	// The files now hold the new pair, and the key stays private.
+	certPEM, keyPEM, err := EnsureClientCertificate(certPath, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(certPEM) != string(newCert) || string(keyPEM) != string(newKey) {
+		t.Error("files on disk do not match the rotated pair")
+	}
+	if info, err := os.Stat(keyPath); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("client key mode = %v (%v), want 0600", info.Mode().Perm(), err)
+	}
+}
@@ -0,0 +1,98 @@
+package incus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrorKind classifies why an Incus API request failed, so a readiness wait
+// can say what it is waiting on rather than echo a raw transport error.
+type ErrorKind string
+
+const (
+	// ErrorKindConnectionRefused means nothing answered yet: the port forward
+	// has no listener behind it, or incusd dropped the connection while
+	// starting. It is the normal state early in a boot.
+	ErrorKindConnectionRefused ErrorKind = "connection-refused"
+	// ErrorKindTLSHandshake means the TLS handshake failed, often a guest
+	// clock far enough off that a certificate is not yet valid.
+	ErrorKindTLSHandshake ErrorKind = "tls-handshake"
+	// ErrorKindCertUntrusted means the API answered but has not accepted the
+	// host's client certificate into its trust store.
+	ErrorKindCertUntrusted ErrorKind = "cert-untrusted"
+	// ErrorKindTimeout means the request got no answer in time.
+	ErrorKindTimeout ErrorKind = "timeout"
+)
+
+// Describe returns a short human phrase for k, for status lines.
+func (k ErrorKind) Describe() string {
+	switch k {
+	case ErrorKindConnectionRefused:
+		return "incus not listening yet"
+	case ErrorKindTLSHandshake:
+		return "TLS handshake failed"
+	case ErrorKindCertUntrusted:
+		return "client certificate not trusted yet"
+	case ErrorKindTimeout:
+		return "request timed out"
+	default:
+		return string(k)
+	}
+}
+
+// untrustedError is returned when the API answers without trusting this
+// client (see checkAuthorized).
+type untrustedError struct{ auth string }
+
+func (e *untrustedError) Error() string {
+	return fmt.Sprintf("incus client not authorized yet (auth=%q)", e.auth)
+}
+
+// ClassifyError returns the kind of err, or "" when it fits none.
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+	var untrusted *untrustedError
+	if errors.As(err, &untrusted) {
+		return ErrorKindCertUntrusted
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorKindConnectionRefused
+	}
+	var (
+		netErr     net.Error
+		recordErr  tls.RecordHeaderError
+		verifyErr  *tls.CertificateVerificationError
+		alertErr   tls.AlertError
+		invalidErr x509.CertificateInvalidError
+		unknownErr x509.UnknownAuthorityError
+	)
+	switch {
+	case errors.As(err, &verifyErr), errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &invalidErr), errors.As(err, &unknownErr):
+		return ErrorKindTLSHandshake
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorKindTimeout
+	}
+
+	// The Incus client flattens some transport errors into strings.
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "connection refused"), strings.Contains(msg, "connection reset by peer"), strings.HasSuffix(msg, "EOF"):
+		return ErrorKindConnectionRefused
+	case strings.Contains(msg, "tls:"), strings.Contains(msg, "x509:"):
+		return ErrorKindTLSHandshake
+	case strings.Contains(msg, "i/o timeout"), strings.Contains(msg, "Client.Timeout exceeded"), strings.Contains(msg, "deadline exceeded"):
+		return ErrorKindTimeout
+	}
+	return ""
+}
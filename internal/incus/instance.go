@@ -35,6 +35,7 @@ func Connect(cfg ClientConfig) (*Client, error) {
 		TLSClientKey:       string(cfg.KeyPEM),
 		InsecureSkipVerify: true,
 		SkipGetEvents:      true,
+		TransportWrapper:   keepAlive,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("connect incus: %w", err)
@@ -42,7 +43,9 @@ func Connect(cfg ClientConfig) (*Client, error) {
 	return &Client{server: server}, nil
 }
 
-// ConnectFromFiles is a convenience helper that reads cert+key from disk.
+// ConnectFromFiles is a convenience helper that reads cert+key from disk and
+// returns a client from DefaultPool, so repeated calls in one process share a
+// connection.
 func ConnectFromFiles(endpoint, certPath, keyPath string) (*Client, error) {
 	certPEM, err := os.ReadFile(certPath)
 	if err != nil {
@@ -52,7 +55,7 @@ func ConnectFromFiles(endpoint, certPath, keyPath string) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read client key: %w", err)
 	}
-	return Connect(ClientConfig{Endpoint: endpoint, CertPEM: certPEM, KeyPEM: keyPEM})
+	return defaultPool.Get(ClientConfig{Endpoint: endpoint, CertPEM: certPEM, KeyPEM: keyPEM})
 }
 
 // Server exposes the underlying InstanceServer for callers needing custom calls.
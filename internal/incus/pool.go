@@ -0,0 +1,197 @@
+package incus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	incusclient "github.com/lxc/incus/v6/client"
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+)
+
+// DefaultHealthInterval is how long a pooled connection is trusted before the
+// next Get re-checks it with a GetServer round-trip. Within the interval a
+// cached client is handed out without touching the network.
+const DefaultHealthInterval = 10 * time.Second
+
+// Pool caches Incus clients keyed by endpoint and client credentials so
+// repeated callers in one process share a single underlying HTTP/2 connection
+// instead of paying a TLS handshake per call. A cached client is health-checked
+// before reuse once it has been idle for HealthInterval; a failed check drops it
+// and the next dial replaces it. The health check and the dial run outside
+// the pool's lock, one at a time per key, so a slow endpoint only holds up
+// the callers waiting for that same connection.
+type Pool struct {
+	// HealthInterval overrides DefaultHealthInterval when non-zero. A negative
+	// value checks on every Get.
+	HealthInterval time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+	pending map[string]*poolCall
+	dial    func(ClientConfig) (*Client, error)
+	now     func() time.Time
+}
+
+type poolEntry struct {
+	client  *Client
+	checked time.Time
+}
+
+// poolCall is a health check or dial in flight for one key. Later callers for
+// the key wait on done and share its result.
+type poolCall struct {
+	done   chan struct{}
+	client *Client
+	server *api.Server
+	err    error
+}
+
+// NewPool returns an empty Pool that dials with Connect.
+func NewPool() *Pool {
+	return &Pool{
+		entries: make(map[string]*poolEntry),
+		pending: make(map[string]*poolCall),
+		dial:    Connect,
+		now:     time.Now,
+	}
+}
+
+var defaultPool = NewPool()
+
+// DefaultPool returns the process-wide pool shared by the runner and the CLI
+// commands.
+func DefaultPool() *Pool {
+	return defaultPool
+}
+
+// Get returns a healthy client for cfg, reusing a pooled connection when one
+// exists and dialing a new one otherwise.
+func (p *Pool) Get(cfg ClientConfig) (*Client, error) {
+	client, _, err := p.get(cfg)
+	return client, err
+}
+
+// get is Get that also returns the server info when a health check fetched
+// it, so a caller after that info need not ask again. The info is nil when a
+// cached client was handed out unchecked or a fresh one was dialed.
+func (p *Pool) get(cfg ClientConfig) (*Client, *api.Server, error) {
+	key := poolKey(cfg)
+
+	p.mu.Lock()
+	e := p.entries[key]
+	if e != nil && p.now().Sub(e.checked) < p.healthInterval() {
+		p.mu.Unlock()
+		return e.client, nil, nil
+	}
+	if c, ok := p.pending[key]; ok {
+		p.mu.Unlock()
+		<-c.done
+		return c.client, c.server, c.err
+	}
+	c := &poolCall{done: make(chan struct{})}
+	p.pending[key] = c
+	p.mu.Unlock()
+
+	c.client, c.server, c.err = p.refresh(cfg, e)
+
+	p.mu.Lock()
+	delete(p.pending, key)
+	switch {
+	case c.err == nil:
+		p.entries[key] = &poolEntry{client: c.client, checked: p.now()}
+	case e != nil && p.entries[key] == e:
+		delete(p.entries, key)
+	}
+	p.mu.Unlock()
+	close(c.done)
+	return c.client, c.server, c.err
+}
+
+// refresh health-checks the pooled entry e, when there is one, and dials a
+// replacement when there is none or the check fails. It runs without p.mu.
+func (p *Pool) refresh(cfg ClientConfig, e *poolEntry) (*Client, *api.Server, error) {
+	if e != nil {
+		server, _, err := e.client.server.GetServer()
+		if err == nil {
+			return e.client, server, nil
+		}
+		logging.L().Debug("pooled incus connection failed health check, re-dialing", "endpoint", cfg.Endpoint, "err", err)
+		e.client.server.Disconnect()
+	}
+	client, err := p.dial(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, nil, nil
+}
+
+// Evict drops the pooled connection for cfg, if any, so the next Get re-dials.
+// Callers use it after a request fails in a way that suggests the connection
+// itself is bad.
+func (p *Pool) Evict(cfg ClientConfig) {
+	key := poolKey(cfg)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.entries[key]; ok {
+		e.client.server.Disconnect()
+		delete(p.entries, key)
+	}
+}
+
+// Close disconnects and drops every pooled connection.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, e := range p.entries {
+		e.client.server.Disconnect()
+		delete(p.entries, key)
+	}
+}
+
+func (p *Pool) healthInterval() time.Duration {
+	if p.HealthInterval != 0 {
+		return p.HealthInterval
+	}
+	return DefaultHealthInterval
+}
+
+// poolKey identifies a connection by endpoint and a digest of the credentials,
+// so two callers presenting different certificates never share a session.
+func poolKey(cfg ClientConfig) string {
+	h := sha256.New()
+	h.Write(cfg.CertPEM)
+	h.Write([]byte{0})
+	h.Write(cfg.KeyPEM)
+	return cfg.Endpoint + "|" + hex.EncodeToString(h.Sum(nil))
+}
+
+// keepAliveTransport lets a pooled client actually hold its connection open.
+// The Incus SDK builds a transport with keep-alives disabled, which would make
+// every request a fresh TLS handshake no matter how long the client is cached.
+type keepAliveTransport struct {
+	t *http.Transport
+}
+
+// keepAlive is the ConnectionArgs.TransportWrapper that re-enables keep-alives
+// and negotiates HTTP/2, so concurrent requests multiplex over one connection.
+func keepAlive(t *http.Transport) incusclient.HTTPTransporter {
+	t.DisableKeepAlives = false
+	t.ForceAttemptHTTP2 = true
+	t.IdleConnTimeout = 90 * time.Second
+	return &keepAliveTransport{t: t}
+}
+
+func (k *keepAliveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return k.t.RoundTrip(req)
+}
+
+func (k *keepAliveTransport) Transport() *http.Transport {
+	return k.t
+}
@@ -0,0 +1,252 @@
+package incus
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sharedtls "github.com/lxc/incus/v6/shared/tls"
+)
+
+// fakeIncus serves just enough of GET /1.0 for ConnectIncus and health checks,
+// counting TCP connections so tests can prove the pool reuses one session.
+type fakeIncus struct {
+	srv     *httptest.Server
+	conns   atomic.Int32
+	failing atomic.Bool
+	http1   atomic.Int32
+}
+
+func newFakeIncus(t *testing.T) *fakeIncus {
+	t.Helper()
+	f := &fakeIncus{}
+	f.srv = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 2 {
+			f.http1.Add(1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if f.failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"type":"error","error":"unavailable","error_code":500}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"type":"sync","status":"Success","status_code":200,"metadata":{"api_version":"1.0","auth":"trusted"}}`))
+	}))
+	f.srv.EnableHTTP2 = true
+	f.srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			f.conns.Add(1)
+		}
+	}
+	f.srv.StartTLS()
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func testClientConfig(t *testing.T, endpoint string) ClientConfig {
+	t.Helper()
+	certPEM, keyPEM, err := sharedtls.GenerateMemCert(true, false)
+	if err != nil {
+		t.Fatalf("generate client cert: %v", err)
+	}
+	return ClientConfig{Endpoint: endpoint, CertPEM: certPEM, KeyPEM: keyPEM}
+}
+
+// countingPool wraps the pool's dialer so tests can see when it re-dials.
+func countingPool(dials *atomic.Int32) *Pool {
+	p := NewPool()
+	p.HealthInterval = -1 // health-check on every Get
+	p.dial = func(cfg ClientConfig) (*Client, error) {
+		dials.Add(1)
+		return Connect(cfg)
+	}
+	return p
+}
+
+func TestPoolReusesConnection(t *testing.T) {
+	f := newFakeIncus(t)
+	cfg := testClientConfig(t, f.srv.URL)
+	var dials atomic.Int32
+	p := countingPool(&dials)
+	defer p.Close()
+
+	first, err := p.Get(cfg)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		c, err := p.Get(cfg)
+		if err != nil {
+			t.Fatalf("Get #%d: %v", i+2, err)
+		}
+		if c != first {
+			t.Fatalf("Get #%d returned a different client", i+2)
+		}
+		if _, _, err := c.Server().GetServer(); err != nil {
+			t.Fatalf("GetServer: %v", err)
+		}
+	}
+
+	if got := dials.Load(); got != 1 {
+		t.Errorf("dials = %d, want 1", got)
+	}
+	if got := f.conns.Load(); got != 1 {
+		t.Errorf("TCP connections = %d, want 1", got)
+	}
+	if got := f.http1.Load(); got != 0 {
+		t.Errorf("%d requests fell back to HTTP/1.x, want all over HTTP/2", got)
+	}
+}
+
+func TestPoolKeysByCredentials(t *testing.T) {
+	f := newFakeIncus(t)
+	var dials atomic.Int32
+	p := countingPool(&dials)
+	defer p.Close()
+
+	a, err := p.Get(testClientConfig(t, f.srv.URL))
+	if err != nil {
+		t.Fatalf("Get a: %v", err)
+	}
+	b, err := p.Get(testClientConfig(t, f.srv.URL))
+	if err != nil {
+		t.Fatalf("Get b: %v", err)
+	}
+	if a == b {
+		t.Fatal("clients with different certificates share a pooled connection")
+	}
+	if got := dials.Load(); got != 2 {
+		t.Errorf("dials = %d, want 2", got)
+	}
+}
+
+func TestPoolRedialsAfterFailedHealthCheck(t *testing.T) {
+	f := newFakeIncus(t)
+	cfg := testClientConfig(t, f.srv.URL)
+	var dials atomic.Int32
+	p := countingPool(&dials)
+	defer p.Close()
+
+	first, err := p.Get(cfg)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	f.failing.Store(true)
+	if _, err := p.Get(cfg); err == nil {
+		t.Fatal("Get succeeded while the server was failing")
+	}
+
+	f.failing.Store(false)
+	second, err := p.Get(cfg)
+	if err != nil {
+		t.Fatalf("Get after recovery: %v", err)
+	}
+	if second == first {
+		t.Error("Get after a failed health check returned the evicted client")
+	}
+	if got := dials.Load(); got != 3 {
+		t.Errorf("dials = %d, want 3 (initial, failed re-dial, recovery)", got)
+	}
+}
+
+func TestPoolEvict(t *testing.T) {
+	f := newFakeIncus(t)
+	cfg := testClientConfig(t, f.srv.URL)
+	var dials atomic.Int32
+	p := countingPool(&dials)
+	defer p.Close()
+
+	if _, err := p.Get(cfg); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	p.Evict(cfg)
+	if _, err := p.Get(cfg); err != nil {
+		t.Fatalf("Get after Evict: %v", err)
+	}
+	if got := dials.Load(); got != 2 {
+		t.Errorf("dials = %d, want 2", got)
+	}
+}
+
+func TestPoolSharesConcurrentDial(t *testing.T) {
+	f := newFakeIncus(t)
+	cfg := testClientConfig(t, f.srv.URL)
+	var dials atomic.Int32
+	release := make(chan struct{})
+	p := NewPool()
+	p.dial = func(cfg ClientConfig) (*Client, error) {
+		dials.Add(1)
+		<-release
+		return Connect(cfg)
+	}
+	defer p.Close()
+
+	const callers = 5
+	clients := make([]*Client, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Go(func() {
+			c, err := p.Get(cfg)
+			if err != nil {
+				t.Errorf("Get: %v", err)
+			}
+			clients[i] = c
+		})
+	}
+	// Let every caller reach the pool before the one dial finishes.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := dials.Load(); got != 1 {
+		t.Errorf("dials = %d, want 1 shared by %d callers", got, callers)
+	}
+	for i, c := range clients {
+		if c != clients[0] {
+			t.Errorf("caller %d got a different client", i)
+		}
+	}
+}
+
+func TestPoolSlowDialDoesNotBlockOtherKeys(t *testing.T) {
+	f := newFakeIncus(t)
+	slow := testClientConfig(t, f.srv.URL)
+	fast := testClientConfig(t, f.srv.URL)
+	release := make(chan struct{})
+	p := NewPool()
+	p.dial = func(cfg ClientConfig) (*Client, error) {
+		if poolKey(cfg) == poolKey(slow) {
+			<-release
+		}
+		return Connect(cfg)
+	}
+	defer p.Close()
+
+	slowDone := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		_, _ = p.Get(slow)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	got := make(chan error, 1)
+	go func() {
+		_, err := p.Get(fast)
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Errorf("Get(fast): %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Get for one endpoint waited on another endpoint's dial")
+	}
+	close(release)
+	<-slowDone
+}
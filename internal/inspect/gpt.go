@@ -0,0 +1,122 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+const sectorSize = 512
+
+// Partition type GUIDs the root filesystem is looked for under, in order of
+// preference. Debian cloud images tag the root with the arch-specific
+// discoverable-partitions type; other images use plain Linux data.
+const (
+	guidLinuxRootARM64 = "B921B045-1DF0-41C3-AF44-4C6F280D3FAE"
+	guidLinuxRootAMD64 = "4F68BCE3-E8CD-4DB1-96E7-FBCAF984B709"
+	guidLinuxData      = "0FC63DAF-8483-4772-8E79-3D69D8477DE4"
+)
+
+// ErrNoRootPartition means the image has a partition table but nothing that
+// looks like a Linux root filesystem.
+var ErrNoRootPartition = errors.New("no Linux root partition found")
+
+// Partition is one GPT entry.
+type Partition struct {
+	// Number is the 1-based partition number, as in /dev/vda1.
+	Number int    `json:"number"`
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	// Offset and Size are in bytes.
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+// ReadPartitions parses the GPT of the disk image at path.
+func ReadPartitions(path string) ([]Partition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open disk image: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	hdr := make([]byte, 92)
+	if _, err := f.ReadAt(hdr, sectorSize); err != nil {
+		return nil, fmt.Errorf("read partition table: %w", err)
+	}
+	if string(hdr[:8]) != "EFI PART" {
+		return nil, errors.New("no GPT partition table")
+	}
+	entriesLBA := int64(binary.LittleEndian.Uint64(hdr[72:80]))
+	count := binary.LittleEndian.Uint32(hdr[80:84])
+	entrySize := binary.LittleEndian.Uint32(hdr[84:88])
+	if entrySize < 128 || count > 1024 {
+		return nil, fmt.Errorf("implausible GPT header (%d entries of %d bytes)", count, entrySize)
+	}
+
+	entries := make([]byte, int(count)*int(entrySize))
+	if _, err := f.ReadAt(entries, entriesLBA*sectorSize); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read partition entries: %w", err)
+	}
+
+	var parts []Partition
+	for i := range int(count) {
+		e := entries[i*int(entrySize) : (i+1)*int(entrySize)]
+		if bytes.Equal(e[:16], make([]byte, 16)) {
+			continue
+		}
+		first := int64(binary.LittleEndian.Uint64(e[32:40]))
+		last := int64(binary.LittleEndian.Uint64(e[40:48]))
+		parts = append(parts, Partition{
+			Number: i + 1,
+			Type:   guidString(e[:16]),
+			Name:   utf16String(e[56:128]),
+			Offset: first * sectorSize,
+			Size:   (last - first + 1) * sectorSize,
+		})
+	}
+	return parts, nil
+}
+
+// RootPartition picks the partition holding the root filesystem: the first
+// with a Linux root type, else the largest Linux data partition.
+func RootPartition(parts []Partition) (Partition, error) {
+	for _, p := range parts {
+		if p.Type == guidLinuxRootARM64 || p.Type == guidLinuxRootAMD64 {
+			return p, nil
+		}
+	}
+	var best Partition
+	for _, p := range parts {
+		if p.Type == guidLinuxData && p.Size > best.Size {
+			best = p
+		}
+	}
+	if best.Number == 0 {
+		return Partition{}, ErrNoRootPartition
+	}
+	return best, nil
+}
+
+// guidString formats a GPT GUID, whose first three fields are little-endian.
+func guidString(b []byte) string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10], b[10:16])
+}
+
+// utf16String decodes a NUL-padded UTF-16LE partition name.
+func utf16String(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return strings.TrimRight(string(utf16.Decode(u)), "\x00")
+}
@@ -0,0 +1,157 @@
+// Package inspect reads files out of a stopped VM's disk image without
+// booting it, for diagnosing a guest that won't come up (cloud-init logs,
+// vsock-diag.txt, the ready sentinel).
+//
+// macOS cannot mount ext4, so reads go through debugfs from e2fsprogs, which
+// opens the filesystem read-only straight from the image file at the root
+// partition's offset. Nothing is mounted and the image is never written. The
+// journal is not replayed, so after a crash the newest writes may be missing.
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ext4 superblock magic, at byte 56 of the superblock 1024 bytes into the
+// filesystem; used to accept a bare filesystem image with no partition table.
+const (
+	extSuperblockOffset = 1024
+	extMagicOffset      = 56
+	extMagic            = 0xEF53
+)
+
+// debugfsFallbacks are where Homebrew puts debugfs; e2fsprogs is keg-only, so
+// it is usually not on PATH.
+var debugfsFallbacks = []string{
+	"/opt/homebrew/opt/e2fsprogs/sbin/debugfs",
+	"/usr/local/opt/e2fsprogs/sbin/debugfs",
+}
+
+// Image is the root filesystem inside a disk image.
+type Image struct {
+	// Path is the disk image file.
+	Path string
+	// Partition is the partition read from; zero for a bare filesystem image.
+	Partition Partition
+
+	debugfs string
+}
+
+// Open locates the root filesystem in the disk image at path. partition
+// selects a GPT partition number explicitly; zero picks the root partition.
+func Open(path string, partition int) (*Image, error) {
+	debugfs, err := findDebugfs()
+	if err != nil {
+		return nil, err
+	}
+	img := &Image{Path: path, debugfs: debugfs}
+
+	parts, err := ReadPartitions(path)
+	if err != nil {
+		// No partition table: accept a bare ext filesystem image.
+		if partition == 0 && isExtFilesystem(path) {
+			return img, nil
+		}
+		return nil, err
+	}
+	if partition == 0 {
+		img.Partition, err = RootPartition(parts)
+		return img, err
+	}
+	for _, p := range parts {
+		if p.Number == partition {
+			img.Partition = p
+			return img, nil
+		}
+	}
+	return nil, fmt.Errorf("disk image has no partition %d", partition)
+}
+
+// Cat copies the guest file at guestPath to w.
+func (img *Image) Cat(ctx context.Context, guestPath string, w io.Writer) error {
+	return img.run(ctx, "cat", guestPath, w)
+}
+
+// List writes a long listing of the guest directory at guestPath to w.
+func (img *Image) List(ctx context.Context, guestPath string, w io.Writer) error {
+	return img.run(ctx, "ls -l", guestPath, w)
+}
+
+// run executes one debugfs request against the image, read-only.
+func (img *Image) run(ctx context.Context, request, guestPath string, w io.Writer) error {
+	if !strings.HasPrefix(guestPath, "/") {
+		return fmt.Errorf("guest path must be absolute: %q", guestPath)
+	}
+	if strings.ContainsAny(guestPath, "\"\n") {
+		return fmt.Errorf("guest path contains unsupported characters: %q", guestPath)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, img.debugfs, "-R", request+` "`+guestPath+`"`, img.spec())
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("debugfs: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	// debugfs exits 0 even when the request fails; errors only reach stderr.
+	if msg := debugfsError(stderr.String()); msg != "" {
+		return fmt.Errorf("%s: %s", guestPath, msg)
+	}
+	return nil
+}
+
+// spec is the image argument for debugfs, with the partition offset as an
+// e2fsprogs I/O option.
+func (img *Image) spec() string {
+	if img.Partition.Offset == 0 {
+		return img.Path
+	}
+	return img.Path + "?offset=" + strconv.FormatInt(img.Partition.Offset, 10)
+}
+
+// debugfsError returns debugfs's stderr minus its version banner.
+func debugfsError(stderr string) string {
+	var lines []string
+	for _, l := range strings.Split(stderr, "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "debugfs ") {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return strings.Join(lines, "; ")
+}
+
+func isExtFilesystem(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	b := make([]byte, 2)
+	if _, err := f.ReadAt(b, extSuperblockOffset+extMagicOffset); err != nil {
+		return false
+	}
+	return binary.LittleEndian.Uint16(b) == extMagic
+}
+
+func findDebugfs() (string, error) {
+	if p, err := exec.LookPath("debugfs"); err == nil {
+		return p, nil
+	}
+	for _, p := range debugfsFallbacks {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", errors.New("debugfs not found (install with: brew install e2fsprogs)")
+}
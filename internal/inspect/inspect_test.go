@@ -0,0 +1,199 @@
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const guidEFISystem = "C12A7328-F81F-11D2-BA4B-00A0C93EC93B"
+
+// guidBytes encodes a GUID string the way GPT stores it.
+func guidBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	raw, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(raw) != 16 {
+		t.Fatalf("bad guid %q", s)
+	}
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint32(b[0:], binary.BigEndian.Uint32(raw[0:]))
+	binary.LittleEndian.PutUint16(b[4:], binary.BigEndian.Uint16(raw[4:]))
+	binary.LittleEndian.PutUint16(b[6:], binary.BigEndian.Uint16(raw[6:]))
+	copy(b[8:], raw[8:])
+	return b
+}
+
+type testPart struct {
+	number      int
+	typ, name   string
+	first, last uint64
+}
+
+// writeGPT writes a minimal GPT (header + 128 entries at LBA 2) to f.
+func writeGPT(t *testing.T, f *os.File, parts []testPart) {
+	t.Helper()
+	hdr := make([]byte, 92)
+	copy(hdr, "EFI PART")
+	binary.LittleEndian.PutUint64(hdr[72:], 2)
+	binary.LittleEndian.PutUint32(hdr[80:], 128)
+	binary.LittleEndian.PutUint32(hdr[84:], 128)
+	if _, err := f.WriteAt(hdr, sectorSize); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range parts {
+		e := make([]byte, 128)
+		copy(e, guidBytes(t, p.typ))
+		binary.LittleEndian.PutUint64(e[32:], p.first)
+		binary.LittleEndian.PutUint64(e[40:], p.last)
+		for i, u := range utf16.Encode([]rune(p.name)) {
+			binary.LittleEndian.PutUint16(e[56+2*i:], u)
+		}
+		if _, err := f.WriteAt(e, 2*sectorSize+int64(p.number-1)*128); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadPartitionsAndRoot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.raw")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeGPT(t, f, []testPart{
+		{number: 1, typ: guidLinuxRootARM64, name: "root", first: 262144, last: 524287},
+		{number: 15, typ: guidEFISystem, name: "EFI", first: 2048, last: 262143},
+	})
+	_ = f.Close()
+
+	parts, err := ReadPartitions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 || parts[1].Number != 15 || parts[1].Name != "EFI" || parts[1].Type != guidEFISystem {
+		t.Fatalf("partitions = %+v", parts)
+	}
+	root, err := RootPartition(parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Number != 1 || root.Offset != 262144*sectorSize || root.Size != 262144*sectorSize {
+		t.Errorf("root = %+v", root)
+	}
+
+	if _, err := RootPartition(parts[1:]); err != ErrNoRootPartition {
+		t.Errorf("EFI-only table: err = %v, want ErrNoRootPartition", err)
+	}
+	data := []Partition{
+		{Number: 2, Type: guidLinuxData, Size: 10},
+		{Number: 3, Type: guidLinuxData, Size: 99},
+	}
+	if p, _ := RootPartition(data); p.Number != 3 {
+		t.Errorf("largest data partition not chosen: %+v", p)
+	}
+}
+
+func TestReadPartitionsWithoutGPT(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blank.raw")
+	if err := os.WriteFile(path, make([]byte, 4096), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPartitions(path); err == nil {
+		t.Fatal("expected an error for a disk without GPT")
+	}
+}
+
+func TestDebugfsError(t *testing.T) {
+	if got := debugfsError("debugfs 1.47.0 (5-Feb-2023)\n"); got != "" {
+		t.Errorf("banner only: %q", got)
+	}
+	got := debugfsError("debugfs 1.47.0 (5-Feb-2023)\n/nope: File not found by ext2_lookup \n")
+	if got != "/nope: File not found by ext2_lookup" {
+		t.Errorf("debugfsError = %q", got)
+	}
+}
+
+// TestCatFromPartitionedImage builds a GPT disk with a real ext4 root
+// partition and reads a file back out of it, as `br inspect cat` does.
+func TestCatFromPartitionedImage(t *testing.T) {
+	if _, err := exec.LookPath("mke2fs"); err != nil {
+		t.Skip("mke2fs not available")
+	}
+	if _, err := findDebugfs(); err != nil {
+		t.Skip(err)
+	}
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "var/lib/bladerunner"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "var/lib/bladerunner/vsock-diag.txt"), []byte("vsock: no listener\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fsPath := filepath.Join(dir, "fs.img")
+	if err := os.WriteFile(fsPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(fsPath, 8<<20); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("mke2fs", "-q", "-F", "-t", "ext4", "-d", root, fsPath).CombinedOutput(); err != nil {
+		t.Skipf("mke2fs -d failed: %v: %s", err, out)
+	}
+	fsData, err := os.ReadFile(fsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const firstLBA = 2048
+	diskPath := filepath.Join(dir, "disk.raw")
+	f, err := os.Create(diskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeGPT(t, f, []testPart{
+		{number: 1, typ: guidLinuxRootAMD64, name: "root", first: firstLBA, last: firstLBA + uint64(len(fsData))/sectorSize - 1},
+	})
+	if _, err := f.WriteAt(fsData, firstLBA*sectorSize); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	img, err := Open(diskPath, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	var out bytes.Buffer
+	if err := img.Cat(context.Background(), "/var/lib/bladerunner/vsock-diag.txt", &out); err != nil {
+		t.Fatalf("Cat: %v", err)
+	}
+	if out.String() != "vsock: no listener\n" {
+		t.Errorf("Cat = %q", out.String())
+	}
+
+	out.Reset()
+	if err := img.List(context.Background(), "/var/lib/bladerunner", &out); err != nil || !strings.Contains(out.String(), "vsock-diag.txt") {
+		t.Errorf("List = %q, %v", out.String(), err)
+	}
+
+	if err := img.Cat(context.Background(), "/var/lib/bladerunner/ready", &out); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Cat of a missing file: err = %v", err)
+	}
+	if err := img.Cat(context.Background(), "relative", &out); err == nil {
+		t.Error("relative guest path should be rejected")
+	}
+
+	// A bare filesystem image (no partition table) opens at offset 0.
+	bare, err := Open(fsPath, 0)
+	if err != nil || bare.Partition.Offset != 0 {
+		t.Fatalf("Open(bare) = %+v, %v", bare, err)
+	}
+}
@@ -16,6 +16,25 @@ import (
 // LogLevelEnvVar is the environment variable used to set the log level.
 const LogLevelEnvVar = "BLADERUNNER_LOG_LEVEL"
 
+// Format selects how Init's logger writes records.
+type Format string
+
+const (
+	// FormatText writes one human-readable line per record.
+	FormatText Format = "text"
+	// FormatJSON writes one JSON object per record, for log pipelines.
+	FormatJSON Format = "json"
+)
+
+// formatter maps f to its charmlog formatter; anything but FormatJSON is
+// text.
+func (f Format) formatter() charmlog.Formatter {
+	if f == FormatJSON {
+		return charmlog.JSONFormatter
+	}
+	return charmlog.TextFormatter
+}
+
 var (
 	mu     sync.RWMutex
 	logger = charmlog.NewWithOptions(os.Stdout, charmlog.Options{
@@ -29,24 +48,30 @@ var (
 	fileWriter io.Writer
 )
 
-// parseLevel maps a string (case-insensitive) to a charmlog.Level. Accepted
-// values are "debug", "info", "warn"/"warning", and "error". Unknown or empty
-// values fall back to InfoLevel.
-func parseLevel(s string) charmlog.Level {
+// ParseLevel maps a string (case-insensitive) to a charmlog.Level. Accepted
+// values are "debug", "info", "warn"/"warning", and "error".
+func ParseLevel(s string) (charmlog.Level, error) {
 	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "debug":
-		return charmlog.DebugLevel
+		return charmlog.DebugLevel, nil
 	case "info":
-		return charmlog.InfoLevel
+		return charmlog.InfoLevel, nil
 	case "warn", "warning":
-		return charmlog.WarnLevel
+		return charmlog.WarnLevel, nil
 	case "error":
-		return charmlog.ErrorLevel
+		return charmlog.ErrorLevel, nil
 	default:
-		return charmlog.InfoLevel
+		return charmlog.InfoLevel, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
 	}
 }
 
+// parseLevel is ParseLevel with unknown or empty values falling back to
+// InfoLevel.
+func parseLevel(s string) charmlog.Level {
+	level, _ := ParseLevel(s)
+	return level
+}
+
 // levelFromEnv reads BLADERUNNER_LOG_LEVEL and returns the parsed level.
 func levelFromEnv() charmlog.Level {
 	return parseLevel(os.Getenv(LogLevelEnvVar))
@@ -60,7 +85,11 @@ func levelFromEnv() charmlog.Level {
 //
 // In non-TTY environments (CI, log capture) the logger writes to both the
 // file and stdout so existing scrapers keep working.
-func Init(logPath string) error {
+//
+// The file rotates by size as rotate says; rotation happens under the
+// rotator's lock, so concurrent loggers never interleave with it. Records
+// are written in format, on the terminal as in the file.
+func Init(logPath string, rotate RotateOptions, format Format) error {
 	if logPath == "" {
 		return fmt.Errorf("log path is empty")
 	}
@@ -71,10 +100,10 @@ func Init(logPath string) error {
 
 	rotator := &lumberjack.Logger{
 		Filename:   logPath,
-		MaxSize:    25, // MB
-		MaxBackups: 5,
-		MaxAge:     14, // days
-		Compress:   true,
+		MaxSize:    rotate.MaxSize,
+		MaxBackups: rotate.MaxBackups,
+		MaxAge:     rotate.MaxAge,
+		Compress:   rotate.Compress,
 	}
 
 	level := levelFromEnv()
@@ -86,13 +115,15 @@ func Init(logPath string) error {
 		Level:           level,
 		ReportTimestamp: true,
 		TimeFormat:      "2006-01-02 15:04:05",
+		Formatter:       format.formatter(),
 	})
 
 	mu.Lock()
 	logger = l
 	mu.Unlock()
 
-	logger.Info("logging initialized", "path", logPath, "level", level)
+	// Not "level": that key is the record's own, and JSON would repeat it.
+	logger.Info("logging initialized", "path", logPath, "log_level", level, "format", format)
 	return nil
 }
 
@@ -130,12 +161,21 @@ func isStdoutTTY() bool {
 	return term.IsTerminal(int(os.Stdout.Fd()))
 }
 
+// SetLevel changes the log level at runtime. Loggers already taken from L
+// follow it, since they share the one logger.
 func SetLevel(level charmlog.Level) {
 	mu.Lock()
 	defer mu.Unlock()
 	logger.SetLevel(level)
 }
 
+// Level returns the current log level.
+func Level() charmlog.Level {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logger.GetLevel()
+}
+
 func L() *charmlog.Logger {
 	mu.RLock()
 	defer mu.RUnlock()
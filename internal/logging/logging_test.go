@@ -1,7 +1,15 @@
 package logging
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	charmlog "github.com/charmbracelet/log"
 )
@@ -59,3 +67,122 @@ func TestLevelFromEnv(t *testing.T) {
 		t.Fatalf("bogus env: got %v, want InfoLevel", got)
 	}
 }
+
+func TestParseLevelRejectsUnknown(t *testing.T) {
+	for _, in := range []string{"", "trace", "nonsense"} {
+		if _, err := ParseLevel(in); err == nil {
+			t.Errorf("ParseLevel(%q) accepted", in)
+		}
+	}
+	if got, err := ParseLevel("Warning"); err != nil || got != charmlog.WarnLevel {
+		t.Errorf("ParseLevel(Warning) = %v, %v", got, err)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	orig := Level()
+	t.Cleanup(func() { SetLevel(orig) })
+
+	log := L()
+	SetLevel(charmlog.DebugLevel)
+	if Level() != charmlog.DebugLevel || log.GetLevel() != charmlog.DebugLevel {
+		t.Errorf("after SetLevel(debug): Level() = %v, held logger at %v", Level(), log.GetLevel())
+	}
+}
+
+// TestInitRotatesUnderConcurrentLogging logs past the size limit from many
+// goroutines at once and checks the file rotated and kept its bound.
+func TestInitRotatesUnderConcurrentLogging(t *testing.T) {
+	restoreLoggerAfter(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bladerunner.log")
+	if err := Init(path, RotateOptions{MaxSize: 1, MaxBackups: 2}, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	SetQuiet(true)
+
+	line := strings.Repeat("x", 1000)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 400; i++ {
+				L().Info("filler", "line", line)
+			}
+		}()
+	}
+	wg.Wait()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() > 1<<20 {
+		t.Errorf("live log is %d bytes, past the 1 MiB limit", fi.Size())
+	}
+	// Old backups are pruned asynchronously; wait for the bound to hold.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		backups, _ := filepath.Glob(filepath.Join(dir, "bladerunner-*.log"))
+		if len(backups) >= 1 && len(backups) <= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("backups = %v, want 1 or 2", backups)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// restoreLoggerAfter puts back the package logger Init replaces once t ends.
+func restoreLoggerAfter(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	origLogger, origFile := logger, fileWriter
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		logger, fileWriter = origLogger, origFile
+		mu.Unlock()
+	})
+}
+
+// TestInitJSONFormat checks every record, progress ones included, is a JSON
+// object carrying its message and fields.
+func TestInitJSONFormat(t *testing.T) {
+	restoreLoggerAfter(t)
+	path := filepath.Join(t.TempDir(), "bladerunner.log")
+	if err := Init(path, RotateOptions{MaxSize: 1, MaxBackups: 1}, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	SetQuiet(true)
+
+	p := NewByteProgressTo("download", 100, io.Discard, false)
+	_, _ = p.Write(make([]byte, 50))
+	p.Finish()
+	tp := NewTimedProgressTo("incus", time.Minute, io.Discard, false)
+	tp.Fail(errors.New("timed out"))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("not JSON: %q: %v", line, err)
+		}
+		msgs[rec["msg"].(string)] = rec
+	}
+	if rec := msgs["progress"]; rec == nil || rec["task"] != "download" || rec["percent"] != float64(50) {
+		t.Errorf("progress record = %v", rec)
+	}
+	if rec := msgs["task complete"]; rec == nil || rec["written"] != "50B" {
+		t.Errorf("task complete record = %v", rec)
+	}
+	if rec := msgs["wait failed"]; rec == nil || rec["err"] != "timed out" || rec["level"] != "error" {
+		t.Errorf("wait failed record = %v", rec)
+	}
+}
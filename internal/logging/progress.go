@@ -13,6 +13,24 @@ import (
 
 var spinnerFrames = []string{"|", "/", "-", "\\"}
 
+// ProgressEnvVar set to "plain" makes progress print one plain line per
+// significant step to stdout, terminal or not, instead of drawing bars. It
+// is meant for CI logs, which show neither redrawn bars nor silence well.
+const ProgressEnvVar = "BLADERUNNER_PROGRESS"
+
+// plainStepPct is how far, in percent of the total, plain byte progress
+// moves between lines; plainUnknownEvery paces them when the total is
+// unknown.
+const (
+	plainStepPct      = 25
+	plainUnknownEvery = 30 * time.Second
+)
+
+// PlainProgress reports whether ProgressEnvVar asks for plain progress.
+func PlainProgress() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv(ProgressEnvVar)), "plain")
+}
+
 // ByteProgress tracks long byte-stream operations such as downloads/copies.
 type ByteProgress struct {
 	label string
@@ -22,18 +40,37 @@ type ByteProgress struct {
 
 	start        time.Time
 	written      int64
+	resumed      int64 // bytes already done before start; not part of the speed
 	lastRender   time.Time
 	nextLogPct   int
 	nextUnknown  time.Time
 	spinnerFrame int
+	lastLineLen  int
 	finished     bool
 
+	// plain prints a line per plainStepPct instead of drawing a bar.
+	plain          bool
+	nextPlainPct   int
+	nextPlainBytes time.Time
+
 	interactive bool
 	out         io.Writer
 }
 
+// NewByteProgress renders to stdout, drawing the bar only when it is a
+// terminal, or printing plain lines when PlainProgress is set.
 func NewByteProgress(label string, total int64) *ByteProgress {
-	interactive := term.IsTerminal(int(os.Stdout.Fd()))
+	if PlainProgress() {
+		p := NewByteProgressTo(label, total, os.Stdout, false)
+		p.plain = true
+		return p
+	}
+	return NewByteProgressTo(label, total, os.Stdout, term.IsTerminal(int(os.Stdout.Fd())))
+}
+
+// NewByteProgressTo renders to out; the bar is drawn only when interactive.
+// A total <= 0 means unknown and shows a spinner instead.
+func NewByteProgressTo(label string, total int64, out io.Writer, interactive bool) *ByteProgress {
 	return &ByteProgress{
 		label:       label,
 		total:       total,
@@ -41,10 +78,23 @@ func NewByteProgress(label string, total int64) *ByteProgress {
 		nextLogPct:  10,
 		nextUnknown: time.Now().Add(10 * time.Second),
 		interactive: interactive,
-		out:         os.Stdout,
+		out:         out,
+
+		nextPlainPct:   plainStepPct,
+		nextPlainBytes: time.Now().Add(plainUnknownEvery),
 	}
 }
 
+// SetResumed counts n bytes finished by an earlier attempt (a resumed
+// download) toward the total, without crediting them to this run's speed.
+// Call it before the first Write.
+func (p *ByteProgress) SetResumed(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resumed = n
+	p.written = n
+}
+
 func (p *ByteProgress) Write(b []byte) (int, error) {
 	n := len(b)
 	p.mu.Lock()
@@ -56,6 +106,7 @@ func (p *ByteProgress) Write(b []byte) (int, error) {
 
 	p.written += int64(n)
 	p.maybeRenderLocked(false)
+	p.maybePlainLocked()
 	p.maybeLogLocked()
 	return n, nil
 }
@@ -68,6 +119,7 @@ func (p *ByteProgress) Finish() {
 	}
 	p.finished = true
 	p.maybeRenderLocked(true)
+	p.plainDoneLocked(nil)
 	p.logCompletionLocked(nil)
 }
 
@@ -79,6 +131,7 @@ func (p *ByteProgress) Fail(err error) {
 	}
 	p.finished = true
 	p.maybeRenderLocked(true)
+	p.plainDoneLocked(err)
 	p.logCompletionLocked(err)
 }
 
@@ -91,10 +144,7 @@ func (p *ByteProgress) maybeRenderLocked(force bool) {
 	}
 
 	elapsed := time.Since(p.start)
-	speed := int64(0)
-	if elapsed > 0 {
-		speed = int64(float64(p.written) / elapsed.Seconds())
-	}
+	speed := p.speedLocked(elapsed)
 
 	if p.total > 0 {
 		fraction := float64(p.written) / float64(p.total)
@@ -109,15 +159,22 @@ func (p *ByteProgress) maybeRenderLocked(force bool) {
 			"\r%s %s %s/%s %s/s",
 			p.label,
 			renderBar(fraction, 34),
-			humanBytes(p.written),
-			humanBytes(p.total),
-			humanBytes(speed),
+			HumanBytes(p.written),
+			HumanBytes(p.total),
+			HumanBytes(speed),
 		)
+		if eta, ok := p.etaLocked(speed); ok {
+			line += fmt.Sprintf(" ~%s left", eta)
+		}
+		// Pad over the tail of a longer previous line, e.g. when the ETA
+		// drops off or shrinks from "1m30s" to "9s".
+		line += strings.Repeat(" ", max(0, p.lastLineLen-len(line)))
+		p.lastLineLen = len(line)
 		fmt.Fprint(p.out, line)
 	} else {
 		frame := spinnerFrames[p.spinnerFrame%len(spinnerFrames)]
 		p.spinnerFrame++
-		line := fmt.Sprintf("\r%s %s %s downloaded %s/s", frame, p.label, humanBytes(p.written), humanBytes(speed))
+		line := fmt.Sprintf("\r%s %s %s downloaded %s/s", frame, p.label, HumanBytes(p.written), HumanBytes(speed))
 		fmt.Fprint(p.out, line)
 	}
 
@@ -128,12 +185,81 @@ func (p *ByteProgress) maybeRenderLocked(force bool) {
 	p.lastRender = time.Now()
 }
 
+// speedLocked is the transfer rate of this run, leaving out resumed bytes.
+func (p *ByteProgress) speedLocked(elapsed time.Duration) int64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return int64(float64(p.written-p.resumed) / elapsed.Seconds())
+}
+
+// etaLocked estimates the time left at speed bytes per second. It is false
+// when the total is unknown, the transfer is done, or nothing has moved yet.
+func (p *ByteProgress) etaLocked(speed int64) (time.Duration, bool) {
+	if p.total <= 0 || p.written >= p.total || speed <= 0 {
+		return 0, false
+	}
+	left := time.Duration(float64(p.total-p.written) / float64(speed) * float64(time.Second))
+	return max(left.Round(time.Second), time.Second), true
+}
+
+// maybePlainLocked prints a plain line each time the transfer crosses
+// another plainStepPct of its total, or every plainUnknownEvery when the
+// total is unknown.
+func (p *ByteProgress) maybePlainLocked() {
+	if !p.plain {
+		return
+	}
+	elapsed := time.Since(p.start)
+	if p.total > 0 {
+		percent := int(float64(p.written) * 100 / float64(p.total))
+		// 100% is left to Finish, which reports the whole transfer.
+		if percent < p.nextPlainPct || percent >= 100 {
+			return
+		}
+		for p.nextPlainPct <= percent {
+			p.nextPlainPct += plainStepPct
+		}
+		speed := p.speedLocked(elapsed)
+		line := fmt.Sprintf("%s: %d%% (%s/%s) %s elapsed, %s/s", p.label, percent,
+			HumanBytes(p.written), HumanBytes(p.total), elapsed.Round(time.Second), HumanBytes(speed))
+		if eta, ok := p.etaLocked(speed); ok {
+			line += fmt.Sprintf(", ~%s left", eta)
+		}
+		fmt.Fprintln(p.out, line)
+		return
+	}
+	if time.Now().After(p.nextPlainBytes) {
+		p.nextPlainBytes = time.Now().Add(plainUnknownEvery)
+		fmt.Fprintf(p.out, "%s: %s %s elapsed, %s/s\n", p.label,
+			HumanBytes(p.written), elapsed.Round(time.Second), HumanBytes(p.speedLocked(elapsed)))
+	}
+}
+
+// plainDoneLocked prints the plain line that ends the transfer.
+func (p *ByteProgress) plainDoneLocked(err error) {
+	if !p.plain {
+		return
+	}
+	elapsed := time.Since(p.start)
+	if err != nil {
+		fmt.Fprintf(p.out, "%s: failed after %s (%s): %v\n", p.label, elapsed.Round(time.Second), HumanBytes(p.written), err)
+		return
+	}
+	fmt.Fprintf(p.out, "%s: done, %s in %s, %s/s\n", p.label,
+		HumanBytes(p.written), elapsed.Round(time.Second), HumanBytes(p.speedLocked(elapsed)))
+}
+
 func (p *ByteProgress) maybeLogLocked() {
 	elapsed := time.Since(p.start)
 	if p.total > 0 {
 		percent := int(float64(p.written) * 100 / float64(p.total))
 		if percent >= p.nextLogPct {
-			L().Info("progress", "task", p.label, "percent", percent, "written", humanBytes(p.written), "total", humanBytes(p.total), "elapsed", elapsed.Round(time.Second).String())
+			kv := []any{"task", p.label, "percent", percent, "written", HumanBytes(p.written), "total", HumanBytes(p.total), "elapsed", elapsed.Round(time.Second).String()}
+			if eta, ok := p.etaLocked(p.speedLocked(elapsed)); ok {
+				kv = append(kv, "eta", eta.String())
+			}
+			L().Info("progress", kv...)
 			for p.nextLogPct <= percent {
 				p.nextLogPct += 10
 			}
@@ -142,7 +268,7 @@ func (p *ByteProgress) maybeLogLocked() {
 	}
 
 	if time.Now().After(p.nextUnknown) {
-		L().Info("progress", "task", p.label, "written", humanBytes(p.written), "elapsed", elapsed.Round(time.Second).String())
+		L().Info("progress", "task", p.label, "written", HumanBytes(p.written), "elapsed", elapsed.Round(time.Second).String())
 		p.nextUnknown = time.Now().Add(10 * time.Second)
 	}
 }
@@ -150,15 +276,15 @@ func (p *ByteProgress) maybeLogLocked() {
 func (p *ByteProgress) logCompletionLocked(err error) {
 	elapsed := time.Since(p.start)
 	if err != nil {
-		L().Error("task failed", "task", p.label, "written", humanBytes(p.written), "elapsed", elapsed.Round(time.Millisecond).String(), "err", err)
+		L().Error("task failed", "task", p.label, "written", HumanBytes(p.written), "elapsed", elapsed.Round(time.Millisecond).String(), "err", err)
 		return
 	}
 
 	if p.total > 0 {
-		L().Info("task complete", "task", p.label, "written", humanBytes(p.written), "total", humanBytes(p.total), "elapsed", elapsed.Round(time.Millisecond).String())
+		L().Info("task complete", "task", p.label, "written", HumanBytes(p.written), "total", HumanBytes(p.total), "elapsed", elapsed.Round(time.Millisecond).String())
 		return
 	}
-	L().Info("task complete", "task", p.label, "written", humanBytes(p.written), "elapsed", elapsed.Round(time.Millisecond).String())
+	L().Info("task complete", "task", p.label, "written", HumanBytes(p.written), "elapsed", elapsed.Round(time.Millisecond).String())
 }
 
 // TimedProgress tracks waiting operations with a timeout budget.
@@ -175,16 +301,49 @@ type TimedProgress struct {
 	frame       int
 	interactive bool
 	out         io.Writer
+
+	// plain prints a line when the status changes and at each plainStepPct
+	// of the timeout, instead of drawing a bar. Guarded by mu.
+	plain        bool
+	plainStatus  string
+	nextPlainPct int
+	plainEnded   bool
 }
 
+// NewTimedProgress renders to stdout, drawing only when it is a terminal, or
+// printing plain lines when PlainProgress is set.
 func NewTimedProgress(label string, timeout time.Duration) *TimedProgress {
+	if PlainProgress() {
+		return newPlainTimedProgress(label, timeout, os.Stdout)
+	}
+	return NewTimedProgressTo(label, timeout, os.Stdout, term.IsTerminal(int(os.Stdout.Fd())))
+}
+
+// newPlainTimedProgress is NewTimedProgressTo in plain mode.
+func newPlainTimedProgress(label string, timeout time.Duration, out io.Writer) *TimedProgress {
+	tp := &TimedProgress{
+		label:        label,
+		timeout:      timeout,
+		start:        time.Now(),
+		done:         make(chan struct{}),
+		out:          out,
+		plain:        true,
+		nextPlainPct: plainStepPct,
+	}
+	go tp.loop()
+	return tp
+}
+
+// NewTimedProgressTo renders to out; nothing is drawn unless interactive. A
+// timeout <= 0 shows a spinner instead of a bar.
+func NewTimedProgressTo(label string, timeout time.Duration, out io.Writer, interactive bool) *TimedProgress {
 	tp := &TimedProgress{
 		label:       label,
 		timeout:     timeout,
 		start:       time.Now(),
 		done:        make(chan struct{}),
-		interactive: term.IsTerminal(int(os.Stdout.Fd())),
-		out:         os.Stdout,
+		interactive: interactive,
+		out:         out,
 	}
 
 	go tp.loop()
@@ -213,6 +372,7 @@ func (t *TimedProgress) SetStatus(status string) {
 
 func (t *TimedProgress) Finish() {
 	t.once.Do(func() { close(t.done) })
+	t.plainDone(nil)
 	t.render(true)
 	if t.interactive {
 		fmt.Fprint(t.out, "\n")
@@ -222,6 +382,7 @@ func (t *TimedProgress) Finish() {
 
 func (t *TimedProgress) Fail(err error) {
 	t.once.Do(func() { close(t.done) })
+	t.plainDone(err)
 	t.render(true)
 	if t.interactive {
 		fmt.Fprint(t.out, "\n")
@@ -232,6 +393,9 @@ func (t *TimedProgress) Fail(err error) {
 func (t *TimedProgress) render(force bool) {
 	t.mu.Lock()
 	status := t.status
+	if t.plain && !force {
+		t.plainStepLocked(status)
+	}
 	t.mu.Unlock()
 
 	if !t.interactive {
@@ -266,6 +430,55 @@ func (t *TimedProgress) render(force bool) {
 	fmt.Fprint(t.out, line)
 }
 
+// plainStepLocked prints a plain line when status has changed since the
+// last one, or the wait has used another plainStepPct of its timeout.
+func (t *TimedProgress) plainStepLocked(status string) {
+	if t.plainEnded {
+		return
+	}
+	elapsed := time.Since(t.start)
+	status = strings.TrimSpace(status)
+	step := false
+	if t.timeout > 0 {
+		pct := int(elapsed * 100 / t.timeout)
+		for t.nextPlainPct <= pct && t.nextPlainPct < 100 {
+			t.nextPlainPct += plainStepPct
+			step = true
+		}
+	}
+	if status != t.plainStatus {
+		t.plainStatus = status
+		step = true
+	}
+	if !step {
+		return
+	}
+	if status == "" {
+		status = "waiting"
+	}
+	if t.timeout > 0 {
+		fmt.Fprintf(t.out, "%s: %s (%s of %s)\n", t.label, status, elapsed.Round(time.Second), t.timeout.Round(time.Second))
+		return
+	}
+	fmt.Fprintf(t.out, "%s: %s (%s)\n", t.label, status, elapsed.Round(time.Second))
+}
+
+// plainDone prints the plain line that ends the wait.
+func (t *TimedProgress) plainDone(err error) {
+	if !t.plain {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.plainEnded = true
+	elapsed := time.Since(t.start).Round(time.Second)
+	if err != nil {
+		fmt.Fprintf(t.out, "%s: failed after %s: %v\n", t.label, elapsed, err)
+		return
+	}
+	fmt.Fprintf(t.out, "%s: done in %s\n", t.label, elapsed)
+}
+
 func renderBar(fraction float64, width int) string {
 	if width < 10 {
 		width = 10
@@ -279,10 +492,14 @@ func renderBar(fraction float64, width int) string {
 
 	full := min(int(fraction*float64(width)), width)
 	empty := width - full
-	return fmt.Sprintf("[%s%s] %3.0f%%", strings.Repeat("#", full), strings.Repeat("-", empty), fraction*100)
+	// Truncate like the bar (and the logged percent) so 100% means done; the
+	// epsilon keeps e.g. 0.29*100 = 28.999... from showing as 28%.
+	pct := int(fraction*100 + 1e-9)
+	return fmt.Sprintf("[%s%s] %3d%%", strings.Repeat("#", full), strings.Repeat("-", empty), pct)
 }
 
-func humanBytes(v int64) string {
+// HumanBytes formats a byte count with binary (KiB, MiB, …) units.
+func HumanBytes(v int64) string {
 	const unit = 1024
 	if v < unit {
 		return fmt.Sprintf("%dB", v)
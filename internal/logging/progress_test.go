@@ -0,0 +1,213 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderBar(t *testing.T) {
+	cases := []struct {
+		fraction float64
+		width    int
+		want     string
+	}{
+		{0, 10, "[----------]   0%"},
+		{0.5, 10, "[#####-----]  50%"},
+		{0.29, 10, "[##--------]  29%"},
+		// Truncated, not rounded: 99.6% is not done yet.
+		{0.996, 10, "[#########-]  99%"},
+		{1, 10, "[##########] 100%"},
+		{1.7, 10, "[##########] 100%"},
+		{-1, 10, "[----------]   0%"},
+		// Narrower than 10 is widened to 10.
+		{0.5, 4, "[#####-----]  50%"},
+	}
+	for _, c := range cases {
+		if got := renderBar(c.fraction, c.width); got != c.want {
+			t.Errorf("renderBar(%v, %d) = %q, want %q", c.fraction, c.width, got, c.want)
+		}
+	}
+}
+
+func TestByteProgressKnownTotal(t *testing.T) {
+	var out bytes.Buffer
+	p := NewByteProgressTo("base image", 2048, &out, true)
+	_, _ = p.Write(make([]byte, 1024))
+	p.Finish()
+
+	lines := strings.Split(out.String(), "\r")
+	if len(lines) != 3 || lines[0] != "" {
+		t.Fatalf("want two carriage-return renders, got %q", out.String())
+	}
+	want := "base image [" + strings.Repeat("#", 17) + strings.Repeat("-", 17) + "]  50% 1.0KiB/2.0KiB "
+	if !strings.HasPrefix(lines[1], want) || !strings.HasSuffix(lines[1], "/s ~1s left") {
+		t.Errorf("render = %q, want prefix %q and an ETA", lines[1], want)
+	}
+	if !strings.HasSuffix(lines[2], "\n") || !strings.HasSuffix(strings.TrimRight(lines[2], " \n"), " left") {
+		t.Errorf("final render should end the line: %q", lines[2])
+	}
+
+	// Writes after Finish are accepted but not drawn.
+	out.Reset()
+	_, _ = p.Write([]byte("x"))
+	if out.Len() != 0 {
+		t.Errorf("rendered after Finish: %q", out.String())
+	}
+}
+
+func TestByteProgressUnknownTotal(t *testing.T) {
+	var out bytes.Buffer
+	p := NewByteProgressTo("guest image", 0, &out, true)
+	_, _ = p.Write(make([]byte, 10))
+	p.Fail(errors.New("boom"))
+
+	lines := strings.Split(out.String(), "\r")
+	if len(lines) != 3 {
+		t.Fatalf("want two renders, got %q", out.String())
+	}
+	if !strings.HasPrefix(lines[1], "| guest image 10B downloaded ") {
+		t.Errorf("first spinner frame = %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "/ guest image 10B downloaded ") || !strings.HasSuffix(lines[2], "\n") {
+		t.Errorf("second spinner frame = %q", lines[2])
+	}
+}
+
+func TestByteProgressNonInteractive(t *testing.T) {
+	var out bytes.Buffer
+	p := NewByteProgressTo("copy", 100, &out, false)
+	_, _ = p.Write(make([]byte, 100))
+	p.Finish()
+	if out.Len() != 0 {
+		t.Errorf("non-interactive progress drew %q", out.String())
+	}
+}
+
+func TestTimedProgressSpinner(t *testing.T) {
+	var out bytes.Buffer
+	p := NewTimedProgressTo("waiting for incus", 0, &out, true)
+	p.SetStatus("  booting  ")
+	p.Finish()
+	if got := out.String(); !strings.HasPrefix(got, "\r") || !strings.HasSuffix(got, "waiting for incus booting\n") {
+		t.Errorf("spinner render = %q", got)
+	}
+}
+
+func TestTimedProgressBar(t *testing.T) {
+	var out bytes.Buffer
+	p := NewTimedProgressTo("incus", time.Hour, &out, true)
+	p.Finish()
+	// Elapsed is ~0s of the 1h budget.
+	want := "\rincus [" + strings.Repeat("-", 34) + "]   0% 0s/1h0m0s in progress\n"
+	if got := out.String(); got != want {
+		t.Errorf("bar render = %q, want %q", got, want)
+	}
+}
+
+func TestByteProgressResumed(t *testing.T) {
+	var out bytes.Buffer
+	p := NewByteProgressTo("base image", 4096, &out, true)
+	p.SetResumed(3072)
+	_, _ = p.Write(make([]byte, 1024))
+	p.Finish()
+
+	lines := strings.Split(out.String(), "\r")
+	if len(lines) != 3 {
+		t.Fatalf("want two renders, got %q", out.String())
+	}
+	if !strings.Contains(lines[2], "100% 4.0KiB/4.0KiB ") {
+		t.Errorf("final render = %q, want the resumed bytes counted", lines[2])
+	}
+}
+
+func TestPlainProgressEnv(t *testing.T) {
+	for val, want := range map[string]bool{"plain": true, " PLAIN ": true, "": false, "bar": false} {
+		t.Setenv(ProgressEnvVar, val)
+		if got := PlainProgress(); got != want {
+			t.Errorf("PlainProgress() with %q = %v, want %v", val, got, want)
+		}
+	}
+}
+
+func TestByteProgressPlain(t *testing.T) {
+	var out bytes.Buffer
+	p := NewByteProgressTo("base image", 4096, &out, false)
+	p.plain = true
+	_, _ = p.Write(make([]byte, 1024))
+	_, _ = p.Write(make([]byte, 100))  // 27%: no new step
+	_, _ = p.Write(make([]byte, 1900)) // 73%: one line, not two
+	_, _ = p.Write(make([]byte, 1072)) // 100%: left to Finish
+	p.Finish()
+
+	got := out.String()
+	if strings.ContainsAny(got, "\r\x1b") {
+		t.Errorf("plain output has control characters: %q", got)
+	}
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want 25%%, 73%% and done lines, got %q", got)
+	}
+	for i, prefix := range []string{"base image: 25% (1.0KiB/4.0KiB) ", "base image: 73% (3.0KiB/4.0KiB) ", "base image: done, 4.0KiB in "} {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("line %d = %q, want prefix %q", i, lines[i], prefix)
+		}
+	}
+	if !strings.HasSuffix(lines[1], "/s, ~1s left") || !strings.HasSuffix(lines[2], "/s") {
+		t.Errorf("want an ETA on progress lines only, got %q", got)
+	}
+}
+
+func TestByteProgressETA(t *testing.T) {
+	cases := []struct {
+		total, written, speed int64
+		want                  time.Duration
+		ok                    bool
+	}{
+		{total: 1000, written: 250, speed: 5, want: 2*time.Minute + 30*time.Second, ok: true},
+		{total: 1000, written: 999, speed: 1000, want: time.Second, ok: true}, // never "~0s"
+		{total: 0, written: 250, speed: 5},                                    // unknown total
+		{total: 1000, written: 1000, speed: 5},                                // done
+		{total: 1000, written: 0, speed: 0},                                   // not started
+	}
+	for _, c := range cases {
+		p := &ByteProgress{total: c.total, written: c.written}
+		got, ok := p.etaLocked(c.speed)
+		if got != c.want || ok != c.ok {
+			t.Errorf("etaLocked(%d) with %d/%d = %v, %v; want %v, %v", c.speed, c.written, c.total, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestByteProgressUnknownTotalNoETA(t *testing.T) {
+	var out bytes.Buffer
+	p := NewByteProgressTo("guest image", 0, &out, true)
+	_, _ = p.Write(make([]byte, 10))
+	p.Finish()
+	if strings.Contains(out.String(), "left") {
+		t.Errorf("spinner render has an ETA: %q", out.String())
+	}
+}
+
+func TestTimedProgressPlain(t *testing.T) {
+	var out bytes.Buffer
+	p := newPlainTimedProgress("incus", time.Minute, &out)
+	p.mu.Lock()
+	p.start = time.Now().Add(-31 * time.Second)
+	p.mu.Unlock()
+
+	p.SetStatus("booting")
+	p.render(false) // status change and 50% of the budget: one line
+	p.render(false) // nothing new
+	p.SetStatus("ready")
+	p.render(false)
+	p.Fail(errors.New("boom"))
+	p.render(false) // after the end: silent
+
+	want := "incus: booting (31s of 1m0s)\nincus: ready (31s of 1m0s)\nincus: failed after 31s: boom\n"
+	if got := out.String(); got != want {
+		t.Errorf("plain output = %q, want %q", got, want)
+	}
+}
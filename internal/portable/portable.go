@@ -0,0 +1,282 @@
+// Package portable moves a provisioned VM between machines: Export streams the
+// VM's disk and identity into a zstd-compressed tar, and Import unpacks one
+// into an empty VM directory after verifying it against the manifest stored in
+// the archive.
+//
+// Host-specific state is deliberately not carried over. The runtime metadata
+// (the NIC's MAC address) is archived for reference but not restored, so the
+// importing host generates a fresh MAC; the effective config is likewise only
+// informational, since ports and paths are resolved on the importing host
+// (`br import` moves a port that is taken there).
+package portable
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stuffbucket/bladerunner/internal/util"
+)
+
+const (
+	// FormatVersion is the archive layout version recorded in the manifest.
+	// Import rejects archives from a newer format.
+	FormatVersion = 1
+
+	// ManifestName is the last entry of every export archive.
+	ManifestName = "bladerunner-export.json"
+
+	requiredFile = "disk.raw"
+	metadataFile = "runtime-metadata.json"
+	configFile   = "effective-config.json"
+)
+
+// Files lists the VM directory entries captured in an export, relative to the
+// VM directory. disk.raw is required; the rest are included when present.
+var Files = []string{
+	"disk.raw",
+	"efi-vars.bin",
+	"machine-id.bin",
+	metadataFile,
+	configFile,
+}
+
+// restored reports whether Import puts an archived file into the VM
+// directory. See the package doc for why the others stay behind.
+func restored(name string) bool {
+	return name != metadataFile && name != configFile
+}
+
+// Manifest describes an export archive and lets Import verify it.
+type Manifest struct {
+	FormatVersion int         `json:"format_version"`
+	Created       time.Time   `json:"created"`
+	Files         []FileEntry `json:"files"`
+	// Config is the exporting host's effective config, when one was present.
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// FileEntry is one archived file.
+type FileEntry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Export writes the VM in vmDir to w as a zstd-compressed tar. Zero runs in
+// the sparse disk image compress to almost nothing, so the archive is about
+// the size of the data the guest has written. The manifest, holding each
+// file's checksum, is written last. The caller must ensure the VM is stopped.
+func Export(vmDir string, w io.Writer, now time.Time) (Manifest, error) {
+	if _, err := os.Stat(filepath.Join(vmDir, requiredFile)); err != nil {
+		return Manifest{}, fmt.Errorf("nothing to export: %w", err)
+	}
+
+	zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	if err != nil {
+		return Manifest{}, err
+	}
+	tw := tar.NewWriter(zw)
+
+	m := Manifest{FormatVersion: FormatVersion, Created: now.UTC()}
+	for _, name := range Files {
+		entry, ok, err := addFile(tw, vmDir, name)
+		if err != nil {
+			_ = zw.Close()
+			return Manifest{}, err
+		}
+		if ok {
+			m.Files = append(m.Files, entry)
+		}
+	}
+	if b, err := os.ReadFile(filepath.Join(vmDir, configFile)); err == nil && json.Valid(b) {
+		m.Config = b
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		_ = zw.Close()
+		return Manifest{}, err
+	}
+	hdr := &tar.Header{Name: ManifestName, Mode: 0o644, Size: int64(len(b)), ModTime: m.Created, Format: tar.FormatPAX}
+	if err := tw.WriteHeader(hdr); err != nil {
+		_ = zw.Close()
+		return Manifest{}, fmt.Errorf("write manifest: %w", err)
+	}
+	if _, err := tw.Write(b); err != nil {
+		_ = zw.Close()
+		return Manifest{}, fmt.Errorf("write manifest: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		_ = zw.Close()
+		return Manifest{}, fmt.Errorf("finish export archive: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return Manifest{}, fmt.Errorf("finish export archive: %w", err)
+	}
+	return m, nil
+}
+
+func addFile(tw *tar.Writer, vmDir, name string) (FileEntry, bool, error) {
+	src, err := os.Open(filepath.Join(vmDir, name))
+	if errors.Is(err, os.ErrNotExist) && name != requiredFile {
+		return FileEntry{}, false, nil
+	}
+	if err != nil {
+		return FileEntry{}, false, fmt.Errorf("open %s: %w", name, err)
+	}
+	defer func() { _ = src.Close() }()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return FileEntry{}, false, fmt.Errorf("stat %s: %w", name, err)
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(fi.Mode().Perm()),
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		Format:  tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return FileEntry{}, false, fmt.Errorf("archive %s: %w", name, err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, h), src); err != nil {
+		return FileEntry{}, false, fmt.Errorf("archive %s: %w", name, err)
+	}
+	return FileEntry{Name: name, Size: fi.Size(), SHA256: hex.EncodeToString(h.Sum(nil))}, true, nil
+}
+
+// Import unpacks an export archive into vmDir, which must not already hold a
+// disk. Files are extracted to a staging directory inside vmDir and only
+// moved into place once every checksum matches the manifest, so a truncated
+// or corrupt archive leaves vmDir untouched.
+func Import(r io.Reader, vmDir string) (Manifest, error) {
+	if _, err := os.Stat(filepath.Join(vmDir, requiredFile)); err == nil {
+		return Manifest{}, fmt.Errorf("%s already holds a VM disk; remove it first ('br reset')", vmDir)
+	}
+	if err := os.MkdirAll(vmDir, 0o755); err != nil {
+		return Manifest{}, fmt.Errorf("create vm dir: %w", err)
+	}
+	staging, err := os.MkdirTemp(vmDir, ".import-*")
+	if err != nil {
+		return Manifest{}, fmt.Errorf("create staging dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read archive: %w", err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	got := make(map[string]FileEntry, len(Files))
+	var m *Manifest
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("read archive: %w", err)
+		}
+		switch {
+		case hdr.Name == ManifestName:
+			m = &Manifest{}
+			if err := json.NewDecoder(io.LimitReader(tr, 1<<20)).Decode(m); err != nil {
+				return Manifest{}, fmt.Errorf("read manifest: %w", err)
+			}
+		case isExportFile(hdr.Name) && hdr.Typeflag == tar.TypeReg:
+			if _, dup := got[hdr.Name]; dup {
+				return Manifest{}, fmt.Errorf("archive has %s twice", hdr.Name)
+			}
+			entry, err := extractFile(staging, hdr, tr)
+			if err != nil {
+				return Manifest{}, err
+			}
+			got[hdr.Name] = entry
+		default:
+			return Manifest{}, fmt.Errorf("unexpected archive entry %q", hdr.Name)
+		}
+	}
+
+	if err := verify(m, got); err != nil {
+		return Manifest{}, err
+	}
+	for _, name := range Files {
+		if _, ok := got[name]; !ok || !restored(name) {
+			continue
+		}
+		if err := os.Rename(filepath.Join(staging, name), filepath.Join(vmDir, name)); err != nil {
+			return Manifest{}, fmt.Errorf("install %s: %w", name, err)
+		}
+	}
+	return *m, nil
+}
+
+// verify checks the extracted files against the archive's manifest.
+func verify(m *Manifest, got map[string]FileEntry) error {
+	if m == nil {
+		return errors.New("archive has no manifest; not a bladerunner export")
+	}
+	if m.FormatVersion < 1 || m.FormatVersion > FormatVersion {
+		return fmt.Errorf("unsupported export format %d (this build reads up to %d)", m.FormatVersion, FormatVersion)
+	}
+	if _, ok := got[requiredFile]; !ok {
+		return fmt.Errorf("archive is missing %s", requiredFile)
+	}
+	if len(m.Files) != len(got) {
+		return fmt.Errorf("archive has %d files, manifest lists %d", len(got), len(m.Files))
+	}
+	for _, want := range m.Files {
+		have, ok := got[want.Name]
+		if !ok {
+			return fmt.Errorf("archive is missing %s", want.Name)
+		}
+		if have.Size != want.Size || have.SHA256 != want.SHA256 {
+			return fmt.Errorf("%s does not match the manifest checksum; the archive is corrupt", want.Name)
+		}
+	}
+	return nil
+}
+
+func isExportFile(name string) bool {
+	for _, f := range Files {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+func extractFile(dir string, hdr *tar.Header, r io.Reader) (FileEntry, error) {
+	dst, err := os.OpenFile(filepath.Join(dir, hdr.Name), os.O_CREATE|os.O_EXCL|os.O_RDWR, os.FileMode(hdr.Mode).Perm())
+	if err != nil {
+		return FileEntry{}, fmt.Errorf("extract %s: %w", hdr.Name, err)
+	}
+	h := sha256.New()
+	// Skip zero runs so the imported disk.raw is sparse again.
+	if err := util.WriteSparse(dst, io.TeeReader(r, h)); err != nil {
+		_ = dst.Close()
+		return FileEntry{}, fmt.Errorf("extract %s: %w", hdr.Name, err)
+	}
+	fi, err := dst.Stat()
+	if err != nil {
+		_ = dst.Close()
+		return FileEntry{}, fmt.Errorf("extract %s: %w", hdr.Name, err)
+	}
+	if err := dst.Close(); err != nil {
+		return FileEntry{}, fmt.Errorf("extract %s: %w", hdr.Name, err)
+	}
+	return FileEntry{Name: hdr.Name, Size: fi.Size(), SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
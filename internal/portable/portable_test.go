@@ -0,0 +1,207 @@
+package portable
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var exportTime = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func writeVMFiles(t *testing.T, dir string, contents map[string]string) {
+	t.Helper()
+	for name, body := range contents {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func exportFixture(t *testing.T) []byte {
+	t.Helper()
+	src := t.TempDir()
+	writeVMFiles(t, src, map[string]string{
+		"disk.raw":              "disk" + strings.Repeat("\x00", 200<<10) + "tail",
+		"efi-vars.bin":          "efi",
+		"machine-id.bin":        "mid",
+		"runtime-metadata.json": `{"mac_address":"52:54:00:aa:bb:cc"}`,
+		"effective-config.json": `{"ssh_port":6022}`,
+	})
+	var buf bytes.Buffer
+	if _, err := Export(src, &buf, exportTime); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	archive := exportFixture(t)
+	if len(archive) > 64<<10 {
+		t.Errorf("archive is %d bytes; zero runs should compress away", len(archive))
+	}
+
+	dst := filepath.Join(t.TempDir(), "vm")
+	m, err := Import(bytes.NewReader(archive), dst)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if !m.Created.Equal(exportTime) || len(m.Files) != 5 {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+	var cfg bytes.Buffer
+	if err := json.Compact(&cfg, m.Config); err != nil || cfg.String() != `{"ssh_port":6022}` {
+		t.Errorf("manifest config = %s (%v)", m.Config, err)
+	}
+
+	disk, err := os.ReadFile(filepath.Join(dst, "disk.raw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(disk), "disk") || !strings.HasSuffix(string(disk), "tail") || len(disk) != 200<<10+8 {
+		t.Errorf("disk.raw not restored intact (%d bytes)", len(disk))
+	}
+	for _, name := range []string{"efi-vars.bin", "machine-id.bin"} {
+		if _, err := os.Stat(filepath.Join(dst, name)); err != nil {
+			t.Errorf("%s not restored: %v", name, err)
+		}
+	}
+	// Host-specific state stays behind so the MAC and ports are regenerated.
+	for _, name := range []string{"runtime-metadata.json", "effective-config.json"} {
+		if _, err := os.Stat(filepath.Join(dst, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be restored (err=%v)", name, err)
+		}
+	}
+	entries, _ := os.ReadDir(dst)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".import-") {
+			t.Errorf("staging dir %s left behind", e.Name())
+		}
+	}
+}
+
+func TestExportRequiresDisk(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Export(t.TempDir(), &buf, exportTime); err == nil {
+		t.Fatal("Export without disk.raw succeeded")
+	}
+}
+
+func TestImportRefusesExistingDisk(t *testing.T) {
+	archive := exportFixture(t)
+	dst := t.TempDir()
+	writeVMFiles(t, dst, map[string]string{"disk.raw": "mine"})
+	if _, err := Import(bytes.NewReader(archive), dst); err == nil {
+		t.Fatal("Import over an existing disk succeeded")
+	}
+	if b, _ := os.ReadFile(filepath.Join(dst, "disk.raw")); string(b) != "mine" {
+		t.Errorf("existing disk was modified: %q", b)
+	}
+}
+
+// rewrite decodes an export, lets edit change its entries, and re-encodes it.
+func rewrite(t *testing.T, archive []byte, edit func(name string, body []byte) (string, []byte)) []byte {
+	t.Helper()
+	zr, err := zstd.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	var out bytes.Buffer
+	zw, _ := zstd.NewWriter(&out)
+	tw := tar.NewWriter(zw)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		var body bytes.Buffer
+		if _, err := body.ReadFrom(tr); err != nil {
+			t.Fatal(err)
+		}
+		name, b := edit(hdr.Name, body.Bytes())
+		if name == "" {
+			continue
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(b)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = tw.Close()
+	_ = zw.Close()
+	return out.Bytes()
+}
+
+func TestImportRejectsBadArchives(t *testing.T) {
+	archive := exportFixture(t)
+	cases := []struct {
+		name string
+		edit func(string, []byte) (string, []byte)
+		want string
+	}{
+		{"corrupt disk", func(n string, b []byte) (string, []byte) {
+			if n == "disk.raw" {
+				b = append([]byte("DISK"), b[4:]...)
+			}
+			return n, b
+		}, "checksum"},
+		{"unexpected entry", func(n string, b []byte) (string, []byte) {
+			if n == "efi-vars.bin" {
+				return "../../etc/passwd", b
+			}
+			return n, b
+		}, "unexpected archive entry"},
+		{"missing manifest", func(n string, b []byte) (string, []byte) {
+			if n == ManifestName {
+				return "", nil
+			}
+			return n, b
+		}, "no manifest"},
+		{"dropped file", func(n string, b []byte) (string, []byte) {
+			if n == "machine-id.bin" {
+				return "", nil
+			}
+			return n, b
+		}, "manifest lists"},
+		{"future format", func(n string, b []byte) (string, []byte) {
+			if n == ManifestName {
+				var m Manifest
+				if err := json.Unmarshal(b, &m); err != nil {
+					t.Fatal(err)
+				}
+				m.FormatVersion = FormatVersion + 1
+				b, _ = json.Marshal(m)
+			}
+			return n, b
+		}, "unsupported export format"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := t.TempDir()
+			_, err := Import(bytes.NewReader(rewrite(t, archive, tc.edit)), dst)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Import err = %v, want %q", err, tc.want)
+			}
+			if _, err := os.Stat(filepath.Join(dst, "disk.raw")); !os.IsNotExist(err) {
+				t.Errorf("disk.raw installed from a rejected archive")
+			}
+		})
+	}
+}
+
+func TestImportRejectsGarbage(t *testing.T) {
+	if _, err := Import(strings.NewReader("not an archive"), t.TempDir()); err == nil {
+		t.Fatal("Import of garbage succeeded")
+	}
+}
@@ -0,0 +1,124 @@
+package provision
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// cloudConfigHeader marks user-data as a cloud-config document.
+const cloudConfigHeader = "#cloud-config\n"
+
+// CloudConfig is the #cloud-config user-data bladerunner seeds the guest
+// with. Field order is output order; optional sections are pointers or
+// slices that drop out when empty.
+type CloudConfig struct {
+	Hostname       string      `yaml:"hostname"`
+	ManageEtcHosts bool        `yaml:"manage_etc_hosts"`
+	Apt            *AptConfig  `yaml:"apt,omitempty"`
+	Users          []User      `yaml:"users,omitempty"`
+	ChPasswd       *ChPasswd   `yaml:"chpasswd,omitempty"`
+	WriteFiles     []WriteFile `yaml:"write_files,omitempty"`
+	RandomSeed     *RandomSeed `yaml:"random_seed,omitempty"`
+	BootCmd        []Command   `yaml:"bootcmd,omitempty"`
+	GrowPart       *GrowPart   `yaml:"growpart,omitempty"`
+	ResizeRootFS   bool        `yaml:"resize_rootfs"`
+	RunCmd         []Command   `yaml:"runcmd,omitempty"`
+	PowerState     *PowerState `yaml:"power_state,omitempty"`
+}
+
+// AptConfig points apt at a mirror for the primary and security archives.
+type AptConfig struct {
+	Primary  []AptArchive `yaml:"primary"`
+	Security []AptArchive `yaml:"security"`
+}
+
+// AptArchive is one apt archive entry.
+type AptArchive struct {
+	Arches []string `yaml:"arches,flow"`
+	URI    string   `yaml:"uri"`
+}
+
+// User is a users entry. Default selects the image's default user, which
+// cloud-init spells as the bare string "default"; the other fields are then
+// ignored.
+type User struct {
+	Default           bool     `yaml:"-"`
+	Name              string   `yaml:"name"`
+	Shell             string   `yaml:"shell,omitempty"`
+	Sudo              string   `yaml:"sudo,omitempty"`
+	Groups            []string `yaml:"groups,flow,omitempty"`
+	LockPasswd        bool     `yaml:"lock_passwd"`
+	SSHAuthorizedKeys []string `yaml:"ssh_authorized_keys,omitempty"`
+}
+
+// MarshalYAML emits the default user as "default".
+func (u User) MarshalYAML() (interface{}, error) {
+	if u.Default {
+		return "default", nil
+	}
+	type plain User
+	return plain(u), nil
+}
+
+// ChPasswd sets user passwords.
+type ChPasswd struct {
+	Expire bool           `yaml:"expire"`
+	Users  []ChPasswdUser `yaml:"users"`
+}
+
+// ChPasswdUser is one chpasswd entry.
+type ChPasswdUser struct {
+	Name     string `yaml:"name"`
+	Password string `yaml:"password"`
+	Type     string `yaml:"type"`
+}
+
+// WriteFile is a write_files entry. cloud-init applies these before bootcmd
+// and runcmd.
+type WriteFile struct {
+	Path        string `yaml:"path"`
+	Permissions string `yaml:"permissions"`
+	Content     string `yaml:"content"`
+}
+
+// RandomSeed is extra entropy cloud-init mixes into the guest pool.
+type RandomSeed struct {
+	File     string `yaml:"file"`
+	Encoding string `yaml:"encoding"`
+	Data     string `yaml:"data"`
+}
+
+// GrowPart grows partitions to fill the disk.
+type GrowPart struct {
+	Mode                   string   `yaml:"mode"`
+	Devices                []string `yaml:"devices,flow"`
+	IgnoreGrowrootDisabled bool     `yaml:"ignore_growroot_disabled"`
+}
+
+// PowerState is cloud-init's power_state module: once the final stage is done
+// it reboots (or powers off) the guest, but only when Condition, run as an
+// argv, exits 0.
+type PowerState struct {
+	Mode      string  `yaml:"mode"`
+	Message   string  `yaml:"message,omitempty"`
+	Timeout   int     `yaml:"timeout,omitempty"`
+	Condition Command `yaml:"condition,omitempty"`
+}
+
+// Command is a bootcmd/runcmd entry, run as an argv without a shell.
+type Command []string
+
+// ShellCommand wraps script in `sh -c`.
+func ShellCommand(script string) Command {
+	return Command{"sh", "-c", script}
+}
+
+// Marshal renders c as user-data, header included.
+func (c *CloudConfig) Marshal() (string, error) {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("marshal cloud-config: %w", err)
+	}
+	return cloudConfigHeader + string(b), nil
+}
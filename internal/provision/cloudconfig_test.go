@@ -0,0 +1,164 @@
+package provision
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+// parseUserData round-trips rendered user-data through a YAML parser, so the
+// assertions below see what cloud-init will, not the builder's intent.
+func parseUserData(t *testing.T, userData string) map[string]interface{} {
+	t.Helper()
+	if !strings.HasPrefix(userData, cloudConfigHeader) {
+		t.Fatalf("user-data does not start with %q", cloudConfigHeader)
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal([]byte(userData), &doc); err != nil {
+		t.Fatalf("user-data is not valid YAML: %v\n---\n%s", err, userData)
+	}
+	return doc
+}
+
+func TestCloudConfig_Users(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	doc := parseUserData(t, renderUserData(t, cfg, ""))
+
+	users, ok := doc["users"].([]interface{})
+	if !ok || len(users) != 2 {
+		t.Fatalf("users = %#v, want default plus the ssh user", doc["users"])
+	}
+	if users[0] != "default" {
+		t.Errorf("users[0] = %#v, want \"default\"", users[0])
+	}
+	u, _ := users[1].(map[interface{}]interface{})
+	if u["name"] != cfg.SSHUser || u["sudo"] != "ALL=(ALL) NOPASSWD:ALL" || u["lock_passwd"] != false {
+		t.Errorf("ssh user = %#v", u)
+	}
+	if keys, _ := u["ssh_authorized_keys"].([]interface{}); len(keys) != 1 || keys[0] != cfg.SSHPublicKey {
+		t.Errorf("ssh_authorized_keys = %#v, want [%s]", u["ssh_authorized_keys"], cfg.SSHPublicKey)
+	}
+	if groups, _ := u["groups"].([]interface{}); len(groups) != 1 || groups[0] != "sudo" {
+		t.Errorf("groups = %#v, want [sudo]", u["groups"])
+	}
+}
+
+func TestCloudConfig_DistroFedora(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	cfg.Distro = config.DistroFedora
+	userData := renderUserData(t, cfg, "")
+	doc := parseUserData(t, userData)
+
+	users, _ := doc["users"].([]interface{})
+	u, _ := users[1].(map[interface{}]interface{})
+	if groups, _ := u["groups"].([]interface{}); len(groups) != 1 || groups[0] != "wheel" {
+		t.Errorf("groups = %#v, want [wheel]", u["groups"])
+	}
+	if _, ok := doc["apt"]; ok {
+		t.Errorf("apt = %#v, want no Debian mirror for Fedora", doc["apt"])
+	}
+	if !strings.Contains(userData, "BR_PKG='dnf'") {
+		t.Error("bootstrap does not pin BR_PKG to dnf")
+	}
+}
+
+func TestCloudConfig_WriteFilesRoundTrip(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	cfg.IncusProfile = "name: dev\nconfig:\n  limits.cpu: \"2\"\n"
+	cfg.DNS = []string{"1.1.1.1"}
+	cert := "-----BEGIN CERTIFICATE-----\nFAKE\n-----END CERTIFICATE-----\n"
+
+	want := NewCloudConfig(cfg, cert).WriteFiles
+	var got struct {
+		WriteFiles []WriteFile `yaml:"write_files"`
+	}
+	if err := yaml.Unmarshal([]byte(renderUserData(t, cfg, cert)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.WriteFiles) != len(want) {
+		t.Fatalf("%d write_files after round trip, want %d", len(got.WriteFiles), len(want))
+	}
+	for i := range want {
+		if got.WriteFiles[i] != want[i] {
+			t.Errorf("write_files[%d] (%s) changed in marshaling:\n got %q\nwant %q", i, want[i].Path, got.WriteFiles[i].Content, want[i].Content)
+		}
+	}
+
+	paths := make([]string, 0, len(want))
+	for _, f := range want {
+		paths = append(paths, f.Path)
+	}
+	for _, p := range []string{"/var/lib/bladerunner/host-client.crt", "/usr/local/sbin/bladerunner-bootstrap.sh", "/etc/default/grub.d/99_bladerunner.cfg", consoleRebootScriptPath, incusProfileGuestPath, resolvedDropInPath} {
+		if !slices.Contains(paths, p) {
+			t.Errorf("write_files missing %s (have %v)", p, paths)
+		}
+	}
+}
+
+// Scripts must stay readable in the seed: a literal block, not one long
+// escaped string.
+func TestCloudConfig_ScriptsAreLiteralBlocks(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	cfg.ShareDir = "/tmp/share"
+	cfg.FastEntropy = true
+	userData := renderUserData(t, cfg, "")
+	for _, want := range []string{
+		"- path: /usr/local/sbin/bladerunner-bootstrap.sh\n  permissions: \"0755\"\n  content: |\n    #!/usr/bin/env bash\n",
+		"- path: " + consoleRebootScriptPath + "\n  permissions: \"0755\"\n  content: |\n    #!/bin/sh\n",
+	} {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing literal block %q", want)
+		}
+	}
+}
+
+func TestCloudConfig_BootAndRunCmd(t *testing.T) {
+	t.Parallel()
+	cc := NewCloudConfig(testConfig(), "")
+	if len(cc.BootCmd) != 2 {
+		t.Errorf("default bootcmd has %d entries, want update-grub and the console reboot: %v", len(cc.BootCmd), cc.BootCmd)
+	}
+	if want := []Command{{"bash", "/usr/local/sbin/bladerunner-bootstrap.sh"}}; !slices.EqualFunc(cc.RunCmd, want, slices.Equal) {
+		t.Errorf("runcmd = %v, want %v", cc.RunCmd, want)
+	}
+
+	doc := parseUserData(t, renderUserData(t, testConfig(), ""))
+	runcmd, _ := doc["runcmd"].([]interface{})
+	if len(runcmd) != 1 {
+		t.Fatalf("runcmd = %#v", doc["runcmd"])
+	}
+	if argv, _ := runcmd[0].([]interface{}); len(argv) != 2 || argv[0] != "bash" {
+		t.Errorf("runcmd[0] = %#v, want an argv list", runcmd[0])
+	}
+}
+
+func TestCloudConfig_GrowPart(t *testing.T) {
+	t.Parallel()
+	doc := parseUserData(t, renderUserData(t, testConfig(), ""))
+	gp, _ := doc["growpart"].(map[interface{}]interface{})
+	devices, _ := gp["devices"].([]interface{})
+	if gp["mode"] != "auto" || len(devices) != 1 || devices[0] != "/" || gp["ignore_growroot_disabled"] != false {
+		t.Errorf("growpart = %#v", gp)
+	}
+	if doc["resize_rootfs"] != true {
+		t.Errorf("resize_rootfs = %#v, want true", doc["resize_rootfs"])
+	}
+	if doc["hostname"] != "bladerunner-test" || doc["manage_etc_hosts"] != true {
+		t.Errorf("hostname/manage_etc_hosts = %#v/%#v", doc["hostname"], doc["manage_etc_hosts"])
+	}
+}
+
+func TestCloudConfig_OptionalSectionsOmitted(t *testing.T) {
+	t.Parallel()
+	doc := parseUserData(t, renderUserData(t, testConfig(), ""))
+	if _, ok := doc["random_seed"]; ok {
+		t.Error("random_seed emitted without FastEntropy")
+	}
+}
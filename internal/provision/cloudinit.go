@@ -3,6 +3,8 @@ package provision
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"os/exec"
@@ -15,74 +17,107 @@ import (
 	"github.com/stuffbucket/bladerunner/internal/util"
 )
 
-func BuildCloudInit(cfg *config.Config, clientCertPEM string) (string, string) {
-	bootstrapScript := renderBootstrapScript(cfg)
+// BuildCloudInit renders the cloud-init user-data and meta-data for cfg.
+func BuildCloudInit(cfg *config.Config, clientCertPEM string) (string, string, error) {
+	userData, err := NewCloudConfig(cfg, clientCertPEM).Marshal()
+	if err != nil {
+		return "", "", err
+	}
+	metaData := fmt.Sprintf("instance-id: bladerunner-%s\nlocal-hostname: %s\n", cfg.Name, cfg.Hostname)
+	return userData, metaData, nil
+}
 
-	var b strings.Builder
-	b.WriteString("#cloud-config\n")
-	fmt.Fprintf(&b, "hostname: %s\n", cfg.Hostname)
-	b.WriteString("manage_etc_hosts: true\n")
-	aptMirror := config.DefaultAptMirrorURI(cfg.Arch)
-	b.WriteString("apt:\n")
-	b.WriteString("  primary:\n")
-	b.WriteString("    - arches: [default]\n")
-	fmt.Fprintf(&b, "      uri: %s\n", aptMirror)
-	b.WriteString("  security:\n")
-	b.WriteString("    - arches: [default]\n")
-	fmt.Fprintf(&b, "      uri: %s\n", aptMirror)
-	b.WriteString("users:\n")
-	b.WriteString("  - default\n")
-	fmt.Fprintf(&b, "  - name: %s\n", cfg.SSHUser)
-	b.WriteString("    shell: /bin/bash\n")
-	b.WriteString("    sudo: ALL=(ALL) NOPASSWD:ALL\n")
-	b.WriteString("    groups: [sudo]\n")
-	b.WriteString("    lock_passwd: false\n")
-	b.WriteString("    ssh_authorized_keys:\n")
-	fmt.Fprintf(&b, "      - %s\n", cfg.SSHPublicKey)
-	b.WriteString("chpasswd:\n")
-	b.WriteString("  expire: false\n")
-	b.WriteString("  users:\n")
-	fmt.Fprintf(&b, "    - name: %s\n", cfg.SSHUser)
-	b.WriteString("      password: bladerunner\n")
-	b.WriteString("      type: text\n")
-	b.WriteString("write_files:\n")
-	b.WriteString("  - path: /var/lib/bladerunner/host-client.crt\n")
-	b.WriteString("    permissions: '0644'\n")
-	b.WriteString("    content: |\n")
-	b.WriteString(indent(clientCertPEM, 6))
-	b.WriteString("  - path: /usr/local/sbin/bladerunner-bootstrap.sh\n")
-	b.WriteString("    permissions: '0755'\n")
-	b.WriteString("    content: |\n")
-	b.WriteString(indent(bootstrapScript, 6))
-	// Drop-in grub override: routes the kernel's own console to hvc0 (the
-	// VZ-captured serial device) on every subsequent natural boot. cloud-init
-	// applies write_files before bootcmd/runcmd, so the file is in place when
-	// update-grub runs below. This file APPENDS to GRUB_CMDLINE_LINUX rather than
+// NewCloudConfig builds the user-data document for cfg: the SSH user, the
+// host client certificate and bootstrap script, the kernel console drop-in,
+// and the optional Incus profile, DNS and entropy pieces.
+func NewCloudConfig(cfg *config.Config, clientCertPEM string) *CloudConfig {
+	c := &CloudConfig{
+		Hostname:       cfg.Hostname,
+		ManageEtcHosts: true,
+		Users: []User{
+			{Default: true},
+			{
+				Name:              cfg.SSHUser,
+				Shell:             "/bin/bash",
+				Sudo:              "ALL=(ALL) NOPASSWD:ALL",
+				Groups:            []string{config.DistroAdminGroup(cfg.Distro)},
+				SSHAuthorizedKeys: []string{cfg.SSHPublicKey},
+			},
+		},
+		ChPasswd: &ChPasswd{
+			Users: []ChPasswdUser{{Name: cfg.SSHUser, Password: "bladerunner", Type: "text"}},
+		},
+		GrowPart: &GrowPart{
+			Mode:    "auto",
+			Devices: []string{"/"},
+		},
+		ResizeRootFS: true,
+		RunCmd:       []Command{{"bash", "/usr/local/sbin/bladerunner-bootstrap.sh"}},
+	}
+	// Ubuntu and Fedora images ship their own mirrors; only Debian-based
+	// images are pointed at the Debian one.
+	if config.DistroUsesDebianMirror(cfg.Distro) {
+		aptMirror := []AptArchive{{Arches: []string{"default"}, URI: config.DefaultAptMirrorURI(cfg.Arch)}}
+		c.Apt = &AptConfig{Primary: aptMirror, Security: aptMirror}
+	}
+
+	// Drop-in grub override: routes the kernel's own console (Config.KernelConsole,
+	// hvc0 by default — the VZ-captured serial device) on every boot after
+	// update-grub runs in bootcmd. It APPENDS to GRUB_CMDLINE_LINUX rather than
 	// replacing it, so any existing distro defaults are preserved.
-	b.WriteString("  - path: /etc/default/grub.d/99_bladerunner.cfg\n")
-	b.WriteString("    permissions: '0644'\n")
-	b.WriteString("    content: |\n")
-	b.WriteString("      GRUB_CMDLINE_LINUX=\"$GRUB_CMDLINE_LINUX console=hvc0 console=tty0\"\n")
-	b.WriteString("bootcmd:\n")
-	b.WriteString("  # Regenerate grub config so the 99_bladerunner.cfg drop-in (written by\n")
-	b.WriteString("  # write_files above, which cloud-init applies before bootcmd) lands in\n")
-	b.WriteString("  # /boot/grub/grub.cfg, routing the KERNEL's console to hvc0 from the next\n")
-	b.WriteString("  # natural boot onward. We deliberately do NOT force a first-boot reboot:\n")
-	b.WriteString("  # the bootstrap's br_stage breadcrumbs write straight to /dev/hvc0, which\n")
-	b.WriteString("  # is present in late userspace regardless of the kernel console= cmdline,\n")
-	b.WriteString("  # so first-boot progress is already visible. The old bootcmd reboot never\n")
-	b.WriteString("  # fired reliably and doubled cold-boot time (#57).\n")
-	b.WriteString("  - [sh, -c, 'update-grub || grub-mkconfig -o /boot/grub/grub.cfg || true']\n")
-	b.WriteString("growpart:\n")
-	b.WriteString("  mode: auto\n")
-	b.WriteString("  devices: [/]\n")
-	b.WriteString("  ignore_growroot_disabled: false\n")
-	b.WriteString("resize_rootfs: true\n")
-	b.WriteString("runcmd:\n")
-	b.WriteString("  - [bash, /usr/local/sbin/bladerunner-bootstrap.sh]\n")
+	consoleArgs := kernelConsoleArgs(cfg)
+	c.WriteFiles = []WriteFile{
+		{Path: "/var/lib/bladerunner/host-client.crt", Permissions: "0644", Content: clientCertPEM},
+		{Path: "/usr/local/sbin/bladerunner-bootstrap.sh", Permissions: "0755", Content: renderBootstrapScript(cfg)},
+		{Path: "/etc/default/grub.d/99_bladerunner.cfg", Permissions: "0644", Content: fmt.Sprintf("GRUB_CMDLINE_LINUX=\"$GRUB_CMDLINE_LINUX %s\"\n", consoleArgs)},
+		{Path: consoleRebootScriptPath, Permissions: "0755", Content: renderConsoleRebootScript(consoleArgs)},
+	}
+	if cfg.IncusProfile != "" {
+		c.WriteFiles = append(c.WriteFiles, WriteFile{Path: incusProfileGuestPath, Permissions: "0644", Content: cfg.IncusProfile})
+	}
+	resolvedConf := renderResolvedConf(cfg)
+	if resolvedConf != "" {
+		c.WriteFiles = append(c.WriteFiles, WriteFile{Path: resolvedDropInPath, Permissions: "0644", Content: resolvedConf})
+	}
 
-	metaData := fmt.Sprintf("instance-id: bladerunner-%s\nlocal-hostname: %s\n", cfg.Name, cfg.Hostname)
-	return b.String(), metaData
+	c.BootCmd = []Command{
+		// Regenerate grub config so the 99_bladerunner.cfg drop-in lands in
+		// /boot/grub/grub.cfg, routing the KERNEL's console from the next boot on.
+		ShellCommand("update-grub || grub-mkconfig -o /boot/grub/grub.cfg || true"),
+	}
+	if cfg.FirstBootReboot {
+		// Let cloud-init reboot once the first boot is fully done; the script,
+		// in check mode, only says whether the cmdline still needs the args.
+		c.PowerState = &PowerState{
+			Mode:      "reboot",
+			Message:   "bladerunner: rebooting once to apply the kernel console args",
+			Timeout:   firstBootRebootTimeout,
+			Condition: Command{"sh", consoleRebootScriptPath, "--check"},
+		}
+	} else {
+		// Otherwise reboot right away if the running kernel lacks those console
+		// args, so kernel output reaches console.log from the first provisioning
+		// boot. A sentinel makes it fire at most once; see the script for details.
+		c.BootCmd = append(c.BootCmd, Command{"sh", consoleRebootScriptPath})
+	}
+
+	if cfg.FastEntropy {
+		// seed_random is the first cloud-init module, ahead of the ssh module's
+		// host-key generation, so this host-drawn seed is in the guest pool
+		// before anything asks for randomness. It is mixed in, not trusted as
+		// the sole source; the seed ISO is deleted with the VM dir.
+		c.RandomSeed = &RandomSeed{File: "/dev/urandom", Encoding: "b64", Data: entropySeed()}
+		// Load the virtio RNG driver early so the kernel's hwrng thread feeds the
+		// pool from VZ's entropy device (configureMisc) on every boot.
+		c.BootCmd = append(c.BootCmd, ShellCommand("modprobe virtio_rng 2>/dev/null || true"))
+	}
+	if resolvedConf != "" {
+		// systemd-resolved is already up by the time write_files lands the
+		// drop-in; restart it so the first boot resolves via the configured
+		// servers too, not just later ones.
+		c.BootCmd = append(c.BootCmd, ShellCommand("systemctl try-restart systemd-resolved || true"))
+	}
+	return c
 }
 
 func WriteSeedFiles(cfg *config.Config, userData, metaData string) error {
@@ -139,7 +174,59 @@ func BuildCloudInitISO(ctx context.Context, cfg *config.Config) error {
 		}
 	}
 
-	return fmt.Errorf("cloud-init ISO not produced at expected paths (wanted %s)", cfg.CloudInitISO)
+	// hdiutil has picked the extension itself across macOS releases; rather
+	// than fail on a name we didn't anticipate, take the image it just wrote.
+	found, err := findFreshISO(filepath.Dir(cfg.CloudInitISO), filepath.Base(baseOut), start)
+	if err != nil {
+		return fmt.Errorf("cloud-init ISO not produced at expected paths (wanted %s): %w", cfg.CloudInitISO, err)
+	}
+	logging.L().Warn("hdiutil wrote the cloud-init ISO under an unexpected name; using it", "found", found, "tried", candidates)
+	if err := os.Rename(found, cfg.CloudInitISO); err != nil {
+		return fmt.Errorf("rename cloud-init iso from %s: %w", found, err)
+	}
+	logging.L().Info("cloud-init ISO built", "path", cfg.CloudInitISO, "elapsed", time.Since(start).Round(time.Millisecond).String())
+	return nil
+}
+
+// isoSectorSize is the ISO 9660 logical block size; every image hdiutil
+// makehybrid writes is a whole number of sectors.
+const isoSectorSize = 2048
+
+// findFreshISO looks in dir for the image hdiutil wrote since start: a
+// non-empty regular file whose size is a whole number of ISO sectors. Files
+// named after base are preferred over other fresh files, then the newest wins.
+func findFreshISO(dir, base string, start time.Time) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	// Filesystem timestamps can be coarser than the clock (HFS+ keeps whole
+	// seconds), so a file written just after start may read as slightly older.
+	since := start.Truncate(time.Second)
+
+	var best string
+	var bestTime time.Time
+	bestNamed := false
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().Before(since) {
+			continue
+		}
+		if info.Size() == 0 || info.Size()%isoSectorSize != 0 {
+			continue
+		}
+		named := strings.HasPrefix(e.Name(), base)
+		if best == "" || (named && !bestNamed) || (named == bestNamed && info.ModTime().After(bestTime)) {
+			best, bestTime, bestNamed = filepath.Join(dir, e.Name()), info.ModTime(), named
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no image written to %s since the build started", dir)
+	}
+	return best, nil
 }
 
 func renderBootstrapScript(cfg *config.Config) string {
@@ -147,6 +234,17 @@ func renderBootstrapScript(cfg *config.Config) string {
 set -euxo pipefail
 export DEBIAN_FRONTEND=noninteractive
 
+# Package manager: known for a --distro image, detected otherwise (the
+# pre-baked image or a custom --image-url / --image-path).
+BR_PKG='%s'
+if [ -z "$BR_PKG" ]; then
+  if command -v apt-get >/dev/null 2>&1; then
+    BR_PKG=apt
+  elif command -v dnf >/dev/null 2>&1; then
+    BR_PKG=dnf
+  fi
+fi
+
 mkdir -p /var/lib/bladerunner
 
 # Emit a host-visible breadcrumb to the VZ-captured virtio console so first-boot
@@ -238,7 +336,7 @@ native_incus_distro() {
 # --- Critical control-path packages FIRST, resiliently. socat + sshd are all
 #     the host<->guest vsock SSH bridge needs; install them (with retries)
 #     before the heavier, failure-prone incus provisioning below.
-if command -v apt-get >/dev/null 2>&1; then
+if [ "$BR_PKG" = apt ]; then
   br_stage apt-update
   apt_update_retry
   br_stage apt-install-base
@@ -249,7 +347,7 @@ if command -v apt-get >/dev/null 2>&1; then
     echo "bladerunner: core package install failed (attempt ${attempt}/3), retrying" >&2
     sleep 3
   done
-elif command -v dnf >/dev/null 2>&1; then
+elif [ "$BR_PKG" = dnf ]; then
   dnf install -y -q openssh-server socat jq chrony || true
 fi
 
@@ -280,7 +378,7 @@ printf '%%s\n' "$SSH_PUBKEY" > "$SSH_HOME/.ssh/authorized_keys"
 chmod 700 "$SSH_HOME/.ssh"
 chmod 600 "$SSH_HOME/.ssh/authorized_keys"
 chown -R "$SSH_USER:$SSH_USER" "$SSH_HOME/.ssh" 2>/dev/null || true
-usermod -aG sudo "$SSH_USER" 2>/dev/null || true
+usermod -aG sudo "$SSH_USER" 2>/dev/null || usermod -aG wheel "$SSH_USER" 2>/dev/null || true
 echo "$SSH_USER ALL=(ALL) NOPASSWD:ALL" > /etc/sudoers.d/90-bladerunner
 chmod 440 /etc/sudoers.d/90-bladerunner
 echo "$SSH_USER:bladerunner" | chpasswd 2>/dev/null || true
@@ -302,7 +400,7 @@ br_stage vsock-services-up
 # --- Best-effort incus provisioning. Everything below is non-fatal: if it
 #     fails, host<->guest SSH (configured above) still works, so the VM stays
 #     reachable and debuggable instead of silently stranding the operator.
-if command -v apt-get >/dev/null 2>&1; then
+if [ "$BR_PKG" = apt ]; then
   br_stage apt-install-incus
   if native_incus_distro; then
     apt-get install -y -qq incus incus-client || true
@@ -311,7 +409,7 @@ if command -v apt-get >/dev/null 2>&1; then
     apt_update_retry
     apt-get install -y -qq incus incus-client || true
   fi
-elif command -v dnf >/dev/null 2>&1; then
+elif [ "$BR_PKG" = dnf ]; then
   dnf install -y -q incus incus-client || true
 fi
 
@@ -333,7 +431,7 @@ br_stage incus-ready
 
 incus admin init --auto || true
 incus config set core.https_address "[::]:8443" || true
-br_stage incus-init-done
+%sbr_stage incus-init-done
 
 # Configure Incus to trust the bladerunner local OIDC provider.
 # The issuer URL is the loopback address inside the guest, which is forwarded
@@ -346,6 +444,8 @@ incus config set oidc.audience  "%s" || true
 
 # Add the host client certificate to trust store (kept for the --auth=cert
 # fallback path; safe to leave even when OIDC is the primary auth method).
+# The host also adds its current certificate over SSH on every start, so this
+# first-boot copy is only a fallback and 'br trust rotate' needs no reprovision.
 incus config trust add-certificate /var/lib/bladerunner/host-client.crt --name bladerunner-host 2>/dev/null ||
   incus config trust add /var/lib/bladerunner/host-client.crt --name bladerunner-host 2>/dev/null ||
   echo "Note: Could not add host certificate to trust store (may already exist)"
@@ -373,26 +473,30 @@ fi
 # incus should be listening now; nudge the API relay so it picks up :8443
 # without waiting for its restart timer.
 systemctl restart bladerunner-vsock-relay@incus.service || true
-
+%s
 # Wait a moment for services to start
 sleep 2
 
 date -u +%%Y-%%m-%%dT%%H:%%M:%%SZ >/var/lib/bladerunner/ready
 br_stage bootstrap-done
 `,
+		config.DistroPackageManager(cfg.Distro),
 		// Break-glass SSH block (SSH_USER, SSH_PUBKEY), placed first because it
 		// appears early in the bootstrap, before the vsock relays.
 		cfg.SSHUser, cfg.SSHPublicKey,
-		// All guest-side vsock relays (ssh/incus/oidc/ntp as ONE template unit) +
+		// The optional rngd install (Config.FastEntropy), then all guest-side
+		// vsock relays (ssh/incus/oidc/ntp as ONE template unit) +
 		// the chrony/watchdog time stack + the optional VirtioFS share, rendered
 		// as one fragment. Ordered relays -> time-heal -> share, all before incus,
 		// so the control path + time stack + backstop are in place regardless of
 		// any later incus failure. Each sub-fragment is self-contained (its own
 		// heredocs / port substitution), so the positional arg list here carries a
 		// single %s for the whole block.
-		renderVsockRelays(cfg)+renderTimeHeal(cfg)+renderShareSetup(cfg),
+		renderEntropy(cfg)+renderVsockRelays(cfg)+renderTimeHeal(cfg)+renderShareSetup(cfg),
 		cfg.SSHUser,
+		renderIncusProfile(cfg),
 		cfg.OIDCIssuerURL, cfg.OIDCClientID, cfg.OIDCAudience,
+		renderExtraPackages(cfg),
 	)
 }
 
@@ -403,7 +507,8 @@ br_stage bootstrap-done
 // /etc/bladerunner/relays/, and one `systemctl enable --now` covering all
 // instances. Every channel keeps the exact socat address pair (and ssh/incus
 // their backend spin-wait) of the standalone unit it supersedes — see
-// relayChannels — so socat is exec'd with byte-identical argv and ports.
+// relayChannels — so socat is exec'd with byte-identical argv and ports. It
+// also installs the guest agent the "control" channel execs.
 //
 // A pre-baked guest image (scripts/build-guest-image.sh) still ships the legacy
 // standalone bladerunner-vsock-{ssh,incus,oidc,ntp} units enabled. To avoid a
@@ -420,6 +525,14 @@ func renderVsockRelays(cfg *config.Config) string {
 	b.WriteString(relayTemplateUnit)
 	b.WriteString("UNIT\n")
 
+	// The guest agent the control channel execs; it must exist before that
+	// instance starts.
+	b.WriteString("mkdir -p /usr/local/lib/bladerunner\n")
+	b.WriteString("cat >" + guestAgentPath + " <<'AGENT'\n")
+	b.WriteString(guestAgentScript)
+	b.WriteString("AGENT\n")
+	b.WriteString("chmod 0755 " + guestAgentPath + "\n")
+
 	// Per-channel arg files: RELAY_ARGS (socat address pair, byte-identical to the
 	// old unit) + optional RELAY_WAIT (backend port to spin-wait for).
 	for _, ch := range relayChannels(cfg) {
@@ -596,15 +709,169 @@ systemctl restart systemd-logind 2>/dev/null || true
 	)
 }
 
-func indent(s string, spaces int) string {
-	prefix := strings.Repeat(" ", spaces)
-	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
-	for i := range lines {
-		if lines[i] == "" {
-			lines[i] = prefix
-			continue
-		}
-		lines[i] = prefix + lines[i]
+// resolvedDropInPath is the systemd-resolved drop-in carrying Config.DNS and
+// Config.SearchDomains.
+const resolvedDropInPath = "/etc/systemd/resolved.conf.d/90-bladerunner-dns.conf"
+
+// consoleRebootScriptPath runs from bootcmd on every boot (or as the
+// power_state condition with Config.FirstBootReboot); the sentinel it writes
+// makes the first-boot console reboot fire at most once per instance.
+const (
+	consoleRebootScriptPath = "/usr/local/sbin/bladerunner-console-reboot.sh"
+	consoleRebootSentinel   = "/var/lib/bladerunner/console-rebooted"
+)
+
+// firstBootRebootTimeout is how long, in seconds, power_state waits for
+// cloud-init's own processes to finish before rebooting anyway.
+const firstBootRebootTimeout = 60
+
+// kernelConsoleArgs returns the configured kernel console args normalized to
+// single spaces, falling back to config.DefaultKernelConsole.
+func kernelConsoleArgs(cfg *config.Config) string {
+	if args := strings.Fields(cfg.KernelConsole); len(args) > 0 {
+		return strings.Join(args, " ")
+	}
+	return config.DefaultKernelConsole
+}
+
+// renderConsoleRebootScript renders the first-boot console reboot. The grub
+// drop-in only affects the NEXT kernel, so on a base image whose cmdline lacks
+// the console args the first boot's kernel messages never reach console.log —
+// the frozen-console symptom. The script reboots exactly once in that case:
+// the sentinel is written before rebooting (so a failed or repeated boot can't
+// loop), it is a no-op when /proc/cmdline already carries every arg (e.g. the
+// pre-baked guest image), and it announces itself on hvc0 so the double boot
+// is visible in console.log as two kernel banners.
+//
+// With --check (the power_state condition, Config.FirstBootReboot) it leaves
+// the reboot to cloud-init and only reports by exit status: 0 when a reboot is
+// due, 1 otherwise. The sentinel guards both modes alike.
+func renderConsoleRebootScript(args string) string {
+	return fmt.Sprintf(`#!/bin/sh
+check=
+[ "$1" = --check ] && check=1
+skip() {
+  [ -n "$check" ] && exit 1
+  exit 0
+}
+sentinel=%[1]s
+[ -e "$sentinel" ] && skip
+mkdir -p "$(dirname "$sentinel")"
+date -u +%%Y-%%m-%%dT%%H:%%M:%%SZ >"$sentinel"
+cmdline=" $(cat /proc/cmdline) "
+for arg in %[2]s; do
+  case "$cmdline" in
+    *" $arg "*) ;;
+    *)
+      msg="bladerunner: kernel console args (%[2]s) not active; rebooting once to apply them"
+      echo "$msg" >/dev/hvc0 2>/dev/null || echo "$msg" >/dev/console 2>/dev/null || true
+      [ -n "$check" ] && exit 0
+      systemctl --no-block reboot || reboot
+      exit 0
+      ;;
+  esac
+done
+skip
+`, consoleRebootSentinel, args)
+}
+
+// renderResolvedConf renders the systemd-resolved drop-in for the configured
+// DNS servers and search domains, or "" when neither is set. It is emitted for
+// both network modes: in shared mode the DHCP-provided server is the host's NAT
+// resolver, which is exactly what breaks on VPN / split-horizon hosts. When
+// servers are given, the "~." routing domain makes resolved send every lookup
+// to them rather than to the per-link (DHCP) servers.
+func renderResolvedConf(cfg *config.Config) string {
+	if len(cfg.DNS) == 0 && len(cfg.SearchDomains) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("[Resolve]\n")
+	if len(cfg.DNS) > 0 {
+		fmt.Fprintf(&b, "DNS=%s\n", strings.Join(cfg.DNS, " "))
 	}
-	return strings.Join(lines, "\n") + "\n"
+	domains := append([]string(nil), cfg.SearchDomains...)
+	if len(cfg.DNS) > 0 {
+		domains = append(domains, "~.")
+	}
+	fmt.Fprintf(&b, "Domains=%s\n", strings.Join(domains, " "))
+	return b.String()
+}
+
+// incusProfileGuestPath is where write_files lands Config.IncusProfile.
+const incusProfileGuestPath = "/var/lib/bladerunner/incus-profile.yaml"
+
+// renderIncusProfile returns the bootstrap fragment applying
+// Config.IncusProfile once incusd is initialised, or "" when none is set. A
+// profile other than "default" is created first. Failure is logged, not fatal:
+// a bad profile must not cost the user SSH and Incus API access.
+func renderIncusProfile(cfg *config.Config) string {
+	if cfg.IncusProfile == "" {
+		return ""
+	}
+	doc, err := config.ParseIncusProfile([]byte(cfg.IncusProfile))
+	if err != nil {
+		// LoadIncusProfile already rejected this; don't ship a broken file.
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n# Apply the user's Incus profile (Config.IncusProfilePath).\n")
+	if doc.Name != config.DefaultIncusProfile {
+		fmt.Fprintf(&b, "incus profile show %[1]s >/dev/null 2>&1 || incus profile create %[1]s || true\n", doc.Name)
+	}
+	fmt.Fprintf(&b, "incus profile edit %s <%s ||\n", doc.Name, incusProfileGuestPath)
+	fmt.Fprintf(&b, "  echo \"bladerunner: applying Incus profile %s failed (non-fatal)\" >&2\n", doc.Name)
+	b.WriteString("br_stage incus-profile-applied\n\n")
+	return b.String()
+}
+
+// entropySeedBytes is how much host randomness random_seed hands the guest.
+const entropySeedBytes = 512
+
+// entropySeed returns fresh host randomness, base64-encoded for random_seed.
+func entropySeed() string {
+	seed := make([]byte, entropySeedBytes)
+	_, _ = rand.Read(seed) // never fails on supported platforms
+	return base64.StdEncoding.EncodeToString(seed)
+}
+
+// renderExtraPackages returns the guest-side bootstrap fragment that installs
+// Config.ExtraPackages, or "" when there are none. It runs last, after Incus,
+// and a failure (say, a name the distro doesn't have) is only reported, so the
+// extras can never hold up SSH or the Incus API. Names are interpolated
+// unquoted; Config.Validate restricts them to a shell-safe charset.
+func renderExtraPackages(cfg *config.Config) string {
+	if len(cfg.ExtraPackages) == 0 {
+		return ""
+	}
+	pkgs := strings.Join(cfg.ExtraPackages, " ")
+	var b strings.Builder
+	b.WriteString("\n# --- Extra packages (Config.ExtraPackages, br start --packages).\n")
+	b.WriteString("br_stage extra-packages\n")
+	b.WriteString("if [ \"$BR_PKG\" = apt ]; then\n")
+	fmt.Fprintf(&b, "  apt-get install -y -qq %s || echo \"bladerunner: extra package install failed (non-fatal)\" >&2\n", pkgs)
+	b.WriteString("elif [ \"$BR_PKG\" = dnf ]; then\n")
+	fmt.Fprintf(&b, "  dnf install -y -q %s || echo \"bladerunner: extra package install failed (non-fatal)\" >&2\n", pkgs)
+	b.WriteString("fi\n")
+	return b.String()
+}
+
+// renderEntropy returns the bootstrap fragment that installs and starts rngd
+// (rng-tools5) so /dev/random stays fed from the virtio RNG, or "" unless
+// Config.FastEntropy is set. Best-effort: entropy help must never abort the
+// bootstrap.
+func renderEntropy(cfg *config.Config) string {
+	if !cfg.FastEntropy {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n# --- Entropy (Config.FastEntropy): rngd feeds /dev/random from virtio-rng.\n")
+	b.WriteString("if [ \"$BR_PKG\" = apt ]; then\n")
+	b.WriteString("  apt-get install -y -qq rng-tools5 || echo \"bladerunner: rng-tools5 install failed (non-fatal)\" >&2\n")
+	b.WriteString("elif [ \"$BR_PKG\" = dnf ]; then\n")
+	b.WriteString("  dnf install -y -q rng-tools || true\n")
+	b.WriteString("fi\n")
+	b.WriteString("systemctl enable --now rngd 2>/dev/null || systemctl enable --now rng-tools 2>/dev/null || true\n")
+	b.WriteString("br_stage entropy-ready\n")
+	return b.String()
 }
@@ -1,8 +1,15 @@
 package provision
 
 import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stuffbucket/bladerunner/internal/config"
 )
@@ -29,6 +36,16 @@ func testConfig() *config.Config {
 	}
 }
 
+// renderUserData returns BuildCloudInit's user-data, failing t on error.
+func renderUserData(t *testing.T, cfg *config.Config, clientCertPEM string) string {
+	t.Helper()
+	userData, _, err := BuildCloudInit(cfg, clientCertPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return userData
+}
+
 // TestBuildCloudInit_FirstBootConsoleDropIn verifies that the user-data
 // emitted by BuildCloudInit installs the /etc/default/grub.d/99_bladerunner.cfg
 // drop-in that appends console=hvc0 to GRUB_CMDLINE_LINUX, so the KERNEL's own
@@ -39,7 +56,7 @@ func TestBuildCloudInit_FirstBootConsoleDropIn(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "-----BEGIN CERTIFICATE-----\nFAKE\n-----END CERTIFICATE-----\n")
+	userData := renderUserData(t, cfg, "-----BEGIN CERTIFICATE-----\nFAKE\n-----END CERTIFICATE-----\n")
 
 	wants := []string{
 		"path: /etc/default/grub.d/99_bladerunner.cfg",
@@ -52,27 +69,171 @@ func TestBuildCloudInit_FirstBootConsoleDropIn(t *testing.T) {
 	}
 }
 
-// TestBuildCloudInit_NoFirstBootReboot guards #57: there must be NO forced
-// first-boot reboot. The old bootcmd reboot (touch .boot1-rebooted +
-// systemctl reboot) never fired reliably and doubled cold-boot time. First-boot
-// visibility now comes from br_stage writing straight to /dev/hvc0, so the
-// reboot is unnecessary and must not return.
-func TestBuildCloudInit_NoFirstBootReboot(t *testing.T) {
+// TestBuildCloudInit_FirstBootRebootGuarded: the #57 bootcmd reboot (touch
+// .boot1-rebooted + systemctl reboot) fired unreliably and on every image. Its
+// replacement lives in a script that bootcmd runs, is guarded by a sentinel so
+// it fires at most once, and skips the reboot when the kernel already has the
+// console args. The old inline forms must not come back.
+func TestBuildCloudInit_FirstBootRebootGuarded(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
-	forbidden := []string{
-		".boot1-rebooted",
-		"systemctl reboot",
-		"shutdown -r now",
-	}
-	for _, bad := range forbidden {
+	for _, bad := range []string{".boot1-rebooted", "shutdown -r now"} {
 		if strings.Contains(userData, bad) {
-			t.Errorf("user-data still contains forced first-boot reboot snippet %q; #57 reboot must be gone\n---\n%s\n---", bad, userData)
+			t.Errorf("user-data contains legacy first-boot reboot snippet %q\n---\n%s\n---", bad, userData)
+		}
+	}
+	for _, want := range []string{"path: " + consoleRebootScriptPath, consoleRebootSentinel} {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing %q\n---\n%s\n---", want, userData)
+		}
+	}
+
+	cc := NewCloudConfig(cfg, "")
+	grub, reboot := -1, -1
+	for i, cmd := range cc.BootCmd {
+		line := strings.Join(cmd, " ")
+		switch {
+		case strings.Contains(line, "systemctl reboot"):
+			t.Errorf("bootcmd %d reboots inline: %q", i, line)
+		case strings.Contains(line, "update-grub"):
+			grub = i
+		case slices.Equal(cmd, Command{"sh", consoleRebootScriptPath}):
+			reboot = i
+		}
+	}
+	// The reboot must run after update-grub, or the next kernel still lacks the args.
+	if grub < 0 || reboot < grub {
+		t.Errorf("bootcmd update-grub at %d, console reboot at %d; want reboot after update-grub", grub, reboot)
+	}
+}
+
+// TestConsoleRebootScript runs the rendered script under sh with /proc/cmdline,
+// the sentinel and the reboot swapped for test doubles: it must reboot exactly
+// once when an arg is missing and never when all are present.
+func TestConsoleRebootScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	run := func(t *testing.T, dir, cmdline string) string {
+		t.Helper()
+		cmdlinePath := filepath.Join(dir, "cmdline")
+		if err := os.WriteFile(cmdlinePath, []byte(cmdline+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		script := renderConsoleRebootScript("console=hvc0 console=tty0")
+		script = strings.NewReplacer(
+			consoleRebootSentinel, filepath.Join(dir, "state", "sentinel"),
+			"/proc/cmdline", cmdlinePath,
+			"/dev/hvc0", "/dev/null",
+			"systemctl --no-block reboot || reboot", "echo REBOOT",
+		).Replace(script)
+		out, err := exec.Command("sh", "-c", script).Output()
+		if err != nil {
+			t.Fatalf("script: %v", err)
+		}
+		return string(out)
+	}
+
+	t.Run("missing arg reboots once", func(t *testing.T) {
+		dir := t.TempDir()
+		if out := run(t, dir, "root=/dev/vda1 ro console=tty0"); !strings.Contains(out, "REBOOT") {
+			t.Errorf("first boot without console=hvc0 did not reboot: %q", out)
+		}
+		if out := run(t, dir, "root=/dev/vda1 ro console=tty0"); strings.Contains(out, "REBOOT") {
+			t.Error("sentinel did not stop a second reboot")
+		}
+	})
+	t.Run("args present", func(t *testing.T) {
+		if out := run(t, t.TempDir(), "root=/dev/vda1 ro console=hvc0 console=tty0"); strings.Contains(out, "REBOOT") {
+			t.Errorf("rebooted although the cmdline has every arg: %q", out)
+		}
+	})
+}
+
+// TestConsoleRebootScriptCheck runs the script the way the power_state
+// condition does (--check): it must never reboot itself, and its exit status
+// says whether cloud-init should, once per instance.
+func TestConsoleRebootScriptCheck(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	check := func(t *testing.T, dir, cmdline string) bool {
+		t.Helper()
+		cmdlinePath := filepath.Join(dir, "cmdline")
+		if err := os.WriteFile(cmdlinePath, []byte(cmdline+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		script := strings.NewReplacer(
+			consoleRebootSentinel, filepath.Join(dir, "state", "sentinel"),
+			"/proc/cmdline", cmdlinePath,
+			"/dev/hvc0", "/dev/null",
+			"systemctl --no-block reboot || reboot", "echo REBOOT",
+		).Replace(renderConsoleRebootScript("console=hvc0"))
+		out, err := exec.Command("sh", "-c", script, "sh", "--check").Output()
+		if strings.Contains(string(out), "REBOOT") {
+			t.Errorf("--check rebooted the guest itself: %q", out)
+		}
+		return err == nil
+	}
+
+	dir := t.TempDir()
+	if !check(t, dir, "root=/dev/vda1 ro") {
+		t.Error("--check said no reboot although console=hvc0 is missing")
+	}
+	if check(t, dir, "root=/dev/vda1 ro") {
+		t.Error("sentinel did not stop a second reboot")
+	}
+	if check(t, t.TempDir(), "root=/dev/vda1 ro console=hvc0") {
+		t.Error("--check asked for a reboot although the cmdline has every arg")
+	}
+}
+
+// TestBuildCloudInit_FirstBootRebootPowerState: with Config.FirstBootReboot the
+// reboot moves from bootcmd to power_state, conditioned on the same guarded
+// script; without it there is no power_state at all.
+func TestBuildCloudInit_FirstBootRebootPowerState(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	if cc := NewCloudConfig(cfg, ""); cc.PowerState != nil {
+		t.Errorf("power_state set without FirstBootReboot: %+v", cc.PowerState)
+	}
+
+	cfg.FirstBootReboot = true
+	cc := NewCloudConfig(cfg, "")
+	for i, cmd := range cc.BootCmd {
+		if slices.Contains(cmd, consoleRebootScriptPath) {
+			t.Errorf("bootcmd %d still runs the console reboot: %q", i, cmd)
 		}
 	}
+	ps := cc.PowerState
+	if ps == nil {
+		t.Fatal("no power_state with FirstBootReboot")
+	}
+	if ps.Mode != "reboot" || !slices.Equal(ps.Condition, Command{"sh", consoleRebootScriptPath, "--check"}) {
+		t.Errorf("power_state = %+v, want a reboot conditioned on the console script's --check", ps)
+	}
+
+	userData := renderUserData(t, cfg, "")
+	for _, want := range []string{"power_state:", "mode: reboot", "path: " + consoleRebootScriptPath} {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing %q\n---\n%s\n---", want, userData)
+		}
+	}
+}
+
+func TestBuildCloudInit_CustomKernelConsole(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	cfg.KernelConsole = "  console=tty0   console=hvc0,115200n8 "
+
+	userData := renderUserData(t, cfg, "")
+
+	if want := `GRUB_CMDLINE_LINUX="$GRUB_CMDLINE_LINUX console=tty0 console=hvc0,115200n8"`; !strings.Contains(userData, want) {
+		t.Errorf("user-data missing %q\n---\n%s\n---", want, userData)
+	}
 }
 
 // TestBuildCloudInit_NoLegacySedGrubEdit guards against regression: the old
@@ -83,7 +244,7 @@ func TestBuildCloudInit_NoLegacySedGrubEdit(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	if strings.Contains(userData, "sed -i 's/^GRUB_CMDLINE_LINUX=") {
 		t.Errorf("user-data still contains legacy sed grub edit; should be replaced by 99_bladerunner.cfg drop-in\n---\n%s\n---", userData)
@@ -103,7 +264,7 @@ func TestBuildCloudInit_VsockSSHBridgeBeforeIncusInstall(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	bridgeIdx := strings.Index(userData, "/etc/bladerunner/relays/ssh.env")
 	incusIdx := strings.Index(userData, "incus incus-client")
@@ -126,7 +287,7 @@ func TestBuildCloudInit_AptUpdateResilient(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	wants := []string{
 		"apt_update_retry",       // retry helper is defined and used
@@ -153,7 +314,7 @@ func TestBuildCloudInit_BootBreadcrumbs(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	wants := []string{
 		"br_stage() {",   // helper defined
@@ -180,7 +341,7 @@ func TestBuildCloudInit_ShareAutomountWhenEnabled(t *testing.T) {
 	cfg.ShareDir = "/some/host/dir"
 	cfg.ShareTag = config.DefaultShareTag
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	wants := []string{
 		"Type=virtiofs",                  // mount unit type
@@ -209,7 +370,7 @@ func TestBuildCloudInit_ShareHonorsGuestPath(t *testing.T) {
 	cfg.ShareTag = config.DefaultShareTag
 	cfg.ShareGuestPath = "/srv/data"
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	wants := []string{
 		"Where=/srv/data",
@@ -233,7 +394,7 @@ func TestBuildCloudInit_NoShareWhenDisabled(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig() // ShareDir empty
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	unwanted := []string{
 		"Type=virtiofs",
@@ -248,6 +409,43 @@ func TestBuildCloudInit_NoShareWhenDisabled(t *testing.T) {
 	}
 }
 
+// TestBuildCloudInit_DNSDropIn verifies configured DNS servers and search
+// domains land in a systemd-resolved drop-in that routes all lookups to them,
+// and that resolved is restarted so first boot already uses them.
+func TestBuildCloudInit_DNSDropIn(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	cfg.DNS = []string{"1.1.1.1", "2606:4700:4700::1111"}
+	cfg.SearchDomains = []string{"corp.example.com"}
+
+	userData := renderUserData(t, cfg, "")
+
+	wants := []string{
+		"path: " + resolvedDropInPath,
+		"DNS=1.1.1.1 2606:4700:4700::1111",
+		"Domains=corp.example.com ~.",
+		"systemctl try-restart systemd-resolved",
+	}
+	for _, want := range wants {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing dns snippet %q\n---\n%s\n---", want, userData)
+		}
+	}
+}
+
+// TestBuildCloudInit_NoDNSDropInByDefault verifies the DHCP-provided resolver
+// is left alone when no DNS override is configured.
+func TestBuildCloudInit_NoDNSDropInByDefault(t *testing.T) {
+	t.Parallel()
+	userData := renderUserData(t, testConfig(), "")
+
+	for _, bad := range []string{resolvedDropInPath, "systemd-resolved"} {
+		if strings.Contains(userData, bad) {
+			t.Errorf("user-data unexpectedly contains %q without a DNS override", bad)
+		}
+	}
+}
+
 // TestBuildCloudInit_UpdateGrubStillRuns ensures bootcmd still regenerates
 // /boot/grub/grub.cfg so the 99_bladerunner.cfg drop-in routes the kernel
 // console to hvc0 on the next natural boot (no forced reboot needed; see #57).
@@ -255,7 +453,7 @@ func TestBuildCloudInit_UpdateGrubStillRuns(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	if !strings.Contains(userData, "update-grub") {
 		t.Errorf("user-data missing update-grub invocation in bootcmd\n---\n%s\n---", userData)
@@ -269,7 +467,7 @@ func TestBuildCloudInit_ChronyInstalled(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	if !strings.Contains(userData, "openssh-server socat jq chrony") {
 		t.Errorf("user-data does not install chrony in the core apt line\n---\n%s\n---", userData)
@@ -285,7 +483,7 @@ func TestBuildCloudInit_ChronyInstallsInCoreBlockBeforeIncus(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	chronyIdx := strings.Index(userData, "openssh-server socat jq chrony")
 	incusIdx := strings.Index(userData, "incus incus-client")
@@ -306,7 +504,7 @@ func TestBuildCloudInit_ChronyConfEmitted(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	wants := []string{
 		"/etc/chrony/chrony.conf",
@@ -337,7 +535,7 @@ func TestBuildCloudInit_NTPBridgeEmitted(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	wants := []string{
 		"/etc/bladerunner/relays/ntp.env",
@@ -361,7 +559,7 @@ func TestBuildCloudInit_AllFourRelayChannels(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	// One template unit, exec'ing socat with the word-split $RELAY_ARGS argv.
 	tmplWants := []string{
@@ -443,7 +641,7 @@ func TestBuildCloudInit_RelayPortsThreadThrough(t *testing.T) {
 	cfg.VsockOIDCPort = 28556
 	cfg.VsockNTPPort = 28557
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	wants := []string{
 		"RELAY_ARGS=VSOCK-LISTEN:20022,fork,reuseaddr TCP:127.0.0.1:22",
@@ -458,6 +656,91 @@ func TestBuildCloudInit_RelayPortsThreadThrough(t *testing.T) {
 	}
 }
 
+// TestBuildCloudInit_ForwardRelays verifies each declared forward gets its own
+// relay instance listening on its vsock port and relaying to the guest port.
+func TestBuildCloudInit_ForwardRelays(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	fwd, err := config.ParseForward("8080:tcp:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Forwards = []config.ForwardSpec{fwd}
+
+	userData := renderUserData(t, cfg, "")
+
+	for _, want := range []string{
+		fmt.Sprintf("RELAY_ARGS=VSOCK-LISTEN:%d,fork,reuseaddr TCP:127.0.0.1:80", fwd.VsockPort),
+		"bladerunner-vsock-relay@fwd-8080.service",
+	} {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing %q\n---\n%s\n---", want, userData)
+		}
+	}
+}
+
+// TestForwardRelayScripts verifies a forward added at runtime gets the same
+// relay env file and template instance as one rendered into the user-data,
+// and that removing it stops the instance and deletes the env file.
+func TestForwardRelayScripts(t *testing.T) {
+	t.Parallel()
+	fwd, err := config.ParseForward("8080:tcp:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	install := ForwardRelayInstallScript(fwd)
+	for _, want := range []string{
+		"cat >/etc/bladerunner/relays/fwd-8080.env <<'RELAYENV'",
+		fmt.Sprintf("RELAY_ARGS=VSOCK-LISTEN:%d,fork,reuseaddr TCP:127.0.0.1:80", fwd.VsockPort),
+		"systemctl enable --now bladerunner-vsock-relay@fwd-8080.service",
+	} {
+		if !strings.Contains(install, want) {
+			t.Errorf("install script missing %q\n---\n%s", want, install)
+		}
+	}
+
+	remove := ForwardRelayRemoveScript("fwd-8080")
+	for _, want := range []string{
+		"systemctl disable --now bladerunner-vsock-relay@fwd-8080.service",
+		"rm -f /etc/bladerunner/relays/fwd-8080.env",
+	} {
+		if !strings.Contains(remove, want) {
+			t.Errorf("remove script missing %q\n---\n%s", want, remove)
+		}
+	}
+}
+
+// TestBuildCloudInit_GuestAgentChannel verifies the guest agent is installed
+// before the relays start and the "control" channel execs it on the vsock
+// control port; with the port unset the channel is left out.
+func TestBuildCloudInit_GuestAgentChannel(t *testing.T) {
+	t.Parallel()
+	cfg := testConfig()
+	cfg.VsockControlPort = 28558
+
+	userData := renderUserData(t, cfg, "")
+
+	for _, want := range []string{
+		"cat >/usr/local/lib/bladerunner/guest-agent <<'AGENT'",
+		"chmod 0755 /usr/local/lib/bladerunner/guest-agent",
+		"RELAY_ARGS=VSOCK-LISTEN:28558,fork,reuseaddr EXEC:/usr/local/lib/bladerunner/guest-agent",
+		"bladerunner-vsock-relay@control.service",
+	} {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing %q\n---\n%s\n---", want, userData)
+		}
+	}
+	if agent, enable := strings.Index(userData, "guest-agent <<'AGENT'"), strings.Index(userData, "systemctl enable --now bladerunner-vsock-relay@"); agent > enable {
+		t.Errorf("guest agent (idx %d) is installed after the relays are enabled (idx %d)", agent, enable)
+	}
+
+	cfg.VsockControlPort = 0
+	if userData := renderUserData(t, cfg, ""); strings.Contains(userData, "bladerunner-vsock-relay@control.service") {
+		t.Error("user-data enables the control channel with VsockControlPort = 0")
+	}
+}
+
 // TestBuildCloudInit_TimesyncdMaskedAfterChronyActive verifies systemd-timesyncd
 // is masked, AND that the mask is gated behind an `is-active chrony` check that
 // precedes it — the half-removal guard that prevents a failed chrony install
@@ -466,7 +749,7 @@ func TestBuildCloudInit_TimesyncdMaskedAfterChronyActive(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	guardIdx := strings.Index(userData, "systemctl is-active --quiet chrony")
 	maskIdx := strings.Index(userData, "systemctl mask systemd-timesyncd")
@@ -489,7 +772,7 @@ func TestBuildCloudInit_WatchdogEmitted(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	wants := []string{
 		"/usr/local/sbin/bladerunner-watchdog.sh",
@@ -517,7 +800,7 @@ func TestBuildCloudInit_WatchdogNeverRestartsNetworkd(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	if strings.Contains(userData, "systemctl restart systemd-networkd") {
 		t.Errorf("watchdog must NEVER restart systemd-networkd (disrupts Incus container bridges)\n---\n%s\n---", userData)
@@ -531,7 +814,7 @@ func TestBuildCloudInit_WatchdogLogsEveryCycle(t *testing.T) {
 	t.Parallel()
 	cfg := testConfig()
 
-	userData, _ := BuildCloudInit(cfg, "")
+	userData := renderUserData(t, cfg, "")
 
 	wants := []string{
 		"logger -t \"$TAG\"", // journal logging via the bladerunner-watchdog tag
@@ -550,3 +833,138 @@ func TestBuildCloudInit_WatchdogLogsEveryCycle(t *testing.T) {
 // of the shared relay template, so its port-threading is covered by
 // TestBuildCloudInit_RelayPortsThreadThrough (non-default VsockNTPPort) and the
 // exact-line assertion in TestBuildCloudInit_AllFourRelayChannels.
+
+// TestBuildCloudInit_IncusProfile verifies a configured Incus profile is
+// shipped via write_files and applied after `incus admin init`, creating a
+// non-default profile first, and that nothing is emitted without one.
+func TestBuildCloudInit_IncusProfile(t *testing.T) {
+	t.Parallel()
+
+	userData := renderUserData(t, testConfig(), "")
+	if strings.Contains(userData, incusProfileGuestPath) {
+		t.Errorf("user-data references the Incus profile without one configured")
+	}
+
+	cfg := testConfig()
+	cfg.IncusProfile = "name: dev\nconfig:\n  limits.cpu: \"2\"\n"
+	userData = renderUserData(t, cfg, "")
+	wants := []string{
+		"path: " + incusProfileGuestPath,
+		"      limits.cpu: \"2\"",
+		"incus profile create dev",
+		"incus profile edit dev <" + incusProfileGuestPath,
+	}
+	for _, want := range wants {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing expected snippet %q", want)
+		}
+	}
+	initIdx := strings.Index(userData, "incus admin init --auto")
+	editIdx := strings.Index(userData, "incus profile edit dev")
+	if initIdx < 0 || editIdx < initIdx {
+		t.Errorf("profile edit (idx %d) does not follow incus admin init (idx %d)", editIdx, initIdx)
+	}
+
+	cfg.IncusProfile = "config:\n  limits.memory: 2GiB\n"
+	userData = renderUserData(t, cfg, "")
+	if !strings.Contains(userData, "incus profile edit default <") || strings.Contains(userData, "incus profile create") {
+		t.Errorf("unnamed profile should edit default without creating one")
+	}
+}
+
+// TestBuildCloudInit_ExtraPackages checks the extra packages land on both
+// install lines after Incus is provisioned, and nothing is emitted without them.
+func TestBuildCloudInit_ExtraPackages(t *testing.T) {
+	t.Parallel()
+
+	if userData := renderUserData(t, testConfig(), ""); strings.Contains(userData, "br_stage extra-packages") {
+		t.Error("user-data installs extra packages without ExtraPackages")
+	}
+
+	cfg := testConfig()
+	cfg.ExtraPackages = []string{"htop", "git"}
+	userData := renderUserData(t, cfg, "")
+	for _, want := range []string{"apt-get install -y -qq htop git ||", "dnf install -y -q htop git ||"} {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing %q\n---\n%s\n---", want, userData)
+		}
+	}
+	if extras, incus := strings.Index(userData, "br_stage extra-packages"), strings.Index(userData, "br_stage incus-init-done"); extras < incus {
+		t.Errorf("extra packages (idx %d) install before Incus is initialised (idx %d)", extras, incus)
+	}
+}
+
+// TestBuildCloudInit_FastEntropy checks the entropy helpers (host seed,
+// virtio_rng load, rngd package) appear only when requested.
+func TestBuildCloudInit_FastEntropy(t *testing.T) {
+	t.Parallel()
+
+	snippets := []string{"random_seed:", "modprobe virtio_rng", "rng-tools5", "br_stage entropy-ready"}
+	userData := renderUserData(t, testConfig(), "")
+	for _, bad := range snippets {
+		if strings.Contains(userData, bad) {
+			t.Errorf("user-data contains %q without FastEntropy", bad)
+		}
+	}
+
+	cfg := testConfig()
+	cfg.FastEntropy = true
+	userData = renderUserData(t, cfg, "")
+	for _, want := range snippets {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user-data missing %q with FastEntropy", want)
+		}
+	}
+
+	// The seed is fresh host randomness of the advertised size.
+	_, rest, _ := strings.Cut(userData, "random_seed:\n")
+	_, data, _ := strings.Cut(rest, "  data: ")
+	data, _, _ = strings.Cut(data, "\n")
+	seed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(seed) != entropySeedBytes {
+		t.Errorf("seed decodes to %d bytes (err %v), want %d", len(seed), err, entropySeedBytes)
+	}
+	again := renderUserData(t, cfg, "")
+	if again == userData {
+		t.Error("two renders share the same entropy seed")
+	}
+
+	// rngd goes in ahead of the vsock relays, in the control-path block.
+	if rng, relays := strings.Index(userData, "rng-tools5"), strings.Index(userData, "bladerunner-vsock-relay@"); rng > relays {
+		t.Errorf("rngd install (idx %d) should precede the vsock relays (idx %d)", rng, relays)
+	}
+}
+
+// TestFindFreshISO covers the fallback for an hdiutil output name we didn't
+// anticipate: the fresh, sector-sized image wins over stale and odd-sized files.
+func TestFindFreshISO(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	write := func(name string, size int, mtime time.Time) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now()
+	write("disk.img", 4*isoSectorSize, start.Add(-time.Hour)) // stale
+	write("cloud-init.log", 100, start.Add(time.Second))      // not sector-sized
+	write("other.bin", isoSectorSize, start.Add(2*time.Second))
+	write("cloud-init.dmg", 2*isoSectorSize, start.Add(time.Second))
+
+	got, err := findFreshISO(dir, "cloud-init", start)
+	if err != nil {
+		t.Fatalf("findFreshISO: %v", err)
+	}
+	if want := filepath.Join(dir, "cloud-init.dmg"); got != want {
+		t.Errorf("findFreshISO = %s, want %s (named after the output beats a newer stranger)", got, want)
+	}
+
+	if _, err := findFreshISO(t.TempDir(), "cloud-init", start); err == nil {
+		t.Error("findFreshISO in an empty dir should fail")
+	}
+}
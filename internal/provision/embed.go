@@ -52,13 +52,23 @@ var chronyConf string
 //go:embed scripts/bladerunner-vsock-relay@.service
 var relayTemplateUnit string
 
+// guestAgentScript is the guest agent that answers host status queries on the
+// control relay channel. Written to guestAgentPath by renderVsockRelays.
+//
+//go:embed scripts/bladerunner-guest-agent.sh
+var guestAgentScript string
+
+// guestAgentPath is where the guest agent is installed; the control channel's
+// socat execs it once per connection.
+const guestAgentPath = "/usr/local/lib/bladerunner/guest-agent"
+
 // relayChannel is one vsock relay instance: the systemd template instance name,
 // the exact socat address pair (word-split by systemd's $RELAY_ARGS expansion
 // into socat's argv), and an optional backend TCP port to spin-wait for before
 // starting (empty for channels that dial out over vsock rather than proxy a
 // local listener).
 type relayChannel struct {
-	name string // template instance name (ssh/incus/oidc/ntp)
+	name string // template instance name (ssh/incus/oidc/ntp, or a forward's name)
 	args string // socat address pair, byte-identical to the old per-channel unit
 	wait string // backend TCP port for ExecStartPre spin-wait, or "" for none
 }
@@ -72,9 +82,13 @@ type relayChannel struct {
 //	ntp   UDP4-RECVFROM:123,bind=127.0.0.1,fork,reuseaddr       VSOCK-CONNECT:2:<vsockNTP>
 //
 // The ports are threaded from cfg so a non-default port config renders the same
-// socat lines the old inline heredocs did.
+// socat lines the old inline heredocs did. Each user-declared forward
+// (cfg.Forwards) follows as a "fwd-..." channel relaying its vsock port to the
+// guest port; it does not wait for a backend, since the service behind it may
+// start at any time. Last comes the "control" channel, which execs the guest
+// agent per connection (omitted when cfg.VsockControlPort is 0).
 func relayChannels(cfg *config.Config) []relayChannel {
-	return []relayChannel{
+	channels := []relayChannel{
 		{
 			name: "ssh",
 			args: fmt.Sprintf("VSOCK-LISTEN:%d,fork,reuseaddr TCP:127.0.0.1:22", cfg.VsockSSHPort),
@@ -94,6 +108,46 @@ func relayChannels(cfg *config.Config) []relayChannel {
 			args: fmt.Sprintf("UDP4-RECVFROM:123,bind=127.0.0.1,fork,reuseaddr VSOCK-CONNECT:2:%d", cfg.VsockNTPPort),
 		},
 	}
+	for _, f := range cfg.Forwards {
+		channels = append(channels, forwardChannel(f))
+	}
+	if cfg.VsockControlPort != 0 {
+		channels = append(channels, relayChannel{
+			name: "control",
+			args: fmt.Sprintf("VSOCK-LISTEN:%d,fork,reuseaddr EXEC:%s", cfg.VsockControlPort, guestAgentPath),
+		})
+	}
+	return channels
+}
+
+// forwardChannel is the relay channel for one user-declared forward.
+func forwardChannel(f config.ForwardSpec) relayChannel {
+	return relayChannel{
+		name: f.Name,
+		args: fmt.Sprintf("VSOCK-LISTEN:%d,fork,reuseaddr TCP:127.0.0.1:%d", f.VsockPort, f.GuestPort),
+	}
+}
+
+// ForwardRelayInstallScript returns a root shell script that installs and
+// starts the guest relay for f on a running guest, the same template
+// instance a new disk gets for each --forward. Enabling it keeps the relay
+// across guest reboots.
+func ForwardRelayInstallScript(f config.ForwardSpec) string {
+	ch := forwardChannel(f)
+	var b strings.Builder
+	b.WriteString("mkdir -p /etc/bladerunner/relays\n")
+	fmt.Fprintf(&b, "cat >/etc/bladerunner/relays/%s.env <<'RELAYENV'\n", ch.name)
+	b.WriteString(relayEnvFile(ch))
+	b.WriteString("RELAYENV\n")
+	fmt.Fprintf(&b, "systemctl enable --now bladerunner-vsock-relay@%s.service\n", ch.name)
+	return b.String()
+}
+
+// ForwardRelayRemoveScript returns a root shell script that stops and removes
+// the guest relay of the forward named name. A relay that is already gone is
+// not an error.
+func ForwardRelayRemoveScript(name string) string {
+	return fmt.Sprintf("systemctl disable --now bladerunner-vsock-relay@%[1]s.service 2>/dev/null || true\nrm -f /etc/bladerunner/relays/%[1]s.env\n", name)
 }
 
 // relayEnvFile renders the /etc/bladerunner/relays/<name>.env body for one
@@ -0,0 +1,42 @@
+#!/bin/sh
+# bladerunner-guest-agent.sh — answers host status queries over vsock.
+#
+# Run by the "control" vsock relay (bladerunner-vsock-relay@control), whose
+# socat forks one instance per connection with the connection on stdin/stdout.
+# It speaks the host control protocol's line format (internal/control): one
+# request line in, one response line out, then exit.
+#
+#   v1 guest.status   ->  v1 {"ready":true,"ready_at":"...","cloud_init":"done",...}
+#   anything else     ->  v1 error: unknown command: <command>
+#
+# This lets the host ask whether provisioning finished without scraping the
+# console log. The answer is best-effort: a field the guest cannot determine
+# is reported as "unknown" rather than failing the request.
+
+IFS= read -r line || exit 0
+line=$(printf '%s' "$line" | tr -d '\r')
+cmd=${line#v1 }
+
+if [ "$cmd" != "guest.status" ]; then
+  printf 'v1 error: unknown command: %s\n' "$cmd"
+  exit 0
+fi
+
+ready=false
+ready_at=""
+if [ -f /var/lib/bladerunner/ready ]; then
+  ready=true
+  ready_at=$(head -n 1 /var/lib/bladerunner/ready | tr -d '"\\')
+fi
+
+cloud_init=unknown
+if command -v cloud-init >/dev/null 2>&1; then
+  status=$(cloud-init status 2>/dev/null | sed -n 's/^status: *//p' | head -n 1 | tr -d '"\\')
+  [ -n "$status" ] && cloud_init=$status
+fi
+
+uptime=$(cut -d ' ' -f 1 /proc/uptime 2>/dev/null)
+uptime=${uptime%%.*}
+
+printf 'v1 {"ready":%s,"ready_at":"%s","cloud_init":"%s","uptime_seconds":%s}\n' \
+  "$ready" "$ready_at" "$cloud_init" "${uptime:-0}"
@@ -0,0 +1,170 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadJSON reads a report written by SaveJSON.
+func LoadJSON(path string) (*StartupReport, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read startup report: %w", err)
+	}
+	var r StartupReport
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("decode startup report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// section is one titled block of a rendered report. Rows with an empty value
+// are dropped when the section is built, so every renderer omits the same
+// optional fields.
+type section struct {
+	title string
+	rows  [][2]string
+	// items is a bullet list, used instead of rows for problems.
+	items []string
+}
+
+func (s *section) row(k, v string) {
+	if v != "" {
+		s.rows = append(s.rows, [2]string{k, v})
+	}
+}
+
+// sections lays out r for the renderers: how to reach the VM first, then
+// what it runs and anything that went wrong. The Incus section only lists
+// server details once the API answered; before that it shows why it did not.
+func sections(r *StartupReport) []section {
+	access := section{title: "Access"}
+	access.row("SSH", r.Access.SSHCommand)
+	access.row("SSH config", r.Access.SSHConfigPath)
+	access.row("API", r.Network.LocalAPIEndpoint)
+	access.row("Dashboard", r.Network.DashboardURL)
+	access.row("Client cert", r.Access.ClientCertPath)
+	access.row("Client key", r.Access.ClientKeyPath)
+	access.row("REST", r.Access.RESTExample)
+	access.row("Log", r.Access.LogPath)
+
+	vmSec := section{title: "VM"}
+	vmSec.row("Name", r.VM.Name)
+	vmSec.row("Hostname", r.VM.Hostname)
+	vmSec.row("Guest", strings.TrimSpace(strings.Join([]string{r.VM.OSRelease, r.VM.GuestArch}, " ")))
+	vmSec.row("Kernel", r.VM.KernelVersion)
+	vmSec.row("Resources", fmt.Sprintf("%d CPUs, %d GiB memory, %d GiB disk", r.Host.RequestedCPU, r.VM.MemoryGiB, r.VM.DiskSizeGiB))
+	vmSec.row("Image", firstNonEmpty(r.VM.BaseImagePath, r.VM.BaseImageURL))
+	vmSec.row("Console", r.VM.ConsoleLog)
+
+	host := section{title: "Host"}
+	if r.Host.OS != "" {
+		host.row("Platform", fmt.Sprintf("%s/%s, %d CPUs", r.Host.OS, r.Host.Arch, r.Host.CPUCount))
+	}
+
+	network := section{title: "Network"}
+	network.row("Mode", strings.TrimSpace(r.Network.Mode+" "+r.Network.BridgeInterface))
+	network.row("MAC", r.Network.MACAddress)
+	for _, f := range r.Network.Forwards {
+		network.row("Forward", fmt.Sprintf("%s %s -> guest:%d/%s", f.Name, f.LocalAddr, f.GuestPort, f.Protocol))
+	}
+
+	incus := section{title: "Incus"}
+	switch {
+	case r.Incus.ServerVersion != "":
+		incus.row("Version", r.Incus.ServerVersion)
+		incus.row("API", fmt.Sprintf("%s (%d extensions)", r.Incus.APIVersion, r.Incus.APIExtensions))
+		incus.row("Auth", r.Incus.Auth)
+		incus.row("Server", r.Incus.ServerName)
+		incus.row("Addresses", strings.Join(r.Incus.Addresses, ", "))
+	case r.Incus.Diagnostics != nil:
+		incus.row("Status", "not ready: "+r.Incus.Diagnostics.ReadyError)
+		incus.row("incusd", lastLine(r.Incus.Diagnostics.LogTail))
+	default:
+		incus.row("Status", "not ready")
+	}
+
+	problems := section{title: "Problems"}
+	if c := r.Clock; c != nil && c.Warning != "" {
+		problems.items = append(problems.items, strings.TrimSpace(c.Warning+" "+c.Hint))
+	}
+	if boot := r.Boot; boot != nil {
+		if ci := boot.CloudInit; ci != nil && ci.Summary != "" {
+			problems.items = append(problems.items, "cloud-init: "+ci.Summary)
+		}
+		if len(boot.MissingCommands) > 0 {
+			problems.items = append(problems.items, "missing commands: "+strings.Join(boot.MissingCommands, ", "))
+		}
+		for _, e := range boot.ConsoleErrors {
+			problems.items = append(problems.items, "console: "+e)
+		}
+	}
+
+	var out []section
+	for _, s := range []section{access, vmSec, host, network, incus, problems} {
+		if len(s.rows) > 0 || len(s.items) > 0 {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// RenderText formats r for a terminal.
+func RenderText(r *StartupReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Generated %s\n", r.GeneratedAt.Local().Format("2006-01-02 15:04:05"))
+	for _, s := range sections(r) {
+		fmt.Fprintf(&b, "\n%s\n", s.title)
+		for _, kv := range s.rows {
+			fmt.Fprintf(&b, "  %-12s %s\n", kv[0]+":", kv[1])
+		}
+		for _, item := range s.items {
+			fmt.Fprintf(&b, "  - %s\n", item)
+		}
+	}
+	return b.String()
+}
+
+// RenderMarkdown formats r as a Markdown document with one table per
+// section, for pasting into an issue. It lists the same fields as RenderText.
+func RenderMarkdown(r *StartupReport) string {
+	var b strings.Builder
+	b.WriteString("# Bladerunner startup report\n\n")
+	fmt.Fprintf(&b, "Generated %s\n", r.GeneratedAt.UTC().Format("2006-01-02 15:04:05 UTC"))
+	for _, s := range sections(r) {
+		fmt.Fprintf(&b, "\n## %s\n\n", s.title)
+		if len(s.rows) > 0 {
+			b.WriteString("| Field | Value |\n| --- | --- |\n")
+			for _, kv := range s.rows {
+				fmt.Fprintf(&b, "| %s | %s |\n", kv[0], markdownCell(kv[1]))
+			}
+		}
+		for _, item := range s.items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+	}
+	return b.String()
+}
+
+// markdownCell makes v safe inside a table cell: pipes would end the cell
+// and newlines the row.
+func markdownCell(v string) string {
+	v = strings.ReplaceAll(v, "|", "\\|")
+	return strings.ReplaceAll(v, "\n", " ")
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
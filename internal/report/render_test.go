@@ -0,0 +1,100 @@
+package report
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderText(t *testing.T) {
+	got := RenderText(testReport())
+	for _, want := range []string{
+		"SSH:         ssh -F /tmp/config bladerunner",
+		"API:         https://127.0.0.1:18443",
+		"Version:     5.0.0",
+		"Addresses:   10.0.0.1, fd00::1",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderText missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Problems") {
+		t.Errorf("healthy report lists problems:\n%s", got)
+	}
+}
+
+// An Incus that never answered shows why, not empty server details.
+func TestRenderTextIncusNotReady(t *testing.T) {
+	r := testReport()
+	r.Incus = IncusInfo{Diagnostics: &IncusDiagnostics{
+		ReadyError: "wait for incus server: context deadline exceeded",
+		LogTail:    "level=info msg=starting\nlevel=error msg=\"Failed to start the daemon\"\n",
+	}}
+	r.Boot = &BootInfo{CloudInit: &CloudInitCheck{Present: true, Summary: "runcmd failed"}}
+
+	got := RenderText(r)
+	for _, want := range []string{
+		"Status:      not ready: wait for incus server: context deadline exceeded",
+		`incusd:      level=error msg="Failed to start the daemon"`,
+		"- cloud-init: runcmd failed",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderText missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Version:") {
+		t.Errorf("not-ready report renders server details:\n%s", got)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "startup-report.json")
+	want := testReport()
+	if err := SaveJSON(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadJSON(path)
+	if err != nil {
+		t.Fatalf("LoadJSON: %v", err)
+	}
+	if !got.GeneratedAt.Equal(want.GeneratedAt) || got.Incus.ServerVersion != want.Incus.ServerVersion {
+		t.Errorf("LoadJSON = %+v, want %+v", got, want)
+	}
+	if _, err := LoadJSON(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadJSON of a missing file succeeded")
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	r := testReport()
+	r.Access.RESTExample = "curl -k https://127.0.0.1:18443/1.0 | jq ."
+	got := RenderMarkdown(r)
+	for _, want := range []string{
+		"# Bladerunner startup report\n",
+		"Generated 2026-02-08 12:00:00 UTC\n",
+		"## Incus\n\n| Field | Value |\n| --- | --- |\n| Version | 5.0.0 |\n",
+		"| Platform | darwin/arm64, 10 CPUs |",
+		`| REST | curl -k https://127.0.0.1:18443/1.0 \| jq . |`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderMarkdown missing %q:\n%s", want, got)
+		}
+	}
+}
+
+// Both renderers drop the same empty optional fields.
+func TestRenderOmitsEmptyFields(t *testing.T) {
+	r := testReport()
+	r.Access.SSHConfigPath = ""
+	for name, got := range map[string]string{"text": RenderText(r), "markdown": RenderMarkdown(r)} {
+		if strings.Contains(got, "SSH config") {
+			t.Errorf("%s renders an empty SSH config path:\n%s", name, got)
+		}
+	}
+	r.Access.SSHConfigPath = "/tmp/ssh/config"
+	for name, got := range map[string]string{"text": RenderText(r), "markdown": RenderMarkdown(r)} {
+		if !strings.Contains(got, "/tmp/ssh/config") {
+			t.Errorf("%s omits the SSH config path:\n%s", name, got)
+		}
+	}
+}
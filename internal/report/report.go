@@ -14,6 +14,42 @@ type StartupReport struct {
 	Network     NetInfo   `json:"network"`
 	Incus       IncusInfo `json:"incus"`
 	Access      Access    `json:"access"`
+
+	// Clock is the guest-vs-host clock comparison, when the guest was
+	// reachable over SSH to take it.
+	Clock *ClockCheck `json:"clock,omitempty"`
+
+	// Boot is what the guest reported about its own provisioning, when it was
+	// reachable over SSH to ask.
+	Boot *BootInfo `json:"boot,omitempty"`
+}
+
+// BootInfo collects guest-side provisioning results.
+type BootInfo struct {
+	CloudInit *CloudInitCheck `json:"cloud_init,omitempty"`
+	// MissingCommands lists the configured required commands the guest
+	// does not have.
+	MissingCommands []string `json:"missing_commands,omitempty"`
+	// ConsoleErrors are the distinct errors seen on the serial console this
+	// boot, with repeat counts ("connection refused ×14").
+	ConsoleErrors []string `json:"console_errors,omitempty"`
+}
+
+// CloudInitCheck is the guest's `cloud-init status --long`, reduced to what
+// explains a failed provision.
+type CloudInitCheck struct {
+	// Present is false when the guest image has no cloud-init at all.
+	Present bool `json:"present"`
+	// Status is cloud-init's overall status: done, running, error, disabled...
+	Status         string `json:"status,omitempty"`
+	ExtendedStatus string `json:"extended_status,omitempty"`
+	// FailedModules names the cloud-init modules that reported errors, e.g.
+	// scripts_user (runcmd), in the order cloud-init listed them.
+	FailedModules []string `json:"failed_modules,omitempty"`
+	// Errors are cloud-init's error lines verbatim.
+	Errors []string `json:"errors,omitempty"`
+	// Summary is a one-line description of the failure, if any.
+	Summary string `json:"summary,omitempty"`
 }
 
 type HostInfo struct {
@@ -36,6 +72,11 @@ type VMInfo struct {
 	CloudInitISO  string `json:"cloud_init_iso"`
 	BaseImageURL  string `json:"base_image_url,omitempty"`
 	BaseImagePath string `json:"base_image_path,omitempty"`
+
+	// KernelVersion and OSRelease are read from the running guest (uname -r
+	// and os-release's PRETTY_NAME); blank when it couldn't be reached.
+	KernelVersion string `json:"kernel_version,omitempty"`
+	OSRelease     string `json:"os_release,omitempty"`
 }
 
 type NetInfo struct {
@@ -45,6 +86,17 @@ type NetInfo struct {
 	LocalSSHEndpoint string `json:"local_ssh_endpoint"`
 	LocalAPIEndpoint string `json:"local_api_endpoint"`
 	DashboardURL     string `json:"dashboard_url"`
+
+	// Forwards lists every host-to-guest port forward, built-ins first.
+	Forwards []ForwardInfo `json:"forwards,omitempty"`
+}
+
+// ForwardInfo describes one port forward the runner stood up.
+type ForwardInfo struct {
+	Name      string `json:"name"`
+	LocalAddr string `json:"local_addr"`
+	GuestPort int    `json:"guest_port"`
+	Protocol  string `json:"protocol"`
 }
 
 type IncusInfo struct {
@@ -54,6 +106,32 @@ type IncusInfo struct {
 	ServerName    string   `json:"server_name,omitempty"`
 	Addresses     []string `json:"addresses,omitempty"`
 	APIExtensions int      `json:"api_extensions"`
+
+	// Diagnostics is set only when Incus failed to become ready.
+	Diagnostics *IncusDiagnostics `json:"diagnostics,omitempty"`
+}
+
+// IncusDiagnostics explains a failed Incus readiness wait from the guest side.
+type IncusDiagnostics struct {
+	// ReadyError is the host-side readiness failure.
+	ReadyError string `json:"ready_error"`
+	// LogTail is the tail of incusd's log fetched from the guest, if any.
+	LogTail string `json:"log_tail,omitempty"`
+	// LogTailError says why LogTail is empty (guest unreachable, log absent).
+	LogTailError string `json:"log_tail_error,omitempty"`
+}
+
+// ClockCheck compares the guest clock with the host's. A skewed guest clock
+// makes TLS fail with certificates that look not-yet-valid or expired.
+type ClockCheck struct {
+	// SkewSeconds is guest minus host; positive means the guest is ahead.
+	SkewSeconds float64 `json:"skew_seconds"`
+	// UncertaintySeconds is half the SSH round trip the reading was taken over.
+	UncertaintySeconds float64 `json:"uncertainty_seconds"`
+	ThresholdSeconds   float64 `json:"threshold_seconds"`
+	// Warning and Hint are set when the skew exceeds the threshold.
+	Warning string `json:"warning,omitempty"`
+	Hint    string `json:"hint,omitempty"`
 }
 
 type Access struct {
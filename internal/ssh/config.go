@@ -36,9 +36,15 @@ type ConfigParams struct {
 }
 
 // WriteSSHConfig writes an SSH config file for Bladerunner to the config directory.
-// Returns the path to the generated config file.
-func WriteSSHConfig(port int, user string, identityFile string) (string, error) {
-	configPath := filepath.Join(ConfigDir(), "ssh", "config")
+// Returns the path to the generated config file. A named VM (instance != "")
+// gets its own file, so VMs running side by side don't overwrite each other's
+// port; the host alias is "bladerunner" in every file.
+func WriteSSHConfig(instance string, port int, user string, identityFile string) (string, error) {
+	fileName := "config"
+	if instance != "" {
+		fileName = "config-" + instance
+	}
+	configPath := filepath.Join(ConfigDir(), "ssh", fileName)
 
 	if err := os.MkdirAll(filepath.Dir(configPath), 0o700); err != nil {
 		return "", fmt.Errorf("create ssh config directory: %w", err)
@@ -115,7 +115,7 @@ func TestWriteSSHConfig(t *testing.T) {
 	tmpDir := t.TempDir()
 	t.Setenv("XDG_CONFIG_HOME", tmpDir)
 
-	configPath, err := WriteSSHConfig(6022, "testuser", "/path/to/key")
+	configPath, err := WriteSSHConfig("", 6022, "testuser", "/path/to/key")
 	if err != nil {
 		t.Fatalf("WriteSSHConfig() error = %v", err)
 	}
@@ -149,6 +149,18 @@ func TestWriteSSHConfig(t *testing.T) {
 	if mode := info.Mode().Perm(); mode != 0o600 {
 		t.Errorf("config permissions = %o, want %o", mode, 0o600)
 	}
+
+	// A named VM's config sits beside the default one instead of replacing it.
+	namedPath, err := WriteSSHConfig("build", 6032, "testuser", "/path/to/key")
+	if err != nil {
+		t.Fatalf("WriteSSHConfig(build) error = %v", err)
+	}
+	if namedPath == configPath {
+		t.Fatalf("named VM reused the default config path %s", configPath)
+	}
+	if content, _ := os.ReadFile(configPath); !strings.Contains(string(content), "Port 6022") {
+		t.Error("writing the named config changed the default one")
+	}
 }
 
 func TestCommand(t *testing.T) {
@@ -61,3 +61,30 @@ type PathEscapeError struct {
 func (e *PathEscapeError) Error() string {
 	return "path escapes base directory: " + e.Path
 }
+
+// writeProbeSize is how much CheckDirWritable writes: enough to fail on a
+// volume with no space left rather than just a read-only one.
+const writeProbeSize = 4 << 10
+
+// CheckDirWritable creates, fills and removes a small temp file in dir,
+// returning the OS error if any step fails.
+func CheckDirWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".write-probe-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_, werr := f.Write(make([]byte, writeProbeSize))
+	if werr == nil {
+		werr = f.Sync()
+	}
+	cerr := f.Close()
+	rerr := os.Remove(name)
+	if werr != nil {
+		return werr
+	}
+	if cerr != nil {
+		return cerr
+	}
+	return rerr
+}
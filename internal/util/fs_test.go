@@ -143,3 +143,42 @@ func TestPathEscapeError(t *testing.T) {
 		t.Errorf("PathEscapeError.Error() = %q, want %q", msg, "path escapes base directory: ../escape")
 	}
 }
+
+func TestCheckDirWritable(t *testing.T) {
+	dir := t.TempDir()
+	if err := CheckDirWritable(dir); err != nil {
+		t.Fatalf("CheckDirWritable(writable) = %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("probe file left behind: %v", entries)
+	}
+
+	if err := CheckDirWritable(filepath.Join(dir, "missing")); err == nil {
+		t.Error("CheckDirWritable(missing dir) succeeded")
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+	ro := filepath.Join(dir, "ro")
+	if err := os.Mkdir(ro, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDirWritable(ro); err == nil {
+		t.Error("CheckDirWritable(read-only dir) succeeded")
+	}
+}
+
+func TestFreeBytes(t *testing.T) {
+	free, err := FreeBytes(t.TempDir())
+	if err != nil {
+		t.Skipf("free space unavailable here: %v", err)
+	}
+	if free == 0 {
+		t.Error("FreeBytes reported a full temp volume")
+	}
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+)
+
+// maxPortScan bounds how many ports FindFreePort tries past start.
+const maxPortScan = 100
+
+// FindFreePort returns the first port from start upward that can be bound on
+// 127.0.0.1, passing over any in skip (ports reserved for something not yet
+// listening). It gives up after maxPortScan candidates. A port found free can
+// still be taken before the caller binds it.
+func FindFreePort(start int, skip ...int) (int, error) {
+	for port := start; port < start+maxPortScan && port <= 65535; port++ {
+		if port < 1 || slices.Contains(skip, port) {
+			continue
+		}
+		ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			continue
+		}
+		_ = ln.Close()
+		return port, nil
+	}
+	return 0, fmt.Errorf("no free port in %d-%d", start, min(start+maxPortScan-1, 65535))
+}
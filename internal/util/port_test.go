@@ -0,0 +1,31 @@
+package util
+
+import (
+	"net"
+	"testing"
+)
+
+func TestFindFreePort(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = busy.Close() }()
+	start := busy.Addr().(*net.TCPAddr).Port
+
+	got, err := FindFreePort(start)
+	if err != nil {
+		t.Fatalf("FindFreePort(%d): %v", start, err)
+	}
+	if got <= start {
+		t.Errorf("FindFreePort(%d) = %d, want a later port", start, got)
+	}
+
+	skipped, err := FindFreePort(start, got)
+	if err != nil {
+		t.Fatalf("FindFreePort(%d, %d): %v", start, got, err)
+	}
+	if skipped == got || skipped <= start {
+		t.Errorf("FindFreePort(%d, %d) = %d, want a later port other than the skipped one", start, got, skipped)
+	}
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// sparseChunk is the granularity at which WriteSparse skips runs of zeros.
+const sparseChunk = 64 << 10
+
+// WriteSparse copies r into dst, seeking over all-zero chunks instead of
+// writing them so a restored raw disk image stays sparse. dst is truncated to
+// the number of bytes read.
+func WriteSparse(dst *os.File, r io.Reader) error {
+	buf := make([]byte, sparseChunk)
+	zero := make([]byte, sparseChunk)
+	var off int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if !bytes.Equal(buf[:n], zero[:n]) {
+				if _, werr := dst.WriteAt(buf[:n], off); werr != nil {
+					return werr
+				}
+			}
+			off += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return dst.Truncate(off)
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSparseSkipsZeros(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	data := make([]byte, 3*sparseChunk+5)
+	data[len(data)-1] = 'z'
+	if err := WriteSparse(f, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	fi, _ := f.Stat()
+	if fi.Size() != int64(len(data)) {
+		t.Fatalf("size = %d, want %d", fi.Size(), len(data))
+	}
+	got := make([]byte, 1)
+	if _, err := f.ReadAt(got, int64(len(data)-1)); err != nil || got[0] != 'z' {
+		t.Fatalf("tail byte = %q, err %v", got, err)
+	}
+}
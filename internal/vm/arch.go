@@ -0,0 +1,24 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// ErrForeignArch means the requested guest architecture differs from the
+// host's and the VM backend has no way to run it.
+var ErrForeignArch = errors.New("foreign arch not supported")
+
+// CheckGuestArch rejects a guest architecture the VM backend cannot run. The
+// Virtualization framework only runs guests natively — it has no CPU
+// emulation — so anything but the host's own arch is refused here, before an
+// image is downloaded, rather than surfacing later as a guest that never
+// boots. An emulating backend would relax this check.
+func CheckGuestArch(arch string) error {
+	if arch == "" || arch == runtime.GOARCH {
+		return nil
+	}
+	return fmt.Errorf("%w: guest arch %s on a %s host; the macOS Virtualization framework runs guests natively and cannot emulate another CPU architecture (omit --arch to use %s)",
+		ErrForeignArch, arch, runtime.GOARCH, runtime.GOARCH)
+}
@@ -0,0 +1,29 @@
+package vm
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCheckGuestArch(t *testing.T) {
+	if err := CheckGuestArch(runtime.GOARCH); err != nil {
+		t.Errorf("CheckGuestArch(host) = %v, want nil", err)
+	}
+	if err := CheckGuestArch(""); err != nil {
+		t.Errorf("CheckGuestArch(\"\") = %v, want nil", err)
+	}
+
+	foreign := "amd64"
+	if runtime.GOARCH == "amd64" {
+		foreign = "arm64"
+	}
+	err := CheckGuestArch(foreign)
+	if !errors.Is(err, ErrForeignArch) {
+		t.Fatalf("CheckGuestArch(%s) = %v, want ErrForeignArch", foreign, err)
+	}
+	if !strings.Contains(err.Error(), "foreign arch not supported") || !strings.Contains(err.Error(), foreign) {
+		t.Errorf("error %q should name the foreign arch", err)
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -52,18 +54,83 @@ func fetchSidecarSHA256(ctx context.Context, imageURL string) (string, error) {
 	if len(first) == 0 {
 		return "", fmt.Errorf("sidecar checksum is empty")
 	}
-	digest := strings.ToLower(first[0])
+	return parseSHA256Hex("sidecar checksum", first[0])
+}
+
+// parseSHA256Hex lowercases s and checks it is a hex SHA-256 digest; what
+// names the digest's origin in errors.
+func parseSHA256Hex(what, s string) (string, error) {
+	digest := strings.ToLower(s)
 	if len(digest) != sha256.Size*2 {
-		return "", fmt.Errorf("sidecar checksum has unexpected length: %d", len(digest))
+		return "", fmt.Errorf("%s has unexpected length: %d", what, len(digest))
 	}
 	for _, r := range digest {
 		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
-			return "", fmt.Errorf("sidecar checksum is not hex: %q", digest)
+			return "", fmt.Errorf("%s is not hex: %q", what, digest)
 		}
 	}
 	return digest, nil
 }
 
+// sha256SumsHosts are the image hosts that publish a SHA256SUMS file beside
+// their images instead of per-image .sha256 sidecars. A package var so tests
+// can point it at an httptest server.
+var sha256SumsHosts = map[string]bool{
+	"cloud-images.ubuntu.com": true,
+}
+
+// isSHA256SumsHost reports whether imageURL is on one of sha256SumsHosts.
+func isSHA256SumsHost(imageURL string) bool {
+	u, err := url.Parse(imageURL)
+	return err == nil && sha256SumsHosts[u.Host]
+}
+
+// fetchSHA256SUMS looks up imageURL's file in the SHA256SUMS published in the
+// same directory (lines of "<hex> *<filename>" or "<hex>  <filename>") and
+// returns its lowercased digest. Like fetchSidecarSHA256 it returns "" with
+// no error when the file 404s, and also when it doesn't list the image.
+func fetchSHA256SUMS(ctx context.Context, imageURL string) (string, error) {
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return "", fmt.Errorf("parse image url: %w", err)
+	}
+	i := strings.LastIndex(u.Path, "/")
+	name := u.Path[i+1:]
+	u.Path = u.Path[:i+1] + "SHA256SUMS"
+	u.RawQuery = ""
+	sumsURL := u.String()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sumsURL, http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("create SHA256SUMS request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch SHA256SUMS: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("fetch SHA256SUMS: %s", resp.Status)
+	}
+
+	const maxSumsBytes = 1 << 20
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxSumsBytes))
+	if err != nil {
+		return "", fmt.Errorf("read SHA256SUMS: %w", err)
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return parseSHA256Hex("SHA256SUMS entry for "+name, fields[0])
+		}
+	}
+	return "", nil
+}
+
 // fileSHA256 returns the hex-encoded SHA-256 digest of the file at path.
 func fileSHA256(path string) (string, error) {
 	f, err := os.Open(path)
@@ -127,6 +194,18 @@ func verifyImageChecksum(ctx context.Context, imageURL, expectedSHA512 string, s
 	}
 
 	want, err := fetchSidecarSHA256(ctx, imageURL)
+	if err == nil && want == "" && !strictSidecar && isSHA256SumsHost(imageURL) {
+		// No sidecar, but the host lists its images in a SHA256SUMS file.
+		if want, err = fetchSHA256SUMS(ctx, imageURL); err != nil {
+			logging.L().Warn("SHA256SUMS fetch failed, continuing without verification",
+				"url", imageURL, "err", err)
+			return nil
+		}
+		if want == "" {
+			logging.L().Warn("image not listed in SHA256SUMS, skipping verification", "url", imageURL)
+			return nil
+		}
+	}
 	if err != nil {
 		if strictSidecar {
 			return fmt.Errorf("hosted image sidecar SHA-256 unreachable (%s): %w", imageURL+".sha256", err)
@@ -174,6 +253,20 @@ func RequireQemuImg() error {
 	return nil
 }
 
+// checkDiskNotSmaller verifies a disk copied from the base image without a
+// resize holds at least the base image's bytes, catching a short copy before
+// the guest boots from a truncated partition table.
+func checkDiskNotSmaller(diskPath string, baseSize int64) error {
+	info, err := os.Stat(diskPath)
+	if err != nil {
+		return fmt.Errorf("stat disk image: %w", err)
+	}
+	if info.Size() < baseSize {
+		return fmt.Errorf("disk image %s is %d bytes, smaller than its %d-byte base image", diskPath, info.Size(), baseSize)
+	}
+	return nil
+}
+
 // MaterializeRawDisk copies a resolved RAW base image to dst and resizes it to
 // diskSizeGiB via qemu-img (which correctly rewrites the GPT backup header).
 // Used by `br disk pack` to write the cartridge's root.img. srcRaw must
@@ -232,13 +325,46 @@ func ensureBaseImage(ctx context.Context, cfg *config.Config) (string, error) {
 
 	path := filepath.Join(cfg.VMDir, "base-image.raw")
 	if util.FileExists(path) {
+		if cfg.RefreshBaseImage {
+			return refreshBaseImage(ctx, cfg, path)
+		}
 		if err := ensureRawDiskImage(path); err != nil {
 			return "", err
 		}
 		logging.L().Info("using cached base image", "path", path)
 		return path, nil
 	}
+	return fetchBaseImage(ctx, cfg, path)
+}
+
+// refreshBaseImage re-downloads (and re-verifies) the base image over an
+// existing cached copy at path. The old copy is set aside rather than deleted
+// up front, and put back if the fresh download or its verification fails, so
+// a refresh on a flaky network never leaves the VM without a base image.
+func refreshBaseImage(ctx context.Context, cfg *config.Config, path string) (string, error) {
+	stale := path + ".stale"
+	if err := os.Rename(path, stale); err != nil {
+		return "", fmt.Errorf("set aside cached base image: %w", err)
+	}
+	logging.L().Info("refreshing cached base image", "path", path)
 
+	got, err := fetchBaseImage(ctx, cfg, path)
+	if err != nil {
+		_ = os.Remove(path)
+		if rerr := os.Rename(stale, path); rerr != nil {
+			logging.L().Warn("could not restore previous base image after failed refresh", "path", stale, "err", rerr)
+		}
+		return "", fmt.Errorf("refresh base image: %w", err)
+	}
+	_ = os.Remove(stale)
+	return got, nil
+}
+
+// fetchBaseImage downloads, verifies, and converts the base image to path.
+func fetchBaseImage(ctx context.Context, cfg *config.Config, path string) (string, error) {
+	if cfg.BaseImageRef != "" {
+		return fetchFromRef(ctx, cfg, path)
+	}
 	if cfg.BaseImageURL == "" {
 		return "", fmt.Errorf("base image url is empty")
 	}
@@ -254,7 +380,7 @@ func ensureBaseImage(ctx context.Context, cfg *config.Config) (string, error) {
 	}
 
 	logging.L().Info("downloading base image", "url", cfg.BaseImageURL, "destination", path)
-	if err := downloadFile(ctx, cfg.BaseImageURL, path); err != nil {
+	if err := (HTTPImageSource{URL: cfg.BaseImageURL}).Fetch(ctx, path); err != nil {
 		return "", err
 	}
 
@@ -291,7 +417,7 @@ var useDebianImage = config.UseDebianImage
 // chosen path is logged.
 func ensureHostedOrDebian(ctx context.Context, cfg *config.Config, path string) (string, error) {
 	logging.L().Info("downloading pre-baked guest image (default)", "url", cfg.BaseImageURL, "destination", path)
-	err := downloadFile(ctx, cfg.BaseImageURL, path)
+	err := HTTPImageSource{URL: cfg.BaseImageURL}.Fetch(ctx, path)
 	if err == nil {
 		// BaseImageSHA512 is empty for the hosted image; strictSidecar=true makes a
 		// missing/unreachable/mismatched .sha256 fatal (fail-closed).
@@ -317,7 +443,7 @@ func ensureHostedOrDebian(ctx context.Context, cfg *config.Config, path string)
 		"reason", err, "hosted_url", hostedURL, "fallback_url", cfg.BaseImageURL)
 
 	logging.L().Info("downloading base image", "url", cfg.BaseImageURL, "destination", path)
-	if err := downloadFile(ctx, cfg.BaseImageURL, path); err != nil {
+	if err := (HTTPImageSource{URL: cfg.BaseImageURL}).Fetch(ctx, path); err != nil {
 		return "", err
 	}
 	// The Debian fallback carries the pinned SHA-512, checked fail-closed here.
@@ -345,13 +471,16 @@ func ensureHostedOrDebian(ctx context.Context, cfg *config.Config, path string)
 func ensureCachedBaseImage(ctx context.Context, cfg *config.Config) (string, error) {
 	cachePath := config.ImageCachePath(cfg.BaseImageExpectedSHA256)
 	okStamp := cachePath + ".ok"
-	if util.FileExists(cachePath) && util.FileExists(okStamp) {
+	// A refresh skips the hit and re-verifies the entry from a fresh download
+	// (clearing it below), so a corrupted cache entry can be repaired too.
+	if !cfg.RefreshBaseImage && util.FileExists(cachePath) && util.FileExists(okStamp) {
 		logging.L().Info("using cached base image (content-addressed)", "path", cachePath, "sha256", cfg.BaseImageExpectedSHA256)
 		return cachePath, nil
 	}
 
-	if cfg.BaseImageURL == "" {
-		return "", fmt.Errorf("base image url is empty")
+	src, err := baseImageSource(cfg)
+	if err != nil {
+		return "", err
 	}
 	if err := os.MkdirAll(config.ImageCacheDir(), 0o755); err != nil {
 		return "", fmt.Errorf("create image cache dir: %w", err)
@@ -362,8 +491,8 @@ func ensureCachedBaseImage(ctx context.Context, cfg *config.Config) (string, err
 	_ = os.Remove(okStamp)
 
 	dlPath := cachePath + ".dl"
-	logging.L().Info("downloading base image", "url", cfg.BaseImageURL, "destination", cachePath, "sha256", cfg.BaseImageExpectedSHA256)
-	if err := downloadFile(ctx, cfg.BaseImageURL, dlPath); err != nil {
+	logging.L().Info("downloading base image", "source", src, "destination", cachePath, "sha256", cfg.BaseImageExpectedSHA256)
+	if err := src.Fetch(ctx, dlPath); err != nil {
 		_ = os.Remove(dlPath)
 		return "", err
 	}
@@ -397,25 +526,49 @@ func ensureCachedBaseImage(ctx context.Context, cfg *config.Config) (string, err
 	return cachePath, nil
 }
 
-func ensureRawDiskImage(path string) error {
+// LocalBaseImagePath returns where a start would look for cfg's base image
+// before downloading it: the user-supplied path, the content-addressed cache
+// entry for a pinned digest, or the per-VM cached copy. The file may not
+// exist yet.
+func LocalBaseImagePath(cfg *config.Config) string {
+	switch {
+	case cfg.BaseImagePath != "":
+		return cfg.BaseImagePath
+	case cfg.BaseImageExpectedSHA256 != "":
+		return config.ImageCachePath(cfg.BaseImageExpectedSHA256)
+	default:
+		return filepath.Join(cfg.VMDir, "base-image.raw")
+	}
+}
+
+// IsQcow2 reports whether the image at path starts with the qcow2 magic. A
+// file too short to hold the header is not qcow2.
+func IsQcow2(path string) (bool, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("open disk image: %w", err)
+		return false, err
 	}
+	defer func() { _ = f.Close() }()
 
 	header := make([]byte, 4)
-	if _, err := io.ReadFull(f, header); err == nil {
-		if string(header) == "QFI\xfb" {
-			_ = f.Close()
-			logging.L().Info("qcow2 image detected, converting to raw format", "path", path)
-			if err := convertQcow2ToRaw(path); err != nil {
-				return fmt.Errorf("convert qcow2 to raw: %w", err)
-			}
-			logging.L().Info("conversion complete", "path", path)
-			return nil
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false, nil
+	}
+	return string(header) == "QFI\xfb", nil
+}
+
+func ensureRawDiskImage(path string) error {
+	qcow2, err := IsQcow2(path)
+	if err != nil {
+		return fmt.Errorf("open disk image: %w", err)
+	}
+	if qcow2 {
+		logging.L().Info("qcow2 image detected, converting to raw format", "path", path)
+		if err := convertQcow2ToRaw(path); err != nil {
+			return fmt.Errorf("convert qcow2 to raw: %w", err)
 		}
+		logging.L().Info("conversion complete", "path", path)
 	}
-	_ = f.Close()
 	return nil
 }
 
@@ -447,12 +600,38 @@ func convertQcow2ToRaw(qcow2Path string) error {
 	return nil
 }
 
-func downloadFile(ctx context.Context, url, path string) error {
+// downloadFile downloads rawURL to path via path+".tmp". A .tmp left by an
+// interrupted download of the same URL (recorded beside it in .tmp-src, with
+// the image's ETag or Last-Modified) is resumed with a range request. The
+// request carries If-Range with that validator, so a server whose image has
+// changed since sends it whole instead of splicing new bytes onto the old
+// partial. A server that ignores the range, or answers it from the wrong
+// offset, gets the download started over; a 416 for a partial that is
+// already the whole image completes it, and any other 416 (the image
+// changed size) discards the partial and fetches afresh. A failed download
+// keeps its .tmp for the next attempt.
+func downloadFile(ctx context.Context, rawURL, path string) error {
 	start := time.Now()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	tmpPath := path + ".tmp"
+	srcPath := tmpPath + "-src"
+	var offset int64
+	srcURL, validator := readDownloadSource(srcPath)
+	if srcURL == rawURL {
+		if fi, err := os.Stat(tmpPath); err == nil && fi.Mode().IsRegular() {
+			offset = fi.Size()
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
 	if err != nil {
 		return fmt.Errorf("create download request: %w", err)
 	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -460,32 +639,131 @@ func downloadFile(ctx context.Context, url, path string) error {
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && contentRangeSize(resp) == offset:
+		logging.L().Info("partial download is already the whole image", "path", tmpPath, "bytes", offset)
+		return finishDownload(tmpPath, srcPath, path, rawURL, start)
+	case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		logging.L().Info("partial download does not match the image, starting over", "path", tmpPath, "bytes", offset)
+		return restartDownload(ctx, resp, srcPath, rawURL, path)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300, offset == 0 && resp.StatusCode == http.StatusPartialContent:
 		return fmt.Errorf("download base image failed: %s", resp.Status)
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent && contentRangeStart(resp) == offset:
+		flags = os.O_WRONLY | os.O_APPEND
+		logging.L().Info("resuming base image download", "url", rawURL, "offset", logging.HumanBytes(offset))
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		logging.L().Info("server answered the resume from the wrong offset, starting over", "path", tmpPath, "content_range", resp.Header.Get("Content-Range"))
+		return restartDownload(ctx, resp, srcPath, rawURL, path)
+	default:
+		offset = 0
+		if err := writeDownloadSource(srcPath, rawURL, resp); err != nil {
+			return fmt.Errorf("record download source: %w", err)
+		}
 	}
 
-	tmpPath := path + ".tmp"
-	_ = os.Remove(tmpPath)
-
-	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	f, err := os.OpenFile(tmpPath, flags, 0o644)
 	if err != nil {
 		return fmt.Errorf("create temp image file: %w", err)
 	}
 
-	progress := logging.NewByteProgress("Downloading base image", resp.ContentLength)
+	total := resp.ContentLength
+	if total > 0 {
+		total += offset
+	}
+	progress := logging.NewByteProgress("Downloading base image", total)
+	progress.SetResumed(offset)
 	if _, err := io.Copy(f, io.TeeReader(resp.Body, progress)); err != nil {
 		progress.Fail(err)
 		_ = f.Close()
-		return fmt.Errorf("write image to disk: %w", err)
+		return fmt.Errorf("write image to disk (rerun to resume): %w", err)
 	}
 	progress.Finish()
 	if err := f.Close(); err != nil {
 		return fmt.Errorf("close temp image file: %w", err)
 	}
 
+	return finishDownload(tmpPath, srcPath, path, rawURL, start)
+}
+
+// finishDownload moves a complete .tmp into place and drops its .tmp-src.
+func finishDownload(tmpPath, srcPath, path, rawURL string, start time.Time) error {
 	if err := os.Rename(tmpPath, path); err != nil {
 		return fmt.Errorf("move downloaded image into place: %w", err)
 	}
-	logging.L().Info("download complete", "url", url, "path", path, "elapsed", time.Since(start).Round(time.Millisecond).String())
+	_ = os.Remove(srcPath)
+	logging.L().Info("download complete", "url", rawURL, "path", path, "elapsed", time.Since(start).Round(time.Millisecond).String())
 	return nil
 }
+
+// restartDownload abandons resp and the partial download and fetches rawURL
+// from the start. Without .tmp-src the retry sends no Range, so it cannot
+// come back here.
+func restartDownload(ctx context.Context, resp *http.Response, srcPath, rawURL, path string) error {
+	_ = resp.Body.Close()
+	if err := os.Remove(srcPath); err != nil {
+		return fmt.Errorf("remove partial download: %w", err)
+	}
+	return downloadFile(ctx, rawURL, path)
+}
+
+// readDownloadSource returns the URL and validator a .tmp-src records, or
+// empty strings when there is none. The validator line is absent when the
+// server sent neither a strong ETag nor Last-Modified.
+func readDownloadSource(srcPath string) (rawURL, validator string) {
+	b, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", ""
+	}
+	rawURL, validator, _ = strings.Cut(string(b), "\n")
+	return rawURL, validator
+}
+
+// writeDownloadSource records rawURL and the validator of resp, the response
+// a fresh download is being written from, for a later resume's If-Range. A
+// weak ETag cannot be used in If-Range, so Last-Modified stands in for it.
+func writeDownloadSource(srcPath, rawURL string, resp *http.Response) error {
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = resp.Header.Get("Last-Modified")
+	}
+	content := rawURL
+	if validator != "" {
+		content += "\n" + validator
+	}
+	return os.WriteFile(srcPath, []byte(content), 0o644)
+}
+
+// contentRangeSize returns the complete length from a 416 response's
+// "Content-Range: bytes */<size>" header, or -1 when it is missing or
+// malformed.
+func contentRangeSize(resp *http.Response) int64 {
+	rest, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes */")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// contentRangeStart returns the first byte offset of a 206 response's
+// "Content-Range: bytes <start>-<end>/<size>" header, or -1 when it is
+// missing or malformed.
+func contentRangeStart(resp *http.Response) int64 {
+	rest, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+	first, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
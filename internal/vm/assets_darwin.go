@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/stuffbucket/bladerunner/internal/config"
@@ -15,23 +16,14 @@ import (
 	"github.com/stuffbucket/bladerunner/internal/util"
 )
 
-// ensureVMDir and ensureMainDisk prepare the on-disk VM working set. They are
+// ensureMainDisk prepares the main disk of the on-disk VM working set. It is
 // only invoked by the darwin VM runner (runner_darwin.go); on other platforms
-// the VM runner is an unsupported stub, so they live in this darwin-tagged file
-// to keep them out of those builds.
-
-func ensureVMDir(cfg *config.Config) error {
-	start := time.Now()
-	if err := os.MkdirAll(cfg.VMDir, 0o755); err != nil {
-		return fmt.Errorf("create vm directory %s: %w", cfg.VMDir, err)
-	}
-	logging.L().Info("ensured VM directory", "path", cfg.VMDir, "elapsed", time.Since(start).Round(time.Millisecond).String())
-	return nil
-}
+// the VM runner is an unsupported stub, so it lives in this darwin-tagged file
+// to keep it out of those builds.
 
 func ensureMainDisk(cfg *config.Config, baseImagePath string) error {
-	if util.FileExists(cfg.DiskPath) {
-		logging.L().Info("reusing existing VM disk", "path", cfg.DiskPath)
+	if path := mainDiskPath(cfg); util.FileExists(path) {
+		logging.L().Info("reusing existing VM disk", "path", path)
 		return nil
 	}
 
@@ -39,6 +31,15 @@ func ensureMainDisk(cfg *config.Config, baseImagePath string) error {
 		return fmt.Errorf("create disk parent: %w", err)
 	}
 
+	if cfg.DiskBackingMode == config.DiskBackingQcow2Overlay {
+		if qcow2AttachSupported() {
+			return createOverlayDisk(cfg, baseImagePath)
+		}
+		logging.L().Warn("disk-backing-mode qcow2-overlay needs qcow2 disk attachment, which Virtualization.framework lacks; copying the base image instead",
+			"base", baseImagePath)
+	}
+	start := time.Now()
+
 	// Copy base image to disk location.
 	in, err := os.Open(baseImagePath)
 	if err != nil {
@@ -66,6 +67,16 @@ func ensureMainDisk(cfg *config.Config, baseImagePath string) error {
 	if err := out.Close(); err != nil {
 		return fmt.Errorf("close disk image: %w", err)
 	}
+	logging.L().Info("copied base image to main disk", "mode", config.DiskBackingCopy,
+		"bytes", sourceSize, "elapsed", time.Since(start).Round(time.Millisecond).String())
+
+	if cfg.NoResize {
+		if err := checkDiskNotSmaller(cfg.DiskPath, sourceSize); err != nil {
+			return err
+		}
+		logging.L().Info("created VM disk image without resizing", "path", cfg.DiskPath, "bytes", sourceSize)
+		return nil
+	}
 
 	// Use qemu-img to resize the disk. This correctly updates the GPT backup
 	// header and avoids corrupting the partition table (unlike raw truncate).
@@ -79,3 +90,47 @@ func ensureMainDisk(cfg *config.Config, baseImagePath string) error {
 	logging.L().Info("created VM disk image", "path", cfg.DiskPath, "size", targetSize)
 	return nil
 }
+
+// qcow2AttachSupported reports whether the VM can boot from a qcow2 main disk.
+// VZDiskImageStorageDeviceAttachment only reads raw images (and ASIF), and
+// converting an overlay to raw at attach time would cost the full copy the
+// overlay exists to avoid, so this is false until the framework grows qcow2
+// support. Until then ensureMainDisk copies the base image for qcow2-overlay
+// too, and says so.
+func qcow2AttachSupported() bool {
+	return false
+}
+
+// overlayDiskPath is where qcow2-overlay mode puts the main disk: next to
+// DiskPath, with a .qcow2 extension.
+func overlayDiskPath(cfg *config.Config) string {
+	return strings.TrimSuffix(cfg.DiskPath, filepath.Ext(cfg.DiskPath)) + ".qcow2"
+}
+
+// mainDiskPath returns the main disk the VM boots from: the qcow2 overlay when
+// one exists and can be attached, else the raw DiskPath.
+func mainDiskPath(cfg *config.Config) string {
+	if overlay := overlayDiskPath(cfg); qcow2AttachSupported() && util.FileExists(overlay) {
+		return overlay
+	}
+	return cfg.DiskPath
+}
+
+// createOverlayDisk makes the main disk a qcow2 overlay on the raw base
+// image, sized to DiskSizeGiB unless NoResize is set. Only the guest's writes
+// land in the overlay, so no base-image bytes are copied.
+func createOverlayDisk(cfg *config.Config, baseImagePath string) error {
+	start := time.Now()
+	path := overlayDiskPath(cfg)
+	args := []string{"create", "-f", "qcow2", "-b", baseImagePath, "-F", "raw", path}
+	if !cfg.NoResize {
+		args = append(args, fmt.Sprintf("%dG", cfg.DiskSizeGiB))
+	}
+	if output, err := exec.Command("qemu-img", args...).CombinedOutput(); err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("qemu-img create overlay failed: %w: %s", err, string(output))
+	}
+	logging.L().Info("created VM disk overlay", "mode", config.DiskBackingQcow2Overlay, "path", path,
+		"base", baseImagePath, "elapsed", time.Since(start).Round(time.Millisecond).String())
+	return nil
+}
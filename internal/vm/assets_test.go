@@ -8,10 +8,12 @@ import (
 	"encoding/hex"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stuffbucket/bladerunner/internal/config"
 	"github.com/stuffbucket/bladerunner/internal/util"
@@ -286,6 +288,88 @@ func TestEnsureCachedBaseImage_DigestMismatch(t *testing.T) {
 	}
 }
 
+func TestEnsureCachedBaseImage_RefreshRedownloads(t *testing.T) {
+	data := []byte("trixie genericcloud refreshed")
+	digest := sha256Hex(data)
+	srv := fakeServer(t, data, "404")
+	defer srv.Close()
+
+	t.Setenv("BLADERUNNER_STATE_DIR", t.TempDir())
+
+	// Seed a stamped but corrupted cache entry: a plain start trusts it.
+	cachePath := config.ImageCachePath(digest)
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cachePath, []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cachePath+".ok", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		BaseImageURL:            srv.URL + "/image",
+		BaseImageExpectedSHA256: digest,
+		RefreshBaseImage:        true,
+	}
+	if _, err := ensureBaseImage(context.Background(), cfg); err != nil {
+		t.Fatalf("ensureBaseImage (refresh): %v", err)
+	}
+	if got, _ := os.ReadFile(cachePath); !bytes.Equal(got, data) {
+		t.Errorf("cache entry = %q, want the re-downloaded bytes", got)
+	}
+}
+
+func TestEnsureBaseImage_RefreshReplacesCachedImage(t *testing.T) {
+	fresh := []byte("new point release")
+	srv := fakeServer(t, fresh, sha256Hex(fresh))
+	defer srv.Close()
+
+	cfg := &config.Config{VMDir: t.TempDir(), BaseImageURL: srv.URL + "/image"}
+	path := filepath.Join(cfg.VMDir, "base-image.raw")
+	if err := os.WriteFile(path, []byte("old point release"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the flag the cached copy wins.
+	if _, err := ensureBaseImage(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "old point release" {
+		t.Fatalf("cached image changed without refresh: %q", got)
+	}
+
+	cfg.RefreshBaseImage = true
+	if _, err := ensureBaseImage(context.Background(), cfg); err != nil {
+		t.Fatalf("ensureBaseImage (refresh): %v", err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, fresh) {
+		t.Errorf("base image = %q, want the fresh download", got)
+	}
+	if util.FileExists(path + ".stale") {
+		t.Error("stale copy should be removed after a successful refresh")
+	}
+}
+
+func TestEnsureBaseImage_FailedRefreshKeepsCachedImage(t *testing.T) {
+	srv := fakeServer(t, []byte("tampered"), strings.Repeat("0", 64))
+	defer srv.Close()
+
+	cfg := &config.Config{VMDir: t.TempDir(), BaseImageURL: srv.URL + "/image", RefreshBaseImage: true}
+	path := filepath.Join(cfg.VMDir, "base-image.raw")
+	if err := os.WriteFile(path, []byte("known good"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ensureBaseImage(context.Background(), cfg); err == nil {
+		t.Fatal("expected checksum mismatch on refresh")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "known good" {
+		t.Errorf("base image = %q, want the previous copy restored", got)
+	}
+}
+
 func TestVerifyImageChecksum_PinnedSHA512(t *testing.T) {
 	data := []byte("trixie genericcloud pinned")
 	sum := sha512.Sum512(data)
@@ -463,3 +547,235 @@ func TestEnsureBaseImage_HostedMissingSidecarFallsBackToDebian(t *testing.T) {
 		t.Errorf("booted image = %q, want the Debian fallback bytes", string(data))
 	}
 }
+
+func TestCheckDiskNotSmaller(t *testing.T) {
+	path := writeTempFile(t, make([]byte, 4096))
+	if err := checkDiskNotSmaller(path, 4096); err != nil {
+		t.Errorf("equal size: %v", err)
+	}
+	if err := checkDiskNotSmaller(path, 8192); err == nil || !strings.Contains(err.Error(), "smaller than") {
+		t.Errorf("short copy: got %v, want a smaller-than error", err)
+	}
+	if err := checkDiskNotSmaller(filepath.Join(t.TempDir(), "missing.raw"), 1); err == nil {
+		t.Error("missing disk should fail")
+	}
+}
+
+// rangeServer serves image at /image with range support, recording each
+// request's Range header.
+func rangeServer(t *testing.T, image []byte, ranges *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "image", time.Time{}, bytes.NewReader(image))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDownloadFile_ResumesPartial(t *testing.T) {
+	image := bytes.Repeat([]byte("0123456789"), 1000)
+	var ranges []string
+	srv := rangeServer(t, image, &ranges)
+	dest := filepath.Join(t.TempDir(), "base-image.raw")
+
+	// An earlier attempt of the same URL got 4000 bytes in.
+	if err := os.WriteFile(dest+".tmp", image[:4000], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+".tmp-src", []byte(srv.URL), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := downloadFile(context.Background(), srv.URL, dest); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, image) {
+		t.Error("resumed download does not match the image")
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=4000-" {
+		t.Errorf("Range headers = %q, want one request for bytes=4000-", ranges)
+	}
+	if util.FileExists(dest+".tmp") || util.FileExists(dest+".tmp-src") {
+		t.Error("temp files left behind after a complete download")
+	}
+}
+
+func TestDownloadFile_PartialOfAnotherURLStartsOver(t *testing.T) {
+	image := []byte("the image we want")
+	var ranges []string
+	srv := rangeServer(t, image, &ranges)
+	dest := filepath.Join(t.TempDir(), "base-image.raw")
+
+	if err := os.WriteFile(dest+".tmp", []byte("other"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+".tmp-src", []byte("https://elsewhere.test/image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := downloadFile(context.Background(), srv.URL, dest); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, image) {
+		t.Errorf("download = %q, want %q", got, image)
+	}
+	if len(ranges) != 1 || ranges[0] != "" {
+		t.Errorf("Range headers = %q, want one plain request", ranges)
+	}
+}
+
+func TestDownloadFile_UnsatisfiableRangeStartsOver(t *testing.T) {
+	image := []byte("short image")
+	var ranges []string
+	srv := rangeServer(t, image, &ranges)
+	dest := filepath.Join(t.TempDir(), "base-image.raw")
+
+	// A partial longer than the image: the image changed under the URL.
+	if err := os.WriteFile(dest+".tmp", bytes.Repeat([]byte("x"), 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+".tmp-src", []byte(srv.URL), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := downloadFile(context.Background(), srv.URL, dest); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, image) {
+		t.Errorf("download = %q, want %q", got, image)
+	}
+	if len(ranges) != 2 || ranges[1] != "" {
+		t.Errorf("Range headers = %q, want a range request then a plain one", ranges)
+	}
+}
+
+// sumsServer serves image at /images/disk.img with no sidecar and a
+// SHA256SUMS listing it with digest, and marks its host as one that
+// publishes SHA256SUMS.
+func sumsServer(t *testing.T, image []byte, digest string) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/images/disk.img", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(image)
+	})
+	mux.HandleFunc("/images/SHA256SUMS", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("0", 64) + " *other.img\n" + digest + " *disk.img\n"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha256SumsHosts[u.Host] = true
+	t.Cleanup(func() { delete(sha256SumsHosts, u.Host) })
+	return srv.URL + "/images/disk.img"
+}
+
+func TestVerifyImageChecksum_SHA256SUMS(t *testing.T) {
+	image := []byte("ubuntu cloud image")
+	path := writeTempFile(t, image)
+
+	imageURL := sumsServer(t, image, sha256Hex(image))
+	if err := verifyImageChecksum(context.Background(), imageURL, "", false, path); err != nil {
+		t.Errorf("verifyImageChecksum with a matching SHA256SUMS entry: %v", err)
+	}
+
+	imageURL = sumsServer(t, image, strings.Repeat("f", 64))
+	err := verifyImageChecksum(context.Background(), imageURL, "", false, path)
+	if err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("verifyImageChecksum with a wrong SHA256SUMS entry: err = %v, want mismatch", err)
+	}
+}
+
+func TestDownloadFile_SendsIfRangeAndRestartsOnChange(t *testing.T) {
+	image := bytes.Repeat([]byte("new!"), 100)
+	var ranges, ifRanges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "image", time.Time{}, bytes.NewReader(image))
+	}))
+	t.Cleanup(srv.Close)
+	dest := filepath.Join(t.TempDir(), "base-image.raw")
+
+	// The partial came from an earlier version of the image.
+	if err := os.WriteFile(dest+".tmp", []byte("old!old!"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+".tmp-src", []byte(srv.URL+"\n\"v1\""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := downloadFile(context.Background(), srv.URL, dest); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, image) {
+		t.Error("download spliced the new image onto the old partial")
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=8-" || ifRanges[0] != `"v1"` {
+		t.Errorf("Range = %q, If-Range = %q; want one bytes=8- request with If-Range \"v1\"", ranges, ifRanges)
+	}
+}
+
+func TestDownloadFile_CompletePartialIsAccepted(t *testing.T) {
+	image := []byte("already whole")
+	var ranges []string
+	srv := rangeServer(t, image, &ranges)
+	dest := filepath.Join(t.TempDir(), "base-image.raw")
+
+	if err := os.WriteFile(dest+".tmp", image, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+".tmp-src", []byte(srv.URL), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := downloadFile(context.Background(), srv.URL, dest); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, image) {
+		t.Errorf("download = %q, want %q", got, image)
+	}
+	if len(ranges) != 1 {
+		t.Errorf("Range headers = %q, want the one range request", ranges)
+	}
+}
+
+func TestDownloadFile_WrongRangeStartsOver(t *testing.T) {
+	image := []byte("0123456789")
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if r.Header.Get("Range") != "" {
+			// A broken server that answers from the wrong offset.
+			w.Header().Set("Content-Range", "bytes 2-9/10")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(image[2:])
+			return
+		}
+		_, _ = w.Write(image)
+	}))
+	t.Cleanup(srv.Close)
+	dest := filepath.Join(t.TempDir(), "base-image.raw")
+
+	if err := os.WriteFile(dest+".tmp", image[:4], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest+".tmp-src", []byte(srv.URL), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := downloadFile(context.Background(), srv.URL, dest); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, image) {
+		t.Errorf("download = %q, want %q", got, image)
+	}
+	if len(ranges) != 2 || ranges[1] != "" {
+		t.Errorf("Range headers = %q, want a range request then a plain one", ranges)
+	}
+}
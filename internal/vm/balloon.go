@@ -0,0 +1,17 @@
+package vm
+
+import "fmt"
+
+// balloonTargetBytes validates a live memory target against the boot-time
+// size. The balloon can only hand memory back to the host and reclaim it, so
+// the target must lie in [1, bootGiB]; growing past the boot size needs a
+// reset with a larger memory-gib.
+func balloonTargetBytes(gib, bootGiB uint64) (uint64, error) {
+	if gib == 0 {
+		return 0, fmt.Errorf("memory target must be at least 1 GiB")
+	}
+	if gib > bootGiB {
+		return 0, fmt.Errorf("memory target %d GiB exceeds the boot size of %d GiB; raising it requires a reset", gib, bootGiB)
+	}
+	return gib * 1024 * 1024 * 1024, nil
+}
@@ -0,0 +1,41 @@
+//go:build darwin
+
+package vm
+
+import (
+	"errors"
+
+	"github.com/Code-Hex/vz/v3"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+)
+
+// MemoryTargetGiB returns the guest's current memory balloon target, which
+// is the boot size until SetMemoryTargetGiB lowers it.
+func (r *Runner) MemoryTargetGiB() uint64 {
+	if gib := r.memoryTarget.Load(); gib != 0 {
+		return gib
+	}
+	return r.cfg.MemoryGiB
+}
+
+// SetMemoryTargetGiB resizes the running guest's working set through the
+// virtio balloon. The guest gives pages back as the balloon inflates; it can
+// be deflated again up to the boot size but never beyond it.
+func (r *Runner) SetMemoryTargetGiB(gib uint64) error {
+	target, err := balloonTargetBytes(gib, r.cfg.MemoryGiB)
+	if err != nil {
+		return err
+	}
+	if r.vm == nil {
+		return errors.New("VM is not started yet")
+	}
+	for _, dev := range r.vm.MemoryBalloonDevices() {
+		if balloon := vz.AsVirtioTraditionalMemoryBalloonDevice(dev); balloon != nil {
+			balloon.SetTargetVirtualMachineMemorySize(clampMemory(target))
+			r.memoryTarget.Store(gib)
+			logging.L().Info("memory balloon target set", "memory_gib", gib)
+			return nil
+		}
+	}
+	return errors.New("VM has no memory balloon device")
+}
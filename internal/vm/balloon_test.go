@@ -0,0 +1,18 @@
+package vm
+
+import "testing"
+
+func TestBalloonTargetBytes(t *testing.T) {
+	if got, err := balloonTargetBytes(4, 8); err != nil || got != 4<<30 {
+		t.Errorf("balloonTargetBytes(4, 8) = %d, %v; want %d", got, err, uint64(4<<30))
+	}
+	if _, err := balloonTargetBytes(8, 8); err != nil {
+		t.Errorf("target equal to boot size: %v", err)
+	}
+	if _, err := balloonTargetBytes(0, 8); err == nil {
+		t.Error("expected error for zero target")
+	}
+	if _, err := balloonTargetBytes(16, 8); err == nil {
+		t.Error("expected error for target above boot size")
+	}
+}
@@ -0,0 +1,181 @@
+package vm
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+// bridgeIface is a host interface Virtualization.framework can bridge onto.
+type bridgeIface struct {
+	ID      string // BSD name, e.g. en0
+	Display string // localized name, e.g. Wi-Fi
+}
+
+func (b bridgeIface) matches(name string) bool {
+	return b.ID == name || strings.EqualFold(b.Display, name)
+}
+
+// label is how b is listed to users: "en0 (Wi-Fi)", or the BSD name alone
+// when there is no separate display name.
+func (b bridgeIface) label() string {
+	if b.Display == "" || b.Display == b.ID {
+		return b.ID
+	}
+	return fmt.Sprintf("%s (%s)", b.ID, b.Display)
+}
+
+// ListBridgeInterfaces lists the host interfaces a bridged VM can use, as
+// "en0 (Wi-Fi)": the name --bridge takes, then the display name, which it
+// also accepts. It is empty off darwin and for a binary without the VM
+// networking entitlement.
+func ListBridgeInterfaces() []string {
+	return bridgeLabels(hostBridgeInterfaces())
+}
+
+// CheckBridgeInterfaces reports, before a VM is built, whether candidates
+// (Config.BridgeCandidates) can resolve to a host interface, so a mistyped
+// --bridge fails up front with the choices instead of deep in VM setup.
+func CheckBridgeInterfaces(candidates []string) error {
+	return checkBridgeCandidates(candidates, hostBridgeInterfaces())
+}
+
+// checkBridgeCandidates is CheckBridgeInterfaces against the given host
+// interfaces. "auto" always passes when there is any interface at all; that
+// none is usable can only be told when the VM is built.
+func checkBridgeCandidates(candidates []string, available []bridgeIface) error {
+	if len(available) == 0 {
+		return fmt.Errorf("no host interface can be bridged; bridged networking needs the VM networking entitlement (or use --network shared)")
+	}
+	for _, name := range candidates {
+		if name == config.BridgeInterfaceAuto {
+			return nil
+		}
+		for _, b := range available {
+			if b.matches(name) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("bridged interface %s was not found; available: %s (see 'br net interfaces')",
+		strings.Join(candidates, ", "), strings.Join(bridgeLabels(available), ", "))
+}
+
+func bridgeLabels(available []bridgeIface) []string {
+	out := make([]string, 0, len(available))
+	for _, b := range available {
+		out = append(out, b.label())
+	}
+	return out
+}
+
+// chooseBridgeInterface picks the interface to bridge onto from the
+// configured candidates, tried in order:
+//
+//   - a name matches an interface by BSD or display name, and is taken if it
+//     is usable (up with an IPv4 address);
+//   - "auto" takes the primary interface (the default route's) if it is
+//     bridgeable, else the first usable bridgeable interface.
+//
+// If no candidate is usable, the first named candidate that exists is taken
+// anyway, so a single explicit name still works with the link down.
+func chooseBridgeInterface(candidates []string, available []bridgeIface, usable func(string) bool, primary string) (bridgeIface, error) {
+	var fallback *bridgeIface
+	for _, name := range candidates {
+		if name == config.BridgeInterfaceAuto {
+			if b, ok := autoBridgeInterface(available, usable, primary); ok {
+				return b, nil
+			}
+			continue
+		}
+		for i, b := range available {
+			if !b.matches(name) {
+				continue
+			}
+			if usable(b.ID) {
+				return b, nil
+			}
+			if fallback == nil {
+				fallback = &available[i]
+			}
+		}
+	}
+	if fallback != nil {
+		return *fallback, nil
+	}
+
+	ids := make([]string, 0, len(available))
+	for _, b := range available {
+		ids = append(ids, b.ID)
+	}
+	return bridgeIface{}, fmt.Errorf("no usable bridged interface among %s (available: %s)",
+		strings.Join(candidates, ", "), strings.Join(ids, ", "))
+}
+
+func autoBridgeInterface(available []bridgeIface, usable func(string) bool, primary string) (bridgeIface, bool) {
+	if primary != "" {
+		for _, b := range available {
+			if b.ID == primary {
+				return b, true
+			}
+		}
+	}
+	for _, b := range available {
+		if usable(b.ID) {
+			return b, true
+		}
+	}
+	return bridgeIface{}, false
+}
+
+// hostInterfaceUsable reports whether the named host interface is up and has
+// an IPv4 address.
+func hostInterfaceUsable(name string) bool {
+	iface, err := net.InterfaceByName(name)
+	if err != nil || iface.Flags&net.FlagUp == 0 {
+		return false
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if ipn, ok := a.(*net.IPNet); ok && ipn.IP.To4() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// primaryHostInterface returns the interface carrying the IPv4 default route,
+// or "" if there is none. Connecting a UDP socket sends no packets; it only
+// makes the kernel pick the outbound source address.
+func primaryHostInterface() string {
+	conn, err := net.Dial("udp4", "192.0.2.1:9") // TEST-NET-1, never contacted
+	if err != nil {
+		return ""
+	}
+	local, ok := conn.LocalAddr().(*net.UDPAddr)
+	_ = conn.Close()
+	if !ok {
+		return ""
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipn, ok := a.(*net.IPNet); ok && ipn.IP.Equal(local.IP) {
+				return iface.Name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChooseBridgeInterface(t *testing.T) {
+	available := []bridgeIface{
+		{ID: "en0", Display: "Wi-Fi"},
+		{ID: "en1", Display: "Thunderbolt 1"},
+		{ID: "en7", Display: "USB 10/100/1000 LAN"},
+	}
+	up := map[string]bool{"en1": true, "en7": true}
+	usable := func(id string) bool { return up[id] }
+
+	cases := []struct {
+		name       string
+		candidates []string
+		primary    string
+		want       string
+		wantErr    bool
+	}{
+		{"named and usable", []string{"en7"}, "", "en7", false},
+		{"display name", []string{"usb 10/100/1000 lan"}, "", "en7", false},
+		{"list skips a down link", []string{"en0", "en1"}, "", "en1", false},
+		{"single down link still taken", []string{"en0"}, "", "en0", false},
+		{"auto prefers the primary", []string{"auto"}, "en7", "en7", false},
+		{"auto without a primary takes the first usable", []string{"auto"}, "", "en1", false},
+		{"auto ignores a non-bridgeable primary", []string{"auto"}, "utun3", "en1", false},
+		{"named before auto", []string{"en7", "auto"}, "en1", "en7", false},
+		{"missing name falls through to auto", []string{"en9", "auto"}, "en7", "en7", false},
+		{"nothing matches", []string{"en9"}, "", "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := chooseBridgeInterface(c.candidates, available, usable, c.primary)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want error", got.ID)
+				}
+				if !strings.Contains(err.Error(), "en0, en1, en7") {
+					t.Errorf("error should list available interfaces: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != c.want {
+				t.Errorf("chose %q, want %q", got.ID, c.want)
+			}
+		})
+	}
+}
+
+func TestChooseBridgeInterfaceAutoNoneUsable(t *testing.T) {
+	available := []bridgeIface{{ID: "en0"}}
+	if _, err := chooseBridgeInterface([]string{"auto"}, available, func(string) bool { return false }, ""); err == nil {
+		t.Error("auto with no usable interface should fail")
+	}
+}
+
+func TestCheckBridgeCandidates(t *testing.T) {
+	available := []bridgeIface{
+		{ID: "en0", Display: "Wi-Fi"},
+		{ID: "en1", Display: "Thunderbolt 1"},
+		{ID: "bridge0"},
+	}
+	cases := []struct {
+		name       string
+		candidates []string
+		available  []bridgeIface
+		wantErr    string
+	}{
+		{"name", []string{"en1"}, available, ""},
+		{"display name", []string{"wi-fi"}, available, ""},
+		{"one of a list", []string{"en9", "en0"}, available, ""},
+		{"auto", []string{"en9", "auto"}, available, ""},
+		{"missing", []string{"en9"}, available, "available: en0 (Wi-Fi), en1 (Thunderbolt 1), bridge0"},
+		{"none bridgeable", []string{"auto"}, nil, "entitlement"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := checkBridgeCandidates(c.candidates, c.available)
+			if c.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, c.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/report"
+)
+
+// ClockSkewThreshold is how far the guest clock may drift from the host's
+// before the startup report flags it. Freshly minted TLS certificates are
+// not valid until their issue time, so even a guest a few seconds behind can
+// reject the Incus certificate.
+const ClockSkewThreshold = 5 * time.Second
+
+// clockSkewHint is the remediation shown alongside a skew warning.
+const clockSkewHint = "the guest syncs to the host clock over NTP via chrony; check that chrony is running in the guest ('chronyc tracking') and that the host clock is correct"
+
+// guestClockCommand prints the guest's UTC time as seconds.nanoseconds.
+const guestClockCommand = "date -u +%s.%N"
+
+// MeasureClockSkew reads the guest clock over SSH and compares it with the
+// host clock at the midpoint of the round trip. The result is an estimate
+// within half the round trip, which clockCheck accounts for before warning.
+func MeasureClockSkew(cfg *config.Config) (skew, uncertainty time.Duration, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sent := time.Now()
+	out, runErr := runGuestSSH(ctx, cfg.SSHConfigPath, nil, guestClockCommand)
+	received := time.Now()
+	if runErr != nil {
+		return 0, 0, fmt.Errorf("read guest clock: %w", runErr)
+	}
+
+	guest, err := parseGuestClock(string(out))
+	if err != nil {
+		return 0, 0, err
+	}
+	rtt := received.Sub(sent)
+	return guest.Sub(sent.Add(rtt / 2)), rtt / 2, nil
+}
+
+// parseGuestClock parses guestClockCommand's output.
+func parseGuestClock(out string) (time.Time, error) {
+	s := strings.TrimSpace(out)
+	secStr, nsecStr, _ := strings.Cut(s, ".")
+	sec, err := strconv.ParseInt(secStr, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse guest clock %q: %w", s, err)
+	}
+	var nsec int64
+	if nsecStr != "" {
+		// %N is always nine digits with GNU date; pad or trim defensively.
+		nsecStr = (nsecStr + "000000000")[:9]
+		if nsec, err = strconv.ParseInt(nsecStr, 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("parse guest clock %q: %w", s, err)
+		}
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// CheckGuestClock measures the guest clock skew for the startup report. It
+// returns nil when the guest could not be reached; the reason is logged.
+func CheckGuestClock(cfg *config.Config) *report.ClockCheck {
+	skew, uncertainty, err := MeasureClockSkew(cfg)
+	if err != nil {
+		logging.L().Warn("could not check guest clock", "err", err)
+		return nil
+	}
+	c := clockCheck(skew, uncertainty, ClockSkewThreshold)
+	if c.Warning != "" {
+		logging.L().Warn(c.Warning, "hint", c.Hint)
+	} else {
+		logging.L().Info("guest clock in sync with host", "skew", skew.Round(time.Millisecond).String())
+	}
+	return c
+}
+
+// clockCheck builds the report entry for a measured skew. It warns only when
+// the skew exceeds the threshold by more than the measurement uncertainty.
+func clockCheck(skew, uncertainty, threshold time.Duration) *report.ClockCheck {
+	c := &report.ClockCheck{
+		SkewSeconds:        skew.Seconds(),
+		UncertaintySeconds: uncertainty.Seconds(),
+		ThresholdSeconds:   threshold.Seconds(),
+	}
+	if skew.Abs()-uncertainty > threshold {
+		c.Warning = skewWarning(skew)
+		c.Hint = clockSkewHint
+	}
+	return c
+}
+
+// skewWarning phrases a skew as "guest clock skewed by 47s (behind the host)".
+func skewWarning(skew time.Duration) string {
+	dir := "ahead of"
+	if skew < 0 {
+		dir = "behind"
+	}
+	return fmt.Sprintf("guest clock skewed by %s (%s the host)", skew.Abs().Round(time.Second), dir)
+}
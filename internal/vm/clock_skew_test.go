@@ -0,0 +1,60 @@
+package vm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+func TestParseGuestClock(t *testing.T) {
+	got, err := parseGuestClock("1760000000.250000000\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Unix(1760000000, 250_000_000); !got.Equal(want) {
+		t.Errorf("parseGuestClock = %v, want %v", got, want)
+	}
+	if got, err := parseGuestClock("1760000000.5"); err != nil || got.Nanosecond() != 500_000_000 {
+		t.Errorf("short fraction: %v %v", got, err)
+	}
+	for _, bad := range []string{"", "Fri Oct 17", "12.x"} {
+		if _, err := parseGuestClock(bad); err == nil {
+			t.Errorf("parseGuestClock(%q) should fail", bad)
+		}
+	}
+}
+
+func TestClockCheck(t *testing.T) {
+	cases := []struct {
+		skew, uncertainty time.Duration
+		want              string
+	}{
+		{-47 * time.Second, 20 * time.Millisecond, "guest clock skewed by 47s (behind the host)"},
+		{90 * time.Second, 0, "guest clock skewed by 1m30s (ahead of the host)"},
+		{2 * time.Second, 0, ""},
+		// Within the threshold once the measurement error is allowed for.
+		{6 * time.Second, 2 * time.Second, ""},
+	}
+	for _, tc := range cases {
+		c := clockCheck(tc.skew, tc.uncertainty, ClockSkewThreshold)
+		if c.Warning != tc.want {
+			t.Errorf("clockCheck(%v, ±%v).Warning = %q, want %q", tc.skew, tc.uncertainty, c.Warning, tc.want)
+		}
+		if (c.Hint != "") != (tc.want != "") {
+			t.Errorf("clockCheck(%v): hint %q should accompany a warning", tc.skew, c.Hint)
+		}
+		if c.SkewSeconds != tc.skew.Seconds() {
+			t.Errorf("SkewSeconds = %v, want %v", c.SkewSeconds, tc.skew.Seconds())
+		}
+	}
+}
+
+func TestMeasureClockSkewNeedsSSHConfig(t *testing.T) {
+	if _, _, err := MeasureClockSkew(&config.Config{}); err == nil {
+		t.Fatal("expected error without an ssh config path")
+	}
+	if CheckGuestClock(&config.Config{}) != nil {
+		t.Fatal("CheckGuestClock should report nothing when the guest is unreachable")
+	}
+}
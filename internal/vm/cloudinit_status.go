@@ -0,0 +1,207 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/boot"
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/report"
+)
+
+// cloudInitAbsent is what cloudInitStatusScript prints when the guest has no
+// cloud-init binary.
+const cloudInitAbsent = "bladerunner: no cloud-init"
+
+// cloudInitStatusScript prints `cloud-init status --long`, as JSON where the
+// guest's cloud-init supports --format, else as text. status exits 1 or 2 on
+// errors, which is exactly when the output matters, so the exit code is
+// dropped.
+const cloudInitStatusScript = `if ! command -v cloud-init >/dev/null 2>&1; then echo '` + cloudInitAbsent + `'; exit 0; fi
+out=$(cloud-init status --long --format json 2>/dev/null)
+[ -n "$out" ] || out=$(cloud-init status --long 2>/dev/null)
+printf '%s\n' "$out"`
+
+// cloudInitModuleHints says what a module covers when the name alone doesn't.
+var cloudInitModuleHints = map[string]string{
+	"scripts_user":                   "runcmd",
+	"scripts_per_once":               "per-once scripts",
+	"package_update_upgrade_install": "packages",
+	"apt_configure":                  "apt sources",
+	"users_groups":                   "users",
+	"set_passwords":                  "chpasswd",
+	"ssh":                            "ssh host keys",
+}
+
+// patternCloudInitModule picks the module out of an error entry such as
+// "('scripts_user', RuntimeError('Runparts: 1 failures (runcmd) ...'))".
+var patternCloudInitModule = regexp.MustCompile(`^\s*\(\s*'([A-Za-z0-9_-]+)'`)
+
+// ReadCloudInitStatus asks the guest's cloud-init how provisioning went, over
+// SSH.
+func ReadCloudInitStatus(cfg *config.Config) (*report.CloudInitCheck, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	out, err := runGuestSSH(ctx, cfg.SSHConfigPath, nil, rootScript(cloudInitStatusScript)...)
+	if err != nil {
+		return nil, fmt.Errorf("read cloud-init status: %w", err)
+	}
+	return parseCloudInitStatus(string(out))
+}
+
+// cloudInitStatusJSON is the subset of `cloud-init status --format json` used.
+type cloudInitStatusJSON struct {
+	Status         string   `json:"status"`
+	ExtendedStatus string   `json:"extended_status"`
+	Errors         []string `json:"errors"`
+}
+
+// parseCloudInitStatus reads cloudInitStatusScript's output in either form.
+func parseCloudInitStatus(out string) (*report.CloudInitCheck, error) {
+	out = strings.TrimSpace(out)
+	switch {
+	case out == cloudInitAbsent:
+		return &report.CloudInitCheck{Present: false}, nil
+	case out == "":
+		return nil, errors.New("cloud-init status printed nothing")
+	}
+
+	c := &report.CloudInitCheck{Present: true}
+	if strings.HasPrefix(out, "{") {
+		var doc cloudInitStatusJSON
+		if err := json.Unmarshal([]byte(out), &doc); err != nil {
+			return nil, fmt.Errorf("parse cloud-init status: %w", err)
+		}
+		c.Status, c.ExtendedStatus, c.Errors = doc.Status, doc.ExtendedStatus, doc.Errors
+	} else {
+		parseCloudInitStatusText(c, out)
+	}
+	if c.Status == "" {
+		return nil, fmt.Errorf("parse cloud-init status: no status in %q", firstLine(out))
+	}
+
+	seen := map[string]bool{}
+	for _, e := range c.Errors {
+		if m := patternCloudInitModule.FindStringSubmatch(e); m != nil && !seen[m[1]] {
+			seen[m[1]] = true
+			c.FailedModules = append(c.FailedModules, m[1])
+		}
+	}
+	c.Summary = cloudInitSummary(c)
+	return c, nil
+}
+
+// parseCloudInitStatusText fills c from the plain `status --long` layout:
+// "key: value" lines, with errors as an indented "- ..." list under "errors:".
+func parseCloudInitStatusText(c *report.CloudInitCheck, out string) {
+	inErrors := false
+	for _, line := range strings.Split(out, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if inErrors {
+			if item, ok := strings.CutPrefix(trimmed, "- "); ok {
+				c.Errors = append(c.Errors, item)
+				continue
+			}
+			inErrors = false
+		}
+		k, v, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "status":
+			c.Status = strings.TrimSpace(v)
+		case "extended_status":
+			c.ExtendedStatus = strings.TrimSpace(v)
+		case "errors":
+			inErrors = true
+		}
+	}
+}
+
+// cloudInitSummary phrases a failed status for logs and errors, naming the
+// modules with their hint, e.g. "cloud-init module scripts_user (runcmd)
+// failed". It is empty when cloud-init reported no error.
+func cloudInitSummary(c *report.CloudInitCheck) string {
+	if c.Status != "error" && len(c.Errors) == 0 {
+		return ""
+	}
+	if len(c.FailedModules) == 0 {
+		return "cloud-init reported an error"
+	}
+	names := make([]string, 0, len(c.FailedModules))
+	for _, m := range c.FailedModules {
+		if hint, ok := cloudInitModuleHints[m]; ok {
+			m += " (" + hint + ")"
+		}
+		names = append(names, m)
+	}
+	noun := "module"
+	if len(names) > 1 {
+		noun = "modules"
+	}
+	return fmt.Sprintf("cloud-init %s %s failed", noun, strings.Join(names, ", "))
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// CheckCloudInit fetches the guest's cloud-init result for the startup
+// report. It returns nil when the guest could not be asked; the reason is
+// logged.
+func CheckCloudInit(cfg *config.Config) *report.BootInfo {
+	c, err := ReadCloudInitStatus(cfg)
+	if err != nil {
+		logging.L().Warn("could not check cloud-init status", "err", err)
+		return nil
+	}
+	switch {
+	case !c.Present:
+		logging.L().Info("guest has no cloud-init; skipping provisioning check")
+	case c.Summary != "":
+		logging.L().Warn(c.Summary, "status", c.Status)
+	default:
+		logging.L().Info("cloud-init finished cleanly", "status", c.Status)
+	}
+	return &report.BootInfo{CloudInit: c}
+}
+
+// CheckBoot gathers the startup report's boot section: the cloud-init result
+// plus the distinct errors on this boot's serial console. It returns nil when
+// neither has anything to say.
+func CheckBoot(cfg *config.Config) *report.BootInfo {
+	info := CheckCloudInit(cfg)
+	errs := consoleErrors(cfg.ConsoleLogPath)
+	if len(errs) == 0 {
+		return info
+	}
+	if info == nil {
+		info = &report.BootInfo{}
+	}
+	info.ConsoleErrors = errs
+	return info
+}
+
+// consoleErrors scans the console log for error lines, rendered with their
+// repeat counts. A missing or unreadable log yields none.
+func consoleErrors(path string) []string {
+	st, err := boot.ScanFile(path)
+	if err != nil {
+		logging.L().Debug("could not scan console log for errors", "path", path, "err", err)
+		return nil
+	}
+	out := make([]string, 0, len(st.Errors))
+	for _, e := range st.Errors {
+		out = append(out, e.String())
+	}
+	return out
+}
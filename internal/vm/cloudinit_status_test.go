@@ -0,0 +1,90 @@
+package vm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCloudInitStatusJSON(t *testing.T) {
+	out := `{
+  "boot_status_code": "enabled-by-generator",
+  "datasource": "nocloud",
+  "detail": "DataSourceNoCloud [seed=/dev/vdb][dsmode=net]",
+  "errors": [
+    "('scripts_user', RuntimeError('Runparts: 1 failures (runcmd) in 1 attempted commands'))",
+    "('package_update_upgrade_install', ProcessExecutionError('apt-get install'))",
+    "('scripts_user', RuntimeError('again'))"
+  ],
+  "extended_status": "error - running",
+  "status": "error"
+}`
+	c, err := parseCloudInitStatus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Present || c.Status != "error" || c.ExtendedStatus != "error - running" {
+		t.Errorf("got %+v", c)
+	}
+	if want := []string{"scripts_user", "package_update_upgrade_install"}; !slices.Equal(c.FailedModules, want) {
+		t.Errorf("FailedModules = %v, want %v", c.FailedModules, want)
+	}
+	if want := "cloud-init modules scripts_user (runcmd), package_update_upgrade_install (packages) failed"; c.Summary != want {
+		t.Errorf("Summary = %q, want %q", c.Summary, want)
+	}
+}
+
+func TestParseCloudInitStatusText(t *testing.T) {
+	out := `status: error
+extended_status: error - done
+boot_status_code: enabled-by-generator
+detail:
+DataSourceNoCloud [seed=/dev/vdb][dsmode=net]
+errors:
+	- ('write_files', OSError('Permission denied'))
+recoverable_errors: {}
+`
+	c, err := parseCloudInitStatus(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Status != "error" || len(c.Errors) != 1 {
+		t.Errorf("got %+v", c)
+	}
+	if c.Summary != "cloud-init module write_files failed" {
+		t.Errorf("Summary = %q", c.Summary)
+	}
+}
+
+func TestParseCloudInitStatusClean(t *testing.T) {
+	c, err := parseCloudInitStatus(`{"status": "done", "extended_status": "done", "errors": []}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Summary != "" || len(c.FailedModules) != 0 {
+		t.Errorf("clean run reported a failure: %+v", c)
+	}
+
+	// An error status without module detail still gets a summary.
+	c, err = parseCloudInitStatus("status: error\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Summary != "cloud-init reported an error" {
+		t.Errorf("Summary = %q", c.Summary)
+	}
+}
+
+func TestParseCloudInitStatusAbsent(t *testing.T) {
+	c, err := parseCloudInitStatus(cloudInitAbsent + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Present || c.Status != "" {
+		t.Errorf("got %+v, want absent", c)
+	}
+	for _, bad := range []string{"", "{not json", "bash: oops"} {
+		if _, err := parseCloudInitStatus(bad); err == nil {
+			t.Errorf("parseCloudInitStatus(%q) should fail", bad)
+		}
+	}
+}
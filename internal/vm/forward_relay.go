@@ -0,0 +1,23 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// forwardRelayTimeout bounds one SSH round trip that installs or removes a
+// forward's guest relay.
+const forwardRelayTimeout = 30 * time.Second
+
+// runRelayScript runs a relay install/remove script (see
+// provision.ForwardRelayInstallScript) as root in the guest over SSH.
+func runRelayScript(ctx context.Context, sshConfigPath, script string) error {
+	ctx, cancel := context.WithTimeout(ctx, forwardRelayTimeout)
+	defer cancel()
+
+	if _, err := runGuestSSH(ctx, sshConfigPath, nil, rootScript(script)...); err != nil {
+		return fmt.Errorf("guest relay: %w", err)
+	}
+	return nil
+}
@@ -1,87 +1,209 @@
-//go:build darwin
-
 // The host-to-guest port forwarder is only wired up by the darwin VM runner
-// (runner_darwin.go); on other platforms the VM runner is an unsupported stub,
-// so this file is darwin-tagged to keep it out of those builds.
+// (runner_darwin.go), but it needs nothing beyond net, so it builds (and is
+// tested) everywhere.
 package vm
 
 import (
+	"fmt"
 	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/stuffbucket/bladerunner/internal/config"
 	"github.com/stuffbucket/bladerunner/internal/logging"
 )
 
 const (
-	forwarderDialRetries    = 30
-	forwarderDialRetryDelay = 500 * time.Millisecond
+	// forwarderDrainTimeout is how long Close lets connections in flight
+	// finish before closing them.
+	forwarderDrainTimeout = 5 * time.Second
+	// forwarderLinger is how long a proxied connection whose one direction has
+	// ended waits for the other when the end could not be passed on as a
+	// half-close (vsock connections have no CloseWrite).
+	forwarderLinger = 2 * time.Second
 )
 
+// dialRetry is how a forwarder retries dialing the guest for one connection:
+// up to attempts dials, delay apart (Config.ForwarderDialRetries and
+// ForwarderDialRetryDelay).
+type dialRetry struct {
+	attempts int
+	delay    time.Duration
+}
+
 type portForwarder struct {
 	name       string
 	listenAddr string
 	guestPort  uint32
 
-	ln     net.Listener
 	dialer func(uint32) (net.Conn, error)
+	retry  dialRetry
+
+	// mu guards ln and paused. A paused forwarder has closed its host
+	// listener but keeps its configuration so Resume can re-bind the same
+	// address; connections already proxied are left alone.
+	mu     sync.Mutex
+	ln     net.Listener
+	paused bool
+
+	// conns holds the host and guest side of every connection being
+	// proxied, so Close can cut them off once the drain times out. Guarded by
+	// mu.
+	conns map[net.Conn]struct{}
+	// drainTimeout bounds how long Close waits for connections in flight;
+	// zero closes them at once.
+	drainTimeout time.Duration
+
+	// toGuest and fromGuest count bytes proxied in each direction over the
+	// forwarder's lifetime, across pauses.
+	toGuest   atomic.Uint64
+	fromGuest atomic.Uint64
+	// activeConns counts the connections being proxied now, totalConns every
+	// connection accepted since the forwarder started.
+	activeConns atomic.Int64
+	totalConns  atomic.Uint64
 
-	stop chan struct{}
-	wg   sync.WaitGroup
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
 }
 
-func newPortForwarder(name, listenAddr string, guestPort uint32, dialer func(uint32) (net.Conn, error)) *portForwarder {
+func newPortForwarder(name, listenAddr string, guestPort uint32, dialer func(uint32) (net.Conn, error), retry dialRetry) *portForwarder {
 	return &portForwarder{
-		name:       name,
-		listenAddr: listenAddr,
-		guestPort:  guestPort,
-		dialer:     dialer,
-		stop:       make(chan struct{}),
+		name:         name,
+		listenAddr:   listenAddr,
+		guestPort:    guestPort,
+		dialer:       dialer,
+		retry:        retry,
+		conns:        make(map[net.Conn]struct{}),
+		drainTimeout: forwarderDrainTimeout,
+		stop:         make(chan struct{}),
 	}
 }
 
+// startPortForwarders starts one forwarder per spec, all or nothing: if any
+// fails to bind, every one started before it is closed again so no host port
+// stays bound, and the error names the forwarder that failed.
+func startPortForwarders(specs []config.ForwardSpec, dialer func(uint32) (net.Conn, error), retry dialRetry) ([]*portForwarder, error) {
+	started := make([]*portForwarder, 0, len(specs))
+	for _, spec := range specs {
+		f := newPortForwarder(spec.Name, spec.LocalAddr, spec.VsockPort, dialer, retry)
+		if err := f.Start(); err != nil {
+			for _, s := range started {
+				_ = s.Close()
+			}
+			return nil, fmt.Errorf("start %s forwarder: %w", spec.Name, bindError(spec, err))
+		}
+		started = append(started, f)
+	}
+	return started, nil
+}
+
 func (f *portForwarder) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.listenLocked(); err != nil {
+		return err
+	}
+	logging.L().Info("started port forwarder", "name", f.name, "listen", f.listenAddr, "guest_vsock_port", f.guestPort)
+	return nil
+}
+
+// listenLocked binds the host listener and starts its accept loop. f.mu must
+// be held.
+func (f *portForwarder) listenLocked() error {
 	ln, err := net.Listen("tcp", f.listenAddr)
 	if err != nil {
 		return err
 	}
 	f.ln = ln
-	logging.L().Info("started port forwarder", "name", f.name, "listen", f.listenAddr, "guest_vsock_port", f.guestPort)
+	f.wg.Go(func() { f.acceptLoop(ln) })
+	return nil
+}
 
-	f.wg.Go(func() {
-		for {
-			conn, err := f.ln.Accept()
-			if err != nil {
-				select {
-				case <-f.stop:
-				default:
+func (f *portForwarder) acceptLoop(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-f.stop:
+			default:
+				if !f.Paused() {
 					logging.L().Debug("accept error", "name", f.name, "err", err)
 				}
-				return
 			}
+			return
+		}
 
-			f.wg.Go(func() {
-				defer func() { _ = conn.Close() }()
+		f.totalConns.Add(1)
+		f.activeConns.Add(1)
+		f.wg.Go(func() {
+			defer f.activeConns.Add(-1)
+			f.track(conn)
+			defer f.untrack(conn)
 
-				guestConn, err := f.dialWithRetry()
-				if err != nil {
-					logging.L().Warn("forward dial failed after retries", "name", f.name, "guest_vsock_port", f.guestPort, "err", err)
-					return
-				}
-				defer func() { _ = guestConn.Close() }()
+			guestConn, err := f.dialWithRetry()
+			if err != nil {
+				logging.L().Warn("forward dial failed after retries", "name", f.name, "guest_vsock_port", f.guestPort, "err", err)
+				return
+			}
+			f.track(guestConn)
+			defer f.untrack(guestConn)
 
-				proxyBidirectional(conn, guestConn)
-			})
-		}
-	})
+			proxyBidirectional(conn, guestConn, &f.toGuest, &f.fromGuest)
+		})
+	}
+}
+
+// Pause closes the host listener so the port is no longer exposed, without
+// touching the VM. Pausing a paused forwarder is a no-op.
+func (f *portForwarder) Pause() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.paused {
+		return
+	}
+	f.paused = true
+	if f.ln != nil {
+		_ = f.ln.Close()
+		f.ln = nil
+	}
+	logging.L().Info("paused port forwarder", "name", f.name, "listen", f.listenAddr)
+}
 
+// Resume re-binds the same host address. It errors (and stays paused) if the
+// port was taken while paused. Resuming an active forwarder is a no-op.
+func (f *portForwarder) Resume() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.paused {
+		return nil
+	}
+	select {
+	case <-f.stop:
+		return net.ErrClosed
+	default:
+	}
+	if err := f.listenLocked(); err != nil {
+		return fmt.Errorf("resume %s forwarder on %s: %w", f.name, f.listenAddr, err)
+	}
+	f.paused = false
+	logging.L().Info("resumed port forwarder", "name", f.name, "listen", f.listenAddr)
 	return nil
 }
 
+// Paused reports whether the forwarder's host listener is paused.
+func (f *portForwarder) Paused() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.paused
+}
+
 func (f *portForwarder) dialWithRetry() (net.Conn, error) {
 	var lastErr error
-	for i := range forwarderDialRetries {
+	for i := range f.retry.attempts {
 		select {
 		case <-f.stop:
 			return nil, net.ErrClosed
@@ -97,37 +219,110 @@ func (f *portForwarder) dialWithRetry() (net.Conn, error) {
 		}
 		lastErr = err
 
-		if i < forwarderDialRetries-1 {
-			time.Sleep(forwarderDialRetryDelay)
+		if i < f.retry.attempts-1 {
+			time.Sleep(f.retry.delay)
 		}
 	}
 	return nil, lastErr
 }
 
+// track registers a proxied connection for Close to cut off.
+func (f *portForwarder) track(c net.Conn) {
+	f.mu.Lock()
+	f.conns[c] = struct{}{}
+	f.mu.Unlock()
+}
+
+// untrack closes c and forgets it.
+func (f *portForwarder) untrack(c net.Conn) {
+	_ = c.Close()
+	f.mu.Lock()
+	delete(f.conns, c)
+	f.mu.Unlock()
+}
+
+// Close stops accepting and lets connections in flight finish for up to
+// drainTimeout, so a transfer over the forward is not cut short by a stop,
+// then closes whatever is still open. Calls after the first do nothing.
 func (f *portForwarder) Close() error {
+	f.closeOnce.Do(f.close)
+	return nil
+}
+
+func (f *portForwarder) close() {
 	close(f.stop)
+	f.mu.Lock()
 	if f.ln != nil {
 		_ = f.ln.Close()
+		f.ln = nil
+	}
+	f.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(f.drainTimeout):
+		logging.L().Warn("closing forwarded connections still open after drain", "name", f.name, "drain", f.drainTimeout)
+		f.mu.Lock()
+		for c := range f.conns {
+			_ = c.Close()
+		}
+		f.mu.Unlock()
+		<-drained
 	}
-	f.wg.Wait()
 	logging.L().Info("stopped port forwarder", "name", f.name, "listen", f.listenAddr)
-	return nil
 }
 
-func proxyBidirectional(a, b net.Conn) {
-	done := make(chan struct{}, 2)
+// proxyBidirectional copies between a and b in both directions, adding the
+// bytes copied from a to b to aToB and the reverse to bToA as they flow; a nil
+// counter is not counted. When one direction ends, its end is passed on as a
+// half-close and the other direction is waited for, so a reply still in flight
+// is not truncated. A side that cannot be half-closed gets forwarderLinger to
+// finish instead, since its peer never sees the EOF. The caller closes a and
+// b, which also ends a copy still running after the linger.
+func proxyBidirectional(a, b net.Conn, aToB, bToA *atomic.Uint64) {
+	type result struct{ halfClosed bool }
+	done := make(chan result, 2)
 
-	cp := func(dst, src net.Conn) {
-		_, _ = io.Copy(dst, src)
+	cp := func(dst, src net.Conn, n *atomic.Uint64) {
+		var w io.Writer = dst
+		if n != nil {
+			w = countingWriter{w: dst, n: n}
+		}
+		_, _ = io.Copy(w, src)
 		// Signal write completion so the reverse copy sees EOF.
-		if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+		cw, ok := dst.(interface{ CloseWrite() error })
+		if ok {
 			_ = cw.CloseWrite()
 		}
-		done <- struct{}{}
+		done <- result{halfClosed: ok}
 	}
 
-	go cp(a, b)
-	go cp(b, a)
+	go cp(b, a, aToB)
+	go cp(a, b, bToA)
+
+	if first := <-done; first.halfClosed {
+		<-done
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(forwarderLinger):
+	}
+}
+
+// countingWriter adds the length of each successful write to n.
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Uint64
+}
 
-	<-done
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(uint64(n))
+	return n, err
 }
@@ -64,7 +64,7 @@ func (f *reversePortForwarder) Start() error {
 				}
 				defer func() { _ = hostConn.Close() }()
 
-				proxyBidirectional(conn, hostConn)
+				proxyBidirectional(conn, hostConn, nil, nil)
 			})
 		}
 	})
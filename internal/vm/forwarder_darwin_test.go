@@ -0,0 +1,22 @@
+//go:build darwin
+
+package vm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+func TestRunnerForwardChangesAfterStop(t *testing.T) {
+	r := &Runner{cfg: &config.Config{}}
+	r.closeForwarders()
+	if err := r.RemoveForward(context.Background(), "fwd-8080"); !errors.Is(err, errForwardersClosed) {
+		t.Errorf("RemoveForward after stop = %v, want errForwardersClosed", err)
+	}
+	if err := r.PauseForwarders(""); !errors.Is(err, errForwardersClosed) {
+		t.Errorf("PauseForwarders after stop = %v, want errForwardersClosed", err)
+	}
+}
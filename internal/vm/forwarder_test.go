@@ -0,0 +1,314 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+// testDialRetry is the default dial policy.
+var testDialRetry = dialRetry{attempts: config.DefaultForwarderDialRetries, delay: config.DefaultForwarderDialRetryDelay}
+
+// freeAddr returns a loopback address that was free a moment ago.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return addr
+}
+
+// TestPortForwarderPauseResume verifies pausing releases the host port and
+// resuming re-binds the same address, failing while the port is taken.
+func TestPortForwarderPauseResume(t *testing.T) {
+	addr := freeAddr(t)
+	dial := func(uint32) (net.Conn, error) { return nil, errors.New("no guest") }
+	f := newPortForwarder("ssh", addr, 22, dial, testDialRetry)
+	if err := f.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	f.Pause()
+	if !f.Paused() {
+		t.Fatal("Paused() = false after Pause")
+	}
+
+	// The port is free while paused: someone else can take it, and resume fails.
+	squatter, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("port not released on pause: %v", err)
+	}
+	if err := f.Resume(); err == nil {
+		t.Fatal("Resume succeeded while the port was taken")
+	}
+	if !f.Paused() {
+		t.Error("failed Resume must leave the forwarder paused")
+	}
+	_ = squatter.Close()
+
+	if err := f.Resume(); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if f.Paused() {
+		t.Error("Paused() = true after Resume")
+	}
+	if ln, err := net.Listen("tcp", addr); err == nil {
+		_ = ln.Close()
+		t.Error("resumed forwarder is not bound to its original address")
+	}
+}
+
+// TestStartPortForwardersAllOrNothing makes the Nth forwarder's port
+// unavailable and checks that startup fails naming it, with every forwarder
+// started before it closed again, for each N.
+func TestStartPortForwardersAllOrNothing(t *testing.T) {
+	dial := func(uint32) (net.Conn, error) { return nil, errors.New("no guest") }
+	const n = 4
+	for fail := 0; fail < n; fail++ {
+		t.Run(fmt.Sprintf("fail at %d", fail), func(t *testing.T) {
+			specs := make([]config.ForwardSpec, n)
+			for i := range specs {
+				specs[i] = config.ForwardSpec{Name: fmt.Sprintf("fwd-%d", i), LocalAddr: freeAddr(t), VsockPort: uint32(i)}
+			}
+			squatter, err := net.Listen("tcp", specs[fail].LocalAddr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = squatter.Close() }()
+
+			fs, err := startPortForwarders(specs, dial, testDialRetry)
+			if err == nil {
+				for _, f := range fs {
+					_ = f.Close()
+				}
+				t.Fatal("startPortForwarders succeeded with a port taken")
+			}
+			if !strings.Contains(err.Error(), specs[fail].Name) {
+				t.Errorf("error %q does not name %s", err, specs[fail].Name)
+			}
+			for i, spec := range specs {
+				if i == fail {
+					continue
+				}
+				ln, err := net.Listen("tcp", spec.LocalAddr)
+				if err != nil {
+					t.Errorf("%s still bound after a failed startup: %v", spec.Name, err)
+					continue
+				}
+				_ = ln.Close()
+			}
+		})
+	}
+}
+
+// TestPortForwarderCountsBytes proxies one request/response through a
+// forwarder and checks both directions and the connection are counted.
+func TestPortForwarderCountsBytes(t *testing.T) {
+	addr := freeAddr(t)
+	dial := func(uint32) (net.Conn, error) {
+		guest, host := net.Pipe()
+		go func() {
+			defer func() { _ = guest.Close() }()
+			buf := make([]byte, len("ping"))
+			if _, err := io.ReadFull(guest, buf); err == nil {
+				_, _ = guest.Write([]byte("pong!"))
+			}
+		}()
+		return host, nil
+	}
+	f := newPortForwarder("ssh", addr, 22, dial, testDialRetry)
+	if err := f.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial forwarder: %v", err)
+	}
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := io.ReadFull(conn, make([]byte, len("pong!"))); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	_ = conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for f.toGuest.Load() != 4 || f.fromGuest.Load() != 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("counted %d bytes to guest, %d from guest; want 4, 5", f.toGuest.Load(), f.fromGuest.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for f.activeConns.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d connections still active after the client closed", f.activeConns.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := f.totalConns.Load(); n != 1 {
+		t.Errorf("totalConns = %d, want 1", n)
+	}
+}
+
+// tcpPair returns the two ends of a loopback TCP connection, which, unlike
+// net.Pipe, can be half-closed.
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ln.Accept()
+		accepted <- c
+	}()
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := <-accepted
+	if s == nil {
+		t.Fatal("accept failed")
+	}
+	return c, s
+}
+
+// TestPortForwarderReplyAfterHalfClose sends a request and half-closes, the
+// way `ssh host cmd <file` ends its input, and checks the guest's reply,
+// written only after it sees EOF, still arrives in full.
+func TestPortForwarderReplyAfterHalfClose(t *testing.T) {
+	addr := freeAddr(t)
+	reply := strings.Repeat("x", 1<<20)
+	dial := func(uint32) (net.Conn, error) {
+		host, guest := tcpPair(t)
+		go func() {
+			defer func() { _ = guest.Close() }()
+			if _, err := io.ReadAll(guest); err == nil {
+				_, _ = io.WriteString(guest, reply)
+			}
+		}()
+		return host, nil
+	}
+	f := newPortForwarder("ssh", addr, 22, dial, testDialRetry)
+	if err := f.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial forwarder: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.Write([]byte("request")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_ = conn.(*net.TCPConn).CloseWrite()
+	got, err := io.ReadAll(conn)
+	if err != nil || len(got) != len(reply) {
+		t.Fatalf("read %d bytes (err %v), want the full %d-byte reply", len(got), err, len(reply))
+	}
+}
+
+// TestPortForwarderCloseDrains checks Close lets a connection in flight
+// finish its reply, and cuts off one still open once the drain times out.
+func TestPortForwarderCloseDrains(t *testing.T) {
+	addr := freeAddr(t)
+	release := make(chan struct{})
+	dial := func(uint32) (net.Conn, error) {
+		host, guest := tcpPair(t)
+		go func() {
+			defer func() { _ = guest.Close() }()
+			buf := make([]byte, len("ping"))
+			if _, err := io.ReadFull(guest, buf); err != nil {
+				return
+			}
+			<-release
+			_, _ = guest.Write([]byte("pong"))
+		}()
+		return host, nil
+	}
+	f := newPortForwarder("ssh", addr, 22, dial, testDialRetry)
+	f.drainTimeout = 2 * time.Second
+	if err := f.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial forwarder: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond) // let the forwarder pick the connection up
+
+	closed := make(chan struct{})
+	go func() {
+		_ = f.Close()
+		close(closed)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	buf := make([]byte, len("pong"))
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "pong" {
+		t.Fatalf("reply during drain = %q, %v; want pong", buf, err)
+	}
+	_ = conn.Close()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return once the connection finished")
+	}
+
+	// A connection that never finishes is closed when the drain runs out.
+	f = newPortForwarder("ssh", freeAddr(t), 22, func(uint32) (net.Conn, error) {
+		host, guest := tcpPair(t)
+		t.Cleanup(func() { _ = guest.Close() })
+		return host, nil
+	}, testDialRetry)
+	f.drainTimeout = 100 * time.Millisecond
+	if err := f.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	idle, err := net.Dial("tcp", f.listenAddr)
+	if err != nil {
+		t.Fatalf("dial forwarder: %v", err)
+	}
+	defer func() { _ = idle.Close() }()
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	_ = f.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close took %v with an idle connection, want about the 100ms drain", elapsed)
+	}
+}
+
+func TestPortForwarderCloseTwice(t *testing.T) {
+	f := newPortForwarder("fwd-8080", freeAddr(t), 8080, func(uint32) (net.Conn, error) {
+		return nil, errors.New("unused")
+	}, testDialRetry)
+	if err := f.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	_ = f.Close()
+	// A forward removed while Stop is closing everything closes it again.
+	if err := f.Close(); err != nil {
+		t.Errorf("second Close = %v, want nil", err)
+	}
+}
@@ -0,0 +1,31 @@
+package vm
+
+import "time"
+
+// ForwarderState describes one host-to-guest port forwarder for status and
+// the forward.list control command.
+type ForwarderState struct {
+	// Name identifies the forwarder ("ssh", "incus-api").
+	Name string
+	// Listen is the host address the forwarder binds when active.
+	Listen string
+	// Paused is true while the host listener is closed via PauseForwarders.
+	Paused bool
+	// BytesToGuest and BytesFromGuest count the bytes proxied in each
+	// direction since the forwarder started.
+	BytesToGuest   uint64
+	BytesFromGuest uint64
+	// ActiveConns is the number of connections being proxied now; TotalConns
+	// counts every connection accepted since the forwarder started.
+	ActiveConns int64
+	TotalConns  uint64
+}
+
+// Stats is a live snapshot of a running VM for the stats control command.
+type Stats struct {
+	// StartedAt is when the VM reached the running state; zero while booting.
+	StartedAt time.Time
+	// MemoryTargetGiB is the balloon's current target.
+	MemoryTargetGiB uint64
+	Forwarders      []ForwarderState
+}
@@ -0,0 +1,77 @@
+package vm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/report"
+)
+
+// guestOSCommand prints the running kernel release on the first line followed
+// by the guest's os-release file.
+const guestOSCommand = "uname -r && cat /etc/os-release"
+
+// ReadGuestOS reads the running guest's kernel release and OS name over SSH,
+// e.g. "6.8.0-45-generic" and "Ubuntu 24.04.1 LTS". This is what actually
+// booted, which can differ from what BaseImageURL suggests (a stale cached
+// image, or a guest that upgraded its kernel).
+func ReadGuestOS(cfg *config.Config) (kernel, osRelease string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := runGuestSSH(ctx, cfg.SSHConfigPath, nil, guestOSCommand)
+	if err != nil {
+		return "", "", fmt.Errorf("read guest os: %w", err)
+	}
+	kernel, osRelease = parseGuestOS(string(out))
+	if kernel == "" {
+		return "", "", fmt.Errorf("read guest os: no kernel release in output")
+	}
+	return kernel, osRelease, nil
+}
+
+// parseGuestOS splits guestOSCommand's output into the kernel release and the
+// os-release PRETTY_NAME, falling back to NAME and VERSION_ID when the guest
+// doesn't set one.
+func parseGuestOS(out string) (kernel, osRelease string) {
+	sc := bufio.NewScanner(strings.NewReader(out))
+	if sc.Scan() {
+		kernel = strings.TrimSpace(sc.Text())
+	}
+	fields := map[string]string{}
+	for sc.Scan() {
+		k, v, ok := strings.Cut(strings.TrimSpace(sc.Text()), "=")
+		if !ok || strings.HasPrefix(k, "#") {
+			continue
+		}
+		if uq, err := strconv.Unquote(v); err == nil {
+			v = uq
+		} else {
+			v = strings.Trim(v, `'"`)
+		}
+		fields[k] = v
+	}
+	osRelease = fields["PRETTY_NAME"]
+	if osRelease == "" {
+		osRelease = strings.TrimSpace(fields["NAME"] + " " + fields["VERSION_ID"])
+	}
+	return kernel, osRelease
+}
+
+// CheckGuestOS fills the report's guest kernel and OS release. An unreachable
+// guest leaves them blank; the reason is logged.
+func CheckGuestOS(cfg *config.Config, vm *report.VMInfo) {
+	kernel, osRelease, err := ReadGuestOS(cfg)
+	if err != nil {
+		logging.L().Warn("could not read guest kernel and OS release", "err", err)
+		return
+	}
+	vm.KernelVersion = kernel
+	vm.OSRelease = osRelease
+}
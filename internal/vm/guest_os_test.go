@@ -0,0 +1,52 @@
+package vm
+
+import "testing"
+
+func TestParseGuestOS(t *testing.T) {
+	tests := []struct {
+		name       string
+		out        string
+		wantKernel string
+		wantOS     string
+	}{
+		{
+			name: "ubuntu",
+			out: `6.8.0-45-generic
+PRETTY_NAME="Ubuntu 24.04.1 LTS"
+NAME="Ubuntu"
+VERSION_ID="24.04"
+`,
+			wantKernel: "6.8.0-45-generic",
+			wantOS:     "Ubuntu 24.04.1 LTS",
+		},
+		{
+			name: "debian",
+			out: `6.12.12+bpo-arm64
+PRETTY_NAME="Debian GNU/Linux 13 (trixie)"
+NAME="Debian GNU/Linux"
+`,
+			wantKernel: "6.12.12+bpo-arm64",
+			wantOS:     "Debian GNU/Linux 13 (trixie)",
+		},
+		{
+			name:       "no pretty name",
+			out:        "6.1.0\n# comment\nNAME=Alpine\nVERSION_ID='3.20'\n",
+			wantKernel: "6.1.0",
+			wantOS:     "Alpine 3.20",
+		},
+		{
+			name:       "kernel only",
+			out:        "6.1.0\n",
+			wantKernel: "6.1.0",
+		},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kernel, osRelease := parseGuestOS(tt.out)
+			if kernel != tt.wantKernel || osRelease != tt.wantOS {
+				t.Errorf("parseGuestOS = %q, %q; want %q, %q", kernel, osRelease, tt.wantKernel, tt.wantOS)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+//go:build darwin
+
+package vm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// guestRebootTimeout bounds the SSH round trip that asks the guest to reboot;
+// the reboot itself runs on after the session returns.
+const guestRebootTimeout = 15 * time.Second
+
+// rebootGuest asks the guest to reboot itself over SSH. --no-block queues the
+// reboot and returns, so the session ends cleanly instead of being cut off.
+func rebootGuest(ctx context.Context, sshConfigPath string) error {
+	ctx, cancel := context.WithTimeout(ctx, guestRebootTimeout)
+	defer cancel()
+
+	if _, err := runGuestSSH(ctx, sshConfigPath, nil, "sudo", "-n", "systemctl", "--no-block", "reboot"); err != nil {
+		return fmt.Errorf("reboot guest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// guestSSHHost is the host alias every generated ssh config defines (see
+// ssh.WriteSSHConfig).
+const guestSSHHost = "bladerunner"
+
+// errGuestSSHTimeout is returned by runGuestSSH when ctx's deadline passes
+// before the remote command finishes.
+var errGuestSSHTimeout = errors.New("timeout")
+
+// guestSSHArgs builds the ssh arguments that run argv in the guest through
+// the config at sshConfigPath, failing fast rather than prompting.
+func guestSSHArgs(sshConfigPath string, argv []string) []string {
+	args := make([]string, 0, 6+len(argv))
+	args = append(args,
+		"-F", sshConfigPath,
+		"-o", "ConnectTimeout=5",
+		"-o", "BatchMode=yes",
+		guestSSHHost,
+	)
+	return append(args, argv...)
+}
+
+// rootScript is the argv that runs script as root through the guest's sh.
+func rootScript(script string) []string {
+	return []string{"sudo", "-n", "sh", "-c", shellQuote(script)}
+}
+
+// runGuestSSH runs argv in the guest over SSH, feeding it stdin when that is
+// not nil, and returns what it wrote to stdout. Callers bound it with ctx. A
+// failed command's error wraps the *exec.ExitError and carries its stderr;
+// one cut off by ctx's deadline is errGuestSSHTimeout. stdout is returned
+// either way, for commands whose exit status alone is not a failure.
+func runGuestSSH(ctx context.Context, sshConfigPath string, stdin io.Reader, argv ...string) ([]byte, error) {
+	if sshConfigPath == "" {
+		return nil, errors.New("ssh config path not set")
+	}
+	cmd := exec.CommandContext(ctx, "ssh", guestSSHArgs(sshConfigPath, argv)...)
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return stdout.Bytes(), errGuestSSHTimeout
+		}
+		return stdout.Bytes(), fmt.Errorf("%w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
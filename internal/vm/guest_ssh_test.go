@@ -0,0 +1,27 @@
+package vm
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestGuestSSHArgs(t *testing.T) {
+	got := guestSSHArgs("/tmp/ssh_config", rootScript("echo 'hi'"))
+	want := []string{
+		"-F", "/tmp/ssh_config",
+		"-o", "ConnectTimeout=5",
+		"-o", "BatchMode=yes",
+		"bladerunner",
+		"sudo", "-n", "sh", "-c", `'echo '\''hi'\'''`,
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("guestSSHArgs = %q, want %q", got, want)
+	}
+}
+
+func TestRunGuestSSHRequiresConfigPath(t *testing.T) {
+	if _, err := runGuestSSH(context.Background(), "", nil, "true"); err == nil {
+		t.Fatal("runGuestSSH with no ssh config path succeeded")
+	}
+}
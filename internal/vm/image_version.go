@@ -1,10 +1,8 @@
 package vm
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -22,35 +20,16 @@ import (
 // Callers (e.g. `br status`) should treat any error as "unknown" and render
 // a fallback string; the absence of a version is informational, not fatal.
 func ReadGuestImageVersion(cfg *config.Config) (string, error) {
-	if cfg.SSHConfigPath == "" {
-		return "", fmt.Errorf("ssh config path not set")
-	}
-
 	// Hard cap the SSH probe at 10s so a stuck VM never wedges `br status`.
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	args := []string{
-		"-F", cfg.SSHConfigPath,
-		"-o", "ConnectTimeout=5",
-		"-o", "BatchMode=yes",
-		"bladerunner",
-		"cat", config.GuestImageVersionPath,
-	}
-
-	cmd := exec.CommandContext(ctx, "ssh", args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("read guest image version: timeout")
-		}
-		return "", fmt.Errorf("read guest image version: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	out, err := runGuestSSH(ctx, cfg.SSHConfigPath, nil, "cat", config.GuestImageVersionPath)
+	if err != nil {
+		return "", fmt.Errorf("read guest image version: %w", err)
 	}
 
-	version := strings.TrimSpace(stdout.String())
+	version := strings.TrimSpace(string(out))
 	if version == "" {
 		return "", fmt.Errorf("guest image version file is empty")
 	}
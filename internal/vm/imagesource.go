@@ -0,0 +1,147 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/util"
+)
+
+// ImageSource acquires a base image artifact. Fetch writes the image to dest,
+// replacing anything already there; it only moves bytes. Checksum
+// verification, qcow2->raw conversion, and caching stay in ensureBaseImage so
+// every source shares them.
+type ImageSource interface {
+	Fetch(ctx context.Context, dest string) error
+}
+
+// HTTPImageSource downloads the image from an http(s) URL. It is the source
+// behind Config.BaseImageURL.
+type HTTPImageSource struct {
+	URL string
+}
+
+// Fetch downloads the image to dest with a progress line.
+func (s HTTPImageSource) Fetch(ctx context.Context, dest string) error {
+	return downloadFile(ctx, s.URL, dest)
+}
+
+func (s HTTPImageSource) String() string { return s.URL }
+
+// FileImageSource is an image already on the local disk. It is the source
+// behind Config.BaseImagePath, which ensureBaseImage uses in place; Fetch
+// copies it for callers that need the image somewhere else.
+type FileImageSource struct {
+	Path string
+}
+
+// Fetch copies the image to dest, keeping zero runs sparse.
+func (s FileImageSource) Fetch(ctx context.Context, dest string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	in, err := os.Open(s.Path)
+	if err != nil {
+		return fmt.Errorf("open base image: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	tmpPath := dest + ".tmp"
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create temp image file: %w", err)
+	}
+	if err := util.WriteSparse(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("copy base image: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("close temp image file: %w", err)
+	}
+	if err := os.Rename(tmpPath, dest); err != nil {
+		return fmt.Errorf("move copied image into place: %w", err)
+	}
+	return nil
+}
+
+func (s FileImageSource) String() string { return s.Path }
+
+// ImageSourceFactory builds the source for a Config.BaseImageRef whose scheme
+// it was registered under. ref is the full reference, scheme included.
+type ImageSourceFactory func(ref string) (ImageSource, error)
+
+var (
+	imageSourcesMu sync.RWMutex
+	imageSources   = map[string]ImageSourceFactory{}
+)
+
+// RegisterImageSource makes refs of the form "<scheme>://..." in
+// Config.BaseImageRef resolve through factory. A new kind of image store
+// (an Incus image server, an OCI registry, S3) plugs in here without
+// touching ensureBaseImage or the runner. Registering a scheme twice panics.
+func RegisterImageSource(scheme string, factory ImageSourceFactory) {
+	imageSourcesMu.Lock()
+	defer imageSourcesMu.Unlock()
+	if _, dup := imageSources[scheme]; dup {
+		panic("vm: image source registered twice for scheme " + scheme)
+	}
+	imageSources[scheme] = factory
+}
+
+// imageSourceForRef resolves a Config.BaseImageRef through the registry.
+// http(s) refs are accepted too and map to HTTPImageSource.
+func imageSourceForRef(ref string) (ImageSource, error) {
+	scheme, _, ok := strings.Cut(ref, "://")
+	if !ok || scheme == "" {
+		return nil, fmt.Errorf("base image ref %q has no scheme (want <scheme>://...)", ref)
+	}
+	if scheme == "http" || scheme == "https" {
+		return HTTPImageSource{URL: ref}, nil
+	}
+	imageSourcesMu.RLock()
+	factory, ok := imageSources[scheme]
+	imageSourcesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no image source registered for %q refs", scheme)
+	}
+	return factory(ref)
+}
+
+// baseImageSource picks the source for a download: BaseImageRef when set,
+// otherwise the BaseImageURL over HTTP.
+func baseImageSource(cfg *config.Config) (ImageSource, error) {
+	if cfg.BaseImageRef != "" {
+		return imageSourceForRef(cfg.BaseImageRef)
+	}
+	if cfg.BaseImageURL == "" {
+		return nil, fmt.Errorf("base image url is empty")
+	}
+	return HTTPImageSource{URL: cfg.BaseImageURL}, nil
+}
+
+// fetchFromRef fetches cfg.BaseImageRef to path and converts it to raw.
+// Sources reached through the registry own their integrity checks (an OCI
+// pull verifies layer digests, for example); a disk manifest's SHA-256 pin is
+// enforced by ensureCachedBaseImage, which handles that case before this.
+func fetchFromRef(ctx context.Context, cfg *config.Config, path string) (string, error) {
+	src, err := imageSourceForRef(cfg.BaseImageRef)
+	if err != nil {
+		return "", err
+	}
+	logging.L().Info("fetching base image", "ref", cfg.BaseImageRef, "destination", path)
+	if err := src.Fetch(ctx, path); err != nil {
+		return "", err
+	}
+	if err := ensureRawDiskImage(path); err != nil {
+		return "", err
+	}
+	logging.L().Info("fetched base image", "path", path)
+	return path, nil
+}
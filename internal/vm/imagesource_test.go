@@ -0,0 +1,74 @@
+package vm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+// memSource is a minimal ImageSource, standing in for a new store plugged in
+// through RegisterImageSource.
+type memSource struct {
+	data []byte
+}
+
+func (s memSource) Fetch(_ context.Context, dest string) error {
+	return os.WriteFile(dest, s.data, 0o644)
+}
+
+func TestEnsureBaseImage_RegisteredSource(t *testing.T) {
+	RegisterImageSource("memtest", func(ref string) (ImageSource, error) {
+		return memSource{data: []byte("image for " + ref)}, nil
+	})
+
+	cfg := &config.Config{VMDir: t.TempDir(), BaseImageRef: "memtest://debian/trixie"}
+	path, err := ensureBaseImage(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("ensureBaseImage: %v", err)
+	}
+	if path != filepath.Join(cfg.VMDir, "base-image.raw") {
+		t.Errorf("path = %s", path)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "image for memtest://debian/trixie" {
+		t.Errorf("base image = %q", got)
+	}
+}
+
+func TestImageSourceForRef(t *testing.T) {
+	src, err := imageSourceForRef("https://example.test/image.qcow2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, ok := src.(HTTPImageSource); !ok || h.URL != "https://example.test/image.qcow2" {
+		t.Errorf("https ref resolved to %#v", src)
+	}
+
+	for ref, want := range map[string]string{
+		"oci://ghcr.io/x/y:latest": "no image source registered",
+		"just-a-name":              "no scheme",
+	} {
+		if _, err := imageSourceForRef(ref); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("imageSourceForRef(%q) err = %v, want %q", ref, err, want)
+		}
+	}
+}
+
+func TestFileImageSourceFetch(t *testing.T) {
+	data := append([]byte("head"), make([]byte, 128<<10)...)
+	src := FileImageSource{Path: writeTempFile(t, data)}
+	dest := filepath.Join(t.TempDir(), "copy.raw")
+	if err := src.Fetch(context.Background(), dest); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(data) || string(got[:4]) != "head" {
+		t.Errorf("copy is %d bytes, want %d", len(got), len(data))
+	}
+}
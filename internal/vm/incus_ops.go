@@ -0,0 +1,145 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+// incusOpsScript lists the guest's Incus operations as the API's
+// /1.0/operations?recursion=1 document. It prints nothing until incusd is up.
+const incusOpsScript = "command -v incus >/dev/null 2>&1 && incus query '/1.0/operations?recursion=1' 2>/dev/null || true"
+
+// incusOpsPollInterval paces the operation polls during the Incus wait.
+const incusOpsPollInterval = 3 * time.Second
+
+// patternOpPercent finds the percentage in an operation's progress metadata,
+// e.g. "rootfs: 62% (12.30MB/s)" or "Unpack: 45%".
+var patternOpPercent = regexp.MustCompile(`(\d{1,3})%`)
+
+// readIncusOperations fetches the guest's running and recent Incus operations
+// over SSH. The host's client certificate only joins the trust store at the
+// end of the bootstrap, so the host-side API cannot see the image pulls the
+// bootstrap itself starts; incus in the guest, as root, can.
+func readIncusOperations(ctx context.Context, sshConfigPath string) ([]api.Operation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	out, err := runGuestSSH(ctx, sshConfigPath, nil, rootScript(incusOpsScript)...)
+	if err != nil {
+		return nil, fmt.Errorf("read incus operations: %w", err)
+	}
+	return parseIncusOperations(out)
+}
+
+// parseIncusOperations decodes /1.0/operations?recursion=1, which groups
+// operations by status ("running", "success", ...). Empty input is no
+// operations.
+func parseIncusOperations(data []byte) ([]api.Operation, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var byStatus map[string][]api.Operation
+	if err := json.Unmarshal(data, &byStatus); err != nil {
+		return nil, fmt.Errorf("parse incus operations: %w", err)
+	}
+	var ops []api.Operation
+	for _, group := range byStatus {
+		ops = append(ops, group...)
+	}
+	return ops, nil
+}
+
+// summarizeOperations describes the running operation most worth showing in
+// a status line, e.g. "incus: downloading image 62%". Operations reporting a
+// percentage win over those that don't; ties go to the oldest. It returns ""
+// when nothing is running.
+func summarizeOperations(ops []api.Operation) string {
+	type candidate struct {
+		op  api.Operation
+		pct string
+	}
+	var running []candidate
+	for _, op := range ops {
+		if op.StatusCode != api.Running {
+			continue
+		}
+		running = append(running, candidate{op: op, pct: operationPercent(op)})
+	}
+	if len(running) == 0 {
+		return ""
+	}
+	sort.SliceStable(running, func(i, j int) bool {
+		if (running[i].pct != "") != (running[j].pct != "") {
+			return running[i].pct != ""
+		}
+		return running[i].op.CreatedAt.Before(running[j].op.CreatedAt)
+	})
+
+	best := running[0]
+	desc := strings.TrimSpace(best.op.Description)
+	if desc == "" {
+		desc = "operation running"
+	}
+	msg := "incus: " + strings.ToLower(desc[:1]) + desc[1:]
+	if best.pct != "" {
+		msg += " " + best.pct
+	}
+	if n := len(running) - 1; n > 0 {
+		msg += fmt.Sprintf(" (+%d more)", n)
+	}
+	return msg
+}
+
+// operationPercent returns the percentage from an operation's *_progress
+// metadata, or "" if it reports none.
+func operationPercent(op api.Operation) string {
+	keys := make([]string, 0, len(op.Metadata))
+	for k := range op.Metadata {
+		if strings.HasSuffix(k, "progress") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s, ok := op.Metadata[k].(string)
+		if !ok {
+			continue
+		}
+		if m := patternOpPercent.FindStringSubmatch(s); m != nil {
+			return m[1] + "%"
+		}
+	}
+	return ""
+}
+
+// watchIncusOperations polls the guest's Incus operations until ctx ends,
+// passing each change of summary (including back to "") to report. Poll
+// failures are expected while the guest boots and are ignored.
+func watchIncusOperations(ctx context.Context, sshConfigPath string, interval time.Duration, report func(string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ops, err := readIncusOperations(ctx, sshConfigPath)
+		if err != nil {
+			continue
+		}
+		if s := summarizeOperations(ops); s != last {
+			last = s
+			report(s)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package vm
+
+import (
+	"testing"
+)
+
+func TestSummarizeOperations(t *testing.T) {
+	doc := []byte(`{
+  "running": [
+    {"id": "a", "class": "task", "description": "Creating instance", "created_at": "2026-10-17T10:00:00Z",
+     "status": "Running", "status_code": 103, "metadata": null},
+    {"id": "b", "class": "task", "description": "Downloading image", "created_at": "2026-10-17T10:00:05Z",
+     "status": "Running", "status_code": 103, "metadata": {"download_progress": "rootfs: 62% (12.30MB/s)"}}
+  ],
+  "success": [
+    {"id": "c", "class": "task", "description": "Updating profile", "created_at": "2026-10-17T09:59:00Z",
+     "status": "Success", "status_code": 200, "metadata": null}
+  ]
+}`)
+	ops, err := parseIncusOperations(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 3 {
+		t.Fatalf("parsed %d operations, want 3", len(ops))
+	}
+	if got, want := summarizeOperations(ops), "incus: downloading image 62% (+1 more)"; got != want {
+		t.Errorf("summarizeOperations = %q, want %q", got, want)
+	}
+}
+
+func TestSummarizeOperationsIdle(t *testing.T) {
+	ops, err := parseIncusOperations([]byte("\n"))
+	if err != nil || ops != nil {
+		t.Fatalf("empty output: %v %v", ops, err)
+	}
+	if got := summarizeOperations(nil); got != "" {
+		t.Errorf("no operations summarized as %q", got)
+	}
+
+	ops, err = parseIncusOperations([]byte(`{"success": [{"description": "Downloading image", "status_code": 200}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summarizeOperations(ops); got != "" {
+		t.Errorf("finished operations summarized as %q", got)
+	}
+
+	if _, err := parseIncusOperations([]byte("Error: not found")); err == nil {
+		t.Error("garbage should fail to parse")
+	}
+}
+
+func TestSummarizeOperationsWithoutPercent(t *testing.T) {
+	ops, err := parseIncusOperations([]byte(`{"running": [{"description": "Creating instance", "status_code": 103,
+	  "metadata": {"create_instance_from_image_unpack_progress": "Unpack: 45%"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := summarizeOperations(ops), "incus: creating instance 45%"; got != want {
+		t.Errorf("summarizeOperations = %q, want %q", got, want)
+	}
+
+	ops[0].Metadata = nil
+	if got, want := summarizeOperations(ops), "incus: creating instance"; got != want {
+		t.Errorf("summarizeOperations = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+const (
+	// IncusdLogPath is incusd's own log file inside the guest.
+	IncusdLogPath = "/var/log/incus/incusd.log"
+
+	// incusdLogTailLines bounds how much of the log lands in the report.
+	incusdLogTailLines = 50
+)
+
+// ErrIncusdLogAbsent means the guest was reachable but had no incusd log to
+// read — typically Incus was never installed or never started.
+var ErrIncusdLogAbsent = errors.New("incusd log not present in guest")
+
+// incusdLogTailScript prints the last n lines of the incusd log, falling back
+// to the incus unit's journal when the file doesn't exist (the daemon may
+// only log there). It prints nothing when neither has content.
+func incusdLogTailScript(n int) string {
+	return fmt.Sprintf(
+		"if [ -s %[1]s ]; then tail -n %[2]d %[1]s; else journalctl -u incus --no-pager -n %[2]d -o short-iso 2>/dev/null | grep -v '^-- No entries --$'; fi",
+		IncusdLogPath, n)
+}
+
+// IncusdLogStreamLines is how much history incusdLogStreamScript prints before
+// following, when no --since window is given.
+const IncusdLogStreamLines = 100
+
+// incusdLogStreamScript returns the guest shell script behind `br logs
+// --incus`. Without since it prints the last IncusdLogStreamLines lines of the
+// incusd log file, or of the incus unit's journal when the file is absent;
+// since selects the journal (the file has no time index) and is passed to
+// journalctl --since. follow keeps the stream open. The script exits non-zero
+// with a message on stderr when the guest has neither source.
+func incusdLogStreamScript(follow bool, since string) string {
+	var tailArgs, journalArgs []string
+	if since != "" {
+		journalArgs = append(journalArgs, "--since", shellQuote(journalSince(since)))
+	} else {
+		tailArgs = append(tailArgs, "-n", strconv.Itoa(IncusdLogStreamLines))
+		journalArgs = append(journalArgs, "-n", strconv.Itoa(IncusdLogStreamLines))
+	}
+	if follow {
+		tailArgs = append(tailArgs, "-F")
+		journalArgs = append(journalArgs, "-f")
+	}
+
+	var b strings.Builder
+	if since == "" {
+		fmt.Fprintf(&b, "if [ -s %[1]s ]; then exec tail %[2]s %[1]s; fi; ", IncusdLogPath, strings.Join(tailArgs, " "))
+	}
+	fmt.Fprintf(&b, `if [ -n "$(journalctl -u incus -q -n 1 --no-pager 2>/dev/null)" ]; then exec journalctl -u incus --no-pager -o short-iso %s; fi; `, strings.Join(journalArgs, " "))
+	fmt.Fprintf(&b, "echo 'no incusd log in guest (%s is missing and the incus unit has no journal entries)' >&2; exit 1", IncusdLogPath)
+	return b.String()
+}
+
+// IncusdLogStreamCommand is the remote command (for ssh's argument tail) that
+// streams incusd's log as described by incusdLogStreamScript.
+func IncusdLogStreamCommand(follow bool, since string) []string {
+	return []string{"sudo", "-n", "sh", "-c", shellQuote(incusdLogStreamScript(follow, since))}
+}
+
+// journalSince turns a Go duration ("15m", "2h") into the relative form
+// journalctl understands ("-900s"); anything else (a timestamp, "today",
+// "1 hour ago") is passed through for journalctl to interpret.
+func journalSince(since string) string {
+	if d, err := time.ParseDuration(since); err == nil && d > 0 {
+		return fmt.Sprintf("-%ds", int64(d.Round(time.Second)/time.Second))
+	}
+	return since
+}
+
+// ReadIncusdLogTail fetches the tail of incusd's log from the guest over SSH.
+// It is a diagnostic for a failed Incus readiness wait: SSH (over vsock) is
+// often reachable when the Incus API is not. Returns ErrIncusdLogAbsent when
+// the guest has no incusd log, and an error when SSH itself fails.
+func ReadIncusdLogTail(cfg *config.Config) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	out, err := runGuestSSH(ctx, cfg.SSHConfigPath, nil, rootScript(incusdLogTailScript(incusdLogTailLines))...)
+	if err != nil {
+		// grep exits 1 when the journal fallback had nothing to print.
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 || len(out) > 0 {
+			return "", fmt.Errorf("read incusd log: %w", err)
+		}
+	}
+
+	tail := strings.TrimRight(string(out), "\n")
+	if strings.TrimSpace(tail) == "" {
+		return "", ErrIncusdLogAbsent
+	}
+	return tail, nil
+}
+
+// lastLogLine returns the last non-empty line of a log tail, used to put the
+// most likely cause straight into a readiness error.
+func lastLogLine(tail string) string {
+	lines := strings.Split(strings.TrimSpace(tail), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(lines[i]); l != "" {
+			return l
+		}
+	}
+	return ""
+}
+
+// shellQuote single-quotes s for the remote shell ssh hands the command to.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,107 @@
+package vm
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+func TestIncusdLogTailScriptFallsBackToJournal(t *testing.T) {
+	script := incusdLogTailScript(50)
+	for _, want := range []string{
+		"[ -s " + IncusdLogPath + " ]",
+		"tail -n 50 " + IncusdLogPath,
+		"journalctl -u incus --no-pager -n 50",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q: %s", want, script)
+		}
+	}
+}
+
+func TestIncusdLogStreamScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		follow  bool
+		since   string
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "tail prefers the log file",
+			want: []string{
+				"[ -s " + IncusdLogPath + " ]",
+				"exec tail -n 100 " + IncusdLogPath,
+				"exec journalctl -u incus --no-pager -o short-iso -n 100;",
+			},
+			notWant: []string{"-F", " -f"},
+		},
+		{
+			name:   "follow",
+			follow: true,
+			want:   []string{"exec tail -n 100 -F " + IncusdLogPath, "-n 100 -f;"},
+		},
+		{
+			name:    "since uses the journal only",
+			since:   "2024-05-01 10:00",
+			follow:  true,
+			want:    []string{"--since '2024-05-01 10:00' -f;"},
+			notWant: []string{"tail", "-n 100 -f"},
+		},
+		{
+			name:  "since duration becomes relative",
+			since: "15m",
+			want:  []string{"--since '-900s'"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			script := incusdLogStreamScript(tc.follow, tc.since)
+			for _, w := range tc.want {
+				if !strings.Contains(script, w) {
+					t.Errorf("script missing %q: %s", w, script)
+				}
+			}
+			for _, w := range tc.notWant {
+				if strings.Contains(script, w) {
+					t.Errorf("script unexpectedly contains %q: %s", w, script)
+				}
+			}
+		})
+	}
+}
+
+// TestShellQuoteRoundTrip checks the quoted script survives a POSIX shell
+// unchanged, since ssh joins its args into one remote command line.
+func TestShellQuoteRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	in := incusdLogTailScript(5) + " 'quoted' \"double\""
+	out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(in)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Errorf("round trip = %q, want %q", out, in)
+	}
+}
+
+func TestLastLogLine(t *testing.T) {
+	tail := "time=1 level=info msg=starting\ntime=2 level=error msg=\"Failed to start the daemon\" err=\"no space left\"\n\n"
+	got := lastLogLine(tail)
+	if !strings.Contains(got, "no space left") {
+		t.Errorf("lastLogLine = %q", got)
+	}
+	if lastLogLine("  \n") != "" {
+		t.Error("lastLogLine of blank input should be empty")
+	}
+}
+
+func TestReadIncusdLogTailNeedsSSHConfig(t *testing.T) {
+	if _, err := ReadIncusdLogTail(&config.Config{}); err == nil {
+		t.Fatal("expected error without an ssh config path")
+	}
+}
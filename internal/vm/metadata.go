@@ -20,27 +20,43 @@ type runtimeMetadata struct {
 	MACAddress string `json:"mac_address"`
 }
 
+// loadOrCreateMetadata returns the VM's persisted metadata, creating it with
+// a random MAC on first start. A configured MAC (Config.MACAddress) replaces
+// the persisted one and is saved, so it stays after the setting is dropped.
 func loadOrCreateMetadata(cfg *config.Config) (*runtimeMetadata, error) {
+	var md runtimeMetadata
 	if util.FileExists(cfg.MetadataPath) {
-		b, err := os.ReadFile(cfg.MetadataPath)
-		if err == nil {
-			var md runtimeMetadata
-			if err := json.Unmarshal(b, &md); err == nil && md.MACAddress != "" {
-				return &md, nil
+		if b, err := os.ReadFile(cfg.MetadataPath); err == nil {
+			if err := json.Unmarshal(b, &md); err != nil {
+				md = runtimeMetadata{}
 			}
 		}
 	}
 
-	mac, err := generateLocalMAC()
-	if err != nil {
-		return nil, err
+	switch {
+	case cfg.MACAddress != "":
+		hw, err := net.ParseMAC(cfg.MACAddress)
+		if err != nil {
+			return nil, fmt.Errorf("parse configured mac address %q: %w", cfg.MACAddress, err)
+		}
+		if md.MACAddress == hw.String() {
+			return &md, nil
+		}
+		md.MACAddress = hw.String()
+	case md.MACAddress != "":
+		return &md, nil
+	default:
+		mac, err := generateLocalMAC()
+		if err != nil {
+			return nil, err
+		}
+		md.MACAddress = mac.String()
 	}
 
-	md := &runtimeMetadata{MACAddress: mac.String()}
-	if err := saveMetadata(cfg, md); err != nil {
+	if err := saveMetadata(cfg, &md); err != nil {
 		return nil, err
 	}
-	return md, nil
+	return &md, nil
 }
 
 func saveMetadata(cfg *config.Config, md *runtimeMetadata) error {
@@ -0,0 +1,36 @@
+//go:build darwin
+
+package vm
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+func TestLoadOrCreateMetadataMAC(t *testing.T) {
+	cfg := &config.Config{MetadataPath: filepath.Join(t.TempDir(), "metadata.json")}
+
+	md, err := loadOrCreateMetadata(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated := md.MACAddress
+	if generated == "" {
+		t.Fatal("no MAC generated")
+	}
+	if md, _ = loadOrCreateMetadata(cfg); md.MACAddress != generated {
+		t.Errorf("second start MAC = %q, want the persisted %q", md.MACAddress, generated)
+	}
+
+	// A configured MAC wins, canonicalized, and is persisted.
+	cfg.MACAddress = "52-54-00-AB-CD-EF"
+	if md, err = loadOrCreateMetadata(cfg); err != nil || md.MACAddress != "52:54:00:ab:cd:ef" {
+		t.Fatalf("configured MAC = %q, %v; want 52:54:00:ab:cd:ef", md.MACAddress, err)
+	}
+	cfg.MACAddress = ""
+	if md, _ = loadOrCreateMetadata(cfg); md.MACAddress != "52:54:00:ab:cd:ef" {
+		t.Errorf("MAC after dropping the setting = %q, want the pinned one kept", md.MACAddress)
+	}
+}
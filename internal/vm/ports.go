@@ -0,0 +1,63 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+// PortInUseError reports a host address a forwarder needs that another
+// process already listens on.
+type PortInUseError struct {
+	Name string // forwarder name (ssh, incus-api, fwd-...)
+	Addr string // host listen address
+	Err  error
+}
+
+func (e *PortInUseError) Error() string {
+	_, port, _ := net.SplitHostPort(e.Addr)
+	return fmt.Sprintf("local port %s for the %s forward is already in use; %s", port, e.Name, portInUseHint(e.Name))
+}
+
+func (e *PortInUseError) Unwrap() error { return e.Err }
+
+// portInUseHint names the flag that moves the forward to another port.
+func portInUseHint(name string) string {
+	switch name {
+	case config.ForwardSSH:
+		return "stop whatever owns it or pick another port with --ssh-port (or --auto-port)"
+	case config.ForwardIncusAPI:
+		return "stop whatever owns it or pick another port with --api-port (or --auto-port)"
+	default:
+		return "stop whatever owns it or pick another local port in its --forward spec"
+	}
+}
+
+// bindError turns a failed bind of spec's host address into a
+// PortInUseError when the port is taken, and leaves other errors as they are.
+func bindError(spec config.ForwardSpec, err error) error {
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return &PortInUseError{Name: spec.Name, Addr: spec.LocalAddr, Err: err}
+	}
+	return err
+}
+
+// checkLocalPorts binds and releases every forward's host address, so a port
+// owned by another process fails the start before any provisioning work
+// rather than when the forwarders start.
+func checkLocalPorts(specs []config.ForwardSpec) error {
+	for _, spec := range specs {
+		ln, err := net.Listen("tcp", spec.LocalAddr)
+		if err != nil {
+			if errors.Is(err, syscall.EADDRINUSE) {
+				return bindError(spec, err)
+			}
+			return fmt.Errorf("check %s port %s: %w", spec.Name, spec.LocalAddr, err)
+		}
+		_ = ln.Close()
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package vm
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+func TestCheckLocalPorts(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = busy.Close() }()
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	freeAddr := free.Addr().String()
+	_ = free.Close()
+
+	specs := []config.ForwardSpec{
+		{Name: config.ForwardSSH, LocalAddr: freeAddr},
+		{Name: config.ForwardIncusAPI, LocalAddr: busy.Addr().String()},
+	}
+	err = checkLocalPorts(specs)
+	var inUse *PortInUseError
+	if !errors.As(err, &inUse) {
+		t.Fatalf("checkLocalPorts = %v, want a PortInUseError", err)
+	}
+	if inUse.Name != config.ForwardIncusAPI {
+		t.Errorf("conflict reported for %q, want %q", inUse.Name, config.ForwardIncusAPI)
+	}
+	_, port, _ := net.SplitHostPort(busy.Addr().String())
+	if msg := err.Error(); !strings.Contains(msg, "local port "+port) || !strings.Contains(msg, "--api-port") {
+		t.Errorf("error %q should name port %s and suggest --api-port", msg, port)
+	}
+
+	if err := checkLocalPorts(specs[:1]); err != nil {
+		t.Errorf("checkLocalPorts on a free port = %v", err)
+	}
+}
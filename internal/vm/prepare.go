@@ -0,0 +1,18 @@
+package vm
+
+// Artifacts lists the on-disk provisioning outputs built before a VM boots:
+// what `br prepare` stages and a later `br start` reuses.
+type Artifacts struct {
+	ClientCertPath string `json:"client_cert"`
+	ClientKeyPath  string `json:"client_key"`
+	UserDataPath   string `json:"user_data,omitempty"`
+	MetaDataPath   string `json:"meta_data,omitempty"`
+	CloudInitISO   string `json:"cloud_init_iso"`
+	BaseImagePath  string `json:"base_image"`
+	DiskPath       string `json:"disk"`
+	// DiskCreated is false when an existing main disk was reused as-is.
+	DiskCreated bool `json:"disk_created"`
+
+	certPEM []byte
+	keyPEM  []byte
+}
@@ -0,0 +1,18 @@
+package vm
+
+import (
+	"reflect"
+
+	incusctl "github.com/stuffbucket/bladerunner/internal/incus"
+)
+
+// reportRefreshNeeded reports whether the startup report is stale: the Incus
+// API has become reachable or stopped being so, or now describes itself
+// differently (a restart, an upgrade, new addresses). Anything else would
+// rewrite the same report, so it is skipped.
+func reportRefreshNeeded(prev, cur *incusctl.ServerInfo) bool {
+	if (prev == nil) != (cur == nil) {
+		return true
+	}
+	return cur != nil && !reflect.DeepEqual(*prev, *cur)
+}
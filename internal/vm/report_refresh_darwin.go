@@ -0,0 +1,77 @@
+//go:build darwin
+
+package vm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	incusctl "github.com/stuffbucket/bladerunner/internal/incus"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/report"
+)
+
+// reportRefreshMisses is how many consecutive failed probes RefreshReport needs
+// before it reports a previously ready Incus API as down.
+const reportRefreshMisses = 2
+
+// RefreshReport keeps the startup report current for as long as ctx lives.
+// Every interval it probes the Incus API once; when readiness flips or the
+// server info changes it regenerates and re-saves the report, so a VM whose
+// Incus came up after the startup wait gave up no longer reports it as down.
+// Probes that find nothing new write nothing, and a single failed probe of a
+// ready API is not trusted as an outage. Call after WaitForIncus.
+func (r *Runner) RefreshReport(ctx context.Context, interval time.Duration) {
+	log := logging.L()
+	endpoint := fmt.Sprintf("https://127.0.0.1:%d", r.cfg.LocalAPIPort)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	misses := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		certPEM, keyPEM := r.clientCreds()
+		info, err := incusctl.ProbeServer(endpoint, certPEM, keyPEM)
+		if err != nil {
+			info = nil
+		}
+		prev := r.serverInfo.Load()
+		if info == nil && prev != nil {
+			if misses++; misses < reportRefreshMisses {
+				continue
+			}
+		} else {
+			misses = 0
+		}
+		if !reportRefreshNeeded(prev, info) {
+			continue
+		}
+
+		var data *report.StartupReport
+		if info != nil {
+			if prev == nil {
+				log.Info("Incus API is ready; refreshing startup report", "endpoint", endpoint)
+			}
+			data = r.makeReport(r.baseImagePath, endpoint, info)
+			data.Clock = CheckGuestClock(r.cfg)
+			data.Boot = CheckBoot(r.cfg)
+			CheckGuestOS(r.cfg, &data.VM)
+		} else {
+			log.Warn("Incus API stopped answering; refreshing startup report", "endpoint", endpoint, "err", err)
+			data = r.makeReport(r.baseImagePath, endpoint, nil)
+			data.Incus.Diagnostics = r.incusDiagnostics(err)
+		}
+		if err := report.SaveJSON(r.cfg.ReportPath, data); err != nil {
+			log.Warn("failed to refresh startup report", "path", r.cfg.ReportPath, "err", err)
+			continue
+		}
+		r.serverInfo.Store(info)
+		r.incusReady.Store(info != nil)
+	}
+}
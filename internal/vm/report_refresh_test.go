@@ -0,0 +1,32 @@
+package vm
+
+import (
+	"testing"
+
+	incusctl "github.com/stuffbucket/bladerunner/internal/incus"
+)
+
+func TestReportRefreshNeeded(t *testing.T) {
+	ready := &incusctl.ServerInfo{ServerVersion: "6.0", Auth: "trusted", Addresses: []string{"10.0.0.2:8443"}}
+	same := &incusctl.ServerInfo{ServerVersion: "6.0", Auth: "trusted", Addresses: []string{"10.0.0.2:8443"}}
+	upgraded := &incusctl.ServerInfo{ServerVersion: "6.1", Auth: "trusted", Addresses: []string{"10.0.0.2:8443"}}
+
+	tests := []struct {
+		name      string
+		prev, cur *incusctl.ServerInfo
+		want      bool
+	}{
+		{"still not ready", nil, nil, false},
+		{"came up after the wait", nil, ready, true},
+		{"went down", ready, nil, true},
+		{"unchanged", ready, same, false},
+		{"server info changed", ready, upgraded, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := reportRefreshNeeded(tc.prev, tc.cur); got != tc.want {
+				t.Errorf("reportRefreshNeeded = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
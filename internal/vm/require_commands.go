@@ -0,0 +1,42 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+)
+
+// MissingCommandsError reports required guest commands (Config.RequireCommands)
+// that provisioning did not install.
+type MissingCommandsError struct {
+	Missing []string
+}
+
+func (e *MissingCommandsError) Error() string {
+	return "guest is missing required commands: " + strings.Join(e.Missing, ", ")
+}
+
+// requireCommandsScript prints each of cmds the guest cannot find, one per
+// line. The names are validated by config, so they need no quoting.
+func requireCommandsScript(cmds []string) string {
+	return fmt.Sprintf("for c in %s; do command -v \"$c\" >/dev/null 2>&1 || echo \"$c\"; done", strings.Join(cmds, " "))
+}
+
+// FindMissingCommands checks Config.RequireCommands in the guest over SSH, as
+// the SSH user and so with that user's PATH, and returns those not found.
+func FindMissingCommands(cfg *config.Config) ([]string, error) {
+	if len(cfg.RequireCommands) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	out, err := runGuestSSH(ctx, cfg.SSHConfigPath, nil, "sh", "-c", shellQuote(requireCommandsScript(cfg.RequireCommands)))
+	if err != nil {
+		return nil, fmt.Errorf("check required commands: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
@@ -0,0 +1,25 @@
+package vm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRequireCommandsScript(t *testing.T) {
+	got := requireCommandsScript([]string{"incus", "/usr/local/bin/tool"})
+	want := `for c in incus /usr/local/bin/tool; do command -v "$c" >/dev/null 2>&1 || echo "$c"; done`
+	if got != want {
+		t.Errorf("requireCommandsScript =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMissingCommandsError(t *testing.T) {
+	var err error = &MissingCommandsError{Missing: []string{"jq", "docker"}}
+	if got, want := err.Error(), "guest is missing required commands: jq, docker"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	var missing *MissingCommandsError
+	if !errors.As(err, &missing) || len(missing.Missing) != 2 {
+		t.Errorf("errors.As failed on %v", err)
+	}
+}
@@ -10,8 +10,10 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Code-Hex/vz/v3"
@@ -21,6 +23,8 @@ import (
 	"github.com/stuffbucket/bladerunner/internal/provision"
 	"github.com/stuffbucket/bladerunner/internal/report"
 	"github.com/stuffbucket/bladerunner/internal/ssh"
+	"github.com/stuffbucket/bladerunner/internal/util"
+	"golang.org/x/sys/unix"
 )
 
 // Eject tuning.
@@ -39,9 +43,12 @@ type Runner struct {
 	vm            *vz.VirtualMachine
 	vmConfig      *vz.VirtualMachineConfiguration
 	metadata      *runtimeMetadata
-	clientCrt     []byte
-	clientKey     []byte
 	baseImagePath string
+	// credsMu guards clientCrt and clientKey, which RotateClientCert replaces
+	// while the VM runs; read them through clientCreds.
+	credsMu   sync.RWMutex
+	clientCrt []byte
+	clientKey []byte
 	// restoreFrom, when set before StartVM, makes StartVM restore the guest
 	// from a saved-state file (and resume it) instead of cold-booting.
 	restoreFrom string
@@ -50,13 +57,38 @@ type Runner struct {
 	// VM down directly (the guest must not resume after a save).
 	savedState bool
 
+	// fwdMu guards forwarders, forwards and fwdClosed. forwards starts as a
+	// copy of cfg.Forwards and is what AddForward and RemoveForward change
+	// while the VM runs; cfg itself is left alone, as the config handler reads
+	// and saves it under its own lock. fwdClosed is set once Stop has closed
+	// the forwarders; none may be started or changed after that. Forwarders
+	// are closed after fwdMu is released, since a close can wait out a drain.
+	fwdMu             sync.Mutex
 	forwarders        []*portForwarder
+	forwards          []config.ForwardSpec
+	fwdClosed         bool
 	reverseForwarders []*reversePortForwarder
 	consoleLog        *logging.RotatingFile
 	progress          Progress
 	nestedVirt        string // resolved nested-virt state: enabled|unsupported|disabled
+	bridgeIface       string // host interface picked for bridged networking
 	stopOnce          sync.Once
 	stopErr           error
+	stopResult        StopResult
+	memoryTarget      atomic.Uint64 // balloon target in GiB; 0 means the boot size
+	startedAt         atomic.Int64  // unix nanos the VM reached running; 0 before
+	incusReady        atomic.Bool
+	// serverInfo is what the last saved report recorded about Incus; nil
+	// while the API has not been reached (see RefreshReport).
+	serverInfo atomic.Pointer[incusctl.ServerInfo]
+}
+
+// VirtualizationSupported reports whether the host's hypervisor is usable by
+// the Virtualization framework (the kern.hv_support sysctl). It is false on
+// Macs without hardware virtualization and inside most VMs.
+func VirtualizationSupported() bool {
+	v, err := unix.SysctlUint32("kern.hv_support")
+	return err == nil && v == 1
 }
 
 // NestedVirtualizationSupported reports whether the host can run nested VMs
@@ -73,6 +105,20 @@ func (r *Runner) NestedVirtState() string {
 	return r.nestedVirt
 }
 
+// StartedAt returns when the VM reached the running state (booted or resumed
+// from a saved state), or the zero time before then.
+func (r *Runner) StartedAt() time.Time {
+	if ns := r.startedAt.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// IncusReady reports whether WaitForIncus has seen the Incus API authorize us.
+func (r *Runner) IncusReady() bool {
+	return r.incusReady.Load()
+}
+
 // SetRestoreFrom configures StartVM to restore the guest from a saved-state
 // file (produced by SaveState) and resume it, instead of cold-booting. Must be
 // called before StartVM.
@@ -125,7 +171,7 @@ func (r *Runner) SaveState(path string) error {
 	// Record the snapshot's hardware config + disk stamp alongside the file so
 	// restore can rebuild a matching configuration and detect a changed disk.
 	// Written while paused (disk frozen). Non-fatal: the save itself succeeded.
-	if err := writeSaveMetadata(path, r.cfg.CPUs, r.cfg.MemoryGiB, r.cfg.DiskSizeGiB, r.cfg.GUI, r.cfg.DiskPath, r.effectiveShareTag()); err != nil {
+	if err := writeSaveMetadata(path, r.cfg.CPUs, r.cfg.MemoryGiB, r.cfg.DiskSizeGiB, r.cfg.GUI, r.cfg.GUIInput, r.cfg.DiskPath, r.effectiveShareTag()); err != nil {
 		logging.L().Warn("could not write saved-state metadata sidecar", "err", err)
 	}
 	return nil
@@ -168,6 +214,9 @@ func (r *Runner) prepareRestore() error {
 	if meta.GUI != nil && *meta.GUI != r.cfg.GUI {
 		return fmt.Errorf("refusing restore: saved state is %s but boot requested %s; re-boot with the matching mode", guiModeLabel(*meta.GUI), guiModeLabel(r.cfg.GUI))
 	}
+	if meta.GUIInput != nil {
+		r.cfg.GUIInput = *meta.GUIInput
+	}
 	// The VirtioFS directory-sharing topology is fixed when the VZ configuration
 	// is built, exactly like graphics, so a share present-vs-absent or a different
 	// tag between the snapshot and this boot would fail deep inside VZ. Refuse
@@ -231,19 +280,19 @@ func (r *Runner) waitForStopped(ctx context.Context, timeout time.Duration) erro
 		case vz.VirtualMachineStateStopped:
 			return nil
 		case vz.VirtualMachineStateError:
-			return errors.New("vm entered error state during eject")
+			return errors.New("vm entered error state")
 		default:
 		}
 		select {
 		case <-waitCtx.Done():
 			return fmt.Errorf("vm did not stop within %s: %w", timeout, waitCtx.Err())
 		case st := <-r.vm.StateChangedNotify():
-			logging.L().Info("eject: vm state changed", "state", st.String())
+			logging.L().Info("vm state changed while stopping", "state", st.String())
 			switch st {
 			case vz.VirtualMachineStateStopped:
 				return nil
 			case vz.VirtualMachineStateError:
-				return errors.New("vm entered error state during eject")
+				return errors.New("vm entered error state")
 			default:
 			}
 		}
@@ -262,6 +311,49 @@ func (r *Runner) ResumeVM() error {
 	return r.vm.Resume()
 }
 
+// PauseVM freezes the guest's vCPUs in place. Unlike SaveState nothing is
+// written; ResumeVM continues it. Pausing an already paused guest is a no-op.
+func (r *Runner) PauseVM() error {
+	if r.vm == nil {
+		return errors.New("vm not started")
+	}
+	if r.Paused() {
+		return nil
+	}
+	if !r.vm.CanPause() {
+		return errors.New("vm is not in a pausable state")
+	}
+	if err := r.vm.Pause(); err != nil {
+		return fmt.Errorf("pause vm: %w", err)
+	}
+	return nil
+}
+
+// Paused reports whether the guest is paused (PauseVM, or a save in progress).
+func (r *Runner) Paused() bool {
+	if r.vm == nil {
+		return false
+	}
+	switch r.vm.State() {
+	case vz.VirtualMachineStatePaused, vz.VirtualMachineStatePausing:
+		return true
+	default:
+		return false
+	}
+}
+
+// Reboot asks the guest to reboot itself over SSH. A paused guest can't
+// answer, so it is refused rather than left to time out.
+func (r *Runner) Reboot(ctx context.Context) error {
+	if r.vm == nil {
+		return errors.New("vm not started")
+	}
+	if r.Paused() {
+		return errors.New("vm is paused (resume it first)")
+	}
+	return rebootGuest(ctx, r.cfg.SSHConfigPath)
+}
+
 // SetProgress attaches a Progress reporter. Must be called before Start /
 // StartVM. Passing nil clears any previous reporter.
 func (r *Runner) SetProgress(p Progress) {
@@ -284,60 +376,112 @@ func NewRunner(cfg *config.Config) (*Runner, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
+	if err := CheckGuestArch(cfg.Arch); err != nil {
+		return nil, err
+	}
 	return &Runner{cfg: cfg, progress: NewTimedProgress()}, nil
 }
 
-// StartVM provisions and starts the VM, returning as soon as it's running.
-// Call WaitForIncus() separately to wait for cloud-init and Incus API readiness.
-func (r *Runner) StartVM(ctx context.Context) (*StartVMResult, error) {
-	log := logging.L()
-
-	// On restore, adopt the snapshot's hardware config and verify the disk
-	// hasn't changed before touching anything.
-	if r.restoreFrom != "" {
-		if err := r.prepareRestore(); err != nil {
-			return nil, err
-		}
+// Prepare builds everything StartVM needs before the Virtualization framework
+// is involved — client TLS credentials, the cloud-init seed and ISO, the base
+// image and the main disk — and reports the paths. A later StartVM with the
+// same config reuses the base image and disk.
+func Prepare(ctx context.Context, cfg *config.Config) (*Artifacts, error) {
+	if cfg == nil {
+		return nil, errors.New("config is nil")
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return prepareArtifacts(ctx, cfg, true)
+}
 
-	log.Info("starting VM provisioning", "name", r.cfg.Name, "vm_dir", r.cfg.VMDir, "cpus", r.cfg.CPUs, "memory_gib", r.cfg.MemoryGiB)
+// prepareArtifacts is the provisioning half of StartVM. seed=false skips
+// building cloud-init (used on restore, where the guest is already configured).
+func prepareArtifacts(ctx context.Context, cfg *config.Config, seed bool) (*Artifacts, error) {
+	log := logging.L()
 
-	if err := ensureVMDir(r.cfg); err != nil {
+	start := time.Now()
+	if err := EnsureVMDir(cfg); err != nil {
 		return nil, err
 	}
+	log.Info("ensured VM directory", "path", cfg.VMDir, "elapsed", time.Since(start).Round(time.Millisecond).String())
 
 	log.Info("ensuring client TLS credentials")
-	certPEM, keyPEM, err := incusctl.EnsureClientCertificate(r.cfg.ClientCertPath, r.cfg.ClientKeyPath)
+	certPEM, keyPEM, err := incusctl.EnsureClientCertificate(cfg.ClientCertPath, cfg.ClientKeyPath)
 	if err != nil {
 		return nil, err
 	}
-	r.clientCrt = certPEM
-	r.clientKey = keyPEM
+	a := &Artifacts{
+		ClientCertPath: cfg.ClientCertPath,
+		ClientKeyPath:  cfg.ClientKeyPath,
+		CloudInitISO:   cfg.CloudInitISO,
+		DiskPath:       cfg.DiskPath,
+		certPEM:        certPEM,
+		keyPEM:         keyPEM,
+	}
 
-	// On restore the guest is already configured and frozen in the saved
-	// state; regenerating cloud-init would needlessly rewrite the seed ISO. The
-	// existing ISO file is still attached so the device topology matches the
-	// saved configuration.
-	if r.restoreFrom == "" {
+	if seed {
 		log.Info("building cloud-init payload")
-		userData, metaData := provision.BuildCloudInit(r.cfg, string(certPEM))
-		if err := provision.WriteSeedFiles(r.cfg, userData, metaData); err != nil {
+		userData, metaData, err := provision.BuildCloudInit(cfg, string(certPEM))
+		if err != nil {
+			return nil, err
+		}
+		if err := provision.WriteSeedFiles(cfg, userData, metaData); err != nil {
 			return nil, err
 		}
-		if err := provision.BuildCloudInitISO(ctx, r.cfg); err != nil {
+		if err := provision.BuildCloudInitISO(ctx, cfg); err != nil {
 			return nil, err
 		}
+		a.UserDataPath = filepath.Join(cfg.CloudInitDir, "user-data")
+		a.MetaDataPath = filepath.Join(cfg.CloudInitDir, "meta-data")
 	}
 
 	log.Info("resolving base image and main disk")
-	baseImagePath, err := ensureBaseImage(ctx, r.cfg)
+	baseImagePath, err := ensureBaseImage(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
-	r.baseImagePath = baseImagePath
-	if err := ensureMainDisk(r.cfg, baseImagePath); err != nil {
+	a.BaseImagePath = baseImagePath
+	a.DiskCreated = !util.FileExists(cfg.DiskPath)
+	if err := ensureMainDisk(cfg, baseImagePath); err != nil {
 		return nil, err
 	}
+	return a, nil
+}
+
+// StartVM provisions and starts the VM, returning as soon as it's running.
+// Call WaitForIncus() separately to wait for cloud-init and Incus API readiness.
+func (r *Runner) StartVM(ctx context.Context) (*StartVMResult, error) {
+	log := logging.L()
+
+	// On restore, adopt the snapshot's hardware config and verify the disk
+	// hasn't changed before touching anything.
+	if r.restoreFrom != "" {
+		if err := r.prepareRestore(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fail on a taken host port now, before the image download and disk
+	// work, rather than when the forwarders bind after boot.
+	if err := checkLocalPorts(r.cfg.ForwardSpecs()); err != nil {
+		return nil, err
+	}
+
+	log.Info("starting VM provisioning", "name", r.cfg.Name, "vm_dir", r.cfg.VMDir, "cpus", r.cfg.CPUs, "memory_gib", r.cfg.MemoryGiB)
+
+	// On restore the guest is already configured and frozen in the saved
+	// state; regenerating cloud-init would needlessly rewrite the seed ISO. The
+	// existing ISO file is still attached so the device topology matches the
+	// saved configuration.
+	artifacts, err := prepareArtifacts(ctx, r.cfg, r.restoreFrom == "")
+	if err != nil {
+		return nil, err
+	}
+	r.clientCrt = artifacts.certPEM
+	r.clientKey = artifacts.keyPEM
+	r.baseImagePath = artifacts.BaseImagePath
 
 	md, err := loadOrCreateMetadata(r.cfg)
 	if err != nil {
@@ -352,6 +496,11 @@ func (r *Runner) StartVM(ctx context.Context) (*StartVMResult, error) {
 	}
 	r.vmConfig = vmCfg
 
+	// Recorded after configuration so resolved fields (NestedVirt) are set.
+	if err := config.WriteEffectiveConfig(r.cfg); err != nil {
+		log.Warn("failed to write effective config", "path", r.cfg.EffectiveConfigPath, "err", err)
+	}
+
 	log.Info("creating virtual machine instance")
 	vm, err := vz.NewVirtualMachine(vmCfg)
 	if err != nil {
@@ -384,6 +533,7 @@ func (r *Runner) StartVM(ctx context.Context) (*StartVMResult, error) {
 		return nil, err
 	}
 	r.progress.Done(StageVMBoot)
+	r.startedAt.Store(time.Now().UnixNano())
 
 	log.Info("starting localhost forwarders")
 	if err := r.startForwarders(); err != nil {
@@ -403,9 +553,29 @@ func (r *Runner) WaitForIncus(ctx context.Context) (*report.StartupReport, error
 	defer cancel()
 
 	r.progress.Begin(StageIncusWait, "Waiting for Incus API readiness", r.cfg.WaitForIncus)
-	serverInfo, err := incusctl.WaitForServer(incusCtx, endpoint, r.clientCrt, r.clientKey, 4*time.Second, func(p incusctl.WaitProgress) {
-		r.progress.Substatus(StageIncusWait, fmt.Sprintf("attempt=%d %s", p.Attempt, summarizeErr(p.LastError)))
+	// A running Incus operation (typically an image pull) explains a long wait
+	// better than the latest failed attempt, so it takes over the status line
+	// while it lasts.
+	var opStatus atomic.Pointer[string]
+	sshConfigPath := r.earlySSHConfig()
+	r.ensureTrust(incusCtx, sshConfigPath)
+	r.watchIncusOperations(incusCtx, sshConfigPath, func(s string) {
+		opStatus.Store(&s)
+		if s != "" {
+			r.progress.Substatus(StageIncusWait, s)
+		}
+	})
+	var lastKind atomic.Value // incusctl.ErrorKind of the latest failed attempt
+	certPEM, keyPEM := r.clientCreds()
+	serverInfo, err := incusctl.WaitForServer(incusCtx, endpoint, certPEM, keyPEM, func(p incusctl.WaitProgress) {
+		lastKind.Store(p.Kind)
+		if s := opStatus.Load(); s != nil && *s != "" {
+			r.progress.Substatus(StageIncusWait, *s)
+			return
+		}
+		r.progress.Substatus(StageIncusWait, fmt.Sprintf("attempt=%d %s", p.Attempt, waitStatus(p)))
 	})
+	cancel()
 	if err != nil {
 		r.progress.Fail(StageIncusWait, err)
 		// The readiness probe now gates on the Incus API reporting our client as
@@ -415,23 +585,188 @@ func (r *Runner) WaitForIncus(ctx context.Context) (*report.StartupReport, error
 		// reads as success. Persist the partial report for diagnostics first.
 		log.Error("incus api never became authorized before timeout", "endpoint", endpoint, "err", err)
 		reportData := r.makeReport(r.baseImagePath, endpoint, nil)
+		diag := r.incusDiagnostics(err)
+		reportData.Incus.Diagnostics = diag
+		reportData.Clock = CheckGuestClock(r.cfg)
+		reportData.Boot = CheckBoot(r.cfg)
+		CheckGuestOS(r.cfg, &reportData.VM)
 		if saveErr := report.SaveJSON(r.cfg.ReportPath, reportData); saveErr != nil {
 			log.Warn("failed to save partial startup report", "path", r.cfg.ReportPath, "err", saveErr)
 		}
+		// A skewed guest clock turns into TLS certificate errors, so name it
+		// as the likely cause ahead of whatever incusd logged.
+		if c := reportData.Clock; c != nil && c.Warning != "" {
+			return nil, fmt.Errorf("wait for incus authorization: %w (%s; see %s)", err, c.Warning, r.cfg.ReportPath)
+		}
+		// An API that answers but never trusts us points at provisioning
+		// rather than at Incus itself.
+		if kind, _ := lastKind.Load().(incusctl.ErrorKind); kind == incusctl.ErrorKindCertUntrusted {
+			log.Warn(untrustedCertHint, "cert", r.cfg.ClientCertPath)
+			return nil, fmt.Errorf("wait for incus authorization: %w (%s; see %s)", err, untrustedCertHint, r.cfg.ReportPath)
+		}
+		// A failed provisioning module (say, runcmd) usually explains a missing
+		// Incus better than the daemon's own log.
+		if b := reportData.Boot; b != nil && b.CloudInit != nil && b.CloudInit.Summary != "" {
+			return nil, fmt.Errorf("wait for incus authorization: %w (%s; see %s)", err, b.CloudInit.Summary, r.cfg.ReportPath)
+		}
+		if cause := lastLogLine(diag.LogTail); cause != "" {
+			return nil, fmt.Errorf("wait for incus authorization: %w (incusd: %s; see %s)", err, cause, r.cfg.ReportPath)
+		}
 		return nil, fmt.Errorf("wait for incus authorization: %w", err)
 	}
 	r.progress.Done(StageIncusWait)
+	r.incusReady.Store(true)
+	r.serverInfo.Store(serverInfo)
 
 	log.Info("assembling startup report")
 	reportData := r.makeReport(r.baseImagePath, endpoint, serverInfo)
+	reportData.Clock = CheckGuestClock(r.cfg)
+	reportData.Boot = CheckBoot(r.cfg)
+	CheckGuestOS(r.cfg, &reportData.VM)
+	missing, missingErr := FindMissingCommands(r.cfg)
+	if len(missing) > 0 {
+		if reportData.Boot == nil {
+			reportData.Boot = &report.BootInfo{}
+		}
+		reportData.Boot.MissingCommands = missing
+	}
 	if err := report.SaveJSON(r.cfg.ReportPath, reportData); err != nil {
 		return nil, err
 	}
 	log.Info("startup report saved", "path", r.cfg.ReportPath)
 
+	// Incus is up, but provisioning that skipped a tool the user said they
+	// need has still failed them.
+	if missingErr != nil {
+		return reportData, missingErr
+	}
+	if len(missing) > 0 {
+		err := &MissingCommandsError{Missing: missing}
+		log.Error("required commands missing in guest", "missing", strings.Join(missing, ","))
+		return reportData, err
+	}
+
 	return reportData, nil
 }
 
+// earlySSHConfig writes the SSH config makeReport would write later, for the
+// guest helpers that run during the Incus wait, and returns its path. It
+// returns "" when there is no SSH key to get in with or the write fails; those
+// helpers are then skipped.
+func (r *Runner) earlySSHConfig() string {
+	if r.cfg.SSHPrivateKeyPath == "" {
+		return ""
+	}
+	configPath, err := ssh.WriteSSHConfig(r.cfg.Instance(), r.cfg.LocalSSHPort, r.cfg.SSHUser, r.cfg.SSHPrivateKeyPath)
+	if err != nil {
+		logging.L().Warn("no guest helpers during the incus wait: write ssh config", "err", err)
+		return ""
+	}
+	return configPath
+}
+
+// ensureTrust adds the client certificate to the guest Incus's trust store
+// over SSH, retrying in the background until it succeeds or ctx ends. The
+// bootstrap trusts the certificate it was provisioned with too, but only on
+// the first boot; this step is what lets a rotated certificate (see
+// RotateClientCert) reach a guest provisioned with an older one.
+func (r *Runner) ensureTrust(ctx context.Context, sshConfigPath string) {
+	if sshConfigPath == "" {
+		return
+	}
+	go func() {
+		log := logging.L()
+		ticker := time.NewTicker(trustRetryInterval)
+		defer ticker.Stop()
+		lastErr := ""
+		for {
+			certPEM, _ := r.clientCreds()
+			err := trustCertificate(ctx, sshConfigPath, certPEM)
+			if err == nil {
+				log.Info("client certificate trusted by guest incus", "cert", r.cfg.ClientCertPath)
+				return
+			}
+			// SSH and incusd come up during the wait, so early failures are
+			// expected; only note each new one.
+			if msg := err.Error(); msg != lastErr {
+				lastErr = msg
+				log.Debug("client certificate not trusted yet", "err", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// clientCreds returns the client certificate and key the runner talks to
+// Incus with.
+func (r *Runner) clientCreds() (certPEM, keyPEM []byte) {
+	r.credsMu.RLock()
+	defer r.credsMu.RUnlock()
+	return r.clientCrt, r.clientKey
+}
+
+// RotateClientCert replaces the Incus client certificate of the running VM.
+// It generates a new key pair, trusts it in the guest over SSH (dropping the
+// old certificate), and only then saves it over Config.ClientCertPath and
+// ClientKeyPath, so a failed rotation leaves the old pair working.
+func (r *Runner) RotateClientCert(ctx context.Context) error {
+	if r.vm == nil {
+		return errors.New("vm not started")
+	}
+	if r.Paused() {
+		return errors.New("vm is paused (resume it first)")
+	}
+	certPEM, keyPEM, err := incusctl.GenerateClientCertificate()
+	if err != nil {
+		return err
+	}
+	if err := trustCertificate(ctx, r.cfg.SSHConfigPath, certPEM); err != nil {
+		return err
+	}
+	if err := incusctl.SaveClientCertificate(r.cfg.ClientCertPath, r.cfg.ClientKeyPath, certPEM, keyPEM); err != nil {
+		return err
+	}
+	r.credsMu.Lock()
+	r.clientCrt, r.clientKey = certPEM, keyPEM
+	r.credsMu.Unlock()
+	logging.L().Info("rotated incus client certificate", "cert", r.cfg.ClientCertPath)
+	return nil
+}
+
+// watchIncusOperations starts polling the guest's Incus operations in the
+// background until ctx ends. It is skipped without an SSH config to reach the
+// guest with (see earlySSHConfig).
+func (r *Runner) watchIncusOperations(ctx context.Context, configPath string, report func(string)) {
+	if configPath == "" {
+		return
+	}
+	go watchIncusOperations(ctx, configPath, incusOpsPollInterval, func(s string) {
+		if s != "" {
+			logging.L().Info("incus operation in progress", "status", s)
+		}
+		report(s)
+	})
+}
+
+// incusDiagnostics gathers guest-side evidence for a failed Incus wait. It
+// runs after makeReport, which writes the SSH config the log fetch uses.
+func (r *Runner) incusDiagnostics(readyErr error) *report.IncusDiagnostics {
+	diag := &report.IncusDiagnostics{ReadyError: readyErr.Error()}
+	tail, err := ReadIncusdLogTail(r.cfg)
+	if err != nil {
+		logging.L().Warn("could not fetch incusd log tail", "err", err)
+		diag.LogTailError = err.Error()
+		return diag
+	}
+	diag.LogTail = tail
+	logging.L().Error("incusd log tail", "last", lastLogLine(tail))
+	return diag
+}
+
 // Start provisions, starts, and waits for Incus. Convenience wrapper for StartVM + WaitForIncus.
 func (r *Runner) Start(ctx context.Context) (*report.StartupReport, error) {
 	if _, err := r.StartVM(ctx); err != nil {
@@ -473,10 +808,24 @@ func (r *Runner) Wait(ctx context.Context) error {
 	}
 }
 
-func (r *Runner) Stop() error {
+// Stop shuts the VM down and closes its forwarders: it sends the guest up to
+// three ACPI stop requests, waiting briefly after each for it to power off,
+// then forces the VM down if it is still up. The result says which happened.
+// Only the first Stop or ForceStop acts; later calls return its result.
+func (r *Runner) Stop() (StopResult, error) {
+	return r.stop(false)
+}
+
+// ForceStop is Stop without the ACPI requests: the VM is torn down at once,
+// for a guest known to be hung.
+func (r *Runner) ForceStop() (StopResult, error) {
+	return r.stop(true)
+}
+
+func (r *Runner) stop(force bool) (StopResult, error) {
 	r.stopOnce.Do(func() {
 		log := logging.L()
-		log.Info("stopping vm and forwarders")
+		log.Info("stopping vm and forwarders", "force", force)
 		r.closeForwarders()
 
 		if r.consoleLog != nil {
@@ -493,45 +842,83 @@ func (r *Runner) Stop() error {
 			return
 		}
 
-		r.requestStopVM(log)
-		r.forceStopVMIfNeeded(log)
+		if !force {
+			r.stopResult.Attempts = r.requestStopVM(log)
+		}
+		if r.vm.State() == vz.VirtualMachineStateStopped {
+			r.stopResult.Graceful = true
+			return
+		}
+		r.stopResult.Forced = r.forceStopVMIfNeeded(log)
 	})
 
-	return r.stopErr
+	return r.stopResult, r.stopErr
 }
 
+// closeForwarders closes every forwarder. The host-to-guest ones drain
+// their connections in flight in parallel, so a stop waits for one drain
+// timeout at most rather than one per forwarder.
 func (r *Runner) closeForwarders() {
-	for _, f := range r.forwarders {
-		if err := f.Close(); err != nil && r.stopErr == nil {
+	r.fwdMu.Lock()
+	r.fwdClosed = true
+	forwarders, reverse := r.forwarders, r.reverseForwarders
+	r.forwarders, r.reverseForwarders = nil, nil
+	r.fwdMu.Unlock()
+
+	errs := make([]error, len(forwarders))
+	var wg sync.WaitGroup
+	for i, f := range forwarders {
+		wg.Go(func() { errs[i] = f.Close() })
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil && r.stopErr == nil {
 			r.stopErr = err
 		}
 	}
-	for _, f := range r.reverseForwarders {
+	for _, f := range reverse {
 		if err := f.Close(); err != nil && r.stopErr == nil {
 			r.stopErr = err
 		}
 	}
 }
 
-func (r *Runner) requestStopVM(log loggerLike) {
+// errForwardersClosed is returned for forward changes that arrive once Stop
+// has closed the forwarders; the control socket outlives them briefly.
+var errForwardersClosed = errors.New("vm is stopping; its forwarders are closed")
+
+// requestStopStep is how long requestStopVM waits for the guest to power off
+// after each ACPI stop request.
+const requestStopStep = 2 * time.Second
+
+// requestStopVM asks the guest to power off, up to three times, and returns
+// how many requests it sent. It returns as soon as the VM is stopped.
+func (r *Runner) requestStopVM(log loggerLike) int {
 	if r.savedState {
 		// State already saved and the guest is paused; a graceful ACPI request
 		// would only stall. forceStopVMIfNeeded tears it down directly.
-		return
+		return 0
 	}
-	for i := 0; i < 3 && r.vm.CanRequestStop(); i++ {
+	attempts := 0
+	for attempts < 3 && r.vm.CanRequestStop() {
+		attempts++
 		ok, err := r.vm.RequestStop()
-		log.Info("sent stop request", "attempt", i+1, "accepted", ok, "err", err)
+		log.Info("sent stop request", "attempt", attempts, "accepted", ok, "err", err)
 		if err != nil && r.stopErr == nil {
 			r.stopErr = err
 		}
-		time.Sleep(2 * time.Second)
+		if r.waitForStopped(context.Background(), requestStopStep) == nil {
+			break
+		}
 	}
+	return attempts
 }
 
-func (r *Runner) forceStopVMIfNeeded(log loggerLike) {
+// forceStopVMIfNeeded stops the VM without asking the guest, unless it
+// can't be stopped (already stopped). It reports whether it did.
+func (r *Runner) forceStopVMIfNeeded(log loggerLike) bool {
 	if !r.vm.CanStop() {
-		return
+		return false
 	}
 	if err := r.vm.Stop(); err != nil {
 		log.Warn("forced stop failed", "err", err)
@@ -539,6 +926,7 @@ func (r *Runner) forceStopVMIfNeeded(log loggerLike) {
 			r.stopErr = err
 		}
 	}
+	return true
 }
 
 // loggerLike is the subset of charmlog.Logger used by stop helpers.
@@ -579,6 +967,20 @@ func (r *Runner) waitForRunning(ctx context.Context, timeout time.Duration, onSt
 	}
 }
 
+// DialGuest opens a vsock connection to port in the guest. It returns an
+// error when the VM or its socket device is not yet available. The dial is a
+// blocking cgo call with no timeout of its own.
+func (r *Runner) DialGuest(port uint32) (net.Conn, error) {
+	if r.vm == nil {
+		return nil, errors.New("vm not started")
+	}
+	socketDevices := r.vm.SocketDevices()
+	if len(socketDevices) == 0 {
+		return nil, errors.New("vm has no virtio socket device")
+	}
+	return socketDevices[0].Connect(port)
+}
+
 // ProbeGuest checks guest liveness by opening a vsock connection to the
 // in-guest SSH bridge port and immediately closing it. A successful connect
 // means the guest kernel is alive and the vsock SSH forwarder is listening;
@@ -587,22 +989,13 @@ func (r *Runner) waitForRunning(ctx context.Context, timeout time.Duration, onSt
 // VM or its socket device is not yet available. The ctx bounds how long the
 // (blocking, cgo) dial may take.
 func (r *Runner) ProbeGuest(ctx context.Context) error {
-	if r.vm == nil {
-		return errors.New("vm not started")
-	}
-	socketDevices := r.vm.SocketDevices()
-	if len(socketDevices) == 0 {
-		return errors.New("vm has no virtio socket device")
-	}
-	device := socketDevices[0]
-
 	type dialResult struct {
 		conn net.Conn
 		err  error
 	}
 	ch := make(chan dialResult, 1)
 	go func() {
-		conn, err := device.Connect(r.cfg.VsockSSHPort)
+		conn, err := r.DialGuest(r.cfg.VsockSSHPort)
 		ch <- dialResult{conn: conn, err: err}
 	}()
 
@@ -629,29 +1022,26 @@ func (r *Runner) startForwarders() error {
 		return device.Connect(port)
 	}
 
-	sshForward := newPortForwarder(
-		"ssh",
-		fmt.Sprintf("127.0.0.1:%d", r.cfg.LocalSSHPort),
-		r.cfg.VsockSSHPort,
-		dial,
-	)
-	if err := sshForward.Start(); err != nil {
-		return fmt.Errorf("start ssh forwarder: %w", err)
+	specs := r.cfg.ForwardSpecs()
+	forwarders, err := startPortForwarders(specs, dial, r.dialRetry())
+	if err != nil {
+		return err
 	}
-
-	apiForward := newPortForwarder(
-		"incus-api",
-		fmt.Sprintf("127.0.0.1:%d", r.cfg.LocalAPIPort),
-		r.cfg.VsockAPIPort,
-		dial,
-	)
-	if err := apiForward.Start(); err != nil {
-		_ = sshForward.Close()
-		return fmt.Errorf("start api forwarder: %w", err)
+	active := make([]any, 0, 2*len(specs))
+	for _, spec := range specs {
+		active = append(active, spec.Name, spec.LocalAddr)
 	}
 
-	r.forwarders = []*portForwarder{sshForward, apiForward}
-	logging.L().Info("forwarders active", "ssh", fmt.Sprintf("127.0.0.1:%d", r.cfg.LocalSSHPort), "api", fmt.Sprintf("127.0.0.1:%d", r.cfg.LocalAPIPort))
+	r.fwdMu.Lock()
+	if r.fwdClosed {
+		r.fwdMu.Unlock()
+		closeAll(forwarders)
+		return errForwardersClosed
+	}
+	r.forwarders = forwarders
+	r.forwards = slices.Clone(r.cfg.Forwards)
+	r.fwdMu.Unlock()
+	logging.L().Info("forwarders active", active...)
 
 	r.startOIDCReverseForwarder(device)
 	r.startNTPReverseForwarder(device)
@@ -659,6 +1049,196 @@ func (r *Runner) startForwarders() error {
 	return nil
 }
 
+// dialRetry is the forwarders' guest dial policy from the config.
+func (r *Runner) dialRetry() dialRetry {
+	return dialRetry{attempts: r.cfg.ForwarderDialRetries, delay: r.cfg.ForwarderDialRetryDelay}
+}
+
+// PauseForwarders closes the host listeners of the named forwarder (or of all
+// of them when name is empty) while the VM keeps running.
+func (r *Runner) PauseForwarders(name string) error {
+	r.fwdMu.Lock()
+	defer r.fwdMu.Unlock()
+	fs, err := r.selectForwarders(name)
+	if err != nil {
+		return err
+	}
+	for _, f := range fs {
+		f.Pause()
+	}
+	return nil
+}
+
+// ResumeForwarders re-binds the host listeners paused by PauseForwarders on
+// their original addresses. Every selected forwarder is attempted; the errors
+// of any that could not re-bind (e.g. the port was taken meanwhile) are joined.
+func (r *Runner) ResumeForwarders(name string) error {
+	r.fwdMu.Lock()
+	defer r.fwdMu.Unlock()
+	fs, err := r.selectForwarders(name)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, f := range fs {
+		errs = append(errs, f.Resume())
+	}
+	return errors.Join(errs...)
+}
+
+// Forwarders reports the host-to-guest forwarders and whether each is paused.
+func (r *Runner) Forwarders() []ForwarderState {
+	r.fwdMu.Lock()
+	defer r.fwdMu.Unlock()
+	out := make([]ForwarderState, 0, len(r.forwarders))
+	for _, f := range r.forwarders {
+		out = append(out, ForwarderState{
+			Name:           f.name,
+			Listen:         f.listenAddr,
+			Paused:         f.Paused(),
+			BytesToGuest:   f.toGuest.Load(),
+			BytesFromGuest: f.fromGuest.Load(),
+			ActiveConns:    f.activeConns.Load(),
+			TotalConns:     f.totalConns.Load(),
+		})
+	}
+	return out
+}
+
+// Stats returns a live snapshot of the VM: start time, balloon target and
+// per-forwarder traffic.
+func (r *Runner) Stats() Stats {
+	return Stats{
+		StartedAt:       r.StartedAt(),
+		MemoryTargetGiB: r.MemoryTargetGiB(),
+		Forwarders:      r.Forwarders(),
+	}
+}
+
+// AddForward starts forwarding f.LocalAddr to f.GuestPort on the running VM.
+// The host listener is bound first, so a taken port fails before the guest is
+// touched; then the guest relay is installed over SSH. f is recorded with the
+// runner's forwards, so status and the forward list show it until the VM
+// stops.
+func (r *Runner) AddForward(ctx context.Context, f config.ForwardSpec) error {
+	if r.vm == nil {
+		return errors.New("vm not started")
+	}
+	if r.Paused() {
+		return errors.New("vm is paused (resume it first)")
+	}
+	r.fwdMu.Lock()
+	if r.fwdClosed {
+		r.fwdMu.Unlock()
+		return errForwardersClosed
+	}
+	if len(r.forwarders) == 0 {
+		r.fwdMu.Unlock()
+		return errors.New("forwarders are not started yet")
+	}
+	forwards, err := r.cfg.AddForward(r.forwards, f)
+	if err != nil {
+		r.fwdMu.Unlock()
+		return err
+	}
+	fs, err := startPortForwarders([]config.ForwardSpec{f}, r.DialGuest, r.dialRetry())
+	if err != nil {
+		r.fwdMu.Unlock()
+		return err
+	}
+	r.forwarders = append(r.forwarders, fs...)
+	r.forwards = forwards
+	r.fwdMu.Unlock()
+
+	if err := runRelayScript(ctx, r.cfg.SSHConfigPath, provision.ForwardRelayInstallScript(f)); err != nil {
+		r.fwdMu.Lock()
+		dropped := r.detachForwarder(f.Name)
+		if rest, _, rerr := config.RemoveForward(r.forwards, f.Name); rerr == nil {
+			r.forwards = rest
+		}
+		r.fwdMu.Unlock()
+		closeAll(dropped)
+		return err
+	}
+	logging.L().Info("forward added", "name", f.Name, "listen", f.LocalAddr, "guest_port", f.GuestPort)
+	return nil
+}
+
+// RemoveForward closes the host listener of the user-declared forward named
+// name and removes its guest relay. The built-in forwards can only be paused.
+func (r *Runner) RemoveForward(ctx context.Context, name string) error {
+	r.fwdMu.Lock()
+	if r.fwdClosed {
+		r.fwdMu.Unlock()
+		return errForwardersClosed
+	}
+	rest, _, err := config.RemoveForward(r.forwards, name)
+	if err != nil {
+		r.fwdMu.Unlock()
+		return err
+	}
+	r.forwards = rest
+	dropped := r.detachForwarder(name)
+	r.fwdMu.Unlock()
+	closeAll(dropped)
+
+	if err := runRelayScript(ctx, r.cfg.SSHConfigPath, provision.ForwardRelayRemoveScript(name)); err != nil {
+		return fmt.Errorf("host listener closed, but %w", err)
+	}
+	logging.L().Info("forward removed", "name", name)
+	return nil
+}
+
+// detachForwarder forgets the forwarder named name and returns it for the
+// caller to close once fwdMu, which must be held, is released.
+func (r *Runner) detachForwarder(name string) []*portForwarder {
+	var dropped []*portForwarder
+	r.forwarders = slices.DeleteFunc(r.forwarders, func(f *portForwarder) bool {
+		if f.name != name {
+			return false
+		}
+		dropped = append(dropped, f)
+		return true
+	})
+	return dropped
+}
+
+// closeAll closes forwarders detached from the runner.
+func closeAll(forwarders []*portForwarder) {
+	for _, f := range forwarders {
+		_ = f.Close()
+	}
+}
+
+// forwardSpecs is cfg.ForwardSpecs as the running VM has it, with the
+// forwards added or removed since it started.
+func (r *Runner) forwardSpecs() []config.ForwardSpec {
+	r.fwdMu.Lock()
+	defer r.fwdMu.Unlock()
+	if r.forwarders == nil {
+		return r.cfg.ForwardSpecs()
+	}
+	return r.cfg.ForwardSpecsWith(r.forwards)
+}
+
+func (r *Runner) selectForwarders(name string) ([]*portForwarder, error) {
+	if r.fwdClosed {
+		return nil, errForwardersClosed
+	}
+	if len(r.forwarders) == 0 {
+		return nil, errors.New("forwarders are not started yet")
+	}
+	if name == "" {
+		return r.forwarders, nil
+	}
+	for _, f := range r.forwarders {
+		if f.name == name {
+			return []*portForwarder{f}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown forwarder %q", name)
+}
+
 // startOIDCReverseForwarder wires the host-side OIDC provider so it is reachable
 // from inside the guest via vsock. Failure is logged and ignored: the mTLS
 // fallback path keeps Incus access working without OIDC.
@@ -717,7 +1297,7 @@ func (r *Runner) makeReport(baseImagePath, endpoint string, server *incusctl.Ser
 	var sshCommand string
 	var sshConfigPath string
 	if r.cfg.SSHPrivateKeyPath != "" {
-		configPath, err := ssh.WriteSSHConfig(r.cfg.LocalSSHPort, r.cfg.SSHUser, r.cfg.SSHPrivateKeyPath)
+		configPath, err := ssh.WriteSSHConfig(r.cfg.Instance(), r.cfg.LocalSSHPort, r.cfg.SSHUser, r.cfg.SSHPrivateKeyPath)
 		if err != nil {
 			logging.L().Warn("failed to write SSH config", "err", err)
 			sshCommand = fmt.Sprintf("ssh -p %d -i %s %s@127.0.0.1", r.cfg.LocalSSHPort, r.cfg.SSHPrivateKeyPath, r.cfg.SSHUser)
@@ -745,7 +1325,7 @@ func (r *Runner) makeReport(baseImagePath, endpoint string, server *incusctl.Ser
 			DiskPath:      r.cfg.DiskPath,
 			DiskSizeGiB:   r.cfg.DiskSizeGiB,
 			MemoryGiB:     r.cfg.MemoryGiB,
-			GuestArch:     runtime.GOARCH,
+			GuestArch:     r.cfg.Arch,
 			GUIEnabled:    r.cfg.GUI,
 			ConsoleLog:    r.cfg.ConsoleLogPath,
 			CloudInitISO:  r.cfg.CloudInitISO,
@@ -754,7 +1334,7 @@ func (r *Runner) makeReport(baseImagePath, endpoint string, server *incusctl.Ser
 		},
 		Network: report.NetInfo{
 			Mode:             r.cfg.NetworkMode,
-			BridgeInterface:  bridgeField(r.cfg),
+			BridgeInterface:  r.bridgeField(),
 			MACAddress:       r.metadata.MACAddress,
 			LocalSSHEndpoint: sshEndpoint,
 			LocalAPIEndpoint: apiEndpoint,
@@ -772,6 +1352,15 @@ func (r *Runner) makeReport(baseImagePath, endpoint string, server *incusctl.Ser
 		},
 	}
 
+	for _, f := range r.forwardSpecs() {
+		data.Network.Forwards = append(data.Network.Forwards, report.ForwardInfo{
+			Name:      f.Name,
+			LocalAddr: f.LocalAddr,
+			GuestPort: f.GuestPort,
+			Protocol:  f.Protocol,
+		})
+	}
+
 	if server != nil {
 		data.Incus = report.IncusInfo{
 			ServerVersion: server.ServerVersion,
@@ -787,11 +1376,29 @@ func (r *Runner) makeReport(baseImagePath, endpoint string, server *incusctl.Ser
 	return data
 }
 
-func bridgeField(cfg *config.Config) string {
-	if cfg.NetworkMode == config.NetworkModeBridged {
-		return cfg.BridgeInterface
+// bridgeField is the interface actually bridged onto, which for "auto" or a
+// list differs from the configured value.
+func (r *Runner) bridgeField() string {
+	if r.cfg.NetworkMode != config.NetworkModeBridged {
+		return ""
+	}
+	if r.bridgeIface != "" {
+		return r.bridgeIface
+	}
+	return r.cfg.BridgeInterface
+}
+
+// untrustedCertHint explains an Incus API that answered every attempt but
+// never accepted the host's client certificate.
+const untrustedCertHint = "the host client certificate was never added to the Incus trust store"
+
+// waitStatus is the Incus wait's status line detail: the error's kind when
+// it has one, else the raw error, shortened.
+func waitStatus(p incusctl.WaitProgress) string {
+	if p.Kind != "" {
+		return p.Kind.Describe()
 	}
-	return ""
+	return summarizeErr(p.LastError)
 }
 
 func summarizeErr(err error) string {
@@ -5,6 +5,7 @@ package vm
 import (
 	"context"
 	"errors"
+	"net"
 	"time"
 
 	"github.com/stuffbucket/bladerunner/internal/config"
@@ -21,6 +22,10 @@ func NewRunner(*config.Config) (*Runner, error) {
 	return nil, errors.New("bladerunner requires macOS (darwin)")
 }
 
+func Prepare(context.Context, *config.Config) (*Artifacts, error) {
+	return nil, errors.New("bladerunner requires macOS (darwin)")
+}
+
 func (r *Runner) Start(context.Context) (*report.StartupReport, error) {
 	return nil, errors.New("unsupported platform")
 }
@@ -33,20 +38,50 @@ func (r *Runner) WaitForIncus(context.Context) (*report.StartupReport, error) {
 	return nil, errors.New("unsupported platform")
 }
 
-func (r *Runner) StartGUI() error                  { return errors.New("unsupported platform") }
-func (r *Runner) Wait(context.Context) error       { return errors.New("unsupported platform") }
-func (r *Runner) Stop() error                      { return nil }
-func (r *Runner) SetProgress(Progress)             {}
-func (r *Runner) ProbeGuest(context.Context) error { return errors.New("unsupported platform") }
-func (r *Runner) NestedVirtState() string          { return "unsupported" }
-func (r *Runner) SetRestoreFrom(string)            {}
-func (r *Runner) SupportsSaveRestore() error       { return errors.New("unsupported platform") }
-func (r *Runner) SaveState(string) error           { return errors.New("unsupported platform") }
-func (r *Runner) ResumeVM() error                  { return errors.New("unsupported platform") }
+func (r *Runner) StartGUI() error                        { return errors.New("unsupported platform") }
+func (r *Runner) Wait(context.Context) error             { return errors.New("unsupported platform") }
+func (r *Runner) Stop() (StopResult, error)              { return StopResult{}, nil }
+func (r *Runner) ForceStop() (StopResult, error)         { return StopResult{}, nil }
+func (r *Runner) SetProgress(Progress)                   {}
+func (r *Runner) ProbeGuest(context.Context) error       { return errors.New("unsupported platform") }
+func (r *Runner) DialGuest(uint32) (net.Conn, error)     { return nil, errors.New("unsupported platform") }
+func (r *Runner) NestedVirtState() string                { return "unsupported" }
+func (r *Runner) SetRestoreFrom(string)                  {}
+func (r *Runner) SupportsSaveRestore() error             { return errors.New("unsupported platform") }
+func (r *Runner) SaveState(string) error                 { return errors.New("unsupported platform") }
+func (r *Runner) ResumeVM() error                        { return errors.New("unsupported platform") }
+func (r *Runner) PauseVM() error                         { return errors.New("unsupported platform") }
+func (r *Runner) Paused() bool                           { return false }
+func (r *Runner) Reboot(context.Context) error           { return errors.New("unsupported platform") }
+func (r *Runner) RotateClientCert(context.Context) error { return errors.New("unsupported platform") }
+func (r *Runner) PauseForwarders(string) error           { return errors.New("unsupported platform") }
+func (r *Runner) ResumeForwarders(string) error          { return errors.New("unsupported platform") }
+func (r *Runner) Forwarders() []ForwarderState           { return nil }
+func (r *Runner) MemoryTargetGiB() uint64                { return 0 }
+func (r *Runner) SetMemoryTargetGiB(uint64) error        { return errors.New("unsupported platform") }
+func (r *Runner) StartedAt() time.Time                   { return time.Time{} }
+func (r *Runner) Stats() Stats                           { return Stats{} }
+func (r *Runner) IncusReady() bool                       { return false }
+
+func (r *Runner) RefreshReport(context.Context, time.Duration) {}
+
+func (r *Runner) AddForward(context.Context, config.ForwardSpec) error {
+	return errors.New("unsupported platform")
+}
+
+func (r *Runner) RemoveForward(context.Context, string) error {
+	return errors.New("unsupported platform")
+}
 
 func (r *Runner) Eject(context.Context, time.Duration, bool) error {
 	return errors.New("unsupported platform")
 }
 
+// VirtualizationSupported is always false off darwin.
+func VirtualizationSupported() bool { return false }
+
 // NestedVirtualizationSupported is always false off darwin.
 func NestedVirtualizationSupported() bool { return false }
+
+// hostBridgeInterfaces is always empty off darwin.
+func hostBridgeInterfaces() []bridgeIface { return nil }
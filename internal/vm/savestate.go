@@ -25,6 +25,12 @@ type SaveMetadata struct {
 	// pointer so a sidecar written before this field (nil) skips the check.
 	GUI *bool `json:"gui,omitempty"`
 
+	// GUIInput records whether the GUI snapshot had the pointing and keyboard
+	// devices attached. Those are part of the device topology too, so restore
+	// adopts it like the CPU and memory sizing. Nil in older sidecars, which
+	// always had input.
+	GUIInput *bool `json:"gui_input,omitempty"`
+
 	// ShareTag records the VirtioFS directory-sharing device tag the snapshot was
 	// taken with ("" when no share device was attached). Like graphics, the
 	// directory-sharing topology is fixed at VZ-config-build time, so a mismatched
@@ -48,7 +54,7 @@ func SaveMetadataPath(savePath string) string { return savePath + ".json" }
 // writeSaveMetadata captures the hardware config and current disk stamp and
 // writes the sidecar next to savePath. Call it while the guest is paused, so
 // the disk is frozen and the stamp is consistent with the saved RAM.
-func writeSaveMetadata(savePath string, cpus uint, memGiB uint64, diskGiB int, gui bool, diskPath, shareTag string) error {
+func writeSaveMetadata(savePath string, cpus uint, memGiB uint64, diskGiB int, gui, guiInput bool, diskPath, shareTag string) error {
 	m, err := diskStamp(diskPath)
 	if err != nil {
 		return err
@@ -57,6 +63,7 @@ func writeSaveMetadata(savePath string, cpus uint, memGiB uint64, diskGiB int, g
 	m.MemoryGiB = memGiB
 	m.DiskSizeGiB = diskGiB
 	m.GUI = &gui
+	m.GUIInput = &guiInput
 	m.ShareTag = shareTag
 	m.DiskPath = diskPath
 
@@ -16,7 +16,7 @@ func TestSaveMetadataRoundTripAndVerify(t *testing.T) {
 	}
 	savePath := filepath.Join(dir, "saved-state.bin")
 
-	if err := writeSaveMetadata(savePath, 4, 8, 64, true, diskPath, "bladerunner-share"); err != nil {
+	if err := writeSaveMetadata(savePath, 4, 8, 64, true, false, diskPath, "bladerunner-share"); err != nil {
 		t.Fatalf("writeSaveMetadata: %v", err)
 	}
 
@@ -30,6 +30,9 @@ func TestSaveMetadataRoundTripAndVerify(t *testing.T) {
 	if meta.GUI == nil || !*meta.GUI {
 		t.Errorf("GUI not round-tripped: %+v", meta.GUI)
 	}
+	if meta.GUIInput == nil || *meta.GUIInput {
+		t.Errorf("GUIInput not round-tripped: %+v", meta.GUIInput)
+	}
 	if meta.ShareTag != "bladerunner-share" {
 		t.Errorf("ShareTag not round-tripped: %q", meta.ShareTag)
 	}
@@ -64,6 +67,9 @@ func TestLoadSaveMetadataOldSidecarHasNilGUI(t *testing.T) {
 	if meta.GUI != nil {
 		t.Errorf("expected nil GUI for a pre-field sidecar, got %v", *meta.GUI)
 	}
+	if meta.GUIInput != nil {
+		t.Errorf("expected nil GUIInput for a pre-field sidecar, got %v", *meta.GUIInput)
+	}
 }
 
 func TestSaveMetadataNoShareOmitsTag(t *testing.T) {
@@ -76,7 +82,7 @@ func TestSaveMetadataNoShareOmitsTag(t *testing.T) {
 		t.Fatal(err)
 	}
 	savePath := filepath.Join(dir, "saved-state.bin")
-	if err := writeSaveMetadata(savePath, 4, 8, 64, false, diskPath, ""); err != nil {
+	if err := writeSaveMetadata(savePath, 4, 8, 64, false, true, diskPath, ""); err != nil {
 		t.Fatalf("writeSaveMetadata: %v", err)
 	}
 	b, err := os.ReadFile(SaveMetadataPath(savePath))
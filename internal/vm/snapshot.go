@@ -0,0 +1,221 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/util"
+)
+
+// SnapshotDirName is the snapshots directory under the VM directory.
+const SnapshotDirName = "snapshots"
+
+const (
+	snapshotDiskExt = ".raw"
+	snapshotMetaExt = ".json"
+)
+
+// snapshotNameRE keeps snapshot names usable as file names and on the
+// command line.
+var snapshotNameRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// SnapshotInfo describes one disk snapshot. It is also the metadata recorded
+// next to the snapshot's disk copy.
+type SnapshotInfo struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Created time.Time `json:"created"`
+	// Size is the disk's apparent size; the copy is sparse, so it usually
+	// takes far less space.
+	Size int64 `json:"size_bytes"`
+}
+
+// SnapshotDir returns the snapshots directory for the VM described by cfg.
+func SnapshotDir(cfg *config.Config) string {
+	return filepath.Join(cfg.VMDir, SnapshotDirName)
+}
+
+// CreateSnapshot copies the VM's disk to <vm-dir>/snapshots/<name>.raw and
+// records its metadata. The VM must be stopped, so the copy is consistent, and
+// the name must not be taken.
+func CreateSnapshot(cfg *config.Config, name string) (SnapshotInfo, error) {
+	if err := validateSnapshotName(name); err != nil {
+		return SnapshotInfo{}, err
+	}
+	if err := requireVMStopped(cfg, "snapshotting"); err != nil {
+		return SnapshotInfo{}, err
+	}
+	if !util.FileExists(cfg.DiskPath) {
+		return SnapshotInfo{}, fmt.Errorf("nothing to snapshot: %s does not exist", cfg.DiskPath)
+	}
+
+	dir := SnapshotDir(cfg)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("create snapshots dir: %w", err)
+	}
+	path := filepath.Join(dir, name+snapshotDiskExt)
+	if util.FileExists(path) {
+		return SnapshotInfo{}, fmt.Errorf("snapshot %q already exists", name)
+	}
+
+	size, err := copyDisk(cfg.DiskPath, path, "Snapshotting disk")
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	info := SnapshotInfo{Name: name, Path: path, Created: time.Now().UTC(), Size: size}
+	b, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		_ = os.Remove(path)
+		return SnapshotInfo{}, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+snapshotMetaExt), b, 0o644); err != nil {
+		_ = os.Remove(path)
+		return SnapshotInfo{}, fmt.Errorf("write snapshot metadata: %w", err)
+	}
+	logging.L().Info("created disk snapshot", "name", name, "path", path, "bytes", size)
+	return info, nil
+}
+
+// ListSnapshots returns the VM's snapshots, oldest first. A missing snapshots
+// directory yields an empty list; a disk copy without readable metadata is
+// listed with its file's size and modification time.
+func ListSnapshots(cfg *config.Config) ([]SnapshotInfo, error) {
+	dir := SnapshotDir(cfg)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []SnapshotInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snapshots dir: %w", err)
+	}
+	out := []SnapshotInfo{}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), snapshotDiskExt)
+		if e.IsDir() || !ok || validateSnapshotName(name) != nil {
+			continue
+		}
+		info, err := readSnapshotInfo(dir, name)
+		if err != nil {
+			continue
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.Before(out[j].Created) })
+	return out, nil
+}
+
+// RestoreSnapshot replaces the VM's disk with the named snapshot. It refuses
+// while the VM's control socket answers (or exists but is unresponsive), since
+// swapping disk.raw under a live guest would corrupt it. The snapshot is
+// copied to a temporary file and renamed into place, so a failed restore
+// leaves the current disk untouched.
+func RestoreSnapshot(cfg *config.Config, name string) (SnapshotInfo, error) {
+	if err := validateSnapshotName(name); err != nil {
+		return SnapshotInfo{}, err
+	}
+	if err := requireVMStopped(cfg, "restoring a snapshot"); err != nil {
+		return SnapshotInfo{}, err
+	}
+	info, err := readSnapshotInfo(SnapshotDir(cfg), name)
+	if errors.Is(err, os.ErrNotExist) {
+		return SnapshotInfo{}, fmt.Errorf("snapshot %q not found (see 'br snapshot list')", name)
+	}
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.DiskPath), 0o755); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("create disk parent: %w", err)
+	}
+	tmp := cfg.DiskPath + ".restore"
+	if _, err := copyDisk(info.Path, tmp, "Restoring disk"); err != nil {
+		return SnapshotInfo{}, err
+	}
+	if err := os.Rename(tmp, cfg.DiskPath); err != nil {
+		_ = os.Remove(tmp)
+		return SnapshotInfo{}, fmt.Errorf("replace disk: %w", err)
+	}
+	logging.L().Info("restored disk snapshot", "name", name, "disk", cfg.DiskPath)
+	return info, nil
+}
+
+// requireVMStopped fails unless the VM's control socket is gone. An
+// unresponsive socket counts as running: the host may still hold the disk.
+func requireVMStopped(cfg *config.Config, verb string) error {
+	err := control.NewClient(cfg.VMDir).Ping(context.Background())
+	if errors.Is(err, control.ErrNotRunning) {
+		return nil
+	}
+	return fmt.Errorf("VM is running; stop it first ('br stop') before %s", verb)
+}
+
+func validateSnapshotName(name string) error {
+	if !snapshotNameRE.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use letters, digits, '.', '_' and '-', starting with a letter or digit", name)
+	}
+	return nil
+}
+
+// readSnapshotInfo loads the named snapshot's metadata, falling back to the
+// disk copy's file info when the metadata is missing or unreadable. The error
+// wraps os.ErrNotExist when there is no disk copy.
+func readSnapshotInfo(dir, name string) (SnapshotInfo, error) {
+	path := filepath.Join(dir, name+snapshotDiskExt)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("stat snapshot %s: %w", name, err)
+	}
+	info := SnapshotInfo{Name: name, Path: path, Created: fi.ModTime().UTC(), Size: fi.Size()}
+	if b, err := os.ReadFile(filepath.Join(dir, name+snapshotMetaExt)); err == nil {
+		var meta SnapshotInfo
+		if json.Unmarshal(b, &meta) == nil && !meta.Created.IsZero() {
+			info.Created = meta.Created
+		}
+	}
+	return info, nil
+}
+
+// copyDisk copies the raw disk image src to dst, keeping it sparse, and
+// reports progress under label. A partial dst is removed on failure.
+func copyDisk(src, dst, label string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", src, err)
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", dst, err)
+	}
+
+	sourceSize := int64(0)
+	if fi, err := in.Stat(); err == nil {
+		sourceSize = fi.Size()
+	}
+	progress := logging.NewByteProgress(label, sourceSize)
+	if err := util.WriteSparse(out, io.TeeReader(in, progress)); err != nil {
+		progress.Fail(err)
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return 0, fmt.Errorf("copy %s to %s: %w", src, dst, err)
+	}
+	progress.Finish()
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dst)
+		return 0, fmt.Errorf("close %s: %w", dst, err)
+	}
+	return sourceSize, nil
+}
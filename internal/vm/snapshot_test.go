@@ -0,0 +1,114 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/control"
+)
+
+func TestSnapshotCreateListRestore(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := append(bytes.Repeat([]byte{0}, 128<<10), []byte("configured")...)
+	if err := os.WriteFile(cfg.DiskPath, original, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := CreateSnapshot(cfg, "clean")
+	if err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	if s.Size != int64(len(original)) {
+		t.Errorf("Size = %d, want %d", s.Size, len(original))
+	}
+	if _, err := CreateSnapshot(cfg, "clean"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("second CreateSnapshot: err = %v, want already exists", err)
+	}
+
+	list, err := ListSnapshots(cfg)
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "clean" || !list[0].Created.Equal(s.Created) {
+		t.Errorf("ListSnapshots = %+v, want [%+v]", list, s)
+	}
+
+	if err := os.WriteFile(cfg.DiskPath, []byte("experiment"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RestoreSnapshot(cfg, "clean"); err != nil {
+		t.Fatalf("RestoreSnapshot: %v", err)
+	}
+	got, err := os.ReadFile(cfg.DiskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, original) {
+		t.Error("restored disk does not match the snapshot")
+	}
+
+	if _, err := RestoreSnapshot(cfg, "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("RestoreSnapshot(missing): err = %v, want not found", err)
+	}
+}
+
+func TestSnapshotRejectsBadNames(t *testing.T) {
+	cfg, err := config.Default(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "../disk", "a/b", ".hidden", "-flag"} {
+		if _, err := CreateSnapshot(cfg, name); err == nil || !strings.Contains(err.Error(), "invalid snapshot name") {
+			t.Errorf("CreateSnapshot(%q): err = %v, want invalid name", name, err)
+		}
+	}
+}
+
+// TestRestoreSnapshotRefusesRunningVM serves the VM's control socket and
+// checks the restore fails without touching the disk.
+func TestRestoreSnapshotRefusesRunningVM(t *testing.T) {
+	// A short base dir keeps the control socket path under the sun_path limit.
+	dir, err := os.MkdirTemp("/tmp", "snap-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	cfg, err := config.Default(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.DiskPath, []byte("snapshot"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateSnapshot(cfg, "s1"); err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	if err := os.WriteFile(cfg.DiskPath, []byte("live"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := control.NewListenerWithConfig(control.ListenerConfig{StateDir: cfg.VMDir, Controller: control.ControllerFunc{}})
+	if err != nil {
+		t.Fatalf("NewListenerWithConfig: %v", err)
+	}
+	defer func() { _ = server.Close() }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := RestoreSnapshot(cfg, "s1"); err == nil || !strings.Contains(err.Error(), "VM is running") {
+		t.Fatalf("RestoreSnapshot while running: err = %v, want VM is running", err)
+	}
+	if got, _ := os.ReadFile(cfg.DiskPath); string(got) != "live" {
+		t.Errorf("disk = %q after refused restore, want it untouched", got)
+	}
+}
@@ -0,0 +1,53 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/logging"
+	"github.com/stuffbucket/bladerunner/internal/util"
+)
+
+// EnsureVMDir creates cfg.VMDir and probes that it takes writes. Callers run
+// it before the first write into the VM dir (the control socket, the PID
+// file, the seed ISO, the image download), so a read-only or full volume
+// fails with one clear cause instead of a raw OS error from whichever write
+// happens to hit it first. It does not log, as start runs it before the log
+// is set up.
+func EnsureVMDir(cfg *config.Config) error {
+	if err := os.MkdirAll(cfg.VMDir, 0o755); err != nil {
+		return stateDirError(cfg, err)
+	}
+	if err := util.CheckDirWritable(cfg.VMDir); err != nil {
+		return stateDirError(cfg, err)
+	}
+	return nil
+}
+
+// stateDirError reports that the state dir cannot take writes, with the free
+// space on its volume when that can be read.
+func stateDirError(cfg *config.Config, err error) error {
+	free := "unknown"
+	if b, ferr := util.FreeBytes(nearestExistingDir(cfg.VMDir)); ferr == nil {
+		free = logging.HumanBytes(int64(min(b, math.MaxInt64)))
+	}
+	return fmt.Errorf("state dir %s is not writable or is full (%s free): %w", cfg.StateDir, free, err)
+}
+
+// nearestExistingDir walks up from path to the first directory that exists,
+// so free space can be reported even when VMDir itself could not be created.
+func nearestExistingDir(path string) string {
+	for {
+		if util.DirExists(path) {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,33 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stuffbucket/bladerunner/internal/config"
+	"github.com/stuffbucket/bladerunner/internal/util"
+)
+
+func TestEnsureVMDir(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{StateDir: dir, VMDir: filepath.Join(dir, "vms", "a")}
+	if err := EnsureVMDir(cfg); err != nil {
+		t.Fatalf("EnsureVMDir(writable) = %v", err)
+	}
+	if !util.DirExists(cfg.VMDir) {
+		t.Fatalf("VM dir %s not created", cfg.VMDir)
+	}
+
+	// A VM dir under a regular file can never be created, even as root.
+	blocker := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.VMDir = filepath.Join(blocker, "vm")
+	err := EnsureVMDir(cfg)
+	if err == nil || !strings.Contains(err.Error(), "is not writable or is full") {
+		t.Fatalf("EnsureVMDir(blocked) = %v, want a state dir error", err)
+	}
+}
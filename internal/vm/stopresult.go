@@ -0,0 +1,64 @@
+package vm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// stopResultFileName is where the host records how its VM last went down.
+const stopResultFileName = "last-stop.json"
+
+// StopResult reports how Stop (or ForceStop) brought the VM down.
+type StopResult struct {
+	// Graceful is true when the VM reached the stopped state without a forced
+	// stop: the guest acknowledged an ACPI stop request and powered off, or
+	// had already powered off on its own.
+	Graceful bool `json:"graceful"`
+	// Forced is true when the VM had to be torn down without the guest's
+	// cooperation, either because it ignored the stop requests or because
+	// none were sent (ForceStop, or a guest paused after a state save).
+	Forced bool `json:"forced"`
+	// Attempts is how many ACPI stop requests were sent.
+	Attempts int `json:"attempts"`
+}
+
+// StopResultPath returns where WriteStopResult records the result for the VM
+// in vmDir.
+func StopResultPath(vmDir string) string {
+	return filepath.Join(vmDir, stopResultFileName)
+}
+
+// WriteStopResult records res for the VM in vmDir, for `br stop` to read once
+// the host process has gone and its control socket with it.
+func WriteStopResult(vmDir string, res StopResult) error {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(StopResultPath(vmDir), b, 0o644); err != nil {
+		return fmt.Errorf("write stop result: %w", err)
+	}
+	return nil
+}
+
+// ReadStopResult returns the result recorded for the VM in vmDir. ok is false
+// when there is none, as with a host that predates it.
+func ReadStopResult(vmDir string) (StopResult, bool) {
+	b, err := os.ReadFile(StopResultPath(vmDir))
+	if err != nil {
+		return StopResult{}, false
+	}
+	var res StopResult
+	if err := json.Unmarshal(b, &res); err != nil {
+		return StopResult{}, false
+	}
+	return res, true
+}
+
+// ClearStopResult removes a recorded result (best effort), so a later read
+// can't mistake it for the outcome of a new stop.
+func ClearStopResult(vmDir string) {
+	_ = os.Remove(StopResultPath(vmDir))
+}
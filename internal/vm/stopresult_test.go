@@ -0,0 +1,24 @@
+package vm
+
+import "testing"
+
+func TestStopResultRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := ReadStopResult(dir); ok {
+		t.Fatal("ReadStopResult found a result in an empty dir")
+	}
+
+	want := StopResult{Forced: true, Attempts: 3}
+	if err := WriteStopResult(dir, want); err != nil {
+		t.Fatalf("WriteStopResult: %v", err)
+	}
+	got, ok := ReadStopResult(dir)
+	if !ok || got != want {
+		t.Errorf("ReadStopResult = %+v, %v; want %+v, true", got, ok, want)
+	}
+
+	ClearStopResult(dir)
+	if _, ok := ReadStopResult(dir); ok {
+		t.Error("ReadStopResult still found a result after ClearStopResult")
+	}
+}
@@ -0,0 +1,70 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	incusctl "github.com/stuffbucket/bladerunner/internal/incus"
+)
+
+// trustCertName is the name the host client certificate is trusted under in
+// the guest's Incus, the same one the bootstrap's fallback trust add uses.
+const trustCertName = "bladerunner-host"
+
+// trustTimeout bounds one SSH round trip that updates the guest's trust
+// store.
+const trustTimeout = 30 * time.Second
+
+// trustRetryInterval paces ensureTrust's attempts during the Incus wait.
+const trustRetryInterval = 5 * time.Second
+
+// trustExitIncusDown is the trust script's exit status when incusd is not up
+// in the guest yet.
+const trustExitIncusDown = 3
+
+// errIncusNotRunning reports that the guest has no running Incus to trust the
+// certificate yet; the caller can retry once it is up.
+var errIncusNotRunning = errors.New("incus is not running in the guest yet")
+
+// trustScript makes the certificate on stdin, whose fingerprint is given, the
+// only one trusted as trustCertName: it adds it unless it is already trusted,
+// then removes any other certificate under that name, so a rotated-out key
+// stops working. The fingerprint is hex, so it needs no quoting.
+func trustScript(fingerprint string) string {
+	return fmt.Sprintf(`command -v incus >/dev/null 2>&1 && incus query /1.0 >/dev/null 2>&1 || exit %[3]d
+f=$(mktemp) || exit 1
+trap 'rm -f "$f"' EXIT
+cat >"$f"
+if ! incus query /1.0/certificates/%[1]s >/dev/null 2>&1; then
+  incus config trust add-certificate "$f" --name %[2]s || exit 1
+fi
+for old in $(incus query '/1.0/certificates?recursion=1' | jq -r '.[] | select(.name == "%[2]s" and .fingerprint != "%[1]s") | .fingerprint'); do
+  incus config trust remove "$old" || exit 1
+done
+`, fingerprint, trustCertName, trustExitIncusDown)
+}
+
+// trustCertificate adds certPEM to the guest Incus's trust store over SSH,
+// replacing any earlier host certificate. It returns errIncusNotRunning while
+// incusd is not up.
+func trustCertificate(ctx context.Context, sshConfigPath string, certPEM []byte) error {
+	fingerprint, err := incusctl.CertFingerprint(certPEM)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, trustTimeout)
+	defer cancel()
+
+	if _, err := runGuestSSH(ctx, sshConfigPath, bytes.NewReader(certPEM), rootScript(trustScript(fingerprint))...); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == trustExitIncusDown {
+			return errIncusNotRunning
+		}
+		return fmt.Errorf("trust client certificate: %w", err)
+	}
+	return nil
+}
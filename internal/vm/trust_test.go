@@ -0,0 +1,26 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+// The script adds the certificate only when its fingerprint is not trusted
+// yet, and removes every other certificate under the host's name.
+func TestTrustScript(t *testing.T) {
+	script := trustScript("abc123")
+	for _, want := range []string{
+		"|| exit 3",
+		"incus query /1.0/certificates/abc123 >/dev/null",
+		`incus config trust add-certificate "$f" --name bladerunner-host`,
+		`select(.name == "bladerunner-host" and .fingerprint != "abc123")`,
+		`incus config trust remove "$old"`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("trust script missing %q\n---\n%s", want, script)
+		}
+	}
+	if add, remove := strings.Index(script, "add-certificate"), strings.Index(script, "trust remove"); add > remove {
+		t.Error("trust script removes the old certificate before adding the new one")
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"net"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/Code-Hex/vz/v3"
 	"github.com/stuffbucket/bladerunner/internal/config"
@@ -106,7 +105,7 @@ func (r *Runner) configureNestedVirt(platformConfig *vz.GenericPlatformConfigura
 }
 
 func (r *Runner) configureStorage(cfg *vz.VirtualMachineConfiguration) error {
-	mainDiskAttach, err := vz.NewDiskImageStorageDeviceAttachment(r.cfg.DiskPath, false)
+	mainDiskAttach, err := vz.NewDiskImageStorageDeviceAttachment(mainDiskPath(r.cfg), false)
 	if err != nil {
 		return fmt.Errorf("create main disk attachment: %w", err)
 	}
@@ -124,10 +123,40 @@ func (r *Runner) configureStorage(cfg *vz.VirtualMachineConfiguration) error {
 		return fmt.Errorf("create cloud-init block config: %w", err)
 	}
 
-	cfg.SetStorageDevicesVirtualMachineConfiguration([]vz.StorageDeviceConfiguration{mainDisk, cloudInitDisk})
+	devices := []vz.StorageDeviceConfiguration{mainDisk, cloudInitDisk}
+	for i, d := range r.cfg.ExtraDisks {
+		dev, err := newExtraDisk(i, d)
+		if err != nil {
+			return err
+		}
+		devices = append(devices, dev)
+	}
+
+	cfg.SetStorageDevicesVirtualMachineConfiguration(devices)
 	return nil
 }
 
+// newExtraDisk attaches a user image as the i'th extra block device. The
+// identifier gives the guest a stable /dev/disk/by-id/virtio-extraN name; on
+// hosts that can't set one the device still attaches, in order, after vdb.
+func newExtraDisk(i int, d config.ExtraDisk) (*vz.VirtioBlockDeviceConfiguration, error) {
+	attach, err := vz.NewDiskImageStorageDeviceAttachment(d.Path, d.ReadOnly)
+	if err != nil {
+		return nil, fmt.Errorf("create extra disk attachment %s: %w", d.Path, err)
+	}
+	dev, err := vz.NewVirtioBlockDeviceConfiguration(attach)
+	if err != nil {
+		return nil, fmt.Errorf("create extra disk block config %s: %w", d.Path, err)
+	}
+	id := fmt.Sprintf("extra%d", i)
+	if err := dev.SetBlockDeviceIdentifier(id); err != nil {
+		logging.L().Warn("extra disk has no stable guest id", "path", d.Path, "err", err)
+		id = ""
+	}
+	logging.L().Info("attaching extra disk", "path", d.Path, "read_only", d.ReadOnly, "guest_id", id)
+	return dev, nil
+}
+
 func (r *Runner) configureNetwork(cfg *vz.VirtualMachineConfiguration) error {
 	attachment, err := r.newNetworkAttachment()
 	if err != nil {
@@ -156,16 +185,7 @@ func (r *Runner) configureNetwork(cfg *vz.VirtualMachineConfiguration) error {
 
 func (r *Runner) newNetworkAttachment() (vz.NetworkDeviceAttachment, error) {
 	if r.cfg.NetworkMode == config.NetworkModeBridged {
-		for _, iface := range vz.NetworkInterfaces() {
-			if iface.Identifier() == r.cfg.BridgeInterface || strings.EqualFold(iface.LocalizedDisplayName(), r.cfg.BridgeInterface) {
-				bridge, err := vz.NewBridgedNetworkDeviceAttachment(iface)
-				if err != nil {
-					return nil, fmt.Errorf("create bridged attachment for %s: %w", iface.Identifier(), err)
-				}
-				return bridge, nil
-			}
-		}
-		return nil, fmt.Errorf("bridged interface %s was not found", r.cfg.BridgeInterface)
+		return r.newBridgedAttachment()
 	}
 
 	nat, err := vz.NewNATNetworkDeviceAttachment()
@@ -175,6 +195,45 @@ func (r *Runner) newNetworkAttachment() (vz.NetworkDeviceAttachment, error) {
 	return nat, nil
 }
 
+// newBridgedAttachment resolves the configured bridge candidates against the
+// interfaces Virtualization.framework offers and bridges onto the first usable
+// one, recording the pick for the startup report.
+func (r *Runner) newBridgedAttachment() (vz.NetworkDeviceAttachment, error) {
+	ifaces := vz.NetworkInterfaces()
+	chosen, err := chooseBridgeInterface(r.cfg.BridgeCandidates(), bridgeIfaces(ifaces), hostInterfaceUsable, primaryHostInterface())
+	if err != nil {
+		return nil, err
+	}
+	logging.L().Info("bridged network interface selected", "interface", chosen.ID, "name", chosen.Display, "requested", r.cfg.BridgeInterface)
+	r.bridgeIface = chosen.ID
+
+	for _, iface := range ifaces {
+		if iface.Identifier() != chosen.ID {
+			continue
+		}
+		bridge, err := vz.NewBridgedNetworkDeviceAttachment(iface)
+		if err != nil {
+			return nil, fmt.Errorf("create bridged attachment for %s: %w", iface.Identifier(), err)
+		}
+		return bridge, nil
+	}
+	return nil, fmt.Errorf("bridged interface %s was not found", chosen.ID)
+}
+
+// hostBridgeInterfaces returns the interfaces Virtualization.framework
+// offers for bridging.
+func hostBridgeInterfaces() []bridgeIface {
+	return bridgeIfaces(vz.NetworkInterfaces())
+}
+
+func bridgeIfaces(ifaces []vz.BridgedNetwork) []bridgeIface {
+	available := make([]bridgeIface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		available = append(available, bridgeIface{ID: iface.Identifier(), Display: iface.LocalizedDisplayName()})
+	}
+	return available
+}
+
 func (r *Runner) configureGraphics(cfg *vz.VirtualMachineConfiguration) error {
 	graphics, err := vz.NewVirtioGraphicsDeviceConfiguration()
 	if err != nil {
@@ -187,6 +246,15 @@ func (r *Runner) configureGraphics(cfg *vz.VirtualMachineConfiguration) error {
 	}
 	graphics.SetScanouts(scanout)
 
+	cfg.SetGraphicsDevicesVirtualMachineConfiguration([]vz.GraphicsDeviceConfiguration{graphics})
+
+	// A view-only console gets the framebuffer alone: no pointing device or
+	// keyboard for the window to forward input to.
+	if !r.cfg.GUIInput {
+		logging.L().Info("GUI input devices disabled; console is view-only")
+		return nil
+	}
+
 	pointing, err := vz.NewUSBScreenCoordinatePointingDeviceConfiguration()
 	if err != nil {
 		return fmt.Errorf("create pointing device config: %w", err)
@@ -197,7 +265,6 @@ func (r *Runner) configureGraphics(cfg *vz.VirtualMachineConfiguration) error {
 		return fmt.Errorf("create keyboard config: %w", err)
 	}
 
-	cfg.SetGraphicsDevicesVirtualMachineConfiguration([]vz.GraphicsDeviceConfiguration{graphics})
 	cfg.SetPointingDevicesVirtualMachineConfiguration([]vz.PointingDeviceConfiguration{pointing})
 	cfg.SetKeyboardsVirtualMachineConfiguration([]vz.KeyboardConfiguration{keyboard})
 